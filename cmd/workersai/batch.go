@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func runBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai batch [flags] <prompts.jsonl|->\n\n"+
+			"Runs the chat requests of a JSON Lines file and writes a result per line as\n"+
+			"soon as it completes. A record is a chat request, with \"prompt\" and \"system\"\n"+
+			"as shorthands for the messages and an optional \"id\" copied to its result:\n\n"+
+			"  {\"id\": \"q1\", \"prompt\": \"What is 2+2?\"}\n"+
+			"  {\"id\": \"q2\", \"model\": \"@cf/meta/llama-3-8b-instruct\", \"messages\": [...], \"max_tokens\": 64}\n\n"+
+			"The results file doubles as a checkpoint: with -resume, the records that\n"+
+			"succeeded in a previous run are skipped and the others retried.\n\n")
+		fs.PrintDefaults()
+	}
+	model := fs.String("m", "", "`model` of the records that don't set one")
+	output := fs.String("o", "", "write the results to this `file` instead of stdout")
+	resume := fs.Bool("resume", false, "skip the records that already succeeded in the -o file")
+	concurrency := fs.Int("concurrency", workersai.DefaultBatchWorkers, "number of requests sent at once")
+	rate := fs.Float64("rate", 0, "maximum requests per second, 0 for no limit")
+	asJSON := fs.Bool("json", false, "print the summary of the batch as JSON (requires -o)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || (*resume || *asJSON) && *output == "" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	input, err := readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	records, err := parseBatchRecords(input, *model)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		done := map[string]bool{}
+		if *resume {
+			if done, err = loadCheckpoint(*output); err != nil {
+				return err
+			}
+		}
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if !*resume {
+			if err := f.Truncate(0); err != nil {
+				return err
+			}
+		}
+		out = f
+
+		pending := records[:0]
+		for _, record := range records {
+			if !done[record.key()] {
+				pending = append(pending, record)
+			}
+		}
+		if skipped := len(records) - len(pending); skipped > 0 {
+			fmt.Fprintf(os.Stderr, "Skipping %d records completed in %s\n", skipped, *output)
+		}
+		records = pending
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	if *rate > 0 {
+		client.Scheduler = workersai.NewScheduler(*rate, *concurrency)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	writer := bufio.NewWriter(out)
+	results := workersai.NewJSONLWriter(writer)
+	summary := runBatchRecords(ctx, client, records, *concurrency, results)
+	if err := results.Err(); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := printJSON(os.Stdout, summary); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "%d records: %d succeeded, %d failed, %d tokens\n", summary.Total, summary.Succeeded, summary.Failed, summary.Usage.TotalTokens)
+	}
+	switch {
+	case ctx.Err() != nil:
+		return &exitCodeError{code: exitInterrupted, err: fmt.Errorf("interrupted, %d records failed", summary.Failed)}
+	case summary.Failed > 0:
+		return fmt.Errorf("%d of %d records failed", summary.Failed, summary.Total)
+	}
+	return nil
+}
+
+// batchRecord is a record of the input of 'workersai batch'.
+type batchRecord struct {
+	// ID is the "id" of the record, if any.
+	ID json.RawMessage
+	// Line is the line of the record in the input, from 1.
+	Line    int
+	Request workersai.ChatCompletionRequest
+}
+
+// key identifies the record in a checkpoint: its ID, or its line when it
+// has none.
+func (r *batchRecord) key() string {
+	if len(r.ID) > 0 {
+		return string(r.ID)
+	}
+	return "line " + strconv.Itoa(r.Line)
+}
+
+// parseBatchRecords decodes the records of input, one per non-blank line,
+// sending those without a model to model.
+func parseBatchRecords(input []byte, model string) ([]batchRecord, error) {
+	var records []batchRecord
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		record := batchRecord{Line: line}
+		var shorthand struct {
+			ID     json.RawMessage `json:"id"`
+			Prompt string          `json:"prompt"`
+			System string          `json:"system"`
+		}
+		if err := json.Unmarshal(data, &shorthand); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := json.Unmarshal(data, &record.Request); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !bytes.Equal(shorthand.ID, []byte("null")) {
+			record.ID = shorthand.ID
+		}
+
+		var messages []workersai.Message
+		if shorthand.System != "" {
+			messages = append(messages, workersai.ChatMessage{Role: "system", Content: shorthand.System})
+		}
+		if shorthand.Prompt != "" {
+			messages = append(messages, workersai.ChatMessage{Role: "user", Content: shorthand.Prompt})
+		}
+		record.Request.Messages = append(messages, record.Request.Messages...)
+		if len(record.Request.Messages) == 0 {
+			return nil, fmt.Errorf("line %d: no prompt or messages", line)
+		}
+
+		if record.Request.Model == "" {
+			record.Request.Model = model
+		}
+		if record.Request.Model == "" {
+			return nil, fmt.Errorf("line %d: no model, use -m or set \"model\"", line)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	return records, nil
+}
+
+// batchResult is a line of the output of 'workersai batch'.
+type batchResult struct {
+	ID        json.RawMessage      `json:"id,omitempty"`
+	Line      int                  `json:"line"`
+	Status    string               `json:"status"`
+	Model     string               `json:"model"`
+	Content   string               `json:"content,omitempty"`
+	ToolCalls []workersai.ToolCall `json:"tool_calls,omitempty"`
+	Usage     workersai.Usage      `json:"usage"`
+	LatencyMS int64                `json:"latency_ms,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	ErrorType string               `json:"error_type,omitempty"`
+}
+
+// key identifies the result in a checkpoint, like batchRecord.key.
+func (r *batchResult) key() string {
+	record := batchRecord{ID: r.ID, Line: r.Line}
+	return record.key()
+}
+
+// runBatchRecords sends the requests of records, concurrency at a time,
+// writing their results to w as they complete.
+func runBatchRecords(ctx context.Context, client *workersai.Client, records []batchRecord, concurrency int, w *workersai.JSONLWriter) workersai.BatchSummary {
+	requests := make([]workersai.ChatCompletionRequest, len(records))
+	for i, record := range records {
+		requests[i] = record.Request
+	}
+
+	batch := client.ChatBatchEach(ctx, requests, concurrency, func(item workersai.BatchItem[*workersai.ChatResponse]) {
+		record := records[item.Index]
+		result := batchResult{ID: record.ID, Line: record.Line, Status: item.Status, Model: record.Request.Model, Usage: item.Usage}
+		if item.Err != nil {
+			result.Error = item.Err.Error()
+			result.ErrorType = item.ErrorType
+		} else {
+			result.Content = item.Result.GetContent()
+			result.ToolCalls = item.Result.GetToolCalls()
+			result.LatencyMS = item.Result.Latency().Milliseconds()
+		}
+		w.Write(result)
+	})
+	return batch.Summary
+}
+
+// loadCheckpoint reads the results file of a previous run and returns the
+// keys of the records that succeeded. The results of the others are
+// removed from the file, as they are about to be retried.
+func loadCheckpoint(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[string]bool{}
+	var kept bytes.Buffer
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var result batchResult
+		// Skip a line cut short by an interruption, and the failures.
+		if json.Unmarshal(line, &result) != nil || result.Status != workersai.BatchSucceeded || done[result.key()] {
+			continue
+		}
+		done[result.key()] = true
+		kept.Write(line)
+		kept.WriteByte('\n')
+	}
+
+	if err := os.WriteFile(path+".tmp", kept.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+	return done, nil
+}
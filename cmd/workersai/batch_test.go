@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestParseBatchRecords(t *testing.T) {
+	input := `{"id": "q1", "prompt": "What is 2+2?", "system": "Be brief."}
+
+{"model": "custom/model", "messages": [{"role": "user", "content": "Hi"}], "max_tokens": 64}
+`
+	records, err := parseBatchRecords([]byte(input), workersai.ModelLlama38B)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, `"q1"`, string(records[0].ID))
+	assert.Equal(t, `"q1"`, records[0].key())
+	assert.Equal(t, workersai.ModelLlama38B, records[0].Request.Model)
+	assert.Equal(t, []workersai.Message{
+		workersai.ChatMessage{Role: "system", Content: "Be brief."},
+		workersai.ChatMessage{Role: "user", Content: "What is 2+2?"},
+	}, records[0].Request.Messages)
+
+	assert.Nil(t, records[1].ID)
+	assert.Equal(t, "line 3", records[1].key())
+	assert.Equal(t, "custom/model", records[1].Request.Model)
+	assert.Equal(t, int64(64), records[1].Request.MaxTokens)
+
+	_, err = parseBatchRecords([]byte(`{"prompt": "Hi"}`), "")
+	assert.EqualError(t, err, `line 1: no model, use -m or set "model"`)
+	_, err = parseBatchRecords([]byte(`{"id": 1}`), workersai.ModelLlama38B)
+	assert.EqualError(t, err, "line 1: no prompt or messages")
+}
+
+func TestRunBatchRecords_Resume(t *testing.T) {
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req workersai.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		prompt := req.Messages[len(req.Messages)-1].(workersai.ChatMessage).Content
+		if prompt == "fail" && failing {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"success": false, "errors": [{"code": 5006, "message": "bad input"}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "result": {"response": "echo %s", "usage": {"prompt_tokens": 1, "completion_tokens": 2, "total_tokens": 3}}}`, prompt)
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	records, err := parseBatchRecords([]byte(`{"id": "a", "prompt": "one"}
+{"id": "b", "prompt": "fail"}
+{"prompt": "three"}
+`), workersai.ModelLlama38B)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	run := func(records []batchRecord) workersai.BatchSummary {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		require.NoError(t, err)
+		defer f.Close()
+		return runBatchRecords(context.Background(), client, records, 2, workersai.NewJSONLWriter(f))
+	}
+	readResults := func() []batchResult {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var results []batchResult
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var result batchResult
+			require.NoError(t, json.Unmarshal([]byte(line), &result))
+			results = append(results, result)
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+		return results
+	}
+
+	summary := run(records)
+	assert.Equal(t, workersai.BatchSummary{Total: 3, Succeeded: 2, Failed: 1, Usage: workersai.Usage{PromptTokens: 2, CompletionTokens: 4, TotalTokens: 6}}, summary)
+	results := readResults()
+	require.Len(t, results, 3)
+	assert.Equal(t, "echo one", results[0].Content)
+	assert.Equal(t, workersai.BatchFailed, results[1].Status)
+	assert.Equal(t, workersai.ErrorTypeInvalidRequest, results[1].ErrorType)
+	assert.Equal(t, 3, results[2].Line)
+	assert.Nil(t, results[2].ID)
+
+	// A line cut short by an interruption is dropped with the failure.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	f.WriteString(`{"id": "c", "sta`)
+	f.Close()
+
+	done, err := loadCheckpoint(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{`"a"`: true, "line 3": true}, done)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(data, []byte("\n")))
+
+	failing = false
+	summary = run(records[1:2])
+	assert.Equal(t, 1, summary.Succeeded)
+	results = readResults()
+	require.Len(t, results, 3)
+	assert.Equal(t, `"b"`, string(results[1].ID))
+	assert.Equal(t, "echo fail", results[1].Content)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// demo is a subsystem of the library exercised end to end by 'workersai
+// demo', failing when the outcome isn't what a working setup gives.
+type demo struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *demoEnv) error
+}
+
+// demoEnv is what the demos run with. They narrate to out.
+type demoEnv struct {
+	client *workersai.Client
+	model  string
+	index  string
+	out    io.Writer
+}
+
+var demos = []demo{
+	{"chat", "a chat completion with a system prompt", demoChat},
+	{"tools", "a tool loop calling a typed Go function", demoTools},
+	{"stream", "a streamed completion with timing", demoStream},
+	{"rag", "ingestion into Vectorize and a retrieval-augmented answer", demoRAG},
+}
+
+// errDemoSkipped is returned by the demos missing their configuration.
+var errDemoSkipped = errors.New("skipped")
+
+// demoOutcome is the outcome of a demo, as printed by -json.
+type demoOutcome struct {
+	Demo      string `json:"demo"`
+	Status    string `json:"status"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+func runDemo(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai demo [flags] [demo...]\n\n"+
+			"Runs demos of the library against the configured account, all of them by\n"+
+			"default. They check their outcome, so that they double as smoke tests: the\n"+
+			"command fails if any demo fails.\n\nDemos:\n")
+		for _, d := range demos {
+			fmt.Fprintf(fs.Output(), "  %-8s %s\n", d.name, d.summary)
+		}
+		fmt.Fprintf(fs.Output(), "\n")
+		fs.PrintDefaults()
+	}
+	model := fs.String("m", workersai.ModelQwen330ba3b, "the chat `model`, which must support tool calling")
+	index := fs.String("index", os.Getenv("VECTORIZE_INDEX"), "Vectorize `index` of 768 dimensions for the rag demo, which is skipped without one (default $VECTORIZE_INDEX)")
+	asJSON := fs.Bool("json", false, "print the outcome of every demo as JSON instead of narrating them")
+	fs.Parse(args)
+
+	selected, err := selectDemos(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env := &demoEnv{client: client, model: *model, index: *index, out: os.Stdout}
+	if *asJSON {
+		env.out = io.Discard
+	}
+	outcomes := runDemos(ctx, env, selected)
+
+	failed := 0
+	for _, outcome := range outcomes {
+		if outcome.Status == "failed" {
+			failed++
+		}
+	}
+	if *asJSON {
+		if err := printJSON(os.Stdout, outcomes); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d demos failed", failed, len(outcomes))
+	}
+	return nil
+}
+
+// selectDemos returns the demos named, all of them if none is.
+func selectDemos(names []string) ([]demo, error) {
+	if len(names) == 0 {
+		return demos, nil
+	}
+	var selected []demo
+	for _, name := range names {
+		found := false
+		for _, d := range demos {
+			if d.name == name {
+				selected = append(selected, d)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown demo %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// runDemos runs the demos one after the other, reporting their outcome on
+// stderr as they complete.
+func runDemos(ctx context.Context, env *demoEnv, selected []demo) []demoOutcome {
+	var outcomes []demoOutcome
+	for _, d := range selected {
+		fmt.Fprintf(env.out, "--- %s: %s\n", d.name, d.summary)
+		start := time.Now()
+		err := d.run(ctx, env)
+		outcome := demoOutcome{Demo: d.name, Status: "ok", ElapsedMS: time.Since(start).Milliseconds()}
+		switch {
+		case errors.Is(err, errDemoSkipped):
+			outcome.Status = "skipped"
+			outcome.Error = err.Error()
+		case err != nil:
+			outcome.Status = "failed"
+			outcome.Error = err.Error()
+		}
+		if outcome.Error != "" {
+			fmt.Fprintf(os.Stderr, "%-7s %s (%dms): %s\n", outcome.Status, d.name, outcome.ElapsedMS, outcome.Error)
+		} else {
+			fmt.Fprintf(os.Stderr, "%-7s %s (%dms)\n", outcome.Status, d.name, outcome.ElapsedMS)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+func demoChat(ctx context.Context, env *demoEnv) error {
+	resp, err := env.client.ChatCompletionContext(ctx, workersai.ChatCompletionRequest{
+		Model: env.model,
+		Messages: []workersai.Message{
+			workersai.ChatMessage{Role: "system", Content: "You are a friendly assistant. Answer in one sentence."},
+			workersai.ChatMessage{Role: "user", Content: "Why is pizza so good?"},
+		},
+		ModelParameters: workersai.ModelParameters{Temperature: 0.1},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.out, "Response: %s\n", resp.GetContent())
+	if reasoning := resp.GetReasoningContent(); reasoning != "" {
+		fmt.Fprintf(env.out, "Reasoning: %s\n", reasoning)
+	}
+	usage := resp.GetUsage()
+	fmt.Fprintf(env.out, "Usage: %d prompt + %d completion = %d total tokens\n", usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+	if strings.TrimSpace(resp.GetContent()) == "" {
+		return errors.New("empty response")
+	}
+	return nil
+}
+
+// weatherArgs are the arguments of the get_weather tool of the tools demo.
+type weatherArgs struct {
+	Location string `json:"location" description:"The city and state, e.g. San Francisco, CA"`
+	Unit     string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+}
+
+func demoTools(ctx context.Context, env *demoEnv) error {
+	registry := workersai.NewToolRegistry()
+	err := workersai.RegisterTool(registry, "get_weather", "Get the current weather in a given location",
+		func(ctx context.Context, args weatherArgs) (map[string]interface{}, error) {
+			fmt.Fprintf(env.out, "Tool call: get_weather(location=%q, unit=%q)\n", args.Location, args.Unit)
+			return map[string]interface{}{"location": args.Location, "temperature": 18, "unit": "celsius", "conditions": "foggy"}, nil
+		})
+	if err != nil {
+		return err
+	}
+
+	runner := workersai.NewToolRunner(registry, workersai.ToolGuard{Timeout: 10 * time.Second})
+	result, err := runner.Loop(ctx, env.client, workersai.ChatCompletionRequest{
+		Model: env.model,
+		Messages: []workersai.Message{
+			workersai.ChatMessage{Role: "system", Content: "You are a helpful assistant with access to weather information."},
+			workersai.ChatMessage{Role: "user", Content: "What's the weather like in San Francisco?"},
+		},
+		Tools: runner.Tools(),
+	}, workersai.ToolBudget{MaxSteps: 4})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.out, "Response: %s\n", result.Response.GetContent())
+	fmt.Fprintf(env.out, "%d steps, %d tool calls, %d tokens\n", result.Steps, result.Calls, result.Usage.TotalTokens)
+	if result.Calls == 0 {
+		return errors.New("the model didn't call the tool")
+	}
+	return nil
+}
+
+func demoStream(ctx context.Context, env *demoEnv) error {
+	messages := []workersai.Message{workersai.ChatMessage{Role: "user", Content: "Count from 1 to 10, separated by spaces."}}
+	summary, err := streamCompletion(ctx, env.client, env.model, messages, nil, nil, env.out)
+	if err != nil {
+		return err
+	}
+
+	stats := summary.Stats
+	fmt.Fprintf(env.out, "%d chunks, first after %s, %.1f tokens/s\n", stats.Chunks, stats.TimeToFirstChunk.Round(time.Millisecond), stats.TokensPerSecond)
+	if stats.Chunks < 2 {
+		return fmt.Errorf("expected several chunks, got %d", stats.Chunks)
+	}
+	return nil
+}
+
+// demoDocuments are the documents ingested by the rag demo.
+var demoDocuments = []workersai.Document{
+	{ID: "demo-pizza", Text: "Pizza is a dish of Italian origin: a flat base of dough topped with tomatoes and cheese, baked at a high temperature."},
+	{ID: "demo-pasta", Text: "Pasta is made from unleavened wheat dough, shaped into sheets or strands and cooked by boiling."},
+}
+
+func demoRAG(ctx context.Context, env *demoEnv) error {
+	if env.index == "" {
+		return fmt.Errorf("%w: no Vectorize index, use -index or set VECTORIZE_INDEX", errDemoSkipped)
+	}
+	index := env.client.VectorizeIndex(env.index)
+
+	ingester := workersai.NewIngester(env.client, workersai.ModelBAAI, index)
+	for _, doc := range demoDocuments {
+		chunks, err := ingester.Ingest(ctx, doc)
+		if err != nil {
+			return fmt.Errorf("failed to ingest %s: %w", doc.ID, err)
+		}
+		fmt.Fprintf(env.out, "Ingested %s in %d chunks\n", doc.ID, chunks)
+	}
+
+	// Upserts are applied asynchronously: poll until they are visible.
+	question := "How is pasta cooked?"
+	retriever := workersai.NewRetriever(env.client, workersai.ModelBAAI, index)
+	query := &workersai.VectorQuery{TopK: 1}
+	var matches []workersai.VectorMatch
+	for attempt := 0; ; attempt++ {
+		var err error
+		if matches, err = retriever.Retrieve(ctx, question, query); err != nil {
+			return err
+		}
+		if len(matches) > 0 && strings.HasPrefix(matches[0].ID, "demo-pasta") || attempt == 10 {
+			break
+		}
+		select {
+		case <-time.After(3 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if len(matches) == 0 || !strings.HasPrefix(matches[0].ID, "demo-pasta") {
+		return fmt.Errorf("retrieved %v for %q, expected demo-pasta", matches, question)
+	}
+	fmt.Fprintf(env.out, "Retrieved %s (score %.2f)\n", matches[0].ID, matches[0].Score)
+
+	text, _ := matches[0].Metadata["text"].(string)
+	resp, err := env.client.ChatCompletionContext(ctx, workersai.ChatCompletionRequest{
+		Model: env.model,
+		Messages: []workersai.Message{
+			workersai.ChatMessage{Role: "system", Content: "Answer the question using only this context:\n" + text},
+			workersai.ChatMessage{Role: "user", Content: question},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.out, "Response: %s\n", resp.GetContent())
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestRunDemos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req workersai.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range []string{`{"response": "1 2 3"}`, `{"response": " 4 5"}`, `[DONE]`} {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+			return
+		}
+		_, called := req.Messages[len(req.Messages)-1].(workersai.ToolMessage)
+		if len(req.Tools) > 0 && !called {
+			fmt.Fprint(w, `{"success": true, "result": {"tool_calls": [{"name": "get_weather", "arguments": {"location": "San Francisco, CA"}}]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Because of the cheese."}}`)
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var out strings.Builder
+	env := &demoEnv{client: client, model: workersai.ModelLlama38B, out: &out}
+	outcomes := runDemos(context.Background(), env, demos)
+
+	require.Len(t, outcomes, 4)
+	for _, outcome := range outcomes[:3] {
+		assert.Equal(t, "ok", outcome.Status, "%s: %s", outcome.Demo, outcome.Error)
+	}
+	assert.Equal(t, "rag", outcomes[3].Demo)
+	assert.Equal(t, "skipped", outcomes[3].Status)
+
+	assert.Contains(t, out.String(), "Response: Because of the cheese.")
+	assert.Contains(t, out.String(), `Tool call: get_weather(location="San Francisco, CA", unit="")`)
+	assert.Contains(t, out.String(), "1 2 3 4 5\n")
+}
+
+func TestSelectDemos(t *testing.T) {
+	selected, err := selectDemos([]string{"stream", "chat"})
+	require.NoError(t, err)
+	require.Len(t, selected, 2)
+	assert.Equal(t, "stream", selected[0].name)
+
+	all, err := selectDemos(nil)
+	require.NoError(t, err)
+	assert.Len(t, all, len(demos))
+
+	_, err = selectDemos([]string{"vision"})
+	assert.EqualError(t, err, `unknown demo "vision"`)
+}
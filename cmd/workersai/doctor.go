@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// Statuses of a doctorCheck.
+const (
+	checkOK      = "ok"
+	checkWarn    = "warn"
+	checkFail    = "fail"
+	checkSkipped = "skipped"
+)
+
+// doctorCheck is the outcome of a check of 'workersai doctor'.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	// Fix tells how to solve the problem found, if any.
+	Fix string `json:"fix,omitempty"`
+}
+
+// Pages linked to by the fixes of 'workersai doctor'.
+const (
+	tokenURL  = "https://dash.cloudflare.com/profile/api-tokens"
+	modelsURL = "https://developers.cloudflare.com/workers-ai/models/"
+)
+
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai doctor [flags]\n\n"+
+			"Checks the configuration, the credentials and their permissions, the\n"+
+			"connectivity to the API, the AI Gateway and the availability of models, and\n"+
+			"tells how to fix the problems found. It runs a one-token completion unless\n"+
+			"-no-run is given.\n\n")
+		fs.PrintDefaults()
+	}
+	task := fs.String("task", "Text Generation", "check that models are available for this `task`")
+	model := fs.String("m", workersai.ModelLlama38B, "check that this `model` exists and runs")
+	noRun := fs.Bool("no-run", false, "don't run the model, which costs a token")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of every check")
+	asJSON := fs.Bool("json", false, "print the checks as JSON")
+	fs.Parse(args)
+
+	d := &doctor{task: *task, model: *model, run: !*noRun, timeout: *timeout}
+	checks := d.runChecks(context.Background())
+
+	if *asJSON {
+		if err := printJSON(os.Stdout, checks); err != nil {
+			return err
+		}
+	} else {
+		printChecks(os.Stdout, checks)
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if check.Status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
+
+// doctor runs the checks of 'workersai doctor'.
+type doctor struct {
+	task    string
+	model   string
+	run     bool
+	timeout time.Duration
+
+	// cfg and client are set by the configuration check; client is nil if
+	// it failed.
+	cfg    *workersai.Config
+	client *workersai.Client
+}
+
+// runChecks runs the checks in order. Those needing a working client are
+// skipped once one of the configuration, connectivity or credentials
+// checks failed.
+func (d *doctor) runChecks(ctx context.Context) []doctorCheck {
+	steps := []struct {
+		name  string
+		check func(ctx context.Context) doctorCheck
+	}{
+		{"config", d.checkConfig},
+		{"connectivity", d.checkConnectivity},
+		{"credentials", d.checkCredentials},
+		{"gateway", d.checkGateway},
+		{"task", d.checkTask},
+		{"model", d.checkModel},
+		{"run", d.checkRun},
+	}
+
+	var checks []doctorCheck
+	blocked := ""
+	for _, step := range steps {
+		if blocked != "" {
+			checks = append(checks, doctorCheck{Name: step.name, Status: checkSkipped, Detail: "skipped after the failed " + blocked + " check"})
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, d.timeout)
+		check := step.check(ctx)
+		cancel()
+		check.Name = step.name
+		checks = append(checks, check)
+		if check.Status == checkFail && (step.name == "config" || step.name == "connectivity" || step.name == "credentials") {
+			blocked = step.name
+		}
+	}
+	return checks
+}
+
+func (d *doctor) checkConfig(ctx context.Context) doctorCheck {
+	cfg, err := loadConfig()
+	if err != nil {
+		return doctorCheck{Status: checkFail, Detail: err.Error(), Fix: "fix the configuration file given with -config or $" + workersai.EnvConfigFile}
+	}
+	d.cfg = cfg
+
+	source := "$" + workersai.EnvAPIToken
+	if os.Getenv(workersai.EnvAPIToken) == "" {
+		source = "the configuration file"
+	}
+	if cfg.APIToken == "" && cfg.AccountID != "" && !*noKeyring {
+		token, err := systemKeyring.Get(cfg.AccountID)
+		if err != nil && !errors.Is(err, errNotInKeyring) {
+			return doctorCheck{Status: checkFail, Detail: "failed to read the keyring: " + err.Error(), Fix: "set $" + workersai.EnvAPIToken + " or use -no-keyring"}
+		}
+		cfg.APIToken, source = token, "the keyring"
+	}
+
+	switch {
+	case cfg.AccountID == "":
+		return doctorCheck{Status: checkFail, Detail: "no account ID", Fix: "set $" + workersai.EnvAccountID + " to the ID in the URL of the dashboard, dash.cloudflare.com/<account ID>"}
+	case cfg.APIToken == "":
+		return doctorCheck{Status: checkFail, Detail: "no API token", Fix: "create a token from the Workers AI template at " + tokenURL + " and run 'workersai login' or set $" + workersai.EnvAPIToken}
+	}
+
+	client, err := cfg.NewClient()
+	if err != nil {
+		return doctorCheck{Status: checkFail, Detail: err.Error()}
+	}
+	d.client = client
+
+	detail := fmt.Sprintf("account %s, token from %s", cfg.AccountID, source)
+	if cfg.Profile != "" {
+		detail += ", profile " + cfg.Profile
+	}
+	if cfg.Gateway != "" {
+		detail += ", gateway " + cfg.Gateway
+	}
+	return doctorCheck{Status: checkOK, Detail: detail}
+}
+
+func (d *doctor) checkConnectivity(ctx context.Context) doctorCheck {
+	report, err := d.client.Ping(ctx)
+	if report.StatusCode == 0 {
+		// The API is reached directly even when model runs go through
+		// the AI Gateway.
+		apiURL := d.client.BaseURL
+		if d.cfg.Gateway != "" && apiURL == workersai.DefaultGatewayBaseURL {
+			apiURL = workersai.DefaultBaseURL
+		}
+		return doctorCheck{Status: checkFail, Detail: err.Error(), Fix: "check the network access to " + apiURL + ", and $HTTPS_PROXY if a proxy is required"}
+	}
+	check := doctorCheck{Status: checkOK, Detail: fmt.Sprintf("API reached in %s", report.Latency.Round(time.Millisecond))}
+	if report.Latency > 2*time.Second {
+		check.Status = checkWarn
+		check.Fix = "the API is slow to reach: check the network or the proxy"
+	}
+	return check
+}
+
+func (d *doctor) checkCredentials(ctx context.Context) doctorCheck {
+	err := d.client.Validate(ctx)
+	switch {
+	case err == nil:
+		return doctorCheck{Status: checkOK, Detail: "token active with access to the account and to Workers AI"}
+	case errors.Is(err, workersai.ErrInvalidToken):
+		return doctorCheck{Status: checkFail, Detail: err.Error(), Fix: "the token is revoked, expired or mistyped: create one from the Workers AI template at " + tokenURL}
+	case errors.Is(err, workersai.ErrAccountNotFound):
+		return doctorCheck{Status: checkFail, Detail: err.Error(), Fix: "check the account ID, the one in the URL of the dashboard, dash.cloudflare.com/<account ID>"}
+	case errors.Is(err, workersai.ErrMissingPermission):
+		return doctorCheck{Status: checkFail, Detail: err.Error(), Fix: "check the account ID, then add the Workers AI Read and Workers AI Edit permissions on the account to the token at " + tokenURL}
+	}
+	return doctorCheck{Status: checkFail, Detail: err.Error()}
+}
+
+func (d *doctor) checkGateway(ctx context.Context) doctorCheck {
+	if d.cfg.Gateway == "" {
+		return doctorCheck{Status: checkSkipped, Detail: "no gateway configured"}
+	}
+	settings, err := d.client.GetGateway(ctx, d.cfg.Gateway)
+	if err != nil {
+		check := doctorCheck{Status: checkFail, Detail: err.Error()}
+		var apiErr *workersai.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.StatusCode {
+			case http.StatusNotFound:
+				check.Fix = fmt.Sprintf("create the AI Gateway %q in the account, or remove the gateway setting", d.cfg.Gateway)
+			case http.StatusForbidden, http.StatusUnauthorized:
+				// Running models through the gateway doesn't need the
+				// permission, so the gateway may still work.
+				check.Status = checkWarn
+				check.Fix = "add the AI Gateway Read permission to the token at " + tokenURL + " to check the gateway"
+			}
+		}
+		return check
+	}
+
+	detail := "gateway " + settings.ID + " found"
+	if settings.CacheTTL > 0 {
+		detail += fmt.Sprintf(", caching for %s", time.Duration(settings.CacheTTL)*time.Second)
+	}
+	if settings.Authentication {
+		return doctorCheck{Status: checkFail, Detail: detail + ", authentication enabled", Fix: fmt.Sprintf("disable the authentication of the AI Gateway %q, which the client doesn't support, or remove the gateway setting", d.cfg.Gateway)}
+	}
+	return doctorCheck{Status: checkOK, Detail: detail}
+}
+
+func (d *doctor) checkTask(ctx context.Context) doctorCheck {
+	if d.task == "" {
+		return doctorCheck{Status: checkSkipped, Detail: "no task given"}
+	}
+	page, err := d.client.SearchModels(ctx, workersai.ModelSearch{Task: d.task, PerPage: 1})
+	switch {
+	case err != nil:
+		return doctorCheck{Status: checkFail, Detail: err.Error()}
+	case page.TotalCount == 0 && len(page.Models) == 0:
+		return doctorCheck{Status: checkFail, Detail: fmt.Sprintf("no models for the task %q", d.task), Fix: `check the name of the task, e.g. "Text Generation" or "Text Embeddings"`}
+	}
+	return doctorCheck{Status: checkOK, Detail: fmt.Sprintf("%d models for %s", max(page.TotalCount, len(page.Models)), d.task)}
+}
+
+func (d *doctor) checkModel(ctx context.Context) doctorCheck {
+	if d.model == "" {
+		return doctorCheck{Status: checkSkipped, Detail: "no model given"}
+	}
+	page, err := d.client.SearchModels(ctx, workersai.ModelSearch{Search: d.model})
+	if err != nil {
+		return doctorCheck{Status: checkFail, Detail: err.Error()}
+	}
+	for _, model := range page.Models {
+		if model.Name == d.model {
+			return doctorCheck{Status: checkOK, Detail: fmt.Sprintf("%s is available (%s)", model.Name, model.Task.Name)}
+		}
+	}
+	return doctorCheck{Status: checkFail, Detail: d.model + " is not in the catalog", Fix: "the model is mistyped or was deprecated: see " + modelsURL}
+}
+
+func (d *doctor) checkRun(ctx context.Context) doctorCheck {
+	if !d.run || d.model == "" {
+		return doctorCheck{Status: checkSkipped, Detail: "not running the model"}
+	}
+	resp, err := d.client.ChatCompletionContext(ctx, workersai.ChatCompletionRequest{
+		Model:           d.model,
+		Messages:        []workersai.Message{workersai.ChatMessage{Role: "user", Content: "Say OK."}},
+		ModelParameters: workersai.ModelParameters{MaxTokens: 1},
+	})
+	if err == nil {
+		return doctorCheck{Status: checkOK, Detail: fmt.Sprintf("%s answered in %s", d.model, resp.Latency().Round(time.Millisecond))}
+	}
+
+	check := doctorCheck{Status: checkFail, Detail: err.Error()}
+	var apiErr *workersai.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusForbidden || apiErr.StatusCode == http.StatusUnauthorized:
+			check.Fix = "add the Workers AI Edit permission to the token, which running models requires, at " + tokenURL
+		case d.cfg.Gateway != "" && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusBadRequest):
+			check.Fix = fmt.Sprintf("check that the AI Gateway %q exists in the account and has no authentication enabled, or remove the gateway setting", d.cfg.Gateway)
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			check.Status = checkWarn
+			check.Fix = "the account is rate limited or out of free neurons: retry later or check the plan"
+		}
+	}
+	return check
+}
+
+// printChecks prints the checks for humans.
+func printChecks(w io.Writer, checks []doctorCheck) {
+	for _, check := range checks {
+		fmt.Fprintf(w, "%-7s %-12s %s\n", check.Status, check.Name, check.Detail)
+		if check.Fix != "" {
+			fmt.Fprintf(w, "        %-12s fix: %s\n", "", check.Fix)
+		}
+	}
+}
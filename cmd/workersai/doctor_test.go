@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestDoctor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/user/tokens/verify":
+			fmt.Fprint(w, `{"success": true, "result": {"id": "token-id", "status": "active"}}`)
+		case r.URL.Path == "/accounts/test-account/ai/models/search":
+			if r.URL.Query().Get("task") == "Text Generation" || r.URL.Query().Get("per_page") == "1" {
+				fmt.Fprint(w, `{"success": true, "result": [{"name": "@cf/meta/llama-3-8b-instruct"}], "result_info": {"page": 1, "per_page": 1, "total_count": 42}}`)
+				return
+			}
+			fmt.Fprint(w, `{"success": true, "result": [{"name": "@cf/meta/llama-3-8b-instruct-awq"}, {"name": "@cf/meta/llama-3-8b-instruct", "task": {"name": "Text Generation"}}]}`)
+		case r.URL.Path == "/accounts/test-account/ai-gateway/gateways/my-gateway":
+			fmt.Fprint(w, `{"success": true, "result": {"id": "my-gateway", "cache_ttl": 60, "authentication": true}}`)
+		case strings.HasPrefix(r.URL.Path, "/accounts/test-account/ai/run/"), strings.HasPrefix(r.URL.Path, "/test-account/my-gateway/workers-ai/"):
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv(workersai.EnvConfigFile, "")
+	t.Setenv(workersai.EnvProfile, "")
+	t.Setenv(workersai.EnvGateway, "")
+	t.Setenv(workersai.EnvAccountID, "test-account")
+	t.Setenv(workersai.EnvAPIToken, "test-token")
+	t.Setenv(workersai.EnvBaseURL, server.URL)
+
+	d := &doctor{task: "Text Generation", model: workersai.ModelLlama38B, run: true, timeout: 5 * time.Second}
+	checks := d.runChecks(context.Background())
+
+	require.Len(t, checks, 7)
+	statuses := map[string]string{}
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+	assert.Equal(t, map[string]string{
+		"config":       checkOK,
+		"connectivity": checkOK,
+		"credentials":  checkOK,
+		"gateway":      checkSkipped,
+		"task":         checkOK,
+		"model":        checkOK,
+		"run":          checkFail,
+	}, statuses)
+	assert.Equal(t, "account test-account, token from $CLOUDFLARE_API_TOKEN", checks[0].Detail)
+	assert.Equal(t, "no gateway configured", checks[3].Detail)
+	assert.Equal(t, "42 models for Text Generation", checks[4].Detail)
+	assert.Equal(t, "@cf/meta/llama-3-8b-instruct is available (Text Generation)", checks[5].Detail)
+	assert.Contains(t, checks[6].Fix, "Workers AI Edit permission")
+
+	var out strings.Builder
+	printChecks(&out, checks)
+	assert.Contains(t, out.String(), "fail    run          API returned status 403")
+
+	// Through a gateway, its settings are checked.
+	t.Setenv(workersai.EnvGateway, "my-gateway")
+	d = &doctor{task: "Text Generation", model: workersai.ModelLlama38B, timeout: 5 * time.Second}
+	checks = d.runChecks(context.Background())
+	require.Len(t, checks, 7)
+	assert.Equal(t, "gateway", checks[3].Name)
+	assert.Equal(t, checkFail, checks[3].Status)
+	assert.Equal(t, "gateway my-gateway found, caching for 1m0s, authentication enabled", checks[3].Detail)
+	assert.Contains(t, checks[3].Fix, "disable the authentication")
+}
+
+func TestDoctor_Unreachable(t *testing.T) {
+	t.Setenv(workersai.EnvConfigFile, "")
+	t.Setenv(workersai.EnvProfile, "")
+	t.Setenv(workersai.EnvGateway, "my-gateway")
+	t.Setenv(workersai.EnvAccountID, "test-account")
+	t.Setenv(workersai.EnvAPIToken, "test-token")
+	t.Setenv(workersai.EnvBaseURL, "")
+
+	d := &doctor{timeout: time.Second}
+	require.Equal(t, checkOK, d.checkConfig(context.Background()).Status)
+	// Make the API unreachable without leaving the default gateway URL.
+	d.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})}
+
+	check := d.checkConnectivity(context.Background())
+	assert.Equal(t, checkFail, check.Status)
+	assert.Contains(t, check.Fix, "check the network access to "+workersai.DefaultBaseURL+",")
+}
+
+func TestDoctor_MissingCredentials(t *testing.T) {
+	t.Setenv(workersai.EnvConfigFile, "")
+	t.Setenv(workersai.EnvProfile, "")
+	t.Setenv(workersai.EnvAccountID, "")
+	t.Setenv(workersai.EnvAPIToken, "")
+
+	d := &doctor{model: workersai.ModelLlama38B, timeout: time.Second}
+	checks := d.runChecks(context.Background())
+
+	require.Len(t, checks, 7)
+	assert.Equal(t, checkFail, checks[0].Status)
+	assert.Equal(t, "no account ID", checks[0].Detail)
+	assert.Contains(t, checks[0].Fix, "CLOUDFLARE_ACCOUNT_ID")
+	for _, check := range checks[1:] {
+		assert.Equal(t, checkSkipped, check.Status)
+		assert.Equal(t, "skipped after the failed config check", check.Detail)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling itself.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
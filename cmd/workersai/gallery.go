@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func runGallery(args []string) error {
+	fs := flag.NewFlagSet("gallery", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai gallery [flags] <prompt>\n\n"+
+			"Generates an image per combination of the swept parameters and writes them\n"+
+			"with an index.html contact sheet, a row per seed and steps and a column per\n"+
+			"guidance, for comparing the settings side by side:\n\n"+
+			"  workersai gallery -guidance 3,7.5,12 -steps 10,20 -seed 1,2 'a lighthouse at dusk'\n\n"+
+			"Parameters that aren't swept keep the model's defaults.\n\n")
+		fs.PrintDefaults()
+	}
+	model := fs.String("m", workersai.ModelStableDiffusion, "the text-to-image `model`")
+	negative := fs.String("negative", "", "negative prompt")
+	width := fs.Int("width", 0, "image width")
+	height := fs.Int("height", 0, "image height")
+	steps := fs.String("steps", "", "comma-separated diffusion `steps` to sweep")
+	guidance := fs.String("guidance", "", "comma-separated guidance `values` to sweep")
+	seeds := fs.String("seed", "", "comma-separated `seeds` to sweep")
+	out := fs.String("out", "gallery", "write the images and index.html to this `directory`")
+	parallel := fs.Int("parallel", 2, "number of images generated at a time")
+	asJSON := fs.Bool("json", false, "print the generated images and their settings as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	sheet := &contactSheet{Model: *model, Prompt: fs.Arg(0)}
+	var err error
+	if sheet.Steps, err = parseSweep(*steps, strconv.Atoi); err != nil {
+		return fmt.Errorf("invalid -steps: %w", err)
+	}
+	if sheet.Guidance, err = parseSweep(*guidance, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }); err != nil {
+		return fmt.Errorf("invalid -guidance: %w", err)
+	}
+	if sheet.Seeds, err = parseSweep(*seeds, func(s string) (int64, error) { return strconv.ParseInt(s, 10, 64) }); err != nil {
+		return fmt.Errorf("invalid -seed: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+
+	base := workersai.ImageOptions{NegativePrompt: *negative, Width: *width, Height: *height}
+	generateGallery(client, sheet, base, *out, *parallel)
+
+	index := filepath.Join(*out, "index.html")
+	if err := writeContactSheet(index, sheet); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, cell := range sheet.Cells {
+		if cell.Error != "" {
+			failed++
+		}
+	}
+	if *asJSON {
+		if err := printJSON(os.Stdout, sheet); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "Wrote %d images and %s\n", len(sheet.Cells)-failed, index)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d images failed", failed, len(sheet.Cells))
+	}
+	return nil
+}
+
+// parseSweep parses a comma-separated list of values. An empty list sweeps
+// over the zero value, which keeps the model's default.
+func parseSweep[T any](list string, parse func(string) (T, error)) ([]T, error) {
+	if strings.TrimSpace(list) == "" {
+		var zero T
+		return []T{zero}, nil
+	}
+	var values []T
+	for _, field := range strings.Split(list, ",") {
+		value, err := parse(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// contactSheet is a grid of images of a prompt generated with different
+// settings.
+type contactSheet struct {
+	Model    string    `json:"model"`
+	Prompt   string    `json:"prompt"`
+	Steps    []int     `json:"steps"`
+	Guidance []float64 `json:"guidance"`
+	Seeds    []int64   `json:"seeds"`
+	// Cells are ordered by seed, steps and guidance, so that each row of
+	// the grid is a run of len(Guidance) cells.
+	Cells []galleryCell `json:"images"`
+}
+
+// galleryCell is an image of a contactSheet.
+type galleryCell struct {
+	Steps    int     `json:"steps"`
+	Guidance float64 `json:"guidance"`
+	Seed     int64   `json:"seed"`
+	// File is the name of the image in the output directory.
+	File      string `json:"file,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Rows returns the cells of the sheet a row at a time.
+func (s *contactSheet) Rows() [][]galleryCell {
+	var rows [][]galleryCell
+	for i := 0; i < len(s.Cells); i += len(s.Guidance) {
+		rows = append(rows, s.Cells[i:i+len(s.Guidance)])
+	}
+	return rows
+}
+
+// generateGallery generates the images of sheet into dir, parallel at a
+// time, recording the failures in the cells.
+func generateGallery(client *workersai.Client, sheet *contactSheet, base workersai.ImageOptions, dir string, parallel int) {
+	sheet.Cells = nil
+	for _, seed := range sheet.Seeds {
+		for _, steps := range sheet.Steps {
+			for _, guidance := range sheet.Guidance {
+				sheet.Cells = append(sheet.Cells, galleryCell{Steps: steps, Guidance: guidance, Seed: seed})
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(parallel, 1))
+	for i := range sheet.Cells {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(cell *galleryCell) {
+			defer func() { <-sem; wg.Done() }()
+
+			opts := base
+			opts.Steps, opts.Guidance, opts.Seed = cell.Steps, cell.Guidance, cell.Seed
+			result, err := client.GenerateImage(sheet.Model, sheet.Prompt, &opts)
+			if err != nil {
+				cell.Error = err.Error()
+				return
+			}
+			cell.LatencyMS = result.Latency().Milliseconds()
+			name := fmt.Sprintf("steps%d-guidance%s-seed%d%s", cell.Steps, strconv.FormatFloat(cell.Guidance, 'f', -1, 64), cell.Seed, imageExtension(result.ContentType))
+			if err := os.WriteFile(filepath.Join(dir, name), result.Image, 0o644); err != nil {
+				cell.Error = err.Error()
+				return
+			}
+			cell.File = name
+		}(&sheet.Cells[i])
+	}
+	wg.Wait()
+}
+
+// imageExtension returns the file extension of images of contentType.
+func imageExtension(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/jpeg"):
+		return ".jpg"
+	case strings.HasPrefix(contentType, "image/webp"):
+		return ".webp"
+	}
+	return ".png"
+}
+
+var contactSheetTemplate = template.Must(template.New("sheet").Funcs(template.FuncMap{
+	"setting": func(value interface{}) string {
+		if fmt.Sprint(value) == "0" {
+			return "default"
+		}
+		return fmt.Sprint(value)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Prompt}}</title>
+<style>
+body { font-family: sans-serif; }
+td, th { padding: 4px; text-align: center; vertical-align: top; font-size: small; }
+img { max-width: 256px; display: block; }
+.error { color: #b00; max-width: 256px; }
+</style>
+</head>
+<body>
+<h1>{{.Prompt}}</h1>
+<p>{{.Model}}</p>
+<table>
+<tr><th>seed / steps</th>{{range .Guidance}}<th>guidance {{setting .}}</th>{{end}}</tr>
+{{range .Rows}}<tr><th>{{with index . 0}}seed {{setting .Seed}}<br>steps {{setting .Steps}}{{end}}</th>
+{{range .}}<td>{{if .File}}<a href="{{.File}}"><img src="{{.File}}" alt="{{.File}}"></a>{{.LatencyMS}} ms{{else}}<div class="error">{{.Error}}</div>{{end}}</td>
+{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// writeContactSheet writes sheet as an HTML page to path.
+func writeContactSheet(path string, sheet *contactSheet) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := contactSheetTemplate.Execute(f, sheet); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write contact sheet: %w", err)
+	}
+	return f.Close()
+}
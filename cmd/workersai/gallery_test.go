@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestParseSweep(t *testing.T) {
+	steps, err := parseSweep("10, 20", strconv.Atoi)
+	require.NoError(t, err)
+	assert.Equal(t, []int{10, 20}, steps)
+
+	steps, err = parseSweep("", strconv.Atoi)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, steps)
+
+	_, err = parseSweep("10,x", strconv.Atoi)
+	require.Error(t, err)
+}
+
+func TestGenerateGallery(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nimage")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Guidance float64 `json:"guidance"`
+			Seed     int64   `json:"seed"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.Guidance == 12 && req.Seed == 2 {
+			http.Error(w, `{"success": false, "errors": [{"code": 3030, "message": "NSFW image"}]}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	dir := t.TempDir()
+	sheet := &contactSheet{
+		Model:    workersai.ModelStableDiffusion,
+		Prompt:   "a <b>lighthouse</b>",
+		Steps:    []int{0},
+		Guidance: []float64{7.5, 12},
+		Seeds:    []int64{1, 2},
+	}
+	generateGallery(client, sheet, workersai.ImageOptions{}, dir, 3)
+
+	require.Len(t, sheet.Cells, 4)
+	rows := sheet.Rows()
+	require.Len(t, rows, 2)
+	assert.Equal(t, galleryCell{Guidance: 7.5, Seed: 1, File: "steps0-guidance7.5-seed1.png", LatencyMS: rows[0][0].LatencyMS}, rows[0][0])
+	assert.Equal(t, int64(2), rows[1][1].Seed)
+	assert.Equal(t, 12.0, rows[1][1].Guidance)
+	assert.Empty(t, rows[1][1].File)
+	assert.Contains(t, rows[1][1].Error, "NSFW image")
+
+	image, err := os.ReadFile(filepath.Join(dir, "steps0-guidance12-seed1.png"))
+	require.NoError(t, err)
+	assert.Equal(t, png, image)
+
+	index := filepath.Join(dir, "index.html")
+	require.NoError(t, writeContactSheet(index, sheet))
+	html, err := os.ReadFile(index)
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "<h1>a &lt;b&gt;lighthouse&lt;/b&gt;</h1>")
+	assert.Contains(t, string(html), "<th>guidance 7.5</th><th>guidance 12</th>")
+	assert.Contains(t, string(html), "seed 2<br>steps default")
+	assert.Contains(t, string(html), `<img src="steps0-guidance7.5-seed2.png"`)
+	assert.Contains(t, string(html), "NSFW image")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keyringService names the CLI's entries in the OS credential store.
+const keyringService = "workersai"
+
+// errNotInKeyring is returned when the keyring has no token for an account.
+var errNotInKeyring = errors.New("no API token in the keyring")
+
+// keyring stores API tokens by account ID.
+type keyring interface {
+	Get(account string) (string, error)
+	Set(account, token string) error
+	Delete(account string) error
+}
+
+// systemKeyring is the credential store of the OS. It is a variable so that
+// tests can replace it.
+var systemKeyring keyring = newSystemKeyring()
+
+func newSystemKeyring() keyring {
+	switch runtime.GOOS {
+	case "darwin":
+		return macKeychain{}
+	case "linux", "freebsd", "openbsd":
+		return secretService{}
+	default:
+		return unsupportedKeyring{}
+	}
+}
+
+// macKeychain uses the macOS login keychain through the security tool.
+type macKeychain struct{}
+
+func (macKeychain) Get(account string) (string, error) {
+	out, err := runKeyringTool("", "security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", errNotInKeyring
+	}
+	return out, err
+}
+
+func (macKeychain) Set(account, token string) error {
+	// security only takes the password as an argument, so the command is
+	// given on stdin to its interactive mode, where it doesn't show in the
+	// process list.
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", securityQuote(keyringService), securityQuote(account), securityQuote(token))
+	_, err := runKeyringTool(command, "security", "-i")
+	return err
+}
+
+// securityQuote quotes s as an argument of a command of 'security -i'.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (macKeychain) Delete(account string) error {
+	_, err := runKeyringTool("", "security", "delete-generic-password", "-s", keyringService, "-a", account)
+	return err
+}
+
+// secretService uses the freedesktop Secret Service (GNOME Keyring, KWallet)
+// through the secret-tool command of libsecret.
+type secretService struct{}
+
+func (secretService) Get(account string) (string, error) {
+	out, err := runKeyringTool("", "secret-tool", "lookup", "service", keyringService, "account", account)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", errNotInKeyring
+	}
+	return out, err
+}
+
+func (secretService) Set(account, token string) error {
+	// The token is passed on stdin so that it doesn't show in the process list.
+	_, err := runKeyringTool(token, "secret-tool", "store", "--label=Workers AI API token ("+account+")", "service", keyringService, "account", account)
+	return err
+}
+
+func (secretService) Delete(account string) error {
+	_, err := runKeyringTool("", "secret-tool", "clear", "service", keyringService, "account", account)
+	return err
+}
+
+type unsupportedKeyring struct{}
+
+func (unsupportedKeyring) Get(string) (string, error) {
+	return "", fmt.Errorf("keyring not supported on %s, set CLOUDFLARE_API_TOKEN instead", runtime.GOOS)
+}
+
+func (k unsupportedKeyring) Set(account, token string) error {
+	_, err := k.Get(account)
+	return err
+}
+
+func (k unsupportedKeyring) Delete(account string) error {
+	_, err := k.Get(account)
+	return err
+}
+
+// runKeyringTool runs a credential store command with stdin as input and
+// returns its trimmed output. It is a variable so that tests can replace
+// it.
+var runKeyringTool = func(stdin, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("keyring unavailable: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// memoryKeyring is an in-memory keyring for tests.
+type memoryKeyring map[string]string
+
+func (k memoryKeyring) Get(account string) (string, error) {
+	token, ok := k[account]
+	if !ok {
+		return "", errNotInKeyring
+	}
+	return token, nil
+}
+
+func (k memoryKeyring) Set(account, token string) error {
+	k[account] = token
+	return nil
+}
+
+func (k memoryKeyring) Delete(account string) error {
+	delete(k, account)
+	return nil
+}
+
+func TestNewClient_Keyring(t *testing.T) {
+	saved := systemKeyring
+	defer func() { systemKeyring = saved }()
+	systemKeyring = memoryKeyring{"test-account": "keyring-token"}
+
+	t.Setenv(workersai.EnvConfigFile, "")
+	t.Setenv(workersai.EnvAccountID, "test-account")
+	t.Setenv(workersai.EnvAPIToken, "")
+
+	client, err := newClient()
+	require.NoError(t, err)
+	assert.Equal(t, "keyring-token", client.APIToken)
+
+	t.Setenv(workersai.EnvAPIToken, "env-token")
+	client, err = newClient()
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", client.APIToken, "the environment takes precedence")
+
+	t.Setenv(workersai.EnvAPIToken, "")
+	*noKeyring = true
+	defer func() { *noKeyring = false }()
+	_, err = newClient()
+	assert.ErrorContains(t, err, "missing API token")
+}
+
+func TestMacKeychain_SetKeepsTokenOffArgs(t *testing.T) {
+	saved := runKeyringTool
+	defer func() { runKeyringTool = saved }()
+	var stdin string
+	var args []string
+	runKeyringTool = func(in, name string, a ...string) (string, error) {
+		stdin, args = in, append([]string{name}, a...)
+		return "", nil
+	}
+
+	require.NoError(t, macKeychain{}.Set("test-account", `s3cr"et`))
+	assert.Equal(t, []string{"security", "-i"}, args)
+	for _, arg := range args {
+		assert.NotContains(t, arg, "s3cr")
+	}
+	assert.Equal(t, `add-generic-password -U -s "workersai" -a "test-account" -w "s3cr\"et"`+"\n", stdin)
+}
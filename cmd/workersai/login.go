@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func runLogin(args []string) error {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai login [flags]\n\n"+
+			"Stores an API token in the OS keyring, from where it is read when\n"+
+			"CLOUDFLARE_API_TOKEN is not set. The token is read from stdin.\n\n")
+		fs.PrintDefaults()
+	}
+	account := fs.String("account", "", "account ID the token belongs to (default $CLOUDFLARE_ACCOUNT_ID or the config file and profile)")
+	asJSON := fs.Bool("json", false, "print the outcome as JSON")
+	fs.Parse(args)
+
+	accountID, err := keyringAccount(*account)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "API token for account %s: ", accountID)
+	token, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return fmt.Errorf("no token given: %v", err)
+	}
+
+	err = systemKeyring.Set(accountID, token)
+	if *asJSON {
+		out := keyringOutput{Account: accountID}
+		if err == nil {
+			out.Action = "stored"
+		}
+		return writeOutput(os.Stdout, &out, err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Token stored in the keyring.\n")
+	return nil
+}
+
+func runLogout(args []string) error {
+	fs := flag.NewFlagSet("logout", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai logout [flags]\n\nRemoves the API token of an account from the OS keyring.\n\n")
+		fs.PrintDefaults()
+	}
+	account := fs.String("account", "", "account ID whose token is removed (default $CLOUDFLARE_ACCOUNT_ID or the config file and profile)")
+	asJSON := fs.Bool("json", false, "print the outcome as JSON")
+	fs.Parse(args)
+
+	accountID, err := keyringAccount(*account)
+	if err != nil {
+		return err
+	}
+	err = systemKeyring.Delete(accountID)
+	if *asJSON {
+		out := keyringOutput{Account: accountID}
+		if err == nil {
+			out.Action = "removed"
+		}
+		return writeOutput(os.Stdout, &out, err)
+	}
+	return err
+}
+
+// keyringAccount returns the account ID for login and logout: account if
+// set, the configured one otherwise.
+func keyringAccount(account string) (string, error) {
+	if account != "" {
+		return account, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if cfg.AccountID == "" {
+		return "", fmt.Errorf("no account ID, use -account or set CLOUDFLARE_ACCOUNT_ID")
+	}
+	return cfg.AccountID, nil
+}
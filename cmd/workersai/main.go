@@ -0,0 +1,128 @@
+// Command workersai is a command line client for Cloudflare Workers AI.
+//
+// The client is configured from the file given with -config or
+// $WORKERS_AI_CONFIG, if any, and from the CLOUDFLARE_ACCOUNT_ID and
+// CLOUDFLARE_API_TOKEN environment variables, which take precedence. The
+// file may hold named profiles for several accounts, one of which is
+// selected with -profile or $WORKERS_AI_PROFILE:
+//
+//	account_id: 0123456789abcdef
+//	profiles:
+//	  staging:
+//	    account_id: fedcba9876543210
+//	    gateway: staging-gateway
+//
+// Profiles are applied over the top-level settings of the file and over the
+// environment. When no API token is configured, it is read from the OS
+// keyring, where 'workersai login' stores it; -no-keyring disables the
+// lookup.
+//
+// Every command accepts -json to print its outcome as a line of JSON for
+// scripts: the content, tool calls, usage, latency and model of a
+// completion, along with the error if the command failed.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// command is a workersai subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var (
+	configPath = flag.String("config", "", "read the client configuration from this YAML or TOML `file`")
+	noKeyring  = flag.Bool("no-keyring", false, "don't read the API token from the OS keyring")
+	profile    = flag.String("profile", "", "use the `name`d profile of the configuration file (default $WORKERS_AI_PROFILE)")
+)
+
+var commands = []command{
+	{"login", "store an API token in the OS keyring", runLogin},
+	{"logout", "remove an API token from the OS keyring", runLogout},
+	{"replay", "re-send a captured chat request", runReplay},
+	{"run", "stream the completion of a prompt read from stdin", runRun},
+	{"gallery", "generate images sweeping over generation settings", runGallery},
+	{"subtitles", "transcribe audio into SRT or WebVTT subtitles", runSubtitles},
+	{"batch", "run the chat requests of a JSON Lines file", runBatch},
+	{"demo", "run end-to-end demos of the library, as smoke tests", runDemo},
+	{"doctor", "diagnose the configuration, credentials and connectivity", runDoctor},
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name, args := flag.Arg(0), flag.Args()[1:]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(args); err != nil {
+				fmt.Fprintf(os.Stderr, "workersai %s: %v\n", name, err)
+				code := 1
+				var exitErr *exitCodeError
+				if errors.As(err, &exitErr) {
+					code = exitErr.code
+				}
+				os.Exit(code)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "workersai: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// exitCodeError is an error making workersai exit with code instead of 1.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: workersai [-config file] [-profile name] [-no-keyring] <command> [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'workersai <command> -h' for the flags of a command.\n")
+}
+
+// newClient builds a client from the configuration file and the
+// environment, falling back to the keyring for the API token.
+func newClient() (*workersai.Client, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.APIToken == "" && cfg.AccountID != "" && !*noKeyring {
+		token, err := systemKeyring.Get(cfg.AccountID)
+		if err != nil && !errors.Is(err, errNotInKeyring) {
+			return nil, err
+		}
+		cfg.APIToken = token
+	}
+
+	return cfg.NewClient()
+}
+
+// loadConfig reads the configuration selected by -config and -profile.
+func loadConfig() (*workersai.Config, error) {
+	return workersai.LoadConfigProfile(*configPath, *profile)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// completionOutput is what the -json flag of the subcommands running a
+// model prints, on one line, instead of the completion.
+type completionOutput struct {
+	Model        string               `json:"model"`
+	Content      string               `json:"content"`
+	ToolCalls    []workersai.ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string               `json:"finish_reason,omitempty"`
+	Usage        workersai.Usage      `json:"usage"`
+	// UsageEstimated is set when a stream ended before the model
+	// reported its usage.
+	UsageEstimated bool `json:"usage_estimated,omitempty"`
+	// LatencyMS is the duration of the request, retries included.
+	LatencyMS int64 `json:"latency_ms"`
+	// TimeToFirstTokenMS is set for streamed completions.
+	TimeToFirstTokenMS int64  `json:"time_to_first_token_ms,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+// responseOutput describes a chat response.
+func responseOutput(model string, resp *workersai.ChatResponse) completionOutput {
+	out := completionOutput{Model: model}
+	if resp != nil {
+		out.Content = resp.GetContent()
+		out.ToolCalls = resp.GetToolCalls()
+		out.FinishReason = resp.GetFinishReason()
+		out.Usage = resp.GetUsage()
+		out.LatencyMS = resp.Latency().Milliseconds()
+	}
+	return out
+}
+
+// summaryOutput describes a streamed chat response.
+func summaryOutput(model string, summary *workersai.StreamSummary) completionOutput {
+	out := completionOutput{Model: model}
+	if summary != nil {
+		out.Content = summary.Content
+		out.FinishReason = summary.FinishReason
+		out.Usage = summary.Usage
+		out.UsageEstimated = summary.UsageEstimated
+		out.LatencyMS = summary.Stats.Duration.Milliseconds()
+		out.TimeToFirstTokenMS = summary.Stats.TimeToFirstChunk.Milliseconds()
+	}
+	return out
+}
+
+// keyringOutput is what the -json flag of login and logout prints.
+type keyringOutput struct {
+	Account string `json:"account"`
+	// Action is "stored" or "removed", empty if the command failed.
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// writeOutput prints out as a line of JSON, with the error of the command
+// if any, and returns that error so that the exit code reflects it.
+func writeOutput(w io.Writer, out interface{}, err error) error {
+	if err != nil {
+		switch out := out.(type) {
+		case *completionOutput:
+			out.Error = err.Error()
+		case *keyringOutput:
+			out.Error = err.Error()
+		}
+	}
+	if werr := json.NewEncoder(w).Encode(out); werr != nil && err == nil {
+		return werr
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestResponseOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hello", "tool_calls": [{"name": "get_weather", "arguments": {"city": "Paris"}}], "usage": {"prompt_tokens": 4, "completion_tokens": 1, "total_tokens": 5}}}`)
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	resp, err := client.ChatCompletion(workersai.ChatCompletionRequest{
+		Model:    workersai.ModelLlama38B,
+		Messages: []workersai.Message{workersai.ChatMessage{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	out := responseOutput(workersai.ModelLlama38B, resp)
+	require.NoError(t, writeOutput(&buf, &out, nil))
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, workersai.ModelLlama38B, decoded["model"])
+	assert.Equal(t, "Hello", decoded["content"])
+	assert.Len(t, decoded["tool_calls"], 1)
+	assert.Equal(t, map[string]interface{}{"prompt_tokens": 4.0, "completion_tokens": 1.0, "total_tokens": 5.0}, decoded["usage"])
+	assert.Contains(t, decoded, "latency_ms")
+	assert.NotContains(t, decoded, "error")
+}
+
+func TestWriteOutput_Error(t *testing.T) {
+	var buf bytes.Buffer
+	out := responseOutput(workersai.ModelLlama38B, nil)
+	err := writeOutput(&buf, &out, errors.New("boom"))
+	require.EqualError(t, err, "boom")
+	assert.JSONEq(t, `{"model": "@cf/meta/llama-3-8b-instruct", "content": "", "usage": {"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0}, "latency_ms": 0, "error": "boom"}`, buf.String())
+
+	buf.Reset()
+	err = writeOutput(&buf, &keyringOutput{Account: "acc"}, errors.New("locked"))
+	require.EqualError(t, err, "locked")
+	assert.JSONEq(t, `{"account": "acc", "error": "locked"}`, buf.String())
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// debugBodyPrefix precedes the request body in the client's debug logs.
+const debugBodyPrefix = "Request Body: "
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai replay [flags] <file|->\n\n"+
+			"Re-sends a chat request captured as JSON or copied from a WORKERS_AI_DEBUG\n"+
+			"\"Request Body:\" log line. Flags override the captured values. Requests\n"+
+			"captured with \"stream\": true are streamed.\n\n")
+		fs.PrintDefaults()
+	}
+	model := fs.String("model", "", "send to this model instead of the captured one")
+	temperature := fs.Float64("temperature", 0, "override the temperature")
+	maxTokens := fs.Int64("max-tokens", 0, "override max_tokens")
+	topP := fs.Float64("top-p", 0, "override top_p")
+	topK := fs.Int("top-k", 0, "override top_k")
+	raw := fs.Bool("raw", false, "print the raw result JSON instead of the content")
+	asJSON := fs.Bool("json", false, "print the content, tool calls, usage and latency as JSON")
+	dryRun := fs.Bool("dry-run", false, "print the request that would be sent and exit")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *raw && *asJSON {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	input, err := readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	request, err := parseCapturedRequest(input)
+	if err != nil {
+		return err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "model":
+			request.Model = *model
+		case "temperature":
+			request.Temperature = *temperature
+			request.Zero |= workersai.ParamTemperature
+		case "max-tokens":
+			request.MaxTokens = *maxTokens
+		case "top-p":
+			request.TopP = *topP
+			request.Zero |= workersai.ParamTopP
+		case "top-k":
+			request.TopK = *topK
+			request.Zero |= workersai.ParamTopK
+		}
+	})
+
+	if *dryRun {
+		return printJSON(os.Stdout, request)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if request.Stream {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		var w io.Writer = os.Stdout
+		if *asJSON {
+			w = io.Discard
+		}
+		summary, err := replayStream(ctx, client, request, *raw, w)
+		if *asJSON {
+			out := summaryOutput(request.Model, summary)
+			return writeOutput(os.Stdout, &out, err)
+		}
+		return err
+	}
+
+	resp, err := client.ChatCompletion(*request)
+	if *asJSON {
+		out := responseOutput(request.Model, resp)
+		return writeOutput(os.Stdout, &out, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *raw {
+		_, err = fmt.Fprintf(os.Stdout, "%s\n", resp.ResultRaw)
+		return err
+	}
+
+	for _, call := range resp.GetToolCalls() {
+		fmt.Printf("tool call %s(%s)\n", call.Function.Name, call.Function.Arguments)
+	}
+	fmt.Println(resp.GetContent())
+	return nil
+}
+
+// readInput reads the named file, or stdin for "-".
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// replayStream streams the completion of a request captured with
+// "stream": true to w, as the content of the chunks, or with raw as their
+// data, one per line, which includes the tool calls.
+func replayStream(ctx context.Context, client *workersai.Client, request *workersai.ChatCompletionRequest, raw bool, w io.Writer) (*workersai.StreamSummary, error) {
+	if raw {
+		return client.ChatStreamFunc(ctx, request.Model, request.Messages, request.Tools, &request.ModelParameters, func(chunk *workersai.StreamChunk) error {
+			_, err := fmt.Fprintf(w, "%s\n", chunk.Raw)
+			return err
+		})
+	}
+	return streamCompletion(ctx, client, request.Model, request.Messages, request.Tools, &request.ModelParameters, w)
+}
+
+// parseCapturedRequest decodes a request from plain JSON or from a debug log
+// line, in which case everything up to the request body is skipped. Only
+// the first JSON value is read, which may span lines and be followed by
+// other log lines. The sampling parameters captured as 0 are kept as such.
+func parseCapturedRequest(input []byte) (*workersai.ChatCompletionRequest, error) {
+	if i := bytes.Index(input, []byte(debugBodyPrefix)); i >= 0 {
+		input = input[i+len(debugBodyPrefix):]
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(input)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse captured request: %w", err)
+	}
+	var request workersai.ChatCompletionRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, fmt.Errorf("failed to parse captured request: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil {
+		for name, param := range map[string]workersai.Params{
+			"temperature": workersai.ParamTemperature,
+			"top_p":       workersai.ParamTopP,
+			"top_k":       workersai.ParamTopK,
+		} {
+			if value, ok := fields[name]; ok && isZero(value) {
+				request.Zero |= param
+			}
+		}
+	}
+	return &request, nil
+}
+
+func isZero(value json.RawMessage) bool {
+	var f float64
+	return json.Unmarshal(value, &f) == nil && f == 0
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestParseCapturedRequest(t *testing.T) {
+	body := `{"model":"@cf/meta/llama-3-8b-instruct","messages":[{"role":"user","content":"Hi"}],"temperature":0.5}`
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"plain JSON", body},
+		{"debug log line", "2025/01/02 03:04:05 [WORKERS_AI_DEBUG] Request Body: " + body + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := parseCapturedRequest([]byte(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, workersai.ModelLlama38B, request.Model)
+			assert.Equal(t, 0.5, request.Temperature)
+			require.Len(t, request.Messages, 1)
+			assert.Equal(t, workersai.ChatMessage{Role: "user", Content: "Hi"}, request.Messages[0])
+		})
+	}
+
+	_, err := parseCapturedRequest([]byte("Request URL: https://example.com"))
+	require.Error(t, err)
+}
+
+func TestParseCapturedRequest_MultiLine(t *testing.T) {
+	input := "2025/01/02 03:04:05 [WORKERS_AI_DEBUG] Request Body: {\n" +
+		"  \"model\": \"@cf/meta/llama-3-8b-instruct\",\n" +
+		"  \"messages\": [{\"role\": \"user\", \"content\": \"Hi\"}],\n" +
+		"  \"stream\": true,\n" +
+		"  \"temperature\": 0\n" +
+		"}\n" +
+		"2025/01/02 03:04:06 [WORKERS_AI_DEBUG] Response Body: {\"success\": true}\n"
+
+	request, err := parseCapturedRequest([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, workersai.ModelLlama38B, request.Model)
+	assert.True(t, request.Stream)
+	assert.Equal(t, workersai.ParamTemperature, request.Zero)
+	require.Len(t, request.Messages, 1)
+}
+
+func TestReplayStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, true, req["stream"])
+		assert.Equal(t, 0.0, req["temperature"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{`{"response": "Hello"}`, `{"response": " world"}`, `[DONE]`} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	request, err := parseCapturedRequest([]byte(`{"model":"@cf/meta/llama-3-8b-instruct","messages":[{"role":"user","content":"Hi"}],"stream":true,"temperature":0}`))
+	require.NoError(t, err)
+
+	var out strings.Builder
+	summary, err := replayStream(context.Background(), client, request, false, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world\n", out.String())
+	assert.Equal(t, "Hello world", summary.Content)
+
+	out.Reset()
+	_, err = replayStream(context.Background(), client, request, true, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"response\": \"Hello\"}\n{\"response\": \" world\"}\n", out.String())
+}
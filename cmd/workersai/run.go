@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// Exit codes of 'workersai run', besides 1 for a failed request.
+const (
+	exitUsage       = 2
+	exitTruncated   = 3
+	exitInterrupted = 130
+)
+
+func runRun(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai run -m model [flags] < prompt\n\n"+
+			"Sends the prompt read from stdin to a model and streams the completion to\n"+
+			"stdout, with nothing else, for use in pipelines:\n\n"+
+			"  git diff | workersai run -m @cf/meta/llama-3-8b-instruct -system 'Review this diff.'\n\n"+
+			"Exits with 1 if the request failed, 2 if the prompt is empty, 3 with -strict\n"+
+			"if the completion was cut at the token limit and 130 when interrupted.\n\n")
+		fs.PrintDefaults()
+	}
+	model := fs.String("m", "", "the `model` to run (required)")
+	system := fs.String("system", "", "system prompt sent before the prompt")
+	maxTokens := fs.Int64("max-tokens", 0, "maximum number of tokens to generate")
+	temperature := fs.Float64("temperature", 0, "sampling temperature")
+	strict := fs.Bool("strict", false, "fail if the completion was cut at the token limit")
+	asJSON := fs.Bool("json", false, "print the content, usage and latency as JSON once complete instead of streaming")
+	fs.Parse(args)
+
+	if *model == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	prompt, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read prompt: %w", err)
+	}
+	if strings.TrimSpace(string(prompt)) == "" {
+		return &exitCodeError{code: exitUsage, err: errors.New("empty prompt on stdin")}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var messages []workersai.Message
+	if *system != "" {
+		messages = append(messages, workersai.ChatMessage{Role: "system", Content: *system})
+	}
+	messages = append(messages, workersai.ChatMessage{Role: "user", Content: string(prompt)})
+	params := &workersai.ModelParameters{MaxTokens: *maxTokens, Temperature: *temperature}
+	fs.Visit(func(f *flag.Flag) {
+		// -temperature 0 asks for greedy decoding rather than the default.
+		if f.Name == "temperature" {
+			params.Zero |= workersai.ParamTemperature
+		}
+	})
+
+	var w io.Writer = os.Stdout
+	if *asJSON {
+		w = io.Discard
+	}
+	summary, err := streamCompletion(ctx, client, *model, messages, nil, params, w)
+	switch {
+	case ctx.Err() != nil:
+		err = &exitCodeError{code: exitInterrupted, err: errors.New("interrupted")}
+	case err == nil && *strict && summary.FinishReason == "length":
+		err = &exitCodeError{code: exitTruncated, err: errors.New("completion cut at the token limit")}
+	}
+	if *asJSON {
+		out := summaryOutput(*model, summary)
+		return writeOutput(os.Stdout, &out, err)
+	}
+	return err
+}
+
+// streamCompletion streams the completion of messages to w as it is
+// generated, ending it with a newline if it doesn't have one. The
+// summary is returned as by Client.ChatStreamFunc.
+func streamCompletion(ctx context.Context, client *workersai.Client, model string, messages []workersai.Message, tools []workersai.Tool, params *workersai.ModelParameters, w io.Writer) (*workersai.StreamSummary, error) {
+	summary, err := client.ChatStreamFunc(ctx, model, messages, tools, params, func(chunk *workersai.StreamChunk) error {
+		_, err := io.WriteString(w, chunk.Content)
+		return err
+	})
+	if summary != nil && summary.Content != "" && !strings.HasSuffix(summary.Content, "\n") {
+		if _, werr := io.WriteString(w, "\n"); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return summary, err
+}
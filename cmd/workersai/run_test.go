@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestStreamCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req workersai.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+		require.Len(t, req.Messages, 1)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{`{"response": "Hello"}`, `{"response": " world"}`, `[DONE]`} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var out strings.Builder
+	messages := []workersai.Message{workersai.ChatMessage{Role: "user", Content: "Hi"}}
+	summary, err := streamCompletion(context.Background(), client, workersai.ModelLlama38B, messages, nil, nil, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world\n", out.String())
+	assert.Equal(t, "Hello world", summary.Content)
+}
+
+func TestStreamCompletion_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"success": false, "errors": [{"code": 5006, "message": "bad input"}]}`)
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var out strings.Builder
+	messages := []workersai.Message{workersai.ChatMessage{Role: "user", Content: "Hi"}}
+	_, err := streamCompletion(context.Background(), client, workersai.ModelLlama38B, messages, nil, nil, &out)
+	require.Error(t, err)
+	assert.Empty(t, out.String())
+}
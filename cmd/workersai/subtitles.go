@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func runSubtitles(args []string) error {
+	fs := flag.NewFlagSet("subtitles", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: workersai subtitles [flags] <audio>\n\n"+
+			"Transcribes an audio file with Whisper and writes SubRip or WebVTT subtitles,\n"+
+			"next to the audio file unless -o is given. Consecutive segments are merged\n"+
+			"into cues of readable length and their text wrapped into short lines.\n\n")
+		fs.PrintDefaults()
+	}
+	model := fs.String("m", workersai.ModelWhisperLargeV3Turbo, "the speech recognition `model`")
+	format := fs.String("format", "", "subtitle `format`, srt or vtt (default from the -o extension, or srt)")
+	output := fs.String("o", "", "write the subtitles to this `file`, - for stdout (default the audio file with the format's extension)")
+	maxLine := fs.Int("max-line-length", 42, "wrap the text into lines of at most this many characters, 0 to disable")
+	mergeGap := fs.Duration("merge-gap", 500*time.Millisecond, "merge segments less than this apart, 0 to disable")
+	maxDuration := fs.Duration("max-duration", 5*time.Second, "don't merge segments into cues longer than this, 0 for no limit")
+	asJSON := fs.Bool("json", false, "print the file written, the cues and the usage as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	audioPath := fs.Arg(0)
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+		if *format != "vtt" {
+			*format = "srt"
+		}
+	}
+	if *format != "srt" && *format != "vtt" {
+		return fmt.Errorf("unknown format %q, expected srt or vtt", *format)
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + "." + *format
+	}
+
+	audio, err := os.ReadFile(audioPath)
+	if err != nil {
+		return err
+	}
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	result, err := client.Transcribe(*model, audio)
+	if err != nil {
+		return err
+	}
+
+	transcript := subtitleTranscript(result.AudioTranscript(), *mergeGap, *maxDuration, *maxLine)
+	subtitles := transcript.SRT()
+	if *format == "vtt" {
+		subtitles = transcript.VTT()
+	}
+
+	if *output == "-" {
+		_, err = os.Stdout.WriteString(subtitles)
+	} else {
+		err = os.WriteFile(*output, []byte(subtitles), 0o644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(os.Stdout, struct {
+			File      string          `json:"file"`
+			Format    string          `json:"format"`
+			Model     string          `json:"model"`
+			Cues      int             `json:"cues"`
+			Usage     workersai.Usage `json:"usage"`
+			LatencyMS int64           `json:"latency_ms"`
+		}{*output, *format, *model, len(transcript.Segments), result.Usage(), result.Latency().Milliseconds()})
+	}
+	if *output != "-" {
+		fmt.Fprintf(os.Stderr, "Wrote %d cues to %s\n", len(transcript.Segments), *output)
+	}
+	return nil
+}
+
+// subtitleTranscript prepares transcript for subtitles: merged into cues
+// with AudioTranscript.Merge and wrapped into lines of at most maxLine
+// characters. A transcript made of a single segment, as returned by the
+// models that only time words, is first split into words so that the
+// cues follow the speech.
+func subtitleTranscript(transcript workersai.AudioTranscript, mergeGap, maxDuration time.Duration, maxLine int) workersai.AudioTranscript {
+	if len(transcript.Segments) == 1 && len(transcript.Segments[0].Words) > 0 {
+		segment := transcript.Segments[0]
+		transcript.Segments = nil
+		for _, word := range segment.Words {
+			transcript.Segments = append(transcript.Segments, workersai.AudioSegment{
+				Start:   time.Duration(math.Round(word.Start * float64(time.Second))),
+				End:     time.Duration(math.Round(word.End * float64(time.Second))),
+				Text:    strings.TrimSpace(word.Word),
+				Speaker: segment.Speaker,
+				Words:   []workersai.TranscriptionWord{word},
+			})
+		}
+	}
+	if mergeGap > 0 {
+		transcript = transcript.Merge(mergeGap, maxDuration)
+	}
+	return transcript.WrapLines(maxLine)
+}
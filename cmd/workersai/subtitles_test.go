@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestSubtitleTranscript(t *testing.T) {
+	result := workersai.TranscriptionResult{
+		Text: "Hello there. General Kenobi, you are a bold one.",
+		Words: []workersai.TranscriptionWord{
+			{Word: "Hello", Start: 0, End: 0.4},
+			{Word: "there.", Start: 0.5, End: 0.9},
+			{Word: "General", Start: 2, End: 2.4},
+			{Word: "Kenobi,", Start: 2.5, End: 3},
+			{Word: "you", Start: 3.1, End: 3.2},
+			{Word: "are", Start: 3.3, End: 3.4},
+			{Word: "a", Start: 3.5, End: 3.6},
+			{Word: "bold", Start: 3.7, End: 3.9},
+			{Word: "one.", Start: 4, End: 4.2},
+		},
+	}
+
+	transcript := subtitleTranscript(result.AudioTranscript(), 500*time.Millisecond, 5*time.Second, 16)
+	require.Len(t, transcript.Segments, 2)
+	assert.Equal(t, "Hello there.", transcript.Segments[0].Text)
+	assert.Equal(t, 900*time.Millisecond, transcript.Segments[0].End)
+	assert.Equal(t, "General Kenobi,\nyou are a bold\none.", transcript.Segments[1].Text)
+	assert.Equal(t, 2*time.Second, transcript.Segments[1].Start)
+
+	transcript = subtitleTranscript(result.AudioTranscript(), 500*time.Millisecond, 2*time.Second, 0)
+	require.Len(t, transcript.Segments, 3)
+	assert.Equal(t, "General Kenobi, you are a bold", transcript.Segments[1].Text)
+
+	// Segments reported by the model are kept unless merged.
+	segmented := workersai.AudioTranscript{Segments: []workersai.AudioSegment{
+		{End: time.Second, Text: "Hello there."},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "General Kenobi."},
+	}}
+	assert.Equal(t, segmented, subtitleTranscript(segmented, 0, 0, 0))
+}
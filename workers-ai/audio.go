@@ -0,0 +1,213 @@
+package workersai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TranscriptionWord is a single word of a transcription with its timing in seconds.
+type TranscriptionWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// TranscriptionResult is the output of the speech recognition models.
+// Requested with TranscriptionText, only Text and WordCount are set.
+type TranscriptionResult struct {
+	Text      string              `json:"text"`
+	WordCount int                 `json:"word_count,omitempty"`
+	Words     []TranscriptionWord `json:"words,omitempty"`
+	VTT       string              `json:"vtt,omitempty"`
+	// Segments are reported by some models only; see AudioTranscript for
+	// a structured form of the result of every model.
+	Segments []TranscriptionSegment `json:"segments,omitempty"`
+	// Language is the language of the audio, e.g. "en", and Duration its
+	// length in seconds, for the models reporting them. They are copied
+	// from Info when the model reports them there.
+	Language string  `json:"language,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	// Info is reported by ModelWhisperLargeV3Turbo.
+	Info *TranscriptionInfo `json:"transcription_info,omitempty"`
+
+	resultMeta
+}
+
+// TranscriptionInfo describes the audio of a transcription.
+type TranscriptionInfo struct {
+	Language string `json:"language"`
+	// LanguageProbability is the confidence in Language, from 0 to 1.
+	LanguageProbability float64 `json:"language_probability"`
+	// Duration is the length of the audio in seconds, and DurationAfterVAD
+	// the length of the speech kept by the voice activity detection.
+	Duration         float64 `json:"duration"`
+	DurationAfterVAD float64 `json:"duration_after_vad"`
+}
+
+// Response formats of TranscriptionOptions.
+const (
+	TranscriptionText        = "text"
+	TranscriptionVerboseJSON = "verbose_json"
+)
+
+// TranscriptionOptions are the optional settings of TranscribeWithOptions.
+type TranscriptionOptions struct {
+	// ResponseFormat is TranscriptionVerboseJSON, the default, for the
+	// segments, words and language detected along with the text, or
+	// TranscriptionText for the text only. The models have no such
+	// setting and always answer in full: the choice is applied by the
+	// client, which drops the rest of the result, so it saves memory but
+	// not bandwidth or neurons.
+	ResponseFormat string
+
+	// The settings below are only supported by ModelWhisperLargeV3Turbo,
+	// setting them with other models is an error.
+
+	// Language is the language of the audio, e.g. "en". It is detected
+	// when empty.
+	Language string
+	// Task is "transcribe", the default, or "translate" to translate the
+	// speech to English.
+	Task string
+	// InitialPrompt guides the style and vocabulary of the transcription,
+	// e.g. with names spelled as they should be.
+	InitialPrompt string
+	// VADFilter drops the parts of the audio without speech first.
+	VADFilter bool
+}
+
+// turboOnly reports whether o sets settings only supported by
+// ModelWhisperLargeV3Turbo.
+func (o *TranscriptionOptions) turboOnly() bool {
+	return o.Language != "" || o.Task != "" || o.InitialPrompt != "" || o.VADFilter
+}
+
+// transcriptionRequest is the JSON input of ModelWhisperLargeV3Turbo.
+type transcriptionRequest struct {
+	Audio         string `json:"audio"`
+	Language      string `json:"language,omitempty"`
+	Task          string `json:"task,omitempty"`
+	InitialPrompt string `json:"initial_prompt,omitempty"`
+	VADFilter     bool   `json:"vad_filter,omitempty"`
+}
+
+// SpeechOptions are the optional settings of TextToSpeech.
+type SpeechOptions struct {
+	// Lang is the language of the text, e.g. "en". Only used by models that
+	// support several languages.
+	Lang string
+}
+
+// speechRequest is the input of the text-to-speech models. MeloTTS reads the
+// text from "prompt" while the other models read it from "text".
+type speechRequest struct {
+	Prompt string `json:"prompt,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Lang   string `json:"lang,omitempty"`
+}
+
+// Transcribe converts speech in audio to text using a speech recognition
+// model such as ModelWhisper. audio holds the encoded file (mp3, wav, ...).
+func (c *Client) Transcribe(modelID string, audio []byte) (*TranscriptionResult, error) {
+	return c.transcribe(context.Background(), modelID, audio, nil)
+}
+
+// TranscribeWithOptions is Transcribe with the settings of opts, which may
+// be nil. Cancelling ctx aborts the request.
+func (c *Client) TranscribeWithOptions(ctx context.Context, modelID string, audio []byte, opts *TranscriptionOptions) (*TranscriptionResult, error) {
+	return c.transcribe(ctx, modelID, audio, opts)
+}
+
+func (c *Client) transcribe(ctx context.Context, modelID string, audio []byte, opts *TranscriptionOptions) (*TranscriptionResult, error) {
+	if opts == nil {
+		opts = &TranscriptionOptions{}
+	}
+	if opts.ResponseFormat != "" && opts.ResponseFormat != TranscriptionText && opts.ResponseFormat != TranscriptionVerboseJSON {
+		return nil, fmt.Errorf("unknown response format %q", opts.ResponseFormat)
+	}
+
+	contentType, body := "application/octet-stream", audio
+	if strings.Contains(modelID, "whisper-large-v3-turbo") {
+		// The turbo model only accepts base64 encoded audio inside a JSON body.
+		var err error
+		contentType = "application/json"
+		body, err = json.Marshal(transcriptionRequest{
+			Audio:         base64.StdEncoding.EncodeToString(audio),
+			Language:      opts.Language,
+			Task:          opts.Task,
+			InitialPrompt: opts.InitialPrompt,
+			VADFilter:     opts.VADFilter,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+	} else if opts.turboOnly() {
+		return nil, fmt.Errorf("%s doesn't support language, task, initial prompt or VAD settings", modelID)
+	}
+
+	start := time.Now()
+	body, _, err := c.send(ctx, modelID, contentType, body, sendOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result TranscriptionResult
+	if err := c.decodeResult(body, &result); err != nil {
+		return nil, err
+	}
+	if result.Info != nil {
+		if result.Language == "" {
+			result.Language = result.Info.Language
+		}
+		if result.Duration == 0 {
+			result.Duration = result.Info.Duration
+		}
+	}
+	if opts.ResponseFormat == TranscriptionText {
+		result = TranscriptionResult{Text: result.Text, WordCount: result.WordCount}
+	}
+	result.setResultMeta(newResultMeta(modelID, time.Since(start), body))
+	return &result, nil
+}
+
+// TextToSpeech synthesizes text with a text-to-speech model such as
+// ModelMeloTTS and returns the encoded audio (usually mp3).
+func (c *Client) TextToSpeech(modelID, text string, opts *SpeechOptions) ([]byte, error) {
+	request := speechRequest{}
+	if strings.Contains(modelID, "melotts") {
+		request.Prompt = text
+	} else {
+		request.Text = text
+	}
+	if opts != nil {
+		request.Lang = opts.Lang
+	}
+
+	body, contentType, err := c.runJSON(context.Background(), modelID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some models stream the audio back as-is, others wrap it in the usual
+	// envelope as a base64 string.
+	if strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "application/octet-stream") {
+		return body, nil
+	}
+
+	var result struct {
+		Audio string `json:"audio"`
+	}
+	if err := c.decodeResult(body, &result); err != nil {
+		return nil, err
+	}
+
+	audio, err := base64.StdEncoding.DecodeString(result.Audio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio: %w", err)
+	}
+	return audio, nil
+}
@@ -0,0 +1,65 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_TranscribeWithOptions(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/accounts/test-account/ai/run/"+ModelWhisperLargeV3Turbo, r.URL.Path)
+		request = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprint(w, `{"success": true, "result": {
+			"text": "Bonjour tout le monde.",
+			"word_count": 4,
+			"transcription_info": {"language": "fr", "language_probability": 0.98, "duration": 2.5, "duration_after_vad": 2.1},
+			"segments": [{"start": 0, "end": 2.1, "text": " Bonjour tout le monde.", "avg_logprob": -0.2,
+				"words": [{"word": "Bonjour", "start": 0, "end": 0.6}]}],
+			"vtt": "WEBVTT\n\n00:00.000 --> 00:02.100\nBonjour tout le monde.\n\n"
+		}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	result, err := client.TranscribeWithOptions(context.Background(), ModelWhisperLargeV3Turbo, []byte("audio"), &TranscriptionOptions{
+		Language:  "fr",
+		VADFilter: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"audio": "YXVkaW8=", "language": "fr", "vad_filter": true}, request)
+	assert.Equal(t, "fr", result.Language)
+	assert.Equal(t, 2.5, result.Duration)
+	assert.Equal(t, &TranscriptionInfo{Language: "fr", LanguageProbability: 0.98, Duration: 2.5, DurationAfterVAD: 2.1}, result.Info)
+	require.Len(t, result.Segments, 1)
+	assert.Equal(t, 2.1, result.Segments[0].End)
+	assert.Len(t, result.Segments[0].Words, 1)
+	assert.NotEmpty(t, result.VTT)
+
+	result, err = client.TranscribeWithOptions(context.Background(), ModelWhisperLargeV3Turbo, []byte("audio"), &TranscriptionOptions{ResponseFormat: TranscriptionText})
+	require.NoError(t, err)
+	// The response format is applied by the client, the model has no such
+	// input.
+	assert.Equal(t, map[string]interface{}{"audio": "YXVkaW8="}, request)
+	assert.Equal(t, "Bonjour tout le monde.", result.Text)
+	assert.Equal(t, 4, result.WordCount)
+	assert.Empty(t, result.Segments)
+	assert.Empty(t, result.VTT)
+	assert.Nil(t, result.Info)
+	assert.Equal(t, ModelWhisperLargeV3Turbo, result.Model())
+
+	_, err = client.TranscribeWithOptions(context.Background(), ModelWhisper, []byte("audio"), &TranscriptionOptions{Language: "fr"})
+	assert.EqualError(t, err, "@cf/openai/whisper doesn't support language, task, initial prompt or VAD settings")
+	_, err = client.TranscribeWithOptions(context.Background(), ModelWhisper, []byte("audio"), &TranscriptionOptions{ResponseFormat: "srt"})
+	assert.EqualError(t, err, `unknown response format "srt"`)
+}
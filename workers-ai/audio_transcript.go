@@ -0,0 +1,265 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TranscriptionSegment is a segment of a transcription as reported by the
+// models that return segments, such as ModelWhisperLargeV3Turbo. Times are
+// in seconds.
+type TranscriptionSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+	// AvgLogProb is the average log probability of the tokens.
+	AvgLogProb   float64             `json:"avg_logprob,omitempty"`
+	NoSpeechProb float64             `json:"no_speech_prob,omitempty"`
+	Words        []TranscriptionWord `json:"words,omitempty"`
+}
+
+// AudioTranscript is a transcription split into timed segments, the form
+// needed for subtitles and for attributing speech to speakers.
+type AudioTranscript struct {
+	Segments []AudioSegment `json:"segments"`
+}
+
+// AudioSegment is a stretch of an AudioTranscript. In JSON, Start and End
+// are in seconds, like the times of the Words.
+type AudioSegment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+	// Speaker labels the voice of the segment. The models don't diarize,
+	// so it is only set by the caller, e.g. per audio channel.
+	Speaker string
+	// Confidence is the model's confidence in the text from 0 to 1, or 0
+	// when unknown.
+	Confidence float64
+	// Words are timed in seconds from the start of the audio, if known.
+	Words []TranscriptionWord
+}
+
+// audioSegmentJSON is the JSON form of an AudioSegment.
+type audioSegmentJSON struct {
+	Start      float64             `json:"start"`
+	End        float64             `json:"end"`
+	Text       string              `json:"text"`
+	Speaker    string              `json:"speaker,omitempty"`
+	Confidence float64             `json:"confidence,omitempty"`
+	Words      []TranscriptionWord `json:"words,omitempty"`
+}
+
+// MarshalJSON encodes the segment with its times in seconds.
+func (s AudioSegment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(audioSegmentJSON{
+		Start:      s.Start.Seconds(),
+		End:        s.End.Seconds(),
+		Text:       s.Text,
+		Speaker:    s.Speaker,
+		Confidence: s.Confidence,
+		Words:      s.Words,
+	})
+}
+
+// UnmarshalJSON decodes a segment encoded by MarshalJSON.
+func (s *AudioSegment) UnmarshalJSON(data []byte) error {
+	var v audioSegmentJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = AudioSegment{
+		Start:      seconds(v.Start),
+		End:        seconds(v.End),
+		Text:       v.Text,
+		Speaker:    v.Speaker,
+		Confidence: v.Confidence,
+		Words:      v.Words,
+	}
+	return nil
+}
+
+// AudioTranscript converts r to a structured transcript. The segments
+// reported by the model are used when present; otherwise the text is a
+// single segment spanning the timed words, if any.
+func (r *TranscriptionResult) AudioTranscript() AudioTranscript {
+	var t AudioTranscript
+	for _, s := range r.Segments {
+		segment := AudioSegment{
+			Start: seconds(s.Start),
+			End:   seconds(s.End),
+			Text:  strings.TrimSpace(s.Text),
+			Words: s.Words,
+		}
+		if s.AvgLogProb != 0 {
+			segment.Confidence = math.Exp(s.AvgLogProb)
+		}
+		t.Segments = append(t.Segments, segment)
+	}
+	if len(t.Segments) > 0 {
+		return t
+	}
+
+	text := strings.TrimSpace(r.Text)
+	if text == "" {
+		return t
+	}
+	segment := AudioSegment{Text: text, Words: r.Words}
+	if n := len(r.Words); n > 0 {
+		segment.Start = seconds(r.Words[0].Start)
+		segment.End = seconds(r.Words[n-1].End)
+	}
+	t.Segments = append(t.Segments, segment)
+	return t
+}
+
+// NewAudioTranscript builds a transcript from the updates of a
+// TranscriptionStream or of TranscribeSpeech. Updates without text are
+// left out.
+func NewAudioTranscript(updates ...TranscriptUpdate) AudioTranscript {
+	var t AudioTranscript
+	for _, u := range updates {
+		if u.Text == "" {
+			continue
+		}
+		t.Segments = append(t.Segments, AudioSegment{Start: u.Start, End: u.End, Text: u.Text, Words: u.Words})
+	}
+	return t
+}
+
+// Append adds the segments of other, shifted by offset, e.g. to join the
+// transcripts of consecutive recordings.
+func (t *AudioTranscript) Append(other AudioTranscript, offset time.Duration) {
+	for _, s := range other.Segments {
+		s.Start += offset
+		s.End += offset
+		s.Words = shiftWords(s.Words, offset)
+		t.Segments = append(t.Segments, s)
+	}
+}
+
+// Merge returns the transcript with consecutive segments of the same
+// speaker joined when they are less than maxGap apart and the result lasts
+// at most maxDuration. A zero maxDuration doesn't limit the length. It is
+// useful to turn many short segments into readable subtitles.
+func (t AudioTranscript) Merge(maxGap, maxDuration time.Duration) AudioTranscript {
+	var merged AudioTranscript
+	for _, s := range t.Segments {
+		if n := len(merged.Segments); n > 0 {
+			last := &merged.Segments[n-1]
+			if last.Speaker == s.Speaker && s.Start-last.End < maxGap && (maxDuration == 0 || s.End-last.Start <= maxDuration) {
+				// Average the confidence by duration.
+				lastLength, length := (last.End - last.Start).Seconds(), (s.End - s.Start).Seconds()
+				if total := lastLength + length; total > 0 {
+					last.Confidence = (last.Confidence*lastLength + s.Confidence*length) / total
+				}
+				last.End = s.End
+				last.Text += " " + s.Text
+				last.Words = append(last.Words, s.Words...)
+				continue
+			}
+		}
+		s.Words = append([]TranscriptionWord(nil), s.Words...)
+		merged.Segments = append(merged.Segments, s)
+	}
+	return merged
+}
+
+// WrapLines returns the transcript with the text of the segments broken
+// into lines of at most maxLength characters at word boundaries, as
+// subtitle guidelines require (42 is common). Longer words are kept on a
+// line of their own. A maxLength of 0 or less leaves the text as is.
+func (t AudioTranscript) WrapLines(maxLength int) AudioTranscript {
+	wrapped := AudioTranscript{Segments: append([]AudioSegment(nil), t.Segments...)}
+	if maxLength <= 0 {
+		return wrapped
+	}
+	for i := range wrapped.Segments {
+		var lines []string
+		line := ""
+		for _, word := range strings.Fields(wrapped.Segments[i].Text) {
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= maxLength:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		wrapped.Segments[i].Text = strings.Join(lines, "\n")
+	}
+	return wrapped
+}
+
+// Text returns the transcript as plain text. Segments are joined with
+// spaces; when speakers are set, every change of speaker starts a new line
+// prefixed with the speaker's label.
+func (t AudioTranscript) Text() string {
+	var b strings.Builder
+	for i, s := range t.Segments {
+		if i > 0 && s.Speaker == t.Segments[i-1].Speaker {
+			b.WriteString(" ")
+			b.WriteString(s.Text)
+			continue
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if s.Speaker != "" {
+			b.WriteString(s.Speaker + ": ")
+		}
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// SRT renders the transcript as SubRip subtitles.
+func (t AudioTranscript) SRT() string {
+	var b strings.Builder
+	for i, s := range t.Segments {
+		text := s.Text
+		if s.Speaker != "" {
+			text = s.Speaker + ": " + text
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, subtitleTime(s.Start, ","), subtitleTime(s.End, ","), text)
+	}
+	return b.String()
+}
+
+// VTT renders the transcript as WebVTT subtitles, with speakers as voice
+// tags. The text is escaped, so that "&", "<" and "-->" show as typed.
+func (t AudioTranscript) VTT() string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, s := range t.Segments {
+		text := vttEscaper.Replace(s.Text)
+		if s.Speaker != "" {
+			text = fmt.Sprintf("<v %s>%s", vttEscaper.Replace(s.Speaker), text)
+		}
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", subtitleTime(s.Start, "."), subtitleTime(s.End, "."), text)
+	}
+	return b.String()
+}
+
+// vttEscaper escapes the characters that would end or start markup in a
+// WebVTT cue: a ">" would end a voice tag and "-->" a timing line.
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// subtitleTime formats d as hh:mm:ss followed by sep and milliseconds.
+func subtitleTime(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
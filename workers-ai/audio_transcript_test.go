@@ -0,0 +1,114 @@
+package workersai
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptionResult_AudioTranscript(t *testing.T) {
+	var result TranscriptionResult
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"text": "Hello there. General Kenobi.",
+		"segments": [
+			{"start": 0, "end": 1.5, "text": " Hello there.", "avg_logprob": -0.1},
+			{"start": 2.25, "end": 3.75, "text": " General Kenobi.", "avg_logprob": -0.5}
+		]
+	}`), &result))
+
+	transcript := result.AudioTranscript()
+	require.Len(t, transcript.Segments, 2)
+	assert.Equal(t, 2250*time.Millisecond, transcript.Segments[1].Start)
+	assert.Equal(t, "General Kenobi.", transcript.Segments[1].Text)
+	assert.InDelta(t, math.Exp(-0.1), transcript.Segments[0].Confidence, 1e-9)
+
+	// Without segments, the words give the timing of the whole text.
+	result = TranscriptionResult{Text: " Hi you ", Words: []TranscriptionWord{{Word: "Hi", Start: 0.5, End: 0.75}, {Word: "you", Start: 0.75, End: 1}}}
+	transcript = result.AudioTranscript()
+	require.Len(t, transcript.Segments, 1)
+	assert.Equal(t, AudioSegment{Start: 500 * time.Millisecond, End: time.Second, Text: "Hi you", Words: result.Words}, transcript.Segments[0])
+
+	assert.Empty(t, (&TranscriptionResult{}).AudioTranscript().Segments)
+}
+
+func TestAudioTranscript_Formats(t *testing.T) {
+	transcript := AudioTranscript{Segments: []AudioSegment{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "Hello there.", Speaker: "Obi-Wan"},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "Hi.", Speaker: "Obi-Wan"},
+		{Start: 3 * time.Second, End: time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond, Text: "General Kenobi.", Speaker: "Grievous"},
+	}}
+
+	assert.Equal(t, "Obi-Wan: Hello there. Hi.\nGrievous: General Kenobi.", transcript.Text())
+
+	assert.Equal(t, "1\n00:00:00,000 --> 00:00:01,500\nObi-Wan: Hello there.\n\n"+
+		"2\n00:00:02,000 --> 00:00:03,000\nObi-Wan: Hi.\n\n"+
+		"3\n00:00:03,000 --> 01:02:03,045\nGrievous: General Kenobi.\n\n", transcript.SRT())
+
+	assert.Equal(t, "WEBVTT\n\n"+
+		"00:00:00.000 --> 00:00:01.500\n<v Obi-Wan>Hello there.\n\n"+
+		"00:00:02.000 --> 00:00:03.000\n<v Obi-Wan>Hi.\n\n"+
+		"00:00:03.000 --> 01:02:03.045\n<v Grievous>General Kenobi.\n\n", transcript.VTT())
+
+	plain := AudioTranscript{Segments: []AudioSegment{{Text: "One."}, {Text: "Two."}}}
+	assert.Equal(t, "One. Two.", plain.Text())
+
+	markup := AudioTranscript{Segments: []AudioSegment{{Text: "Tom & Jerry <3 a --> b", Speaker: "R<2>"}}}
+	assert.Equal(t, "WEBVTT\n\n00:00:00.000 --> 00:00:00.000\n<v R&lt;2&gt;>Tom &amp; Jerry &lt;3 a --&gt; b\n\n", markup.VTT())
+}
+
+func TestAudioSegment_JSON(t *testing.T) {
+	segment := AudioSegment{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "Hi.", Speaker: "A", Words: []TranscriptionWord{{Word: "Hi.", Start: 1.5, End: 2}}}
+	data, err := json.Marshal(segment)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"start": 1.5, "end": 3, "text": "Hi.", "speaker": "A", "words": [{"word": "Hi.", "start": 1.5, "end": 2}]}`, string(data))
+
+	var decoded AudioSegment
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, segment, decoded)
+}
+
+func TestAudioTranscript_Merge(t *testing.T) {
+	transcript := AudioTranscript{Segments: []AudioSegment{
+		{Start: 0, End: time.Second, Text: "a", Confidence: 1, Words: []TranscriptionWord{{Word: "a"}}},
+		{Start: 1200 * time.Millisecond, End: 2200 * time.Millisecond, Text: "b", Confidence: 0.5, Words: []TranscriptionWord{{Word: "b"}}},
+		{Start: 4 * time.Second, End: 5 * time.Second, Text: "c"},
+		{Start: 5 * time.Second, End: 6 * time.Second, Text: "d", Speaker: "B"},
+	}}
+
+	merged := transcript.Merge(500*time.Millisecond, 0)
+	require.Len(t, merged.Segments, 3)
+	assert.Equal(t, "a b", merged.Segments[0].Text)
+	assert.Equal(t, 2200*time.Millisecond, merged.Segments[0].End)
+	assert.InDelta(t, 0.75, merged.Segments[0].Confidence, 1e-9)
+	assert.Equal(t, []TranscriptionWord{{Word: "a"}, {Word: "b"}}, merged.Segments[0].Words)
+	assert.Len(t, transcript.Segments[0].Words, 1, "the original is left alone")
+
+	assert.Len(t, transcript.Merge(500*time.Millisecond, 2*time.Second).Segments, 4)
+
+	var joined AudioTranscript
+	joined.Append(merged, 0)
+	joined.Append(NewAudioTranscript(TranscriptUpdate{Text: "e", Start: time.Second, End: 2 * time.Second, Words: []TranscriptionWord{{Word: "e", Start: 1, End: 2}}}, TranscriptUpdate{}), 10*time.Second)
+	require.Len(t, joined.Segments, 4)
+	assert.Equal(t, 11*time.Second, joined.Segments[3].Start)
+	assert.Equal(t, []TranscriptionWord{{Word: "e", Start: 11, End: 12}}, joined.Segments[3].Words)
+}
+
+func TestAudioTranscript_WrapLines(t *testing.T) {
+	transcript := AudioTranscript{Segments: []AudioSegment{
+		{End: 3 * time.Second, Text: "The quick brown fox jumps over the lazy dog"},
+		{Start: 3 * time.Second, End: 4 * time.Second, Text: "Supercalifragilistic is long"},
+	}}
+
+	wrapped := transcript.WrapLines(16)
+	assert.Equal(t, "The quick brown\nfox jumps over\nthe lazy dog", wrapped.Segments[0].Text)
+	assert.Equal(t, "Supercalifragilistic\nis long", wrapped.Segments[1].Text)
+	assert.Equal(t, "The quick brown fox jumps over the lazy dog", transcript.Segments[0].Text, "the transcript is not modified")
+	assert.True(t, strings.HasPrefix(wrapped.SRT(), "1\n00:00:00,000 --> 00:00:03,000\nThe quick brown\nfox jumps over\nthe lazy dog\n\n2\n"))
+
+	assert.Equal(t, transcript, transcript.WrapLines(0))
+}
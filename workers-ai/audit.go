@@ -0,0 +1,86 @@
+package workersai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// AuditEntry is a request logged by an AuditLog.
+type AuditEntry struct {
+	// Time is when the request was sent.
+	Time       time.Time `json:"time"`
+	Tenant     string    `json:"tenant,omitempty"`
+	Model      string    `json:"model"`
+	StatusCode int       `json:"status_code"`
+	DurationMS int64     `json:"duration_ms"`
+	Usage      Usage     `json:"usage"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// AuditLog writes an AuditEntry per request of a client as JSON Lines,
+// e.g. to an append-only file, for AuditReport to read back with
+// ReadAuditLog:
+//
+//	log := workersai.NewAuditLog(file)
+//	client.Use(log.Hooks())
+//
+// Write errors are kept, see Err; they don't fail the requests.
+type AuditLog struct {
+	w   *JSONLWriter
+	now func() time.Time
+}
+
+// NewAuditLog returns an audit log writing to w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: NewJSONLWriter(w), now: time.Now}
+}
+
+// Hooks returns the client hooks writing to the log.
+func (l *AuditLog) Hooks() Hooks {
+	return Hooks{AfterResponse: l.record}
+}
+
+func (l *AuditLog) record(event RequestEvent) {
+	entry := AuditEntry{
+		Time:       l.now().Add(-event.Duration).UTC(),
+		Tenant:     event.Tenant,
+		Model:      event.Model,
+		StatusCode: event.StatusCode,
+		DurationMS: event.Duration.Milliseconds(),
+		Usage:      event.Usage,
+	}
+	if event.Err != nil {
+		entry.Error = event.Err.Error()
+	}
+	l.w.Write(entry)
+}
+
+// Err returns the error that stopped the log, if any.
+func (l *AuditLog) Err() error {
+	return l.w.Err()
+}
+
+// ReadAuditLog reads the entries written by an AuditLog. Blank lines are
+// skipped.
+func ReadAuditLog(r io.Reader) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: failed to parse audit entry: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,40 @@
+package workersai
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewAuditLog(&buf)
+	now := time.Date(2025, 6, 1, 12, 0, 2, 0, time.UTC)
+	log.now = func() time.Time { return now }
+
+	record := log.Hooks().AfterResponse
+	record(RequestEvent{Model: ModelLlama38B, Tenant: "acme", StatusCode: 200, Duration: 2 * time.Second, Usage: Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}})
+	record(RequestEvent{Model: ModelLlama38B, StatusCode: 500, Err: errors.New("boom")})
+	require.NoError(t, log.Err())
+
+	entries, err := ReadAuditLog(strings.NewReader(buf.String() + "\n"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, AuditEntry{
+		Time:       time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		Tenant:     "acme",
+		Model:      ModelLlama38B,
+		StatusCode: 200,
+		DurationMS: 2000,
+		Usage:      Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3},
+	}, entries[0])
+	assert.Equal(t, "boom", entries[1].Error)
+
+	_, err = ReadAuditLog(strings.NewReader("{}\nnot json\n"))
+	assert.ErrorContains(t, err, "line 2: failed to parse audit entry")
+}
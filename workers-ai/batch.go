@@ -0,0 +1,296 @@
+package workersai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultBatchWorkers is the number of requests ChatBatch sends at once
+// when workers is not set.
+const DefaultBatchWorkers = 4
+
+// Statuses of a BatchItem.
+const (
+	BatchSucceeded = "succeeded"
+	BatchFailed    = "failed"
+)
+
+// Types of the errors of failed batch items, from ErrorType. ErrorTypeClient
+// is an error raised by the client rather than by the API or the network,
+// e.g. ErrNeuronLimitExceeded.
+const (
+	ErrorTypeRateLimit       = "rate_limit"
+	ErrorTypeCapacity        = "capacity"
+	ErrorTypeContextOverflow = "context_overflow"
+	ErrorTypeInvalidRequest  = "invalid_request"
+	ErrorTypeServer          = "server"
+	ErrorTypeNetwork         = "network"
+	ErrorTypeClient          = "client"
+	ErrorTypeCanceled        = "canceled"
+	ErrorTypePanic           = "panic"
+)
+
+// BatchItem is the outcome of one item of a batch.
+type BatchItem[T any] struct {
+	// Index is the position of the item in the input.
+	Index  int
+	Status string
+	// Result is set when Status is BatchSucceeded.
+	Result T
+	// Err, ErrorType and Retryable describe the failure when Status is
+	// BatchFailed.
+	Err       error
+	ErrorType string
+	Retryable bool
+	// RetryToken encodes the input of a failed item, to retry it later,
+	// e.g. from another process, with Client.RetryChat or
+	// Client.RetryTranslation.
+	RetryToken string
+	Usage      Usage
+}
+
+// BatchSummary sums up a batch.
+type BatchSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// Usage is the token usage of all items, failed ones included.
+	Usage Usage `json:"usage"`
+}
+
+// BatchResult is the outcome of a batch whose items succeed or fail
+// independently: one failed item doesn't fail the batch.
+type BatchResult[T any] struct {
+	// Items are in input order.
+	Items   []BatchItem[T]
+	Summary BatchSummary
+}
+
+// MarshalJSON encodes the item as a record of a JSON Lines report, with
+// the error as a string.
+func (i BatchItem[T]) MarshalJSON() ([]byte, error) {
+	record := struct {
+		Index      int    `json:"index"`
+		Status     string `json:"status"`
+		Result     *T     `json:"result,omitempty"`
+		Error      string `json:"error,omitempty"`
+		ErrorType  string `json:"error_type,omitempty"`
+		Retryable  bool   `json:"retryable,omitempty"`
+		RetryToken string `json:"retry_token,omitempty"`
+		Usage      Usage  `json:"usage"`
+	}{
+		Index:      i.Index,
+		Status:     i.Status,
+		ErrorType:  i.ErrorType,
+		Retryable:  i.Retryable,
+		RetryToken: i.RetryToken,
+		Usage:      i.Usage,
+	}
+	if i.Err != nil {
+		record.Error = i.Err.Error()
+	} else {
+		record.Result = &i.Result
+	}
+	return json.Marshal(record)
+}
+
+// Results returns the results of the items, in input order, with the zero
+// value for failed items.
+func (r *BatchResult[T]) Results() []T {
+	results := make([]T, len(r.Items))
+	for i, item := range r.Items {
+		results[i] = item.Result
+	}
+	return results
+}
+
+// Failed returns the failed items.
+func (r *BatchResult[T]) Failed() []BatchItem[T] {
+	var failed []BatchItem[T]
+	for _, item := range r.Items {
+		if item.Status == BatchFailed {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// Err joins the errors of the failed items, nil if all succeeded.
+func (r *BatchResult[T]) Err() error {
+	var errs []error
+	for _, item := range r.Failed() {
+		errs = append(errs, item.Err)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d items failed: %w", len(errs), len(r.Items), errors.Join(errs...))
+}
+
+// set records the outcome of item i. Results must be set in a single
+// goroutine or under a lock.
+func (r *BatchResult[T]) set(i int, result T, usage Usage, err error, retry func() string) {
+	item := BatchItem[T]{Index: i, Status: BatchSucceeded, Result: result, Usage: usage}
+	if err != nil {
+		item.Status = BatchFailed
+		item.Err = err
+		item.ErrorType = ErrorType(err)
+		item.Retryable = IsRetryable(err)
+		item.RetryToken = retry()
+	}
+	r.Items[i] = item
+}
+
+// summarize fills r.Summary from the items.
+func (r *BatchResult[T]) summarize() {
+	r.Summary = BatchSummary{Total: len(r.Items)}
+	for _, item := range r.Items {
+		if item.Status == BatchFailed {
+			r.Summary.Failed++
+		} else {
+			r.Summary.Succeeded++
+		}
+		r.Summary.Usage.PromptTokens += item.Usage.PromptTokens
+		r.Summary.Usage.CompletionTokens += item.Usage.CompletionTokens
+		r.Summary.Usage.TotalTokens += item.Usage.TotalTokens
+	}
+}
+
+// ErrorType classifies err for reporting, as one of the ErrorType
+// constants.
+func ErrorType(err error) string {
+	var panicErr *PanicError
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &panicErr):
+		return ErrorTypePanic
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return ErrorTypeCanceled
+	case IsCapacity(err):
+		return ErrorTypeCapacity
+	case IsRateLimit(err):
+		return ErrorTypeRateLimit
+	case IsContextOverflow(err):
+		return ErrorTypeContextOverflow
+	case isNetworkError(err):
+		return ErrorTypeNetwork
+	case !errors.As(err, &apiErr):
+		return ErrorTypeClient
+	case IsRetryable(err):
+		return ErrorTypeServer
+	}
+	return ErrorTypeInvalidRequest
+}
+
+// ChatBatch sends requests, up to workers at once, and reports the
+// outcome of each. Cancelling ctx fails the requests not sent yet.
+func (c *Client) ChatBatch(ctx context.Context, requests []ChatCompletionRequest, workers int) *BatchResult[*ChatResponse] {
+	return c.ChatBatchEach(ctx, requests, workers, nil)
+}
+
+// ChatBatchEach is ChatBatch also passing every item to onItem as soon as
+// it completes, e.g. to write it with a JSONLWriter. Calls of onItem are
+// serialized; its panics are recovered and ignored.
+func (c *Client) ChatBatchEach(ctx context.Context, requests []ChatCompletionRequest, workers int, onItem func(BatchItem[*ChatResponse])) *BatchResult[*ChatResponse] {
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+	result := &BatchResult[*ChatResponse]{Items: make([]BatchItem[*ChatResponse], len(requests))}
+
+	var mu sync.Mutex
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(requests); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				resp, err := c.batchChat(ctx, requests[i])
+				var usage Usage
+				if resp != nil {
+					usage = resp.GetUsage()
+				}
+				mu.Lock()
+				result.set(i, resp, usage, err, func() string { return retryToken(retryChat, requests[i]) })
+				if onItem != nil {
+					var panicErr error
+					func() {
+						defer recoverPanic(&panicErr)
+						onItem(result.Items[i])
+					}()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	result.summarize()
+	return result
+}
+
+// batchChat sends one request of ChatBatch.
+func (c *Client) batchChat(ctx context.Context, request ChatCompletionRequest) (resp *ChatResponse, err error) {
+	defer recoverPanic(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.ChatCompletionContext(ctx, request)
+}
+
+// RetryChat sends again the request of a failed item of ChatBatch.
+func (c *Client) RetryChat(ctx context.Context, token string) (*ChatResponse, error) {
+	var request ChatCompletionRequest
+	if err := decodeRetryToken(token, retryChat, &request); err != nil {
+		return nil, err
+	}
+	return c.ChatCompletionContext(ctx, request)
+}
+
+// Kinds of retry tokens.
+const (
+	retryChat        = "chat"
+	retryTranslation = "translation"
+)
+
+// retryToken encodes the input of a batch item of kind.
+func retryToken(kind string, input interface{}) string {
+	data, err := json.Marshal(struct {
+		Kind  string      `json:"kind"`
+		Input interface{} `json:"input"`
+	}{kind, input})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeRetryToken decodes the input of a batch item of kind into out.
+func decodeRetryToken(token, kind string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("invalid retry token: %w", err)
+	}
+	var decoded struct {
+		Kind  string          `json:"kind"`
+		Input json.RawMessage `json:"input"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("invalid retry token: %w", err)
+	}
+	if decoded.Kind != kind {
+		return fmt.Errorf("retry token is for a %s item, not %s", decoded.Kind, kind)
+	}
+	if err := json.Unmarshal(decoded.Input, out); err != nil {
+		return fmt.Errorf("invalid retry token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorType(t *testing.T) {
+	assert.Equal(t, ErrorTypeRateLimit, ErrorType(&APIError{StatusCode: http.StatusTooManyRequests}))
+	assert.Equal(t, ErrorTypeCapacity, ErrorType(&APIError{StatusCode: http.StatusServiceUnavailable, Body: "Out of capacity"}))
+	assert.Equal(t, ErrorTypeServer, ErrorType(&APIError{StatusCode: http.StatusBadGateway}))
+	assert.Equal(t, ErrorTypeInvalidRequest, ErrorType(&APIError{StatusCode: http.StatusBadRequest}))
+	assert.Equal(t, ErrorTypeCanceled, ErrorType(fmt.Errorf("send: %w", context.DeadlineExceeded)))
+	assert.Equal(t, ErrorTypePanic, ErrorType(&PanicError{Value: "boom"}))
+	assert.Equal(t, ErrorTypeNetwork, ErrorType(fmt.Errorf("failed to make request: %w", &url.Error{Op: "Post", URL: "https://api.cloudflare.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}})))
+	assert.Equal(t, ErrorTypeClient, ErrorType(fmt.Errorf("send: %w", ErrNeuronLimitExceeded)))
+}
+
+func TestClient_ChatBatch(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		content := req.Messages[0].(ChatMessage).Content
+		if content == "bad" && failing.Load() {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"success": false}`)
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q, "usage": {"prompt_tokens": 2, "completion_tokens": 3, "total_tokens": 5}}}`, "re: "+content)
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var requests []ChatCompletionRequest
+	for _, content := range []string{"one", "bad", "three"} {
+		requests = append(requests, ChatCompletionRequest{
+			Model:    ModelLlama38B,
+			Messages: []Message{ChatMessage{Role: "user", Content: content}},
+		})
+	}
+	result := client.ChatBatch(context.Background(), requests, 2)
+
+	assert.Equal(t, BatchSummary{Total: 3, Succeeded: 2, Failed: 1, Usage: Usage{PromptTokens: 4, CompletionTokens: 6, TotalTokens: 10}}, result.Summary)
+	assert.Equal(t, "re: three", result.Items[2].Result.GetContent())
+	assert.Error(t, result.Err())
+
+	failed := result.Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, 1, failed[0].Index)
+	assert.Equal(t, BatchFailed, failed[0].Status)
+	assert.Equal(t, ErrorTypeRateLimit, failed[0].ErrorType)
+	assert.True(t, failed[0].Retryable)
+
+	failing.Store(false)
+	resp, err := client.RetryChat(context.Background(), failed[0].RetryToken)
+	require.NoError(t, err)
+	assert.Equal(t, "re: bad", resp.GetContent())
+
+	_, err = client.RetryTranslation(failed[0].RetryToken)
+	assert.ErrorContains(t, err, "retry token is for a chat item")
+}
+
+func TestClient_TranslateBatchItems(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		last := req.Messages[len(req.Messages)-1].(ChatMessage).Content
+		if last == "bad" && calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"success": false}`)
+			return
+		}
+		// Failed texts are not in the conversation.
+		for _, msg := range req.Messages[:len(req.Messages)-1] {
+			assert.NotEqual(t, "bad", msg.(ChatMessage).Content)
+		}
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q}}`, "T:"+last)
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	opts := TranslationOptions{TargetLang: "French"}
+	result, err := client.TranslateBatchItems(ModelLlama38B, []string{"a", "bad", "c"}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"T:a", "", "T:c"}, result.Results())
+	assert.Equal(t, 1, result.Summary.Failed)
+	assert.Equal(t, ErrorTypeInvalidRequest, result.Items[1].ErrorType)
+	assert.False(t, result.Items[1].Retryable)
+
+	translated, err := client.RetryTranslation(result.Items[1].RetryToken)
+	require.NoError(t, err)
+	assert.Equal(t, "T:bad", translated)
+
+	// TranslateBatch still fails the whole batch.
+	calls.Store(0)
+	_, err = client.TranslateBatch(ModelLlama38B, []string{"a", "bad", "c"}, opts)
+	assert.ErrorContains(t, err, "failed to translate text 1")
+}
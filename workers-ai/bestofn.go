@@ -0,0 +1,158 @@
+package workersai
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// Scorer rates a candidate response; higher scores are better.
+type Scorer func(resp *ChatResponse) (float64, error)
+
+// Candidate is one of the generations of BestOfN.
+type Candidate struct {
+	Response *ChatResponse
+	Score    float64
+	// Err is set when the generation or its scoring failed.
+	Err error
+}
+
+// BestOfNResult holds the outcome of BestOfN.
+type BestOfNResult struct {
+	// Best is the candidate with the highest score. Ties go to the
+	// candidate generated first.
+	Best *Candidate
+	// Candidates are all generations, in request order.
+	Candidates []Candidate
+	// Usage is the token usage summed over all generations. Tokens spent by
+	// a Judge are tracked by the Judge.
+	Usage Usage
+}
+
+// BestOfN sends request n times concurrently, scores every response and
+// returns the best one. Set a non-zero Temperature on the request, or the
+// candidates will likely all be the same.
+//
+// Failed generations don't fail the call as long as one candidate could be
+// scored.
+func (c *Client) BestOfN(request ChatCompletionRequest, n int, scorer Scorer) (*BestOfNResult, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("n must be at least 1, got %d", n)
+	}
+
+	result := &BestOfNResult{Candidates: make([]Candidate, n)}
+
+	var wg sync.WaitGroup
+	for i := range result.Candidates {
+		wg.Add(1)
+		go func(candidate *Candidate) {
+			defer wg.Done()
+			defer recoverPanic(&candidate.Err)
+
+			candidate.Response, candidate.Err = c.ChatCompletion(request)
+			if candidate.Err != nil {
+				return
+			}
+			if candidate.Score, candidate.Err = scorer(candidate.Response); candidate.Err != nil {
+				candidate.Err = fmt.Errorf("failed to score candidate: %w", candidate.Err)
+			}
+		}(&result.Candidates[i])
+	}
+	wg.Wait()
+
+	var errs []error
+	for i := range result.Candidates {
+		candidate := &result.Candidates[i]
+		if candidate.Response != nil {
+			usage := candidate.Response.GetUsage()
+			result.Usage.PromptTokens += usage.PromptTokens
+			result.Usage.CompletionTokens += usage.CompletionTokens
+			result.Usage.TotalTokens += usage.TotalTokens
+		}
+		if candidate.Err != nil {
+			errs = append(errs, candidate.Err)
+			continue
+		}
+		if result.Best == nil || candidate.Score > result.Best.Score {
+			result.Best = candidate
+		}
+	}
+
+	if result.Best == nil {
+		return result, fmt.Errorf("all %d candidates failed: %w", n, errors.Join(errs...))
+	}
+	return result, nil
+}
+
+// DefaultJudgeCriteria is used by Judge when no Criteria are set.
+const DefaultJudgeCriteria = "correctness, helpfulness and clarity"
+
+// Judge scores responses by asking a model to rate them from 0 to 10. Pass
+// its Score method to BestOfN. It also grades answers against rubrics and
+// compares pairs of answers. A Judge is safe for concurrent use.
+type Judge struct {
+	Client ClientInterface
+	Model  string
+	// Criteria describe what makes a good answer. Defaults to
+	// DefaultJudgeCriteria.
+	Criteria string
+	// Question, if set, is shown to the judge along with the answer.
+	Question string
+	// Scale is the highest score of Grade, which scores from 1 to Scale.
+	// Defaults to DefaultGradeScale.
+	Scale int
+	// Debias makes PairwiseCompare judge both orders of the answers, to
+	// cancel the bias of judges for one position. When the verdicts
+	// disagree the result is a tie.
+	Debias bool
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+var judgeScoreRe = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// Score asks the judge model to rate the content of resp.
+func (j *Judge) Score(resp *ChatResponse) (float64, error) {
+	criteria := j.Criteria
+	if criteria == "" {
+		criteria = DefaultJudgeCriteria
+	}
+
+	prompt := "Answer:\n" + resp.GetContent()
+	if j.Question != "" {
+		prompt = "Question:\n" + j.Question + "\n\n" + prompt
+	}
+
+	messages := []Message{
+		ChatMessage{Role: "system", Content: fmt.Sprintf("You rate answers on %s. Reply with a single number from 0 (worst) to 10 (best) and nothing else.", criteria)},
+		ChatMessage{Role: "user", Content: prompt},
+	}
+
+	verdict, err := j.Client.Chat(j.Model, messages, nil)
+	if err != nil {
+		return 0, fmt.Errorf("judge request failed: %w", err)
+	}
+
+	j.mu.Lock()
+	usage := verdict.GetUsage()
+	j.usage.PromptTokens += usage.PromptTokens
+	j.usage.CompletionTokens += usage.CompletionTokens
+	j.usage.TotalTokens += usage.TotalTokens
+	j.mu.Unlock()
+
+	match := judgeScoreRe.FindString(verdict.GetContent())
+	if match == "" {
+		return 0, fmt.Errorf("judge gave no score: %q", verdict.GetContent())
+	}
+	return strconv.ParseFloat(match, 64)
+}
+
+// Usage returns the tokens spent by the judge so far.
+func (j *Judge) Usage() Usage {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.usage
+}
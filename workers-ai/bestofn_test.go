@@ -0,0 +1,93 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_BestOfN(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if n == 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"success": false, "errors": ["boom"]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "result": {"response": "%s", "usage": {"prompt_tokens": 5, "completion_tokens": %d, "total_tokens": %d}}}`, strings.Repeat("a", int(n)), n, 5+n)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}}}
+	longest := func(resp *ChatResponse) (float64, error) {
+		return float64(len(resp.GetContent())), nil
+	}
+
+	result, err := client.BestOfN(request, 4, longest)
+	require.NoError(t, err)
+	require.Len(t, result.Candidates, 4)
+	assert.Equal(t, "aaaa", result.Best.Response.GetContent())
+	assert.Equal(t, 4.0, result.Best.Score)
+
+	failed := 0
+	for _, candidate := range result.Candidates {
+		if candidate.Err != nil {
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, Usage{PromptTokens: 15, CompletionTokens: 8, TotalTokens: 23}, result.Usage)
+
+	_, err = client.BestOfN(request, 0, longest)
+	assert.Error(t, err)
+
+	// A panicking scorer fails its candidate only.
+	var scored atomic.Int32
+	result, err = client.BestOfN(request, 2, func(resp *ChatResponse) (float64, error) {
+		if scored.Add(1) == 1 {
+			panic("scorer bug")
+		}
+		return 1, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, result.Best.Score)
+}
+
+func TestJudge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		answer := request.Messages[1].(ChatMessage).Content
+		score := "3"
+		if strings.Contains(answer, "Paris") {
+			score = "Score: 9.5"
+		}
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q, "usage": {"prompt_tokens": 20, "completion_tokens": 1, "total_tokens": 21}}}`, score)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	judge := &Judge{Client: client, Model: ModelLlama38B, Question: "Capital of France?"}
+
+	score, err := judge.Score(&ChatResponse{IsLegacyResult: true, LegacyResponse: LegacyResponse{Response: "Paris"}})
+	require.NoError(t, err)
+	assert.Equal(t, 9.5, score)
+
+	score, err = judge.Score(&ChatResponse{IsLegacyResult: true, LegacyResponse: LegacyResponse{Response: "Lyon"}})
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, score)
+
+	assert.Equal(t, Usage{PromptTokens: 40, CompletionTokens: 2, TotalTokens: 42}, judge.Usage())
+}
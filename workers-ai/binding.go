@@ -0,0 +1,107 @@
+package workersai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// AIBinding runs models the way the Workers AI binding (env.AI.run) of a
+// Cloudflare Worker does: it takes the model input and returns the model
+// output as is, without the REST API's response envelope.
+type AIBinding interface {
+	// Run runs modelID on input, given as JSON or as raw bytes of
+	// contentType. It returns the output along with its content type, e.g.
+	// "application/json", "text/event-stream" or "image/png".
+	Run(ctx context.Context, modelID, contentType string, input io.Reader) (output io.ReadCloser, outputType string, err error)
+}
+
+// BindingTransport is an http.RoundTripper that serves the requests of a
+// Client from an AIBinding instead of the REST API, so that the same code
+// runs in a Cloudflare Worker (compiled to WebAssembly) and on a server.
+// Requests to other endpoints fail.
+type BindingTransport struct {
+	Binding AIBinding
+}
+
+// NewBindingClient returns a client whose requests are served by binding.
+// Inside a Worker, pass NewJSBinding(env.Get("AI")).
+func NewBindingClient(binding AIBinding) *Client {
+	client := NewClient("binding", "")
+	client.HTTPClient = &http.Client{Transport: &BindingTransport{Binding: binding}}
+	return client
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *BindingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	modelID, ok := bindingModel(req.URL.Path)
+	if !ok {
+		return nil, fmt.Errorf("binding transport: unsupported endpoint %s", req.URL.Path)
+	}
+
+	var input io.Reader = http.NoBody
+	if req.Body != nil {
+		defer req.Body.Close()
+		input = req.Body
+	}
+
+	output, outputType, err := t.Binding.Run(req.Context(), modelID, req.Header.Get("Content-Type"), input)
+	if err != nil {
+		return bindingResponse(req, http.StatusInternalServerError, "application/json", envelope(false, err.Error(), nil)), nil
+	}
+
+	if !strings.HasPrefix(outputType, "application/json") {
+		return bindingResponse(req, http.StatusOK, outputType, output), nil
+	}
+
+	defer output.Close()
+	result, err := io.ReadAll(output)
+	if err != nil {
+		return nil, fmt.Errorf("binding transport: failed to read output: %w", err)
+	}
+	return bindingResponse(req, http.StatusOK, outputType, envelope(true, "", result)), nil
+}
+
+// bindingModel extracts the model ID from a /ai/run or AI Gateway URL path.
+func bindingModel(path string) (string, bool) {
+	for _, marker := range []string{"/ai/run/", "/workers-ai/"} {
+		if i := strings.Index(path, marker); i >= 0 {
+			return path[i+len(marker):], true
+		}
+	}
+	return "", false
+}
+
+// envelope wraps a model output or an error in the REST API's response format.
+func envelope(success bool, errMsg string, result json.RawMessage) io.ReadCloser {
+	body := map[string]interface{}{
+		"success":  success,
+		"errors":   []string{},
+		"messages": []string{},
+	}
+	if errMsg != "" {
+		body["errors"] = []string{errMsg}
+	}
+	if result != nil {
+		body["result"] = result
+	}
+	data, _ := json.Marshal(body)
+	return io.NopCloser(bytes.NewReader(data))
+}
+
+func bindingResponse(req *http.Request, status int, contentType string, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       body,
+		Request:    req,
+	}
+}
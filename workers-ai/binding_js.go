@@ -0,0 +1,138 @@
+//go:build js && wasm
+
+package workersai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall/js"
+)
+
+// JSBinding is the AIBinding of a Worker, calling env.AI.run through
+// syscall/js.
+type JSBinding struct {
+	AI js.Value
+}
+
+// NewJSBinding wraps the Workers AI binding object, e.g. env.AI.
+func NewJSBinding(ai js.Value) *JSBinding {
+	return &JSBinding{AI: ai}
+}
+
+// Run implements AIBinding.
+func (b *JSBinding) Run(ctx context.Context, modelID, contentType string, input io.Reader) (io.ReadCloser, string, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var jsInput js.Value
+	if strings.HasPrefix(contentType, "application/json") {
+		jsInput = js.Global().Get("JSON").Call("parse", string(data))
+	} else {
+		jsInput = js.Global().Get("Uint8Array").New(len(data))
+		js.CopyBytesToJS(jsInput, data)
+	}
+
+	output, err := await(ctx, b.AI.Call("run", modelID, jsInput))
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case output.InstanceOf(js.Global().Get("ReadableStream")):
+		return &jsStreamReader{ctx: ctx, reader: output.Call("getReader")}, "text/event-stream", nil
+	case output.InstanceOf(js.Global().Get("Uint8Array")):
+		return io.NopCloser(bytes.NewReader(jsBytes(output))), "application/octet-stream", nil
+	case output.InstanceOf(js.Global().Get("ArrayBuffer")):
+		return io.NopCloser(bytes.NewReader(jsBytes(js.Global().Get("Uint8Array").New(output)))), "application/octet-stream", nil
+	default:
+		text := js.Global().Get("JSON").Call("stringify", output).String()
+		return io.NopCloser(strings.NewReader(text)), "application/json", nil
+	}
+}
+
+// await blocks until promise settles or ctx is done. The callbacks release
+// themselves once the promise settles, since the promise may still call them
+// after ctx is done.
+func await(ctx context.Context, promise js.Value) (js.Value, error) {
+	type settled struct {
+		value js.Value
+		err   error
+	}
+	done := make(chan settled, 1)
+
+	var onResolve, onReject js.Func
+	release := func() {
+		onResolve.Release()
+		onReject.Release()
+	}
+	onResolve = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		done <- settled{value: args[0]}
+		release()
+		return nil
+	})
+	onReject = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		done <- settled{err: errors.New(args[0].Call("toString").String())}
+		release()
+		return nil
+	})
+
+	promise.Call("then", onResolve, onReject)
+
+	select {
+	case s := <-done:
+		if s.err != nil {
+			return js.Undefined(), fmt.Errorf("binding call failed: %w", s.err)
+		}
+		return s.value, nil
+	case <-ctx.Done():
+		return js.Undefined(), ctx.Err()
+	}
+}
+
+func jsBytes(array js.Value) []byte {
+	data := make([]byte, array.Get("length").Int())
+	js.CopyBytesToGo(data, array)
+	return data
+}
+
+// jsStreamReader reads a JavaScript ReadableStream of bytes.
+type jsStreamReader struct {
+	ctx     context.Context
+	reader  js.Value
+	pending []byte
+	done    bool
+}
+
+func (r *jsStreamReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		result, err := await(r.ctx, r.reader.Call("read"))
+		if err != nil {
+			return 0, err
+		}
+		if result.Get("done").Bool() {
+			r.done = true
+			continue
+		}
+		r.pending = jsBytes(result.Get("value"))
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *jsStreamReader) Close() error {
+	if !r.done {
+		r.reader.Call("cancel")
+		r.done = true
+	}
+	return nil
+}
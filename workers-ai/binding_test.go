@@ -0,0 +1,66 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBinding answers like env.AI.run with a fixed output.
+type fakeBinding struct {
+	model      string
+	input      string
+	output     string
+	outputType string
+	err        error
+}
+
+func (b *fakeBinding) Run(ctx context.Context, modelID, contentType string, input io.Reader) (io.ReadCloser, string, error) {
+	data, _ := io.ReadAll(input)
+	b.model, b.input = modelID, string(data)
+	if b.err != nil {
+		return nil, "", b.err
+	}
+	return io.NopCloser(strings.NewReader(b.output)), b.outputType, nil
+}
+
+func TestBindingClient(t *testing.T) {
+	binding := &fakeBinding{output: `{"response": "Hi from the edge"}`, outputType: "application/json"}
+	client := NewBindingClient(binding)
+
+	resp, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi from the edge", resp.GetContent())
+	assert.Equal(t, ModelLlama38B, binding.model)
+
+	var request ChatCompletionRequest
+	require.NoError(t, json.Unmarshal([]byte(binding.input), &request))
+	assert.Equal(t, []Message{ChatMessage{Role: "user", Content: "Hello"}}, request.Messages)
+
+	binding.output, binding.outputType = "RIFF....", "audio/wav"
+	audio, err := client.TextToSpeech(ModelMeloTTS, "Hello", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF...."), audio)
+
+	binding.err = errors.New("model not found")
+	_, err = client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+	assert.ErrorContains(t, err, "model not found")
+}
+
+func TestBindingClient_Stream(t *testing.T) {
+	binding := &fakeBinding{
+		output:     "data: {\"response\": \"Hi\"}\n\ndata: {\"response\": \" there\"}\n\ndata: [DONE]\n\n",
+		outputType: "text/event-stream",
+	}
+	client := NewBindingClient(binding)
+
+	summary, err := client.ChatStreamFunc(context.Background(), ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil, nil, func(*StreamChunk) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", summary.Content)
+}
@@ -0,0 +1,209 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultMaxResponseSize is the size above which responses are rejected
+// when Client.MaxResponseSize is not set.
+const DefaultMaxResponseSize = 64 << 20
+
+// ErrResponseTooLarge is returned for responses larger than
+// Client.MaxResponseSize.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// maxPooledBuffer is the capacity above which buffers aren't returned to
+// bufferPool, so that a few huge responses don't stay in memory.
+const maxPooledBuffer = 4 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// putBuffer returns buf to bufferPool, unless it grew too large.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		buf.Reset()
+		bufferPool.Put(buf)
+	}
+}
+
+// requestBuffer is a pooled buffer holding a marshalled request. It goes
+// back to bufferPool once released by its owner and by every request body
+// reading it: the transport may still read a body after Do returned, and
+// closes it when done.
+type requestBuffer struct {
+	buf  *bytes.Buffer
+	refs atomic.Int32
+}
+
+func newRequestBuffer() *requestBuffer {
+	b := &requestBuffer{buf: bufferPool.Get().(*bytes.Buffer)}
+	b.refs.Store(1)
+	return b
+}
+
+// release drops a reference to b. It does nothing if b is nil.
+func (b *requestBuffer) release() {
+	if b != nil && b.refs.Add(-1) == 0 {
+		putBuffer(b.buf)
+	}
+}
+
+// attach makes the body of req, and those returned by its GetBody, hold a
+// reference to b until they are closed. It does nothing if b is nil.
+func (b *requestBuffer) attach(req *http.Request) {
+	if b == nil || req.Body == nil {
+		return
+	}
+	req.Body = b.hold(req.Body)
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return b.hold(body), nil
+		}
+	}
+}
+
+func (b *requestBuffer) hold(body io.ReadCloser) io.ReadCloser {
+	b.refs.Add(1)
+	return &heldBody{ReadCloser: body, buf: b}
+}
+
+// heldBody is a request body holding a reference to a requestBuffer.
+type heldBody struct {
+	io.ReadCloser
+	buf  *requestBuffer
+	once sync.Once
+}
+
+func (h *heldBody) Close() error {
+	err := h.ReadCloser.Close()
+	h.once.Do(h.buf.release)
+	return err
+}
+
+func (c *Client) maxResponseSize() int64 {
+	switch {
+	case c.MaxResponseSize < 0:
+		return -1
+	case c.MaxResponseSize == 0:
+		return DefaultMaxResponseSize
+	}
+	return c.MaxResponseSize
+}
+
+// readResponse reads the body of resp within the client's size limit.
+func (c *Client) readResponse(resp *http.Response) ([]byte, error) {
+	return readBody(resp.Body, resp.ContentLength, c.maxResponseSize())
+}
+
+// readBody reads r to the end like io.ReadAll, but allocates the result
+// only once. It is used for the responses the client needs as bytes: run
+// responses, which the hooks and the coalescing of requests share, and
+// binary ones; JSON responses are otherwise decoded with decodeBody.
+// When size (the Content-Length) is positive, r is read straight into a
+// slice of that size, otherwise through a pooled buffer. Bodies longer
+// than limit fail with ErrResponseTooLarge, without reading more than
+// limit bytes; a negative limit disables the check.
+func readBody(r io.Reader, size, limit int64) ([]byte, error) {
+	if limit >= 0 {
+		if size > limit {
+			return nil, tooLarge(limit)
+		}
+		r = io.LimitReader(r, limit+1)
+	}
+
+	if size > 0 {
+		body := make([]byte, size)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+		var extra [1]byte
+		n, _ := r.Read(extra[:])
+		if n == 0 {
+			return body, nil
+		}
+		// The transport announced a wrong length: read the rest as if
+		// it were unknown.
+		r = io.MultiReader(bytes.NewReader(body), bytes.NewReader(extra[:n]), r)
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer putBuffer(buf)
+
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	if limit >= 0 && int64(buf.Len()) > limit {
+		return nil, tooLarge(limit)
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// decodeResponse decodes the JSON body of resp into out as it is read,
+// without holding the body in memory, within the client's size limit. The
+// body is read first when Client.JSON is set, for the codec to unmarshal
+// it, or with Debug, to log it.
+func (c *Client) decodeResponse(resp *http.Response, out interface{}) error {
+	if c.JSON != nil || c.Debug {
+		body, err := c.readResponse(resp)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		c.debugLog("Response Body: %s", string(body))
+		if err := c.codec().Unmarshal(body, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		return nil
+	}
+	return decodeBody(resp.Body, resp.ContentLength, c.maxResponseSize(), out)
+}
+
+// decodeBody decodes the JSON value read from r into out with a
+// json.Decoder. size and limit are those of readBody: bodies longer than
+// limit fail with ErrResponseTooLarge, without reading more than limit
+// bytes.
+func decodeBody(r io.Reader, size, limit int64, out interface{}) error {
+	counter := &countingReader{r: r}
+	if limit >= 0 {
+		if size > limit {
+			return fmt.Errorf("failed to read response: %w", tooLarge(limit))
+		}
+		counter.r = io.LimitReader(r, limit+1)
+	}
+	err := json.NewDecoder(counter).Decode(out)
+	if limit >= 0 && counter.n > limit {
+		return fmt.Errorf("failed to read response: %w", tooLarge(limit))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func tooLarge(limit int64) error {
+	return fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+}
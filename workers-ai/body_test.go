@@ -0,0 +1,134 @@
+package workersai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBody(t *testing.T) {
+	first, err := readBody(strings.NewReader("first response"), -1, -1)
+	require.NoError(t, err)
+	second, err := readBody(strings.NewReader("second"), -1, -1)
+	require.NoError(t, err)
+
+	// The results must not share the pooled buffer.
+	assert.Equal(t, "first response", string(first))
+	assert.Equal(t, "second", string(second))
+
+	empty, err := readBody(strings.NewReader(""), -1, -1)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	sized, err := readBody(strings.NewReader("sized"), 5, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "sized", string(sized))
+
+	// A wrong Content-Length doesn't truncate the body.
+	longer, err := readBody(strings.NewReader("longer than announced"), 6, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "longer than announced", string(longer))
+}
+
+func TestReadBody_Limit(t *testing.T) {
+	_, err := readBody(strings.NewReader("0123456789"), 10, 5)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	_, err = readBody(strings.NewReader("0123456789"), -1, 5)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	_, err = readBody(strings.NewReader("0123456789"), 2, 5)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	body, err := readBody(strings.NewReader("01234"), -1, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "01234", string(body))
+}
+
+func TestDecodeBody(t *testing.T) {
+	var out struct {
+		Result []int `json:"result"`
+	}
+	require.NoError(t, decodeBody(strings.NewReader(`{"result": [1, 2]}`), -1, -1, &out))
+	assert.Equal(t, []int{1, 2}, out.Result)
+
+	err := decodeBody(strings.NewReader(`{"result": [1, 2, 3, 4]}`), -1, 10, &out)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	err = decodeBody(strings.NewReader(`{}`), 20, 10, &out)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	err = decodeBody(strings.NewReader(`{"result": `), -1, 100, &out)
+	assert.ErrorContains(t, err, "failed to parse response")
+	assert.NotErrorIs(t, err, ErrResponseTooLarge)
+}
+
+func TestClient_MaxResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q}}`, strings.Repeat("a", 1000))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.MaxResponseSize = 512
+
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+	_, err := client.Chat(ModelLlama38B, messages, nil)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	client.MaxResponseSize = -1
+	response, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Len(t, response.GetContent(), 1000)
+
+	// The responses of the account API are decoded as they are read.
+	client.MaxResponseSize = 512
+	_, err = client.apiGet(context.Background(), "/accounts/test-account/ai/models/search", nil)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	client.MaxResponseSize = 0
+	var result struct {
+		Response string `json:"response"`
+	}
+	_, err = client.apiGet(context.Background(), "/accounts/test-account/ai/models/search", &result)
+	require.NoError(t, err)
+	assert.Len(t, result.Response, 1000)
+}
+
+// BenchmarkReadBody compares reading a 22 KB response with io.ReadAll and
+// with readBody, with and without a Content-Length. Run with -benchmem.
+func BenchmarkReadBody(b *testing.B) {
+	body := benchmarkResponse()
+
+	b.Run("ReadAll", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := io.ReadAll(bytes.NewReader(body)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := readBody(bytes.NewReader(body), -1, DefaultMaxResponseSize); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Sized", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := readBody(bytes.NewReader(body), int64(len(body)), DefaultMaxResponseSize); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
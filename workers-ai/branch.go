@@ -0,0 +1,73 @@
+package workersai
+
+import "fmt"
+
+// Fork starts a branch of the conversation with its first at messages, to
+// explore another continuation from there, e.g. for a UI comparing
+// replies. The branch has the settings of s, no usage yet, and is added to
+// s.Branches under label. at ranges from 0 to len(s.Messages).
+func (s *ChatSession) Fork(at int, label string) (*ChatSession, error) {
+	if at < 0 || at > len(s.Messages) {
+		return nil, fmt.Errorf("cannot fork at message %d of %d", at, len(s.Messages))
+	}
+
+	branch := &ChatSession{
+		Client:       s.Client,
+		Model:        s.Model,
+		SystemPrompt: s.SystemPrompt,
+		Tools:        s.Tools,
+		Messages:     append([]Message(nil), s.Messages[:at]...),
+		Label:        label,
+		Parent:       s,
+		ForkedAt:     at,
+	}
+	if s.ModelParameters != nil {
+		params := *s.ModelParameters
+		branch.ModelParameters = &params
+	}
+	s.Branches = append(s.Branches, branch)
+	return branch, nil
+}
+
+// Root returns the session the conversation started with.
+func (s *ChatSession) Root() *ChatSession {
+	for s.Parent != nil {
+		s = s.Parent
+	}
+	return s
+}
+
+// Branch returns the first session labelled label in the tree of branches
+// below s, depth first, or nil if there is none.
+func (s *ChatSession) Branch(label string) *ChatSession {
+	for _, b := range s.Branches {
+		if b.Label == label {
+			return b
+		}
+		if found := b.Branch(label); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Alternatives returns the branches forked from s at message at: the other
+// continuations of the conversation from that point.
+func (s *ChatSession) Alternatives(at int) []*ChatSession {
+	var alternatives []*ChatSession
+	for _, b := range s.Branches {
+		if b.ForkedAt == at {
+			alternatives = append(alternatives, b)
+		}
+	}
+	return alternatives
+}
+
+// Path returns the labels of the branches leading from the root to s.
+func (s *ChatSession) Path() []string {
+	var path []string
+	for ; s.Parent != nil; s = s.Parent {
+		path = append([]string{s.Label}, path...)
+	}
+	return path
+}
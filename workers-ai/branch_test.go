@@ -0,0 +1,61 @@
+package workersai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatSession_Fork(t *testing.T) {
+	client := &scriptedClient{responses: []*ChatResponse{
+		textResponse("Paris", Usage{TotalTokens: 10}),
+		textResponse("About 2 million", Usage{TotalTokens: 12}),
+		textResponse("Lyon", Usage{TotalTokens: 8}),
+		textResponse("The Eiffel Tower", Usage{TotalTokens: 9}),
+	}}
+
+	session := NewChatSession(client, ModelLlama38B)
+	session.SystemPrompt = "Be brief."
+	session.ModelParameters = &ModelParameters{}
+	_, err := session.Send("Capital of France?")
+	require.NoError(t, err)
+	_, err = session.Send("Population?")
+	require.NoError(t, err)
+
+	// Ask another first question.
+	other, err := session.Fork(0, "second city")
+	require.NoError(t, err)
+	assert.Empty(t, other.Messages)
+	_, err = other.Send("Second city of France?")
+	require.NoError(t, err)
+
+	// Ask another follow-up.
+	sights, err := session.Fork(2, "sights")
+	require.NoError(t, err)
+	_, err = sights.Send("What to see?")
+	require.NoError(t, err)
+
+	assert.Len(t, session.Messages, 4)
+	assert.Equal(t, Usage{TotalTokens: 22}, session.Usage)
+	assert.Equal(t, Usage{TotalTokens: 9}, sights.Usage)
+	assert.Equal(t, []Message{
+		ChatMessage{Role: "system", Content: "Be brief."},
+		ChatMessage{Role: "user", Content: "Capital of France?"},
+		ChatMessage{Role: "assistant", Content: "Paris"},
+		ChatMessage{Role: "user", Content: "What to see?"},
+	}, client.calls[3])
+	assert.NotSame(t, session.ModelParameters, sights.ModelParameters)
+
+	nested, err := sights.Fork(3, "nested")
+	require.NoError(t, err)
+	assert.Same(t, session, nested.Root())
+	assert.Same(t, nested, session.Branch("nested"))
+	assert.Nil(t, session.Branch("missing"))
+	assert.Equal(t, []string{"sights", "nested"}, nested.Path())
+	assert.Equal(t, []*ChatSession{other}, session.Alternatives(0))
+	assert.Equal(t, []*ChatSession{sights}, session.Alternatives(2))
+
+	_, err = session.Fork(5, "too far")
+	assert.Error(t, err)
+}
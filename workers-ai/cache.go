@@ -0,0 +1,91 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultGatewayBaseURL is the base URL of Cloudflare AI Gateway.
+const DefaultGatewayBaseURL = "https://gateway.ai.cloudflare.com/v1"
+
+// AI Gateway cache statuses reported in ChatResponse.CacheStatus.
+const (
+	CacheHit  = "HIT"
+	CacheMiss = "MISS"
+)
+
+const (
+	cacheStatusHeader = "cf-aig-cache-status"
+	cacheTTLHeader    = "cf-aig-cache-ttl"
+	cacheSkipHeader   = "cf-aig-skip-cache"
+	cacheKeyHeader    = "cf-aig-cache-key"
+)
+
+// CacheOptions control how AI Gateway caches a response.
+type CacheOptions struct {
+	// TTL is how long the response is cached, with second precision. Zero
+	// keeps the gateway's setting.
+	TTL time.Duration
+	// Skip bypasses the cache for the request.
+	Skip bool
+	// Key replaces the cache key the gateway derives from the request.
+	Key string
+}
+
+// UseGateway routes the client's requests through the AI Gateway
+// gatewayID, which enables response caching.
+func (c *Client) UseGateway(gatewayID string) {
+	c.BaseURL = DefaultGatewayBaseURL
+	c.Gateway = gatewayID
+}
+
+// GatewaySettings are the settings of an AI Gateway, as returned by
+// GetGateway.
+type GatewaySettings struct {
+	ID string `json:"id"`
+	// CacheTTL is the default cache TTL of the responses in seconds, 0
+	// when caching is disabled.
+	CacheTTL    int  `json:"cache_ttl"`
+	CollectLogs bool `json:"collect_logs"`
+	// RateLimitingLimit requests are allowed per RateLimitingInterval
+	// seconds, when both are set.
+	RateLimitingLimit    int `json:"rate_limiting_limit"`
+	RateLimitingInterval int `json:"rate_limiting_interval"`
+	// Authentication requires the requests to carry a gateway token,
+	// which the client doesn't send.
+	Authentication bool `json:"authentication"`
+}
+
+// GetGateway returns the settings of the AI Gateway gatewayID of the
+// account. The token needs the AI Gateway Read permission.
+func (c *Client) GetGateway(ctx context.Context, gatewayID string) (*GatewaySettings, error) {
+	var settings GatewaySettings
+	path := fmt.Sprintf("/accounts/%s/ai-gateway/gateways/%s", c.AccountID, url.PathEscape(gatewayID))
+	if _, err := c.apiGet(ctx, path, &settings); err != nil {
+		return nil, fmt.Errorf("failed to get gateway %s: %w", gatewayID, err)
+	}
+	return &settings, nil
+}
+
+// cacheHeader returns the headers for opts, or for c.Cache when opts is nil.
+func (c *Client) cacheHeader(opts *CacheOptions) http.Header {
+	if opts == nil {
+		opts = &c.Cache
+	}
+
+	header := http.Header{}
+	if opts.TTL > 0 {
+		header.Set(cacheTTLHeader, strconv.Itoa(int(opts.TTL.Seconds())))
+	}
+	if opts.Skip {
+		header.Set(cacheSkipHeader, "true")
+	}
+	if opts.Key != "" {
+		header.Set(cacheKeyHeader, opts.Key)
+	}
+	return header
+}
@@ -0,0 +1,85 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GatewayCache(t *testing.T) {
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/test-account/my-gateway/workers-ai/@cf/meta/llama-3-8b-instruct", r.URL.Path)
+		headers = append(headers, r.Header.Clone())
+
+		status := CacheMiss
+		if len(headers) > 1 {
+			status = CacheHit
+		}
+		w.Header().Set("cf-aig-cache-status", status)
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.UseGateway("my-gateway")
+	client.BaseURL = server.URL
+	client.Cache = CacheOptions{TTL: time.Hour}
+
+	var events []RequestEvent
+	client.Use(Hooks{AfterResponse: func(event RequestEvent) { events = append(events, event) }})
+
+	messages := []Message{ChatMessage{Role: "user", Content: "Hello"}}
+	resp, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus)
+
+	resp, err = client.ChatCompletion(ChatCompletionRequest{
+		Model:    ModelLlama38B,
+		Messages: messages,
+		Cache:    &CacheOptions{Skip: true, Key: "greeting"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, resp.CacheStatus)
+
+	require.Len(t, headers, 2)
+	assert.Equal(t, "3600", headers[0].Get("cf-aig-cache-ttl"))
+	assert.Empty(t, headers[0].Get("cf-aig-skip-cache"))
+	assert.Empty(t, headers[1].Get("cf-aig-cache-ttl"))
+	assert.Equal(t, "true", headers[1].Get("cf-aig-skip-cache"))
+	assert.Equal(t, "greeting", headers[1].Get("cf-aig-cache-key"))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, CacheMiss, events[0].CacheStatus)
+	assert.Equal(t, CacheHit, events[1].CacheStatus)
+}
+
+func TestClient_GetGateway(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/ai-gateway/gateways/my-gateway" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"success": false, "errors": [{"code": 7002, "message": "Not Found"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"id": "my-gateway", "cache_ttl": 300, "collect_logs": true, "rate_limiting_limit": 10, "rate_limiting_interval": 60, "authentication": true}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	settings, err := client.GetGateway(context.Background(), "my-gateway")
+	require.NoError(t, err)
+	assert.Equal(t, &GatewaySettings{ID: "my-gateway", CacheTTL: 300, CollectLogs: true, RateLimitingLimit: 10, RateLimitingInterval: 60, Authentication: true}, settings)
+
+	_, err = client.GetGateway(context.Background(), "other")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
@@ -0,0 +1,145 @@
+package workersai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ChoiceOptions configures Choose.
+type ChoiceOptions struct {
+	// MaxRetries caps the number of re-prompts after a reply that isn't
+	// one of the options. Defaults to DefaultStructuredRetries; set it to
+	// -1 to disable retries.
+	MaxRetries int
+	// JSONMode constrains the reply with an enum JSON schema. When nil, it
+	// is used for the models DefaultCatalog lists with JSONMode.
+	JSONMode *bool
+}
+
+// Choose asks the model to answer request with exactly one of options,
+// such as yes/no or a list of categories, and returns it. Models in JSON
+// mode are constrained with an enum schema. The replies of the others are
+// matched against the options ignoring case, quotes and trailing
+// punctuation, and the model is asked again when none matches.
+//
+// The usage of the returned response covers all attempts. When no reply
+// matched, the error is an *InvalidOutputError.
+func Choose[T ~string](client ClientInterface, request ChatCompletionRequest, options []T, opts ChoiceOptions) (T, *ChatResponse, error) {
+	var zero T
+	if len(options) == 0 {
+		return zero, nil, errors.New("no options to choose from")
+	}
+	names := make([]string, len(options))
+	for i, option := range options {
+		names[i] = string(option)
+	}
+
+	jsonMode := supportsJSONMode(request.Model)
+	if opts.JSONMode != nil {
+		jsonMode = *opts.JSONMode
+	}
+	instruction := fmt.Sprintf("Answer with exactly one of: %s. Reply with the answer only.", strings.Join(names, ", "))
+	if jsonMode {
+		instruction = fmt.Sprintf(`Reply with a JSON object {"answer": ...} whose answer is exactly one of: %s.`, strings.Join(names, ", "))
+		request.ResponseFormat = &OutputFormat{Type: "json_schema", JSONSchema: choiceSchema(names)}
+	}
+
+	messages := withSystemInstruction(request.Messages, instruction)
+	logf := clientLogf(client)
+	attempts := structuredAttempts(opts.MaxRetries)
+	invalid := &InvalidOutputError{}
+	var usage Usage
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		request.Messages = messages
+
+		resp, err := client.ChatCompletion(request)
+		if err != nil {
+			return zero, nil, err
+		}
+		u := resp.GetUsage()
+		usage.PromptTokens += u.PromptTokens
+		usage.CompletionTokens += u.CompletionTokens
+		usage.TotalTokens += u.TotalTokens
+
+		content := resp.GetContent()
+		i, err := matchChoice(content, names)
+		if err == nil {
+			resp.setUsage(usage)
+			return options[i], resp, nil
+		}
+
+		logf("Invalid choice (attempt %d): %v", attempt+1, err)
+		invalid.Content = content
+		invalid.Errors = append(invalid.Errors, err)
+
+		messages = append(messages,
+			ChatMessage{Role: "assistant", Content: content},
+			ChatMessage{Role: "user", Content: fmt.Sprintf("Your reply was invalid: %v. %s", err, instruction)},
+		)
+	}
+
+	return zero, nil, invalid
+}
+
+// supportsJSONMode reports whether DefaultCatalog lists modelID with
+// JSONMode.
+func supportsJSONMode(modelID string) bool {
+	for _, spec := range DefaultCatalog {
+		if spec.ID == modelID {
+			return spec.JSONMode
+		}
+	}
+	return false
+}
+
+// choiceSchema is the schema of an object whose "answer" is one of names.
+func choiceSchema(names []string) *FunctionParameters {
+	return &FunctionParameters{
+		Type: "object",
+		Properties: map[string]*Parameter{
+			"answer": {Type: "string", Enum: names},
+		},
+		Required: []string{"answer"},
+	}
+}
+
+// withSystemInstruction returns a copy of messages with instruction
+// appended to the system prompt, which is added if there is none.
+func withSystemInstruction(messages []Message, instruction string) []Message {
+	result := make([]Message, 0, len(messages)+1)
+	if hasSystemMessage(messages) {
+		system := messages[0].(ChatMessage)
+		system.Content += "\n\n" + instruction
+		result = append(result, system)
+		messages = messages[1:]
+	} else {
+		result = append(result, ChatMessage{Role: "system", Content: instruction})
+	}
+	return append(result, messages...)
+}
+
+// matchChoice returns the index of the option content answers with, given
+// as the text of the reply or as the "answer" of a JSON object.
+func matchChoice(content string, options []string) (int, error) {
+	answer := content
+	var object struct {
+		Answer *string `json:"answer"`
+	}
+	if err := json.Unmarshal([]byte(extractJSON(content)), &object); err == nil && object.Answer != nil {
+		answer = *object.Answer
+	}
+
+	normalize := func(s string) string {
+		return strings.ToLower(strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "\"'`*.!")))
+	}
+	answer = normalize(answer)
+	for i, option := range options {
+		if normalize(option) == answer {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%q is not one of %s", strings.TrimSpace(content), strings.Join(options, ", "))
+}
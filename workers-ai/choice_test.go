@@ -0,0 +1,110 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sentiment string
+
+const (
+	positive sentiment = "positive"
+	negative sentiment = "negative"
+)
+
+func TestChoose(t *testing.T) {
+	var requests []map[string]interface{}
+	replies := []string{"I think it is positive overall", `"Negative."`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q, "usage": {"total_tokens": 10}}}`, replies[len(requests)-1])
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	choice, resp, err := Choose(client, ChatCompletionRequest{
+		Model:    ModelMistral7B,
+		Messages: []Message{ChatMessage{Role: "user", Content: "Sentiment of: 'awful service'"}},
+	}, []sentiment{positive, negative}, ChoiceOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, negative, choice)
+	assert.Equal(t, 20, resp.GetUsage().TotalTokens)
+
+	require.Len(t, requests, 2)
+	assert.NotContains(t, requests[0], "response_format")
+	messages := requests[1]["messages"].([]interface{})
+	require.Len(t, messages, 4)
+	assert.Contains(t, messages[0].(map[string]interface{})["content"], "exactly one of: positive, negative")
+	assert.Contains(t, messages[3].(map[string]interface{})["content"], `"I think it is positive overall" is not one of positive, negative`)
+}
+
+func TestChoose_JSONMode(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"success": true, "result": {"response": "{\"answer\": \"yes\"}"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	// Any ClientInterface will do, e.g. a recorder.
+	recorder := NewRecorder(client)
+	choice, _, err := Choose(recorder, ChatCompletionRequest{
+		Model: ModelLlama38B,
+		Messages: []Message{
+			ChatMessage{Role: "system", Content: "You are a classifier."},
+			ChatMessage{Role: "user", Content: "Is Paris in France?"},
+		},
+	}, []string{"yes", "no"}, ChoiceOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "yes", choice)
+	assert.Len(t, recorder.Trace().Interactions, 1)
+
+	assert.Equal(t, map[string]interface{}{
+		"type": "json_schema",
+		"json_schema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"answer": map[string]interface{}{"type": "string", "enum": []interface{}{"yes", "no"}},
+			},
+			"required": []interface{}{"answer"},
+		},
+	}, request["response_format"])
+	messages := request["messages"].([]interface{})
+	require.Len(t, messages, 2)
+	assert.Contains(t, messages[0].(map[string]interface{})["content"], "You are a classifier.\n\nReply with a JSON object")
+}
+
+func TestChoose_Invalid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": true, "result": {"response": "maybe"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	_, _, err := Choose(client, ChatCompletionRequest{
+		Model:    ModelMistral7B,
+		Messages: []Message{ChatMessage{Role: "user", Content: "Is it raining?"}},
+	}, []string{"yes", "no"}, ChoiceOptions{MaxRetries: 1})
+	var invalid *InvalidOutputError
+	require.ErrorAs(t, err, &invalid)
+	assert.Len(t, invalid.Errors, 2)
+	assert.Equal(t, "maybe", invalid.Content)
+
+	_, _, err = Choose(client, ChatCompletionRequest{Model: ModelMistral7B}, []string{}, ChoiceOptions{})
+	assert.Error(t, err)
+}
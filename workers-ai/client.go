@@ -2,13 +2,15 @@ package workersai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -21,6 +23,85 @@ type Client struct {
 	APIToken   string
 	HTTPClient *http.Client
 	Debug      bool
+
+	// Defaults are applied to every chat request.
+	Defaults ChatDefaults
+	// ResponseFilters inspect chat responses before they are returned.
+	ResponseFilters []ResponseFilter
+	// AutoContinue continues replies cut off at the token limit.
+	AutoContinue AutoContinue
+	// ToolEmulation emulates function calling for models without native
+	// tool support.
+	ToolEmulation ToolEmulation
+	// Templates maps model IDs to the chat template rendered client-side
+	// for them. Requests to these models are sent as raw prompts, so that
+	// base models without a template of their own can be used for chat.
+	// Streaming requests are sent as they are.
+	Templates map[string]ChatTemplate
+	// OverflowRecovery shortens and resends conversations exceeding the
+	// context window of the model.
+	OverflowRecovery OverflowRecovery
+
+	// Cache sets the AI Gateway cache options of requests that don't set
+	// their own. It only has an effect when requests go through a gateway.
+	Cache CacheOptions
+	// Gateway is the ID of the AI Gateway requests are routed through, if
+	// any. See UseGateway.
+	Gateway string
+	// Retry configures the retries of failed requests. Streaming requests
+	// are not retried.
+	Retry RetryPolicy
+	// Scheduler, if set, queues requests by priority to stay within a rate
+	// and concurrency limit.
+	Scheduler *Scheduler
+	// Hedging sends a duplicate of slow chat requests to cut tail latency.
+	Hedging Hedging
+	// DecodeMode selects whether chat responses of unexpected shape are
+	// decoded leniently, the default, or rejected.
+	DecodeMode DecodeMode
+	// Compatibility selects the chat endpoint and the response formats
+	// accepted. Defaults to CompatLegacy.
+	Compatibility CompatibilityLevel
+	// Pricing overrides and extends DefaultPricing in EstimateCost.
+	Pricing map[string]ModelPrice
+	// NeuronGuard, if set, refuses requests once the account used too many
+	// neurons today.
+	NeuronGuard *NeuronGuard
+
+	// MaxResponseSize caps the size of response bodies, in bytes, to
+	// protect memory. Defaults to DefaultMaxResponseSize; a negative value
+	// disables it.
+	MaxResponseSize int64
+	// Compression configures gzip request bodies and the content encodings
+	// accepted in responses.
+	Compression Compression
+	// Signer, if set, signs every request right before it is sent.
+	Signer Signer
+	// Coalesce sends identical concurrent requests, such as chat or
+	// embedding requests repeated by impatient users, only once and shares
+	// the response. The shared request is cancelled once the contexts of
+	// all the callers waiting for it are.
+	Coalesce bool
+	// Shadow mirrors a share of the chat requests to a candidate model.
+	Shadow Shadow
+	// JSON is the codec of request and response bodies. Defaults to
+	// StdJSON.
+	JSON JSONCodec
+	// FieldAliases are alternate names of the fields of chat responses and
+	// stream chunks, mapped to the names of the documented formats, e.g.
+	// {"stopReason": "finish_reason"}. They extend the built-in camelCase
+	// aliases, like toolCalls for tool_calls.
+	FieldAliases map[string]string
+
+	// StreamIdleTimeout aborts a streaming response when nothing, not even
+	// a keep-alive comment, was received for that long. Zero disables it.
+	StreamIdleTimeout time.Duration
+
+	hooks   []Hooks
+	flights flightGroup
+	formats formatCounts
+	// shadows counts the mirrored requests in flight.
+	shadows atomic.Int64
 }
 
 // Message is an interface implemented by all message types that can be sent to the API.
@@ -34,7 +115,7 @@ func NewClient(accountID, apiToken string) *Client {
 		BaseURL:    DefaultBaseURL,
 		AccountID:  accountID,
 		APIToken:   apiToken,
-		HTTPClient: &http.Client{},
+		HTTPClient: &http.Client{Transport: NewTransport()},
 		Debug:      os.Getenv("WORKERS_AI_DEBUG") == "true",
 	}
 }
@@ -48,68 +129,144 @@ func (c *Client) Chat(modelID string, messages []Message, modelParams *ModelPara
 }
 
 func (c *Client) ChatWithTools(modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) (*ChatResponse, error) {
-	var url string
-	if strings.HasPrefix(modelID, "@cf/") {
-		url = fmt.Sprintf("%s/accounts/%s/ai/run/%s", c.BaseURL, c.AccountID, modelID)
-	} else {
-		url = fmt.Sprintf("%s/accounts/%s/ai/run/@cf/%s", c.BaseURL, c.AccountID, modelID)
-	}
+	return c.ChatCompletion(chatRequest(modelID, messages, tools, modelParams))
+}
 
-	request := ChatCompletionRequest{
-		Model:    modelID, // The model is part of the request body in the standard spec.
-		Messages: messages,
-		Tools:    tools,
-		Stream:   false,
+// ChatCompletion sends a fully built request to the model named in
+// request.Model, after applying the client's Defaults. Chat and ChatWithTools
+// are built on it; it is also useful to re-send a request captured from the
+// debug logs.
+func (c *Client) ChatCompletion(request ChatCompletionRequest) (*ChatResponse, error) {
+	return c.ChatCompletionContext(context.Background(), request)
+}
+
+// ChatCompletionContext is ChatCompletion with a context; cancelling ctx
+// aborts the request.
+func (c *Client) ChatCompletionContext(ctx context.Context, request ChatCompletionRequest) (*ChatResponse, error) {
+	if request.Model == "" {
+		return nil, fmt.Errorf("request has no model")
 	}
 
-	if modelParams != nil {
-		request.ModelParameters = *modelParams
+	ctx = request.tagContext(ctx)
+	c.applyDefaults(&request)
+	if request.Language != nil && request.Language.Language != "" {
+		request.Messages = withLanguageInstruction(request.Messages, request.Language.Language)
 	}
+	original := request
 
-	jsonData, err := json.Marshal(request)
+	start := time.Now()
+	response, err := c.completeChat(ctx, request)
+	if err == nil && request.Language != nil && request.Language.Language != "" {
+		response, err = c.enforceLanguage(ctx, request, response, c.completeChat)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	c.debugLog("Request URL: %s", url)
-	c.debugLog("Request Body: %s", string(jsonData))
+	response.latency = time.Since(start)
+	c.mirror(ctx, original, response, response.latency)
+
+	return response, nil
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+// completeChat runs request through tool emulation, overflow recovery,
+// continuations and filters.
+func (c *Client) completeChat(ctx context.Context, request ChatCompletionRequest) (*ChatResponse, error) {
+	tools := request.Tools
+	emulated := len(tools) > 0 && c.emulatesTools(request.Model)
+	if emulated {
+		request = emulateToolRequest(request)
+	}
+
+	response, err := c.hedgedComplete(ctx, request)
+	if err != nil && c.OverflowRecovery.Strategy != OverflowNone && IsContextOverflow(err) {
+		request, response, err = c.recoverOverflow(ctx, request, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIToken))
-	req.Header.Set("Content-Type", "application/json")
+	if emulated {
+		parseEmulatedToolCall(response, tools, len(request.Messages))
+	}
 
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	if err := c.continueTruncated(ctx, request, response); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	if err := c.applyFilters(response); err != nil {
+		return nil, err
 	}
+	return response, nil
+}
 
-	c.debugLog("Response Body: %s", string(body))
+// complete sends a single chat request and parses the response.
+func (c *Client) complete(ctx context.Context, request ChatCompletionRequest) (*ChatResponse, error) {
+	chat := withPrefill(request)
+	opts := sendOptions{
+		header:   c.cacheHeader(request.Cache),
+		priority: request.Priority,
+	}
+	template, templated := c.template(request.Model)
+	openAI := c.Compatibility == CompatOpenAI && !templated
+	if openAI {
+		chat.Model = openAIModel(chat.Model)
+		opts.url = c.chatURL(request.Model)
+	}
+	var payload interface{} = chat
+	if templated {
+		raw, err := rawPrompt(request, template)
+		if err != nil {
+			return nil, err
+		}
+		raw.Prompt += request.Prefill
+		payload = raw
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		c.debugLog("API Error - Status: %d, Body: %s", resp.StatusCode, string(body))
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	jsonData, buffer, err := c.marshalRequest(withZeroParams(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	opts.buffer = buffer
+
+	start := time.Now()
+	body, header, err := c.send(ctx, request.Model, "application/json", jsonData, opts)
+	buffer.release()
+	if err != nil {
+		return nil, err
+	}
+	if openAI {
+		// The OpenAI-compatible endpoint returns the result bare.
+		body = wrapResult(body)
 	}
 
 	c.debugLog("Starting JSON unmarshal...")
 
-	var response ChatResponse
+	response := ChatResponse{aliases: c.fieldAliases(), codec: c.codec()}
 
-	if err := json.Unmarshal(body, &response); err != nil {
+	if err := c.codec().Unmarshal(body, &response); err != nil {
 		c.debugLog("JSON unmarshal failed: %v", err)
 		return nil, fmt.Errorf("failed to parse ChatResponse: %w", err)
 	}
+	response.aliases, response.codec = nil, nil
 
-	c.debugLog("Successfully parsed response. Detected legacy format: %v", response.IsLegacyResult)
+	c.debugLog("Successfully parsed response. Detected format: %s", response.Format)
+	c.checkFormat(&response)
+
+	if err := c.checkDecode(&response); err != nil {
+		return nil, err
+	}
+
+	if templated {
+		template.cutStop(&response)
+	}
+	if request.Prefill != "" && len(response.GetToolCalls()) == 0 {
+		response.setContent(MergePrefill(request.Prefill, response.GetContent()))
+	}
+
+	response.CacheStatus = header.Get(cacheStatusHeader)
+	response.model = request.Model
+	response.latency = time.Since(start)
 
 	return &response, nil
 }
@@ -129,6 +286,14 @@ func (r *ChatResponse) GetContent() string {
 	return ""
 }
 
+// GetUsage returns the token usage from the response, abstracting away the format differences.
+func (r *ChatResponse) GetUsage() Usage {
+	if r.IsLegacyResult {
+		return r.LegacyResponse.Usage
+	}
+	return r.ChatCompletionResponse.Usage
+}
+
 func (r *ChatResponse) GetReasoningContent() string {
 	if len(r.ChatCompletionResponse.Choices) > 0 {
 		return r.ChatCompletionResponse.Choices[0].Message.ReasoningContent
@@ -166,72 +331,258 @@ func (r *ChatResponse) GetToolCalls() []ToolCall {
 	return nil
 }
 
+// ListModels returns the models of the catalog available to the account,
+// going through every page of the models search.
 func (c *Client) ListModels() ([]ModelInfo, error) {
-	url := "https://ai.cloudflare.com/api/models"
+	var models []ModelInfo
+	for page := 1; ; page++ {
+		var summaries []ModelSummary
+		path := fmt.Sprintf("/accounts/%s/ai/models/search?page=%d&per_page=%d", c.AccountID, page, DefaultModelsPerPage)
+		if _, err := c.apiGet(context.Background(), path, &summaries); err != nil {
+			return nil, err
+		}
+		for _, summary := range summaries {
+			models = append(models, summary.modelInfo())
+		}
+		if len(summaries) < DefaultModelsPerPage {
+			return models, nil
+		}
+	}
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// GetModelInfo returns the description of a model from the Cloudflare API,
+// which is reached directly even when requests go through an AI Gateway.
+func (c *Client) GetModelInfo(modelID string) (*ModelInfo, error) {
+	var modelInfo ModelInfo
+	if _, err := c.apiGet(context.Background(), fmt.Sprintf("/accounts/%s/ai/models/%s", c.AccountID, modelID), &modelInfo); err != nil {
+		return nil, err
 	}
+	return &modelInfo, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
+// runURL returns the /ai/run endpoint for modelID, adding the "@cf/" prefix
+// when it is missing. With a Gateway, the gateway's Workers AI endpoint is
+// used instead.
+func (c *Client) runURL(modelID string) string {
+	if !strings.HasPrefix(modelID, "@cf/") {
+		modelID = "@cf/" + modelID
+	}
+	if c.Gateway != "" {
+		return fmt.Sprintf("%s/%s/%s/workers-ai/%s", c.BaseURL, c.AccountID, c.Gateway, modelID)
+	}
+	return fmt.Sprintf("%s/accounts/%s/ai/run/%s", c.BaseURL, c.AccountID, modelID)
+}
 
-	resp, err := c.HTTPClient.Do(req)
+// run posts payload to the /ai/run endpoint of modelID and unmarshals the
+// "result" field of the response envelope into out, along with the
+// request's metadata for task results implementing Result. It is used by the task
+// specific helpers that don't need the chat response adapter.
+func (c *Client) run(modelID string, payload interface{}, out interface{}) error {
+	return c.runContext(context.Background(), modelID, payload, out)
+}
+
+// runContext is run with a context; cancelling ctx aborts the request.
+func (c *Client) runContext(ctx context.Context, modelID string, payload interface{}, out interface{}) error {
+	start := time.Now()
+	body, _, err := c.runJSON(ctx, modelID, payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if err := c.decodeResult(body, out); err != nil {
+		return err
+	}
+	if result, ok := out.(resultMetaSetter); ok {
+		result.setResultMeta(newResultMeta(modelID, time.Since(start), body))
+	}
+	return nil
+}
+
+// runJSON marshals payload and posts it to the /ai/run endpoint of modelID.
+func (c *Client) runJSON(ctx context.Context, modelID string, payload interface{}) ([]byte, string, error) {
+	jsonData, buffer, err := c.marshalRequest(payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, "", fmt.Errorf("failed to marshal request: %w", err)
 	}
+	defer buffer.release()
+	respBody, respHeader, err := c.send(ctx, modelID, "application/json", jsonData, sendOptions{buffer: buffer})
+	if err != nil {
+		return nil, "", err
+	}
+	return respBody, respHeader.Get("Content-Type"), nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+// runRaw posts body to the /ai/run endpoint of modelID and returns the raw
+// response body along with its content type. Tasks that take binary input
+// (audio) or produce binary output (speech, images) use it directly.
+func (c *Client) runRaw(modelID, contentType string, body []byte) ([]byte, string, error) {
+	respBody, respHeader, err := c.send(context.Background(), modelID, contentType, body, sendOptions{})
+	if err != nil {
+		return nil, "", err
 	}
+	return respBody, respHeader.Get("Content-Type"), nil
+}
 
-	// The fix is here: Unmarshal directly into a slice of ModelInfo.
-	var models ModelsResponse
-	if err := json.Unmarshal(body, &models); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+// sendOptions are the per-request settings of send.
+type sendOptions struct {
+	// header is added to the request headers.
+	header   http.Header
+	priority Priority
+	// url overrides the /ai/run endpoint of the model.
+	url string
+	// buffer, if set, holds the body; the requests reading it hold it
+	// until closed.
+	buffer *requestBuffer
+}
+
+// send posts body to the /ai/run endpoint of modelID and returns the raw
+// response body and headers. Failed attempts are retried according to
+// c.Retry. With c.Coalesce, identical concurrent requests are sent once.
+func (c *Client) send(ctx context.Context, modelID, contentType string, body []byte, opts sendOptions) ([]byte, http.Header, error) {
+	if c.Coalesce {
+		key := flightKey(ctx, modelID, contentType, body, opts)
+		return c.flights.do(ctx, key, func(ctx context.Context) ([]byte, http.Header, error) {
+			return c.sendRetrying(ctx, modelID, contentType, body, opts)
+		})
 	}
+	return c.sendRetrying(ctx, modelID, contentType, body, opts)
+}
+
+// sendRetrying sends body until it succeeds or c.Retry gives up.
+func (c *Client) sendRetrying(ctx context.Context, modelID, contentType string, body []byte, opts sendOptions) ([]byte, http.Header, error) {
+	for attempt := 0; ; attempt++ {
+		respBody, respHeader, err := c.sendOnce(ctx, modelID, contentType, body, opts)
+		if err == nil || attempt >= c.Retry.MaxRetries || !IsRetryable(err) {
+			return respBody, respHeader, err
+		}
 
-	return nil, nil
+		wait := c.Retry.backoff(attempt, err)
+		c.debugLog("Request failed, retrying in %v (%d/%d): %v", wait, attempt+1, c.Retry.MaxRetries, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, err
+		}
+	}
 }
 
-func (c *Client) GetModelInfo(modelID string) (*ModelInfo, error) {
-	url := fmt.Sprintf("%s/accounts/%s/ai/models/%s", c.BaseURL, c.AccountID, modelID)
+// sendOnce makes a single attempt of send.
+func (c *Client) sendOnce(ctx context.Context, modelID, contentType string, body []byte, opts sendOptions) (respBody []byte, respHeader http.Header, err error) {
+	if err := c.checkNeurons(ctx); err != nil {
+		return nil, nil, err
+	}
+	if c.Scheduler != nil {
+		release, err := c.Scheduler.Acquire(ctx, opts.priority)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer release()
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	event := RequestEvent{Model: modelID}
+	defer func() {
+		event.Err = err
+		if err == nil && c.NeuronGuard != nil {
+			c.recordNeurons(modelID, responseUsage(respBody))
+		}
+		c.afterResponse(event, respBody)
+	}()
+
+	url := opts.url
+	if url == "" {
+		url = c.runURL(modelID)
+	}
+	req, err := c.newRunRequest(ctx, url, contentType, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, err
+	}
+	opts.buffer.attach(req)
+	for name, values := range opts.header {
+		req.Header[name] = values
+	}
+	tagRequest(ctx, req, &event)
+	if err := c.sign(req); err != nil {
+		return nil, nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIToken))
-	req.Header.Set("Content-Type", "application/json")
-
+	start := time.Now()
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		event.Duration = time.Since(start)
+		return nil, nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if err := c.decompressResponse(resp); err != nil {
+		event.Duration = time.Since(start)
+		return nil, nil, err
+	}
+	respBody, err = c.readResponse(resp)
+	event.Duration = time.Since(start)
+	event.StatusCode = resp.StatusCode
+	event.CacheStatus = resp.Header.Get(cacheStatusHeader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	respType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(respType, "application/json") || respType == "" {
+		c.debugLog("Response Body: %s", string(respBody))
+	} else {
+		c.debugLog("Response Body: %d bytes of %s", len(respBody), respType)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		c.debugLog("API Error - Status: %d, Body: %s", resp.StatusCode, string(respBody))
+		return nil, nil, newAPIError(resp, respBody)
 	}
 
-	var modelInfo ModelInfo
-	if err := json.Unmarshal(body, &modelInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	return respBody, resp.Header, nil
+}
+
+// newRunRequest builds an authenticated request posting body to url, the
+// endpoint of a model, and passes it through the BeforeRequest hooks.
+func (c *Client) newRunRequest(ctx context.Context, url, contentType string, body []byte) (*http.Request, error) {
+	c.debugLog("Request URL: %s", url)
+	if contentType == "application/json" {
+		c.debugLog("Request Body: %s", string(body))
+	} else {
+		c.debugLog("Request Body: %d bytes of %s", len(body), contentType)
 	}
 
-	return &modelInfo, nil
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIToken))
+	req.Header.Set("Content-Type", contentType)
+	if err := c.compressRequest(req, body); err != nil {
+		return nil, err
+	}
+
+	c.beforeRequest(req)
+
+	return req, nil
+}
+
+// decodeResult unmarshals the "result" field of a response envelope into out.
+// The result is decoded in the same pass as the envelope, without copying
+// it first.
+func (c *Client) decodeResult(body []byte, out interface{}) error {
+	if out == nil {
+		out = new(json.RawMessage)
+	}
+	envelope := struct {
+		Result interface{} `json:"result"`
+	}{Result: out}
+	if err := c.codec().Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
 }
 
 func (c *Client) debugLog(format string, args ...interface{}) {
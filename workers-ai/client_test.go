@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -229,7 +230,7 @@ func TestClient_GetModelInfo(t *testing.T) {
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(mockResponse)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": mockResponse})
 	}))
 	defer server.Close()
 
@@ -250,81 +251,74 @@ func TestClient_GetModelInfo(t *testing.T) {
 	}
 }
 
-// TODO: fix - the method currently queries upstream directly, requires a test mode
-//func TestClient_ListModels(t *testing.T) {
-//	mockResponse := ModelsResponse{
-//		"@cf/meta/llama-3-8b-instruct": &ModelInfo{
-//			Description: "First test model",
-//			Task: struct {
-//				Name        string `json:"name"`
-//				Description string `json:"description"`
-//			}{
-//				Name:        "text-generation",
-//				Description: "Generates text",
-//			},
-//			Parameters: map[string]*Parameter{
-//				"max_tokens": {
-//					Type:        "integer",
-//					Description: "Maximum tokens",
-//					Default:     256,
-//				},
-//			},
-//		},
-//		"@cf/meta/llama-3-70b-instruct": &ModelInfo{
-//			Description: "Second test model",
-//			Task: struct {
-//				Name        string `json:"name"`
-//				Description string `json:"description"`
-//			}{
-//				Name:        "text-generation",
-//				Description: "Generates text",
-//			},
-//			Parameters: map[string]*Parameter{
-//				"temperature": {
-//					Type:        "number",
-//					Description: "Controls randomness",
-//					Default:     0.15,
-//				},
-//			},
-//		},
-//	}
-//
-//	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-//		if r.Method != "GET" {
-//			t.Errorf("Expected GET request, got %s", r.Method)
-//		}
-//
-//		w.Header().Set("Content-Type", "application/json")
-//		json.NewEncoder(w).Encode(mockResponse)
-//	}))
-//	defer server.Close()
-//
-//	client := NewClient("test-account", "test-token")
-//	client.BaseURL = server.URL
-//
-//	models, err := client.ListModels()
-//	if err != nil {
-//		t.Fatalf("Expected no error, got %v", err)
-//	}
-//
-//	if len(models) != 2 {
-//		t.Errorf("Expected 2 models, got %d", len(models))
-//	}
-//
-//	// Check that model names are set from map keys
-//	modelNames := make(map[string]bool)
-//	for _, model := range models {
-//		modelNames[model.Name] = true
-//	}
-//
-//	if !modelNames["@cf/meta/llama-3-8b-instruct"] {
-//		t.Error("Expected model '@cf/meta/llama-3-8b-instruct' not found")
-//	}
-//
-//	if !modelNames["@cf/meta/llama-3-70b-instruct"] {
-//		t.Error("Expected model '@cf/meta/llama-3-70b-instruct' not found")
-//	}
-//}
+func TestClient_GetModelInfo_Gateway(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "result": {"name": "@cf/meta/llama-3-8b-instruct"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.UseGateway("my-gateway")
+	// Send every request to the test server, recording where it was bound.
+	client.HTTPClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		req.URL.Scheme, req.URL.Host = "http", strings.TrimPrefix(server.URL, "http://")
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	modelInfo, err := client.GetModelInfo(ModelLlama38B)
+	require.NoError(t, err)
+	assert.Equal(t, ModelLlama38B, modelInfo.Name)
+	assert.Equal(t, DefaultBaseURL+"/accounts/test-account/ai/models/"+ModelLlama38B, requested)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClient_ListModels(t *testing.T) {
+	signer := NewHMACSigner([]byte("secret"))
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/accounts/test-account/ai/models/search", r.URL.Path)
+		assert.NoError(t, signer.Verify(r))
+		pages = append(pages, r.URL.Query().Get("page"))
+
+		if r.URL.Query().Get("page") == "1" {
+			models := make([]map[string]interface{}, DefaultModelsPerPage)
+			for i := range models {
+				models[i] = map[string]interface{}{"name": fmt.Sprintf("@cf/test/model-%d", i)}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": models})
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": [{
+			"name": "@cf/meta/llama-3-8b-instruct",
+			"description": "Generation over generation",
+			"task": {"name": "Text Generation", "description": "Generates text"},
+			"properties": [{"property_id": "beta", "value": "true"}, {"property_id": "max_total_tokens", "value": "8192"}]
+		}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Signer = signer.Sign
+
+	models, err := client.ListModels()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, pages)
+	require.Len(t, models, DefaultModelsPerPage+1)
+
+	model := models[DefaultModelsPerPage]
+	assert.Equal(t, "@cf/meta/llama-3-8b-instruct", model.Name)
+	assert.Equal(t, "Text Generation", model.Task.Name)
+	assert.True(t, model.Beta)
+	assert.Equal(t, 8192, model.Properties.MaxTotalTokens)
+}
 
 func TestClient_Chat_Integration(t *testing.T) {
 	accountID := os.Getenv("CLOUDFLARE_ACCOUNT_ID")
@@ -0,0 +1,126 @@
+package workersai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// flight is a request in progress whose result is shared by the callers
+// that asked for it while it was running.
+type flight struct {
+	done   chan struct{}
+	body   []byte
+	header http.Header
+	err    error
+
+	// waiters counts the callers still waiting for the result; the request
+	// is cancelled when the last one gives up.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup coalesces identical concurrent requests, like
+// golang.org/x/sync/singleflight.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// do calls fn for key unless a call for key is in progress, in which case
+// it waits for that call and returns its result. fn runs with the values of
+// the ctx of the first caller but not its cancellation: callers return early
+// when their ctx is done, and the ctx of fn is cancelled only once all of
+// them did. The results are shared: callers must not modify them.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, http.Header, error)) ([]byte, http.Header, error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		if g.flights == nil {
+			g.flights = make(map[string]*flight)
+		}
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go func() {
+			defer close(f.done)
+			defer cancel()
+			f.body, f.header, f.err = fn(fctx)
+			g.forget(key, f)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.body, f.header, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			g.forgetLocked(key, f)
+		}
+		g.mu.Unlock()
+		return nil, nil, ctx.Err()
+	}
+}
+
+// forget removes f from the flights in progress, unless it was already
+// replaced by another call for key.
+func (g *flightGroup) forget(key string, f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.forgetLocked(key, f)
+}
+
+func (g *flightGroup) forgetLocked(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
+
+// flightKey identifies a request by its content: the model, the body and
+// the headers and tags that could change the response or its accounting.
+func flightKey(ctx context.Context, modelID, contentType string, body []byte, opts sendOptions) string {
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	write(modelID)
+	write(contentType)
+	h.Write(body)
+	h.Write([]byte{0})
+
+	names := make([]string, 0, len(opts.header))
+	for name := range opts.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		write(name)
+		for _, value := range opts.header[name] {
+			write(value)
+		}
+	}
+
+	tags := tagsFrom(ctx)
+	write(tags.tenant)
+	keys := make([]string, 0, len(tags.metadata))
+	for k := range tags.metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		write(k)
+		write(tags.metadata[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
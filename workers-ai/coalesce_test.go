@@ -0,0 +1,165 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Coalesce(t *testing.T) {
+	var calls atomic.Int64
+	arrived := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		arrived <- struct{}{}
+		<-release
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Shared"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Coalesce = true
+
+	var events atomic.Int64
+	client.Use(Hooks{AfterResponse: func(RequestEvent) { events.Add(1) }})
+
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}}}
+	var wg sync.WaitGroup
+	responses := make([]*ChatResponse, 5)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := client.ChatCompletion(request)
+			assert.NoError(t, err)
+			responses[i] = response
+		}(i)
+	}
+
+	<-arrived
+	// Let the other callers join the request in flight.
+	time.Sleep(50 * time.Millisecond)
+
+	// A request for another tenant isn't shared.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := client.ChatCompletionContext(WithTenant(context.Background(), "acme"), request)
+		assert.NoError(t, err)
+	}()
+	<-arrived
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int64(2), calls.Load())
+	assert.Equal(t, int64(2), events.Load())
+	for _, response := range responses {
+		require.NotNil(t, response)
+		assert.Equal(t, "Shared", response.GetContent())
+	}
+	assert.NotSame(t, responses[0], responses[1])
+
+	// Once finished, the request is sent again.
+	_, err := client.ChatCompletion(request)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), calls.Load())
+}
+
+func TestClient_CoalesceCancelledWaiter(t *testing.T) {
+	release := make(chan struct{})
+	arrived := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Late"}}`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Coalesce = true
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}}}
+
+	go client.ChatCompletion(request)
+	<-arrived
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.ChatCompletionContext(ctx, request)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_CoalesceCancelledFirstCaller(t *testing.T) {
+	release := make(chan struct{})
+	arrived := make(chan struct{}, 1)
+	cancelled := make(chan struct{})
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		arrived <- struct{}{}
+		if calls.Add(1) == 1 {
+			<-release
+			fmt.Fprint(w, `{"success": true, "result": {"response": "Shared"}}`)
+			return
+		}
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Coalesce = true
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}}}
+
+	// The caller that sent the request gives up: the other one still gets
+	// the response.
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := client.ChatCompletionContext(first, request)
+		firstErr <- err
+	}()
+	<-arrived
+
+	second := make(chan *ChatResponse, 1)
+	go func() {
+		response, err := client.ChatCompletion(request)
+		assert.NoError(t, err)
+		second <- response
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancelFirst()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+	close(release)
+	assert.Equal(t, "Shared", (<-second).GetContent())
+
+	// Once every caller gave up, the request is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.ChatCompletionContext(ctx, ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Bye"}}})
+		done <- err
+	}()
+	<-arrived
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request wasn't cancelled")
+	}
+}
@@ -0,0 +1,56 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONCodec marshals requests and unmarshals responses. Faster drop-in
+// replacements of encoding/json can be used as is, for example
+// sonic.ConfigStd or jsoniter.ConfigCompatibleWithStandardLibrary.
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StdJSON is the JSONCodec of encoding/json, used when Client.JSON is nil.
+var StdJSON JSONCodec = stdJSON{}
+
+type stdJSON struct{}
+
+func (stdJSON) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (stdJSON) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+func (c *Client) codec() JSONCodec {
+	if c.JSON != nil {
+		return c.JSON
+	}
+	return StdJSON
+}
+
+// marshalRequest marshals the payload of a request with the codec. With
+// encoding/json, it is encoded into a pooled buffer, returned with the
+// data, that the caller releases once send returned; the requests sent
+// with the buffer in sendOptions hold it until the transport is done with
+// them. Coalesced requests, which may outlive the caller, aren't pooled.
+func (c *Client) marshalRequest(v interface{}) ([]byte, *requestBuffer, error) {
+	if c.JSON != nil || c.Coalesce {
+		data, err := c.codec().Marshal(v)
+		return data, nil, err
+	}
+	b := newRequestBuffer()
+	if err := json.NewEncoder(b.buf).Encode(v); err != nil {
+		b.release()
+		return nil, nil, err
+	}
+	// Encode ends the value with a newline, which Marshal doesn't.
+	return bytes.TrimSuffix(b.buf.Bytes(), []byte("\n")), b, nil
+}
+
+// unmarshal decodes data with the codec of the client decoding the response.
+func (cr *ChatResponse) unmarshal(data []byte, v interface{}) error {
+	if cr.codec != nil {
+		return cr.codec.Unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,113 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingCodec is encoding/json counting its calls.
+type countingCodec struct {
+	marshals, unmarshals atomic.Int64
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	c.marshals.Add(1)
+	return json.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.unmarshals.Add(1)
+	return json.Unmarshal(data, v)
+}
+
+func TestClient_JSONCodec(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi there"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	codec := &countingCodec{}
+	client.JSON = codec
+
+	response, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", response.GetContent())
+	assert.Equal(t, int64(1), codec.marshals.Load())
+	// The result is decoded with the codec too, not only the envelope.
+	assert.Greater(t, codec.unmarshals.Load(), int64(1))
+}
+
+func TestRequestBuffer(t *testing.T) {
+	client := NewClient("test-account", "test-token")
+	data, buffer, err := client.marshalRequest(map[string]string{"prompt": "<Hi>"})
+	require.NoError(t, err)
+	require.NotNil(t, buffer)
+	expected, _ := json.Marshal(map[string]string{"prompt": "<Hi>"})
+	assert.Equal(t, string(expected), string(data))
+
+	req, err := http.NewRequest("POST", "http://example.com", bytes.NewReader(data))
+	require.NoError(t, err)
+	buffer.attach(req)
+	copied, err := req.GetBody()
+	require.NoError(t, err)
+	require.NoError(t, copied.Close())
+	assert.Equal(t, int32(2), buffer.refs.Load())
+
+	// The body still reads the data after the owner released the buffer.
+	buffer.release()
+	read, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(read))
+	require.NoError(t, req.Body.Close())
+	require.NoError(t, req.Body.Close())
+	assert.Equal(t, int32(0), buffer.refs.Load())
+
+	// Coalesced requests may outlive the caller: no buffer is pooled.
+	client.Coalesce = true
+	_, buffer, err = client.marshalRequest("x")
+	require.NoError(t, err)
+	assert.Nil(t, buffer)
+}
+
+func benchmarkResponse() []byte {
+	var b strings.Builder
+	b.WriteString(`{"success": true, "result": {"response": "`)
+	b.WriteString(strings.Repeat("All work and no play makes Jack a dull boy. ", 500))
+	b.WriteString(`", "usage": {"prompt_tokens": 10, "completion_tokens": 5000, "total_tokens": 5010}}}`)
+	return []byte(b.String())
+}
+
+// BenchmarkChatCompletion measures a whole chat request against a local
+// server, to compare codecs set with Client.JSON.
+func BenchmarkChatCompletion(b *testing.B) {
+	body := benchmarkResponse()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	messages := []Message{ChatMessage{Role: "user", Content: strings.Repeat("Tell me a story. ", 200)}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Chat(ModelLlama38B, messages, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
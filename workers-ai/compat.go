@@ -0,0 +1,113 @@
+package workersai
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// CompatibilityLevel selects the chat endpoint of a Client and the response
+// formats it accepts, so that the handling of the legacy format can be
+// phased out gradually: move to CompatModern, check FormatCounts and the
+// diagnostics for legacy responses, then to CompatOpenAI.
+type CompatibilityLevel int
+
+const (
+	// CompatLegacy sends chat requests to the /ai/run endpoint and decodes
+	// the OpenAI, hybrid and legacy formats alike. It is the default.
+	CompatLegacy CompatibilityLevel = iota
+	// CompatModern sends chat requests to the /ai/run endpoint, like
+	// CompatLegacy, but reports legacy responses in the diagnostics, which
+	// DecodeStrict rejects.
+	CompatModern
+	// CompatOpenAI sends chat requests to the OpenAI-compatible
+	// /ai/v1/chat/completions endpoint and reports responses in any other
+	// format in the diagnostics. Requests rendered with a ChatTemplate are
+	// raw prompts, which only /ai/run accepts; they are still sent there.
+	CompatOpenAI
+)
+
+func (l CompatibilityLevel) String() string {
+	switch l {
+	case CompatLegacy:
+		return "legacy"
+	case CompatModern:
+		return "modern"
+	case CompatOpenAI:
+		return "openai"
+	}
+	return fmt.Sprintf("CompatibilityLevel(%d)", int(l))
+}
+
+// chatURL returns the endpoint chat requests for modelID are sent to.
+func (c *Client) chatURL(modelID string) string {
+	if c.Compatibility != CompatOpenAI {
+		return c.runURL(modelID)
+	}
+	if c.Gateway != "" {
+		return fmt.Sprintf("%s/%s/%s/workers-ai/v1/chat/completions", c.BaseURL, c.AccountID, c.Gateway)
+	}
+	return fmt.Sprintf("%s/accounts/%s/ai/v1/chat/completions", c.BaseURL, c.AccountID)
+}
+
+// openAIModel returns the model ID as the OpenAI-compatible endpoint
+// expects it, with its "@cf/" prefix.
+func openAIModel(modelID string) string {
+	if modelID == "" || strings.HasPrefix(modelID, "@") {
+		return modelID
+	}
+	return "@cf/" + modelID
+}
+
+// wrapResult wraps the bare body of an OpenAI-compatible response like the
+// "result" of a Workers AI response, for the decoders.
+func wrapResult(body []byte) []byte {
+	wrapped := make([]byte, 0, len(body)+30)
+	wrapped = append(wrapped, `{"success":true,"result":`...)
+	wrapped = append(wrapped, body...)
+	return append(wrapped, '}')
+}
+
+// checkFormat counts the format of resp and records a diagnostic when the
+// compatibility level of the client no longer accepts it.
+func (c *Client) checkFormat(resp *ChatResponse) {
+	c.formats.add(resp.Format)
+	switch {
+	case c.Compatibility == CompatModern && resp.Format == FormatLegacy:
+		resp.diagnose("result", "legacy format is deprecated")
+	case c.Compatibility == CompatOpenAI && resp.Format != FormatOpenAI:
+		resp.diagnose("result", "%s format, expected openai", resp.Format)
+	}
+}
+
+// FormatCounts returns the number of chat responses decoded in each format
+// since the client was created, to tell whether the legacy handling is
+// still needed.
+func (c *Client) FormatCounts() map[ResponseFormat]int64 {
+	return c.formats.snapshot()
+}
+
+// formatCounts counts the formats of the chat responses of a Client.
+type formatCounts struct {
+	mu     sync.Mutex
+	counts map[ResponseFormat]int64
+}
+
+func (f *formatCounts) add(format ResponseFormat) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts == nil {
+		f.counts = make(map[ResponseFormat]int64)
+	}
+	f.counts[format]++
+}
+
+func (f *formatCounts) snapshot() map[ResponseFormat]int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	counts := make(map[ResponseFormat]int64, len(f.counts))
+	for format, n := range f.counts {
+		counts[format] = n
+	}
+	return counts
+}
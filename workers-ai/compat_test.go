@@ -0,0 +1,171 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	legacyResult = `{"response": "Hi", "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}`
+	openAIResult = `{"id": "chatcmpl-1", "object": "chat.completion", "choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}`
+)
+
+func TestClient_Compatibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		level       CompatibilityLevel
+		body        string
+		path        string
+		format      ResponseFormat
+		diagnostics []string
+	}{
+		{
+			name:   "legacy accepts legacy",
+			level:  CompatLegacy,
+			body:   `{"success": true, "result": ` + legacyResult + `}`,
+			path:   "/accounts/test-account/ai/run/" + ModelLlama38B,
+			format: FormatLegacy,
+		},
+		{
+			name:        "modern deprecates legacy",
+			level:       CompatModern,
+			body:        `{"success": true, "result": ` + legacyResult + `}`,
+			path:        "/accounts/test-account/ai/run/" + ModelLlama38B,
+			format:      FormatLegacy,
+			diagnostics: []string{"result: legacy format is deprecated"},
+		},
+		{
+			name:   "modern accepts openai",
+			level:  CompatModern,
+			body:   `{"success": true, "result": ` + openAIResult + `}`,
+			path:   "/accounts/test-account/ai/run/" + ModelLlama38B,
+			format: FormatOpenAI,
+		},
+		{
+			name:   "openai endpoint",
+			level:  CompatOpenAI,
+			body:   openAIResult,
+			path:   "/accounts/test-account/ai/v1/chat/completions",
+			format: FormatOpenAI,
+		},
+		{
+			name:        "openai rejects legacy",
+			level:       CompatOpenAI,
+			body:        legacyResult,
+			path:        "/accounts/test-account/ai/v1/chat/completions",
+			format:      FormatLegacy,
+			diagnostics: []string{"result: legacy format, expected openai"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.path, r.URL.Path)
+				var req ChatCompletionRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, ModelLlama38B, req.Model)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			client := NewClient("test-account", "test-token")
+			client.BaseURL = server.URL
+			client.Compatibility = tt.level
+
+			resp, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "Hi", resp.GetContent())
+			assert.Equal(t, tt.format, resp.Format)
+			var diagnostics []string
+			for _, d := range resp.Diagnostics {
+				diagnostics = append(diagnostics, d.String())
+			}
+			assert.Equal(t, tt.diagnostics, diagnostics)
+			assert.Equal(t, map[ResponseFormat]int64{tt.format: 1}, client.FormatCounts())
+
+			client.DecodeMode = DecodeStrict
+			_, err = client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+			if tt.diagnostics == nil {
+				assert.NoError(t, err)
+			} else {
+				var decodeErr *DecodeError
+				assert.ErrorAs(t, err, &decodeErr)
+			}
+		})
+	}
+}
+
+func TestClient_CompatOpenAI_Usage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, openAIResult)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Compatibility = CompatOpenAI
+	var events []RequestEvent
+	client.Use(Hooks{AfterResponse: func(e RequestEvent) { events = append(events, e) }})
+
+	resp, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.GetUsage().TotalTokens)
+	require.Len(t, events, 1)
+	assert.Equal(t, 2, events[0].Usage.TotalTokens)
+}
+
+func TestClient_CompatOpenAI_Templates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Raw prompts are only accepted by /ai/run.
+		assert.Equal(t, "/accounts/test-account/ai/run/@cf/meta/llama-3-8b", r.URL.Path)
+		fmt.Fprint(w, `{"success": true, "result": `+legacyResult+`}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Compatibility = CompatOpenAI
+	client.Templates = map[string]ChatTemplate{"meta/llama-3-8b": TemplateLlama3}
+
+	resp, err := client.Chat("meta/llama-3-8b", []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", resp.GetContent())
+}
+
+func TestClient_CompatOpenAI_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/test-account/my-gateway/workers-ai/v1/chat/completions", r.URL.Path)
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "@cf/meta/llama-3-8b", req.Model)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Gateway = "my-gateway"
+	client.Compatibility = CompatOpenAI
+
+	stream, err := client.ChatStream(context.Background(), "meta/llama-3-8b", []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+	}
+	assert.Equal(t, "Hi", stream.Content())
+}
@@ -0,0 +1,79 @@
+package workersai
+
+import "unicode/utf8"
+
+// Completion is one of the completions of a response, as requested with
+// ModelParameters.N.
+type Completion struct {
+	Index            int
+	Content          string
+	ReasoningContent string
+	ToolCalls        []ToolCall
+	// FinishReason is the reason the model stopped; empty for legacy
+	// responses.
+	FinishReason string
+	// Usage attributes the response's tokens to the completion. The prompt
+	// is shared by all completions and counted in full for each; with
+	// several completions, the completion tokens are split in proportion to
+	// the length of their content and UsageEstimated is set.
+	Usage          Usage
+	UsageEstimated bool
+}
+
+// GetCompletions returns all completions of the response, in index order.
+// Legacy responses have a single completion.
+func (r *ChatResponse) GetCompletions() []Completion {
+	usage := r.GetUsage()
+
+	if r.IsLegacyResult {
+		return []Completion{{
+			Content:   r.GetContent(),
+			ToolCalls: r.GetToolCalls(),
+			Usage:     usage,
+		}}
+	}
+
+	choices := r.ChatCompletionResponse.Choices
+	completions := make([]Completion, len(choices))
+	totalLength := 0
+	for i, choice := range choices {
+		completion := Completion{
+			Index:            choice.Index,
+			ReasoningContent: choice.Message.ReasoningContent,
+			ToolCalls:        choice.Message.ToolCalls,
+			FinishReason:     choice.FinishReason,
+		}
+		if choice.Message.Content != nil {
+			completion.Content = *choice.Message.Content
+		}
+		completions[i] = completion
+		totalLength += completionLength(completion)
+	}
+
+	if len(completions) == 1 {
+		completions[0].Usage = usage
+		return completions
+	}
+
+	for i := range completions {
+		c := &completions[i]
+		c.UsageEstimated = true
+		c.Usage.PromptTokens = usage.PromptTokens
+		if totalLength > 0 {
+			c.Usage.CompletionTokens = usage.CompletionTokens * completionLength(*c) / totalLength
+		} else {
+			c.Usage.CompletionTokens = usage.CompletionTokens / len(completions)
+		}
+		c.Usage.TotalTokens = c.Usage.PromptTokens + c.Usage.CompletionTokens
+	}
+	return completions
+}
+
+// completionLength measures the generated text of c, in runes.
+func completionLength(c Completion) int {
+	n := utf8.RuneCountInString(c.Content) + utf8.RuneCountInString(c.ReasoningContent)
+	for _, call := range c.ToolCalls {
+		n += utf8.RuneCountInString(call.Function.Name) + utf8.RuneCountInString(call.Function.Arguments)
+	}
+	return n
+}
@@ -0,0 +1,55 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatResponse_GetCompletions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, 2.0, request["n"])
+
+		fmt.Fprint(w, `{"success": true, "result": {"choices": [
+			{"index": 0, "message": {"role": "assistant", "content": "Yes."}, "finish_reason": "stop"},
+			{"index": 1, "message": {"role": "assistant", "content": "Certainly not"}, "finish_reason": "length"}
+		], "usage": {"prompt_tokens": 10, "completion_tokens": 17, "total_tokens": 27}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	resp, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Well?"}}, &ModelParameters{N: 2})
+	require.NoError(t, err)
+
+	completions := resp.GetCompletions()
+	require.Len(t, completions, 2)
+
+	assert.Equal(t, "Yes.", completions[0].Content)
+	assert.Equal(t, "stop", completions[0].FinishReason)
+	assert.Equal(t, Usage{PromptTokens: 10, CompletionTokens: 4, TotalTokens: 14}, completions[0].Usage)
+	assert.True(t, completions[0].UsageEstimated)
+
+	assert.Equal(t, 1, completions[1].Index)
+	assert.Equal(t, "Certainly not", completions[1].Content)
+	assert.Equal(t, "length", completions[1].FinishReason)
+	assert.Equal(t, Usage{PromptTokens: 10, CompletionTokens: 13, TotalTokens: 23}, completions[1].Usage)
+}
+
+func TestChatResponse_GetCompletions_Legacy(t *testing.T) {
+	resp := &ChatResponse{IsLegacyResult: true, LegacyResponse: LegacyResponse{Response: "Hi", Usage: Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2}}}
+
+	completions := resp.GetCompletions()
+	require.Len(t, completions, 1)
+	assert.Equal(t, "Hi", completions[0].Content)
+	assert.Equal(t, Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2}, completions[0].Usage)
+	assert.False(t, completions[0].UsageEstimated)
+}
@@ -0,0 +1,113 @@
+package workersai
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Decoder decompresses a response body of some content encoding.
+type Decoder func(r io.Reader) (io.Reader, error)
+
+// Compression configures the compression of request and response bodies.
+// Without it, gzip responses are still decompressed by net/http.
+type Compression struct {
+	// RequestThreshold is the size, in bytes, from which request bodies
+	// are gzipped, e.g. for long contexts on slow links. Zero disables
+	// request compression.
+	RequestThreshold int
+	// Level is the gzip level of requests. Defaults to
+	// gzip.DefaultCompression.
+	Level int
+	// Decoders adds content encodings accepted in responses, keyed by
+	// their name. For brotli, with github.com/andybalholm/brotli:
+	//
+	//	client.Compression.Decoders = map[string]workersai.Decoder{
+	//		"br": func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	//	}
+	//
+	// gzip is always accepted.
+	Decoders map[string]Decoder
+}
+
+// compressRequest gzips the body of req if it is large enough and
+// advertises the accepted response encodings.
+func (c *Client) compressRequest(req *http.Request, body []byte) error {
+	if len(c.Compression.Decoders) > 0 {
+		// Setting Accept-Encoding stops net/http from decompressing gzip
+		// on its own, so decompressResponse handles it too.
+		encodings := []string{"gzip"}
+		for name := range c.Compression.Decoders {
+			if name != "gzip" {
+				encodings = append(encodings, name)
+			}
+		}
+		sort.Strings(encodings[1:])
+		req.Header.Set("Accept-Encoding", strings.Join(encodings, ", "))
+	}
+
+	threshold := c.Compression.RequestThreshold
+	if threshold <= 0 || len(body) < threshold {
+		return nil
+	}
+
+	level := c.Compression.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return fmt.Errorf("failed to compress request: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("failed to compress request: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to compress request: %w", err)
+	}
+	c.debugLog("Request Body compressed from %d to %d bytes", len(body), buf.Len())
+
+	compressed := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
+
+// decompressResponse replaces the body of resp by its decompressed
+// content when it is encoded with gzip or one of the Decoders.
+func (c *Client) decompressResponse(resp *http.Response) error {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return nil
+	}
+
+	var decode Decoder
+	if encoding == "gzip" {
+		decode = func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
+	} else if decode = c.Compression.Decoders[encoding]; decode == nil {
+		return fmt.Errorf("unsupported response encoding %q", encoding)
+	}
+
+	r, err := decode(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to decompress response: %w", err)
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{r, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
@@ -0,0 +1,114 @@
+package workersai
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCompression_Request(t *testing.T) {
+	var encodings []string
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		var request struct {
+			Messages []ChatMessage `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(body).Decode(&request))
+		prompts = append(prompts, request.Messages[0].Content)
+		fmt.Fprint(w, `{"success": true, "result": {"response": "OK"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Compression.RequestThreshold = 1024
+
+	long := strings.Repeat("context ", 500)
+	for _, prompt := range []string{"short", long} {
+		_, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: prompt}}, nil)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"", "gzip"}, encodings)
+	assert.Equal(t, []string{"short", long}, prompts)
+}
+
+func TestCompression_Response(t *testing.T) {
+	var accepted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "rot13")
+		fmt.Fprint(w, strings.Map(rot13, `{"success": true, "result": {"response": "Hello"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	_, err := client.Chat(ModelLlama38B, messages, nil)
+	assert.ErrorContains(t, err, `unsupported response encoding "rot13"`)
+
+	client.Compression.Decoders = map[string]Decoder{
+		"rot13": func(r io.Reader) (io.Reader, error) {
+			data, err := io.ReadAll(r)
+			return strings.NewReader(strings.Map(rot13, string(data))), err
+		},
+	}
+	response, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", response.GetContent())
+	assert.Equal(t, "gzip, rot13", accepted)
+}
+
+func TestCompression_GzipResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped(t, `{"success": true, "result": {"response": "Hello"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	// Asking for another encoding turns off the decompression by net/http.
+	client.Compression.Decoders = map[string]Decoder{"br": nil}
+
+	response, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", response.GetContent())
+}
+
+func rot13(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 'a' + (r-'a'+13)%26
+	case r >= 'A' && r <= 'Z':
+		return 'A' + (r-'A'+13)%26
+	}
+	return r
+}
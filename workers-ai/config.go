@@ -0,0 +1,315 @@
+package workersai
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by LoadConfig.
+const (
+	EnvConfigFile = "WORKERS_AI_CONFIG"
+	EnvAccountID  = "CLOUDFLARE_ACCOUNT_ID"
+	EnvAPIToken   = "CLOUDFLARE_API_TOKEN"
+	EnvGateway    = "WORKERS_AI_GATEWAY"
+	EnvBaseURL    = "WORKERS_AI_BASE_URL"
+	EnvDebug      = "WORKERS_AI_DEBUG"
+	EnvProfile    = "WORKERS_AI_PROFILE"
+)
+
+// Config describes a Client declaratively. It is usually read from a file
+// with LoadConfig:
+//
+//	account_id: 0123456789abcdef
+//	gateway: my-gateway
+//	timeout: 60s
+//	defaults:
+//	  system_prompt: You are a helpful assistant.
+//	  max_tokens: 512
+//	retry:
+//	  max_retries: 3
+//	  initial_backoff: 1s
+//	profiles:
+//	  staging:
+//	    account_id: fedcba9876543210
+//	    gateway: staging-gateway
+type Config struct {
+	AccountID string `yaml:"account_id"`
+	APIToken  string `yaml:"api_token"`
+	// BaseURL overrides DefaultBaseURL, or DefaultGatewayBaseURL when a
+	// Gateway is set.
+	BaseURL string `yaml:"base_url"`
+	// Gateway is the ID of an AI Gateway to route requests through.
+	Gateway string `yaml:"gateway"`
+	Debug   bool   `yaml:"debug"`
+	// Timeout bounds every HTTP request. Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	Defaults ConfigDefaults `yaml:"defaults"`
+	Retry    RetryPolicy    `yaml:"retry"`
+
+	// Profiles are named accounts, selected with LoadConfigProfile.
+	Profiles map[string]ConfigProfile `yaml:"profiles"`
+	// Profile is the name of the profile applied, if any.
+	Profile string `yaml:"-"`
+}
+
+// ConfigProfile is a named account of a Config, like an AWS CLI profile.
+// The settings it sets override those at the top level of the file and
+// those of the environment.
+type ConfigProfile struct {
+	AccountID string `yaml:"account_id"`
+	APIToken  string `yaml:"api_token"`
+	BaseURL   string `yaml:"base_url"`
+	Gateway   string `yaml:"gateway"`
+}
+
+// ConfigDefaults are the ChatDefaults of a Config.
+type ConfigDefaults struct {
+	SystemPrompt string  `yaml:"system_prompt"`
+	MaxTokens    int64   `yaml:"max_tokens"`
+	Temperature  float64 `yaml:"temperature"`
+	TopP         float64 `yaml:"top_p"`
+	TopK         int     `yaml:"top_k"`
+}
+
+// LoadConfig reads the client configuration. Settings are taken, from
+// lowest to highest precedence, from:
+//
+//  1. the file at path, or at $WORKERS_AI_CONFIG when path is empty. YAML
+//     (and JSON) files are supported, as well as TOML files with a .toml
+//     extension. No file is read when both are empty.
+//  2. the environment: CLOUDFLARE_ACCOUNT_ID, CLOUDFLARE_API_TOKEN,
+//     WORKERS_AI_GATEWAY, WORKERS_AI_BASE_URL and WORKERS_AI_DEBUG.
+//  3. the profile named by $WORKERS_AI_PROFILE, if set. Selecting a profile
+//     is explicit, so its settings override the environment: an exported
+//     token of another account doesn't leak into it.
+//
+// Keeping the token in the environment rather than in the file is
+// recommended.
+func LoadConfig(path string) (*Config, error) {
+	return LoadConfigProfile(path, "")
+}
+
+// LoadConfigProfile is LoadConfig applying the named profile of the file,
+// or the one named by $WORKERS_AI_PROFILE when profile is empty. Unknown
+// profiles are an error.
+func LoadConfigProfile(path, profile string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(EnvConfigFile)
+	}
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+
+	cfg := &Config{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := cfg.decode(data, filepath.Ext(path)); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+
+	cfg.applyEnv()
+	if profile != "" {
+		if err := cfg.applyProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) applyProfile(name string) error {
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Profiles))
+		for name := range cfg.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown profile %q: the configuration has no profiles", name)
+		}
+		return fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+
+	for _, setting := range []struct{ value, field *string }{
+		{&profile.AccountID, &cfg.AccountID},
+		{&profile.APIToken, &cfg.APIToken},
+		{&profile.Gateway, &cfg.Gateway},
+		{&profile.BaseURL, &cfg.BaseURL},
+	} {
+		if *setting.value != "" {
+			*setting.field = *setting.value
+		}
+	}
+	cfg.Profile = name
+	return nil
+}
+
+func (cfg *Config) decode(data []byte, ext string) error {
+	if strings.EqualFold(ext, ".toml") {
+		table, err := parseTOML(data)
+		if err != nil {
+			return err
+		}
+		// Go through YAML so that both formats share the field tags.
+		if data, err = yaml.Marshal(table); err != nil {
+			return err
+		}
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func (cfg *Config) applyEnv() {
+	for env, field := range map[string]*string{
+		EnvAccountID: &cfg.AccountID,
+		EnvAPIToken:  &cfg.APIToken,
+		EnvGateway:   &cfg.Gateway,
+		EnvBaseURL:   &cfg.BaseURL,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+	if debug := os.Getenv(EnvDebug); debug != "" {
+		cfg.Debug = debug == "true"
+	}
+}
+
+// Validate reports missing credentials.
+func (cfg *Config) Validate() error {
+	var missing []string
+	if cfg.AccountID == "" {
+		missing = append(missing, "account ID ("+EnvAccountID+")")
+	}
+	if cfg.APIToken == "" {
+		missing = append(missing, "API token ("+EnvAPIToken+")")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, " and "))
+	}
+	return nil
+}
+
+// NewClient validates cfg and builds a client from it.
+func (cfg *Config) NewClient() (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	client := NewClient(cfg.AccountID, cfg.APIToken)
+	client.Debug = cfg.Debug
+	client.HTTPClient.Timeout = cfg.Timeout
+	if cfg.Gateway != "" {
+		client.UseGateway(cfg.Gateway)
+	}
+	if cfg.BaseURL != "" {
+		client.BaseURL = cfg.BaseURL
+	}
+	client.Defaults = ChatDefaults{
+		SystemPrompt: cfg.Defaults.SystemPrompt,
+		ModelParameters: ModelParameters{
+			MaxTokens:   cfg.Defaults.MaxTokens,
+			Temperature: cfg.Defaults.Temperature,
+			TopP:        cfg.Defaults.TopP,
+			TopK:        cfg.Defaults.TopK,
+		},
+	}
+	client.Retry = cfg.Retry
+	return client, nil
+}
+
+// parseTOML reads the subset of TOML needed for configuration files:
+// [table] headers and key = value pairs with string, number and boolean
+// values.
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	root := map[string]interface{}{}
+	table := root
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			table = root
+			for _, name := range strings.Split(strings.Trim(line, "[]"), ".") {
+				name = strings.TrimSpace(name)
+				next, ok := table[name].(map[string]interface{})
+				if !ok {
+					next = map[string]interface{}{}
+					table[name] = next
+				}
+				table = next
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		table[strings.Trim(strings.TrimSpace(key), `"`)] = value
+	}
+	return root, scanner.Err()
+}
+
+func parseTOMLValue(raw string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return strconv.Unquote(raw)
+	case strings.HasPrefix(raw, "'") && strings.HasSuffix(raw, "'") && len(raw) > 1:
+		return raw[1 : len(raw)-1], nil
+	case raw == "true" || raw == "false":
+		return raw == "true", nil
+	}
+	if i, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unsupported value %s", raw)
+}
+
+// stripTOMLComment removes a # comment that isn't inside a string.
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,153 @@
+package workersai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	yamlConfig := `
+account_id: file-account
+api_token: file-token
+gateway: my-gateway
+timeout: 45s
+defaults:
+  system_prompt: Be brief.
+  max_tokens: 256
+retry:
+  max_retries: 3
+  initial_backoff: 250ms
+`
+	tomlConfig := `
+account_id = "file-account" # comment
+api_token = 'file-token'
+gateway = "my-gateway"
+timeout = "45s"
+
+[defaults]
+system_prompt = "Be brief."
+max_tokens = 256
+
+[retry]
+max_retries = 3
+initial_backoff = "250ms"
+`
+
+	for name, content := range map[string]string{"config.yaml": yamlConfig, "config.toml": tomlConfig} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			t.Setenv(EnvAPIToken, "env-token")
+			t.Setenv(EnvAccountID, "")
+
+			cfg, err := LoadConfig(path)
+			require.NoError(t, err)
+
+			assert.Equal(t, "file-account", cfg.AccountID)
+			assert.Equal(t, "env-token", cfg.APIToken, "environment takes precedence")
+			assert.Equal(t, 45*time.Second, cfg.Timeout)
+			assert.Equal(t, RetryPolicy{MaxRetries: 3, InitialBackoff: 250 * time.Millisecond}, cfg.Retry)
+
+			client, err := cfg.NewClient()
+			require.NoError(t, err)
+			assert.Equal(t, DefaultGatewayBaseURL, client.BaseURL)
+			assert.Equal(t, "my-gateway", client.Gateway)
+			assert.Equal(t, "Be brief.", client.Defaults.SystemPrompt)
+			assert.Equal(t, int64(256), client.Defaults.MaxTokens)
+			assert.Equal(t, 45*time.Second, client.HTTPClient.Timeout)
+		})
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	t.Setenv(EnvConfigFile, "")
+	t.Setenv(EnvAccountID, "")
+	t.Setenv(EnvAPIToken, "")
+
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	_, err = cfg.NewClient()
+	assert.EqualError(t, err, "missing account ID (CLOUDFLARE_ACCOUNT_ID) and API token (CLOUDFLARE_API_TOKEN)")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("acount_id: typo\n"), 0o600))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "field acount_id not found")
+}
+
+func TestLoadConfigProfile(t *testing.T) {
+	yamlConfig := `
+account_id: prod-account
+gateway: prod-gateway
+profiles:
+  staging:
+    account_id: staging-account
+    api_token: staging-token
+    gateway: staging-gateway
+  dev:
+    base_url: http://localhost:8787
+`
+	tomlConfig := `
+account_id = "prod-account"
+gateway = "prod-gateway"
+
+[profiles.staging]
+account_id = "staging-account"
+api_token = "staging-token"
+gateway = "staging-gateway"
+
+[profiles.dev]
+base_url = "http://localhost:8787"
+`
+
+	for name, content := range map[string]string{"config.yaml": yamlConfig, "config.toml": tomlConfig} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			t.Setenv(EnvAccountID, "")
+			t.Setenv(EnvAPIToken, "")
+			t.Setenv(EnvGateway, "")
+			t.Setenv(EnvBaseURL, "")
+			t.Setenv(EnvProfile, "")
+
+			cfg, err := LoadConfig(path)
+			require.NoError(t, err)
+			assert.Equal(t, "prod-account", cfg.AccountID)
+			assert.Equal(t, "", cfg.Profile)
+
+			cfg, err = LoadConfigProfile(path, "staging")
+			require.NoError(t, err)
+			assert.Equal(t, "staging", cfg.Profile)
+			assert.Equal(t, "staging-account", cfg.AccountID)
+			assert.Equal(t, "staging-token", cfg.APIToken)
+			assert.Equal(t, "staging-gateway", cfg.Gateway)
+
+			t.Setenv(EnvProfile, "dev")
+			cfg, err = LoadConfig(path)
+			require.NoError(t, err)
+			assert.Equal(t, "prod-account", cfg.AccountID, "unset profile settings are inherited")
+			assert.Equal(t, "http://localhost:8787", cfg.BaseURL)
+
+			t.Setenv(EnvAccountID, "env-account")
+			t.Setenv(EnvAPIToken, "env-token")
+			cfg, err = LoadConfigProfile(path, "staging")
+			require.NoError(t, err)
+			assert.Equal(t, "staging-account", cfg.AccountID, "the profile takes precedence over the environment")
+			assert.Equal(t, "staging-token", cfg.APIToken)
+
+			cfg, err = LoadConfigProfile(path, "dev")
+			require.NoError(t, err)
+			assert.Equal(t, "env-account", cfg.AccountID, "the environment fills the settings the profile doesn't set")
+			assert.Equal(t, "env-token", cfg.APIToken)
+
+			_, err = LoadConfigProfile(path, "prod")
+			assert.EqualError(t, err, `unknown profile "prod", expected one of dev, staging`)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultContinuePrompt is the user message sent by AutoContinue when no
+// Prompt is configured.
+const DefaultContinuePrompt = "Continue exactly where you stopped. Don't repeat anything you already wrote."
+
+// AutoContinue makes ChatCompletion issue follow-up requests when a reply is
+// cut off at the token limit, and stitch the parts into a single response.
+//
+// OpenAI-compatible responses report the cut-off as finish_reason "length".
+// Legacy responses have no finish reason, so a reply counts as cut off when
+// it used all of the request's MaxTokens; without MaxTokens it is never
+// continued.
+type AutoContinue struct {
+	// MaxContinuations caps the number of follow-up requests. Zero disables
+	// AutoContinue.
+	MaxContinuations int
+	// Prompt is the user message asking the model to go on. Defaults to
+	// DefaultContinuePrompt.
+	Prompt string
+}
+
+// GetFinishReason returns the reason the model stopped, e.g. "stop",
+// "length" or "tool_calls". It is empty for legacy responses.
+func (r *ChatResponse) GetFinishReason() string {
+	if !r.IsLegacyResult && len(r.ChatCompletionResponse.Choices) > 0 {
+		return r.ChatCompletionResponse.Choices[0].FinishReason
+	}
+	return ""
+}
+
+// truncated reports whether the reply stopped at the token limit.
+func (r *ChatResponse) truncated(maxTokens int64) bool {
+	if len(r.GetToolCalls()) > 0 {
+		return false
+	}
+	if reason := r.GetFinishReason(); reason != "" {
+		return reason == "length"
+	}
+	return maxTokens > 0 && int64(r.GetUsage().CompletionTokens) >= maxTokens
+}
+
+// continueTruncated extends resp with follow-up requests while it is cut off.
+func (c *Client) continueTruncated(ctx context.Context, request ChatCompletionRequest, resp *ChatResponse) error {
+	prompt := c.AutoContinue.Prompt
+	if prompt == "" {
+		prompt = DefaultContinuePrompt
+	}
+
+	for i := 0; i < c.AutoContinue.MaxContinuations && resp.truncated(request.MaxTokens); i++ {
+		content := resp.GetContent()
+
+		followUp := request
+		// The prefill already starts content.
+		followUp.Prefill = ""
+		followUp.Messages = make([]Message, 0, len(request.Messages)+2)
+		followUp.Messages = append(followUp.Messages, request.Messages...)
+		followUp.Messages = append(followUp.Messages,
+			ChatMessage{Role: "assistant", Content: content},
+			ChatMessage{Role: "user", Content: prompt},
+		)
+
+		c.debugLog("Response cut off at the token limit, continuing (%d/%d)", i+1, c.AutoContinue.MaxContinuations)
+
+		next, err := c.complete(ctx, followUp)
+		if err != nil {
+			return fmt.Errorf("failed to continue truncated response: %w", err)
+		}
+
+		resp.setContent(content + next.GetContent())
+		resp.setFinishReason(next.GetFinishReason())
+		resp.addUsage(next.GetUsage())
+		resp.Continuations++
+	}
+	return nil
+}
+
+func (r *ChatResponse) setFinishReason(reason string) {
+	if !r.IsLegacyResult && len(r.ChatCompletionResponse.Choices) > 0 {
+		r.ChatCompletionResponse.Choices[0].FinishReason = reason
+	}
+}
+
+func (r *ChatResponse) addUsage(u Usage) {
+	usage := &r.ChatCompletionResponse.Usage
+	if r.IsLegacyResult {
+		usage = &r.LegacyResponse.Usage
+	}
+	usage.PromptTokens += u.PromptTokens
+	usage.CompletionTokens += u.CompletionTokens
+	usage.TotalTokens += u.TotalTokens
+}
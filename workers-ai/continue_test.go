@@ -0,0 +1,77 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_AutoContinue(t *testing.T) {
+	parts := []string{"The quick brown", " fox jumps over", " the lazy dog."}
+	var requests []ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+
+		i := len(requests) - 1
+		reason := "length"
+		if i == len(parts)-1 {
+			reason = "stop"
+		}
+		fmt.Fprintf(w, `{"success": true, "result": {"choices": [{"message": {"role": "assistant", "content": %q}, "finish_reason": %q}], "usage": {"prompt_tokens": 10, "completion_tokens": 3, "total_tokens": 13}}}`, parts[i], reason)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.AutoContinue = AutoContinue{MaxContinuations: 5}
+
+	user := ChatMessage{Role: "user", Content: "Write a pangram."}
+	resp, err := client.Chat(ModelLlama38B, []Message{user}, &ModelParameters{MaxTokens: 3})
+	require.NoError(t, err)
+
+	assert.Equal(t, "The quick brown fox jumps over the lazy dog.", resp.GetContent())
+	assert.Equal(t, "stop", resp.GetFinishReason())
+	assert.Equal(t, 2, resp.Continuations)
+	assert.Equal(t, Usage{PromptTokens: 30, CompletionTokens: 9, TotalTokens: 39}, resp.GetUsage())
+
+	require.Len(t, requests, 3)
+	assert.Equal(t, []Message{
+		user,
+		ChatMessage{Role: "assistant", Content: "The quick brown fox jumps over"},
+		ChatMessage{Role: "user", Content: DefaultContinuePrompt},
+	}, requests[2].Messages)
+}
+
+func TestClient_AutoContinue_Legacy(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"success": true, "result": {"response": "ab", "usage": {"completion_tokens": 2}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.AutoContinue = AutoContinue{MaxContinuations: 2}
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	// Every reply uses all tokens, so the cap ends the loop.
+	resp, err := client.Chat(ModelLlama38B, messages, &ModelParameters{MaxTokens: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "ababab", resp.GetContent())
+	assert.Equal(t, 3, calls)
+
+	// Without MaxTokens a legacy reply can't be known to be cut off.
+	calls = 0
+	resp, err = client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ab", resp.GetContent())
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,128 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// NeuronPriceUSD is the price of one neuron beyond the daily free
+// allocation.
+const NeuronPriceUSD = 0.011 / 1000
+
+// DefaultMaxTokens is the output limit Workers AI applies to requests that
+// don't set max_tokens.
+const DefaultMaxTokens = 256
+
+// ModelPrice is the price of a model in neurons per million tokens.
+type ModelPrice struct {
+	InputNeurons  float64 `yaml:"input_neurons"`
+	OutputNeurons float64 `yaml:"output_neurons"`
+}
+
+// neurons returns the neurons of usage at price p.
+func (p ModelPrice) neurons(usage Usage) float64 {
+	return (float64(usage.PromptTokens)*p.InputNeurons + float64(usage.CompletionTokens)*p.OutputNeurons) / 1e6
+}
+
+// DefaultPricing is the bundled price list, from the Workers AI pricing page
+// as of mid-2025. Set Client.Pricing to override or extend it.
+var DefaultPricing = map[string]ModelPrice{
+	ModelLlama4Scout17B:   {InputNeurons: 24545, OutputNeurons: 77273},
+	ModelLlama38B:         {InputNeurons: 25608, OutputNeurons: 75147},
+	ModelMistral7B:        {InputNeurons: 10000, OutputNeurons: 17300},
+	ModelQwen330ba3b:      {InputNeurons: 4625, OutputNeurons: 30475},
+	ModelLlama32Vision11B: {InputNeurons: 4410, OutputNeurons: 61493},
+	ModelBAAI:             {InputNeurons: 6058},
+	ModelBAAILarge:        {InputNeurons: 18582},
+	ModelM2M100:           {InputNeurons: 31050, OutputNeurons: 31050},
+}
+
+// CostEstimate is the upper bound of the cost of a request, assuming the
+// model generates all the tokens it may.
+type CostEstimate struct {
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	Neurons      float64
+	// USD is the price of the neurons, ignoring the free allocation.
+	USD float64
+}
+
+// Add returns the sum of e and other, for budgeting several requests. The
+// Model of the sum is kept only when both are for the same model.
+func (e CostEstimate) Add(other CostEstimate) CostEstimate {
+	if e.Model != other.Model {
+		e.Model = ""
+	}
+	e.InputTokens += other.InputTokens
+	e.OutputTokens += other.OutputTokens
+	e.Neurons += other.Neurons
+	e.USD += other.USD
+	return e
+}
+
+// EstimateTokens approximates the number of tokens of text, at about four
+// characters per token. It is meant for budgeting, not for exact limits.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// EstimateCost estimates the cost of sending messages to modelID, without
+// sending anything. The client's Defaults are taken into account; a
+// maxTokens of zero falls back to them and then to DefaultMaxTokens.
+func (c *Client) EstimateCost(modelID string, messages []Message, maxTokens int) (*CostEstimate, error) {
+	price, ok := c.price(modelID)
+	if !ok {
+		return nil, fmt.Errorf("no pricing for model %s", modelID)
+	}
+
+	request := ChatCompletionRequest{Model: modelID, Messages: messages}
+	request.MaxTokens = int64(maxTokens)
+	estimate, err := c.estimate(request)
+	if err != nil {
+		return nil, err
+	}
+	if price.OutputNeurons == 0 {
+		estimate.OutputTokens = 0
+	}
+
+	estimate.Neurons = (float64(estimate.InputTokens)*price.InputNeurons + float64(estimate.OutputTokens)*price.OutputNeurons) / 1e6
+	estimate.USD = estimate.Neurons * NeuronPriceUSD
+	return estimate, nil
+}
+
+// estimate counts the tokens request may use, with the client's Defaults
+// applied.
+func (c *Client) estimate(request ChatCompletionRequest) (*CostEstimate, error) {
+	c.applyDefaults(&request)
+	if request.MaxTokens == 0 {
+		request.MaxTokens = DefaultMaxTokens
+	}
+
+	estimate := &CostEstimate{Model: request.Model, OutputTokens: int(request.MaxTokens)}
+	for _, msg := range request.Messages {
+		// Count the message as it is encoded, so that every message type,
+		// including tool calls and images, is covered.
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message: %w", err)
+		}
+		estimate.InputTokens += EstimateTokens(string(encoded))
+	}
+	return estimate, nil
+}
+
+// price looks modelID up in c.Pricing and then in DefaultPricing.
+func (c *Client) price(modelID string) (ModelPrice, bool) {
+	return lookupPrice(c.Pricing, modelID)
+}
+
+// lookupPrice looks modelID up in pricing and then in DefaultPricing.
+func lookupPrice(pricing map[string]ModelPrice, modelID string) (ModelPrice, bool) {
+	if price, ok := pricing[modelID]; ok {
+		return price, true
+	}
+	price, ok := DefaultPricing[modelID]
+	return price, ok
+}
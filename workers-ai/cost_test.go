@@ -0,0 +1,54 @@
+package workersai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 1, EstimateTokens("Hi"))
+	assert.Equal(t, 3, EstimateTokens("Hello, world"))
+	assert.Equal(t, 1, EstimateTokens("日本語"))
+}
+
+func TestClient_EstimateCost(t *testing.T) {
+	client := NewClient("test-account", "test-token")
+
+	// {"role":"user","content":"Hi"} is 30 characters.
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	estimate, err := client.EstimateCost(ModelLlama38B, messages, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 8, estimate.InputTokens)
+	assert.Equal(t, 100, estimate.OutputTokens)
+	assert.InDelta(t, (8*25608+100*75147)/1e6, estimate.Neurons, 1e-9)
+	assert.InDelta(t, estimate.Neurons*0.011/1000, estimate.USD, 1e-12)
+
+	// Defaults add their system prompt and max tokens.
+	client.Defaults = ChatDefaults{SystemPrompt: "Be brief.", ModelParameters: ModelParameters{MaxTokens: 50}}
+	withDefaults, err := client.EstimateCost(ModelLlama38B, messages, 0)
+	require.NoError(t, err)
+	assert.Greater(t, withDefaults.InputTokens, estimate.InputTokens)
+	assert.Equal(t, 50, withDefaults.OutputTokens)
+
+	client.Defaults = ChatDefaults{}
+	estimate, err = client.EstimateCost(ModelLlama38B, messages, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMaxTokens, estimate.OutputTokens)
+
+	_, err = client.EstimateCost("@cf/custom/model", messages, 0)
+	assert.ErrorContains(t, err, "no pricing")
+
+	client.Pricing = map[string]ModelPrice{"@cf/custom/model": {InputNeurons: 1e6, OutputNeurons: 2e6}}
+	estimate, err = client.EstimateCost("@cf/custom/model", messages, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 8.0+20.0, estimate.Neurons)
+
+	total := estimate.Add(*estimate)
+	assert.Equal(t, "@cf/custom/model", total.Model)
+	assert.Equal(t, 56.0, total.Neurons)
+	assert.Equal(t, "", total.Add(CostEstimate{Model: ModelLlama38B}).Model)
+}
@@ -0,0 +1,298 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// D1Database runs SQL queries on a Cloudflare D1 database through the
+// Cloudflare API. The API token needs the D1 Edit permission.
+type D1Database struct {
+	Client *Client
+	// ID is the UUID of the database, not its name.
+	ID string
+}
+
+// D1Database returns the D1 database with the ID databaseID, of the
+// client's account.
+func (c *Client) D1Database(databaseID string) *D1Database {
+	return &D1Database{Client: c, ID: databaseID}
+}
+
+// D1Result is the result of a D1 statement.
+type D1Result struct {
+	// Rows are the rows returned by the statement, as JSON objects keyed
+	// by column name. Decode them with Scan.
+	Rows []json.RawMessage `json:"results"`
+	Meta struct {
+		Changes   int     `json:"changes"`
+		LastRowID int64   `json:"last_row_id"`
+		Duration  float64 `json:"duration"`
+	} `json:"meta"`
+}
+
+// Scan decodes the rows into out, a pointer to a slice of structs or maps.
+func (r *D1Result) Scan(out interface{}) error {
+	rows := r.Rows
+	if rows == nil {
+		rows = []json.RawMessage{}
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to scan rows: %w", err)
+	}
+	return nil
+}
+
+// Query runs sql, with its ? placeholders bound to params, and returns the
+// result of its last statement. Without params, sql may hold several
+// statements separated by semicolons.
+func (d *D1Database) Query(ctx context.Context, sql string, params ...interface{}) (*D1Result, error) {
+	request := struct {
+		SQL    string        `json:"sql"`
+		Params []interface{} `json:"params,omitempty"`
+	}{sql, params}
+
+	var results []D1Result
+	path := fmt.Sprintf("/accounts/%s/d1/database/%s/query", d.Client.AccountID, url.PathEscape(d.ID))
+	if _, err := d.Client.apiPost(ctx, path, request, &results); err != nil {
+		return nil, fmt.Errorf("failed to query database %s: %w", d.ID, err)
+	}
+	if len(results) == 0 {
+		return &D1Result{}, nil
+	}
+	return &results[len(results)-1], nil
+}
+
+// D1Migration is a change of the schema of a database, applied once.
+type D1Migration struct {
+	// Version orders the migrations. Applied versions are recorded in the
+	// d1_migrations_workersai table.
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrate applies the migrations newer than the version of the database,
+// in order, and returns the number applied. A failed migration stops the
+// run; the ones before it stay applied.
+func (d *D1Database) Migrate(ctx context.Context, migrations []D1Migration) (int, error) {
+	_, err := d.Query(ctx, `CREATE TABLE IF NOT EXISTS d1_migrations_workersai (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+)`)
+	if err != nil {
+		return 0, err
+	}
+	current, err := d.SchemaVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := make([]D1Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+
+	for i, m := range pending {
+		if _, err := d.Query(ctx, m.SQL); err != nil {
+			return i, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		_, err := d.Query(ctx, "INSERT INTO d1_migrations_workersai (version, name, applied_at) VALUES (?, ?, ?)",
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			return i, fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+	}
+	return len(pending), nil
+}
+
+// SchemaVersion returns the version of the last migration applied by
+// Migrate, 0 if none.
+func (d *D1Database) SchemaVersion(ctx context.Context) (int, error) {
+	result, err := d.Query(ctx, "SELECT COALESCE(MAX(version), 0) AS version FROM d1_migrations_workersai")
+	if err != nil {
+		return 0, err
+	}
+	var rows []struct {
+		Version int `json:"version"`
+	}
+	if err := result.Scan(&rows); err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return rows[0].Version, nil
+}
+
+// SessionMigrations create the tables of D1SessionStore: conversations,
+// holding the transcripts of the sessions, and usage_records, holding the
+// token usage of their requests.
+var SessionMigrations = []D1Migration{{
+	Version: 1,
+	Name:    "create conversations and usage_records",
+	SQL: `CREATE TABLE IF NOT EXISTS conversations (
+	id TEXT PRIMARY KEY,
+	model TEXT NOT NULL DEFAULT '',
+	system_prompt TEXT NOT NULL DEFAULT '',
+	messages TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS usage_records (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	conversation_id TEXT NOT NULL,
+	model TEXT NOT NULL,
+	prompt_tokens INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL,
+	total_tokens INTEGER NOT NULL,
+	recorded_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS usage_records_conversation ON usage_records (conversation_id)`,
+}}
+
+// D1SessionStore persists chat sessions and their usage in a D1 database,
+// for applications built entirely on Cloudflare. It is a MemoryStore; call
+// Migrate once to create its tables.
+type D1SessionStore struct {
+	DB *D1Database
+}
+
+// NewD1SessionStore returns a store of sessions in db.
+func NewD1SessionStore(db *D1Database) *D1SessionStore {
+	return &D1SessionStore{DB: db}
+}
+
+// Migrate applies SessionMigrations to the database.
+func (s *D1SessionStore) Migrate(ctx context.Context) error {
+	_, err := s.DB.Migrate(ctx, SessionMigrations)
+	return err
+}
+
+func (s *D1SessionStore) Load(id string) ([]Message, bool, error) {
+	record, err := s.load(context.Background(), id)
+	if err != nil || record == nil {
+		return nil, false, err
+	}
+	messages, err := decodeMessages([]byte(record.Messages))
+	if err != nil {
+		return nil, false, fmt.Errorf("conversation %s: %w", id, err)
+	}
+	return messages, true, nil
+}
+
+func (s *D1SessionStore) Save(id string, messages []Message) error {
+	return s.save(context.Background(), id, nil, messages)
+}
+
+// SaveSession stores the transcript of session under id, with its model and
+// system prompt.
+func (s *D1SessionStore) SaveSession(ctx context.Context, id string, session *ChatSession) error {
+	return s.save(ctx, id, session, session.Messages)
+}
+
+// LoadSession returns the session stored under id, talking to client, with
+// the usage recorded for it. It returns ErrNoMemory when there is none.
+func (s *D1SessionStore) LoadSession(ctx context.Context, id string, client ClientInterface) (*ChatSession, error) {
+	record, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoMemory, id)
+	}
+	messages, err := decodeMessages([]byte(record.Messages))
+	if err != nil {
+		return nil, fmt.Errorf("conversation %s: %w", id, err)
+	}
+	usage, err := s.Usage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	session := NewChatSession(client, record.Model)
+	session.SystemPrompt = record.SystemPrompt
+	session.Messages = messages
+	session.Usage = usage
+	return session, nil
+}
+
+// RecordUsage adds a usage record of model for the conversation id, e.g.
+// with the usage of every response of the session.
+func (s *D1SessionStore) RecordUsage(ctx context.Context, id, model string, usage Usage) error {
+	_, err := s.DB.Query(ctx, `INSERT INTO usage_records (conversation_id, model, prompt_tokens, completion_tokens, total_tokens, recorded_at)
+VALUES (?, ?, ?, ?, ?, ?)`, id, model, usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to record usage of conversation %s: %w", id, err)
+	}
+	return nil
+}
+
+// Usage returns the sum of the usage records of the conversation id.
+func (s *D1SessionStore) Usage(ctx context.Context, id string) (Usage, error) {
+	result, err := s.DB.Query(ctx, `SELECT COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+	COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+	COALESCE(SUM(total_tokens), 0) AS total_tokens
+FROM usage_records WHERE conversation_id = ?`, id)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to read usage of conversation %s: %w", id, err)
+	}
+	var rows []Usage
+	if err := result.Scan(&rows); err != nil || len(rows) == 0 {
+		return Usage{}, err
+	}
+	return rows[0], nil
+}
+
+type d1Conversation struct {
+	Model        string `json:"model"`
+	SystemPrompt string `json:"system_prompt"`
+	Messages     string `json:"messages"`
+}
+
+// load returns the conversation stored under id, nil if none.
+func (s *D1SessionStore) load(ctx context.Context, id string) (*d1Conversation, error) {
+	result, err := s.DB.Query(ctx, "SELECT model, system_prompt, messages FROM conversations WHERE id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation %s: %w", id, err)
+	}
+	var rows []d1Conversation
+	if err := result.Scan(&rows); err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+// save stores messages under id, with the model and system prompt of
+// session if not nil. Otherwise those of the stored conversation are kept.
+func (s *D1SessionStore) save(ctx context.Context, id string, session *ChatSession, messages []Message) error {
+	if messages == nil {
+		messages = []Message{}
+	}
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation %s: %w", id, err)
+	}
+
+	update := "messages = excluded.messages, updated_at = excluded.updated_at"
+	var model, systemPrompt string
+	if session != nil {
+		model, systemPrompt = session.Model, session.SystemPrompt
+		update += ", model = excluded.model, system_prompt = excluded.system_prompt"
+	}
+	_, err = s.DB.Query(ctx, `INSERT INTO conversations (id, model, system_prompt, messages, updated_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET `+update,
+		id, model, systemPrompt, string(data), time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to save conversation %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type d1Query struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params"`
+}
+
+// newFakeD1 serves the query endpoint of the D1 API, answering every query
+// with the rows returned by respond.
+func newFakeD1(t *testing.T, respond func(q d1Query) []map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/accounts/test-account/d1/database/db1/query", r.URL.Path)
+
+		var q d1Query
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+		rows := respond(q)
+		if rows == nil {
+			rows = []map[string]interface{}{}
+		}
+		results, err := json.Marshal(rows)
+		require.NoError(t, err)
+		fmt.Fprintf(w, `{"success": true, "result": [{"results": %s, "success": true, "meta": {"changes": 1, "last_row_id": 7}}]}`, results)
+	}))
+}
+
+func TestD1Database_Query(t *testing.T) {
+	server := newFakeD1(t, func(q d1Query) []map[string]interface{} {
+		assert.Equal(t, "SELECT id, name FROM users WHERE age > ?", q.SQL)
+		assert.Equal(t, []interface{}{30.0}, q.Params)
+		return []map[string]interface{}{{"id": 1, "name": "Ada"}, {"id": 2, "name": "Alan"}}
+	})
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	result, err := client.D1Database("db1").Query(context.Background(), "SELECT id, name FROM users WHERE age > ?", 30)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), result.Meta.LastRowID)
+
+	var users []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	require.NoError(t, result.Scan(&users))
+	require.Len(t, users, 2)
+	assert.Equal(t, "Alan", users[1].Name)
+}
+
+func TestD1Database_Migrate(t *testing.T) {
+	version := 1
+	var statements []string
+	server := newFakeD1(t, func(q d1Query) []map[string]interface{} {
+		switch {
+		case strings.HasPrefix(q.SQL, "SELECT COALESCE(MAX(version)"):
+			return []map[string]interface{}{{"version": version}}
+		case strings.HasPrefix(q.SQL, "INSERT INTO d1_migrations_workersai"):
+			version = int(q.Params[0].(float64))
+		case !strings.HasPrefix(q.SQL, "CREATE TABLE IF NOT EXISTS d1_migrations_workersai"):
+			statements = append(statements, q.SQL)
+		}
+		return nil
+	})
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	db := client.D1Database("db1")
+
+	migrations := []D1Migration{
+		{Version: 3, Name: "third", SQL: "ALTER TABLE t ADD COLUMN c"},
+		{Version: 1, Name: "first", SQL: "CREATE TABLE t (a)"},
+		{Version: 2, Name: "second", SQL: "ALTER TABLE t ADD COLUMN b"},
+	}
+	applied, err := db.Migrate(context.Background(), migrations)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, []string{"ALTER TABLE t ADD COLUMN b", "ALTER TABLE t ADD COLUMN c"}, statements)
+	assert.Equal(t, 3, version)
+
+	applied, err = db.Migrate(context.Background(), migrations)
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+}
+
+func TestD1SessionStore(t *testing.T) {
+	conversations := make(map[string]map[string]interface{})
+	var usage []Usage
+	server := newFakeD1(t, func(q d1Query) []map[string]interface{} {
+		switch {
+		case strings.HasPrefix(q.SQL, "INSERT INTO conversations"):
+			id := q.Params[0].(string)
+			row := map[string]interface{}{"model": q.Params[1], "system_prompt": q.Params[2], "messages": q.Params[3]}
+			if old, ok := conversations[id]; ok && !strings.Contains(q.SQL, "model = excluded.model") {
+				row["model"], row["system_prompt"] = old["model"], old["system_prompt"]
+			}
+			conversations[id] = row
+		case strings.HasPrefix(q.SQL, "SELECT model, system_prompt, messages FROM conversations"):
+			if row, ok := conversations[q.Params[0].(string)]; ok {
+				return []map[string]interface{}{row}
+			}
+		case strings.HasPrefix(q.SQL, "INSERT INTO usage_records"):
+			usage = append(usage, Usage{int(q.Params[2].(float64)), int(q.Params[3].(float64)), int(q.Params[4].(float64))})
+		case strings.Contains(q.SQL, "FROM usage_records"):
+			var sum Usage
+			for _, u := range usage {
+				sum.PromptTokens += u.PromptTokens
+				sum.CompletionTokens += u.CompletionTokens
+				sum.TotalTokens += u.TotalTokens
+			}
+			return []map[string]interface{}{{"prompt_tokens": sum.PromptTokens, "completion_tokens": sum.CompletionTokens, "total_tokens": sum.TotalTokens}}
+		}
+		return nil
+	})
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	store := NewD1SessionStore(client.D1Database("db1"))
+	ctx := context.Background()
+
+	_, err := store.LoadSession(ctx, "chat-1", client)
+	assert.ErrorIs(t, err, ErrNoMemory)
+
+	session := NewChatSession(client, "@cf/meta/llama-3.1-8b-instruct")
+	session.SystemPrompt = "Be brief."
+	session.Messages = []Message{
+		ChatMessage{Role: "user", Content: "Hi"},
+		ChatMessage{Role: "assistant", Content: "Hello!"},
+	}
+	require.NoError(t, store.SaveSession(ctx, "chat-1", session))
+	require.NoError(t, store.RecordUsage(ctx, "chat-1", session.Model, Usage{10, 2, 12}))
+	require.NoError(t, store.RecordUsage(ctx, "chat-1", session.Model, Usage{20, 3, 23}))
+
+	// Saving as a MemoryStore keeps the model and system prompt.
+	session.Messages = append(session.Messages, ChatMessage{Role: "user", Content: "Bye"})
+	require.NoError(t, session.Save(store, "chat-1"))
+
+	loaded, err := store.LoadSession(ctx, "chat-1", client)
+	require.NoError(t, err)
+	assert.Equal(t, session.Model, loaded.Model)
+	assert.Equal(t, "Be brief.", loaded.SystemPrompt)
+	assert.Equal(t, session.Messages, loaded.Messages)
+	assert.Equal(t, Usage{30, 5, 35}, loaded.Usage)
+}
@@ -0,0 +1,341 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DecodeMode selects how a Client treats responses of unexpected shape.
+type DecodeMode int
+
+const (
+	// DecodeLenient decodes what it can and records the problems in
+	// ChatResponse.Diagnostics. It is the default.
+	DecodeLenient DecodeMode = iota
+	// DecodeStrict fails with a *DecodeError when there are diagnostics.
+	// It is meant for tests that should notice API format changes.
+	DecodeStrict
+)
+
+// DecodeDiagnostic is a non-fatal problem found while decoding a response.
+type DecodeDiagnostic struct {
+	// Path locates the offending field, e.g. "result.usage".
+	Path    string
+	Message string
+}
+
+func (d DecodeDiagnostic) String() string {
+	return d.Path + ": " + d.Message
+}
+
+// DecodeError is returned in DecodeStrict mode for responses with
+// diagnostics.
+type DecodeError struct {
+	Diagnostics []DecodeDiagnostic
+}
+
+func (e *DecodeError) Error() string {
+	problems := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		problems[i] = d.String()
+	}
+	return "unexpected response format: " + strings.Join(problems, "; ")
+}
+
+// checkDecode enforces c.DecodeMode on resp.
+func (c *Client) checkDecode(resp *ChatResponse) error {
+	if len(resp.Diagnostics) == 0 {
+		return nil
+	}
+	c.debugLog("Response decoded with diagnostics: %v", resp.Diagnostics)
+	if c.DecodeMode == DecodeStrict {
+		return &DecodeError{Diagnostics: resp.Diagnostics}
+	}
+	return nil
+}
+
+func (cr *ChatResponse) diagnose(path, format string, args ...interface{}) {
+	cr.Diagnostics = append(cr.Diagnostics, DecodeDiagnostic{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkUsage records a diagnostic when the result has no usage.
+func (cr *ChatResponse) checkUsage(fields map[string]json.RawMessage) {
+	if raw, ok := fields["usage"]; !ok || isNull(raw) {
+		cr.diagnose("result.usage", "missing")
+	}
+}
+
+// decodeChatCompletionLeniently decodes an OpenAI-compatible result field
+// by field, after the regular decoding failed.
+func (cr *ChatResponse) decodeChatCompletionLeniently(fields map[string]json.RawMessage) {
+	out := &cr.ChatCompletionResponse
+	cr.decodeField(fields, "id", "result.id", &out.ID)
+	cr.decodeField(fields, "object", "result.object", &out.Object)
+	cr.decodeField(fields, "created", "result.created", &out.Created)
+	cr.decodeField(fields, "model", "result.model", &out.Model)
+	if raw, ok := fields["usage"]; ok {
+		out.Usage = cr.decodeUsage(raw, "result.usage")
+	}
+
+	var choices []json.RawMessage
+	if err := cr.unmarshal(fields["choices"], &choices); err != nil {
+		cr.diagnose("result.choices", "not an array: %v", err)
+		return
+	}
+
+	for i, raw := range choices {
+		path := fmt.Sprintf("result.choices[%d]", i)
+		var choiceFields map[string]json.RawMessage
+		if err := cr.unmarshal(raw, &choiceFields); err != nil {
+			cr.diagnose(path, "not an object: %v", err)
+			continue
+		}
+
+		choice := Choice{Index: i}
+		cr.decodeField(choiceFields, "index", path+".index", &choice.Index)
+		cr.decodeField(choiceFields, "finish_reason", path+".finish_reason", &choice.FinishReason)
+
+		var messageFields map[string]json.RawMessage
+		if raw := choiceFields["message"]; !isNull(raw) {
+			if err := cr.unmarshal(raw, &messageFields); err != nil {
+				cr.diagnose(path+".message", "not an object: %v", err)
+			}
+		}
+		choice.Message.Role = "assistant"
+		cr.decodeField(messageFields, "role", path+".message.role", &choice.Message.Role)
+		cr.decodeField(messageFields, "tool_calls", path+".message.tool_calls", &choice.Message.ToolCalls)
+		cr.decodeField(messageFields, "reasoning_content", path+".message.reasoning_content", &choice.Message.ReasoningContent)
+		if raw, ok := messageFields["content"]; ok && !isNull(raw) {
+			content := cr.decodeContent(raw, path+".message.content")
+			choice.Message.Content = &content
+		}
+
+		out.Choices = append(out.Choices, choice)
+	}
+}
+
+// checkChoices records a diagnostic for the choices without a message, or
+// with an empty one, and defaults their role to "assistant".
+func (cr *ChatResponse) checkChoices() {
+	for i := range cr.ChatCompletionResponse.Choices {
+		message := &cr.ChatCompletionResponse.Choices[i].Message
+		if message.Role == "" {
+			message.Role = "assistant"
+		}
+		if message.Content == nil && len(message.ToolCalls) == 0 && message.ReasoningContent == "" {
+			cr.diagnose(fmt.Sprintf("result.choices[%d].message", i), "empty")
+		}
+	}
+}
+
+// decodeLegacyLeniently decodes a legacy result field by field, after the
+// regular decoding failed.
+func (cr *ChatResponse) decodeLegacyLeniently(fields map[string]json.RawMessage) {
+	out := &cr.LegacyResponse
+	if raw, ok := fields["response"]; ok && !isNull(raw) {
+		out.Response = cr.decodeContent(raw, "result.response")
+	}
+	cr.decodeField(fields, "tool_calls", "result.tool_calls", &out.ToolCalls)
+	if raw, ok := fields["usage"]; ok {
+		out.Usage = cr.decodeUsage(raw, "result.usage")
+	}
+}
+
+// decodeField unmarshals fields[name] into out, recording a diagnostic on
+// failure. Missing and null fields are left alone.
+func (cr *ChatResponse) decodeField(fields map[string]json.RawMessage, name, path string, out interface{}) {
+	raw, ok := fields[name]
+	if !ok || isNull(raw) {
+		return
+	}
+	if err := cr.unmarshal(raw, out); err != nil {
+		cr.diagnose(path, "ignored: %v", err)
+	}
+}
+
+// decodeContent reads message content given as a string, as an array of
+// {"type": "text", "text": ...} parts, or as any other value, which is kept
+// as its JSON text.
+func (cr *ChatResponse) decodeContent(raw json.RawMessage, path string) string {
+	var text string
+	if err := cr.unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := cr.unmarshal(raw, &parts); err == nil {
+		var b strings.Builder
+		for _, part := range parts {
+			b.WriteString(part.Text)
+		}
+		cr.diagnose(path, "content parts joined into text")
+		return b.String()
+	}
+
+	cr.diagnose(path, "not a string, kept as JSON")
+	return string(bytes.TrimSpace(raw))
+}
+
+// decodeUsage reads token counts given as numbers or numeric strings.
+func (cr *ChatResponse) decodeUsage(raw json.RawMessage, path string) Usage {
+	var fields map[string]interface{}
+	if err := cr.unmarshal(raw, &fields); err != nil {
+		cr.diagnose(path, "ignored: %v", err)
+		return Usage{}
+	}
+
+	count := func(name string) int {
+		switch v := fields[name].(type) {
+		case nil:
+			return 0
+		case float64:
+			return int(v)
+		case string:
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				cr.diagnose(path+"."+name, "number given as string")
+				return int(n)
+			}
+		}
+		cr.diagnose(path+"."+name, "ignored: unexpected value %v", fields[name])
+		return 0
+	}
+
+	return Usage{
+		PromptTokens:     count("prompt_tokens"),
+		CompletionTokens: count("completion_tokens"),
+		TotalTokens:      count("total_tokens"),
+	}
+}
+
+// fieldAliases maps the camelCase variants of field names some models
+// return to the snake_case names of the documented formats. Client
+// FieldAliases extends it.
+var fieldAliases = map[string]string{
+	"toolCalls":        "tool_calls",
+	"toolCallId":       "tool_call_id",
+	"finishReason":     "finish_reason",
+	"reasoningContent": "reasoning_content",
+	"promptTokens":     "prompt_tokens",
+	"completionTokens": "completion_tokens",
+	"totalTokens":      "total_tokens",
+}
+
+// fieldAliases returns the built-in field aliases extended with
+// c.FieldAliases.
+func (c *Client) fieldAliases() map[string]string {
+	if len(c.FieldAliases) == 0 {
+		return fieldAliases
+	}
+	aliases := make(map[string]string, len(fieldAliases)+len(c.FieldAliases))
+	for alias, name := range fieldAliases {
+		aliases[alias] = name
+	}
+	for alias, name := range c.FieldAliases {
+		aliases[alias] = name
+	}
+	return aliases
+}
+
+// opaqueFields hold values of the model or the caller, e.g. the arguments
+// of a tool call, whose field names are kept as they are.
+var opaqueFields = map[string]bool{
+	"arguments": true,
+	"content":   true,
+	"response":  true,
+}
+
+// snakeCaseFields renames the fields of raw, and of the objects it contains,
+// named after aliases, fieldAliases if nil, unless the documented name is
+// present too. It returns raw unchanged when nothing was renamed, and the
+// paths of the renamed fields, rooted at path.
+func snakeCaseFields(raw json.RawMessage, path string, aliases map[string]string) (json.RawMessage, []string) {
+	if aliases == nil {
+		aliases = fieldAliases
+	}
+	// Most payloads have no alias: don't decode them a second time.
+	if !mentionsAlias(raw, aliases) {
+		return raw, nil
+	}
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return raw, nil
+	}
+
+	switch trimmed[0] {
+	case '{':
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &fields); err != nil {
+			return raw, nil
+		}
+		var renamed []string
+		normalized := make(map[string]json.RawMessage, len(fields))
+		for name, value := range fields {
+			if opaqueFields[name] {
+				normalized[name] = value
+				continue
+			}
+			target := name
+			if alias, ok := aliases[name]; ok {
+				if _, taken := fields[alias]; !taken {
+					target = alias
+					renamed = append(renamed, path+"."+name)
+				}
+			}
+			var inner []string
+			normalized[target], inner = snakeCaseFields(value, path+"."+target, aliases)
+			renamed = append(renamed, inner...)
+		}
+		if len(renamed) == 0 {
+			return raw, nil
+		}
+		out, err := json.Marshal(normalized)
+		if err != nil {
+			return raw, nil
+		}
+		sort.Strings(renamed)
+		return out, renamed
+
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return raw, nil
+		}
+		var renamed []string
+		for i, item := range items {
+			var inner []string
+			items[i], inner = snakeCaseFields(item, fmt.Sprintf("%s[%d]", path, i), aliases)
+			renamed = append(renamed, inner...)
+		}
+		if len(renamed) == 0 {
+			return raw, nil
+		}
+		out, err := json.Marshal(items)
+		if err != nil {
+			return raw, nil
+		}
+		return out, renamed
+	}
+	return raw, nil
+}
+
+// mentionsAlias reports whether raw may have a field named after one of
+// the aliases: it contains its name. False positives only cost a decoding.
+func mentionsAlias(raw json.RawMessage, aliases map[string]string) bool {
+	for alias := range aliases {
+		if bytes.Contains(raw, []byte(alias)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null"
+}
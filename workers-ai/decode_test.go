@@ -0,0 +1,280 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatResponse_LenientDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		result      string
+		content     string
+		usage       Usage
+		diagnostics []string
+	}{
+		{
+			name:    "well formed",
+			result:  `{"choices": [{"message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop", "logprobs": {"content": []}}], "usage": {"prompt_tokens": 1, "completion_tokens": 2, "total_tokens": 3}}`,
+			content: "Hi",
+			usage:   Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3},
+		},
+		{
+			name:        "content parts and string usage",
+			result:      `{"choices": [{"message": {"role": "assistant", "content": [{"type": "text", "text": "Hel"}, {"type": "text", "text": "lo"}]}}], "usage": {"prompt_tokens": "4", "completion_tokens": 2, "total_tokens": 6}}`,
+			content:     "Hello",
+			usage:       Usage{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6},
+			diagnostics: []string{"result.usage.prompt_tokens: number given as string", "result.choices[0].message.content: content parts joined into text"},
+		},
+		{
+			name:        "missing usage",
+			result:      `{"response": "Hi"}`,
+			content:     "Hi",
+			diagnostics: []string{"result.usage: missing"},
+		},
+		{
+			name:        "malformed legacy tool calls",
+			result:      `{"response": "Hi", "tool_calls": {"name": "x"}, "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}`,
+			content:     "Hi",
+			usage:       Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+			diagnostics: []string{"result.tool_calls: ignored: json: cannot unmarshal object into Go value of type []workersai.LegacyToolCall"},
+		},
+		{
+			name:        "string result",
+			result:      `"Hi"`,
+			content:     "Hi",
+			diagnostics: []string{"result: not an object"},
+		},
+		{
+			name:        "empty choice message",
+			result:      `{"choices": [{}], "usage": {"prompt_tokens": 1, "completion_tokens": 0, "total_tokens": 1}}`,
+			usage:       Usage{PromptTokens: 1, TotalTokens: 1},
+			diagnostics: []string{"result.choices[0].message: empty"},
+		},
+		{
+			name:        "null choice message",
+			result:      `{"choices": [{"message": null, "index": "0"}]}`,
+			diagnostics: []string{"result.choices[0].index: ignored: json: cannot unmarshal string into Go value of type int", "result.choices[0].message: empty", "result.usage: missing"},
+		},
+		{
+			name:    "object response",
+			result:  `{"response": {"text": "Hi"}, "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}`,
+			content: `{"text": "Hi"}`,
+			usage:   Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+		},
+		{
+			name:        "hybrid tool calls with string usage",
+			result:      `{"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "f", "arguments": "{}"}}], "usage": {"prompt_tokens": "2", "completion_tokens": 1, "total_tokens": 3}}`,
+			usage:       Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3},
+			diagnostics: []string{"result.usage.prompt_tokens: number given as string"},
+		},
+		{
+			name:        "camelCase fields",
+			result:      `{"choices": [{"message": {"role": "assistant", "content": "Hi"}, "finishReason": "stop"}], "usage": {"promptTokens": 1, "completionTokens": 2, "totalTokens": 3}}`,
+			content:     "Hi",
+			usage:       Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3},
+			diagnostics: []string{"result.choices[0].finishReason: camelCase name accepted", "result.usage.completionTokens: camelCase name accepted", "result.usage.promptTokens: camelCase name accepted", "result.usage.totalTokens: camelCase name accepted"},
+		},
+		{
+			name:    "null result",
+			result:  `null`,
+			content: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp ChatResponse
+			require.NoError(t, json.Unmarshal([]byte(`{"success": true, "result": `+tt.result+`}`), &resp))
+
+			assert.Equal(t, tt.content, resp.GetContent())
+			assert.Equal(t, tt.usage, resp.GetUsage())
+
+			var diagnostics []string
+			for _, d := range resp.Diagnostics {
+				diagnostics = append(diagnostics, d.String())
+			}
+			assert.Equal(t, tt.diagnostics, diagnostics)
+		})
+	}
+}
+
+func TestChatResponse_CamelCaseToolCalls(t *testing.T) {
+	tests := []struct {
+		name   string
+		result string
+		format ResponseFormat
+	}{
+		{
+			name:   "openai",
+			result: `{"choices": [{"message": {"role": "assistant", "toolCalls": [{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"cityName\": \"Paris\"}"}}]}}], "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}`,
+			format: FormatOpenAI,
+		},
+		{
+			name:   "hybrid",
+			result: `{"toolCalls": [{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"cityName\": \"Paris\"}"}}], "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}`,
+			format: FormatHybrid,
+		},
+		{
+			name:   "legacy",
+			result: `{"response": "", "toolCalls": [{"name": "weather", "arguments": {"cityName": "Paris"}}], "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}`,
+			format: FormatLegacy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"success": true, "result": ` + tt.result + `}`
+			var resp ChatResponse
+			require.NoError(t, json.Unmarshal([]byte(body), &resp))
+
+			assert.Equal(t, tt.format, resp.Format)
+			calls := resp.GetToolCalls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, "weather", calls[0].Function.Name)
+			// The arguments are the model's and keep their names.
+			assert.JSONEq(t, `{"cityName": "Paris"}`, calls[0].Function.Arguments)
+			require.Len(t, resp.Diagnostics, 1)
+			assert.Equal(t, "camelCase name accepted", resp.Diagnostics[0].Message)
+			assert.JSONEq(t, tt.result, string(resp.ResultRaw))
+		})
+	}
+
+	t.Run("snake_case name wins", func(t *testing.T) {
+		var resp ChatResponse
+		require.NoError(t, json.Unmarshal([]byte(`{"success": true, "result": {"response": "Hi", "usage": {"total_tokens": 3, "totalTokens": 5}}}`), &resp))
+		assert.Equal(t, 3, resp.GetUsage().TotalTokens)
+		assert.Empty(t, resp.Diagnostics)
+	})
+}
+
+func TestParseStreamChunk_CamelCase(t *testing.T) {
+	chunk, err := parseStreamChunk([]byte(`{"choices": [{"delta": {"content": "Hi", "reasoningContent": "hmm"}, "finishReason": "stop"}]}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", chunk.Content)
+	assert.Equal(t, "hmm", chunk.ReasoningContent)
+	assert.Equal(t, "stop", chunk.FinishReason)
+}
+
+func TestSnakeCaseFields_NoAlias(t *testing.T) {
+	// Payloads without aliases are returned as is, without decoding them.
+	raw := json.RawMessage(`{"choices": [{"message": {"content": "Hi"}}], "usage": {"total_tokens": 2}}`)
+	normalized, renamed := snakeCaseFields(raw, "result", nil)
+	assert.Same(t, &raw[0], &normalized[0])
+	assert.Empty(t, renamed)
+
+	// An alias mentioned in a value only costs a decoding.
+	raw = json.RawMessage(`{"response": "Use toolCalls"}`)
+	normalized, renamed = snakeCaseFields(raw, "result", nil)
+	assert.Equal(t, raw, normalized)
+	assert.Empty(t, renamed)
+}
+
+func TestClient_FieldAliases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if request.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}, \"stopReason\": \"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"choices": [{"message": {"content": "Hi"}, "stopReason": "length"}], "usage": {"totalTokens": 2}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.FieldAliases = map[string]string{"stopReason": "finish_reason"}
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}}}
+
+	resp, err := client.ChatCompletion(request)
+	require.NoError(t, err)
+	assert.Equal(t, "length", resp.ChatCompletionResponse.Choices[0].FinishReason)
+	// The built-in aliases still apply.
+	assert.Equal(t, 2, resp.GetUsage().TotalTokens)
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, request.Messages, nil, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+	chunk, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "stop", chunk.FinishReason)
+}
+
+func TestChatResponse_LenientEnvelope(t *testing.T) {
+	var resp ChatResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"success": false, "errors": "boom", "messages": [], "result": null}`), &resp))
+	assert.False(t, resp.Success)
+	assert.Empty(t, resp.Errors)
+	require.Len(t, resp.Diagnostics, 1)
+	assert.Equal(t, "errors", resp.Diagnostics[0].Path)
+
+	assert.Error(t, json.Unmarshal([]byte(`[]`), &resp))
+}
+
+func TestClient_DecodeStrict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	messages := []Message{ChatMessage{Role: "user", Content: "Hello"}}
+
+	resp, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Len(t, resp.Diagnostics, 1)
+
+	client.DecodeMode = DecodeStrict
+	_, err = client.Chat(ModelLlama38B, messages, nil)
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.EqualError(t, err, "unexpected response format: result.usage: missing")
+}
+
+// FuzzChatResponse_UnmarshalJSON checks that no response body, however
+// malformed, makes the decoding or the accessors panic. Run it with
+//
+//	go test -run '^$' -fuzz FuzzChatResponse_UnmarshalJSON ./workers-ai
+func FuzzChatResponse_UnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{
+		`{"success": true, "result": {"response": "Hi", "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}}`,
+		`{"success": true, "result": {"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}]}}`,
+		`{"success": true, "result": {"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "f", "arguments": "{}"}}]}}`,
+		`{"success": true, "result": {"response": null, "tool_calls": [{"name": "f", "arguments": {}}]}}`,
+		`{"success": true, "result": {"choices": [{}]}}`,
+		`{"success": true, "result": {"choices": [{"message": null}], "usage": "3"}}`,
+		`{"success": true, "result": {"choices": {"message": "Hi"}}}`,
+		`{"success": true, "result": {"response": {"text": "Hi"}}}`,
+		`{"success": true, "result": "Hi"}`,
+		`{"success": true, "result": null}`,
+		`{"success": false, "errors": "boom", "result": []}`,
+		`{"success": false, "errors": [{"code": 7000, "message": "No route"}], "messages": {}}`,
+		`[]`,
+		`null`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var resp ChatResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return
+		}
+		resp.GetContent()
+		resp.GetUsage()
+		resp.GetToolCalls()
+		resp.GetReasoningContent()
+		resp.GetFinishReason()
+		resp.GetCompletions()
+	})
+}
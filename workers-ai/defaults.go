@@ -0,0 +1,112 @@
+package workersai
+
+// ChatDefaults are applied to every chat request a Client sends, so that
+// services don't have to pass the same settings at every call site.
+type ChatDefaults struct {
+	// SystemPrompt is prepended to requests that don't start with a system
+	// message of their own.
+	SystemPrompt string
+	// ModelParameters fill in the parameters a request leaves unset (zero).
+	// A call overrides a default back to zero with ModelParameters.Zero.
+	ModelParameters
+}
+
+// applyDefaults fills in the unset fields of request from c.Defaults.
+func (c *Client) applyDefaults(request *ChatCompletionRequest) {
+	request.ModelParameters = request.ModelParameters.merge(c.Defaults.ModelParameters)
+
+	if c.Defaults.SystemPrompt == "" || hasSystemMessage(request.Messages) {
+		return
+	}
+
+	messages := make([]Message, 0, len(request.Messages)+1)
+	messages = append(messages, ChatMessage{Role: "system", Content: c.Defaults.SystemPrompt})
+	request.Messages = append(messages, request.Messages...)
+}
+
+// Params is a set of the parameters of ModelParameters that can be set
+// to zero on purpose, see ModelParameters.Zero.
+type Params uint8
+
+const (
+	ParamTemperature Params = 1 << iota
+	ParamTopP
+	ParamTopK
+)
+
+// merge returns p with its unset fields taken from defaults.
+func (p ModelParameters) merge(defaults ModelParameters) ModelParameters {
+	if p.MaxTokens == 0 {
+		p.MaxTokens = defaults.MaxTokens
+	}
+	if p.TopK == 0 && p.Zero&ParamTopK == 0 {
+		p.TopK = defaults.TopK
+		p.Zero |= defaults.Zero & ParamTopK
+	}
+	if p.Temperature == 0 && p.Zero&ParamTemperature == 0 {
+		p.Temperature = defaults.Temperature
+		p.Zero |= defaults.Zero & ParamTemperature
+	}
+	if p.TopP == 0 && p.Zero&ParamTopP == 0 {
+		p.TopP = defaults.TopP
+		p.Zero |= defaults.Zero & ParamTopP
+	}
+	if p.N == 0 {
+		p.N = defaults.N
+	}
+	return p
+}
+
+// explicitParams are the parameters of ModelParameters.Zero as sent.
+// Embedded next to a request, they take precedence over the fields of its
+// ModelParameters, nested one level deeper, which omit zeros. They are
+// set to the values of the request whether zero or not.
+type explicitParams struct {
+	TopK        *int     `json:"top_k,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+func (p ModelParameters) explicit() explicitParams {
+	var explicit explicitParams
+	if p.TopK != 0 || p.Zero&ParamTopK != 0 {
+		explicit.TopK = &p.TopK
+	}
+	if p.Temperature != 0 || p.Zero&ParamTemperature != 0 {
+		explicit.Temperature = &p.Temperature
+	}
+	if p.TopP != 0 || p.Zero&ParamTopP != 0 {
+		explicit.TopP = &p.TopP
+	}
+	return explicit
+}
+
+// withZeroParams returns the chat request payload, marshalled as is unless
+// some of its parameters are set to zero on purpose, which are then sent.
+func withZeroParams(payload interface{}) interface{} {
+	switch request := payload.(type) {
+	case ChatCompletionRequest:
+		if request.Zero != 0 {
+			return struct {
+				ChatCompletionRequest
+				explicitParams
+			}{request, request.explicit()}
+		}
+	case rawPromptRequest:
+		if request.Zero != 0 {
+			return struct {
+				rawPromptRequest
+				explicitParams
+			}{request, request.explicit()}
+		}
+	}
+	return payload
+}
+
+func hasSystemMessage(messages []Message) bool {
+	if len(messages) == 0 {
+		return false
+	}
+	msg, ok := messages[0].(ChatMessage)
+	return ok && msg.Role == "system"
+}
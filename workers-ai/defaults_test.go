@@ -0,0 +1,84 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Defaults(t *testing.T) {
+	var got ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ChatCompletionRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{"success": true, "result": {"response": "ok"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Defaults = ChatDefaults{
+		SystemPrompt:    "You are terse.",
+		ModelParameters: ModelParameters{MaxTokens: 100, Temperature: 0.2},
+	}
+
+	user := ChatMessage{Role: "user", Content: "Hi"}
+
+	_, err := client.Chat(ModelLlama38B, []Message{user}, &ModelParameters{Temperature: 0.9, TopP: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, []Message{ChatMessage{Role: "system", Content: "You are terse."}, user}, got.Messages)
+	assert.Equal(t, ModelParameters{MaxTokens: 100, Temperature: 0.9, TopP: 0.5}, got.ModelParameters)
+
+	// A request with its own system prompt keeps it.
+	own := ChatMessage{Role: "system", Content: "You are verbose."}
+	_, err = client.Chat(ModelLlama38B, []Message{own, user}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Message{own, user}, got.Messages)
+	assert.Equal(t, ModelParameters{MaxTokens: 100, Temperature: 0.2}, got.ModelParameters)
+}
+
+func TestClient_DefaultsZeroParams(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{"success": true, "result": {"response": "ok"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Defaults = ChatDefaults{ModelParameters: ModelParameters{Temperature: 0.7, TopP: 0.9}}
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	// Zero temperature overrides the default and is sent.
+	_, err := client.Chat(ModelLlama38B, messages, &ModelParameters{MaxTokens: 10, Zero: ParamTemperature})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, got["temperature"])
+	assert.Equal(t, 0.9, got["top_p"])
+	assert.Equal(t, 10.0, got["max_tokens"])
+	assert.NotContains(t, got, "top_k")
+
+	// A value set with the flag is sent as is.
+	_, err = client.Chat(ModelLlama38B, messages, &ModelParameters{Temperature: 0.3, Zero: ParamTemperature | ParamTopK})
+	require.NoError(t, err)
+	assert.Equal(t, 0.3, got["temperature"])
+	assert.Equal(t, 0.0, got["top_k"])
+
+	// Zero still means unset without the flag.
+	_, err = client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.7, got["temperature"])
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, messages, nil, &ModelParameters{Zero: ParamTopP})
+	require.NoError(t, err)
+	stream.Close()
+	assert.Equal(t, 0.0, got["top_p"])
+	assert.Equal(t, 0.7, got["temperature"])
+}
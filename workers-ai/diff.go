@@ -0,0 +1,234 @@
+package workersai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the size of the table of the word diff. Longer texts
+// are diffed only around their common prefix and suffix.
+const maxDiffCells = 1 << 22
+
+// DiffOp is the kind of a DiffSpan.
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	// DiffDelete is text of the first response missing from the second.
+	DiffDelete
+	// DiffInsert is text of the second response missing from the first.
+	DiffInsert
+)
+
+func (op DiffOp) String() string {
+	switch op {
+	case DiffEqual:
+		return "="
+	case DiffDelete:
+		return "-"
+	case DiffInsert:
+		return "+"
+	}
+	return fmt.Sprintf("DiffOp(%d)", int(op))
+}
+
+// DiffSpan is a run of words with the same DiffOp.
+type DiffSpan struct {
+	Op   DiffOp
+	Text string
+}
+
+// ResponseDiff compares two responses, for evaluations and shadow traffic.
+type ResponseDiff struct {
+	// Spans turn the words of the first content into those of the second.
+	Spans []DiffSpan
+	// Deleted and Inserted count the words in the spans of these kinds.
+	Deleted  int
+	Inserted int
+	// WordSimilarity is the Jaccard similarity of the lowercased words of
+	// both contents, from 0 for nothing in common to 1 for the same words.
+	WordSimilarity float64
+	// EditSimilarity is the share of the words kept in place, from 0 to 1.
+	// Unlike WordSimilarity it accounts for their order.
+	EditSimilarity float64
+	// SemanticSimilarity is the cosine similarity of the embeddings of both
+	// contents. It is only set by Client.SemanticDiff.
+	SemanticSimilarity float64
+	// LengthRatio is the length of the second content over the length of
+	// the first, 0 when the first is empty.
+	LengthRatio float64
+	// ToolCallsMatch reports whether both responses call the same tools,
+	// in the same order.
+	ToolCallsMatch bool
+}
+
+// String renders the spans like a word diff: deleted words in [-...-] and
+// inserted words in {+...+}.
+func (d *ResponseDiff) String() string {
+	parts := make([]string, len(d.Spans))
+	for i, span := range d.Spans {
+		switch span.Op {
+		case DiffDelete:
+			parts[i] = "[-" + span.Text + "-]"
+		case DiffInsert:
+			parts[i] = "{+" + span.Text + "+}"
+		default:
+			parts[i] = span.Text
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// DiffResponses compares the contents and tool calls of a and b.
+func DiffResponses(a, b *ChatResponse) *ResponseDiff {
+	diff := DiffText(a.GetContent(), b.GetContent())
+
+	calls, otherCalls := a.GetToolCalls(), b.GetToolCalls()
+	diff.ToolCallsMatch = len(calls) == len(otherCalls)
+	for i := 0; diff.ToolCallsMatch && i < len(calls); i++ {
+		diff.ToolCallsMatch = calls[i].Function.Name == otherCalls[i].Function.Name
+	}
+	return diff
+}
+
+// DiffText compares the words of a and b.
+func DiffText(a, b string) *ResponseDiff {
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	diff := &ResponseDiff{
+		Spans:          diffWords(wordsA, wordsB),
+		WordSimilarity: wordSimilarity(a, b),
+		EditSimilarity: 1,
+		ToolCallsMatch: true,
+	}
+	if len(a) > 0 {
+		diff.LengthRatio = float64(len(b)) / float64(len(a))
+	}
+
+	equal := 0
+	for _, span := range diff.Spans {
+		n := len(strings.Fields(span.Text))
+		switch span.Op {
+		case DiffEqual:
+			equal += n
+		case DiffDelete:
+			diff.Deleted += n
+		case DiffInsert:
+			diff.Inserted += n
+		}
+	}
+	if total := len(wordsA) + len(wordsB); total > 0 {
+		diff.EditSimilarity = float64(2*equal) / float64(total)
+	}
+	return diff
+}
+
+// SemanticDiff is DiffResponses with the SemanticSimilarity of the contents,
+// embedded with embeddingModel.
+func (c *Client) SemanticDiff(a, b *ChatResponse, embeddingModel string) (*ResponseDiff, error) {
+	diff := DiffResponses(a, b)
+
+	result, err := c.Embed(embeddingModel, []string{a.GetContent(), b.GetContent()}, &EmbeddingOptions{Normalize: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed responses: %w", err)
+	}
+	for i, v := range result.Data[0] {
+		if i < len(result.Data[1]) {
+			diff.SemanticSimilarity += float64(v) * float64(result.Data[1][i])
+		}
+	}
+	return diff, nil
+}
+
+// diffWords returns the spans of a longest common subsequence diff of a and
+// b. Beyond maxDiffCells, the words between the common prefix and suffix
+// are reported as deleted and inserted as a whole.
+func diffWords(a, b []string) []DiffSpan {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var spans []DiffSpan
+	add := func(op DiffOp, word string) {
+		if n := len(spans); n > 0 && spans[n-1].Op == op {
+			spans[n-1].Text += " " + word
+			return
+		}
+		spans = append(spans, DiffSpan{Op: op, Text: word})
+	}
+
+	for _, w := range a[:prefix] {
+		add(DiffEqual, w)
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) > maxDiffCells {
+		for _, w := range midA {
+			add(DiffDelete, w)
+		}
+		for _, w := range midB {
+			add(DiffInsert, w)
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of
+		// midA[i:] and midB[j:].
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				add(DiffEqual, midA[i])
+				i, j = i+1, j+1
+			case j == len(midB) || i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]:
+				add(DiffDelete, midA[i])
+				i++
+			default:
+				add(DiffInsert, midB[j])
+				j++
+			}
+		}
+	}
+	for _, w := range a[len(a)-suffix:] {
+		add(DiffEqual, w)
+	}
+	return spans
+}
+
+// wordSimilarity returns the Jaccard similarity of the lowercased words of
+// a and b. Two empty texts are identical.
+func wordSimilarity(a, b string) float64 {
+	words := func(s string) map[string]bool {
+		set := make(map[string]bool)
+		for _, w := range strings.Fields(strings.ToLower(s)) {
+			set[w] = true
+		}
+		return set
+	}
+	setA, setB := words(a), words(b)
+	if len(setA) == 0 && len(setB) == 0 {
+		return 1
+	}
+
+	common := 0
+	for w := range setA {
+		if setB[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(setA)+len(setB)-common)
+}
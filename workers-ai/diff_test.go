@@ -0,0 +1,74 @@
+package workersai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffText(t *testing.T) {
+	diff := DiffText("The capital of France is Paris.", "The capital of France is clearly Paris, of course.")
+	assert.Equal(t, []DiffSpan{
+		{Op: DiffEqual, Text: "The capital of France is"},
+		{Op: DiffDelete, Text: "Paris."},
+		{Op: DiffInsert, Text: "clearly Paris, of course."},
+	}, diff.Spans)
+	assert.Equal(t, 1, diff.Deleted)
+	assert.Equal(t, 4, diff.Inserted)
+	assert.InDelta(t, 10.0/15, diff.EditSimilarity, 0.001)
+	assert.Equal(t, "The capital of France is [-Paris.-] {+clearly Paris, of course.+}", diff.String())
+
+	diff = DiffText("a b c d", "a c b d")
+	assert.Equal(t, "a [-b-] c {+b+} d", diff.String())
+	assert.Equal(t, 1.0, diff.WordSimilarity)
+	assert.Equal(t, 0.75, diff.EditSimilarity)
+
+	diff = DiffText("", "")
+	assert.Empty(t, diff.Spans)
+	assert.Equal(t, 1.0, diff.EditSimilarity)
+}
+
+func TestDiffText_Long(t *testing.T) {
+	a := strings.Repeat("x ", 3000) + "same"
+	b := strings.Repeat("y ", 3000) + "same"
+	diff := DiffText(a, b)
+	assert.Equal(t, 3000, diff.Deleted)
+	assert.Equal(t, 3000, diff.Inserted)
+	assert.Equal(t, DiffSpan{Op: DiffEqual, Text: "same"}, diff.Spans[2])
+}
+
+func TestDiffResponses(t *testing.T) {
+	weather := ToolCall{Function: FunctionToCall{Name: "get_weather"}}
+	diff := DiffResponses(toolCallResponse(weather), toolCallResponse(weather))
+	assert.True(t, diff.ToolCallsMatch)
+
+	diff = DiffResponses(toolCallResponse(weather), textResponse("Sunny", Usage{}))
+	assert.False(t, diff.ToolCallsMatch)
+	assert.Equal(t, []DiffSpan{{Op: DiffInsert, Text: "Sunny"}}, diff.Spans)
+}
+
+func TestClient_SemanticDiff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": true, "result": {"shape": [2, 2], "data": [[3, 4], [4, 3]]}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	diff, err := client.SemanticDiff(textResponse("Paris", Usage{}), textResponse("It's Paris", Usage{}), ModelBAAI)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.96, diff.SemanticSimilarity, 0.0001)
+	assert.Equal(t, 0.5, diff.WordSimilarity)
+}
+
+func TestWordSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, wordSimilarity("", ""))
+	assert.Equal(t, 0.0, wordSimilarity("yes", "no"))
+	assert.Equal(t, 0.5, wordSimilarity("Paris France", "paris"))
+	assert.InDelta(t, 0.333, wordSimilarity(strings.Repeat("a ", 3)+"b", "b c"), 0.001)
+}
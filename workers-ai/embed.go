@@ -0,0 +1,106 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Pooling methods of the embedding models.
+const (
+	// PoolingMean averages the token embeddings. It is the default.
+	PoolingMean = "mean"
+	// PoolingCLS uses the embedding of the classification token, which is
+	// more accurate on long inputs. Embeddings of both methods can't be
+	// compared with each other.
+	PoolingCLS = "cls"
+)
+
+// EmbeddingOptions are the optional settings of Embed.
+type EmbeddingOptions struct {
+	// Pooling is PoolingMean or PoolingCLS. Only the BGE models support it.
+	Pooling string
+	// Truncate cuts texts longer than the model's context instead of
+	// failing the request.
+	Truncate bool
+	// Normalize scales the vectors to unit length (L2 norm), so that the
+	// dot product equals the cosine similarity.
+	Normalize bool
+}
+
+// EmbeddingResult is the output of the embedding models.
+type EmbeddingResult struct {
+	// Shape is the number of vectors and their dimension.
+	Shape []int `json:"shape"`
+	// Data holds one vector per input text, in input order.
+	Data [][]float32 `json:"data"`
+	// Pooling is the pooling method used by the model, e.g. "mean".
+	Pooling string `json:"pooling,omitempty"`
+
+	resultMeta
+}
+
+// Dimension returns the length of the vectors, e.g. to configure a vector
+// index. It is zero for an empty result.
+func (r *EmbeddingResult) Dimension() int {
+	if len(r.Shape) == 2 {
+		return r.Shape[1]
+	}
+	if len(r.Data) > 0 {
+		return len(r.Data[0])
+	}
+	return 0
+}
+
+// embeddingRequest is the input of the embedding models.
+type embeddingRequest struct {
+	Text           []string `json:"text"`
+	Pooling        string   `json:"pooling,omitempty"`
+	TruncateInputs bool     `json:"truncate_inputs,omitempty"`
+}
+
+// Embed computes the embeddings of texts with an embedding model such as
+// ModelBAAI. The models accept up to 100 texts per call. opts may be nil.
+func (c *Client) Embed(modelID string, texts []string, opts *EmbeddingOptions) (*EmbeddingResult, error) {
+	return c.EmbedContext(context.Background(), modelID, texts, opts)
+}
+
+// EmbedContext is Embed with a context; cancelling ctx aborts the request.
+func (c *Client) EmbedContext(ctx context.Context, modelID string, texts []string, opts *EmbeddingOptions) (*EmbeddingResult, error) {
+	request := embeddingRequest{Text: texts}
+	if opts != nil {
+		request.Pooling = opts.Pooling
+		request.TruncateInputs = opts.Truncate
+	}
+
+	var result EmbeddingResult
+	if err := c.runContext(ctx, modelID, request, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	if opts != nil && opts.Normalize {
+		for _, vector := range result.Data {
+			normalize(vector)
+		}
+	}
+	return &result, nil
+}
+
+// normalize scales vector to unit length in place. Zero vectors are left
+// alone.
+func normalize(vector []float32) {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i, v := range vector {
+		vector[i] = float32(float64(v) / norm)
+	}
+}
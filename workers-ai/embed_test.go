@@ -0,0 +1,70 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEmbeddingServer answers embedding requests with vectors of dimension 2
+// holding the length of each text, and records the texts it received.
+func newEmbeddingServer(t *testing.T, received *[][]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, ModelBAAI))
+		var req embeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		*received = append(*received, req.Text)
+
+		data := make([][]float32, len(req.Text))
+		for i, text := range req.Text {
+			data[i] = []float32{float32(len(text)), 1}
+		}
+		encoded, _ := json.Marshal(data)
+		fmt.Fprintf(w, `{"success": true, "result": {"shape": [%d, 2], "data": %s, "pooling": "mean"}}`, len(req.Text), encoded)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_Embed(t *testing.T) {
+	var received [][]string
+	server := newEmbeddingServer(t, &received)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	result, err := client.Embed(ModelBAAI, []string{"a", "bcd"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 2}, result.Shape)
+	assert.Equal(t, [][]float32{{1, 1}, {3, 1}}, result.Data)
+	assert.Equal(t, "mean", result.Pooling)
+	assert.Equal(t, 2, result.Dimension())
+	assert.Equal(t, [][]string{{"a", "bcd"}}, received)
+}
+
+func TestClient_Embed_Options(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "cls", req["pooling"])
+		assert.Equal(t, true, req["truncate_inputs"])
+		fmt.Fprint(w, `{"success": true, "result": {"shape": [2, 2], "data": [[3, 4], [0, 0]], "pooling": "cls"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	result, err := client.Embed(ModelBAAI, []string{"a", "b"}, &EmbeddingOptions{Pooling: PoolingCLS, Truncate: true, Normalize: true})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.6, 0.8}, {0, 0}}, result.Data)
+
+	assert.Equal(t, 3, (&EmbeddingResult{Data: [][]float32{{1, 2, 3}}}).Dimension())
+	assert.Zero(t, (&EmbeddingResult{}).Dimension())
+}
@@ -0,0 +1,174 @@
+package workersai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultEmbeddingBatchSize is the number of texts an EmbeddingCache sends
+// per request when BatchSize is not set.
+const DefaultEmbeddingBatchSize = 100
+
+// EmbeddingStore persists embeddings for an EmbeddingCache, keyed by a hash
+// of the model and the text. Implementations must be safe for concurrent
+// use.
+type EmbeddingStore interface {
+	// Get returns the vector stored under key, if any.
+	Get(key string) (vector []float32, ok bool, err error)
+	// Set stores vector under key.
+	Set(key string, vector []float32) error
+}
+
+// MemoryEmbeddingStore is an EmbeddingStore keeping the embeddings in
+// memory, for the lifetime of the process.
+type MemoryEmbeddingStore struct {
+	mu      sync.RWMutex
+	vectors map[string][]float32
+}
+
+// NewMemoryEmbeddingStore returns an empty in-memory store.
+func NewMemoryEmbeddingStore() *MemoryEmbeddingStore {
+	return &MemoryEmbeddingStore{vectors: make(map[string][]float32)}
+}
+
+func (s *MemoryEmbeddingStore) Get(key string) ([]float32, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	vector, ok := s.vectors[key]
+	return vector, ok, nil
+}
+
+func (s *MemoryEmbeddingStore) Set(key string, vector []float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vectors[key] = vector
+	return nil
+}
+
+// Len returns the number of stored embeddings.
+func (s *MemoryEmbeddingStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.vectors)
+}
+
+// EmbeddingCacheStats are the counters of an EmbeddingCache.
+type EmbeddingCacheStats struct {
+	// Hits counts the texts answered from the store, or from an identical
+	// text earlier in the same call.
+	Hits int64
+	// Misses counts the texts sent to the model.
+	Misses int64
+}
+
+// HitRate returns the share of texts that weren't sent to the model.
+func (s EmbeddingCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// EmbeddingCache embeds texts only once: embeddings are stored under the
+// SHA-256 hash of the model and the text, so that chunks recurring across
+// documents and ingestion runs aren't embedded again. Use a persistent
+// Store to share the cache between runs. An EmbeddingCache is safe for
+// concurrent use.
+type EmbeddingCache struct {
+	Client ClientInterface
+	Store  EmbeddingStore
+	// Options are passed to every request. Embeddings made with different
+	// pooling or normalization are stored apart.
+	Options *EmbeddingOptions
+	// BatchSize caps the texts per request. Defaults to
+	// DefaultEmbeddingBatchSize.
+	BatchSize int
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewEmbeddingCache returns a cache embedding through client into store.
+func NewEmbeddingCache(client ClientInterface, store EmbeddingStore) *EmbeddingCache {
+	return &EmbeddingCache{Client: client, Store: store}
+}
+
+// EmbeddingKey returns the key under which the embedding of text by modelID
+// is stored.
+func EmbeddingKey(modelID, text string) string {
+	sum := sha256.Sum256([]byte(modelID + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Embed returns the embeddings of texts, in order, embedding only those
+// not in the store yet. Identical texts within texts are embedded once.
+func (c *EmbeddingCache) Embed(modelID string, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+
+	variant := modelID
+	if o := c.Options; o != nil && (o.Pooling != "" || o.Normalize) {
+		variant = fmt.Sprintf("%s?pooling=%s&normalize=%t", modelID, o.Pooling, o.Normalize)
+	}
+
+	// The positions of every text still to embed, by key.
+	missing := make(map[string][]int)
+	var order []string
+	for i, text := range texts {
+		key := EmbeddingKey(variant, text)
+		if positions, ok := missing[key]; ok {
+			missing[key] = append(positions, i)
+			c.hits.Add(1)
+			continue
+		}
+
+		vector, ok, err := c.Store.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedding cache: %w", err)
+		}
+		if ok {
+			vectors[i] = vector
+			c.hits.Add(1)
+			continue
+		}
+		missing[key] = []int{i}
+		order = append(order, key)
+	}
+
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultEmbeddingBatchSize
+	}
+	for start := 0; start < len(order); start += batchSize {
+		keys := order[start:min(start+batchSize, len(order))]
+		batch := make([]string, len(keys))
+		for i, key := range keys {
+			batch[i] = texts[missing[key][0]]
+		}
+
+		result, err := c.Client.Embed(modelID, batch, c.Options)
+		if err != nil {
+			return nil, err
+		}
+		c.misses.Add(int64(len(batch)))
+
+		for i, key := range keys {
+			vector := result.Data[i]
+			if err := c.Store.Set(key, vector); err != nil {
+				return nil, fmt.Errorf("failed to write embedding cache: %w", err)
+			}
+			for _, position := range missing[key] {
+				vectors[position] = vector
+			}
+		}
+	}
+
+	return vectors, nil
+}
+
+// Stats returns the counters of the cache since it was created.
+func (c *EmbeddingCache) Stats() EmbeddingCacheStats {
+	return EmbeddingCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
@@ -0,0 +1,65 @@
+package workersai
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddingCache(t *testing.T) {
+	var received [][]string
+	server := newEmbeddingServer(t, &received)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	store := NewMemoryEmbeddingStore()
+	cache := NewEmbeddingCache(client, store)
+	cache.BatchSize = 2
+
+	vectors, err := cache.Embed(ModelBAAI, []string{"a", "bb", "a", "ccc"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 1}, {2, 1}, {1, 1}, {3, 1}}, vectors)
+	assert.Equal(t, [][]string{{"a", "bb"}, {"ccc"}}, received)
+	assert.Equal(t, 3, store.Len())
+
+	// A second document sharing chunks only embeds the new ones.
+	vectors, err = cache.Embed(ModelBAAI, []string{"ccc", "dddd", "bb"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{3, 1}, {4, 1}, {2, 1}}, vectors)
+	assert.Equal(t, []string{"dddd"}, received[2])
+
+	stats := cache.Stats()
+	assert.Equal(t, EmbeddingCacheStats{Hits: 3, Misses: 4}, stats)
+	assert.InDelta(t, 3.0/7, stats.HitRate(), 1e-9)
+
+	// Other options are stored apart.
+	cache.Options = &EmbeddingOptions{Normalize: true}
+	vectors, err = cache.Embed(ModelBAAI, []string{"a"})
+	require.NoError(t, err)
+	assert.InDelta(t, 1/math.Sqrt2, vectors[0][0], 1e-6)
+	assert.Equal(t, 5, store.Len())
+
+	// Keys depend on the model.
+	assert.NotEqual(t, EmbeddingKey(ModelBAAI, "a"), EmbeddingKey(ModelBAAILarge, "a"))
+	assert.Zero(t, EmbeddingCacheStats{}.HitRate())
+}
+
+type failingEmbeddingStore struct{}
+
+func (failingEmbeddingStore) Get(key string) ([]float32, bool, error) {
+	return nil, false, errors.New("store down")
+}
+
+func (failingEmbeddingStore) Set(key string, vector []float32) error {
+	return errors.New("store down")
+}
+
+func TestEmbeddingCache_StoreError(t *testing.T) {
+	cache := NewEmbeddingCache(NewClient("test-account", "test-token"), failingEmbeddingStore{})
+	_, err := cache.Embed(ModelBAAI, []string{"a"})
+	assert.ErrorContains(t, err, "store down")
+}
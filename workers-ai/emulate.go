@@ -0,0 +1,128 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ToolEmulation makes tools usable with models that lack native function
+// calling. For the listed models, ChatCompletion describes the tools in the
+// system prompt, asks for ReAct-style "Action:" / "Action Input:" blocks and
+// turns them into ToolCalls, so callers handle both kinds of model the same
+// way. Tool results are sent back to the model as "Observation:" messages.
+type ToolEmulation struct {
+	// Models lists the models for which tools are emulated.
+	Models []string
+}
+
+// toolActionRe matches the action block of an emulated tool call.
+var toolActionRe = regexp.MustCompile(`(?m)^\s*Action:\s*(\S+)\s*$\s*^\s*Action Input:\s*`)
+
+// emulatesTools reports whether tools are emulated for modelID.
+func (c *Client) emulatesTools(modelID string) bool {
+	for _, model := range c.ToolEmulation.Models {
+		if model == modelID || "@cf/"+model == modelID || model == "@cf/"+modelID {
+			return true
+		}
+	}
+	return false
+}
+
+// emulateToolRequest rewrites request for a model without tool support: the
+// tools move into the system prompt and the tool turns of the history become
+// plain text.
+func emulateToolRequest(request ChatCompletionRequest) ChatCompletionRequest {
+	prompt := toolEmulationPrompt(request.Tools)
+
+	messages := make([]Message, 0, len(request.Messages)+1)
+	if hasSystemMessage(request.Messages) {
+		system := request.Messages[0].(ChatMessage)
+		system.Content += "\n\n" + prompt
+		messages = append(messages, system)
+		request.Messages = request.Messages[1:]
+	} else {
+		messages = append(messages, ChatMessage{Role: "system", Content: prompt})
+	}
+
+	for _, msg := range request.Messages {
+		switch m := msg.(type) {
+		case ResponseMessage:
+			var b strings.Builder
+			if m.Content != nil && *m.Content != "" {
+				b.WriteString(*m.Content + "\n")
+			}
+			for _, call := range m.ToolCalls {
+				fmt.Fprintf(&b, "Action: %s\nAction Input: %s\n", call.Function.Name, call.Function.Arguments)
+			}
+			messages = append(messages, ChatMessage{Role: "assistant", Content: strings.TrimSpace(b.String())})
+		case ToolMessage:
+			messages = append(messages, ChatMessage{Role: "user", Content: "Observation: " + m.Content})
+		default:
+			messages = append(messages, msg)
+		}
+	}
+
+	request.Messages = messages
+	request.Tools = nil
+	return request
+}
+
+func toolEmulationPrompt(tools []Tool) string {
+	var b strings.Builder
+	b.WriteString("You can use the following tools:\n\n")
+	for _, tool := range tools {
+		params, _ := json.Marshal(tool.Function.Parameters)
+		fmt.Fprintf(&b, "- %s: %s\n  Parameters (JSON schema): %s\n", tool.Function.Name, tool.Function.Description, params)
+	}
+	b.WriteString("\nTo use a tool, reply with exactly these two lines and nothing after them:\n")
+	b.WriteString("Action: <tool name>\nAction Input: <JSON object with the arguments>\n\n")
+	b.WriteString("You will get the result in a message starting with \"Observation:\". ")
+	b.WriteString("When you don't need a tool, answer the user directly.")
+	return b.String()
+}
+
+// parseEmulatedToolCall turns an action block in the reply of resp into a
+// tool call. Responses without an action block are left unchanged. id
+// disambiguates the generated tool call IDs within a conversation.
+func parseEmulatedToolCall(resp *ChatResponse, tools []Tool, id int) {
+	content := resp.GetContent()
+	match := toolActionRe.FindStringSubmatchIndex(content)
+	if match == nil {
+		return
+	}
+
+	name := content[match[2]:match[3]]
+	known := false
+	for _, tool := range tools {
+		if tool.Function.Name == name {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return
+	}
+
+	arguments := extractJSON(content[match[1]:])
+	if !json.Valid([]byte(arguments)) {
+		return
+	}
+
+	message := newAssistantMessage(strings.TrimSpace(content[:match[0]]), []ToolCall{{
+		ID:   fmt.Sprintf("emulated-tool-call-%d", id),
+		Type: "function",
+		Function: FunctionToCall{
+			Name:      name,
+			Arguments: arguments,
+		},
+	}}).(ResponseMessage)
+
+	usage := resp.GetUsage()
+	resp.Format = FormatOpenAI
+	resp.IsLegacyResult = false
+	resp.LegacyResponse = LegacyResponse{}
+	resp.ChatCompletionResponse.Choices = []Choice{{Message: message, FinishReason: "tool_calls"}}
+	resp.ChatCompletionResponse.Usage = usage
+}
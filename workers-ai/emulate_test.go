@@ -0,0 +1,84 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ToolEmulation(t *testing.T) {
+	replies := []string{
+		"I need the weather first.\nAction: get_weather\nAction Input: {\"city\": \"Paris\"}",
+		"It is sunny in Paris.",
+	}
+	var requests []ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q, "usage": {"prompt_tokens": 50, "completion_tokens": 10, "total_tokens": 60}}}`, replies[len(requests)-1])
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.ToolEmulation = ToolEmulation{Models: []string{"mistral/mistral-7b-instruct-v0.1"}}
+
+	tools := []Tool{{
+		Type: "function",
+		Function: FunctionDefinition{
+			Name:        "get_weather",
+			Description: "Get the current weather of a city",
+			Parameters: FunctionParameters{
+				Type:       "object",
+				Properties: map[string]*Parameter{"city": {Type: "string"}},
+				Required:   []string{"city"},
+			},
+		},
+	}}
+
+	session := NewChatSession(client, ModelMistral7B)
+	session.Tools = tools
+
+	resp, err := session.Send("What's the weather in Paris?")
+	require.NoError(t, err)
+
+	calls := resp.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "get_weather", calls[0].Function.Name)
+	assert.JSONEq(t, `{"city": "Paris"}`, calls[0].Function.Arguments)
+	assert.Equal(t, "tool_calls", resp.GetFinishReason())
+	assert.Equal(t, FormatOpenAI, resp.Format)
+	assert.False(t, resp.IsLegacyResult)
+	assert.Equal(t, "I need the weather first.", resp.GetContent())
+	assert.Equal(t, 60, resp.GetUsage().TotalTokens)
+
+	require.Len(t, requests[0].Messages, 2)
+	assert.Empty(t, requests[0].Tools)
+	system := requests[0].Messages[0].(ChatMessage)
+	assert.Equal(t, "system", system.Role)
+	assert.Contains(t, system.Content, "- get_weather: Get the current weather of a city")
+
+	session.AddToolResult(calls[0].ID, "sunny, 24°C")
+	resp, err = session.Continue()
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny in Paris.", resp.GetContent())
+	assert.Empty(t, resp.GetToolCalls())
+
+	require.Len(t, requests[1].Messages, 4)
+	assert.Equal(t, ChatMessage{Role: "assistant", Content: "I need the weather first.\nAction: get_weather\nAction Input: {\"city\": \"Paris\"}"}, requests[1].Messages[2])
+	assert.Equal(t, ChatMessage{Role: "user", Content: "Observation: sunny, 24°C"}, requests[1].Messages[3])
+}
+
+func TestParseEmulatedToolCall_UnknownTool(t *testing.T) {
+	resp := &ChatResponse{IsLegacyResult: true, LegacyResponse: LegacyResponse{Response: "Action: rm_rf\nAction Input: {}"}}
+	parseEmulatedToolCall(resp, []Tool{{Function: FunctionDefinition{Name: "get_weather"}}}, 0)
+
+	assert.True(t, resp.IsLegacyResult)
+	assert.Empty(t, resp.GetToolCalls())
+}
@@ -0,0 +1,159 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Codes of the errors Workers AI reports in the "errors" array of its
+// responses. Check them with APIError.HasCode.
+const (
+	// ErrorCodeIncompleteRequest is a request missing required input.
+	ErrorCodeIncompleteRequest = 3003
+	// ErrorCodeRequestTooLarge is a request body over the size limit.
+	ErrorCodeRequestTooLarge = 3006
+	// ErrorCodeTimeout and ErrorCodeAborted are inferences that didn't
+	// complete; sending them again may succeed.
+	ErrorCodeTimeout = 3007
+	ErrorCodeAborted = 3008
+	// ErrorCodeAccountBlocked is an account suspended from Workers AI.
+	ErrorCodeAccountBlocked = 3023
+	// ErrorCodeAccountLimited is the rate limit of the account.
+	ErrorCodeAccountLimited = 3036
+	// ErrorCodeOutOfCapacity is the model lacking capacity for the
+	// request, e.g. during a load spike. Unlike rate limits it doesn't
+	// depend on the account.
+	ErrorCodeOutOfCapacity = 3040
+	// ErrorCodeInvalidModelID is a malformed model name.
+	ErrorCodeInvalidModelID = 3042
+	// ErrorCodeInvalidData is input that doesn't match the schema of the
+	// model.
+	ErrorCodeInvalidData = 5004
+	// ErrorCodeLoRAUnsupported is a LoRA adapter given to a model that
+	// doesn't take one.
+	ErrorCodeLoRAUnsupported = 5005
+	// ErrorCodeNoSuchModel is a model that doesn't exist.
+	ErrorCodeNoSuchModel = 5007
+	// ErrorCodeModelAgreement is a model whose license must be accepted
+	// first, by sending "agree" to it.
+	ErrorCodeModelAgreement = 5016
+	// ErrorCodeContextWindow is a request whose input and max_tokens exceed
+	// the context window of the model.
+	ErrorCodeContextWindow = 5021
+)
+
+// transientErrorCodes are the known error codes of requests that may
+// succeed when sent again.
+var transientErrorCodes = []int{
+	ErrorCodeTimeout,
+	ErrorCodeAborted,
+	ErrorCodeAccountLimited,
+	ErrorCodeOutOfCapacity,
+}
+
+// permanentErrorCodes are the known error codes of requests that fail the
+// same way when sent again, whatever their HTTP status.
+var permanentErrorCodes = []int{
+	ErrorCodeIncompleteRequest,
+	ErrorCodeRequestTooLarge,
+	ErrorCodeAccountBlocked,
+	ErrorCodeInvalidModelID,
+	ErrorCodeInvalidData,
+	ErrorCodeLoRAUnsupported,
+	ErrorCodeNoSuchModel,
+	ErrorCodeModelAgreement,
+	ErrorCodeContextWindow,
+}
+
+// IsRetryable reports whether the request that failed with err may succeed
+// when sent again: network errors, rate limits, capacity errors, timeouts
+// and server errors. The retry subsystem of the client retries these.
+// Cancellations, panics, errors of the request itself and errors raised by
+// the client, such as ErrNeuronLimitExceeded or ErrResponseTooLarge, are
+// not retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		return false
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return isNetworkError(err)
+	}
+	if hasAnyCode(apiErr, transientErrorCodes) {
+		return true
+	}
+	if hasAnyCode(apiErr, permanentErrorCodes) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests ||
+		apiErr.StatusCode == http.StatusRequestTimeout ||
+		apiErr.StatusCode >= 500
+}
+
+// isNetworkError reports whether err is the transport failing to send a
+// request or to read its response, e.g. a connection reset. The other
+// errors of http.Client, such as an unsupported scheme, a certificate
+// that doesn't verify or a refused redirect, are permanent: a *url.Error
+// only counts for the network error or the early EOF it wraps, as it
+// implements net.Error itself.
+func isNetworkError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if errors.Is(urlErr.Err, io.EOF) {
+			return true
+		}
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// IsRateLimit reports whether err is the API rejecting a request because
+// the account sent too many. Capacity errors, which are also reported
+// with status 429, are not rate limits.
+func IsRateLimit(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || IsCapacity(err) {
+		return false
+	}
+	return apiErr.HasCode(ErrorCodeAccountLimited) || apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsCapacity reports whether err is the API rejecting a request because
+// the model is out of capacity. Falling back to another model is usually
+// faster than waiting.
+func IsCapacity(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.HasCode(ErrorCodeOutOfCapacity) {
+		return true
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests && apiErr.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	return strings.Contains(strings.ToLower(apiErr.Body), "capacity")
+}
+
+func hasAnyCode(err *APIError, codes []int) bool {
+	for _, code := range codes {
+		if err.HasCode(code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package workersai
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorTaxonomy(t *testing.T) {
+	coded := func(status, code int, body string) error {
+		return fmt.Errorf("request failed: %w", &APIError{StatusCode: status, Body: body, Errors: []APIMessage{{Code: code}}})
+	}
+
+	tests := []struct {
+		name                           string
+		err                            error
+		retryable, rateLimit, capacity bool
+	}{
+		{"network", fmt.Errorf("failed to make request: %w", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}), true, false, false},
+		{"url", &url.Error{Op: "Post", URL: "https://api.cloudflare.com", Err: io.EOF}, true, false, false},
+		{"url network", &url.Error{Op: "Post", URL: "https://api.cloudflare.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}, true, false, false},
+		{"bad scheme", &url.Error{Op: "Post", URL: "htp://api.cloudflare.com", Err: errors.New(`unsupported protocol scheme "htp"`)}, false, false, false},
+		{"x509", &url.Error{Op: "Post", URL: "https://api.cloudflare.com", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}, false, false, false},
+		{"truncated response", fmt.Errorf("failed to read response: %w", io.ErrUnexpectedEOF), true, false, false},
+		{"neuron limit", fmt.Errorf("send: %w", ErrNeuronLimitExceeded), false, false, false},
+		{"response too large", ErrResponseTooLarge, false, false, false},
+		{"client error", errors.New("failed to sign request"), false, false, false},
+		{"canceled", fmt.Errorf("send: %w", context.Canceled), false, false, false},
+		{"panic", &PanicError{Value: "boom"}, false, false, false},
+		{"out of capacity", coded(http.StatusTooManyRequests, ErrorCodeOutOfCapacity, ""), true, false, true},
+		{"capacity without code", &APIError{StatusCode: http.StatusServiceUnavailable, Body: "Capacity temporarily exceeded"}, true, false, true},
+		{"account limited", coded(http.StatusTooManyRequests, ErrorCodeAccountLimited, ""), true, true, false},
+		{"429 without code", &APIError{StatusCode: http.StatusTooManyRequests}, true, true, false},
+		{"timeout", coded(http.StatusRequestTimeout, ErrorCodeTimeout, ""), true, false, false},
+		{"server error", &APIError{StatusCode: http.StatusBadGateway}, true, false, false},
+		{"invalid data", coded(http.StatusBadRequest, ErrorCodeInvalidData, ""), false, false, false},
+		{"no such model on 500", coded(http.StatusInternalServerError, ErrorCodeNoSuchModel, ""), false, false, false},
+		{"context window", coded(http.StatusBadRequest, ErrorCodeContextWindow, ""), false, false, false},
+		{"unauthorized", &APIError{StatusCode: http.StatusUnauthorized}, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.retryable, IsRetryable(tt.err), "IsRetryable")
+			assert.Equal(t, tt.rateLimit, IsRateLimit(tt.err), "IsRateLimit")
+			assert.Equal(t, tt.capacity, IsCapacity(tt.err), "IsCapacity")
+		})
+	}
+	assert.False(t, IsRetryable(nil))
+}
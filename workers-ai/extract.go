@@ -0,0 +1,141 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Extract is ChatJSON decoding the reply into a new T.
+func Extract[T any](client ClientInterface, request ChatCompletionRequest, opts StructuredOptions) (T, *ChatResponse, error) {
+	var out T
+	resp, err := chatJSON(client, clientLogf(client), request, &out, opts)
+	return out, resp, err
+}
+
+// clientLogf returns the debug logger of client, if it has one.
+func clientLogf(client ClientInterface) func(string, ...interface{}) {
+	if c, ok := client.(*Client); ok {
+		return c.debugLog
+	}
+	return func(string, ...interface{}) {}
+}
+
+// SchemaVersion is a past version of an extraction schema, see
+// VersionedSchema. Create it with Version.
+type SchemaVersion struct {
+	Name   string
+	decode func(data []byte) (interface{}, error)
+	up     func(v interface{}) (interface{}, error)
+}
+
+// Version returns the version name of an extraction schema, decoded into
+// Old, with the migration of its values to the next version.
+func Version[Old, New any](name string, up func(Old) (New, error)) SchemaVersion {
+	return SchemaVersion{
+		Name: name,
+		decode: func(data []byte) (interface{}, error) {
+			var v Old
+			return v, decodeStrict(data, &v)
+		},
+		up: func(v interface{}) (interface{}, error) {
+			old, ok := v.(Old)
+			if !ok {
+				return nil, fmt.Errorf("version %s migrates from %T, not %T", name, *new(Old), v)
+			}
+			return up(old)
+		},
+	}
+}
+
+// VersionedSchema is an extraction schema that changed over time, for
+// teams iterating on it in production: replies and stored data matching a
+// past version are accepted and migrated forward to T.
+//
+// Data is matched against the versions from the newest to the oldest and
+// decoded by the first one it fits; data with properties a version doesn't
+// have doesn't fit it.
+type VersionedSchema[T any] struct {
+	// Current is the name of the version decoded into T.
+	Current string
+	// Past are the previous versions, oldest first. Each migrates to the
+	// next one, and the last one to T.
+	Past []SchemaVersion
+}
+
+// Extracted is a value decoded with a VersionedSchema.
+type Extracted[T any] struct {
+	Value T
+	// Version is the name of the version the data matched; Migrated is
+	// set when it is a past version.
+	Version  string
+	Migrated bool
+	// Response is the reply the value was extracted from, for
+	// ExtractVersioned.
+	Response *ChatResponse
+}
+
+// Decode decodes data with the newest version it matches and migrates it
+// to T.
+func (s VersionedSchema[T]) Decode(data []byte) (*Extracted[T], error) {
+	var current T
+	err := decodeStrict(data, &current)
+	if err == nil {
+		return &Extracted[T]{Value: current, Version: s.Current}, nil
+	}
+	errs := []string{fmt.Sprintf("%s: %v", s.Current, err)}
+
+	for i := len(s.Past) - 1; i >= 0; i-- {
+		version := s.Past[i]
+		v, err := version.decode(data)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", version.Name, err))
+			continue
+		}
+		for _, next := range s.Past[i:] {
+			if v, err = next.up(v); err != nil {
+				return nil, fmt.Errorf("failed to migrate %s data: %w", version.Name, err)
+			}
+		}
+		value, ok := v.(T)
+		if !ok {
+			return nil, fmt.Errorf("version %s migrates to %T, not %T", s.Past[len(s.Past)-1].Name, v, current)
+		}
+		return &Extracted[T]{Value: value, Version: version.Name, Migrated: true}, nil
+	}
+	return nil, fmt.Errorf("data matches no schema version (%s)", strings.Join(errs, "; "))
+}
+
+// ExtractVersioned is Extract accepting replies in any version of schema.
+// A reply matching no version is fed back to the model like a validation
+// error.
+func ExtractVersioned[T any](client ClientInterface, request ChatCompletionRequest, schema VersionedSchema[T], opts StructuredOptions) (*Extracted[T], error) {
+	var extracted *Extracted[T]
+	validate := opts.Validate
+	opts.Validate = func(v interface{}) error {
+		var err error
+		if extracted, err = schema.Decode(*v.(*json.RawMessage)); err != nil {
+			return err
+		}
+		if validate != nil {
+			return validate(&extracted.Value)
+		}
+		return nil
+	}
+
+	var raw json.RawMessage
+	resp, err := chatJSON(client, clientLogf(client), request, &raw, opts)
+	if err != nil {
+		return nil, err
+	}
+	extracted.Response = resp
+	return extracted, nil
+}
+
+// decodeStrict unmarshals data into out, failing on unknown properties.
+func decodeStrict(data []byte, out interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
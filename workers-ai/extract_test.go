@@ -0,0 +1,85 @@
+package workersai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contactV1 struct {
+	Name string `json:"name"`
+}
+
+type contactV2 struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+type contactV3 struct {
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Emails    []string `json:"emails"`
+}
+
+var contactSchema = VersionedSchema[contactV3]{
+	Current: "v3",
+	Past: []SchemaVersion{
+		Version("v1", func(v contactV1) (contactV2, error) {
+			first, last, _ := strings.Cut(v.Name, " ")
+			return contactV2{FirstName: first, LastName: last}, nil
+		}),
+		Version("v2", func(v contactV2) (contactV3, error) {
+			return contactV3{FirstName: v.FirstName, LastName: v.LastName}, nil
+		}),
+	},
+}
+
+func TestVersionedSchema_Decode(t *testing.T) {
+	extracted, err := contactSchema.Decode([]byte(`{"first_name": "Ada", "emails": ["ada@example.com"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "v3", extracted.Version)
+	assert.False(t, extracted.Migrated)
+	assert.Equal(t, []string{"ada@example.com"}, extracted.Value.Emails)
+
+	extracted, err = contactSchema.Decode([]byte(`{"name": "Ada Lovelace"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", extracted.Version)
+	assert.True(t, extracted.Migrated)
+	assert.Equal(t, contactV3{FirstName: "Ada", LastName: "Lovelace"}, extracted.Value)
+
+	_, err = contactSchema.Decode([]byte(`{"nickname": "Ada"}`))
+	assert.ErrorContains(t, err, "matches no schema version")
+
+	broken := VersionedSchema[contactV3]{Current: "v3", Past: contactSchema.Past[:1]}
+	_, err = broken.Decode([]byte(`{"name": "Ada"}`))
+	assert.ErrorContains(t, err, "migrates to")
+}
+
+func TestExtractVersioned(t *testing.T) {
+	replies := []string{`{"nickname": "Ada"}`, `{"name": "Ada Lovelace"}`, `{"first_name": "Ada"}`}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q}}`, replies[calls])
+		calls++
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Ada Lovelace"}}}
+
+	extracted, err := ExtractVersioned(client, request, contactSchema, StructuredOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "v1", extracted.Version)
+	assert.Equal(t, "Lovelace", extracted.Value.LastName)
+	assert.NotNil(t, extracted.Response)
+
+	contact, _, err := Extract[contactV2](client, request, StructuredOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", contact.FirstName)
+}
@@ -0,0 +1,188 @@
+package workersai
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultFewShotExamples is the number of examples a FewShot selects when
+// K is not set.
+const DefaultFewShotExamples = 3
+
+// FewShotExample is an input and the output expected for it.
+type FewShotExample struct {
+	Input  string
+	Output string
+}
+
+// FewShot selects, at call time, the examples of a bank most similar to the
+// request, by the cosine similarity of their embeddings, and injects them
+// into the prompt as previous turns. The examples are embedded once, on
+// first use; set Examples before then.
+type FewShot struct {
+	Client         ClientInterface
+	EmbeddingModel string
+	Examples       []FewShotExample
+
+	// K caps the number of examples selected. Defaults to
+	// DefaultFewShotExamples.
+	K int
+	// MaxTokens caps the estimated tokens of the selected examples. Zero
+	// means no limit.
+	MaxTokens int
+	// MinSimilarity leaves out the examples less similar to the request.
+	MinSimilarity float64
+
+	mu      sync.Mutex
+	vectors [][]float32
+}
+
+// NewFewShot returns a selector among examples, embedded with
+// embeddingModel through client.
+func NewFewShot(client ClientInterface, embeddingModel string, examples ...FewShotExample) *FewShot {
+	return &FewShot{Client: client, EmbeddingModel: embeddingModel, Examples: examples}
+}
+
+// Select returns the examples most relevant to query, within K and
+// MaxTokens, from the least to the most similar, so that the closest
+// example ends up next to the request.
+func (f *FewShot) Select(query string) ([]FewShotExample, error) {
+	vectors, err := f.embedExamples()
+	if err != nil {
+		return nil, err
+	}
+	result, err := f.Client.Embed(f.EmbeddingModel, []string{query}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(result.Data) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(result.Data))
+	}
+	queryVector := append([]float32(nil), result.Data[0]...)
+	normalize(queryVector)
+
+	type scored struct {
+		index int
+		score float64
+	}
+	candidates := make([]scored, 0, len(vectors))
+	for i, vector := range vectors {
+		var score float64
+		for j := range vector {
+			if j < len(queryVector) {
+				score += float64(vector[j]) * float64(queryVector[j])
+			}
+		}
+		if score >= f.MinSimilarity {
+			candidates = append(candidates, scored{i, score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	k := f.K
+	if k <= 0 {
+		k = DefaultFewShotExamples
+	}
+	var selected []FewShotExample
+	tokens := 0
+	for _, candidate := range candidates {
+		if len(selected) == k {
+			break
+		}
+		example := f.Examples[candidate.index]
+		cost := EstimateTokens(example.Input) + EstimateTokens(example.Output)
+		if f.MaxTokens > 0 && tokens+cost > f.MaxTokens {
+			// A shorter, less similar example may still fit.
+			continue
+		}
+		tokens += cost
+		selected = append(selected, example)
+	}
+
+	for i, j := 0, len(selected)-1; i < j; i, j = i+1, j-1 {
+		selected[i], selected[j] = selected[j], selected[i]
+	}
+	return selected, nil
+}
+
+// Messages returns the examples selected for query as user and assistant
+// turns.
+func (f *FewShot) Messages(query string) ([]Message, error) {
+	examples, err := f.Select(query)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, 2*len(examples))
+	for _, example := range examples {
+		messages = append(messages,
+			ChatMessage{Role: "user", Content: example.Input},
+			ChatMessage{Role: "assistant", Content: example.Output},
+		)
+	}
+	return messages, nil
+}
+
+// Apply injects into request the examples selected for its last user
+// message, after its system prompt.
+func (f *FewShot) Apply(request *ChatCompletionRequest) error {
+	query, at := "", 0
+	for i, msg := range request.Messages {
+		chat, ok := msg.(ChatMessage)
+		if !ok {
+			continue
+		}
+		if chat.Role == "system" && at == i {
+			at = i + 1
+		}
+		if chat.Role == "user" {
+			query = chat.Content
+		}
+	}
+	if query == "" {
+		return fmt.Errorf("request has no user message")
+	}
+
+	examples, err := f.Messages(query)
+	if err != nil {
+		return err
+	}
+	messages := make([]Message, 0, len(request.Messages)+len(examples))
+	messages = append(messages, request.Messages[:at]...)
+	messages = append(messages, examples...)
+	request.Messages = append(messages, request.Messages[at:]...)
+	return nil
+}
+
+// embedExamples returns the normalized embeddings of the examples,
+// computing them on first use.
+func (f *FewShot) embedExamples() ([][]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.vectors != nil {
+		return f.vectors, nil
+	}
+
+	vectors := make([][]float32, 0, len(f.Examples))
+	for start := 0; start < len(f.Examples); start += DefaultEmbeddingBatchSize {
+		batch := f.Examples[start:min(start+DefaultEmbeddingBatchSize, len(f.Examples))]
+		inputs := make([]string, len(batch))
+		for i, example := range batch {
+			inputs[i] = example.Input
+		}
+		result, err := f.Client.Embed(f.EmbeddingModel, inputs, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed examples: %w", err)
+		}
+		if len(result.Data) != len(batch) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(result.Data))
+		}
+		for _, vector := range result.Data {
+			vector = append([]float32(nil), vector...)
+			normalize(vector)
+			vectors = append(vectors, vector)
+		}
+	}
+	f.vectors = vectors
+	return vectors, nil
+}
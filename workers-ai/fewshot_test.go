@@ -0,0 +1,106 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// topicEmbeddingServer embeds texts on three axes: weather, billing and
+// shipping, by the words they contain.
+func topicEmbeddingServer(t *testing.T, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		var req struct {
+			Text []string `json:"text"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		data := make([][]float32, len(req.Text))
+		for i, text := range req.Text {
+			vector := []float32{0.1, 0.1, 0.1}
+			for axis, word := range []string{"rain", "invoice", "parcel"} {
+				if strings.Contains(text, word) {
+					vector[axis] += 1
+				}
+			}
+			data[i] = vector
+		}
+		encoded, _ := json.Marshal(data)
+		fmt.Fprintf(w, `{"success": true, "result": {"shape": [%d, 3], "data": %s}}`, len(data), encoded)
+	}))
+}
+
+func TestFewShot_Select(t *testing.T) {
+	calls := 0
+	server := topicEmbeddingServer(t, &calls)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	shots := NewFewShot(client, ModelBAAI,
+		FewShotExample{Input: "Will it rain?", Output: "weather"},
+		FewShotExample{Input: "Where is my invoice?", Output: "billing"},
+		FewShotExample{Input: "My parcel is late", Output: "shipping"},
+		FewShotExample{Input: "Is the invoice for the parcel paid?", Output: "billing, shipping " + strings.Repeat("x", 200)},
+	)
+	shots.K = 2
+
+	examples, err := shots.Select("I lost my invoice")
+	require.NoError(t, err)
+	require.Len(t, examples, 2)
+	// The most similar example comes last.
+	assert.Equal(t, "billing", examples[1].Output)
+	assert.Equal(t, "Is the invoice for the parcel paid?", examples[0].Input)
+
+	// The long example doesn't fit the budget.
+	shots.MaxTokens = 20
+	examples, err = shots.Select("I lost my invoice")
+	require.NoError(t, err)
+	for _, example := range examples {
+		assert.NotContains(t, example.Output, "xxx")
+	}
+
+	shots.MaxTokens = 0
+	shots.MinSimilarity = 0.9
+	examples, err = shots.Select("rain tomorrow?")
+	require.NoError(t, err)
+	require.Len(t, examples, 1)
+	assert.Equal(t, "weather", examples[0].Output)
+
+	// The examples are embedded once.
+	assert.Equal(t, 4, calls)
+}
+
+func TestFewShot_Apply(t *testing.T) {
+	calls := 0
+	server := topicEmbeddingServer(t, &calls)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	shots := NewFewShot(client, ModelBAAI,
+		FewShotExample{Input: "Will it rain?", Output: "weather"},
+		FewShotExample{Input: "My parcel is late", Output: "shipping"},
+	)
+	shots.K = 1
+
+	request := ChatCompletionRequest{Messages: []Message{
+		ChatMessage{Role: "system", Content: "Classify the message."},
+		ChatMessage{Role: "user", Content: "Where is my parcel?"},
+	}}
+	require.NoError(t, shots.Apply(&request))
+	assert.Equal(t, []Message{
+		ChatMessage{Role: "system", Content: "Classify the message."},
+		ChatMessage{Role: "user", Content: "My parcel is late"},
+		ChatMessage{Role: "assistant", Content: "shipping"},
+		ChatMessage{Role: "user", Content: "Where is my parcel?"},
+	}, request.Messages)
+
+	assert.Error(t, shots.Apply(&ChatCompletionRequest{}))
+}
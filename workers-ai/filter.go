@@ -0,0 +1,253 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FilterAction is the outcome of a ResponseFilter.
+type FilterAction int
+
+const (
+	// FilterAllow lets the response through unchanged. The verdict is
+	// recorded in ChatResponse.FilterVerdicts only if it has Matches.
+	FilterAllow FilterAction = iota
+	// FilterAnnotate lets the response through and records the verdict in
+	// ChatResponse.FilterVerdicts.
+	FilterAnnotate
+	// FilterRedact replaces the response content with FilterVerdict.Replacement.
+	FilterRedact
+	// FilterBlock fails the call with a *BlockedError.
+	FilterBlock
+)
+
+func (a FilterAction) String() string {
+	switch a {
+	case FilterAllow:
+		return "allow"
+	case FilterAnnotate:
+		return "annotate"
+	case FilterRedact:
+		return "redact"
+	case FilterBlock:
+		return "block"
+	default:
+		return fmt.Sprintf("FilterAction(%d)", int(a))
+	}
+}
+
+// FilterVerdict is the decision of a ResponseFilter about a response.
+type FilterVerdict struct {
+	// Filter names the filter that produced the verdict.
+	Filter string
+	Action FilterAction
+	// Categories are the flagged categories, e.g. Llama Guard's "S1" or
+	// the names of the matching rules.
+	Categories []string
+	// Replacement is the new content when Action is FilterRedact.
+	Replacement string
+	// Matches are the spans matched by the rules of a RuleFilter.
+	Matches []FilterMatch
+}
+
+// ResponseFilter inspects the content of chat responses before they are
+// returned to the caller. Filters are set in Client.ResponseFilters and run
+// in order by Chat, ChatWithTools and ChatCompletion; streamed responses are
+// not filtered.
+type ResponseFilter interface {
+	Check(content string) (FilterVerdict, error)
+}
+
+// BlockedError is returned when a ResponseFilter blocked a response.
+type BlockedError struct {
+	Verdict FilterVerdict
+}
+
+func (e *BlockedError) Error() string {
+	if len(e.Verdict.Categories) == 0 {
+		return fmt.Sprintf("response blocked by %s filter", e.Verdict.Filter)
+	}
+	return fmt.Sprintf("response blocked by %s filter: %s", e.Verdict.Filter, strings.Join(e.Verdict.Categories, ", "))
+}
+
+// applyFilters runs c.ResponseFilters on the content of resp.
+func (c *Client) applyFilters(resp *ChatResponse) error {
+	for _, filter := range c.ResponseFilters {
+		content := resp.GetContent()
+		if content == "" {
+			return nil
+		}
+
+		verdict, err := filter.Check(content)
+		if err != nil {
+			return fmt.Errorf("response filter failed: %w", err)
+		}
+
+		switch verdict.Action {
+		case FilterAllow:
+			// Matches of rules that only allow are kept for the caller
+			// to log.
+			if len(verdict.Matches) == 0 {
+				continue
+			}
+		case FilterBlock:
+			c.debugLog("Response blocked by %s filter: %v", verdict.Filter, verdict.Categories)
+			return &BlockedError{Verdict: verdict}
+		case FilterRedact:
+			resp.setContent(verdict.Replacement)
+		}
+		resp.FilterVerdicts = append(resp.FilterVerdicts, verdict)
+	}
+	return nil
+}
+
+// setContent replaces the content of the response, whatever its format.
+func (r *ChatResponse) setContent(content string) {
+	if r.IsLegacyResult {
+		r.LegacyResponse.Response = content
+		return
+	}
+	if len(r.ChatCompletionResponse.Choices) > 0 {
+		r.ChatCompletionResponse.Choices[0].Message.Content = &content
+	}
+}
+
+// KeywordFilter flags responses matching any of its patterns. It is a
+// RuleFilter with a rule per pattern, all taking the same action.
+type KeywordFilter struct {
+	// Patterns maps a rule name, reported as category, to its expression.
+	Patterns map[string]*regexp.Regexp
+	// Action is taken when a pattern matches.
+	Action FilterAction
+	// Mask replaces the matches when Action is FilterRedact. Defaults to Redacted.
+	Mask string
+}
+
+// NewKeywordFilter returns a filter matching the given words case-insensitively
+// on word boundaries.
+func NewKeywordFilter(action FilterAction, words ...string) *KeywordFilter {
+	patterns := make(map[string]*regexp.Regexp, len(words))
+	for _, word := range words {
+		patterns[word] = regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`)
+	}
+	return &KeywordFilter{Patterns: patterns, Action: action}
+}
+
+// RuleFilter returns the equivalent RuleFilter, with the rules in name
+// order.
+func (f *KeywordFilter) RuleFilter() *RuleFilter {
+	names := make([]string, 0, len(f.Patterns))
+	for name := range f.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make([]FilterRule, len(names))
+	for i, name := range names {
+		rules[i] = FilterRule{Name: name, Pattern: f.Patterns[name], Action: f.Action, Mask: f.Mask}
+	}
+	return &RuleFilter{Name: "keyword", Rules: rules}
+}
+
+func (f *KeywordFilter) Check(content string) (FilterVerdict, error) {
+	verdict, err := f.RuleFilter().Check(content)
+	// Categories are reported in name order, so that verdicts are
+	// deterministic whatever the position of the matches.
+	sort.Strings(verdict.Categories)
+	return verdict, err
+}
+
+// ModelLlamaGuard3 is the moderation model used by ModerationFilter by default.
+const ModelLlamaGuard3 = "@cf/meta/llama-guard-3-8b"
+
+// ModerationFilter classifies responses with a moderation model such as
+// Llama Guard and takes Action on unsafe ones.
+type ModerationFilter struct {
+	Client *Client
+	// Model is the moderation model. Defaults to ModelLlamaGuard3.
+	Model string
+	// Action is taken when the response is classified as unsafe.
+	Action FilterAction
+	// Replacement is the content of redacted responses. Defaults to Redacted.
+	Replacement string
+}
+
+func (f *ModerationFilter) Check(content string) (FilterVerdict, error) {
+	model := f.Model
+	if model == "" {
+		model = ModelLlamaGuard3
+	}
+
+	// The moderation call bypasses the chat helpers, so that the filters
+	// aren't applied to the moderation model's own answer.
+	request := map[string]interface{}{
+		"messages": []Message{
+			ChatMessage{Role: "user", Content: "Classify the following assistant reply."},
+			ChatMessage{Role: "assistant", Content: content},
+		},
+	}
+
+	var result struct {
+		Response json.RawMessage `json:"response"`
+	}
+	if err := f.Client.run(model, request, &result); err != nil {
+		return FilterVerdict{}, fmt.Errorf("moderation request failed: %w", err)
+	}
+
+	safe, categories, err := parseModeration(result.Response)
+	if err != nil {
+		return FilterVerdict{}, err
+	}
+
+	verdict := FilterVerdict{Filter: "moderation"}
+	if safe {
+		return verdict, nil
+	}
+
+	verdict.Action = f.Action
+	verdict.Categories = categories
+	if f.Action == FilterRedact {
+		verdict.Replacement = f.Replacement
+		if verdict.Replacement == "" {
+			verdict.Replacement = Redacted
+		}
+	}
+	return verdict, nil
+}
+
+// parseModeration reads a Llama Guard answer, given either as an object
+// {"safe": false, "categories": ["S1"]} or as the raw "unsafe\nS1,S2" text.
+func parseModeration(raw json.RawMessage) (safe bool, categories []string, err error) {
+	var object struct {
+		Safe       *bool    `json:"safe"`
+		Categories []string `json:"categories"`
+	}
+	if err := json.Unmarshal(raw, &object); err == nil && object.Safe != nil {
+		return *object.Safe, object.Categories, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return false, nil, fmt.Errorf("failed to parse moderation result: %s", raw)
+	}
+
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	switch strings.ToLower(strings.TrimSpace(lines[0])) {
+	case "safe":
+		return true, nil, nil
+	case "unsafe":
+		if len(lines) > 1 {
+			for _, category := range strings.Split(lines[1], ",") {
+				if category = strings.TrimSpace(category); category != "" {
+					categories = append(categories, category)
+				}
+			}
+		}
+		return false, categories, nil
+	default:
+		return false, nil, fmt.Errorf("unexpected moderation result: %q", text)
+	}
+}
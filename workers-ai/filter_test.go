@@ -0,0 +1,97 @@
+package workersai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeywordFilter(t *testing.T) {
+	filter := NewKeywordFilter(FilterRedact, "secret", "p4ss")
+
+	verdict, err := filter.Check("The Secret is p4ss, not secretive.")
+	require.NoError(t, err)
+	assert.Equal(t, FilterRedact, verdict.Action)
+	assert.Equal(t, []string{"p4ss", "secret"}, verdict.Categories)
+	assert.Equal(t, "The [REDACTED] is [REDACTED], not secretive.", verdict.Replacement)
+	require.Len(t, verdict.Matches, 2)
+	assert.Equal(t, FilterMatch{Rule: "secret", Start: 4, End: 10, Text: "Secret", Action: FilterRedact}, verdict.Matches[0])
+
+	verdict, err = filter.Check("Nothing to see.")
+	require.NoError(t, err)
+	assert.Equal(t, FilterAllow, verdict.Action)
+}
+
+func TestParseModeration(t *testing.T) {
+	tests := []struct {
+		raw        string
+		safe       bool
+		categories []string
+	}{
+		{`{"safe": true}`, true, nil},
+		{`{"safe": false, "categories": ["S1"]}`, false, []string{"S1"}},
+		{`"safe"`, true, nil},
+		{`"\n\nunsafe\nS2,S10"`, false, []string{"S2", "S10"}},
+	}
+	for _, tt := range tests {
+		safe, categories, err := parseModeration([]byte(tt.raw))
+		require.NoError(t, err, tt.raw)
+		assert.Equal(t, tt.safe, safe, tt.raw)
+		assert.Equal(t, tt.categories, categories, tt.raw)
+	}
+
+	_, _, err := parseModeration([]byte(`"maybe"`))
+	assert.Error(t, err)
+}
+
+func TestClient_ResponseFilters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ModelLlamaGuard3) {
+			fmt.Fprint(w, `{"success": true, "result": {"response": {"safe": false, "categories": ["S7"]}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Call me at 555-0100."}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	client.ResponseFilters = []ResponseFilter{&ModerationFilter{Client: client, Action: FilterAnnotate}}
+	resp, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Call me at 555-0100.", resp.GetContent())
+	require.Len(t, resp.FilterVerdicts, 1)
+	assert.Equal(t, []string{"S7"}, resp.FilterVerdicts[0].Categories)
+
+	client.ResponseFilters = []ResponseFilter{&ModerationFilter{Client: client, Action: FilterRedact, Replacement: "Sorry."}}
+	resp, err = client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Sorry.", resp.GetContent())
+
+	// The matches of rules that allow are recorded, verdicts without
+	// matches aren't.
+	client.ResponseFilters = []ResponseFilter{
+		NewRuleFilter(FilterRule{Name: "phone", Pattern: regexp.MustCompile(`555-\d{4}`), Action: FilterAllow}),
+		NewKeywordFilter(FilterAllow, "secret"),
+	}
+	resp, err = client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Call me at 555-0100.", resp.GetContent())
+	require.Len(t, resp.FilterVerdicts, 1)
+	assert.Equal(t, FilterAllow, resp.FilterVerdicts[0].Action)
+	assert.Equal(t, []FilterMatch{{Rule: "phone", Start: 11, End: 19, Text: "555-0100", Action: FilterAllow}}, resp.FilterVerdicts[0].Matches)
+
+	client.ResponseFilters = []ResponseFilter{NewKeywordFilter(FilterBlock, "555-0100")}
+	_, err = client.Chat(ModelLlama38B, messages, nil)
+	var blocked *BlockedError
+	require.ErrorAs(t, err, &blocked)
+	assert.Equal(t, []string{"555-0100"}, blocked.Verdict.Categories)
+}
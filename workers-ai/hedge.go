@@ -0,0 +1,81 @@
+package workersai
+
+import (
+	"context"
+	"time"
+)
+
+// Hedging makes ChatCompletion fire a duplicate of a request that hasn't
+// answered after Delay, and return whichever response arrives first. The
+// other request is cancelled. Set Delay around the p95 latency of the
+// model, so that only the slowest requests are duplicated.
+//
+// Streaming requests are not hedged.
+type Hedging struct {
+	// Delay is how long to wait for the first response before hedging.
+	// Zero disables hedging.
+	Delay time.Duration
+	// Model receives the duplicate request, e.g. a smaller and faster
+	// model. Defaults to the model of the request.
+	Model string
+}
+
+// hedgedComplete sends request with complete, hedged according to
+// c.Hedging. An error of the first request before the hedge was sent is
+// returned as is; after that, the call only fails if both requests fail.
+func (c *Client) hedgedComplete(ctx context.Context, request ChatCompletionRequest) (*ChatResponse, error) {
+	if c.Hedging.Delay <= 0 {
+		return c.complete(ctx, request)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		resp  *ChatResponse
+		err   error
+		hedge bool
+	}
+	results := make(chan result, 2)
+	start := func(request ChatCompletionRequest, hedge bool) {
+		go func() {
+			r := result{hedge: hedge}
+			defer func() { results <- r }()
+			defer recoverPanic(&r.err)
+			r.resp, r.err = c.complete(ctx, request)
+		}()
+	}
+
+	start(request, false)
+	pending := 1
+
+	timer := time.NewTimer(c.Hedging.Delay)
+	defer timer.Stop()
+
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			hedge := request
+			if c.Hedging.Model != "" {
+				hedge.Model = c.Hedging.Model
+			}
+			c.debugLog("No response after %v, hedging with %s", c.Hedging.Delay, hedge.Model)
+			start(hedge, true)
+			pending++
+
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				r.resp.Hedged = r.hedge
+				return r.resp, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Hedging(t *testing.T) {
+	var cancelled atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		if request.Model == ModelLlama370B {
+			// The primary model is stuck until the request is cancelled.
+			select {
+			case <-r.Context().Done():
+				cancelled.Store(true)
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "result": {"response": "from %s"}}`, request.Model)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Hedging = Hedging{Delay: 20 * time.Millisecond, Model: ModelLlama38B}
+
+	start := time.Now()
+	resp, err := client.Chat(ModelLlama370B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "from "+ModelLlama38B, resp.GetContent())
+	assert.True(t, resp.Hedged)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Eventually(t, cancelled.Load, time.Second, time.Millisecond, "the slow request is cancelled")
+}
+
+func TestClient_Hedging_FastPrimary(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, `{"success": true, "result": {"response": "fast"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Hedging = Hedging{Delay: time.Second}
+
+	resp, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	assert.False(t, resp.Hedged)
+	assert.Equal(t, int32(1), requests.Load())
+}
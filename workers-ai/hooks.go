@@ -0,0 +1,85 @@
+package workersai
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Hooks are callbacks observing the requests made by a Client, e.g. to
+// collect metrics or traces. Either field may be nil.
+type Hooks struct {
+	// BeforeRequest is called with every request right before it is sent.
+	BeforeRequest func(req *http.Request)
+	// AfterResponse is called once a request finished, successfully or not.
+	AfterResponse func(event RequestEvent)
+}
+
+// RequestEvent describes a finished request to the API.
+type RequestEvent struct {
+	// Model is the model the request was sent to.
+	Model string
+	// StatusCode is the HTTP status of the response, or 0 if none was received.
+	StatusCode int
+	// Duration is the time from sending the request to reading the whole response.
+	Duration time.Duration
+	// Usage is the token usage reported in the response, if any.
+	Usage Usage
+	// CacheStatus is the AI Gateway cache status of the response, if any.
+	CacheStatus string
+	// Tenant and Metadata are the tags of the request, see WithTenant and
+	// WithMetadata.
+	Tenant   string
+	Metadata map[string]string
+	// Stream holds the timing of streamed responses; it is nil otherwise.
+	Stream *StreamStats
+	// Err is the error returned to the caller, if any.
+	Err error
+}
+
+// Use registers hooks on the client. It must be called before the client is
+// used concurrently.
+func (c *Client) Use(hooks Hooks) {
+	c.hooks = append(c.hooks, hooks)
+}
+
+func (c *Client) beforeRequest(req *http.Request) {
+	for _, h := range c.hooks {
+		if h.BeforeRequest != nil {
+			h.BeforeRequest(req)
+		}
+	}
+}
+
+func (c *Client) afterResponse(event RequestEvent, body []byte) {
+	if len(c.hooks) == 0 {
+		return
+	}
+
+	if event.Err == nil && body != nil {
+		event.Usage = responseUsage(body)
+	}
+
+	for _, h := range c.hooks {
+		if h.AfterResponse != nil {
+			h.AfterResponse(event)
+		}
+	}
+}
+
+// responseUsage extracts the token usage from a JSON response body. Both the
+// OpenAI-compatible and the legacy format report it as "result.usage"; the
+// /ai/v1 endpoints as "usage", without envelope.
+func responseUsage(body []byte) Usage {
+	var envelope struct {
+		Result struct {
+			Usage Usage `json:"usage"`
+		} `json:"result"`
+		Usage Usage `json:"usage"`
+	}
+	_ = json.Unmarshal(body, &envelope)
+	if envelope.Result.Usage == (Usage{}) {
+		return envelope.Usage
+	}
+	return envelope.Result.Usage
+}
@@ -0,0 +1,49 @@
+package workersai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Use(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "hooked", r.Header.Get("X-Test"))
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi", "usage": {"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var events []RequestEvent
+	client.Use(Hooks{
+		BeforeRequest: func(req *http.Request) { req.Header.Set("X-Test", "hooked") },
+		AfterResponse: func(event RequestEvent) { events = append(events, event) },
+	})
+
+	_, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+
+	status = http.StatusInternalServerError
+	_, err = client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.Error(t, err)
+
+	require.Len(t, events, 2)
+
+	assert.Equal(t, ModelLlama38B, events[0].Model)
+	assert.Equal(t, http.StatusOK, events[0].StatusCode)
+	assert.Equal(t, Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}, events[0].Usage)
+	assert.NoError(t, events[0].Err)
+	assert.Positive(t, events[0].Duration)
+
+	assert.Equal(t, http.StatusInternalServerError, events[1].StatusCode)
+	assert.Zero(t, events[1].Usage)
+	assert.Error(t, events[1].Err)
+}
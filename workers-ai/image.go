@@ -0,0 +1,89 @@
+package workersai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ImageOptions are the optional settings of GenerateImage. Zero fields keep
+// the model's defaults.
+type ImageOptions struct {
+	// NegativePrompt describes what the image should not contain.
+	NegativePrompt string
+	Width          int
+	Height         int
+	// Steps is the number of diffusion steps; more are slower and finer.
+	Steps int
+	// Guidance is how closely the image follows the prompt.
+	Guidance float64
+	// Seed makes the generation reproducible.
+	Seed int64
+}
+
+// imageRequest is the input of the text-to-image models.
+type imageRequest struct {
+	Prompt         string  `json:"prompt"`
+	NegativePrompt string  `json:"negative_prompt,omitempty"`
+	Width          int     `json:"width,omitempty"`
+	Height         int     `json:"height,omitempty"`
+	Steps          int     `json:"num_steps,omitempty"`
+	Guidance       float64 `json:"guidance,omitempty"`
+	Seed           int64   `json:"seed,omitempty"`
+}
+
+// ImageResult is the output of the text-to-image models.
+type ImageResult struct {
+	// Image is the encoded image.
+	Image []byte
+	// ContentType is the type of Image, e.g. "image/png".
+	ContentType string
+
+	resultMeta
+}
+
+// GenerateImage draws an image from prompt with a text-to-image model such
+// as ModelStableDiffusion. opts may be nil.
+func (c *Client) GenerateImage(modelID, prompt string, opts *ImageOptions) (*ImageResult, error) {
+	request := imageRequest{Prompt: prompt}
+	if opts != nil {
+		request.NegativePrompt = opts.NegativePrompt
+		request.Width = opts.Width
+		request.Height = opts.Height
+		request.Steps = opts.Steps
+		request.Guidance = opts.Guidance
+		request.Seed = opts.Seed
+	}
+
+	start := time.Now()
+	body, contentType, err := c.runJSON(context.Background(), modelID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Most models return the image as-is, others wrap it in the usual
+	// envelope as a base64 string.
+	if !strings.HasPrefix(contentType, "application/json") {
+		result := &ImageResult{Image: body, ContentType: contentType}
+		result.setResultMeta(resultMeta{model: modelID, latency: time.Since(start)})
+		return result, nil
+	}
+
+	var encoded struct {
+		Image string `json:"image"`
+	}
+	if err := c.decodeResult(body, &encoded); err != nil {
+		return nil, err
+	}
+	image, err := base64.StdEncoding.DecodeString(encoded.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	result := &ImageResult{Image: image, ContentType: http.DetectContentType(image)}
+	result.setResultMeta(newResultMeta(modelID, time.Since(start), body))
+	return result, nil
+}
@@ -0,0 +1,28 @@
+package workersai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateImage_Base64(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"success": true, "result": {"image": "iVBORw0KGgo="}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	image, err := client.GenerateImage("@cf/black-forest-labs/flux-1-schnell", "a cat", &ImageOptions{Steps: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\x89PNG\r\n\x1a\n"), image.Image)
+	assert.Equal(t, "image/png", image.ContentType)
+	assert.NotEmpty(t, image.Raw())
+}
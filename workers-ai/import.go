@@ -0,0 +1,238 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ImportOpenAIMessages converts an OpenAI chat completions "messages" array
+// into messages for this client. It accepts either the bare array or a
+// request object with a "messages" field.
+//
+// The "developer" role is mapped to "system". Content given as an array of
+// parts is joined when all parts are text; other parts (images, audio) can't
+// be represented and fail the import.
+func ImportOpenAIMessages(data []byte) ([]Message, error) {
+	type openAIMessage struct {
+		Role       string          `json:"role"`
+		Content    json.RawMessage `json:"content"`
+		ToolCalls  []ToolCall      `json:"tool_calls"`
+		ToolCallID string          `json:"tool_call_id"`
+	}
+
+	var raw []openAIMessage
+	if err := unmarshalMessages(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAI messages: %w", err)
+	}
+
+	messages := make([]Message, 0, len(raw))
+	for i, m := range raw {
+		content, err := openAIContent(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		switch m.Role {
+		case "system", "developer":
+			messages = append(messages, ChatMessage{Role: "system", Content: content})
+		case "user":
+			messages = append(messages, ChatMessage{Role: "user", Content: content})
+		case "assistant":
+			messages = append(messages, newAssistantMessage(content, m.ToolCalls))
+		case "tool":
+			messages = append(messages, ToolMessage{Role: "tool", Content: content, ToolCallID: m.ToolCallID})
+		default:
+			return nil, fmt.Errorf("message %d: unknown message role found: %s", i, m.Role)
+		}
+	}
+
+	return messages, nil
+}
+
+// ImportAnthropicMessages converts an Anthropic Messages API conversation
+// into messages for this client. It accepts either the bare "messages" array
+// or a request object, in which case its "system" prompt is imported too.
+//
+// tool_use blocks become tool calls of the assistant message and every
+// tool_result block becomes a separate tool message.
+func ImportAnthropicMessages(data []byte) ([]Message, error) {
+	type anthropicMessage struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+
+	var raw []anthropicMessage
+	if err := unmarshalMessages(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse Anthropic messages: %w", err)
+	}
+
+	var messages []Message
+
+	var request struct {
+		System json.RawMessage `json:"system"`
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		_ = json.Unmarshal(trimmed, &request)
+	}
+	if len(request.System) > 0 {
+		blocks, err := anthropicBlocks(request.System)
+		if err != nil {
+			return nil, fmt.Errorf("system prompt: %w", err)
+		}
+		messages = append(messages, ChatMessage{Role: "system", Content: joinTextBlocks(blocks)})
+	}
+
+	for i, m := range raw {
+		blocks, err := anthropicBlocks(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		var (
+			text      []string
+			toolCalls []ToolCall
+		)
+		for _, block := range blocks {
+			switch block.Type {
+			case "text":
+				text = append(text, block.Text)
+			case "tool_use":
+				arguments := string(block.Input)
+				if arguments == "" {
+					arguments = "{}"
+				}
+				toolCalls = append(toolCalls, ToolCall{
+					ID:       block.ID,
+					Type:     "function",
+					Function: FunctionToCall{Name: block.Name, Arguments: arguments},
+				})
+			case "tool_result":
+				resultBlocks, err := anthropicBlocks(block.Content)
+				if err != nil {
+					return nil, fmt.Errorf("message %d: tool result: %w", i, err)
+				}
+				content := joinTextBlocks(resultBlocks)
+				if block.IsError {
+					content = "Error: " + content
+				}
+				messages = append(messages, ToolMessage{Role: "tool", Content: content, ToolCallID: block.ToolUseID})
+			case "thinking", "redacted_thinking":
+				// Reasoning blocks are not part of the conversation sent back to the model.
+			default:
+				return nil, fmt.Errorf("message %d: unsupported content block type: %s", i, block.Type)
+			}
+		}
+
+		content := strings.Join(text, "\n")
+		switch m.Role {
+		case "user":
+			if len(text) > 0 {
+				messages = append(messages, ChatMessage{Role: "user", Content: content})
+			}
+		case "assistant":
+			messages = append(messages, newAssistantMessage(content, toolCalls))
+		default:
+			return nil, fmt.Errorf("message %d: unknown message role found: %s", i, m.Role)
+		}
+	}
+
+	return messages, nil
+}
+
+// anthropicBlock is a content block of the Anthropic Messages API.
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+// anthropicBlocks decodes content that is either a plain string or an array
+// of content blocks.
+func anthropicBlocks(raw json.RawMessage) ([]anthropicBlock, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		return []anthropicBlock{{Type: "text", Text: text}}, nil
+	}
+
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, fmt.Errorf("failed to parse content blocks: %w", err)
+	}
+	return blocks, nil
+}
+
+func joinTextBlocks(blocks []anthropicBlock) string {
+	var text []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			text = append(text, block.Text)
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// openAIContent decodes content that is either a string, null or an array of
+// content parts.
+func openAIContent(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+
+	if raw[0] == '"' {
+		var text string
+		err := json.Unmarshal(raw, &text)
+		return text, err
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("failed to parse content parts: %w", err)
+	}
+
+	text := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return "", fmt.Errorf("unsupported content part type: %s", part.Type)
+		}
+		text = append(text, part.Text)
+	}
+	return strings.Join(text, "\n"), nil
+}
+
+// unmarshalMessages decodes data that is either a messages array or an
+// object holding one in its "messages" field.
+func unmarshalMessages(data []byte, out interface{}) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var wrapper struct {
+			Messages json.RawMessage `json:"messages"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return err
+		}
+		if len(wrapper.Messages) == 0 {
+			return fmt.Errorf("object has no messages field")
+		}
+		data = wrapper.Messages
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,103 @@
+package workersai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportOpenAIMessages(t *testing.T) {
+	data := `{
+		"model": "gpt-4o",
+		"messages": [
+			{"role": "developer", "content": "Be brief."},
+			{"role": "user", "content": [{"type": "text", "text": "Weather in"}, {"type": "text", "text": "Paris?"}]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"},
+			{"role": "assistant", "content": "Sunny."}
+		]
+	}`
+
+	messages, err := ImportOpenAIMessages([]byte(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Message{
+		ChatMessage{Role: "system", Content: "Be brief."},
+		ChatMessage{Role: "user", Content: "Weather in\nParis?"},
+		ResponseMessage{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call_1", Type: "function", Function: FunctionToCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+		}},
+		ToolMessage{Role: "tool", Content: "sunny", ToolCallID: "call_1"},
+		ChatMessage{Role: "assistant", Content: "Sunny."},
+	}, messages)
+}
+
+func TestImportOpenAIMessages_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"image part", `[{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "x"}}]}]`},
+		{"unknown role", `[{"role": "function", "content": "x"}]`},
+		{"no messages", `{"model": "gpt-4o"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportOpenAIMessages([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestImportAnthropicMessages(t *testing.T) {
+	data := `{
+		"system": [{"type": "text", "text": "Be brief."}],
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "..."},
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "sunny"}]},
+				{"type": "text", "text": "And tomorrow?"}
+			]},
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_2", "name": "get_weather", "input": {}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_2", "content": "timeout", "is_error": true}]}
+		]
+	}`
+
+	messages, err := ImportAnthropicMessages([]byte(data))
+	require.NoError(t, err)
+
+	checking := "Checking."
+	assert.Equal(t, []Message{
+		ChatMessage{Role: "system", Content: "Be brief."},
+		ChatMessage{Role: "user", Content: "Weather in Paris?"},
+		ResponseMessage{Role: "assistant", Content: &checking, ToolCalls: []ToolCall{
+			{ID: "toolu_1", Type: "function", Function: FunctionToCall{Name: "get_weather", Arguments: `{"city": "Paris"}`}},
+		}},
+		ToolMessage{Role: "tool", Content: "sunny", ToolCallID: "toolu_1"},
+		ChatMessage{Role: "user", Content: "And tomorrow?"},
+		ResponseMessage{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "toolu_2", Type: "function", Function: FunctionToCall{Name: "get_weather", Arguments: `{}`}},
+		}},
+		ToolMessage{Role: "tool", Content: "Error: timeout", ToolCallID: "toolu_2"},
+	}, messages)
+}
+
+func TestImportAnthropicMessages_BareArray(t *testing.T) {
+	messages, err := ImportAnthropicMessages([]byte(`[{"role": "user", "content": "Hi"}, {"role": "assistant", "content": "Hello!"}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []Message{
+		ChatMessage{Role: "user", Content: "Hi"},
+		ChatMessage{Role: "assistant", Content: "Hello!"},
+	}, messages)
+
+	_, err = ImportAnthropicMessages([]byte(`[{"role": "user", "content": [{"type": "image", "source": {}}]}]`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,299 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of an Ingester.
+const (
+	DefaultChunkTokens     = 256
+	DefaultChunkOverlap    = 32
+	DefaultIngestWorkers   = 4
+	DefaultIngestBatchSize = 50
+)
+
+// Document is a text to ingest into a vector index.
+type Document struct {
+	// ID identifies the document. The vectors of its chunks have the IDs
+	// <ID>#<n>, so ingesting a document again replaces them; the chunks
+	// beyond the new count are deleted.
+	ID   string
+	Text string
+	// Metadata is stored with every chunk, along with the fields
+	// "document", "chunk" and "text".
+	Metadata map[string]interface{}
+	// Namespace, if set, is the namespace of the vectors, e.g. the tenant.
+	Namespace string
+}
+
+// IngestStats are the counters of an Ingester.
+type IngestStats struct {
+	// Documents counts the documents ingested; Failed those given up on
+	// after the retries.
+	Documents int64
+	Failed    int64
+	// Chunks counts the vectors upserted.
+	Chunks int64
+	// Retries counts the failed attempts that were retried.
+	Retries int64
+}
+
+// Ingester consumes documents from a channel and runs them through a
+// chunk, embed and upsert pipeline, for long-running ingestion daemons fed
+// by a queue. Failed documents are retried with the backoff of Retry, then
+// reported to OnDocument; they don't stop the ingestion.
+type Ingester struct {
+	Client         ClientInterface
+	EmbeddingModel string
+	Index          *VectorizeIndex
+
+	// ChunkTokens and ChunkOverlap size the chunks, in estimated tokens.
+	// Default to DefaultChunkTokens and DefaultChunkOverlap; a negative
+	// ChunkOverlap disables the overlap.
+	ChunkTokens  int
+	ChunkOverlap int
+	// BatchSize caps the chunks per embedding request and upsert. Defaults
+	// to DefaultIngestBatchSize.
+	BatchSize int
+	// Workers caps the documents processed at once. Defaults to
+	// DefaultIngestWorkers.
+	Workers int
+	// Retry is the retry policy of a document. Errors the retry subsystem
+	// of the client wouldn't retry, like invalid requests, fail at once.
+	Retry RetryPolicy
+
+	// OnDocument, if set, is called after every document with the number
+	// of chunks upserted, or the error it was given up with. Calls may be
+	// concurrent; panics are recovered and ignored.
+	OnDocument func(doc Document, chunks int, err error)
+
+	documents atomic.Int64
+	failed    atomic.Int64
+	chunks    atomic.Int64
+	retries   atomic.Int64
+}
+
+// NewIngester returns an ingester embedding with embeddingModel through
+// client into index.
+func NewIngester(client ClientInterface, embeddingModel string, index *VectorizeIndex) *Ingester {
+	return &Ingester{Client: client, EmbeddingModel: embeddingModel, Index: index}
+}
+
+// Run ingests the documents received from docs until it is closed and
+// drained, or ctx is done, and returns ctx.Err() in the latter case.
+// Documents in progress when ctx is done are abandoned.
+func (g *Ingester) Run(ctx context.Context, docs <-chan Document) error {
+	workers := g.Workers
+	if workers <= 0 {
+		workers = DefaultIngestWorkers
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case doc, ok := <-docs:
+					if !ok {
+						return
+					}
+					g.Ingest(ctx, doc)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Ingest runs doc through the pipeline, with retries, and returns the
+// number of chunks upserted.
+func (g *Ingester) Ingest(ctx context.Context, doc Document) (int, error) {
+	var chunks int
+	var err error
+	for attempt := 0; ; attempt++ {
+		chunks, err = g.safeIngest(ctx, doc)
+		if err == nil || attempt >= g.Retry.MaxRetries || !IsRetryable(err) {
+			break
+		}
+		g.retries.Add(1)
+		timer := time.NewTimer(g.Retry.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, g.done(doc, 0, ctx.Err())
+		case <-timer.C:
+		}
+	}
+	return chunks, g.done(doc, chunks, err)
+}
+
+// done counts the outcome of doc and reports it to OnDocument.
+func (g *Ingester) done(doc Document, chunks int, err error) error {
+	if err != nil {
+		err = fmt.Errorf("failed to ingest document %s: %w", doc.ID, err)
+		g.failed.Add(1)
+	} else {
+		g.documents.Add(1)
+		g.chunks.Add(int64(chunks))
+	}
+	if g.OnDocument != nil {
+		// A panic of the callback mustn't stop the worker.
+		var panicErr error
+		func() {
+			defer recoverPanic(&panicErr)
+			g.OnDocument(doc, chunks, err)
+		}()
+	}
+	return err
+}
+
+// safeIngest is ingest returning a panic of the pipeline as its error.
+func (g *Ingester) safeIngest(ctx context.Context, doc Document) (chunks int, err error) {
+	defer recoverPanic(&err)
+	return g.ingest(ctx, doc)
+}
+
+// ingest chunks, embeds and upserts doc once.
+func (g *Ingester) ingest(ctx context.Context, doc Document) (int, error) {
+	size, overlap := g.ChunkTokens, g.ChunkOverlap
+	if size <= 0 {
+		size = DefaultChunkTokens
+	}
+	if overlap == 0 {
+		overlap = DefaultChunkOverlap
+	}
+	batchSize := g.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultIngestBatchSize
+	}
+
+	chunks := ChunkText(doc.Text, size, overlap)
+	for start := 0; start < len(chunks); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		batch := chunks[start:min(start+batchSize, len(chunks))]
+		embeddings, err := g.Client.EmbedContext(ctx, g.EmbeddingModel, batch, nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		if len(embeddings.Data) != len(batch) {
+			return 0, fmt.Errorf("got %d embeddings for %d chunks", len(embeddings.Data), len(batch))
+		}
+
+		vectors := make([]Vector, len(batch))
+		for i, text := range batch {
+			n := start + i
+			metadata := make(map[string]interface{}, len(doc.Metadata)+3)
+			for k, v := range doc.Metadata {
+				metadata[k] = v
+			}
+			metadata["document"] = doc.ID
+			metadata["chunk"] = n
+			metadata["text"] = text
+			vectors[i] = Vector{
+				ID:        fmt.Sprintf("%s#%d", doc.ID, n),
+				Values:    embeddings.Data[i],
+				Metadata:  metadata,
+				Namespace: doc.Namespace,
+			}
+		}
+		if _, err := g.Index.Upsert(ctx, vectors); err != nil {
+			return 0, err
+		}
+	}
+	if err := g.deleteStale(ctx, doc.ID, len(chunks), batchSize); err != nil {
+		return 0, err
+	}
+	return len(chunks), nil
+}
+
+// deleteStale deletes the chunks of a previous ingestion of the document
+// id numbered from n on, left over when the document got shorter. Chunks
+// are numbered without gaps, so they are looked up batchSize at a time
+// until a batch isn't full.
+func (g *Ingester) deleteStale(ctx context.Context, id string, n, batchSize int) error {
+	for {
+		ids := make([]string, batchSize)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("%s#%d", id, n+i)
+		}
+		stale, err := g.Index.GetByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		found := make([]string, len(stale))
+		for i, v := range stale {
+			found[i] = v.ID
+		}
+		if _, err := g.Index.DeleteByIDs(ctx, found); err != nil {
+			return err
+		}
+		if len(stale) < batchSize {
+			return nil
+		}
+		n += batchSize
+	}
+}
+
+// Stats returns the counters of the ingester since it was created.
+func (g *Ingester) Stats() IngestStats {
+	return IngestStats{
+		Documents: g.documents.Load(),
+		Failed:    g.failed.Load(),
+		Chunks:    g.chunks.Load(),
+		Retries:   g.retries.Load(),
+	}
+}
+
+// ChunkText splits text into chunks of about maxTokens estimated tokens,
+// cut between words, each starting with the last overlap tokens of the
+// previous one.
+func ChunkText(text string, maxTokens, overlap int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+	overlap = min(overlap, maxTokens/2)
+
+	var chunks []string
+	start := 0
+	for start < len(words) {
+		end, tokens := start, 0
+		for end < len(words) {
+			t := EstimateTokens(words[end] + " ")
+			if tokens+t > maxTokens && end > start {
+				break
+			}
+			tokens += t
+			end++
+		}
+		chunks = append(chunks, strings.Join(words[start:end], " "))
+		if end == len(words) {
+			break
+		}
+
+		// Step back over the words making up the overlap, always moving
+		// forward.
+		next, kept := end, 0
+		for next > start+1 && kept+EstimateTokens(words[next-1]+" ") <= overlap {
+			kept += EstimateTokens(words[next-1] + " ")
+			next--
+		}
+		start = next
+	}
+	return chunks
+}
@@ -0,0 +1,162 @@
+package workersai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkText(t *testing.T) {
+	assert.Nil(t, ChunkText("  ", 10, 2))
+	assert.Equal(t, []string{"one two"}, ChunkText("one two", 10, 2))
+
+	// Every word is one estimated token with its separator.
+	text := "aaa bbb ccc ddd eee fff ggg"
+	assert.Equal(t, []string{"aaa bbb ccc", "ccc ddd eee", "eee fff ggg"}, ChunkText(text, 3, 1))
+	assert.Equal(t, []string{"aaa bbb ccc", "ddd eee fff", "ggg"}, ChunkText(text, 3, 0))
+
+	// Words longer than a chunk still make progress.
+	assert.Equal(t, []string{strings.Repeat("x", 40), "y"}, ChunkText(strings.Repeat("x", 40)+" y", 4, 2))
+}
+
+func TestVectorizeIndex_Upsert(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/accounts/test-account/vectorize/v2/indexes/docs/upsert", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+
+		var ids []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var v Vector
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &v))
+			ids = append(ids, v.ID)
+		}
+		assert.Equal(t, []string{"a", "b"}, ids)
+		fmt.Fprint(w, `{"success": true, "result": {"mutationId": "m-1"}}`)
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	mutation, err := client.VectorizeIndex("docs").Upsert(context.Background(), []Vector{
+		{ID: "a", Values: []float32{1, 0}},
+		{ID: "b", Values: []float32{0, 1}, Metadata: map[string]interface{}{"k": "v"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "m-1", mutation)
+}
+
+func TestIngester(t *testing.T) {
+	var mu sync.Mutex
+	var upserted []Vector
+	var deleted []string
+	stored := map[string]bool{}
+	embedCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/upsert"):
+			scanner := bufio.NewScanner(r.Body)
+			for scanner.Scan() {
+				var v Vector
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &v))
+				upserted = append(upserted, v)
+				stored[v.ID] = true
+			}
+			fmt.Fprint(w, `{"success": true, "result": {"mutationId": "m"}}`)
+		case strings.HasSuffix(r.URL.Path, "/get_by_ids"), strings.HasSuffix(r.URL.Path, "/delete_by_ids"):
+			var req struct {
+				IDs []string `json:"ids"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if strings.HasSuffix(r.URL.Path, "/delete_by_ids") {
+				for _, id := range req.IDs {
+					delete(stored, id)
+				}
+				deleted = append(deleted, req.IDs...)
+				fmt.Fprint(w, `{"success": true, "result": {"mutationId": "m"}}`)
+				return
+			}
+			var found []Vector
+			for _, id := range req.IDs {
+				if stored[id] {
+					found = append(found, Vector{ID: id, Values: []float32{0, 1}})
+				}
+			}
+			encoded, _ := json.Marshal(found)
+			fmt.Fprintf(w, `{"success": true, "result": %s}`, encoded)
+		case strings.Contains(r.URL.Path, "/ai/run/"):
+			embedCalls++
+			var req struct {
+				Text []string `json:"text"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if strings.Contains(req.Text[0], "flaky") && embedCalls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, `{"success": false}`)
+				return
+			}
+			if strings.Contains(req.Text[0], "invalid") {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"success": false}`)
+				return
+			}
+			data := make([][]float32, len(req.Text))
+			for i := range data {
+				data[i] = []float32{float32(i), 1}
+			}
+			encoded, _ := json.Marshal(data)
+			fmt.Fprintf(w, `{"success": true, "result": {"shape": [%d, 2], "data": %s}}`, len(data), encoded)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	ingester := NewIngester(client, ModelBAAI, client.VectorizeIndex("docs"))
+	ingester.ChunkTokens = 3
+	ingester.ChunkOverlap = -1
+	ingester.Workers = 1
+	ingester.Retry = RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond}
+	var failures []string
+	ingester.OnDocument = func(doc Document, chunks int, err error) {
+		if err != nil {
+			failures = append(failures, doc.ID)
+		}
+	}
+
+	docs := make(chan Document, 2)
+	docs <- Document{ID: "doc1", Text: "flaky bbb ccc ddd", Metadata: map[string]interface{}{"source": "wiki"}, Namespace: "acme"}
+	docs <- Document{ID: "doc2", Text: "invalid text"}
+	close(docs)
+	require.NoError(t, ingester.Run(context.Background(), docs))
+
+	assert.Equal(t, IngestStats{Documents: 1, Failed: 1, Chunks: 2, Retries: 1}, ingester.Stats())
+	assert.Equal(t, []string{"doc2"}, failures)
+	require.Len(t, upserted, 2)
+	assert.Equal(t, "doc1#1", upserted[1].ID)
+	assert.Equal(t, "acme", upserted[1].Namespace)
+	assert.Equal(t, map[string]interface{}{"source": "wiki", "document": "doc1", "chunk": 1.0, "text": "ccc ddd"}, upserted[1].Metadata)
+	assert.Empty(t, deleted)
+
+	// Ingesting a shorter version deletes the chunks left over.
+	stored["doc1#2"] = true
+	chunks, err := ingester.Ingest(context.Background(), Document{ID: "doc1", Text: "aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, chunks)
+	assert.Equal(t, []string{"doc1#1", "doc1#2"}, deleted)
+	assert.Equal(t, map[string]bool{"doc1#0": true}, stored)
+}
@@ -0,0 +1,27 @@
+package workersai
+
+import "context"
+
+// ClientInterface is the set of operations offered by Client. Code that
+// depends on ClientInterface rather than *Client can be unit tested with the
+// mock in the workersaimock package instead of an HTTP test server.
+type ClientInterface interface {
+	Chat(modelID string, messages []Message, modelParams *ModelParameters) (*ChatResponse, error)
+	ChatWithTools(modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) (*ChatResponse, error)
+	ChatCompletion(request ChatCompletionRequest) (*ChatResponse, error)
+	ChatCompletionContext(ctx context.Context, request ChatCompletionRequest) (*ChatResponse, error)
+	ListModels() ([]ModelInfo, error)
+	GetModelInfo(modelID string) (*ModelInfo, error)
+	Translate(modelID, text string, opts TranslationOptions) (string, error)
+	TranslateBatch(modelID string, texts []string, opts TranslationOptions) ([]string, error)
+	Transcribe(modelID string, audio []byte) (*TranscriptionResult, error)
+	TextToSpeech(modelID, text string, opts *SpeechOptions) ([]byte, error)
+	CaptionImage(modelID string, image []byte, opts *ImageToTextOptions) (string, error)
+	ExtractText(modelID string, image []byte, preset OCRPreset) (string, error)
+	GenerateImage(modelID, prompt string, opts *ImageOptions) (*ImageResult, error)
+	Embed(modelID string, texts []string, opts *EmbeddingOptions) (*EmbeddingResult, error)
+	EmbedContext(ctx context.Context, modelID string, texts []string, opts *EmbeddingOptions) (*EmbeddingResult, error)
+	Ping(ctx context.Context) (*HealthReport, error)
+}
+
+var _ ClientInterface = (*Client)(nil)
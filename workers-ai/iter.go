@@ -0,0 +1,108 @@
+//go:build go1.23
+
+package workersai
+
+import (
+	"context"
+	"io"
+	"iter"
+)
+
+// Chunks returns an iterator over the chunks of the stream, for
+//
+//	for chunk, err := range stream.Chunks() {
+//
+// It ends after the last chunk, or after yielding the error interrupting
+// the stream. Breaking out of the loop doesn't close the stream.
+func (s *ChatStream) Chunks() iter.Seq2[*StreamChunk, error] {
+	return recvSeq(s.Recv)
+}
+
+// Chunks returns an iterator over the chunks of the branch, like
+// ChatStream.Chunks.
+func (b *TeeStream) Chunks() iter.Seq2[*StreamChunk, error] {
+	return recvSeq(b.Recv)
+}
+
+// recvSeq returns an iterator over the chunks returned by recv until
+// io.EOF.
+func recvSeq(recv func() (*StreamChunk, error)) iter.Seq2[*StreamChunk, error] {
+	return func(yield func(*StreamChunk, error) bool) {
+		for {
+			chunk, err := recv()
+			if err == io.EOF {
+				return
+			}
+			if !yield(chunk, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Models returns an iterator over the models of the catalog matching
+// search, fetching the pages as needed from search.Page on. It ends after
+// yielding an error.
+func (c *Client) Models(ctx context.Context, search ModelSearch) iter.Seq2[ModelSummary, error] {
+	return func(yield func(ModelSummary, error) bool) {
+		for {
+			page, err := c.SearchModels(ctx, search)
+			if err != nil {
+				yield(ModelSummary{}, err)
+				return
+			}
+			for _, model := range page.Models {
+				if !yield(model, nil) {
+					return
+				}
+			}
+			if !page.More() {
+				return
+			}
+			search.Page = page.Page + 1
+		}
+	}
+}
+
+// All returns an iterator over the items of the batch, in input order,
+// with their index.
+func (r *BatchResult[T]) All() iter.Seq2[int, BatchItem[T]] {
+	return func(yield func(int, BatchItem[T]) bool) {
+		for i, item := range r.Items {
+			if !yield(i, item) {
+				return
+			}
+		}
+	}
+}
+
+// ChatBatchSeq is ChatBatch returning an iterator over the items as they
+// complete, in completion order. Breaking out of the loop cancels the
+// requests not completed yet.
+func (c *Client) ChatBatchSeq(ctx context.Context, requests []ChatCompletionRequest, workers int) iter.Seq[BatchItem[*ChatResponse]] {
+	return func(yield func(BatchItem[*ChatResponse]) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		items := make(chan BatchItem[*ChatResponse])
+		go func() {
+			defer close(items)
+			c.ChatBatchEach(ctx, requests, workers, func(item BatchItem[*ChatResponse]) {
+				select {
+				case items <- item:
+				case <-ctx.Done():
+				}
+			})
+		}()
+
+		for item := range items {
+			if !yield(item) {
+				cancel()
+				// Let the batch wind down without blocking on items.
+				for range items {
+				}
+				return
+			}
+		}
+	}
+}
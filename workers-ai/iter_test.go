@@ -0,0 +1,121 @@
+//go:build go1.23
+
+package workersai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatStream_Chunks(t *testing.T) {
+	server := newStreamServer(t,
+		`{"response": "Hello"}`,
+		`{"response": " world"}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var pieces []string
+	for chunk, err := range stream.Chunks() {
+		require.NoError(t, err)
+		pieces = append(pieces, chunk.Content)
+	}
+	assert.Equal(t, []string{"Hello", " world"}, pieces)
+}
+
+func TestChatStream_Chunks_Error(t *testing.T) {
+	server := newStreamServer(t, `{"response": "Hello"}`)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var errs []error
+	for _, err := range stream.Chunks() {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "unexpected EOF")
+}
+
+func TestClient_Models(t *testing.T) {
+	server := newModelSearchServer(t, 5)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var names []string
+	for model, err := range client.Models(context.Background(), ModelSearch{Task: "Text Generation", PerPage: 2}) {
+		require.NoError(t, err)
+		names = append(names, model.Name)
+	}
+	assert.Equal(t, []string{"model-1", "model-2", "model-3", "model-4", "model-5"}, names)
+
+	names = nil
+	for model := range client.Models(context.Background(), ModelSearch{Task: "Text Generation", PerPage: 2}) {
+		names = append(names, model.Name)
+		if len(names) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"model-1", "model-2", "model-3"}, names)
+
+	client.BaseURL = "http://127.0.0.1:0"
+	for _, err := range client.Models(context.Background(), ModelSearch{}) {
+		assert.Error(t, err)
+	}
+}
+
+func TestBatch_Iterators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": true, "result": {"response": "ok", "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}}`))
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	requests := make([]ChatCompletionRequest, 5)
+	for i := range requests {
+		requests[i] = ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}}}
+	}
+
+	result := client.ChatBatch(context.Background(), requests, 2)
+	var indexes []int
+	for i, item := range result.All() {
+		assert.Equal(t, i, item.Index)
+		indexes = append(indexes, i)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, indexes)
+
+	seen := make(map[int]bool)
+	for item := range client.ChatBatchSeq(context.Background(), requests, 2) {
+		require.NoError(t, item.Err)
+		seen[item.Index] = true
+	}
+	assert.Len(t, seen, 5)
+
+	count := 0
+	for item := range client.ChatBatchSeq(context.Background(), requests, 1) {
+		assert.False(t, errors.Is(item.Err, context.Canceled))
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+}
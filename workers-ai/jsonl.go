@@ -0,0 +1,84 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// JSONLWriter writes records to an io.Writer as JSON Lines, one JSON
+// value per line, as they are produced, so that batch jobs can be piped
+// into downstream tools without buffering their whole output. It is safe
+// for concurrent use.
+type JSONLWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	count int
+	err   error
+}
+
+// NewJSONLWriter returns a writer of JSON Lines to w. Writers with a
+// Flush method, like a bufio.Writer or an http.ResponseWriter, are flushed
+// after every record.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{w: w}
+}
+
+// Write writes record as one line. After a failed write, Write does
+// nothing and returns the error of that write.
+func (w *JSONLWriter) Write(record interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+
+	// Encode to a buffer first, so that a record failing to encode doesn't
+	// leave half a line behind.
+	var line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(record); err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	if _, err := w.w.Write(line.Bytes()); err != nil {
+		w.err = fmt.Errorf("failed to write record: %w", err)
+		return w.err
+	}
+	switch f := w.w.(type) {
+	case interface{ Flush() error }:
+		if err := f.Flush(); err != nil {
+			w.err = fmt.Errorf("failed to write record: %w", err)
+			return w.err
+		}
+	case http.Flusher:
+		f.Flush()
+	}
+	w.count++
+	return nil
+}
+
+// Count returns the number of records written.
+func (w *JSONLWriter) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Err returns the error that stopped the writer, if any.
+func (w *JSONLWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// JSONLItems returns a callback for ChatBatchEach writing every item to w.
+// Write errors are kept by w, see Err.
+func JSONLItems[T any](w *JSONLWriter) func(BatchItem[T]) {
+	return func(item BatchItem[T]) {
+		w.Write(item)
+	}
+}
@@ -0,0 +1,92 @@
+package workersai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestJSONLWriter(t *testing.T) {
+	var out bytes.Buffer
+	buffered := bufio.NewWriter(&out)
+	w := NewJSONLWriter(buffered)
+
+	require.NoError(t, w.Write(map[string]string{"html": "<b>"}))
+	// Flushed after every record.
+	assert.Equal(t, "{\"html\":\"<b>\"}\n", out.String())
+
+	assert.Error(t, w.Write(func() {}))
+	assert.NoError(t, w.Err(), "encoding errors don't stop the writer")
+	assert.Equal(t, 1, w.Count())
+
+	broken := NewJSONLWriter(failingWriter{})
+	assert.ErrorContains(t, broken.Write(1), "broken pipe")
+	assert.ErrorContains(t, broken.Err(), "broken pipe")
+	assert.Equal(t, 0, broken.Count())
+}
+
+func TestClient_ChatBatchEach_JSONL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		content := req.Messages[0].(ChatMessage).Content
+		if content == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"success": false}`)
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q}}`, "re: "+content)
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var requests []ChatCompletionRequest
+	for _, content := range []string{"one", "bad"} {
+		requests = append(requests, ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: content}}})
+	}
+	var out bytes.Buffer
+	w := NewJSONLWriter(&out)
+	result := client.ChatBatchEach(context.Background(), requests, 1, JSONLItems[*ChatResponse](w))
+	require.NoError(t, w.Write(result.Summary))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+
+	var ok, failed struct {
+		Index      int           `json:"index"`
+		Status     string        `json:"status"`
+		Result     *ChatResponse `json:"result"`
+		Error      string        `json:"error"`
+		ErrorType  string        `json:"error_type"`
+		RetryToken string        `json:"retry_token"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &ok))
+	assert.Equal(t, BatchSucceeded, ok.Status)
+	assert.Equal(t, "re: one", ok.Result.GetContent())
+	assert.Empty(t, ok.Error)
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &failed))
+	assert.Equal(t, 1, failed.Index)
+	assert.Equal(t, BatchFailed, failed.Status)
+	assert.Nil(t, failed.Result)
+	assert.Equal(t, ErrorTypeInvalidRequest, failed.ErrorType)
+	assert.NotEmpty(t, failed.Error)
+	assert.NotEmpty(t, failed.RetryToken)
+
+	assert.JSONEq(t, `{"total": 2, "succeeded": 1, "failed": 1, "usage": {"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0}}`, lines[2])
+}
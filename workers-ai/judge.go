@@ -0,0 +1,159 @@
+package workersai
+
+import "fmt"
+
+// DefaultGradeScale is the highest score of Judge.Grade when Scale is
+// zero.
+const DefaultGradeScale = 5
+
+// Winners of Judge.PairwiseCompare.
+const (
+	WinnerA   = "A"
+	WinnerB   = "B"
+	WinnerTie = "tie"
+)
+
+// GradeResult is the verdict of Judge.Grade.
+type GradeResult struct {
+	// Score ranges from 1, for an answer failing the rubric, to the scale
+	// of the judge.
+	Score     int
+	Reasoning string
+}
+
+// PairwiseResult is the verdict of Judge.PairwiseCompare.
+type PairwiseResult struct {
+	// Winner is WinnerA, WinnerB or WinnerTie.
+	Winner    string
+	Reasoning string
+}
+
+// Grade scores answer against rubric, for evaluations and quality
+// monitoring. The judge replies in JSON with its reasoning, constrained by
+// a schema when the model supports JSON mode and re-prompted when the reply
+// is invalid.
+func (j *Judge) Grade(answer, rubric string) (*GradeResult, error) {
+	scale := j.Scale
+	if scale <= 0 {
+		scale = DefaultGradeScale
+	}
+
+	schema := &FunctionParameters{
+		Type: "object",
+		Properties: map[string]*Parameter{
+			"reasoning": {Type: "string"},
+			"score":     {Type: "integer", Minimum: 1, Maximum: scale},
+		},
+		Required: []string{"reasoning", "score"},
+	}
+	system := fmt.Sprintf("You grade answers against a rubric with a score from 1 (fails the rubric) to %d (fully meets it). "+
+		`Reply with a JSON object {"reasoning": "...", "score": n}, explaining your grade briefly before giving it.`, scale)
+	prompt := "Rubric:\n" + rubric + "\n\nAnswer:\n" + answer
+	if j.Question != "" {
+		prompt = "Question:\n" + j.Question + "\n\n" + prompt
+	}
+
+	type gradeVerdict struct {
+		Reasoning string `json:"reasoning"`
+		Score     int    `json:"score"`
+	}
+	var verdict gradeVerdict
+	err := j.chatJSON(system, prompt, schema, &verdict, func(v interface{}) error {
+		if score := v.(*gradeVerdict).Score; score < 1 || score > scale {
+			return fmt.Errorf("score must be between 1 and %d", scale)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("judge failed to grade answer: %w", err)
+	}
+	return &GradeResult{Score: verdict.Score, Reasoning: verdict.Reasoning}, nil
+}
+
+// PairwiseCompare asks the judge which of the answers a and b better meets
+// criteria, or the judge's Criteria when empty. With Debias, both orders
+// of the answers are judged.
+func (j *Judge) PairwiseCompare(a, b, criteria string) (*PairwiseResult, error) {
+	if criteria == "" {
+		criteria = j.Criteria
+	}
+	if criteria == "" {
+		criteria = DefaultJudgeCriteria
+	}
+
+	result, err := j.compare(a, b, criteria)
+	if err != nil || !j.Debias {
+		return result, err
+	}
+
+	swapped, err := j.compare(b, a, criteria)
+	if err != nil {
+		return nil, err
+	}
+	switch swapped.Winner {
+	case WinnerA:
+		swapped.Winner = WinnerB
+	case WinnerB:
+		swapped.Winner = WinnerA
+	}
+	if swapped.Winner != result.Winner {
+		result.Winner = WinnerTie
+		result.Reasoning += "\n\nWith the answers swapped: " + swapped.Reasoning
+	}
+	return result, nil
+}
+
+// compare judges a and b once, in this order.
+func (j *Judge) compare(a, b, criteria string) (*PairwiseResult, error) {
+	schema := &FunctionParameters{
+		Type: "object",
+		Properties: map[string]*Parameter{
+			"reasoning": {Type: "string"},
+			"winner":    {Type: "string", Enum: []string{WinnerA, WinnerB, WinnerTie}},
+		},
+		Required: []string{"reasoning", "winner"},
+	}
+	system := fmt.Sprintf("You compare two answers on %s, ignoring their order and length. ", criteria) +
+		`Reply with a JSON object {"reasoning": "...", "winner": "A" | "B" | "tie"}, explaining your verdict briefly before giving it.`
+	prompt := "Answer A:\n" + a + "\n\nAnswer B:\n" + b
+	if j.Question != "" {
+		prompt = "Question:\n" + j.Question + "\n\n" + prompt
+	}
+
+	var verdict struct {
+		Reasoning string `json:"reasoning"`
+		Winner    string `json:"winner"`
+	}
+	if err := j.chatJSON(system, prompt, schema, &verdict, nil); err != nil {
+		return nil, fmt.Errorf("judge failed to compare answers: %w", err)
+	}
+	return &PairwiseResult{Winner: verdict.Winner, Reasoning: verdict.Reasoning}, nil
+}
+
+// chatJSON asks the judge model for a reply matching schema, in JSON mode
+// if the model supports it, and tracks its usage.
+func (j *Judge) chatJSON(system, prompt string, schema *FunctionParameters, out interface{}, validate func(interface{}) error) error {
+	request := ChatCompletionRequest{
+		Model: j.Model,
+		Messages: []Message{
+			ChatMessage{Role: "system", Content: system},
+			ChatMessage{Role: "user", Content: prompt},
+		},
+	}
+	if supportsJSONMode(j.Model) {
+		request.ResponseFormat = &OutputFormat{Type: "json_schema", JSONSchema: schema}
+	}
+
+	resp, err := chatJSON(j.Client, clientLogf(j.Client), request, out, StructuredOptions{Schema: schema, Validate: validate})
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	usage := resp.GetUsage()
+	j.usage.PromptTokens += usage.PromptTokens
+	j.usage.CompletionTokens += usage.CompletionTokens
+	j.usage.TotalTokens += usage.TotalTokens
+	j.mu.Unlock()
+	return nil
+}
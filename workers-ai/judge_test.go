@@ -0,0 +1,98 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// judgeServer answers chat requests with replies in order and records
+// them.
+func judgeServer(t *testing.T, replies []string, requests *[]ChatCompletionRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		*requests = append(*requests, request)
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q, "usage": {"total_tokens": 10}}}`, replies[len(*requests)-1])
+	}))
+}
+
+func TestJudge_Grade(t *testing.T) {
+	var requests []ChatCompletionRequest
+	server := judgeServer(t, []string{
+		`{"reasoning": "Great", "score": 9}`,
+		"```json\n{\"reasoning\": \"Correct but terse.\", \"score\": 4}\n```",
+	}, &requests)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	judge := &Judge{Client: client, Model: ModelMistral7B, Question: "Capital of France?"}
+	grade, err := judge.Grade("Paris", "The answer must be correct.")
+	require.NoError(t, err)
+	assert.Equal(t, 4, grade.Score)
+	assert.Equal(t, "Correct but terse.", grade.Reasoning)
+	assert.Equal(t, 20, judge.Usage().TotalTokens)
+
+	// The first reply was out of the scale.
+	require.Len(t, requests, 2)
+	assert.Nil(t, requests[0].ResponseFormat)
+	assert.Contains(t, requests[0].Messages[0].(ChatMessage).Content, "from 1 (fails the rubric) to 5")
+	assert.Equal(t, "Question:\nCapital of France?\n\nRubric:\nThe answer must be correct.\n\nAnswer:\nParis", requests[0].Messages[1].(ChatMessage).Content)
+	assert.Contains(t, requests[1].Messages[3].(ChatMessage).Content, "score must be between 1 and 5")
+}
+
+func TestJudge_PairwiseCompare(t *testing.T) {
+	var requests []ChatCompletionRequest
+	server := judgeServer(t, []string{
+		`{"reasoning": "A is precise.", "winner": "A"}`,
+		`{"reasoning": "B is precise.", "winner": "B"}`,
+		`{"reasoning": "The first is better.", "winner": "A"}`,
+	}, &requests)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	judge := &Judge{Client: client, Model: ModelLlama38B, Debias: true}
+
+	// Both orders agree on "Paris".
+	result, err := judge.PairwiseCompare("Paris", "A city in France", "Correctness")
+	require.NoError(t, err)
+	assert.Equal(t, WinnerA, result.Winner)
+	require.Len(t, requests, 2)
+	assert.NotNil(t, requests[0].ResponseFormat)
+	assert.Contains(t, requests[0].Messages[0].(ChatMessage).Content, "on Correctness, ignoring")
+	assert.Equal(t, "Answer A:\nA city in France\n\nAnswer B:\nParis", requests[1].Messages[1].(ChatMessage).Content)
+
+	// The judge picks the first position whatever it holds.
+	judge.Debias = false
+	result, err = judge.PairwiseCompare("Lyon", "Paris", "Correctness")
+	require.NoError(t, err)
+	assert.Equal(t, WinnerA, result.Winner)
+	assert.Len(t, requests, 3)
+	assert.Equal(t, 30, judge.Usage().TotalTokens)
+}
+
+func TestJudge_PairwiseCompare_Tie(t *testing.T) {
+	var requests []ChatCompletionRequest
+	server := judgeServer(t, []string{
+		`{"reasoning": "The first is better.", "winner": "A"}`,
+		`{"reasoning": "The first is better.", "winner": "A"}`,
+	}, &requests)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	judge := &Judge{Client: client, Model: ModelMistral7B, Debias: true}
+
+	result, err := judge.PairwiseCompare("Lyon", "Paris", "Correctness")
+	require.NoError(t, err)
+	assert.Equal(t, WinnerTie, result.Winner)
+	assert.Contains(t, result.Reasoning, "With the answers swapped")
+}
@@ -0,0 +1,193 @@
+package workersai
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MinKVTTL is the shortest expiration Workers KV accepts.
+const MinKVTTL = 60 * time.Second
+
+// KVNamespace reads and writes the values of a Workers KV namespace through
+// the Cloudflare API, so that state lives in Cloudflare alongside the AI
+// calls. The API token needs the Workers KV Storage permission.
+type KVNamespace struct {
+	Client *Client
+	// ID is the ID of the namespace, not its title.
+	ID string
+}
+
+// KVNamespace returns the KV namespace with the ID namespaceID, of the
+// client's account.
+func (c *Client) KVNamespace(namespaceID string) *KVNamespace {
+	return &KVNamespace{Client: c, ID: namespaceID}
+}
+
+func (n *KVNamespace) valuePath(key string, query url.Values) string {
+	path := fmt.Sprintf("/accounts/%s/storage/kv/namespaces/%s/values/%s", n.Client.AccountID, url.PathEscape(n.ID), url.PathEscape(key))
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// Get returns the value stored under key. ok is false when there is none.
+func (n *KVNamespace) Get(ctx context.Context, key string) (value []byte, ok bool, err error) {
+	value, status, err := n.Client.apiRaw(ctx, http.MethodGet, n.valuePath(key, nil), "", nil)
+	if status == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read KV key %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// Put stores value under key. A ttl of zero keeps the value until it is
+// overwritten or deleted; shorter ttls than MinKVTTL are raised to it.
+func (n *KVNamespace) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	query := url.Values{}
+	if ttl > 0 {
+		query.Set("expiration_ttl", strconv.Itoa(int(max(ttl, MinKVTTL).Seconds())))
+	}
+	if _, _, err := n.Client.apiRaw(ctx, http.MethodPut, n.valuePath(key, query), "application/octet-stream", value); err != nil {
+		return fmt.Errorf("failed to write KV key %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the value stored under key, if any.
+func (n *KVNamespace) Delete(ctx context.Context, key string) error {
+	if _, _, err := n.Client.apiRaw(ctx, http.MethodDelete, n.valuePath(key, nil), "", nil); err != nil {
+		return fmt.Errorf("failed to delete KV key %s: %w", key, err)
+	}
+	return nil
+}
+
+// KVEmbeddingStore is an EmbeddingStore keeping the embeddings in Workers
+// KV, to share an EmbeddingCache between processes and runs. Vectors are
+// stored as little-endian float32s.
+type KVEmbeddingStore struct {
+	Namespace *KVNamespace
+	// Prefix is prepended to the keys, to share a namespace with other
+	// data.
+	Prefix string
+	// TTL expires the embeddings. Zero keeps them forever.
+	TTL time.Duration
+}
+
+// NewKVEmbeddingStore returns a store of embeddings in namespace.
+func NewKVEmbeddingStore(namespace *KVNamespace) *KVEmbeddingStore {
+	return &KVEmbeddingStore{Namespace: namespace}
+}
+
+func (s *KVEmbeddingStore) Get(key string) ([]float32, bool, error) {
+	data, ok, err := s.Namespace.Get(context.Background(), s.Prefix+key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if len(data)%4 != 0 {
+		return nil, false, fmt.Errorf("embedding %s has an invalid length of %d bytes", key, len(data))
+	}
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector, true, nil
+}
+
+func (s *KVEmbeddingStore) Set(key string, vector []float32) error {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return s.Namespace.Put(context.Background(), s.Prefix+key, data, s.TTL)
+}
+
+// MemoryStore persists the history of conversations between processes,
+// keyed by conversation ID. Implementations must be safe for concurrent
+// use.
+type MemoryStore interface {
+	// Load returns the messages stored under id, if any.
+	Load(id string) (messages []Message, ok bool, err error)
+	// Save replaces the messages stored under id.
+	Save(id string, messages []Message) error
+}
+
+// ErrNoMemory is returned by ChatSession.Restore when the store has no
+// conversation under the ID.
+var ErrNoMemory = errors.New("no stored conversation")
+
+// KVMemoryStore is a MemoryStore keeping the conversations in Workers KV, as
+// JSON arrays of messages in the OpenAI format.
+type KVMemoryStore struct {
+	Namespace *KVNamespace
+	// Prefix is prepended to the conversation IDs.
+	Prefix string
+	// TTL expires the conversations after their last save, e.g. to forget
+	// idle chats. Zero keeps them forever.
+	TTL time.Duration
+}
+
+// NewKVMemoryStore returns a store of conversations in namespace.
+func NewKVMemoryStore(namespace *KVNamespace) *KVMemoryStore {
+	return &KVMemoryStore{Namespace: namespace}
+}
+
+func (s *KVMemoryStore) Load(id string) ([]Message, bool, error) {
+	data, ok, err := s.Namespace.Get(context.Background(), s.Prefix+id)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	messages, err := decodeMessages(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("conversation %s: %w", id, err)
+	}
+	return messages, true, nil
+}
+
+func (s *KVMemoryStore) Save(id string, messages []Message) error {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation %s: %w", id, err)
+	}
+	return s.Namespace.Put(context.Background(), s.Prefix+id, data, s.TTL)
+}
+
+// decodeMessages decodes a JSON array of messages, with the concrete types
+// ChatCompletionRequest.UnmarshalJSON picks.
+func decodeMessages(data []byte) ([]Message, error) {
+	var request ChatCompletionRequest
+	if err := json.Unmarshal([]byte(`{"messages":`+string(data)+`}`), &request); err != nil {
+		return nil, fmt.Errorf("failed to decode messages: %w", err)
+	}
+	return request.Messages, nil
+}
+
+// Save stores the conversation of the session in store under id. The
+// system prompt is not stored.
+func (s *ChatSession) Save(store MemoryStore, id string) error {
+	return store.Save(id, s.Messages)
+}
+
+// Restore replaces the conversation of the session with the one stored in
+// store under id. It returns ErrNoMemory when there is none.
+func (s *ChatSession) Restore(store MemoryStore, id string) error {
+	messages, ok, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoMemory, id)
+	}
+	s.Messages = messages
+	return nil
+}
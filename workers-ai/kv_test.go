@@ -0,0 +1,133 @@
+package workersai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKV serves the value endpoints of the KV API from a map.
+type fakeKV struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	ttls   map[string]string
+}
+
+func newFakeKV(t *testing.T) (*fakeKV, *httptest.Server) {
+	kv := &fakeKV{values: make(map[string][]byte), ttls: make(map[string]string)}
+	prefix := "/accounts/test-account/storage/kv/namespaces/ns1/values/"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		require.True(t, strings.HasPrefix(r.URL.Path, prefix), r.URL.Path)
+		key := strings.TrimPrefix(r.URL.Path, prefix)
+
+		kv.mu.Lock()
+		defer kv.mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			value, ok := kv.values[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `{"success": false, "errors": [{"code": 10009, "message": "get: 'key not found'"}]}`)
+				return
+			}
+			w.Write(value)
+		case http.MethodPut:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			kv.values[key] = body
+			kv.ttls[key] = r.URL.Query().Get("expiration_ttl")
+			io.WriteString(w, `{"success": true, "result": null}`)
+		case http.MethodDelete:
+			delete(kv.values, key)
+			io.WriteString(w, `{"success": true, "result": null}`)
+		}
+	}))
+	return kv, server
+}
+
+func TestKVNamespace(t *testing.T) {
+	kv, server := newFakeKV(t)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	ns := client.KVNamespace("ns1")
+	ctx := context.Background()
+
+	_, ok, err := ns.Get(ctx, "greeting")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, ns.Put(ctx, "greeting", []byte("hello"), 10*time.Second))
+	assert.Equal(t, "60", kv.ttls["greeting"], "TTL raised to the KV minimum")
+
+	value, ok, err := ns.Get(ctx, "greeting")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "hello", string(value))
+
+	require.NoError(t, ns.Delete(ctx, "greeting"))
+	_, ok, err = ns.Get(ctx, "greeting")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestKVEmbeddingStore(t *testing.T) {
+	kv, server := newFakeKV(t)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	store := NewKVEmbeddingStore(client.KVNamespace("ns1"))
+	store.Prefix = "emb:"
+	store.TTL = time.Hour
+
+	_, ok, err := store.Get("k")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set("k", []float32{0.5, -1.25, 3}))
+	assert.Len(t, kv.values["emb:k"], 12)
+	assert.Equal(t, "3600", kv.ttls["emb:k"])
+
+	vector, ok, err := store.Get("k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []float32{0.5, -1.25, 3}, vector)
+
+	kv.values["emb:bad"] = []byte("abc")
+	_, _, err = store.Get("bad")
+	assert.Error(t, err)
+}
+
+func TestKVMemoryStore(t *testing.T) {
+	_, server := newFakeKV(t)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	store := NewKVMemoryStore(client.KVNamespace("ns1"))
+
+	session := NewChatSession(nil, "model")
+	err := session.Restore(store, "chat-1")
+	assert.ErrorIs(t, err, ErrNoMemory)
+
+	session.Messages = []Message{
+		ChatMessage{Role: "user", Content: "What's the weather in Paris?"},
+		newAssistantMessage("", []ToolCall{toolCall("call_1", "weather", `{"city":"Paris"}`)}),
+		ToolMessage{Role: "tool", Content: "Sunny", ToolCallID: "call_1"},
+		ChatMessage{Role: "assistant", Content: "It's sunny."},
+	}
+	require.NoError(t, session.Save(store, "chat-1"))
+
+	restored := NewChatSession(nil, "model")
+	require.NoError(t, restored.Restore(store, "chat-1"))
+	assert.Equal(t, session.Messages, restored.Messages)
+}
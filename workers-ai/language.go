@@ -0,0 +1,261 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultLanguageRetries is the number of times a reply in the wrong
+// language is asked again when LanguageOptions.MaxRetries is zero.
+const DefaultLanguageRetries = 1
+
+// DefaultLanguageConfidence is the confidence of the detection above which
+// a reply counts as written in the detected language.
+const DefaultLanguageConfidence = 0.6
+
+// ErrWrongLanguage is returned when a reply is still in the wrong language
+// after the retries of LanguageOptions.
+var ErrWrongLanguage = errors.New("reply in the wrong language")
+
+// LanguageOptions makes ChatCompletion answer in a given language, for
+// localized products whose users may write in another one. The language
+// is asked for in the system prompt, then the reply is checked with
+// language detection and asked again when it is in another language.
+type LanguageOptions struct {
+	// Language is the language of the reply, as an ISO 639-1 code like
+	// "fr" or "pt-BR", or an English name like "French".
+	Language string
+	// MaxRetries caps the follow-up requests made when the reply is in the
+	// wrong language. Defaults to DefaultLanguageRetries; negative disables
+	// the check.
+	MaxRetries int
+	// Detect returns the ISO 639-1 code of the language of text and the
+	// confidence of the detection, between 0 and 1. Defaults to
+	// DetectLanguage, in which case the languages it doesn't know only get
+	// the instruction.
+	Detect func(text string) (lang string, confidence float64)
+	// MinConfidence is the confidence from which a detection is trusted.
+	// Defaults to DefaultLanguageConfidence.
+	MinConfidence float64
+	// Lenient returns the last reply rather than ErrWrongLanguage when it
+	// is still in the wrong language after the retries.
+	Lenient bool
+}
+
+// languageNames are the English names of the languages DetectLanguage
+// knows, by ISO 639-1 code.
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"zh": "Chinese",
+}
+
+// stopwords are frequent short words telling apart the languages written
+// in the Latin script.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "you", "for", "with", "this", "was", "have", "not"},
+	"fr": {"le", "la", "les", "et", "est", "des", "un", "une", "du", "que", "pas", "pour", "dans", "vous", "sur", "ce", "je", "il"},
+	"es": {"el", "la", "los", "las", "y", "es", "de", "que", "en", "un", "una", "por", "para", "con", "no", "se", "del", "está"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "sich", "auf", "ich", "sie", "es", "für"},
+	"it": {"il", "la", "che", "e", "di", "è", "un", "una", "per", "non", "sono", "con", "del", "gli", "le", "della"},
+	"pt": {"o", "a", "os", "as", "e", "de", "que", "não", "um", "uma", "para", "com", "é", "do", "da", "em", "você"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "op", "te", "zijn", "met", "voor", "ik", "je"},
+}
+
+// scripts map the writing systems to the language detected for them.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// DetectLanguage guesses the language of text from its script and, for
+// the Latin script, from its most frequent words. It knows a few major
+// languages and returns an empty code when unsure, e.g. for short texts.
+// Cyrillic text is reported as Russian.
+func DetectLanguage(text string) (lang string, confidence float64) {
+	letters := 0
+	counts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[script.lang]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return "", 0
+	}
+	// Japanese mixes kanji with kana.
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	for code, n := range counts {
+		if share := float64(n) / float64(letters); share > 0.5 {
+			return code, share
+		}
+	}
+
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' }) {
+		for code, words := range stopwords {
+			for _, w := range words {
+				if w == word {
+					hits[code]++
+					break
+				}
+			}
+		}
+	}
+	best, first, second := "", 0, 0
+	for code, n := range hits {
+		switch {
+		case n > first || n == first && code < best:
+			best, first, second = code, n, max(first, second)
+		case n > second:
+			second = n
+		}
+	}
+	if first < 2 {
+		return "", 0
+	}
+	return best, float64(first) / float64(first+second)
+}
+
+// normalizeLanguage returns the ISO 639-1 code of lang, given as a code
+// with an optional region or as an English name, or lang unchanged when
+// unknown.
+func normalizeLanguage(lang string) string {
+	lower := strings.ToLower(strings.TrimSpace(lang))
+	if code, _, ok := strings.Cut(strings.ReplaceAll(lower, "_", "-"), "-"); ok && len(code) == 2 {
+		return code
+	}
+	for code, name := range languageNames {
+		if strings.ToLower(name) == lower {
+			return code
+		}
+	}
+	return lower
+}
+
+// languageName returns the name of lang to use in prompts.
+func languageName(lang string) string {
+	if name, ok := languageNames[normalizeLanguage(lang)]; ok {
+		return name
+	}
+	return lang
+}
+
+// withLanguageInstruction returns messages with a system prompt asking for
+// replies in lang.
+func withLanguageInstruction(messages []Message, lang string) []Message {
+	instruction := fmt.Sprintf("Always reply in %s, whatever the language of the user.", languageName(lang))
+	out := make([]Message, 0, len(messages)+1)
+	if len(messages) > 0 {
+		if system, ok := messages[0].(ChatMessage); ok && system.Role == "system" {
+			system.Content = strings.TrimSpace(system.Content + "\n\n" + instruction)
+			out = append(out, system)
+			return append(out, messages[1:]...)
+		}
+	}
+	out = append(out, ChatMessage{Role: "system", Content: instruction})
+	return append(out, messages...)
+}
+
+// wrongLanguage returns the language content is detected in when it isn't
+// written in the language of opts.
+func (opts *LanguageOptions) wrongLanguage(content string) (string, bool) {
+	want := normalizeLanguage(opts.Language)
+	detect := opts.Detect
+	if detect == nil {
+		if _, known := languageNames[want]; !known {
+			return "", false
+		}
+		detect = DetectLanguage
+	}
+	minConfidence := opts.MinConfidence
+	if minConfidence <= 0 {
+		minConfidence = DefaultLanguageConfidence
+	}
+
+	got, confidence := detect(content)
+	got = normalizeLanguage(got)
+	if got == "" || confidence < minConfidence || got == want {
+		return "", false
+	}
+	return got, true
+}
+
+// enforceLanguage checks the language of response and asks again, with
+// send, while it is wrong.
+func (c *Client) enforceLanguage(ctx context.Context, request ChatCompletionRequest, response *ChatResponse, send func(context.Context, ChatCompletionRequest) (*ChatResponse, error)) (*ChatResponse, error) {
+	opts := request.Language
+	retries := opts.MaxRetries
+	if retries == 0 {
+		retries = DefaultLanguageRetries
+	}
+	if retries < 0 {
+		return response, nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		if len(response.GetToolCalls()) > 0 {
+			return response, nil
+		}
+		got, wrong := opts.wrongLanguage(response.GetContent())
+		if !wrong {
+			return response, nil
+		}
+		if attempt >= retries {
+			if opts.Lenient {
+				return response, nil
+			}
+			return nil, fmt.Errorf("%w: got %s instead of %s", ErrWrongLanguage, languageName(got), languageName(opts.Language))
+		}
+		c.debugLog("Reply in %s instead of %s, asking again (%d/%d)", got, opts.Language, attempt+1, retries)
+
+		followUp := request
+		followUp.Messages = make([]Message, 0, len(request.Messages)+2)
+		followUp.Messages = append(followUp.Messages, request.Messages...)
+		followUp.Messages = append(followUp.Messages,
+			ChatMessage{Role: "assistant", Content: response.GetContent()},
+			ChatMessage{Role: "user", Content: fmt.Sprintf("Your reply was not in %s. Write it again in %s only.", languageName(opts.Language), languageName(opts.Language))},
+		)
+		var err error
+		if response, err = send(ctx, followUp); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	for text, want := range map[string]string{
+		"The weather is nice and the sun is out.":          "en",
+		"Le temps est beau et le soleil est là pour vous.": "fr",
+		"Das Wetter ist schön und die Sonne scheint.":      "de",
+		"El tiempo es bueno y el sol está en el cielo.":    "es",
+		"今日はいい天気ですね。":                                      "ja",
+		"今天天气很好。":                                          "zh",
+		"Сегодня хорошая погода.":                          "ru",
+		"오늘 날씨가 좋네요.":                                      "ko",
+	} {
+		got, confidence := DetectLanguage(text)
+		assert.Equal(t, want, got, text)
+		assert.GreaterOrEqual(t, confidence, DefaultLanguageConfidence, text)
+	}
+
+	got, _ := DetectLanguage("OK")
+	assert.Empty(t, got)
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	assert.Equal(t, "pt", normalizeLanguage("pt-BR"))
+	assert.Equal(t, "fr", normalizeLanguage("French"))
+	assert.Equal(t, "fr", normalizeLanguage("fr"))
+	assert.Equal(t, "Swahili", languageName("Swahili"))
+	assert.Equal(t, "German", languageName("de_AT"))
+}
+
+func TestClient_ChatCompletion_Language(t *testing.T) {
+	replies := []string{"The weather is nice and the sun is out.", "Le temps est beau et le soleil est là."}
+	var requests []ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+		reply := replies[min(len(requests)-1, len(replies)-1)]
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q}}`, reply)
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	request := ChatCompletionRequest{
+		Model: ModelLlama38B,
+		Messages: []Message{
+			ChatMessage{Role: "system", Content: "You are a weather bot."},
+			ChatMessage{Role: "user", Content: "How is the weather?"},
+		},
+		Language: &LanguageOptions{Language: "fr"},
+	}
+	resp, err := client.ChatCompletion(request)
+	require.NoError(t, err)
+	assert.Equal(t, replies[1], resp.GetContent())
+
+	require.Len(t, requests, 2)
+	system := requests[0].Messages[0].(ChatMessage)
+	assert.Equal(t, "You are a weather bot.\n\nAlways reply in French, whatever the language of the user.", system.Content)
+	require.Len(t, requests[1].Messages, 4)
+	assert.Equal(t, replies[0], requests[1].Messages[2].(ChatMessage).Content)
+	// The caller's messages are left untouched.
+	assert.Equal(t, "You are a weather bot.", request.Messages[0].(ChatMessage).Content)
+
+	// Still wrong after the retries.
+	requests = nil
+	request.Language = &LanguageOptions{Language: "German", MaxRetries: 1}
+	_, err = client.ChatCompletion(request)
+	assert.ErrorIs(t, err, ErrWrongLanguage)
+	assert.ErrorContains(t, err, "got French instead of German")
+
+	request.Language.Lenient = true
+	resp, err = client.ChatCompletion(request)
+	require.NoError(t, err)
+	assert.Equal(t, replies[1], resp.GetContent())
+
+	// Languages the detector doesn't know are not checked.
+	requests = nil
+	request.Language = &LanguageOptions{Language: "sw"}
+	_, err = client.ChatCompletion(request)
+	require.NoError(t, err)
+	assert.Len(t, requests, 1)
+}
@@ -0,0 +1,127 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// DefaultModelsPerPage is the page size of SearchModels when PerPage is
+// not set.
+const DefaultModelsPerPage = 50
+
+// ModelSearch filters the models listed by SearchModels. Zero fields don't
+// filter.
+type ModelSearch struct {
+	// Search matches the names and descriptions of the models.
+	Search string
+	// Task is the name of a task, e.g. "Text Generation".
+	Task   string
+	Author string
+	// Page is the page to return, from 1. PerPage defaults to
+	// DefaultModelsPerPage.
+	Page    int
+	PerPage int
+}
+
+// ModelSummary is a model of the catalog, as listed by SearchModels.
+type ModelSummary struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Task        struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"task"`
+	Properties []ModelProperty `json:"properties"`
+}
+
+// ModelProperty is a property of a model, e.g. its context window.
+type ModelProperty struct {
+	PropertyID string      `json:"property_id"`
+	Value      interface{} `json:"value"`
+}
+
+// modelInfo converts s to the ModelInfo returned by ListModels.
+func (s ModelSummary) modelInfo() ModelInfo {
+	info := ModelInfo{Name: s.Name, Description: s.Description}
+	info.Task.Name = s.Task.Name
+	info.Task.Description = s.Task.Description
+	for _, property := range s.Properties {
+		value := fmt.Sprint(property.Value)
+		switch property.PropertyID {
+		case "beta":
+			info.Beta = value == "true"
+		case "max_batch_size":
+			info.Properties.MaxBatchSize, _ = strconv.Atoi(value)
+		case "max_total_tokens":
+			info.Properties.MaxTotalTokens, _ = strconv.Atoi(value)
+		}
+	}
+	return info
+}
+
+// ModelPage is a page of the models listed by SearchModels.
+type ModelPage struct {
+	Models     []ModelSummary
+	Page       int
+	PerPage    int
+	TotalCount int
+}
+
+// More reports whether there are pages after p.
+func (p *ModelPage) More() bool {
+	return len(p.Models) > 0 && p.Page*p.PerPage < p.TotalCount
+}
+
+// SearchModels returns a page of the models of the catalog matching
+// search.
+func (c *Client) SearchModels(ctx context.Context, search ModelSearch) (*ModelPage, error) {
+	if search.Page <= 0 {
+		search.Page = 1
+	}
+	if search.PerPage <= 0 {
+		search.PerPage = DefaultModelsPerPage
+	}
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(search.Page))
+	query.Set("per_page", strconv.Itoa(search.PerPage))
+	if search.Search != "" {
+		query.Set("search", search.Search)
+	}
+	if search.Task != "" {
+		query.Set("task", search.Task)
+	}
+	if search.Author != "" {
+		query.Set("author", search.Author)
+	}
+
+	var envelope struct {
+		Result     []ModelSummary `json:"result"`
+		ResultInfo struct {
+			Page       int `json:"page"`
+			PerPage    int `json:"per_page"`
+			TotalCount int `json:"total_count"`
+		} `json:"result_info"`
+	}
+	if _, err := c.apiDecode(ctx, "GET", fmt.Sprintf("/accounts/%s/ai/models/search?%s", c.AccountID, query.Encode()), "", nil, &envelope); err != nil {
+		return nil, err
+	}
+
+	page := &ModelPage{
+		Models:     envelope.Result,
+		Page:       envelope.ResultInfo.Page,
+		PerPage:    envelope.ResultInfo.PerPage,
+		TotalCount: envelope.ResultInfo.TotalCount,
+	}
+	// Fall back to the requested page for responses without result_info.
+	if page.Page == 0 {
+		page.Page = search.Page
+	}
+	if page.PerPage == 0 {
+		page.PerPage = search.PerPage
+	}
+	return page, nil
+}
@@ -0,0 +1,55 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newModelSearchServer serves a catalog of total models named model-1,
+// model-2, etc.
+func newModelSearchServer(t *testing.T, total int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/accounts/test-account/ai/models/search", r.URL.Path)
+		assert.Equal(t, "Text Generation", r.URL.Query().Get("task"))
+		var page, perPage int
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		fmt.Sscan(r.URL.Query().Get("per_page"), &perPage)
+
+		fmt.Fprint(w, `{"success": true, "result": [`)
+		for i := (page-1)*perPage + 1; i <= min(page*perPage, total); i++ {
+			if i > (page-1)*perPage+1 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id": "%d", "name": "model-%d", "task": {"name": "Text Generation"}, "properties": [{"property_id": "context_window", "value": "8192"}]}`, i, i)
+		}
+		fmt.Fprintf(w, `], "result_info": {"page": %d, "per_page": %d, "total_count": %d}}`, page, perPage, total)
+	}))
+}
+
+func TestClient_SearchModels(t *testing.T) {
+	server := newModelSearchServer(t, 3)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	page, err := client.SearchModels(context.Background(), ModelSearch{Task: "Text Generation", PerPage: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Models, 2)
+	assert.Equal(t, "model-1", page.Models[0].Name)
+	assert.Equal(t, "Text Generation", page.Models[0].Task.Name)
+	assert.Equal(t, []ModelProperty{{PropertyID: "context_window", Value: "8192"}}, page.Models[0].Properties)
+	assert.Equal(t, 3, page.TotalCount)
+	assert.True(t, page.More())
+
+	page, err = client.SearchModels(context.Background(), ModelSearch{Task: "Text Generation", Page: 2, PerPage: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Models, 1)
+	assert.Equal(t, "model-3", page.Models[0].Name)
+	assert.False(t, page.More())
+}
@@ -0,0 +1,246 @@
+package workersai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DailyFreeNeurons is the number of neurons included for free every day,
+// on the Free and the Paid plan. The allocation resets at 00:00 UTC.
+const DailyFreeNeurons = 10000
+
+// DefaultNeuronRefreshInterval is how often a NeuronGuard reads the usage of
+// the account when RefreshInterval is not set.
+const DefaultNeuronRefreshInterval = 5 * time.Minute
+
+// ErrNeuronLimitExceeded is returned for requests refused by a NeuronGuard.
+var ErrNeuronLimitExceeded = errors.New("neuron limit exceeded")
+
+// NeuronUsage is the Workers AI usage of the account on one day.
+type NeuronUsage struct {
+	// Date is the start of the (UTC) day.
+	Date time.Time
+	// Used is the number of neurons used so far that day.
+	Used float64
+	// FreeAllocation is the number of free neurons of the day.
+	FreeAllocation float64
+	// Remaining is the number of free neurons left, or zero once the
+	// allocation is used up.
+	Remaining float64
+}
+
+func newNeuronUsage(date time.Time, used float64) *NeuronUsage {
+	usage := &NeuronUsage{Date: date, Used: used, FreeAllocation: DailyFreeNeurons}
+	if used < DailyFreeNeurons {
+		usage.Remaining = DailyFreeNeurons - used
+	}
+	return usage
+}
+
+const neuronUsageQuery = `query ($accountTag: string!, $start: Time!, $end: Time!) {
+  viewer {
+    accounts(filter: {accountTag: $accountTag}) {
+      aiInferenceAdaptiveGroups(limit: 1, filter: {datetime_geq: $start, datetime_lt: $end}) {
+        sum { totalNeurons }
+      }
+    }
+  }
+}`
+
+// NeuronUsage reads the neurons the account used today from the GraphQL
+// Analytics API. The token needs the Account Analytics Read permission.
+// Analytics lag behind by a few minutes.
+func (c *Client) NeuronUsage(ctx context.Context) (*NeuronUsage, error) {
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	variables := map[string]interface{}{
+		"accountTag": c.AccountID,
+		"start":      start.Format(time.RFC3339),
+		"end":        start.Add(24 * time.Hour).Format(time.RFC3339),
+	}
+
+	var data struct {
+		Viewer struct {
+			Accounts []struct {
+				Groups []struct {
+					Sum struct {
+						TotalNeurons float64 `json:"totalNeurons"`
+					} `json:"sum"`
+				} `json:"aiInferenceAdaptiveGroups"`
+			} `json:"accounts"`
+		} `json:"viewer"`
+	}
+	if err := c.graphQL(ctx, neuronUsageQuery, variables, &data); err != nil {
+		return nil, fmt.Errorf("failed to read neuron usage: %w", err)
+	}
+
+	var used float64
+	for _, account := range data.Viewer.Accounts {
+		for _, group := range account.Groups {
+			used += group.Sum.TotalNeurons
+		}
+	}
+	return newNeuronUsage(start, used), nil
+}
+
+// graphQL runs query against the GraphQL Analytics API and decodes the
+// "data" field of the response into out.
+func (c *Client) graphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	url := c.apiURL() + "/graphql"
+	c.debugLog("Request URL: %s", url)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIToken))
+	req.Header.Set("Content-Type", "application/json")
+	c.beforeRequest(req)
+	if err := c.sign(req); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, err := c.apiResponse(resp)
+		return err
+	}
+
+	// The data is decoded in the same pass as the envelope.
+	envelope := struct {
+		Data   interface{} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{Data: out}
+	if err := c.decodeResponse(resp, &envelope); err != nil {
+		return err
+	}
+	if len(envelope.Errors) > 0 {
+		messages := make([]string, len(envelope.Errors))
+		for i, e := range envelope.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("query failed: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// NeuronGuard stops a Client from sending requests once the account used
+// Threshold neurons today, e.g. to stay within the free allocation:
+//
+//	client.NeuronGuard = &workersai.NeuronGuard{Threshold: workersai.DailyFreeNeurons}
+//
+// The usage is read with NeuronUsage every RefreshInterval. In between, the
+// neurons of the client's own requests are estimated from their token usage
+// and the client's pricing, so the guard also holds while analytics lag
+// behind. If the usage can't be read, the guard goes on with its estimate.
+//
+// A NeuronGuard is safe for concurrent use, but shouldn't be shared by
+// clients of different accounts.
+type NeuronGuard struct {
+	// Threshold is the number of neurons per day after which requests are
+	// refused with ErrNeuronLimitExceeded.
+	Threshold float64
+	// RefreshInterval defaults to DefaultNeuronRefreshInterval.
+	RefreshInterval time.Duration
+
+	mu        sync.Mutex
+	day       time.Time
+	used      float64
+	refreshed time.Time
+}
+
+// Used returns the neurons used today as last known to the guard.
+func (g *NeuronGuard) Used() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.used
+}
+
+// check refreshes the usage when it is stale and fails once the threshold
+// is reached. The usage is read without holding the lock, so that a slow
+// refresh doesn't block the other requests: they go on with the estimate
+// meanwhile.
+func (g *NeuronGuard) check(ctx context.Context, c *Client) error {
+	interval := g.RefreshInterval
+	if interval <= 0 {
+		interval = DefaultNeuronRefreshInterval
+	}
+
+	g.mu.Lock()
+	now := time.Now()
+	if today := now.UTC().Truncate(24 * time.Hour); !today.Equal(g.day) {
+		g.day, g.used, g.refreshed = today, 0, time.Time{}
+	}
+	day := g.day
+	refresh := now.Sub(g.refreshed) >= interval
+	if refresh {
+		// Claim the refresh, and don't retry a failed one before the next
+		// interval either.
+		g.refreshed = now
+	}
+	g.mu.Unlock()
+
+	var usage *NeuronUsage
+	if refresh {
+		var err error
+		if usage, err = c.NeuronUsage(ctx); err != nil {
+			c.debugLog("Neuron guard keeps its estimate: %v", err)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if usage != nil && g.day.Equal(day) && usage.Used > g.used {
+		g.used = usage.Used
+	}
+	if g.used >= g.Threshold {
+		return fmt.Errorf("%w: %.0f of %.0f neurons used today", ErrNeuronLimitExceeded, g.used, g.Threshold)
+	}
+	return nil
+}
+
+// record adds the estimated neurons of a request to the usage.
+func (g *NeuronGuard) record(c *Client, modelID string, usage Usage) {
+	price, ok := c.price(modelID)
+	if !ok {
+		return
+	}
+	neurons := price.neurons(usage)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.used += neurons
+}
+
+// checkNeurons enforces c.NeuronGuard, if any.
+func (c *Client) checkNeurons(ctx context.Context) error {
+	if c.NeuronGuard == nil {
+		return nil
+	}
+	return c.NeuronGuard.check(ctx, c)
+}
+
+// recordNeurons passes the usage of a request to c.NeuronGuard, if any.
+func (c *Client) recordNeurons(modelID string, usage Usage) {
+	if c.NeuronGuard != nil {
+		c.NeuronGuard.record(c, modelID, usage)
+	}
+}
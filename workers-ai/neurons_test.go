@@ -0,0 +1,130 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newNeuronServer(t *testing.T, used float64, queries *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/graphql" {
+			queries.Add(1)
+			var query struct {
+				Query     string                 `json:"query"`
+				Variables map[string]interface{} `json:"variables"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+			assert.Contains(t, query.Query, "aiInferenceAdaptiveGroups")
+			assert.Equal(t, "test-account", query.Variables["accountTag"])
+			fmt.Fprintf(w, `{"data": {"viewer": {"accounts": [{"aiInferenceAdaptiveGroups": [{"sum": {"totalNeurons": %g}}]}]}}, "errors": null}`, used)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi", "usage": {"prompt_tokens": 1000000, "completion_tokens": 0, "total_tokens": 1000000}}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_NeuronUsage(t *testing.T) {
+	var queries atomic.Int32
+	server := newNeuronServer(t, 2500.5, &queries)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	usage, err := client.NeuronUsage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2500.5, usage.Used)
+	assert.Equal(t, float64(DailyFreeNeurons), usage.FreeAllocation)
+	assert.Equal(t, DailyFreeNeurons-2500.5, usage.Remaining)
+	assert.Equal(t, 0, usage.Date.Hour())
+
+	usage = newNeuronUsage(usage.Date, 12000)
+	assert.Zero(t, usage.Remaining)
+}
+
+func TestClient_NeuronUsage_QueryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": null, "errors": [{"message": "not authorized for that account"}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	_, err := client.NeuronUsage(context.Background())
+	assert.ErrorContains(t, err, "not authorized for that account")
+}
+
+func TestNeuronGuard(t *testing.T) {
+	var queries atomic.Int32
+	server := newNeuronServer(t, 9000, &queries)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Pricing = map[string]ModelPrice{ModelLlama38B: {InputNeurons: 600}}
+	client.NeuronGuard = &NeuronGuard{Threshold: DailyFreeNeurons}
+
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	// 9000 used, each request is estimated at 600 neurons.
+	_, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 9600.0, client.NeuronGuard.Used())
+	_, err = client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+
+	_, err = client.Chat(ModelLlama38B, messages, nil)
+	require.ErrorIs(t, err, ErrNeuronLimitExceeded)
+	assert.True(t, strings.Contains(err.Error(), "10200 of 10000"), err.Error())
+
+	_, err = client.ChatStream(context.Background(), ModelLlama38B, messages, nil, nil)
+	assert.ErrorIs(t, err, ErrNeuronLimitExceeded)
+
+	// The usage was read only once within the refresh interval.
+	assert.Equal(t, int32(1), queries.Load())
+}
+
+func TestNeuronGuard_SlowRefresh(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/graphql" {
+			<-release
+			fmt.Fprint(w, `{"data": {"viewer": {"accounts": [{"aiInferenceAdaptiveGroups": [{"sum": {"totalNeurons": 500}}]}]}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.NeuronGuard = &NeuronGuard{Threshold: DailyFreeNeurons}
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := client.Chat(ModelLlama38B, messages, nil)
+		refreshed <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// The refresh in progress doesn't hold back the other requests.
+	_, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, client.NeuronGuard.Used())
+
+	close(release)
+	require.NoError(t, <-refreshed)
+	assert.Equal(t, 500.0, client.NeuronGuard.Used())
+}
@@ -0,0 +1,176 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultOverflowAttempts is the number of reduced requests
+// OverflowRecovery sends when MaxAttempts is zero.
+const DefaultOverflowAttempts = 3
+
+// DefaultSummaryPrompt is the instruction given to the model summarizing
+// the turns dropped by OverflowSummarize.
+const DefaultSummaryPrompt = "Summarize the following conversation in a few sentences, keeping the facts, names and decisions needed to continue it."
+
+// OverflowStrategy is the way OverflowRecovery shortens a conversation.
+type OverflowStrategy int
+
+const (
+	// OverflowNone returns context window errors to the caller.
+	OverflowNone OverflowStrategy = iota
+	// OverflowTruncate drops the oldest turns.
+	OverflowTruncate
+	// OverflowSummarize replaces the oldest turns with a summary, written
+	// by the model and added to the system prompt.
+	OverflowSummarize
+)
+
+// OverflowRecovery makes ChatCompletion retry requests rejected for
+// exceeding the context window of the model with a shorter conversation.
+// Each attempt drops about half of the turns before the last user message;
+// the system prompt and the last user message are always kept. The dropped
+// messages are reported in ChatResponse.Dropped.
+type OverflowRecovery struct {
+	Strategy OverflowStrategy
+	// MaxAttempts caps the number of reduced requests. Defaults to
+	// DefaultOverflowAttempts.
+	MaxAttempts int
+	// SummaryModel writes the summaries of OverflowSummarize. Defaults to
+	// the model of the request.
+	SummaryModel string
+}
+
+// IsContextOverflow reports whether err is the API rejecting a request for
+// exceeding the context window of the model.
+func IsContextOverflow(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.HasCode(ErrorCodeContextWindow) {
+		return true
+	}
+	body := strings.ToLower(apiErr.Body)
+	return strings.Contains(body, "context window") || strings.Contains(body, "context length")
+}
+
+// recoverOverflow resends request with fewer turns while it overflows the
+// context window, and returns the request that succeeded.
+func (c *Client) recoverOverflow(ctx context.Context, request ChatCompletionRequest, err error) (ChatCompletionRequest, *ChatResponse, error) {
+	policy := c.OverflowRecovery
+	attempts := policy.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultOverflowAttempts
+	}
+
+	var system []Message
+	messages := request.Messages
+	if hasSystemMessage(messages) {
+		system, messages = messages[:1], messages[1:]
+	}
+
+	var dropped []Message
+	var summary string
+	for attempt := 0; attempt < attempts && IsContextOverflow(err); attempt++ {
+		cut := overflowCut(messages)
+		if cut == 0 {
+			break
+		}
+		c.debugLog("Request exceeds the context window, dropping %d messages (%d/%d)", cut, attempt+1, attempts)
+
+		reduced := request
+		reduced.Messages = append(append([]Message(nil), system...), messages[cut:]...)
+		if policy.Strategy == OverflowSummarize {
+			summary, err = c.summarize(ctx, request, summary, messages[:cut])
+			if err != nil {
+				return request, nil, fmt.Errorf("failed to summarize dropped messages: %w", err)
+			}
+			reduced.Messages = withSystemInstruction(reduced.Messages, "Summary of the earlier conversation:\n"+summary)
+		}
+		dropped = append(dropped, messages[:cut]...)
+		messages = messages[cut:]
+
+		var response *ChatResponse
+		response, err = c.hedgedComplete(ctx, reduced)
+		if err == nil {
+			response.Dropped = dropped
+			response.Summary = summary
+			return reduced, response, nil
+		}
+	}
+	return request, nil, err
+}
+
+// overflowCut returns the number of leading messages to drop: the turns,
+// starting at user messages, up to about half of messages. The last user
+// message and what follows it are kept.
+func overflowCut(messages []Message) int {
+	var starts []int
+	for i, m := range messages {
+		if i > 0 && isUserMessage(m) {
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) == 0 {
+		return 0
+	}
+	for _, start := range starts {
+		if start >= len(messages)/2 {
+			return start
+		}
+	}
+	return starts[len(starts)-1]
+}
+
+// summarize asks the model for a summary of messages, continuing summary.
+func (c *Client) summarize(ctx context.Context, request ChatCompletionRequest, summary string, messages []Message) (string, error) {
+	var b strings.Builder
+	if summary != "" {
+		fmt.Fprintf(&b, "Summary of what came before:\n%s\n\n", summary)
+	}
+	for _, m := range messages {
+		role, content := messageText(m)
+		fmt.Fprintf(&b, "%s: %s\n", role, content)
+	}
+
+	model := c.OverflowRecovery.SummaryModel
+	if model == "" {
+		model = request.Model
+	}
+	response, err := c.complete(ctx, ChatCompletionRequest{
+		Model: model,
+		Messages: []Message{
+			ChatMessage{Role: "system", Content: DefaultSummaryPrompt},
+			ChatMessage{Role: "user", Content: b.String()},
+		},
+		Priority: request.Priority,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response.GetContent()), nil
+}
+
+// messageText returns the role and a plain text rendering of m, with tool
+// calls written as name(arguments).
+func messageText(m Message) (role, content string) {
+	var calls []ToolCall
+	switch msg := m.(type) {
+	case ChatMessage:
+		role, content, calls = msg.Role, msg.Content, msg.ToolCalls
+	case ResponseMessage:
+		role, calls = msg.Role, msg.ToolCalls
+		if msg.Content != nil {
+			content = *msg.Content
+		}
+	case ToolMessage:
+		return msg.Role, msg.Content
+	}
+	for _, call := range calls {
+		content = strings.TrimSpace(fmt.Sprintf("%s\n%s(%s)", content, call.Function.Name, call.Function.Arguments))
+	}
+	return role, content
+}
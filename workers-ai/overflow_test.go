@@ -0,0 +1,109 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// overflowServer rejects chat requests of more than limit messages with a
+// context window error, and answers summary requests with "SUMMARY".
+func overflowServer(t *testing.T, limit int, requests *[]ChatCompletionRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		*requests = append(*requests, request)
+
+		if request.Messages[0].(ChatMessage).Content == DefaultSummaryPrompt {
+			w.Write([]byte(`{"success": true, "result": {"response": "SUMMARY"}}`))
+			return
+		}
+		if len(request.Messages) > limit {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"success": false, "errors": [{"code": 5021, "message": "The estimated number of input and maximum output tokens (%d) exceeded this model context window limit (8192)."}]}`, 9000)
+			return
+		}
+		w.Write([]byte(`{"success": true, "result": {"response": "Done"}}`))
+	}))
+}
+
+func longConversation() []Message {
+	messages := []Message{ChatMessage{Role: "system", Content: "Be brief."}}
+	for i := 1; i <= 4; i++ {
+		messages = append(messages,
+			ChatMessage{Role: "user", Content: fmt.Sprintf("Question %d", i)},
+			ChatMessage{Role: "assistant", Content: fmt.Sprintf("Answer %d", i)},
+		)
+	}
+	return append(messages, ChatMessage{Role: "user", Content: "Last question"})
+}
+
+func TestOverflowRecovery_Truncate(t *testing.T) {
+	var requests []ChatCompletionRequest
+	server := overflowServer(t, 4, &requests)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.OverflowRecovery = OverflowRecovery{Strategy: OverflowTruncate}
+
+	messages := longConversation()
+	resp, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Done", resp.GetContent())
+
+	// 10 messages, then 6, then 4.
+	require.Len(t, requests, 3)
+	assert.Equal(t, append([]Message{messages[0]}, messages[7:]...), requests[2].Messages)
+	assert.Equal(t, messages[1:7], resp.Dropped)
+	assert.Empty(t, resp.Summary)
+}
+
+func TestOverflowRecovery_Summarize(t *testing.T) {
+	var requests []ChatCompletionRequest
+	server := overflowServer(t, 6, &requests)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.OverflowRecovery = OverflowRecovery{Strategy: OverflowSummarize, SummaryModel: ModelMistral7B}
+
+	messages := longConversation()
+	resp, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "SUMMARY", resp.Summary)
+	assert.Equal(t, messages[1:5], resp.Dropped)
+
+	require.Len(t, requests, 3)
+	assert.Equal(t, ModelMistral7B, requests[1].Model)
+	assert.Contains(t, requests[1].Messages[1].(ChatMessage).Content, "user: Question 1\nassistant: Answer 1\n")
+	assert.Equal(t, ChatMessage{Role: "system", Content: "Be brief.\n\nSummary of the earlier conversation:\nSUMMARY"}, requests[2].Messages[0])
+	assert.Equal(t, messages[5:], requests[2].Messages[1:])
+}
+
+func TestOverflowRecovery_Disabled(t *testing.T) {
+	var requests []ChatCompletionRequest
+	server := overflowServer(t, 1, &requests)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	_, err := client.Chat(ModelLlama38B, longConversation(), nil)
+	assert.True(t, IsContextOverflow(err))
+	assert.Len(t, requests, 1)
+
+	// A single turn can't be shortened.
+	client.OverflowRecovery = OverflowRecovery{Strategy: OverflowTruncate}
+	_, err = client.Chat(ModelLlama38B, []Message{
+		ChatMessage{Role: "system", Content: "Be brief."},
+		ChatMessage{Role: "user", Content: "Hi"},
+	}, nil)
+	assert.True(t, IsContextOverflow(err))
+	assert.Len(t, requests, 2)
+}
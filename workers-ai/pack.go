@@ -0,0 +1,214 @@
+package workersai
+
+import (
+	"sort"
+	"strings"
+)
+
+// Snippet is a piece of context competing for room in a prompt, such as a
+// retrieved document chunk or an agent's note.
+type Snippet struct {
+	// ID identifies the source of the snippet for deduplication. Snippets
+	// without ID are deduplicated by their text.
+	ID    string
+	Text  string
+	Score float64
+	// Tokens is the size of Text. It is estimated with EstimateTokens when
+	// zero.
+	Tokens int
+}
+
+// SnippetsFromMatches turns vector matches into snippets, taking their text
+// from the metadata field textKey. Matches without text are skipped.
+func SnippetsFromMatches(matches []VectorMatch, textKey string) []Snippet {
+	snippets := make([]Snippet, 0, len(matches))
+	for _, m := range matches {
+		text, _ := m.Metadata[textKey].(string)
+		if text == "" {
+			continue
+		}
+		snippets = append(snippets, Snippet{ID: m.ID, Text: text, Score: m.Score})
+	}
+	return snippets
+}
+
+// PackStrategy is the way PackSnippets picks snippets.
+type PackStrategy int
+
+const (
+	// PackGreedy takes the snippets by decreasing score per token while
+	// they fit. It is fast and close to the best packing when snippets
+	// are small compared to the budget.
+	PackGreedy PackStrategy = iota
+	// PackOptimal solves the knapsack problem for the highest total score.
+	// Large budgets are solved at a coarser granularity of tokens.
+	PackOptimal
+)
+
+// PackOrder is the order of the snippets returned by PackSnippets.
+type PackOrder int
+
+const (
+	// OrderScore puts the best snippets first.
+	OrderScore PackOrder = iota
+	// OrderInput keeps the order of the input, e.g. the order of the
+	// chunks in their document.
+	OrderInput
+	// OrderEdges puts the best snippets at the start and the end and the
+	// weakest in the middle, where models pay them the least attention.
+	OrderEdges
+)
+
+// PackOptions configures PackSnippets.
+type PackOptions struct {
+	// Budget is the number of tokens the snippets may take.
+	Budget   int
+	Strategy PackStrategy
+	Order    PackOrder
+	// Overhead is added to the tokens of each snippet, for the separators
+	// or labels placed around it in the prompt.
+	Overhead int
+	// MinScore drops the snippets scoring below it.
+	MinScore float64
+	// Dedup drops the snippets with the ID, or the text, of a better
+	// scoring one.
+	Dedup bool
+}
+
+// maxPackCells bounds the size of the knapsack table of PackOptimal.
+const maxPackCells = 1 << 22
+
+// PackSnippets selects the snippets of the highest total score whose
+// tokens fit in opts.Budget, and orders them as opts.Order says.
+func PackSnippets(snippets []Snippet, opts PackOptions) []Snippet {
+	type candidate struct {
+		Snippet
+		index int
+		cost  int
+	}
+
+	// Deduplication keeps the best scoring snippet of each group.
+	order := make([]int, len(snippets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return snippets[order[i]].Score > snippets[order[j]].Score })
+
+	var candidates []candidate
+	seen := make(map[string]bool)
+	for _, i := range order {
+		s := snippets[i]
+		if s.Score < opts.MinScore {
+			continue
+		}
+		if opts.Dedup {
+			key := "id:" + s.ID
+			if s.ID == "" {
+				key = "text:" + strings.Join(strings.Fields(strings.ToLower(s.Text)), " ")
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		tokens := s.Tokens
+		if tokens == 0 {
+			tokens = EstimateTokens(s.Text)
+		}
+		if cost := tokens + opts.Overhead; cost <= opts.Budget {
+			candidates = append(candidates, candidate{Snippet: s, index: i, cost: cost})
+		}
+	}
+
+	var chosen []candidate
+	switch opts.Strategy {
+	case PackOptimal:
+		costs := make([]int, len(candidates))
+		scores := make([]float64, len(candidates))
+		for i, c := range candidates {
+			costs[i], scores[i] = c.cost, c.Score
+		}
+		for _, i := range knapsack(costs, scores, opts.Budget) {
+			chosen = append(chosen, candidates[i])
+		}
+	default:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Score/float64(candidates[i].cost) > candidates[j].Score/float64(candidates[j].cost)
+		})
+		remaining := opts.Budget
+		for _, c := range candidates {
+			if c.cost <= remaining {
+				chosen = append(chosen, c)
+				remaining -= c.cost
+			}
+		}
+	}
+
+	sort.SliceStable(chosen, func(i, j int) bool {
+		if opts.Order == OrderInput {
+			return chosen[i].index < chosen[j].index
+		}
+		return chosen[i].Score > chosen[j].Score
+	})
+
+	packed := make([]Snippet, len(chosen))
+	if opts.Order == OrderEdges {
+		// Deal the snippets from the best to the front and the back in
+		// turn.
+		front, back := 0, len(chosen)-1
+		for i, c := range chosen {
+			if i%2 == 0 {
+				packed[front] = c.Snippet
+				front++
+			} else {
+				packed[back] = c.Snippet
+				back--
+			}
+		}
+		return packed
+	}
+	for i, c := range chosen {
+		packed[i] = c.Snippet
+	}
+	return packed
+}
+
+// knapsack returns the indexes of the items of the highest total score
+// whose costs fit in budget. Costs are rounded up to units keeping the
+// table under maxPackCells, so the result always fits.
+func knapsack(costs []int, scores []float64, budget int) []int {
+	n := len(costs)
+	if n == 0 || budget <= 0 {
+		return nil
+	}
+	unit := 1
+	if cells := (n + 1) * (budget + 1); cells > maxPackCells {
+		unit = (cells + maxPackCells - 1) / maxPackCells
+	}
+	capacity := budget / unit
+
+	// best[w] is the highest score of the items seen so far fitting in w
+	// units; taken[i][w] records whether item i is part of it.
+	best := make([]float64, capacity+1)
+	taken := make([][]bool, n)
+	for i := range costs {
+		taken[i] = make([]bool, capacity+1)
+		weight := (costs[i] + unit - 1) / unit
+		for w := capacity; w >= weight; w-- {
+			if score := best[w-weight] + scores[i]; score > best[w] {
+				best[w] = score
+				taken[i][w] = true
+			}
+		}
+	}
+
+	var chosen []int
+	w := capacity
+	for i := n - 1; i >= 0; i-- {
+		if taken[i][w] {
+			chosen = append(chosen, i)
+			w -= (costs[i] + unit - 1) / unit
+		}
+	}
+	return chosen
+}
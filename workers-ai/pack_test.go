@@ -0,0 +1,82 @@
+package workersai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func snippetIDs(snippets []Snippet) []string {
+	ids := make([]string, len(snippets))
+	for i, s := range snippets {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func TestPackSnippets(t *testing.T) {
+	snippets := []Snippet{
+		{ID: "d", Score: 0.3, Tokens: 10},
+		{ID: "a", Score: 0.9, Tokens: 60},
+		{ID: "b", Score: 0.8, Tokens: 50},
+		{ID: "c", Score: 0.7, Tokens: 50},
+		{ID: "e", Score: 0.1, Tokens: 200},
+	}
+
+	tests := []struct {
+		name string
+		opts PackOptions
+		want []string
+	}{
+		// Greedy takes d and b for their density, then neither a nor c
+		// fits, while b and c together score more.
+		{"greedy", PackOptions{Budget: 100}, []string{"b", "d"}},
+		{"optimal", PackOptions{Budget: 100, Strategy: PackOptimal}, []string{"b", "c"}},
+		{"input order", PackOptions{Budget: 120, Strategy: PackOptimal, Order: OrderInput}, []string{"d", "a", "b"}},
+		{"edges", PackOptions{Budget: 1000, Order: OrderEdges}, []string{"a", "c", "e", "d", "b"}},
+		{"overhead", PackOptions{Budget: 100, Strategy: PackOptimal, Overhead: 5}, []string{"a", "d"}},
+		{"min score", PackOptions{Budget: 1000, MinScore: 0.5}, []string{"a", "b", "c"}},
+		{"nothing fits", PackOptions{Budget: 5}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, snippetIDs(PackSnippets(snippets, tt.opts)))
+		})
+	}
+}
+
+func TestPackSnippets_Dedup(t *testing.T) {
+	snippets := []Snippet{
+		{ID: "a", Text: "Paris is the capital.", Score: 0.5},
+		{ID: "a", Text: "Paris is the capital.", Score: 0.9},
+		{Text: "The Seine  flows through Paris.", Score: 0.8},
+		{Text: "the seine flows through paris.", Score: 0.6},
+	}
+
+	packed := PackSnippets(snippets, PackOptions{Budget: 1000, Dedup: true})
+	assert.Equal(t, []Snippet{snippets[1], snippets[2]}, packed)
+	assert.Len(t, PackSnippets(snippets, PackOptions{Budget: 1000}), 4)
+}
+
+func TestPackSnippets_LargeBudget(t *testing.T) {
+	var snippets []Snippet
+	for i := 0; i < 500; i++ {
+		snippets = append(snippets, Snippet{Score: float64(i%7 + 1), Tokens: 100 + i*13%900})
+	}
+
+	packed := PackSnippets(snippets, PackOptions{Budget: 100000, Strategy: PackOptimal})
+	total := 0
+	for _, s := range packed {
+		total += s.Tokens
+	}
+	assert.LessOrEqual(t, total, 100000)
+	assert.NotEmpty(t, packed)
+}
+
+func TestSnippetsFromMatches(t *testing.T) {
+	snippets := SnippetsFromMatches([]VectorMatch{
+		{ID: "1", Score: 0.9, Metadata: map[string]interface{}{"text": "Paris"}},
+		{ID: "2", Score: 0.8},
+	}, "text")
+	assert.Equal(t, []Snippet{{ID: "1", Text: "Paris", Score: 0.9}}, snippets)
+}
@@ -0,0 +1,35 @@
+package workersai
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a goroutine of the package that panicked, in
+// a tool handler, a callback or the client itself. The panic is recovered
+// and reported as the error of the item the goroutine worked on, so that
+// it doesn't crash the host process.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine at the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value of the panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic stores a recovered panic in *err as a *PanicError. It must
+// be deferred directly, as recover only stops a panic then.
+func recoverPanic(err *error) {
+	if v := recover(); v != nil {
+		*err = &PanicError{Value: v, Stack: debug.Stack()}
+	}
+}
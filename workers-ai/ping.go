@@ -0,0 +1,163 @@
+package workersai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HealthReport is the result of Ping.
+type HealthReport struct {
+	// Healthy is set when the API answered the ping successfully.
+	Healthy bool
+	// Authenticated is set when the API accepted the credentials. It is
+	// false for 401 and 403 responses and when no response was received.
+	Authenticated bool
+	// StatusCode is the HTTP status of the response, or 0 if none was received.
+	StatusCode int
+	// Latency is the round trip time of the ping, including connection setup
+	// when the connection wasn't warm yet.
+	Latency time.Duration
+	// CheckedAt is when the ping was sent.
+	CheckedAt time.Time
+	// Err is the reason the client is unhealthy.
+	Err error
+}
+
+// Ping performs a cheap authenticated call, listing a single model, to
+// validate the credentials and warm up the connection at startup. The
+// returned error is the report's Err.
+func (c *Client) Ping(ctx context.Context) (*HealthReport, error) {
+	report := &HealthReport{CheckedAt: time.Now()}
+
+	status, err := c.apiGet(ctx, fmt.Sprintf("/accounts/%s/ai/models/search?per_page=1", c.AccountID), nil)
+	report.Latency = time.Since(report.CheckedAt)
+	report.StatusCode = status
+	report.Authenticated = status != 0 && status != http.StatusUnauthorized && status != http.StatusForbidden
+	if err != nil {
+		report.Err = fmt.Errorf("ping failed: %w", err)
+		return report, report.Err
+	}
+
+	report.Healthy = true
+	return report, nil
+}
+
+// apiURL returns the base URL of the Cloudflare API. Requests routed
+// through an AI Gateway only cover model runs; other endpoints are
+// reached directly.
+func (c *Client) apiURL() string {
+	if c.Gateway != "" && c.BaseURL == DefaultGatewayBaseURL {
+		return DefaultBaseURL
+	}
+	return c.BaseURL
+}
+
+// apiGet sends an authenticated GET request for path to the Cloudflare API
+// and decodes the "result" field of the response into out, if not nil. It
+// returns the HTTP status of the response, or 0 if none was received.
+func (c *Client) apiGet(ctx context.Context, path string, out interface{}) (int, error) {
+	return c.apiDo(ctx, "GET", path, nil, out)
+}
+
+// apiPost is apiGet for a POST request with payload as JSON body.
+func (c *Client) apiPost(ctx context.Context, path string, payload, out interface{}) (int, error) {
+	body, err := c.codec().Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return c.apiDo(ctx, "POST", path, body, out)
+}
+
+func (c *Client) apiDo(ctx context.Context, method, path string, body []byte, out interface{}) (int, error) {
+	contentType := ""
+	if body != nil {
+		contentType = "application/json"
+	}
+	if out == nil {
+		out = new(json.RawMessage)
+	}
+	envelope := struct {
+		Result interface{} `json:"result"`
+	}{Result: out}
+	return c.apiDecode(ctx, method, path, contentType, body, &envelope)
+}
+
+// apiDecode sends an authenticated request for path to the Cloudflare API
+// and decodes the whole JSON response into out as it is read.
+func (c *Client) apiDecode(ctx context.Context, method, path, contentType string, body []byte, out interface{}) (int, error) {
+	resp, err := c.apiSend(ctx, method, path, contentType, body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, err := c.apiResponse(resp)
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, c.decodeResponse(resp, out)
+}
+
+// apiRaw sends an authenticated request for path to the Cloudflare API and
+// returns the body of the response as is, for the endpoints that don't
+// answer with a JSON envelope.
+func (c *Client) apiRaw(ctx context.Context, method, path, contentType string, body []byte) ([]byte, int, error) {
+	resp, err := c.apiSend(ctx, method, path, contentType, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := c.apiResponse(resp)
+	return respBody, resp.StatusCode, err
+}
+
+// apiSend sends an authenticated request for path to the Cloudflare API.
+// The caller closes the body of the response.
+func (c *Client) apiSend(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	url := c.apiURL() + path
+	c.debugLog("Request URL: %s", url)
+
+	var reqBody io.Reader
+	if body != nil {
+		c.debugLog("Request Body: %s", string(body))
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIToken))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.beforeRequest(req)
+	if err := c.sign(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	return resp, nil
+}
+
+// apiResponse reads the body of resp, or returns the API error it reports.
+func (c *Client) apiResponse(resp *http.Response) ([]byte, error) {
+	respBody, err := c.readResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	c.debugLog("Response Body: %s", string(respBody))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, respBody)
+	}
+	return respBody, nil
+}
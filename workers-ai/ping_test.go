@@ -0,0 +1,46 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Ping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/accounts/test-account/ai/models/search", r.URL.Path)
+		assert.Equal(t, "1", r.URL.Query().Get("per_page"))
+
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": [{"name": "@cf/meta/llama-3-8b-instruct"}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	report, err := client.Ping(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.Healthy)
+	assert.True(t, report.Authenticated)
+	assert.Equal(t, http.StatusOK, report.StatusCode)
+	assert.Positive(t, report.Latency)
+
+	client.APIToken = "wrong-token"
+	report, err = client.Ping(context.Background())
+	require.Error(t, err)
+	assert.False(t, report.Healthy)
+	assert.False(t, report.Authenticated)
+	assert.Equal(t, http.StatusUnauthorized, report.StatusCode)
+	assert.Equal(t, err, report.Err)
+}
@@ -0,0 +1,273 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultPoolWorkers is the number of sessions a Pool runs at once when
+// Workers is not set.
+const DefaultPoolWorkers = 4
+
+// ErrTokenBudgetExceeded is returned for requests made by a Pool after its
+// TokenBudget was used up.
+var ErrTokenBudgetExceeded = errors.New("token budget exceeded")
+
+// PoolTask is the work of one agent session, e.g. the processing of one
+// document. It talks to the model through session, whose client enforces
+// the limits of the pool, and should return once ctx is done.
+type PoolTask func(ctx context.Context, session *ChatSession) error
+
+// PoolProgress is a snapshot of a Pool run, passed to OnProgress.
+type PoolProgress struct {
+	Total   int
+	Running int
+	Done    int
+	// Failed counts the finished tasks that returned an error; they are
+	// included in Done.
+	Failed int
+	// Usage is the token usage of all sessions so far.
+	Usage Usage
+}
+
+// PoolResult holds the outcome of Pool.Run.
+type PoolResult struct {
+	// Sessions are the sessions of the tasks, in task order. The session
+	// of a task that never started is nil.
+	Sessions []*ChatSession
+	// Errors are the errors of the tasks, in task order; nil for tasks that
+	// succeeded.
+	Errors []error
+	// Usage is the token usage summed over all sessions.
+	Usage Usage
+}
+
+// Pool runs many agent sessions concurrently under shared limits. Every
+// task gets a ChatSession of its own, set up from the fields below, so
+// tasks don't see each other's history; a failing task doesn't stop the
+// others.
+type Pool struct {
+	Client ClientInterface
+	Model  string
+
+	// Workers caps the sessions running at once. Defaults to
+	// DefaultPoolWorkers.
+	Workers int
+	// Rate caps the requests per second over all sessions. Zero means no
+	// limit.
+	Rate float64
+	// TokenBudget caps the tokens used by all sessions. Once it is used
+	// up, further requests fail with ErrTokenBudgetExceeded and waiting
+	// tasks are not started. Requests already in flight may go over the
+	// budget. Zero means no limit.
+	TokenBudget int
+
+	// SystemPrompt, ModelParameters and Tools are set on every session.
+	SystemPrompt    string
+	ModelParameters *ModelParameters
+	Tools           []Tool
+
+	// OnProgress, if set, is called whenever a task starts or finishes.
+	// Calls are serialized.
+	OnProgress func(PoolProgress)
+}
+
+// NewPool returns a pool running up to workers sessions with modelID at
+// once.
+func NewPool(client ClientInterface, modelID string, workers int) *Pool {
+	return &Pool{
+		Client:  client,
+		Model:   modelID,
+		Workers: workers,
+	}
+}
+
+// Run runs tasks and waits for all of them to finish. Cancelling ctx stops
+// the tasks that didn't start yet and is passed on to the running ones.
+// The returned error joins the errors of the failed tasks; the result is
+// returned either way.
+func (p *Pool) Run(ctx context.Context, tasks []PoolTask) (*PoolResult, error) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = DefaultPoolWorkers
+	}
+
+	run := &poolRun{
+		pool:      p,
+		scheduler: NewScheduler(p.Rate, 0),
+		progress:  PoolProgress{Total: len(tasks)},
+		result: &PoolResult{
+			Sessions: make([]*ChatSession, len(tasks)),
+			Errors:   make([]error, len(tasks)),
+		},
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(tasks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				run.runTask(ctx, i, tasks[i])
+			}
+		}()
+	}
+	for i := range tasks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	result := run.result
+	result.Usage = run.progress.Usage
+
+	var errs []error
+	for i, err := range result.Errors {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("task %d: %w", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("%d of %d tasks failed: %w", len(errs), len(tasks), errors.Join(errs...))
+	}
+	return result, nil
+}
+
+// poolRun is the shared state of one Pool.Run.
+type poolRun struct {
+	pool      *Pool
+	scheduler *Scheduler
+
+	mu       sync.Mutex
+	progress PoolProgress
+	result   *PoolResult
+}
+
+func (r *poolRun) runTask(ctx context.Context, i int, task PoolTask) {
+	if err := r.startable(ctx); err != nil {
+		r.finish(i, err)
+		return
+	}
+
+	session := NewChatSession(&poolClient{ClientInterface: r.pool.Client, ctx: ctx, run: r}, r.pool.Model)
+	session.SystemPrompt = r.pool.SystemPrompt
+	session.ModelParameters = r.pool.ModelParameters
+	session.Tools = r.pool.Tools
+
+	r.mu.Lock()
+	r.result.Sessions[i] = session
+	r.progress.Running++
+	r.report()
+	r.mu.Unlock()
+
+	err := runPoolTask(ctx, task, session)
+
+	r.mu.Lock()
+	r.progress.Running--
+	r.mu.Unlock()
+	r.finish(i, err)
+}
+
+// runPoolTask runs task, returning a panic of the task as its error.
+func runPoolTask(ctx context.Context, task PoolTask, session *ChatSession) (err error) {
+	defer recoverPanic(&err)
+	return task(ctx, session)
+}
+
+// startable reports why a task may not start.
+func (r *poolRun) startable(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.checkBudget()
+}
+
+func (r *poolRun) finish(i int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Errors[i] = err
+	r.progress.Done++
+	if err != nil {
+		r.progress.Failed++
+	}
+	r.report()
+}
+
+// checkBudget returns ErrTokenBudgetExceeded once the budget is used up.
+// r.mu must be held.
+func (r *poolRun) checkBudget() error {
+	if r.pool.TokenBudget > 0 && r.progress.Usage.TotalTokens >= r.pool.TokenBudget {
+		return ErrTokenBudgetExceeded
+	}
+	return nil
+}
+
+// report calls OnProgress. r.mu must be held.
+func (r *poolRun) report() {
+	if r.pool.OnProgress != nil {
+		r.pool.OnProgress(r.progress)
+	}
+}
+
+// poolClient is the client of the sessions of a pool. It enforces the rate
+// and token budget on chat requests and runs them with the context of the
+// pool.
+type poolClient struct {
+	ClientInterface
+	ctx context.Context
+	run *poolRun
+}
+
+func (c *poolClient) Chat(modelID string, messages []Message, modelParams *ModelParameters) (*ChatResponse, error) {
+	return c.ChatWithTools(modelID, messages, nil, modelParams)
+}
+
+func (c *poolClient) ChatWithTools(modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) (*ChatResponse, error) {
+	request := ChatCompletionRequest{
+		Model:    modelID,
+		Messages: messages,
+		Tools:    tools,
+	}
+	if modelParams != nil {
+		request.ModelParameters = *modelParams
+	}
+	return c.ChatCompletionContext(c.ctx, request)
+}
+
+func (c *poolClient) ChatCompletion(request ChatCompletionRequest) (*ChatResponse, error) {
+	return c.ChatCompletionContext(c.ctx, request)
+}
+
+func (c *poolClient) ChatCompletionContext(ctx context.Context, request ChatCompletionRequest) (*ChatResponse, error) {
+	c.run.mu.Lock()
+	err := c.run.checkBudget()
+	c.run.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := c.run.scheduler.Acquire(ctx, PriorityBatch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := c.ClientInterface.ChatCompletionContext(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := resp.GetUsage()
+	c.run.mu.Lock()
+	c.run.progress.Usage.PromptTokens += usage.PromptTokens
+	c.run.progress.Usage.CompletionTokens += usage.CompletionTokens
+	c.run.progress.Usage.TotalTokens += usage.TotalTokens
+	c.run.mu.Unlock()
+
+	return resp, nil
+}
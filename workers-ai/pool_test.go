@@ -0,0 +1,166 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPoolServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	var running, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		fmt.Fprint(w, `{"success": true, "result": {"response": "ok", "usage": {"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5}}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &peak
+}
+
+func TestPool_Run(t *testing.T) {
+	server, peak := newPoolServer(t, 20*time.Millisecond)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	pool := NewPool(client, ModelLlama38B, 2)
+	pool.SystemPrompt = "You summarize documents."
+
+	var progress []PoolProgress
+	pool.OnProgress = func(p PoolProgress) { progress = append(progress, p) }
+
+	documents := []string{"a", "b", "c", "d", "e"}
+	tasks := make([]PoolTask, len(documents))
+	for i, doc := range documents {
+		doc := doc
+		tasks[i] = func(ctx context.Context, session *ChatSession) error {
+			if doc == "c" {
+				return errors.New("unreadable document")
+			}
+			_, err := session.Send("Summarize " + doc)
+			return err
+		}
+	}
+
+	result, err := pool.Run(context.Background(), tasks)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 5 tasks failed")
+	assert.Contains(t, err.Error(), "task 2: unreadable document")
+
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+	assert.Equal(t, Usage{PromptTokens: 12, CompletionTokens: 8, TotalTokens: 20}, result.Usage)
+
+	for i, session := range result.Sessions {
+		require.NotNil(t, session)
+		if i == 2 {
+			assert.Error(t, result.Errors[i])
+			assert.Empty(t, session.Messages)
+			continue
+		}
+		assert.NoError(t, result.Errors[i])
+		// Every task has its own history.
+		require.Len(t, session.Messages, 2)
+		assert.Equal(t, "Summarize "+documents[i], session.Messages[0].(ChatMessage).Content)
+		assert.Equal(t, "You summarize documents.", session.SystemPrompt)
+	}
+
+	last := progress[len(progress)-1]
+	assert.Equal(t, PoolProgress{Total: 5, Done: 5, Failed: 1, Usage: result.Usage}, last)
+}
+
+func TestPool_TokenBudget(t *testing.T) {
+	server, _ := newPoolServer(t, 0)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	pool := NewPool(client, ModelLlama38B, 1)
+	pool.TokenBudget = 10
+
+	task := func(ctx context.Context, session *ChatSession) error {
+		_, err := session.Send("Hi")
+		return err
+	}
+
+	result, err := pool.Run(context.Background(), []PoolTask{task, task, task, task})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTokenBudgetExceeded)
+	assert.NoError(t, result.Errors[0])
+	assert.NoError(t, result.Errors[1])
+	assert.ErrorIs(t, result.Errors[2], ErrTokenBudgetExceeded)
+	assert.Nil(t, result.Sessions[2], "tasks are not started once the budget is used up")
+	assert.Equal(t, 10, result.Usage.TotalTokens)
+}
+
+func TestPool_Cancel(t *testing.T) {
+	server, _ := newPoolServer(t, 0)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := NewPool(client, ModelLlama38B, 1)
+	// Slow enough that the second request waits for its slot.
+	pool.Rate = 1
+
+	tasks := []PoolTask{
+		func(ctx context.Context, session *ChatSession) error {
+			_, err := session.Send("Hi")
+			cancel()
+			return err
+		},
+		func(ctx context.Context, session *ChatSession) error {
+			_, err := session.Send("Hi")
+			return err
+		},
+	}
+
+	start := time.Now()
+	result, err := pool.Run(ctx, tasks)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.NoError(t, result.Errors[0])
+	assert.ErrorIs(t, result.Errors[1], context.Canceled)
+}
+
+func TestPool_RecoversPanics(t *testing.T) {
+	server, _ := newPoolServer(t, 0)
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	pool := NewPool(client, ModelLlama38B, 2)
+	tasks := []PoolTask{
+		func(ctx context.Context, session *ChatSession) error {
+			var m map[string]int
+			m["boom"]++
+			return nil
+		},
+		func(ctx context.Context, session *ChatSession) error {
+			_, err := session.Send("Hi")
+			return err
+		},
+	}
+
+	result, err := pool.Run(context.Background(), tasks)
+	require.Error(t, err)
+	var panicErr *PanicError
+	require.ErrorAs(t, result.Errors[0], &panicErr)
+	assert.Contains(t, panicErr.Error(), "assignment to entry in nil map")
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.NoError(t, result.Errors[1])
+}
@@ -0,0 +1,25 @@
+package workersai
+
+import "strings"
+
+// withPrefill returns request with its prefill as a trailing assistant
+// message, which chat models continue rather than answer.
+func withPrefill(request ChatCompletionRequest) ChatCompletionRequest {
+	if request.Prefill == "" {
+		return request
+	}
+	messages := make([]Message, 0, len(request.Messages)+1)
+	messages = append(messages, request.Messages...)
+	request.Messages = append(messages, ChatMessage{Role: "assistant", Content: request.Prefill})
+	return request
+}
+
+// MergePrefill joins prefill with the continuation generated after it.
+// Some models repeat the prefill at the start of their output; it is then
+// kept once.
+func MergePrefill(prefill, continuation string) string {
+	if strings.HasPrefix(continuation, prefill) {
+		return continuation
+	}
+	return prefill + continuation
+}
@@ -0,0 +1,53 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePrefill(t *testing.T) {
+	assert.Equal(t, `{"a": 1}`, MergePrefill("{", `"a": 1}`))
+	assert.Equal(t, `{"a": 1}`, MergePrefill("{", `{"a": 1}`))
+	assert.Equal(t, "", MergePrefill("", ""))
+}
+
+func TestClient_Prefill(t *testing.T) {
+	var request map[string]interface{}
+	reply := `"city": "Paris"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q}}`, reply)
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	chat := ChatCompletionRequest{
+		Model:    ModelLlama38B,
+		Messages: []Message{ChatMessage{Role: "user", Content: "Capital of France as JSON?"}},
+		Prefill:  "{",
+	}
+	resp, err := client.ChatCompletion(chat)
+	require.NoError(t, err)
+	assert.Equal(t, `{"city": "Paris"}`, resp.GetContent())
+
+	messages := request["messages"].([]interface{})
+	require.Len(t, messages, 2)
+	assert.Equal(t, map[string]interface{}{"role": "assistant", "content": "{"}, messages[1])
+	assert.Len(t, chat.Messages, 1)
+
+	// With a chat template, the prefill starts the assistant turn.
+	client.Templates = map[string]ChatTemplate{ModelLlama38B: TemplateLlama3}
+	resp, err = client.ChatCompletion(chat)
+	require.NoError(t, err)
+	assert.Equal(t, `{"city": "Paris"}`, resp.GetContent())
+	assert.Equal(t, "<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\nCapital of France as JSON?<|eot_id|>"+
+		"<|start_header_id|>assistant<|end_header_id|>\n\n{", request["prompt"])
+}
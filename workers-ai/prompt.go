@@ -0,0 +1,77 @@
+package workersai
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SystemPrompt is a system prompt made of a base text and per-environment
+// overlays, with {{name}} variables substituted at render time. It lets
+// teams keep one prompt definition for dev, staging and prod.
+type SystemPrompt struct {
+	// Base is used in every environment.
+	Base string
+	// Overlays maps an environment name to text appended to Base, separated
+	// by a blank line, when rendering for that environment.
+	Overlays map[string]string
+	// Vars are the default values of the {{name}} variables.
+	Vars map[string]string
+}
+
+// promptVar matches a {{name}} variable reference.
+var promptVar = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Render builds the prompt for env. Variables are taken from vars first and
+// then from p.Vars; referencing an undefined variable is an error. An env
+// without overlay renders the base prompt only.
+func (p SystemPrompt) Render(env string, vars map[string]string) (string, error) {
+	text := p.Base
+	if overlay := p.Overlays[env]; overlay != "" {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += overlay
+	}
+
+	var missing []string
+	rendered := promptVar.ReplaceAllStringFunc(text, func(ref string) string {
+		name := promptVar.FindStringSubmatch(ref)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if value, ok := p.Vars[name]; ok {
+			return value
+		}
+		missing = append(missing, name)
+		return ref
+	})
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", fmt.Errorf("system prompt references undefined variables: %s", strings.Join(missing, ", "))
+	}
+	return rendered, nil
+}
+
+// UseSystemPrompt renders p for env and sets it as the session's system prompt.
+func (s *ChatSession) UseSystemPrompt(p SystemPrompt, env string, vars map[string]string) error {
+	rendered, err := p.Render(env, vars)
+	if err != nil {
+		return err
+	}
+	s.SystemPrompt = rendered
+	return nil
+}
+
+// UseSystemPrompt renders p for env and sets it as the client's default
+// system prompt.
+func (c *Client) UseSystemPrompt(p SystemPrompt, env string, vars map[string]string) error {
+	rendered, err := p.Render(env, vars)
+	if err != nil {
+		return err
+	}
+	c.Defaults.SystemPrompt = rendered
+	return nil
+}
@@ -0,0 +1,55 @@
+package workersai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemPrompt_Render(t *testing.T) {
+	prompt := SystemPrompt{
+		Base: "You are the support assistant of {{ company }}.",
+		Overlays: map[string]string{
+			"dev":  "Prefix every answer with [DEV].",
+			"prod": "Never mention internal ticket {{ticket_prefix}} numbers.",
+		},
+		Vars: map[string]string{"company": "Acme", "ticket_prefix": "ACME-"},
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		vars map[string]string
+		want string
+	}{
+		{"no overlay", "staging", nil, "You are the support assistant of Acme."},
+		{"dev overlay", "dev", nil, "You are the support assistant of Acme.\n\nPrefix every answer with [DEV]."},
+		{"call vars win", "prod", map[string]string{"company": "Globex"}, "You are the support assistant of Globex.\n\nNever mention internal ticket ACME- numbers."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prompt.Render(tt.env, tt.vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSystemPrompt_Render_MissingVariable(t *testing.T) {
+	_, err := SystemPrompt{Base: "Hello {{user}} from {{team}}"}.Render("", nil)
+	require.EqualError(t, err, "system prompt references undefined variables: team, user")
+}
+
+func TestSystemPrompt_Use(t *testing.T) {
+	prompt := SystemPrompt{Base: "Base", Overlays: map[string]string{"prod": "Prod"}}
+
+	session := NewChatSession(nil, ModelLlama38B)
+	require.NoError(t, session.UseSystemPrompt(prompt, "prod", nil))
+	assert.Equal(t, "Base\n\nProd", session.SystemPrompt)
+
+	client := NewClient("test-account", "test-token")
+	require.NoError(t, client.UseSystemPrompt(prompt, "dev", nil))
+	assert.Equal(t, "Base", client.Defaults.SystemPrompt)
+}
@@ -0,0 +1,346 @@
+package workersai
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURLExpiry is how long the URLs signed by an R2Bucket are valid
+// when URLExpiry is not set.
+const DefaultURLExpiry = time.Hour
+
+// maxURLExpiry is the longest validity of a presigned URL.
+const maxURLExpiry = 7 * 24 * time.Hour
+
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat  = "20060102T150405Z"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	amzContentSHA256 = "X-Amz-Content-Sha256"
+)
+
+// Artifact is generated media uploaded to R2.
+type Artifact struct {
+	// Key is the key of the object in the bucket.
+	Key         string
+	ContentType string
+	Size        int
+	// URL is a presigned URL downloading the object until ExpiresAt.
+	URL       string
+	ExpiresAt time.Time
+}
+
+// R2Bucket uploads objects to a Cloudflare R2 bucket through its
+// S3-compatible API and signs URLs to download them, so that generated
+// images and audio can be handed back as links rather than raw bytes. The
+// credentials are those of an R2 API token, not the Workers AI token.
+type R2Bucket struct {
+	AccountID       string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Endpoint is the S3 endpoint of the account, by default
+	// https://<AccountID>.r2.cloudflarestorage.com. Objects are addressed
+	// as <Endpoint>/<Bucket>/<key>.
+	Endpoint string
+	// Region is "auto" for R2 by default.
+	Region string
+	// Prefix is prepended to the keys of the stored artifacts, e.g.
+	// "generated/".
+	Prefix string
+	// URLExpiry is the validity of the signed URLs, at most 7 days.
+	// Defaults to DefaultURLExpiry.
+	URLExpiry  time.Duration
+	HTTPClient *http.Client
+
+	now func() time.Time
+}
+
+// NewR2Bucket returns the R2 bucket called bucket of the account
+// accountID, accessed with the keys of an R2 API token.
+func NewR2Bucket(accountID, bucket, accessKeyID, secretAccessKey string) *R2Bucket {
+	return &R2Bucket{
+		AccountID:       accountID,
+		Bucket:          bucket,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+	}
+}
+
+// Put uploads data under key.
+func (b *R2Bucket) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.objectURL(key).String(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	sum := sha256.Sum256(data)
+	req.Header.Set(amzContentSHA256, hex.EncodeToString(sum[:]))
+	b.sign(req, b.time())
+
+	httpClient := b.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("failed to upload %s: %w", key, &APIError{StatusCode: resp.StatusCode, Body: string(body)})
+	}
+	return nil
+}
+
+// SignedURL returns a URL downloading the object key without credentials
+// for expiry, or URLExpiry when zero.
+func (b *R2Bucket) SignedURL(key string, expiry time.Duration) (string, error) {
+	if expiry <= 0 {
+		expiry = b.URLExpiry
+	}
+	if expiry <= 0 {
+		expiry = DefaultURLExpiry
+	}
+	if expiry > maxURLExpiry {
+		return "", fmt.Errorf("signed URLs are valid for at most %s, not %s", maxURLExpiry, expiry)
+	}
+	return b.presign(http.MethodGet, b.objectURL(key), expiry, b.time()), nil
+}
+
+// Store uploads data under a new unique key, with an extension matching
+// contentType, and returns it with a signed URL.
+func (b *R2Bucket) Store(ctx context.Context, data []byte, contentType string) (*Artifact, error) {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	key := b.Prefix + b.time().UTC().Format("2006/01/02/") + hex.EncodeToString(id[:]) + extension(contentType)
+
+	if err := b.Put(ctx, key, data, contentType); err != nil {
+		return nil, err
+	}
+	signed, err := b.SignedURL(key, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	expiry := b.URLExpiry
+	if expiry <= 0 {
+		expiry = DefaultURLExpiry
+	}
+	return &Artifact{
+		Key:         key,
+		ContentType: contentType,
+		Size:        len(data),
+		URL:         signed,
+		ExpiresAt:   b.time().Add(min(expiry, maxURLExpiry)),
+	}, nil
+}
+
+// GenerateImageURL is GenerateImage uploading the image to bucket, for
+// applications serving it as a link.
+func (c *Client) GenerateImageURL(ctx context.Context, bucket *R2Bucket, modelID, prompt string, opts *ImageOptions) (*Artifact, error) {
+	result, err := c.GenerateImage(modelID, prompt, opts)
+	if err != nil {
+		return nil, err
+	}
+	return bucket.Store(ctx, result.Image, result.ContentType)
+}
+
+// TextToSpeechURL is TextToSpeech uploading the audio to bucket, for
+// applications serving it as a link.
+func (c *Client) TextToSpeechURL(ctx context.Context, bucket *R2Bucket, modelID, text string, opts *SpeechOptions) (*Artifact, error) {
+	audio, err := c.TextToSpeech(modelID, text, opts)
+	if err != nil {
+		return nil, err
+	}
+	return bucket.Store(ctx, audio, http.DetectContentType(audio))
+}
+
+func (b *R2Bucket) objectURL(key string) *url.URL {
+	endpoint := b.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.r2.cloudflarestorage.com", b.AccountID)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		u = &url.URL{Scheme: "https", Host: endpoint}
+	}
+	u.Path = path.Join("/", u.Path, b.Bucket, key)
+	// The path is sent encoded as it is signed.
+	u.RawPath = awsEscape(u.Path, false)
+	return u
+}
+
+func (b *R2Bucket) time() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *R2Bucket) region() string {
+	if b.Region != "" {
+		return b.Region
+	}
+	return "auto"
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req, whose
+// X-Amz-Content-Sha256 header must hold the hash of the body.
+func (b *R2Bucket) sign(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format(sigV4TimeFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	signedHeaders, canonicalHeaders := canonicalHeaders(headers)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		req.Header.Get(amzContentSHA256),
+	}, "\n")
+	scope, signature := b.signature(now, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, b.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// presign returns u signed with AWS Signature Version 4 in its query, for
+// method and a validity of expiry from now.
+func (b *R2Bucket) presign(method string, u *url.URL, expiry time.Duration, now time.Time) string {
+	scope := b.scope(now)
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", b.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.UTC().Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		awsEscape(u.Path, false),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	_, signature := b.signature(now, canonicalRequest)
+
+	signed := *u
+	signed.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return signed.String()
+}
+
+func (b *R2Bucket) scope(now time.Time) string {
+	return now.UTC().Format("20060102") + "/" + b.region() + "/s3/aws4_request"
+}
+
+// signature returns the credential scope and the signature of
+// canonicalRequest.
+func (b *R2Bucket) signature(now time.Time, canonicalRequest string) (scope, signature string) {
+	scope = b.scope(now)
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{sigV4Algorithm, now.UTC().Format(sigV4TimeFormat), scope, hex.EncodeToString(hash[:])}, "\n")
+
+	hmacSHA256 := func(key []byte, data string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		return mac.Sum(nil)
+	}
+	key := []byte("AWS4" + b.SecretAccessKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalHeaders returns the signed headers list and the canonical
+// headers block of headers, keyed by lowercase name.
+func canonicalHeaders(headers map[string]string) (signed, canonical string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), sb.String()
+}
+
+// canonicalQuery encodes query sorted by name, then value, as SigV4
+// requires.
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(name, true)+"="+awsEscape(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes every byte of s but the unreserved characters,
+// and slashes unless escapeSlash.
+func awsEscape(s string, escapeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !escapeSlash {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// extension returns the usual file extension of contentType, if known.
+func extension(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/wave", "audio/wav", "audio/x-wav":
+		return ".wav"
+	}
+	if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ""
+}
@@ -0,0 +1,330 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Kinds of Interaction.
+const (
+	InteractionChat = "chat"
+	InteractionTool = "tool"
+)
+
+// ErrTraceExhausted is returned by a Replayer asked for more interactions
+// than its trace holds.
+var ErrTraceExhausted = errors.New("no more recorded interactions")
+
+// Interaction is one step of a recorded run: a chat request and its
+// response, or the execution of a tool call.
+type Interaction struct {
+	Seq      int           `json:"seq"`
+	Kind     string        `json:"kind"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+
+	// Request and Response are set for chat interactions. Response is the
+	// result of the response envelope as the caller received it, after
+	// continuations and filters.
+	Request  *ChatCompletionRequest `json:"request,omitempty"`
+	Response json.RawMessage        `json:"response,omitempty"`
+	// Tenant is the tenant of a chat request, from its Tenant field or
+	// its context. It is saved apart from the request, which doesn't
+	// encode it.
+	Tenant string `json:"tenant,omitempty"`
+
+	// ToolCall and ToolResult are set for tool interactions.
+	ToolCall   *ToolCall `json:"tool_call,omitempty"`
+	ToolResult string    `json:"tool_result,omitempty"`
+
+	// Error is the error returned by the request or the tool, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// ChatResponse decodes the recorded response of a chat interaction.
+func (i Interaction) ChatResponse() (*ChatResponse, error) {
+	envelope, err := json.Marshal(map[string]interface{}{"success": true, "result": i.Response})
+	if err != nil {
+		return nil, err
+	}
+	var response ChatResponse
+	if err := json.Unmarshal(envelope, &response); err != nil {
+		return nil, fmt.Errorf("interaction %d: failed to decode recorded response: %w", i.Seq, err)
+	}
+	return &response, nil
+}
+
+// Trace is the list of interactions of a run, in order.
+type Trace struct {
+	// ID identifies the run, as 32 hexadecimal digits like the trace IDs
+	// of OpenTelemetry.
+	ID           string        `json:"id"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadTrace reads a trace saved by Recorder.Save.
+func LoadTrace(path string) (*Trace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+	var trace Trace
+	if err := json.Unmarshal(data, &trace); err != nil {
+		return nil, fmt.Errorf("failed to parse trace %s: %w", path, err)
+	}
+	return &trace, nil
+}
+
+// Recorder captures the chat requests of an agent run, their responses and
+// the tool executions reported with RecordTool, e.g. by a ToolRunner, to
+// replay the run later with a Replayer. It wraps a client: the chat
+// methods are recorded and the other methods of ClientInterface are passed
+// through. A Recorder is safe for concurrent use.
+type Recorder struct {
+	ClientInterface
+
+	mu    sync.Mutex
+	trace Trace
+}
+
+// NewRecorder returns a recorder sending the requests through client.
+func NewRecorder(client ClientInterface) *Recorder {
+	return &Recorder{ClientInterface: client, trace: Trace{ID: newTraceID()}}
+}
+
+func (r *Recorder) Chat(modelID string, messages []Message, modelParams *ModelParameters) (*ChatResponse, error) {
+	return r.ChatWithTools(modelID, messages, nil, modelParams)
+}
+
+func (r *Recorder) ChatWithTools(modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) (*ChatResponse, error) {
+	return r.ChatCompletion(chatRequest(modelID, messages, tools, modelParams))
+}
+
+func (r *Recorder) ChatCompletion(request ChatCompletionRequest) (*ChatResponse, error) {
+	return r.ChatCompletionContext(context.Background(), request)
+}
+
+func (r *Recorder) ChatCompletionContext(ctx context.Context, request ChatCompletionRequest) (*ChatResponse, error) {
+	start := time.Now()
+	response, err := r.ClientInterface.ChatCompletionContext(ctx, request)
+
+	interaction := Interaction{
+		Kind:     InteractionChat,
+		Time:     start,
+		Duration: time.Since(start),
+		Request:  &request,
+		Tenant:   tagsFrom(request.tagContext(ctx)).tenant,
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		interaction.Response = recordedResult(response)
+	}
+	r.add(interaction)
+	return response, err
+}
+
+// RecordTool records the execution of call, which returned result or err
+// after duration.
+func (r *Recorder) RecordTool(call ToolCall, result string, err error, duration time.Duration) {
+	interaction := Interaction{
+		Kind:       InteractionTool,
+		Time:       time.Now().Add(-duration),
+		Duration:   duration,
+		ToolCall:   &call,
+		ToolResult: result,
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+	r.add(interaction)
+}
+
+func (r *Recorder) add(interaction Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	interaction.Seq = len(r.trace.Interactions) + 1
+	r.trace.Interactions = append(r.trace.Interactions, interaction)
+}
+
+// Trace returns a copy of the interactions recorded so far.
+func (r *Recorder) Trace() *Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Trace{ID: r.trace.ID, Interactions: append([]Interaction(nil), r.trace.Interactions...)}
+}
+
+// Save writes the trace recorded so far to path as indented JSON.
+func (r *Recorder) Save(path string) error {
+	data, err := json.MarshalIndent(r.Trace(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode trace: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return nil
+}
+
+// recordedResult encodes the content of resp as a result the response
+// decoder reads back. Legacy results are converted to the OpenAI format,
+// whose tool calls keep their arguments as they were received.
+func recordedResult(resp *ChatResponse) json.RawMessage {
+	result := resp.ChatCompletionResponse
+	if resp.IsLegacyResult {
+		content := resp.GetContent()
+		result = ChatCompletionResponse{
+			Choices: []Choice{{Message: ResponseMessage{Role: "assistant", Content: &content, ToolCalls: resp.GetToolCalls()}}},
+			Usage:   resp.GetUsage(),
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Replayer re-executes a recorded run without calling the API or running
+// tools: its chat methods return the recorded responses in order, and
+// ToolResult returns the recorded tool results. Step lets a debugger stop
+// at every interaction. The other methods of ClientInterface are passed to
+// the embedded client, nil by default.
+type Replayer struct {
+	ClientInterface
+
+	// Step, if set, is called with every interaction before it is
+	// replayed. Returning an error aborts the replay with that error.
+	Step func(Interaction) error
+	// Strict makes the replay fail when a request or tool call differs
+	// from the recorded one, to find where a run diverges.
+	Strict bool
+
+	mu    sync.Mutex
+	trace *Trace
+	next  int
+}
+
+// NewReplayer returns a replayer of trace.
+func NewReplayer(trace *Trace) *Replayer {
+	return &Replayer{trace: trace}
+}
+
+func (r *Replayer) Chat(modelID string, messages []Message, modelParams *ModelParameters) (*ChatResponse, error) {
+	return r.ChatWithTools(modelID, messages, nil, modelParams)
+}
+
+func (r *Replayer) ChatWithTools(modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) (*ChatResponse, error) {
+	return r.ChatCompletion(chatRequest(modelID, messages, tools, modelParams))
+}
+
+func (r *Replayer) ChatCompletion(request ChatCompletionRequest) (*ChatResponse, error) {
+	return r.ChatCompletionContext(context.Background(), request)
+}
+
+func (r *Replayer) ChatCompletionContext(ctx context.Context, request ChatCompletionRequest) (*ChatResponse, error) {
+	interaction, err := r.advance(InteractionChat)
+	if err != nil {
+		return nil, err
+	}
+	if r.Strict && !sameJSON(request, *interaction.Request) {
+		return nil, fmt.Errorf("interaction %d: request differs from the recorded one", interaction.Seq)
+	}
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+
+	response, err := interaction.ChatResponse()
+	if err != nil {
+		return nil, err
+	}
+	response.model = request.Model
+	response.latency = interaction.Duration
+	return response, nil
+}
+
+// ToolResult returns the recorded result of the next tool execution, in
+// place of running the tool.
+func (r *Replayer) ToolResult(call ToolCall) (string, error) {
+	interaction, err := r.advance(InteractionTool)
+	if err != nil {
+		return "", err
+	}
+	if r.Strict && (interaction.ToolCall.Function.Name != call.Function.Name || !sameJSON(interaction.ToolCall.Function.Arguments, call.Function.Arguments)) {
+		return "", fmt.Errorf("interaction %d: call of %s differs from the recorded call of %s", interaction.Seq, call.Function.Name, interaction.ToolCall.Function.Name)
+	}
+	if interaction.Error != "" {
+		return "", errors.New(interaction.Error)
+	}
+	return interaction.ToolResult, nil
+}
+
+// Remaining returns the number of interactions not replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.trace.Interactions) - r.next
+}
+
+// advance returns the next interaction, which must be of kind.
+func (r *Replayer) advance(kind string) (Interaction, error) {
+	r.mu.Lock()
+	if r.next >= len(r.trace.Interactions) {
+		r.mu.Unlock()
+		return Interaction{}, ErrTraceExhausted
+	}
+	interaction := r.trace.Interactions[r.next]
+	if interaction.Kind != kind {
+		r.mu.Unlock()
+		return Interaction{}, fmt.Errorf("interaction %d: expected a %s interaction, recorded %s", interaction.Seq, kind, interaction.Kind)
+	}
+	r.next++
+	r.mu.Unlock()
+
+	if r.Step != nil {
+		if err := r.Step(interaction); err != nil {
+			return Interaction{}, err
+		}
+	}
+	return interaction, nil
+}
+
+// sameJSON reports whether a and b encode to the same JSON value, since
+// recorded values are decoded from JSON. Strings holding JSON, like tool
+// call arguments, are compared as the values they hold.
+func sameJSON(a, b interface{}) bool {
+	decode := func(v interface{}) (interface{}, bool) {
+		data, ok := v.(string)
+		if !ok {
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, false
+			}
+			data = string(encoded)
+		}
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+			return data, true
+		}
+		return decoded, true
+	}
+	decodedA, okA := decode(a)
+	decodedB, okB := decode(b)
+	return okA && okB && reflect.DeepEqual(decodedA, decodedB)
+}
+
+// chatRequest builds the request of Chat and ChatWithTools.
+func chatRequest(modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) ChatCompletionRequest {
+	// The model is part of the request body in the standard spec.
+	request := ChatCompletionRequest{Model: modelID, Messages: messages, Tools: tools}
+	if modelParams != nil {
+		request.ModelParameters = *modelParams
+	}
+	return request
+}
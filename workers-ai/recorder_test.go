@@ -0,0 +1,126 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_Replay(t *testing.T) {
+	replies := []string{
+		`{"response": "", "tool_calls": [{"name": "get_weather", "arguments": {"location": "Paris"}}]}`,
+		`{"response": "It is sunny in Paris.", "usage": {"total_tokens": 12}}`,
+	}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"success": true, "result": %s}`, replies[calls])
+		calls++
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	recorder := NewRecorder(client)
+
+	// Record a run of a session calling a tool.
+	run := func(client ClientInterface, tool func(ToolCall) (string, error)) string {
+		session := NewChatSession(client, ModelLlama38B)
+		resp, err := session.Send("Weather in Paris?")
+		require.NoError(t, err)
+		for _, call := range resp.GetToolCalls() {
+			result, err := tool(call)
+			require.NoError(t, err)
+			session.AddToolResult(call.ID, result)
+		}
+		resp, err = session.Continue()
+		require.NoError(t, err)
+		return resp.GetContent()
+	}
+	content := run(recorder, func(call ToolCall) (string, error) {
+		recorder.RecordTool(call, "sunny", nil, time.Millisecond)
+		return "sunny", nil
+	})
+	assert.Equal(t, "It is sunny in Paris.", content)
+
+	trace := recorder.Trace()
+	require.Len(t, trace.Interactions, 3)
+	assert.Equal(t, []string{InteractionChat, InteractionTool, InteractionChat},
+		[]string{trace.Interactions[0].Kind, trace.Interactions[1].Kind, trace.Interactions[2].Kind})
+	assert.Equal(t, 3, trace.Interactions[2].Seq)
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	require.NoError(t, recorder.Save(path))
+	loaded, err := LoadTrace(path)
+	require.NoError(t, err)
+
+	// Replay it step by step, without server or tool.
+	replayer := NewReplayer(loaded)
+	replayer.Strict = true
+	var steps []int
+	replayer.Step = func(i Interaction) error {
+		steps = append(steps, i.Seq)
+		return nil
+	}
+	content = run(replayer, replayer.ToolResult)
+	assert.Equal(t, "It is sunny in Paris.", content)
+	assert.Equal(t, []int{1, 2, 3}, steps)
+	assert.Equal(t, 0, replayer.Remaining())
+	assert.Equal(t, 2, calls)
+
+	_, err = replayer.Chat(ModelLlama38B, nil, nil)
+	assert.ErrorIs(t, err, ErrTraceExhausted)
+}
+
+func TestReplayer_Strict(t *testing.T) {
+	trace := &Trace{Interactions: []Interaction{
+		{Seq: 1, Kind: InteractionChat, Request: &ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}}}, Response: []byte(`{"response": "Hello"}`)},
+		{Seq: 2, Kind: InteractionChat, Request: &ChatCompletionRequest{Model: ModelLlama38B}, Error: "API returned status 500: oops"},
+	}}
+
+	replayer := NewReplayer(trace)
+	replayer.Strict = true
+	_, err := replayer.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+	assert.ErrorContains(t, err, "interaction 1: request differs")
+
+	replayer = NewReplayer(trace)
+	resp, err := replayer.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", resp.GetContent())
+	_, err = replayer.ToolResult(ToolCall{})
+	assert.ErrorContains(t, err, "expected a tool interaction, recorded chat")
+	_, err = replayer.Chat(ModelLlama38B, nil, nil)
+	assert.EqualError(t, err, "API returned status 500: oops")
+}
+
+func TestRecorder_Tenant(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	recorder := NewRecorder(client)
+
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}}}
+	_, err := recorder.ChatCompletionContext(WithTenant(context.Background(), "acme"), request)
+	require.NoError(t, err)
+	request.Tenant = "globex"
+	_, err = recorder.ChatCompletion(request)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	require.NoError(t, recorder.Save(path))
+	trace, err := LoadTrace(path)
+	require.NoError(t, err)
+	require.Len(t, trace.Interactions, 2)
+	assert.Equal(t, "acme", trace.Interactions[0].Tenant)
+	assert.Equal(t, "globex", trace.Interactions[1].Tenant)
+}
@@ -0,0 +1,185 @@
+package workersai
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// States of an object or array being repaired by RepairJSON.
+const (
+	repairKey   = iota // Expecting a key, or the end of the object.
+	repairColon        // After a key.
+	repairValue        // Expecting a value.
+	repairAfter        // After a value.
+)
+
+// RepairJSON makes a best effort to turn the almost-JSON models sometimes
+// produce into valid JSON: it removes trailing commas, quotes unquoted
+// keys, converts single-quoted strings, and completes a value cut off by
+// the token limit by closing its strings, objects and arrays. Text after
+// the first complete value is dropped. It reports whether s was changed;
+// the result may still be invalid when s is too damaged.
+func RepairJSON(s string) (string, bool) {
+	if json.Valid([]byte(s)) {
+		return s, false
+	}
+	repaired := repairJSON(s)
+	return repaired, repaired != s
+}
+
+func repairJSON(s string) string {
+	type frame struct {
+		object bool
+		state  int
+	}
+	var stack []frame
+	out := make([]byte, 0, len(s)+16)
+
+	// valueDone moves the enclosing container past a value.
+	valueDone := func() {
+		if len(stack) > 0 {
+			stack[len(stack)-1].state = repairAfter
+		}
+	}
+	// trimComma removes a comma ending the output, with the spaces after
+	// it.
+	trimComma := func() {
+		trimmed := strings.TrimRight(string(out), " \t\r\n")
+		if strings.HasSuffix(trimmed, ",") {
+			out = out[:len(trimmed)-1]
+		}
+	}
+	// closeContainer ends the innermost container, completing a key without
+	// value.
+	closeContainer := func() {
+		top := stack[len(stack)-1]
+		trimComma()
+		switch {
+		case top.object && top.state == repairColon:
+			out = append(out, ":null"...)
+		case top.object && top.state == repairValue:
+			out = append(out, "null"...)
+		}
+		if top.object {
+			out = append(out, '}')
+		} else {
+			out = append(out, ']')
+		}
+		stack = stack[:len(stack)-1]
+		valueDone()
+	}
+
+	var quote byte
+	inString, escaped, started := false, false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				if c == '\'' {
+					// \' is no escape in JSON.
+					out = out[:len(out)-1]
+				}
+				out = append(out, c)
+			case c == '\\':
+				escaped = true
+				out = append(out, c)
+			case c == quote:
+				inString = false
+				out = append(out, '"')
+				if n := len(stack); n > 0 && stack[n-1].object && stack[n-1].state == repairKey {
+					stack[n-1].state = repairColon
+				} else {
+					valueDone()
+				}
+			case c == '"':
+				// A double quote in a single-quoted string.
+				out = append(out, '\\', '"')
+			case c == '\n':
+				out = append(out, '\\', 'n')
+			default:
+				out = append(out, c)
+			}
+			if !inString && started && len(stack) == 0 {
+				break
+			}
+			continue
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			out = append(out, c)
+		case c == '"' || c == '\'':
+			inString, quote, started = true, c, true
+			out = append(out, '"')
+		case c == '{' || c == '[':
+			stack = append(stack, frame{object: c == '{', state: repairValue})
+			if c == '{' {
+				stack[len(stack)-1].state = repairKey
+			}
+			started = true
+			out = append(out, c)
+		case c == '}' || c == ']':
+			if len(stack) > 0 {
+				closeContainer()
+			}
+		case c == ':':
+			out = append(out, c)
+			if n := len(stack); n > 0 {
+				stack[n-1].state = repairValue
+			}
+		case c == ',':
+			out = append(out, c)
+			if n := len(stack); n > 0 {
+				if stack[n-1].object {
+					stack[n-1].state = repairKey
+				} else {
+					stack[n-1].state = repairValue
+				}
+			}
+		case len(stack) > 0 && stack[len(stack)-1].object && stack[len(stack)-1].state == repairKey && isIdentByte(c):
+			// An unquoted key.
+			j := i
+			for j < len(s) && isIdentByte(s[j]) {
+				j++
+			}
+			out = append(out, '"')
+			out = append(out, s[i:j]...)
+			out = append(out, '"')
+			stack[len(stack)-1].state = repairColon
+			i = j - 1
+		default:
+			// Numbers and literals.
+			out = append(out, c)
+			started = true
+			if n := len(stack); n > 0 && stack[n-1].state == repairValue {
+				stack[n-1].state = repairAfter
+			}
+		}
+		if started && len(stack) == 0 && !inString && (c == '}' || c == ']') {
+			break
+		}
+	}
+
+	// Complete a value cut off.
+	if inString {
+		if escaped {
+			out = out[:len(out)-1]
+		}
+		out = append(out, '"')
+		if n := len(stack); n > 0 && stack[n-1].object && stack[n-1].state == repairKey {
+			stack[n-1].state = repairColon
+		} else {
+			valueDone()
+		}
+	}
+	for len(stack) > 0 {
+		closeContainer()
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c == '-' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
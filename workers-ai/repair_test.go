@@ -0,0 +1,62 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairJSON(t *testing.T) {
+	for input, want := range map[string]string{
+		`{"a": 1, "b": [1, 2,],}`:              `{"a": 1, "b": [1, 2]}`,
+		`{name: "Ada", last_name: 'Lovelace'}`: `{"name": "Ada", "last_name": "Lovelace"}`,
+		`{'quote': 'say "hi"', 'it\'s': 1}`:    `{"quote": "say \"hi\"", "it's": 1}`,
+		`{"story": "Once upon a ti`:            `{"story": "Once upon a ti"}`,
+		`{"items": [{"id": 1}, {"id": 2`:       `{"items": [{"id": 1}, {"id": 2}]}`,
+		`{"a": 1, "b":`:                        `{"a": 1, "b":null}`,
+		`{"a": 1, "b`:                          `{"a": 1, "b":null}`,
+		`{"a": "x\`:                            `{"a": "x"}`,
+		`{"a": 1} and some prose {"b": 2}`:     `{"a": 1}`,
+		"{\"a\": \"line\nbreak\"}":             `{"a": "line\nbreak"}`,
+	} {
+		got, repaired := RepairJSON(input)
+		assert.True(t, repaired, input)
+		assert.Equal(t, want, got, input)
+		assert.True(t, json.Valid([]byte(got)), got)
+	}
+
+	got, repaired := RepairJSON(`{"a": [1, 2]}`)
+	assert.False(t, repaired)
+	assert.Equal(t, `{"a": [1, 2]}`, got)
+}
+
+func TestClient_ChatJSON_Repair(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q}}`, "```json\n{name: 'Ada', tags: ['math',],}\n```")
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Who?"}}}
+
+	var person struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	resp, err := client.ChatJSON(request, &person, StructuredOptions{})
+	require.NoError(t, err)
+	assert.True(t, resp.Repaired)
+	assert.Equal(t, "Ada", person.Name)
+	assert.Equal(t, []string{"math"}, person.Tags)
+	assert.Equal(t, 1, calls)
+
+	_, err = client.ChatJSON(request, &person, StructuredOptions{DisableRepair: true, MaxRetries: -1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,397 @@
+package workersai
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportDay is the layout of the days of a UsageReport.
+const reportDay = "2006-01-02"
+
+// usageKey identifies a row of a UsageReport.
+type usageKey struct {
+	day, tenant, model string
+}
+
+// UsageRow is the usage of a model by a tenant on a day.
+type UsageRow struct {
+	// Day is the UTC date of the requests, as YYYY-MM-DD.
+	Day      string
+	Tenant   string
+	Model    string
+	Requests int
+	// Errors counts the requests that failed; they are included in
+	// Requests.
+	Errors int
+	Usage  Usage
+	// Neurons and USD are estimated from the pricing of the model, and
+	// are zero for models without one. USD ignores the free allocation.
+	Neurons float64
+	USD     float64
+}
+
+func (r *UsageRow) add(usage Usage, failed bool) {
+	r.Requests++
+	if failed {
+		r.Errors++
+	}
+	r.Usage.PromptTokens += usage.PromptTokens
+	r.Usage.CompletionTokens += usage.CompletionTokens
+	r.Usage.TotalTokens += usage.TotalTokens
+}
+
+// UsageReport is the usage of a client per day, tenant and model, for
+// answering "what are we spending on AI?". Write it with WriteCSV to share
+// it with a finance team.
+type UsageReport struct {
+	// Rows are sorted by day, tenant and model.
+	Rows []UsageRow
+}
+
+// Total returns the sum of the rows, with the fields identifying a row
+// left empty.
+func (r *UsageReport) Total() UsageRow {
+	var total UsageRow
+	for _, row := range r.Rows {
+		total.Requests += row.Requests
+		total.Errors += row.Errors
+		total.Usage.PromptTokens += row.Usage.PromptTokens
+		total.Usage.CompletionTokens += row.Usage.CompletionTokens
+		total.Usage.TotalTokens += row.Usage.TotalTokens
+		total.Neurons += row.Neurons
+		total.USD += row.USD
+	}
+	return total
+}
+
+// Report returns the usage recorded so far per day, tenant and model,
+// priced with pricing, which overrides and extends DefaultPricing like
+// Client.Pricing.
+func (t *UsageTracker) Report(pricing map[string]ModelPrice) *UsageReport {
+	t.mu.Lock()
+	rows := make([]UsageRow, 0, len(t.daily))
+	for _, row := range t.daily {
+		rows = append(rows, *row)
+	}
+	t.mu.Unlock()
+	return newUsageReport(rows, pricing)
+}
+
+// AuditReport returns the usage of the requests of an audit log per day,
+// tenant and model, priced like UsageTracker.Report. Read the entries with
+// ReadAuditLog.
+func AuditReport(pricing map[string]ModelPrice, entries []AuditEntry) *UsageReport {
+	daily := make(map[usageKey]*UsageRow)
+	for _, entry := range entries {
+		key := usageKey{day: entry.Time.UTC().Format(reportDay), tenant: entry.Tenant, model: entry.Model}
+		row, ok := daily[key]
+		if !ok {
+			row = &UsageRow{Day: key.day, Tenant: key.tenant, Model: key.model}
+			daily[key] = row
+		}
+		row.add(entry.Usage, entry.Error != "")
+	}
+
+	rows := make([]UsageRow, 0, len(daily))
+	for _, row := range daily {
+		rows = append(rows, *row)
+	}
+	return newUsageReport(rows, pricing)
+}
+
+// TraceReport returns the usage of the chat requests of traces per day,
+// tenant and model, priced like UsageTracker.Report.
+func TraceReport(pricing map[string]ModelPrice, traces ...*Trace) (*UsageReport, error) {
+	daily := make(map[usageKey]*UsageRow)
+	for _, trace := range traces {
+		for _, interaction := range trace.Interactions {
+			if interaction.Kind != InteractionChat || interaction.Request == nil {
+				continue
+			}
+			usage, err := interaction.usage()
+			if err != nil {
+				return nil, err
+			}
+
+			key := usageKey{
+				day:    interaction.Time.UTC().Format(reportDay),
+				tenant: interaction.tenant(),
+				model:  interaction.Request.Model,
+			}
+			row, ok := daily[key]
+			if !ok {
+				row = &UsageRow{Day: key.day, Tenant: key.tenant, Model: key.model}
+				daily[key] = row
+			}
+			row.add(usage, interaction.Error != "")
+		}
+	}
+
+	rows := make([]UsageRow, 0, len(daily))
+	for _, row := range daily {
+		rows = append(rows, *row)
+	}
+	return newUsageReport(rows, pricing), nil
+}
+
+// newUsageReport prices and sorts rows.
+func newUsageReport(rows []UsageRow, pricing map[string]ModelPrice) *UsageReport {
+	for i := range rows {
+		if price, ok := lookupPrice(pricing, rows[i].Model); ok {
+			rows[i].Neurons = price.neurons(rows[i].Usage)
+			rows[i].USD = rows[i].Neurons * NeuronPriceUSD
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		return a.Model < b.Model
+	})
+	return &UsageReport{Rows: rows}
+}
+
+// usageReportHeader is the header row of UsageReport.WriteCSV.
+var usageReportHeader = []string{
+	"day", "tenant", "model", "requests", "errors",
+	"prompt_tokens", "completion_tokens", "total_tokens", "neurons", "usd",
+}
+
+// WriteCSV writes the report as CSV, with a header row and a row per day,
+// tenant and model. Amounts use a dot as decimal separator.
+func (r *UsageReport) WriteCSV(w io.Writer) error {
+	return writeReport(w, false, usageReportHeader, r.records())
+}
+
+// WriteExcelCSV writes the report like WriteCSV, in the flavor Excel
+// opens correctly by double click: starting with a UTF-8 byte order mark,
+// so that non-ASCII tenant names are not garbled, and with CRLF line
+// endings. Cells that Excel would evaluate as formulas, e.g. a tenant
+// named "=1+1", are prefixed with a quote.
+func (r *UsageReport) WriteExcelCSV(w io.Writer) error {
+	return writeReport(w, true, usageReportHeader, r.records())
+}
+
+func (r *UsageReport) records() [][]string {
+	records := make([][]string, len(r.Rows))
+	for i, row := range r.Rows {
+		records[i] = []string{
+			row.Day,
+			row.Tenant,
+			row.Model,
+			strconv.Itoa(row.Requests),
+			strconv.Itoa(row.Errors),
+			strconv.Itoa(row.Usage.PromptTokens),
+			strconv.Itoa(row.Usage.CompletionTokens),
+			strconv.Itoa(row.Usage.TotalTokens),
+			formatNeurons(row.Neurons),
+			formatUSD(row.USD),
+		}
+	}
+	return records
+}
+
+// ConversationRow is the usage of a conversation, the chat requests and
+// tool calls of a recorded Trace.
+type ConversationRow struct {
+	// Trace is the ID of the trace.
+	Trace string
+	// Tenant is the tenant of the first chat request having one.
+	Tenant string
+	// Start and End are the times of the first and the last interaction.
+	Start, End time.Time
+	// Models are the models of the chat requests, in order of first use.
+	Models    []string
+	Requests  int
+	ToolCalls int
+	// Errors counts the chat requests and tool calls that failed.
+	Errors int
+	Usage  Usage
+	// Neurons and USD are estimated like those of a UsageRow.
+	Neurons float64
+	USD     float64
+}
+
+// ConversationReport is the usage of conversations, one row per trace, for
+// reviewing what individual conversations cost.
+type ConversationReport struct {
+	// Rows are sorted by start time.
+	Rows []ConversationRow
+}
+
+// NewConversationReport returns the usage of traces, priced like
+// UsageTracker.Report. Traces without interactions are skipped.
+func NewConversationReport(pricing map[string]ModelPrice, traces ...*Trace) (*ConversationReport, error) {
+	report := &ConversationReport{}
+	for _, trace := range traces {
+		if len(trace.Interactions) == 0 {
+			continue
+		}
+		row := ConversationRow{Trace: trace.ID}
+		for _, interaction := range trace.Interactions {
+			if row.Start.IsZero() || interaction.Time.Before(row.Start) {
+				row.Start = interaction.Time
+			}
+			if end := interaction.Time.Add(interaction.Duration); end.After(row.End) {
+				row.End = end
+			}
+			if interaction.Error != "" {
+				row.Errors++
+			}
+			if interaction.Kind == InteractionTool {
+				row.ToolCalls++
+				continue
+			}
+			if interaction.Kind != InteractionChat || interaction.Request == nil {
+				continue
+			}
+
+			usage, err := interaction.usage()
+			if err != nil {
+				return nil, err
+			}
+			row.Requests++
+			row.Usage.PromptTokens += usage.PromptTokens
+			row.Usage.CompletionTokens += usage.CompletionTokens
+			row.Usage.TotalTokens += usage.TotalTokens
+			if price, ok := lookupPrice(pricing, interaction.Request.Model); ok {
+				neurons := price.neurons(usage)
+				row.Neurons += neurons
+				row.USD += neurons * NeuronPriceUSD
+			}
+			if row.Tenant == "" {
+				row.Tenant = interaction.tenant()
+			}
+			if !slices.Contains(row.Models, interaction.Request.Model) {
+				row.Models = append(row.Models, interaction.Request.Model)
+			}
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		return report.Rows[i].Start.Before(report.Rows[j].Start)
+	})
+	return report, nil
+}
+
+// conversationReportHeader is the header row of
+// ConversationReport.WriteCSV.
+var conversationReportHeader = []string{
+	"trace", "tenant", "start", "end", "models", "requests", "tool_calls", "errors",
+	"prompt_tokens", "completion_tokens", "total_tokens", "neurons", "usd",
+}
+
+// WriteCSV writes the report as CSV, with a header row and a row per
+// conversation. Times are in UTC, as RFC 3339, and the models of a
+// conversation are separated by spaces.
+func (r *ConversationReport) WriteCSV(w io.Writer) error {
+	return writeReport(w, false, conversationReportHeader, r.records())
+}
+
+// WriteExcelCSV writes the report like WriteCSV, in the flavor of
+// UsageReport.WriteExcelCSV.
+func (r *ConversationReport) WriteExcelCSV(w io.Writer) error {
+	return writeReport(w, true, conversationReportHeader, r.records())
+}
+
+func (r *ConversationReport) records() [][]string {
+	records := make([][]string, len(r.Rows))
+	for i, row := range r.Rows {
+		records[i] = []string{
+			row.Trace,
+			row.Tenant,
+			row.Start.UTC().Format(time.RFC3339),
+			row.End.UTC().Format(time.RFC3339),
+			strings.Join(row.Models, " "),
+			strconv.Itoa(row.Requests),
+			strconv.Itoa(row.ToolCalls),
+			strconv.Itoa(row.Errors),
+			strconv.Itoa(row.Usage.PromptTokens),
+			strconv.Itoa(row.Usage.CompletionTokens),
+			strconv.Itoa(row.Usage.TotalTokens),
+			formatNeurons(row.Neurons),
+			formatUSD(row.USD),
+		}
+	}
+	return records
+}
+
+// usage returns the usage of a chat interaction, zero if it failed.
+func (i Interaction) usage() (Usage, error) {
+	if i.Error != "" {
+		return Usage{}, nil
+	}
+	response, err := i.ChatResponse()
+	if err != nil {
+		return Usage{}, err
+	}
+	return response.GetUsage(), nil
+}
+
+// tenant returns the tenant of a chat interaction. Traces saved before
+// Interaction.Tenant existed only have the tenant of requests recorded
+// in memory.
+func (i Interaction) tenant() string {
+	if i.Tenant == "" && i.Request != nil {
+		return i.Request.Tenant
+	}
+	return i.Tenant
+}
+
+func formatNeurons(neurons float64) string {
+	return strconv.FormatFloat(neurons, 'f', 2, 64)
+}
+
+func formatUSD(usd float64) string {
+	return strconv.FormatFloat(usd, 'f', 6, 64)
+}
+
+// writeReport writes header and records as CSV, in the flavor of
+// UsageReport.WriteExcelCSV if excel is set.
+func writeReport(w io.Writer, excel bool, header []string, records [][]string) error {
+	writer := csv.NewWriter(w)
+	if excel {
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		writer.UseCRLF = true
+	}
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	for _, record := range records {
+		if excel {
+			for i, cell := range record {
+				record[i] = escapeFormula(cell)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// escapeFormula prefixes cell with a quote if it starts like a formula,
+// so that spreadsheets show it as text instead of evaluating it.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,167 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageTracker_Report(t *testing.T) {
+	tracker := NewUsageTracker()
+	day := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return day }
+
+	record := tracker.Hooks().AfterResponse
+	record(RequestEvent{Model: ModelLlama38B, Tenant: "acme", Usage: Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}})
+	record(RequestEvent{Model: ModelLlama38B, Tenant: "acme", Usage: Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}})
+	record(RequestEvent{Model: "custom/model", Tenant: "acme", Err: errors.New("boom")})
+	day = day.Add(2 * time.Hour)
+	record(RequestEvent{Model: ModelLlama38B, Usage: Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}})
+
+	report := tracker.Report(map[string]ModelPrice{"custom/model": {InputNeurons: 1000}})
+	require.Len(t, report.Rows, 3)
+
+	llama := report.Rows[0]
+	assert.Equal(t, "2025-06-01", llama.Day)
+	assert.Equal(t, ModelLlama38B, llama.Model)
+	assert.Equal(t, 2, llama.Requests)
+	assert.Equal(t, Usage{PromptTokens: 2000, CompletionTokens: 1000, TotalTokens: 3000}, llama.Usage)
+	assert.InDelta(t, (2000*25608.0+1000*75147.0)/1e6, llama.Neurons, 1e-9)
+	assert.InDelta(t, llama.Neurons*NeuronPriceUSD, llama.USD, 1e-12)
+	assert.Equal(t, UsageRow{Day: "2025-06-01", Tenant: "acme", Model: "custom/model", Requests: 1, Errors: 1}, report.Rows[1])
+	assert.Equal(t, "2025-06-02", report.Rows[2].Day)
+	assert.Equal(t, "", report.Rows[2].Tenant)
+
+	total := report.Total()
+	assert.Equal(t, 4, total.Requests)
+	assert.Equal(t, 1, total.Errors)
+	assert.Equal(t, 3015, total.Usage.TotalTokens)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "day,tenant,model,requests,errors,prompt_tokens,completion_tokens,total_tokens,neurons,usd", lines[0])
+	assert.Equal(t, "2025-06-01,acme,@cf/meta/llama-3-8b-instruct,2,0,2000,1000,3000,126.36,0.001390", lines[1])
+	assert.Equal(t, "2025-06-01,acme,custom/model,1,1,0,0,0,0.00,0.000000", lines[2])
+
+	buf.Reset()
+	require.NoError(t, report.WriteExcelCSV(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "\uFEFFday,tenant,"))
+	assert.Contains(t, buf.String(), "usd\r\n")
+}
+
+func TestUsageReport_WriteExcelCSVEscapesFormulas(t *testing.T) {
+	report := &UsageReport{Rows: []UsageRow{
+		{Day: "2025-06-01", Tenant: "=HYPERLINK(\"http://evil\")", Model: "@cf/meta/llama-3-8b-instruct"},
+		{Day: "2025-06-01", Tenant: "+1", Model: "-m"},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteExcelCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\r\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, `2025-06-01,"'=HYPERLINK(""http://evil"")",'@cf/meta/llama-3-8b-instruct,0,0,0,0,0,0.00,0.000000`, lines[1])
+	assert.Equal(t, "2025-06-01,'+1,'-m,0,0,0,0,0,0.00,0.000000", lines[2])
+
+	// Plain CSV is left as is.
+	buf.Reset()
+	require.NoError(t, report.WriteCSV(&buf))
+	assert.Contains(t, buf.String(), "2025-06-01,+1,-m,")
+}
+
+func TestAuditReport(t *testing.T) {
+	day := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	report := AuditReport(nil, []AuditEntry{
+		{Time: day, Tenant: "acme", Model: ModelLlama38B, Usage: Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}},
+		{Time: day.Add(time.Hour), Tenant: "acme", Model: ModelLlama38B, Error: "boom"},
+		{Time: day.Add(24 * time.Hour), Model: ModelLlama38B},
+	})
+	require.Len(t, report.Rows, 2)
+	assert.Equal(t, "2025-06-01", report.Rows[0].Day)
+	assert.Equal(t, 2, report.Rows[0].Requests)
+	assert.Equal(t, 1, report.Rows[0].Errors)
+	assert.Equal(t, 3, report.Rows[0].Usage.TotalTokens)
+	assert.Greater(t, report.Rows[0].USD, 0.0)
+	assert.Equal(t, "2025-06-02", report.Rows[1].Day)
+}
+
+func TestTraceReport(t *testing.T) {
+	day := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	trace := &Trace{Interactions: []Interaction{
+		{
+			Kind:     InteractionChat,
+			Time:     day,
+			Request:  &ChatCompletionRequest{Model: ModelLlama38B},
+			Tenant:   "acme",
+			Response: json.RawMessage(`{"response": "Hi", "usage": {"prompt_tokens": 2, "completion_tokens": 1, "total_tokens": 3}}`),
+		},
+		{Kind: InteractionTool, Time: day, ToolCall: &ToolCall{}},
+		{
+			Kind:    InteractionChat,
+			Time:    day,
+			Request: &ChatCompletionRequest{Model: ModelLlama38B},
+			Tenant:  "acme",
+			Error:   "boom",
+		},
+	}}
+
+	// The tenants survive saving the trace.
+	data, err := json.Marshal(trace)
+	require.NoError(t, err)
+	trace = &Trace{}
+	require.NoError(t, json.Unmarshal(data, trace))
+
+	report, err := TraceReport(nil, trace)
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 1)
+	row := report.Rows[0]
+	assert.Equal(t, "acme", row.Tenant)
+	assert.Equal(t, 2, row.Requests)
+	assert.Equal(t, 1, row.Errors)
+	assert.Equal(t, Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}, row.Usage)
+	assert.Greater(t, row.USD, 0.0)
+}
+
+func TestNewConversationReport(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	response := json.RawMessage(`{"response": "Hi", "usage": {"prompt_tokens": 2, "completion_tokens": 1, "total_tokens": 3}}`)
+	later := &Trace{ID: "later", Interactions: []Interaction{
+		{Kind: InteractionChat, Time: start.Add(time.Hour), Request: &ChatCompletionRequest{Model: ModelLlama38B}, Response: response},
+	}}
+	first := &Trace{ID: "first", Interactions: []Interaction{
+		{Kind: InteractionChat, Time: start, Duration: time.Second, Request: &ChatCompletionRequest{Model: ModelLlama38B}, Tenant: "acme", Response: response},
+		{Kind: InteractionTool, Time: start.Add(time.Second), Duration: time.Second, ToolCall: &ToolCall{}, Error: "tool failed"},
+		{Kind: InteractionChat, Time: start.Add(2 * time.Second), Duration: time.Second, Request: &ChatCompletionRequest{Model: "custom/model"}, Response: response},
+	}}
+
+	report, err := NewConversationReport(map[string]ModelPrice{"custom/model": {InputNeurons: 1000}}, later, &Trace{ID: "empty"}, first)
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 2)
+
+	row := report.Rows[0]
+	assert.Equal(t, "first", row.Trace)
+	assert.Equal(t, "acme", row.Tenant)
+	assert.Equal(t, start, row.Start)
+	assert.Equal(t, start.Add(3*time.Second), row.End)
+	assert.Equal(t, []string{ModelLlama38B, "custom/model"}, row.Models)
+	assert.Equal(t, 2, row.Requests)
+	assert.Equal(t, 1, row.ToolCalls)
+	assert.Equal(t, 1, row.Errors)
+	assert.Equal(t, 6, row.Usage.TotalTokens)
+	assert.Greater(t, row.Neurons, 0.0)
+	assert.Equal(t, "later", report.Rows[1].Trace)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "trace,tenant,start,end,models,requests,tool_calls,errors,prompt_tokens,completion_tokens,total_tokens,neurons,usd", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "first,acme,2025-06-01T12:00:00Z,2025-06-01T12:00:03Z,@cf/meta/llama-3-8b-instruct custom/model,2,1,1,4,2,6,"), lines[1])
+}
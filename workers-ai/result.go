@@ -0,0 +1,77 @@
+package workersai
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Result is implemented by the results of every task, so that middleware,
+// metrics and caches can handle chat, embedding, image and audio results
+// alike.
+type Result interface {
+	// Usage is the token usage reported by the model, zero if none.
+	Usage() Usage
+	// Raw is the "result" field of the response as received, nil for
+	// binary outputs.
+	Raw() json.RawMessage
+	// Model is the model that produced the result.
+	Model() string
+	// Latency is the time the request took, retries included.
+	Latency() time.Duration
+}
+
+var (
+	_ Result = (*ChatResponse)(nil)
+	_ Result = (*EmbeddingResult)(nil)
+	_ Result = (*ImageResult)(nil)
+	_ Result = (*TranscriptionResult)(nil)
+)
+
+// resultMeta implements Result for the task results embedding it.
+type resultMeta struct {
+	model   string
+	latency time.Duration
+	raw     json.RawMessage
+	usage   Usage
+}
+
+// newResultMeta describes the result of the response body of a request to
+// modelID.
+func newResultMeta(modelID string, latency time.Duration, body []byte) resultMeta {
+	meta := resultMeta{model: modelID, latency: latency}
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if json.Unmarshal(body, &envelope) == nil {
+		meta.raw = envelope.Result
+		meta.usage = responseUsage(body)
+	}
+	return meta
+}
+
+func (m *resultMeta) Usage() Usage                  { return m.usage }
+func (m *resultMeta) Raw() json.RawMessage          { return m.raw }
+func (m *resultMeta) Model() string                 { return m.model }
+func (m *resultMeta) Latency() time.Duration        { return m.latency }
+func (m *resultMeta) setResultMeta(meta resultMeta) { *m = meta }
+
+// resultMetaSetter is implemented by the task results embedding
+// resultMeta.
+type resultMetaSetter interface {
+	setResultMeta(meta resultMeta)
+}
+
+// Usage returns the token usage of the response; it is GetUsage.
+func (r *ChatResponse) Usage() Usage {
+	return r.GetUsage()
+}
+
+// Model returns the model the request was sent to.
+func (r *ChatResponse) Model() string {
+	return r.model
+}
+
+// Latency returns the time the request took, retries included.
+func (r *ChatResponse) Latency() time.Duration {
+	return r.latency
+}
@@ -0,0 +1,62 @@
+package workersai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n fake image")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		model := strings.TrimPrefix(r.URL.Path, "/accounts/test-account/ai/run/")
+		switch model {
+		case ModelLlama38B:
+			fmt.Fprint(w, `{"success": true, "result": {"response": "Hi", "usage": {"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4}}}`)
+		case ModelBAAI:
+			fmt.Fprint(w, `{"success": true, "result": {"shape": [1, 2], "data": [[0.1, 0.2]]}}`)
+		case ModelWhisper:
+			fmt.Fprint(w, `{"success": true, "result": {"text": "Hello"}}`)
+		case ModelStableDiffusion:
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	chat, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	embedding, err := client.Embed(ModelBAAI, []string{"Hi"}, nil)
+	require.NoError(t, err)
+	transcription, err := client.Transcribe(ModelWhisper, []byte("audio"))
+	require.NoError(t, err)
+	image, err := client.GenerateImage(ModelStableDiffusion, "a cat", nil)
+	require.NoError(t, err)
+
+	results := map[string]Result{
+		ModelLlama38B:        chat,
+		ModelBAAI:            embedding,
+		ModelWhisper:         transcription,
+		ModelStableDiffusion: image,
+	}
+	for model, result := range results {
+		assert.Equal(t, model, result.Model())
+		assert.Positive(t, result.Latency(), model)
+	}
+
+	assert.Equal(t, Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}, chat.Usage())
+	assert.Equal(t, Usage{}, embedding.Usage())
+	assert.JSONEq(t, `{"shape": [1, 2], "data": [[0.1, 0.2]]}`, string(embedding.Raw()))
+	assert.JSONEq(t, `{"text": "Hello"}`, string(transcription.Raw()))
+	assert.Nil(t, image.Raw())
+	assert.Equal(t, png, image.Image)
+	assert.Equal(t, "image/png", image.ContentType)
+}
@@ -0,0 +1,103 @@
+package workersai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default backoffs of a RetryPolicy.
+const (
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 30 * time.Second
+)
+
+// RetryPolicy configures how a Client retries requests that failed with a
+// retryable error, as classified by IsRetryable: a network error, a rate
+// limit, a capacity error or a 5xx status.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero
+	// disables retries.
+	MaxRetries int `yaml:"max_retries"`
+	// InitialBackoff is the wait before the first retry; it doubles with
+	// every further retry. Defaults to DefaultInitialBackoff.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	// MaxBackoff caps the wait between retries, including waits asked for
+	// with Retry-After. Defaults to DefaultMaxBackoff.
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// APIError is returned when the API answered with an error status.
+type APIError struct {
+	StatusCode int
+	// Body is the raw response body.
+	Body string
+	// Errors are the entries of the "errors" array of the response
+	// envelope, if the body is one.
+	Errors []APIMessage
+	// RetryAfter is the wait asked for by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// HasCode reports whether the API reported an error with code.
+func (e *APIError) HasCode(code int) bool {
+	for _, msg := range e.Errors {
+		if msg.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	err := &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+
+	var envelope struct {
+		Errors []APIMessage `json:"errors"`
+	}
+	if json.Unmarshal(body, &envelope) == nil {
+		err.Errors = envelope.Errors
+	}
+
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+// backoff returns the wait before retry number attempt+1: an exponential
+// backoff with jitter, or the Retry-After of err when that is longer.
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	initial, max := p.InitialBackoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+
+	wait := initial << uint(attempt)
+	if wait <= 0 || wait > max {
+		wait = max
+	}
+	// Equal jitter: wait between half and all of the backoff, so that
+	// clients failing together don't retry together.
+	wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+		wait = apiErr.RetryAfter
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
@@ -0,0 +1,84 @@
+package workersai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Retry(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[attempts]
+		attempts++
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"success": false, "errors": ["try again"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Retry = RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond}
+
+	var events []RequestEvent
+	client.Use(Hooks{AfterResponse: func(event RequestEvent) { events = append(events, event) }})
+
+	resp, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", resp.GetContent())
+	assert.Equal(t, 3, attempts)
+	require.Len(t, events, 3, "every attempt is reported")
+	assert.Equal(t, http.StatusServiceUnavailable, events[0].StatusCode)
+
+	statuses, attempts = []int{http.StatusBadRequest}, 0
+	_, err = client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hello"}}, nil)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, 1, attempts, "client errors are not retried")
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second} {
+		wait := policy.backoff(attempt, errors.New("connection reset"))
+		assert.GreaterOrEqual(t, wait, max/2)
+		assert.LessOrEqual(t, wait, max)
+	}
+
+	rateLimited := &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 700 * time.Millisecond}
+	assert.Equal(t, 700*time.Millisecond, policy.backoff(0, rateLimited))
+
+	rateLimited.RetryAfter = time.Minute
+	assert.Equal(t, time.Second, policy.backoff(0, rateLimited))
+}
+
+func TestAPIError_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"success": false, "errors": [{"code": 3040, "message": "Capacity temporarily exceeded"}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	_, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, []APIMessage{{Code: 3040, Message: "Capacity temporarily exceeded"}}, apiErr.Errors)
+	assert.True(t, apiErr.HasCode(3040))
+	assert.Equal(t, "3040: Capacity temporarily exceeded", apiErr.Errors[0].String())
+}
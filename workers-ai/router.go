@@ -0,0 +1,203 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Tasks of the models in the catalog, named like ModelInfo.Task.Name.
+const (
+	TaskTextGeneration = "Text Generation"
+	TaskTextEmbeddings = "Text Embeddings"
+	TaskImageToText    = "Image-to-Text"
+	TaskTextToImage    = "Text-to-Image"
+	TaskTextToSpeech   = "Text-to-Speech"
+	TaskSpeechToText   = "Automatic Speech Recognition"
+	TaskTranslation    = "Translation"
+)
+
+// CostTier classes models by their price per output token, or per input
+// token for models without output tokens.
+type CostTier int
+
+const (
+	// CostTierAny doesn't limit the cost in Constraints.
+	CostTierAny CostTier = iota
+	CostTierLow
+	CostTierMedium
+	CostTierHigh
+)
+
+// Upper bounds of the cost tiers, in neurons per million tokens.
+const (
+	lowCostNeurons    = 20000
+	mediumCostNeurons = 50000
+)
+
+// ErrNoModel is returned by Router.Select when no model of the catalog
+// meets the constraints.
+var ErrNoModel = errors.New("no model meets the constraints")
+
+// ModelSpec describes the capabilities of a model for routing.
+type ModelSpec struct {
+	ID   string
+	Task string
+	// ContextWindow is the number of tokens of the input and output
+	// together, zero when it doesn't apply.
+	ContextWindow   int
+	FunctionCalling bool
+	Vision          bool
+	// JSONMode reports support for ChatCompletionRequest.ResponseFormat.
+	JSONMode bool
+}
+
+// DefaultCatalog lists the capabilities of the models of this package, as
+// documented by Workers AI as of mid-2025.
+var DefaultCatalog = []ModelSpec{
+	{ID: ModelLlama4Scout17B, Task: TaskTextGeneration, ContextWindow: 131000, FunctionCalling: true, Vision: true, JSONMode: true},
+	{ID: ModelLlama38B, Task: TaskTextGeneration, ContextWindow: 7968, JSONMode: true},
+	{ID: ModelLlama370B, Task: TaskTextGeneration, ContextWindow: 8192},
+	{ID: ModelMistral7B, Task: TaskTextGeneration, ContextWindow: 2824},
+	{ID: ModelCodeLlama7B, Task: TaskTextGeneration, ContextWindow: 4096},
+	{ID: ModelQwen330ba3b, Task: TaskTextGeneration, ContextWindow: 32768, FunctionCalling: true},
+	{ID: ModelLlama32Vision11B, Task: TaskTextGeneration, ContextWindow: 128000, Vision: true},
+	{ID: ModelLlava15, Task: TaskImageToText, Vision: true},
+	{ID: ModelUFormGen2, Task: TaskImageToText, Vision: true},
+	{ID: ModelStableDiffusion, Task: TaskTextToImage},
+	{ID: ModelDreamshaper, Task: TaskTextToImage},
+	{ID: ModelSpeechT5, Task: TaskTextToSpeech},
+	{ID: ModelMeloTTS, Task: TaskTextToSpeech},
+	{ID: ModelWhisper, Task: TaskSpeechToText},
+	{ID: ModelWhisperLargeV3Turbo, Task: TaskSpeechToText},
+	{ID: ModelBAAI, Task: TaskTextEmbeddings, ContextWindow: 512},
+	{ID: ModelBAAILarge, Task: TaskTextEmbeddings, ContextWindow: 512},
+	{ID: ModelM2M100, Task: TaskTranslation},
+}
+
+// Constraints describe the model a caller needs. Zero fields don't
+// constrain the choice.
+type Constraints struct {
+	Task string
+	// MinContext is the number of tokens the context window must hold.
+	MinContext      int
+	FunctionCalling bool
+	Vision          bool
+	// MaxCostTier excludes the models of higher tiers, and the models
+	// without known pricing.
+	MaxCostTier CostTier
+}
+
+// Router picks models by their capabilities, so that code states what it
+// needs rather than hard-coding model IDs. Among the models meeting the
+// constraints it picks the cheapest, then the one with the largest context
+// window.
+type Router struct {
+	Client *Client
+	// Catalog is the list of models to choose from. Defaults to
+	// DefaultCatalog.
+	Catalog []ModelSpec
+}
+
+// NewRouter returns a router choosing among DefaultCatalog with the
+// pricing of client.
+func NewRouter(client *Client) *Router {
+	return &Router{Client: client}
+}
+
+// CostTier returns the cost tier of modelID, or CostTierAny when its price
+// isn't known.
+func (r *Router) CostTier(modelID string) CostTier {
+	price, ok := r.Client.price(modelID)
+	if !ok {
+		return CostTierAny
+	}
+	neurons := price.OutputNeurons
+	if neurons == 0 {
+		neurons = price.InputNeurons
+	}
+	switch {
+	case neurons <= lowCostNeurons:
+		return CostTierLow
+	case neurons <= mediumCostNeurons:
+		return CostTierMedium
+	}
+	return CostTierHigh
+}
+
+// Candidates returns the models meeting c, best first.
+func (r *Router) Candidates(c Constraints) []ModelSpec {
+	catalog := r.Catalog
+	if catalog == nil {
+		catalog = DefaultCatalog
+	}
+
+	var candidates []ModelSpec
+	for _, spec := range catalog {
+		if c.Task != "" && spec.Task != c.Task ||
+			c.MinContext > 0 && spec.ContextWindow < c.MinContext ||
+			c.FunctionCalling && !spec.FunctionCalling ||
+			c.Vision && !spec.Vision {
+			continue
+		}
+		if c.MaxCostTier != CostTierAny {
+			if tier := r.CostTier(spec.ID); tier == CostTierAny || tier > c.MaxCostTier {
+				continue
+			}
+		}
+		candidates = append(candidates, spec)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := r.cost(candidates[i].ID), r.cost(candidates[j].ID)
+		if a != b {
+			return a < b
+		}
+		return candidates[i].ContextWindow > candidates[j].ContextWindow
+	})
+	return candidates
+}
+
+// cost orders models by price, those without known pricing last.
+func (r *Router) cost(modelID string) float64 {
+	price, ok := r.Client.price(modelID)
+	if !ok {
+		return 1e18
+	}
+	return price.InputNeurons + price.OutputNeurons
+}
+
+// Select returns the ID of the best model meeting c.
+func (r *Router) Select(c Constraints) (string, error) {
+	candidates := r.Candidates(c)
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w: %+v", ErrNoModel, c)
+	}
+	return candidates[0].ID, nil
+}
+
+// ChatCompletion sends request to the best text generation model meeting
+// c. The constraints implied by request are added: function calling when
+// it has tools, and a context window holding its messages and output.
+func (r *Router) ChatCompletion(ctx context.Context, request ChatCompletionRequest, c Constraints) (*ChatResponse, error) {
+	c.Task = TaskTextGeneration
+	if len(request.Tools) > 0 {
+		c.FunctionCalling = true
+	}
+	if c.MinContext == 0 {
+		estimate, err := r.Client.estimate(request)
+		if err != nil {
+			return nil, err
+		}
+		c.MinContext = estimate.InputTokens + estimate.OutputTokens
+	}
+
+	model, err := r.Select(c)
+	if err != nil {
+		return nil, err
+	}
+	r.Client.debugLog("Routed request to %s", model)
+	request.Model = model
+	return r.Client.ChatCompletionContext(ctx, request)
+}
@@ -0,0 +1,88 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Select(t *testing.T) {
+	router := NewRouter(NewClient("test-account", "test-token"))
+
+	tests := []struct {
+		name        string
+		constraints Constraints
+		want        string
+	}{
+		{"cheapest text generation", Constraints{Task: TaskTextGeneration}, ModelMistral7B},
+		{"long context", Constraints{Task: TaskTextGeneration, MinContext: 16000}, ModelQwen330ba3b},
+		{"function calling", Constraints{Task: TaskTextGeneration, FunctionCalling: true}, ModelQwen330ba3b},
+		{"vision and tools", Constraints{Task: TaskTextGeneration, FunctionCalling: true, Vision: true}, ModelLlama4Scout17B},
+		{"embeddings", Constraints{Task: TaskTextEmbeddings}, ModelBAAI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := router.Select(tt.constraints)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, model)
+		})
+	}
+
+	_, err := router.Select(Constraints{Task: TaskTextGeneration, MinContext: 100000, MaxCostTier: CostTierMedium})
+	assert.ErrorIs(t, err, ErrNoModel)
+}
+
+func TestRouter_CostTier(t *testing.T) {
+	client := NewClient("test-account", "test-token")
+	router := NewRouter(client)
+
+	assert.Equal(t, CostTierLow, router.CostTier(ModelMistral7B))
+	assert.Equal(t, CostTierMedium, router.CostTier(ModelQwen330ba3b))
+	assert.Equal(t, CostTierHigh, router.CostTier(ModelLlama38B))
+	assert.Equal(t, CostTierAny, router.CostTier(ModelCodeLlama7B))
+
+	// Models without pricing are left out when the cost is limited.
+	for _, spec := range router.Candidates(Constraints{Task: TaskTextGeneration, MaxCostTier: CostTierHigh}) {
+		assert.NotEqual(t, ModelCodeLlama7B, spec.ID)
+	}
+
+	// Client pricing changes the choice.
+	client.Pricing = map[string]ModelPrice{ModelLlama38B: {InputNeurons: 1000, OutputNeurons: 2000}}
+	model, err := router.Select(Constraints{Task: TaskTextGeneration, MaxCostTier: CostTierLow})
+	require.NoError(t, err)
+	assert.Equal(t, ModelLlama38B, model)
+}
+
+func TestRouter_ChatCompletion(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		models = append(models, strings.TrimPrefix(r.URL.Path, "/accounts/test-account/ai/run/"))
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	router := &Router{Client: client}
+
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+	_, err := router.ChatCompletion(context.Background(), ChatCompletionRequest{Messages: messages}, Constraints{})
+	require.NoError(t, err)
+
+	tools := []Tool{{Type: "function", Function: FunctionDefinition{Name: "lookup"}}}
+	_, err = router.ChatCompletion(context.Background(), ChatCompletionRequest{Messages: messages, Tools: tools}, Constraints{})
+	require.NoError(t, err)
+
+	// A long conversation needs a larger context window.
+	long := []Message{ChatMessage{Role: "user", Content: strings.Repeat("word ", 20000)}}
+	_, err = router.ChatCompletion(context.Background(), ChatCompletionRequest{Messages: long}, Constraints{MaxCostTier: CostTierMedium})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{ModelMistral7B, ModelQwen330ba3b, ModelQwen330ba3b}, models)
+}
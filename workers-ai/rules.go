@@ -0,0 +1,148 @@
+package workersai
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FilterMatch is a span of a response matched by a FilterRule.
+type FilterMatch struct {
+	// Rule is the name of the matching rule.
+	Rule string
+	// Start and End are the byte offsets of the match in the content.
+	Start, End int
+	Text       string
+	// Action is the action of the rule.
+	Action FilterAction
+}
+
+// FilterRule is a rule of a RuleFilter. Either Pattern or Func finds its
+// matches.
+type FilterRule struct {
+	// Name identifies the rule in verdicts and matches, e.g. "profanity".
+	Name    string
+	Pattern *regexp.Regexp
+	// Func returns the spans of content it matches, as [start, end] byte
+	// offsets, for checks a pattern can't express.
+	Func   func(content string) [][2]int
+	Action FilterAction
+	// Mask replaces the matches when Action is FilterRedact. Defaults to
+	// Redacted.
+	Mask string
+}
+
+// WordlistRule returns a rule matching the given words case-insensitively
+// on word boundaries, e.g. a profanity list.
+func WordlistRule(name string, action FilterAction, words ...string) FilterRule {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	// Longer words first, so that alternatives sharing a prefix match in
+	// full.
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return FilterRule{
+		Name:    name,
+		Pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+		Action:  action,
+	}
+}
+
+// RuleFilter is a ResponseFilter made of rules with their own action,
+// matching regular expressions, wordlists or custom functions. Its verdict
+// takes the most severe action of the matching rules, block over redact
+// over annotate, and carries every match in Matches so that callers can
+// log them or decide for themselves.
+type RuleFilter struct {
+	// Name is the name of the filter in verdicts. Defaults to "rules".
+	Name  string
+	Rules []FilterRule
+}
+
+// NewRuleFilter returns a filter applying rules.
+func NewRuleFilter(rules ...FilterRule) *RuleFilter {
+	return &RuleFilter{Rules: rules}
+}
+
+// Match returns the matches of the rules in content, in order of
+// position.
+func (f *RuleFilter) Match(content string) []FilterMatch {
+	var matches []FilterMatch
+	for _, rule := range f.Rules {
+		var spans [][2]int
+		if rule.Pattern != nil {
+			for _, loc := range rule.Pattern.FindAllStringIndex(content, -1) {
+				spans = append(spans, [2]int{loc[0], loc[1]})
+			}
+		}
+		if rule.Func != nil {
+			spans = append(spans, rule.Func(content)...)
+		}
+		for _, span := range spans {
+			if span[0] < 0 || span[1] > len(content) || span[0] >= span[1] {
+				continue
+			}
+			matches = append(matches, FilterMatch{
+				Rule:   rule.Name,
+				Start:  span[0],
+				End:    span[1],
+				Text:   content[span[0]:span[1]],
+				Action: rule.Action,
+			})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+func (f *RuleFilter) Check(content string) (FilterVerdict, error) {
+	name := f.Name
+	if name == "" {
+		name = "rules"
+	}
+	verdict := FilterVerdict{Filter: name, Matches: f.Match(content)}
+
+	seen := make(map[string]bool)
+	for _, match := range verdict.Matches {
+		if !seen[match.Rule] {
+			seen[match.Rule] = true
+			verdict.Categories = append(verdict.Categories, match.Rule)
+		}
+		verdict.Action = max(verdict.Action, match.Action)
+	}
+	if verdict.Action == FilterRedact {
+		verdict.Replacement = f.mask(content, verdict.Matches)
+	}
+	return verdict, nil
+}
+
+// mask replaces the spans of the redacting matches with the masks of their
+// rules. Overlapping spans are masked once.
+func (f *RuleFilter) mask(content string, matches []FilterMatch) string {
+	masks := make(map[string]string, len(f.Rules))
+	for _, rule := range f.Rules {
+		masks[rule.Name] = rule.Mask
+	}
+
+	var sb strings.Builder
+	end := 0
+	for _, match := range matches {
+		if match.Action != FilterRedact {
+			continue
+		}
+		if match.Start < end {
+			end = max(end, match.End)
+			continue
+		}
+		sb.WriteString(content[end:match.Start])
+		mask := masks[match.Rule]
+		if mask == "" {
+			mask = Redacted
+		}
+		sb.WriteString(mask)
+		end = match.End
+	}
+	sb.WriteString(content[end:])
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+package workersai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleFilter(t *testing.T) {
+	filter := NewRuleFilter(
+		WordlistRule("profanity", FilterRedact, "darn", "heck"),
+		FilterRule{Name: "phone", Pattern: regexp.MustCompile(`\d{3}-\d{4}`), Action: FilterRedact, Mask: "[phone]"},
+		FilterRule{Name: "shouting", Action: FilterAnnotate, Func: func(content string) [][2]int {
+			if i := strings.Index(content, "!!!"); i >= 0 {
+				return [][2]int{{i, i + 3}}
+			}
+			return nil
+		}},
+	)
+
+	verdict, err := filter.Check("Darn, call 555-0100!!! What the heck.")
+	require.NoError(t, err)
+	assert.Equal(t, "rules", verdict.Filter)
+	assert.Equal(t, FilterRedact, verdict.Action)
+	assert.Equal(t, []string{"profanity", "phone", "shouting"}, verdict.Categories)
+	assert.Equal(t, Redacted+", call [phone]!!! What the "+Redacted+".", verdict.Replacement)
+	require.Len(t, verdict.Matches, 4)
+	assert.Equal(t, FilterMatch{Rule: "phone", Start: 11, End: 19, Text: "555-0100", Action: FilterRedact}, verdict.Matches[1])
+	assert.Equal(t, "shouting", verdict.Matches[2].Rule)
+
+	verdict, err = filter.Check("All good!!!")
+	require.NoError(t, err)
+	assert.Equal(t, FilterAnnotate, verdict.Action)
+	assert.Empty(t, verdict.Replacement)
+
+	verdict, err = filter.Check("All good.")
+	require.NoError(t, err)
+	assert.Equal(t, FilterAllow, verdict.Action)
+	assert.Empty(t, verdict.Matches)
+
+	// The most severe action wins.
+	filter.Rules = append(filter.Rules, WordlistRule("secret", FilterBlock, "password"))
+	verdict, err = filter.Check("darn password")
+	require.NoError(t, err)
+	assert.Equal(t, FilterBlock, verdict.Action)
+}
+
+func TestRuleFilter_OverlappingMatches(t *testing.T) {
+	filter := NewRuleFilter(
+		FilterRule{Name: "a", Pattern: regexp.MustCompile(`abc`), Action: FilterRedact, Mask: "X"},
+		FilterRule{Name: "b", Pattern: regexp.MustCompile(`bcd`), Action: FilterRedact, Mask: "Y"},
+	)
+	verdict, err := filter.Check("_abcde_")
+	require.NoError(t, err)
+	assert.Equal(t, "_Xe_", verdict.Replacement)
+}
+
+func TestClient_ResponseFilters_Rules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Well, heck."}}`)
+	}))
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.ResponseFilters = []ResponseFilter{NewRuleFilter(WordlistRule("profanity", FilterRedact, "heck"))}
+
+	resp, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Well, "+Redacted+".", resp.GetContent())
+	require.Len(t, resp.FilterVerdicts, 1)
+	assert.Equal(t, "heck", resp.FilterVerdicts[0].Matches[0].Text)
+}
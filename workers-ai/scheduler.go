@@ -0,0 +1,206 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Priority is the scheduling class of a request.
+type Priority int
+
+const (
+	// PriorityInteractive is for requests a user is waiting on. It is the
+	// default.
+	PriorityInteractive Priority = iota
+	// PriorityBatch is for background work that can wait.
+	PriorityBatch
+
+	numPriorities = 2
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBatch:
+		return "batch"
+	default:
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+}
+
+// DefaultSchedulerWeights give interactive requests four slots for every
+// batch request while both are waiting.
+var DefaultSchedulerWeights = [numPriorities]int{4, 1}
+
+// Scheduler queues the requests of a Client so that they respect a rate
+// and concurrency limit, sharing the capacity between priorities by
+// weight: while both queues are non-empty, interactive requests get
+// Weights[PriorityInteractive] slots for every Weights[PriorityBatch] slots
+// of batch requests, so batch work is slowed down but never starved.
+//
+// Set it on Client.Scheduler; it may be shared by several clients using
+// the same account. A Scheduler is safe for concurrent use.
+type Scheduler struct {
+	// Rate caps the requests started per second. Zero means no limit.
+	Rate float64
+	// MaxConcurrent caps the requests in flight. Zero means no limit.
+	MaxConcurrent int
+	// Weights are the shares of the priorities. Zero weights default to
+	// DefaultSchedulerWeights.
+	Weights [numPriorities]int
+
+	mu       sync.Mutex
+	queues   [numPriorities][]*schedulerWaiter
+	credits  [numPriorities]int
+	inFlight int
+	next     time.Time
+	timer    *time.Timer
+}
+
+type schedulerWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewScheduler returns a scheduler starting at most rate requests per
+// second with at most maxConcurrent in flight.
+func NewScheduler(rate float64, maxConcurrent int) *Scheduler {
+	return &Scheduler{Rate: rate, MaxConcurrent: maxConcurrent}
+}
+
+// Acquire blocks until a request of priority p may start, or ctx is done.
+// Call release once the request finished.
+func (s *Scheduler) Acquire(ctx context.Context, p Priority) (release func(), err error) {
+	if p < 0 || p >= numPriorities {
+		p = PriorityInteractive
+	}
+
+	w := &schedulerWaiter{ready: make(chan struct{})}
+
+	s.mu.Lock()
+	s.queues[p] = append(s.queues[p], w)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaser(), nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.granted {
+		// Granted while giving up: hand the slot on.
+		s.inFlight--
+		s.dispatch()
+	} else {
+		s.remove(p, w)
+	}
+	return nil, ctx.Err()
+}
+
+// QueueDepth returns the number of requests of priority p waiting to start.
+func (s *Scheduler) QueueDepth(p Priority) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p < 0 || p >= numPriorities {
+		return 0
+	}
+	return len(s.queues[p])
+}
+
+// InFlight returns the number of requests started and not yet released.
+func (s *Scheduler) InFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight
+}
+
+func (s *Scheduler) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.inFlight--
+			s.dispatch()
+		})
+	}
+}
+
+// dispatch starts as many waiting requests as the limits allow. s.mu must
+// be held.
+func (s *Scheduler) dispatch() {
+	for {
+		p, ok := s.pick()
+		if !ok || (s.MaxConcurrent > 0 && s.inFlight >= s.MaxConcurrent) {
+			return
+		}
+
+		now := time.Now()
+		if s.Rate > 0 {
+			if wait := s.next.Sub(now); wait > 0 {
+				if s.timer == nil {
+					s.timer = time.AfterFunc(wait, func() {
+						s.mu.Lock()
+						defer s.mu.Unlock()
+						s.timer = nil
+						s.dispatch()
+					})
+				}
+				return
+			}
+			if s.next.Before(now) {
+				s.next = now
+			}
+			s.next = s.next.Add(time.Duration(float64(time.Second) / s.Rate))
+		}
+
+		w := s.queues[p][0]
+		s.queues[p] = s.queues[p][1:]
+		s.credits[p]--
+		s.inFlight++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// pick returns the priority whose queue is served next, by weighted round
+// robin over the non-empty queues.
+func (s *Scheduler) pick() (Priority, bool) {
+	for refilled := false; ; refilled = true {
+		waiting := false
+		for p := Priority(0); p < numPriorities; p++ {
+			if len(s.queues[p]) == 0 {
+				continue
+			}
+			waiting = true
+			if s.credits[p] > 0 {
+				return p, true
+			}
+		}
+		if !waiting || refilled {
+			return 0, false
+		}
+		for p := range s.credits {
+			s.credits[p] = s.Weights[p]
+			if s.credits[p] <= 0 {
+				s.credits[p] = DefaultSchedulerWeights[p]
+			}
+		}
+	}
+}
+
+func (s *Scheduler) remove(p Priority, w *schedulerWaiter) {
+	queue := s.queues[p]
+	for i, waiting := range queue {
+		if waiting == w {
+			s.queues[p] = append(queue[:i:i], queue[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_WeightedFairShare(t *testing.T) {
+	s := &Scheduler{MaxConcurrent: 1, Weights: [numPriorities]int{2, 1}}
+
+	// Hold the only slot while the queues fill up.
+	hold, err := s.Acquire(context.Background(), PriorityInteractive)
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		order []Priority
+		wg    sync.WaitGroup
+	)
+	enqueue := func(p Priority) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(context.Background(), p)
+			require.NoError(t, err)
+			mu.Lock()
+			order = append(order, p)
+			mu.Unlock()
+			release()
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		enqueue(PriorityBatch)
+	}
+	for i := 0; i < 4; i++ {
+		enqueue(PriorityInteractive)
+	}
+	require.Eventually(t, func() bool {
+		return s.QueueDepth(PriorityBatch) == 3 && s.QueueDepth(PriorityInteractive) == 4
+	}, time.Second, time.Millisecond)
+
+	hold()
+	wg.Wait()
+
+	// The held slot used the first of the two interactive credits of the
+	// first round; from then on, two interactive requests go per batch one.
+	i, b := PriorityInteractive, PriorityBatch
+	assert.Equal(t, []Priority{i, b, i, i, b, i, b}, order)
+	assert.Equal(t, 0, s.InFlight())
+}
+
+func TestScheduler_Rate(t *testing.T) {
+	s := NewScheduler(100, 0)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		release, err := s.Acquire(context.Background(), PriorityBatch)
+		require.NoError(t, err)
+		release()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	s := NewScheduler(0, 1)
+	hold, err := s.Acquire(context.Background(), PriorityInteractive)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(ctx, PriorityBatch)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, s.QueueDepth(PriorityBatch))
+
+	hold()
+	release, err := s.Acquire(context.Background(), PriorityBatch)
+	require.NoError(t, err)
+	release()
+}
+
+func TestClient_Scheduler(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		fmt.Fprint(w, `{"success": true, "result": {"response": "ok"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Scheduler = NewScheduler(0, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := client.ChatCompletion(ChatCompletionRequest{
+				Model:    ModelLlama38B,
+				Messages: []Message{ChatMessage{Role: "user", Content: "Hi"}},
+				Priority: Priority(i % 2),
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, maxInFlight)
+}
@@ -0,0 +1,184 @@
+package workersai
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ChatSession keeps the history of a conversation with a model, so that
+// each call only needs the new user message.
+//
+// A ChatSession is not safe for concurrent use.
+type ChatSession struct {
+	Client ClientInterface
+	Model  string
+
+	// SystemPrompt is sent as the first message of every request when set.
+	SystemPrompt string
+	// ModelParameters are passed to every request.
+	ModelParameters *ModelParameters
+	// Tools are offered to the model on every request.
+	Tools []Tool
+
+	// Messages is the conversation so far, without the system prompt.
+	Messages []Message
+	// Usage is the token usage accumulated over all requests of the session.
+	Usage Usage
+
+	// Label names the session among the branches of its parent.
+	Label string
+	// Parent is the session this one was forked from, nil for the root of
+	// the conversation; ForkedAt is the number of messages taken from it.
+	Parent   *ChatSession
+	ForkedAt int
+	// Branches are the sessions forked from this one, oldest first.
+	Branches []*ChatSession
+}
+
+// NewChatSession starts an empty conversation with modelID.
+func NewChatSession(client ClientInterface, modelID string) *ChatSession {
+	return &ChatSession{
+		Client: client,
+		Model:  modelID,
+	}
+}
+
+// Send adds a user message to the conversation and returns the model's reply,
+// which is appended to the history as well.
+func (s *ChatSession) Send(content string) (*ChatResponse, error) {
+	s.Messages = append(s.Messages, ChatMessage{Role: "user", Content: content})
+
+	resp, err := s.Continue()
+	if err != nil {
+		// Drop the message again so that the call can be retried.
+		s.Messages = s.Messages[:len(s.Messages)-1]
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AddToolResult appends the result of a tool call requested by the model.
+// Call Continue once the results of all requested calls are added.
+func (s *ChatSession) AddToolResult(toolCallID, content string) {
+	s.Messages = append(s.Messages, ToolMessage{Role: "tool", Content: content, ToolCallID: toolCallID})
+}
+
+// Continue asks the model for the next reply to the current history, e.g.
+// after tool results were added.
+func (s *ChatSession) Continue() (*ChatResponse, error) {
+	resp, err := s.Client.ChatWithTools(s.Model, s.History(), s.Tools, s.ModelParameters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to continue chat session: %w", err)
+	}
+
+	s.Messages = append(s.Messages, resp.Message())
+	s.addUsage(resp.GetUsage())
+
+	return resp, nil
+}
+
+// ErrNoUserMessage is returned by Regenerate when the conversation has no
+// user message to answer again.
+var ErrNoUserMessage = errors.New("no user message to regenerate from")
+
+// ErrNotUserMessage is returned by EditUserMessage when the index isn't
+// that of a user message.
+var ErrNotUserMessage = errors.New("not a user message")
+
+// Regenerate drops the turns after the last user message, tool calls
+// included, and asks the model for a new reply to it. The history is left
+// unchanged if the request fails.
+func (s *ChatSession) Regenerate() (*ChatResponse, error) {
+	last := -1
+	for i, m := range s.Messages {
+		if isUserMessage(m) {
+			last = i
+		}
+	}
+	if last < 0 {
+		return nil, ErrNoUserMessage
+	}
+	return s.replay(last + 1)
+}
+
+// EditUserMessage replaces the content of the user message at index i of
+// Messages, drops the turns that followed it and asks the model for a reply
+// to the new content. The history is left unchanged if the request fails.
+func (s *ChatSession) EditUserMessage(i int, content string) (*ChatResponse, error) {
+	if i < 0 || i >= len(s.Messages) || !isUserMessage(s.Messages[i]) {
+		return nil, fmt.Errorf("message %d: %w", i, ErrNotUserMessage)
+	}
+
+	messages := s.Messages
+	s.Messages = append(messages[:i:i], ChatMessage{Role: "user", Content: content})
+	resp, err := s.Continue()
+	if err != nil {
+		s.Messages = messages
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replay truncates the conversation to its first n messages and continues
+// it, restoring the messages on failure.
+func (s *ChatSession) replay(n int) (*ChatResponse, error) {
+	messages := s.Messages
+	s.Messages = messages[:n:n]
+	resp, err := s.Continue()
+	if err != nil {
+		s.Messages = messages
+		return nil, err
+	}
+	return resp, nil
+}
+
+// History returns the messages sent to the model: the system prompt, if
+// any, followed by the conversation.
+func (s *ChatSession) History() []Message {
+	if s.SystemPrompt == "" {
+		return append([]Message(nil), s.Messages...)
+	}
+
+	history := make([]Message, 0, len(s.Messages)+1)
+	history = append(history, ChatMessage{Role: "system", Content: s.SystemPrompt})
+	return append(history, s.Messages...)
+}
+
+// Reset clears the conversation and the accumulated usage.
+func (s *ChatSession) Reset() {
+	s.Messages = nil
+	s.Usage = Usage{}
+}
+
+// isUserMessage reports whether m was written by the user.
+func isUserMessage(m Message) bool {
+	msg, ok := m.(ChatMessage)
+	return ok && msg.Role == "user"
+}
+
+func (s *ChatSession) addUsage(u Usage) {
+	s.Usage.PromptTokens += u.PromptTokens
+	s.Usage.CompletionTokens += u.CompletionTokens
+	s.Usage.TotalTokens += u.TotalTokens
+}
+
+// Message returns the assistant turn of the response, its content or its
+// tool calls, as a message to append to the history of the next request.
+// The results of the tool calls go after it, as ToolMessage.
+func (r *ChatResponse) Message() Message {
+	return newAssistantMessage(r.GetContent(), r.GetToolCalls())
+}
+
+// newAssistantMessage picks the message type for an assistant turn, the same
+// way ChatCompletionRequest.UnmarshalJSON does: a ResponseMessage when it
+// carries tool calls and a ChatMessage otherwise.
+func newAssistantMessage(content string, toolCalls []ToolCall) Message {
+	if len(toolCalls) == 0 {
+		return ChatMessage{Role: "assistant", Content: content}
+	}
+	msg := ResponseMessage{Role: "assistant", ToolCalls: toolCalls}
+	if content != "" {
+		msg.Content = &content
+	}
+	return msg
+}
@@ -0,0 +1,183 @@
+package workersai
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedClient answers ChatWithTools with a fixed list of responses and
+// records the messages it received.
+type scriptedClient struct {
+	*Client
+	responses []*ChatResponse
+	err       error
+	calls     [][]Message
+}
+
+func (c *scriptedClient) ChatWithTools(modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) (*ChatResponse, error) {
+	c.calls = append(c.calls, messages)
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func textResponse(content string, usage Usage) *ChatResponse {
+	return &ChatResponse{
+		Success: true,
+		ChatCompletionResponse: ChatCompletionResponse{
+			Choices: []Choice{{Message: ResponseMessage{Role: "assistant", Content: &content}}},
+			Usage:   usage,
+		},
+	}
+}
+
+func toolCallResponse(calls ...ToolCall) *ChatResponse {
+	return &ChatResponse{
+		Success: true,
+		ChatCompletionResponse: ChatCompletionResponse{
+			Choices: []Choice{{Message: ResponseMessage{Role: "assistant", ToolCalls: calls}}},
+		},
+	}
+}
+
+func TestChatSession_Send(t *testing.T) {
+	weatherCall := ToolCall{ID: "call_1", Type: "function", Function: FunctionToCall{Name: "get_weather", Arguments: `{"location":"Paris"}`}}
+
+	client := &scriptedClient{responses: []*ChatResponse{
+		toolCallResponse(weatherCall),
+		textResponse("It is sunny in Paris.", Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}),
+	}}
+
+	session := NewChatSession(client, ModelLlama38B)
+	session.SystemPrompt = "You are a weather bot."
+
+	resp, err := session.Send("Weather in Paris?")
+	require.NoError(t, err)
+	require.Len(t, resp.GetToolCalls(), 1)
+
+	session.AddToolResult("call_1", "sunny")
+	resp, err = session.Continue()
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny in Paris.", resp.GetContent())
+
+	require.Len(t, client.calls, 2)
+	assert.Equal(t, ChatMessage{Role: "system", Content: "You are a weather bot."}, client.calls[1][0])
+	assert.Len(t, client.calls[1], 4)
+
+	require.Len(t, session.Messages, 4)
+	assert.Equal(t, ResponseMessage{Role: "assistant", ToolCalls: []ToolCall{weatherCall}}, session.Messages[1])
+	assert.Equal(t, ChatMessage{Role: "assistant", Content: "It is sunny in Paris."}, session.Messages[3])
+	assert.Equal(t, 15, session.Usage.TotalTokens)
+
+	session.Reset()
+	assert.Empty(t, session.Messages)
+	assert.Zero(t, session.Usage)
+}
+
+func TestChatSession_Send_Error(t *testing.T) {
+	client := &scriptedClient{err: errors.New("boom")}
+
+	session := NewChatSession(client, ModelLlama38B)
+	_, err := session.Send("Hello")
+	require.Error(t, err)
+	assert.Empty(t, session.Messages, "failed turn should not stay in the history")
+}
+
+func TestChatSession_Regenerate(t *testing.T) {
+	client := &scriptedClient{responses: []*ChatResponse{
+		textResponse("Paris", Usage{TotalTokens: 3}),
+		textResponse("Berlin", Usage{TotalTokens: 4}),
+		textResponse("It's Paris.", Usage{TotalTokens: 5}),
+	}}
+	session := NewChatSession(client, ModelLlama38B)
+
+	_, err := session.Regenerate()
+	assert.ErrorIs(t, err, ErrNoUserMessage)
+
+	_, err = session.Send("Capital of France?")
+	require.NoError(t, err)
+	_, err = session.Send("And Germany?")
+	require.NoError(t, err)
+
+	resp, err := session.Regenerate()
+	require.NoError(t, err)
+	assert.Equal(t, "It's Paris.", resp.GetContent())
+	assert.Equal(t, []Message{
+		ChatMessage{Role: "user", Content: "Capital of France?"},
+		ChatMessage{Role: "assistant", Content: "Paris"},
+		ChatMessage{Role: "user", Content: "And Germany?"},
+	}, client.calls[2])
+	assert.Equal(t, ChatMessage{Role: "assistant", Content: "It's Paris."}, session.Messages[3])
+	assert.Len(t, session.Messages, 4)
+	assert.Equal(t, 12, session.Usage.TotalTokens)
+
+	client.err = errors.New("unavailable")
+	_, err = session.Regenerate()
+	assert.Error(t, err)
+	assert.Len(t, session.Messages, 4)
+}
+
+func TestChatSession_EditUserMessage(t *testing.T) {
+	client := &scriptedClient{responses: []*ChatResponse{
+		textResponse("Paris", Usage{}),
+		textResponse("Berlin", Usage{}),
+		textResponse("Rome", Usage{}),
+	}}
+	session := NewChatSession(client, ModelLlama38B)
+	_, err := session.Send("Capital of France?")
+	require.NoError(t, err)
+	_, err = session.Send("And Germany?")
+	require.NoError(t, err)
+	messages := append([]Message(nil), session.Messages...)
+
+	_, err = session.EditUserMessage(1, "Not a user message")
+	assert.EqualError(t, err, "message 1: not a user message")
+	assert.ErrorIs(t, err, ErrNotUserMessage)
+	_, err = session.EditUserMessage(4, "Out of range")
+	assert.ErrorIs(t, err, ErrNotUserMessage)
+
+	client.err = errors.New("unavailable")
+	_, err = session.EditUserMessage(0, "Capital of Italy?")
+	assert.Error(t, err)
+	assert.Equal(t, messages, session.Messages)
+
+	client.err = nil
+	resp, err := session.EditUserMessage(0, "Capital of Italy?")
+	require.NoError(t, err)
+	assert.Equal(t, "Rome", resp.GetContent())
+	assert.Equal(t, []Message{
+		ChatMessage{Role: "user", Content: "Capital of Italy?"},
+		ChatMessage{Role: "assistant", Content: "Rome"},
+	}, session.Messages)
+}
+
+func TestChatResponse_Message(t *testing.T) {
+	var legacy ChatResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"success": true, "result": {"response": "Hello!"}}`), &legacy))
+	assert.Equal(t, ChatMessage{Role: "assistant", Content: "Hello!"}, legacy.Message())
+
+	var tools ChatResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"success": true, "result": {"choices": [{"index": 0, "message": {
+		"role": "assistant", "content": "Checking.",
+		"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{}"}}]
+	}}]}}`), &tools))
+	msg, ok := tools.Message().(ResponseMessage)
+	require.True(t, ok)
+	assert.Equal(t, "assistant", msg.Role)
+	assert.Equal(t, "Checking.", *msg.Content)
+	assert.Equal(t, "call_1", msg.ToolCalls[0].ID)
+
+	// The message round-trips through a request.
+	data, err := json.Marshal(ChatCompletionRequest{Messages: []Message{msg}})
+	require.NoError(t, err)
+	var request ChatCompletionRequest
+	require.NoError(t, json.Unmarshal(data, &request))
+	assert.Equal(t, tools.Message(), request.Messages[0])
+}
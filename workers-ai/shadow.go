@@ -0,0 +1,133 @@
+package workersai
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// DefaultShadowConcurrency is the default of Shadow.MaxConcurrent.
+const DefaultShadowConcurrency = 16
+
+// ShadowMetadataKey is the metadata key marking mirrored requests, so that
+// hooks and AI Gateway logs can tell them apart.
+const ShadowMetadataKey = "shadow"
+
+// Shadow mirrors a share of the chat requests to a candidate model, to
+// compare it with the current one before migrating. The mirrored requests
+// are sent in the background with PriorityBatch once the caller got its
+// response, which they never change, and survive the cancellation of the
+// caller's context. At most MaxConcurrent of them run at once: requests
+// drawn while that many are in flight are not mirrored, so that a slow
+// candidate can't pile up goroutines.
+//
+// Streaming requests are not mirrored.
+type Shadow struct {
+	// Model is the candidate model. Empty disables mirroring.
+	Model string
+	// Rate is the share of requests mirrored, from 0 to 1.
+	Rate float64
+	// MaxConcurrent caps the mirrored requests in flight. Defaults to
+	// DefaultShadowConcurrency.
+	MaxConcurrent int
+	// OnResult receives the comparison of every mirrored request. It is
+	// called from the goroutine of the mirrored request.
+	OnResult func(ShadowResult)
+}
+
+// ShadowResult compares the response of a mirrored request with the one
+// returned to the caller.
+type ShadowResult struct {
+	Model       string
+	ShadowModel string
+	Primary     *ChatResponse
+	// Shadow is the response of the candidate, nil if Err is set.
+	Shadow *ChatResponse
+	Err    error
+
+	Latency       time.Duration
+	ShadowLatency time.Duration
+	// Similarity is the Jaccard similarity of the words of both contents,
+	// from 0 for nothing in common to 1 for the same words.
+	Similarity float64
+	// LengthRatio is the length of the shadow content over the length of
+	// the primary content, 0 when the primary content is empty.
+	LengthRatio float64
+	// ToolCallsMatch reports whether both responses call the same tools,
+	// in the same order.
+	ToolCallsMatch bool
+	// Diff is the full comparison of the responses, from the primary to
+	// the shadow response.
+	Diff *ResponseDiff
+}
+
+// mirror sends request to the shadow model, if it was drawn, and reports
+// the comparison with primary.
+func (c *Client) mirror(ctx context.Context, request ChatCompletionRequest, primary *ChatResponse, latency time.Duration) {
+	shadow := c.Shadow
+	if shadow.Model == "" || shadow.Rate <= 0 || rand.Float64() >= shadow.Rate {
+		return
+	}
+	limit := int64(shadow.MaxConcurrent)
+	if limit <= 0 {
+		limit = DefaultShadowConcurrency
+	}
+	if c.shadows.Add(1) > limit {
+		c.shadows.Add(-1)
+		c.debugLog("Shadow request to %s dropped: %d already in flight", shadow.Model, limit)
+		return
+	}
+
+	ctx = WithMetadata(context.WithoutCancel(ctx), map[string]string{ShadowMetadataKey: "true"})
+	result := ShadowResult{
+		Model:       request.Model,
+		ShadowModel: shadow.Model,
+		Primary:     primary,
+		Latency:     latency,
+	}
+	request.Model = shadow.Model
+	request.Priority = PriorityBatch
+	tools := request.Tools
+	emulated := len(tools) > 0 && c.emulatesTools(request.Model)
+	if emulated {
+		request = emulateToolRequest(request)
+	}
+
+	go func() {
+		defer c.shadows.Add(-1)
+		func() {
+			defer recoverPanic(&result.Err)
+			start := time.Now()
+			result.Shadow, result.Err = c.complete(ctx, request)
+			result.ShadowLatency = time.Since(start)
+			if result.Err == nil && emulated {
+				parseEmulatedToolCall(result.Shadow, tools, len(request.Messages))
+			}
+			if result.Err == nil {
+				result.compare()
+			}
+		}()
+		if result.Err != nil {
+			c.debugLog("Shadow request to %s failed: %v", shadow.Model, result.Err)
+		}
+
+		if shadow.OnResult != nil {
+			var err error
+			func() {
+				defer recoverPanic(&err)
+				shadow.OnResult(result)
+			}()
+			if err != nil {
+				c.debugLog("Shadow OnResult callback failed: %v", err)
+			}
+		}
+	}()
+}
+
+// compare fills the divergence metrics of r.
+func (r *ShadowResult) compare() {
+	r.Diff = DiffResponses(r.Primary, r.Shadow)
+	r.Similarity = r.Diff.WordSimilarity
+	r.LengthRatio = r.Diff.LengthRatio
+	r.ToolCallsMatch = r.Diff.ToolCallsMatch
+}
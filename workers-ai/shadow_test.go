@@ -0,0 +1,153 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Shadow(t *testing.T) {
+	var mu sync.Mutex
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		mu.Lock()
+		models = append(models, request.Model)
+		mu.Unlock()
+
+		if request.Model == ModelQwen330ba3b {
+			fmt.Fprint(w, `{"success": true, "result": {"response": "the capital of France is Paris"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Paris is the capital of France"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var events []RequestEvent
+	client.Use(Hooks{AfterResponse: func(e RequestEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}})
+
+	results := make(chan ShadowResult, 1)
+	client.Shadow = Shadow{Model: ModelQwen330ba3b, Rate: 1, OnResult: func(r ShadowResult) { results <- r }}
+
+	// The mirrored request outlives the caller's context.
+	ctx, cancel := context.WithCancel(context.Background())
+	response, err := client.ChatCompletionContext(ctx, ChatCompletionRequest{
+		Model:    ModelLlama38B,
+		Messages: []Message{ChatMessage{Role: "user", Content: "What is the capital of France?"}},
+	})
+	cancel()
+	require.NoError(t, err)
+	assert.Equal(t, "Paris is the capital of France", response.GetContent())
+
+	var result ShadowResult
+	select {
+	case result = <-results:
+	case <-time.After(time.Second):
+		t.Fatal("no shadow result")
+	}
+	require.NoError(t, result.Err)
+	assert.Equal(t, ModelLlama38B, result.Model)
+	assert.Equal(t, ModelQwen330ba3b, result.ShadowModel)
+	assert.Same(t, response, result.Primary)
+	assert.Equal(t, 1.0, result.Similarity)
+	assert.Equal(t, 1.0, result.LengthRatio)
+	assert.True(t, result.ToolCallsMatch)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{ModelLlama38B, ModelQwen330ba3b}, models)
+	require.Len(t, events, 2)
+	assert.Empty(t, events[0].Metadata)
+	assert.Equal(t, map[string]string{ShadowMetadataKey: "true"}, events[1].Metadata)
+}
+
+func TestClient_ShadowRate(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		fmt.Fprint(w, `{"success": true, "result": {"response": "OK"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Shadow = Shadow{Model: ModelQwen330ba3b, Rate: 0}
+
+	for i := 0; i < 10; i++ {
+		_, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+		require.NoError(t, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 10, calls)
+}
+
+func TestClient_ShadowMaxConcurrent(t *testing.T) {
+	var shadowCalls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if request.Model == ModelQwen330ba3b {
+			shadowCalls.Add(1)
+			<-release
+		}
+		fmt.Fprint(w, `{"success": true, "result": {"response": "OK"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	results := make(chan ShadowResult, 10)
+	client.Shadow = Shadow{Model: ModelQwen330ba3b, Rate: 1, MaxConcurrent: 2, OnResult: func(r ShadowResult) { results <- r }}
+
+	// The shadows beyond the limit are dropped while two are blocked.
+	for i := 0; i < 5; i++ {
+		_, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return shadowCalls.Load() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, int64(2), client.shadows.Load())
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-results:
+		case <-time.After(time.Second):
+			t.Fatal("shadow result not reported")
+		}
+	}
+	require.Eventually(t, func() bool { return client.shadows.Load() == 0 }, time.Second, time.Millisecond)
+
+	// The slots are free again.
+	_, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	select {
+	case <-results:
+	case <-time.After(time.Second):
+		t.Fatal("shadow result not reported")
+	}
+	assert.Equal(t, int32(3), shadowCalls.Load())
+}
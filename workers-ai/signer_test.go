@@ -0,0 +1,78 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Signer(t *testing.T) {
+	signer := NewHMACSigner([]byte("secret"))
+	var verified []error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified = append(verified, signer.Verify(r))
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Signer = signer.Sign
+	client.Compression.RequestThreshold = 100
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	_, err := client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+
+	// The signature covers the compressed body.
+	_, err = client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: strings.Repeat("Hi ", 100)}}, nil)
+	require.NoError(t, err)
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, messages, nil, nil)
+	require.NoError(t, err)
+	stream.Close()
+
+	assert.Equal(t, []error{nil, nil, nil}, verified)
+
+	client.Signer = func(req *http.Request, body []byte) error {
+		return errors.New("no key")
+	}
+	_, err = client.Chat(ModelLlama38B, messages, nil)
+	assert.ErrorContains(t, err, "failed to sign request: no key")
+	assert.Len(t, verified, 3)
+}
+
+func TestHMACSigner_Verify(t *testing.T) {
+	signed := time.Unix(1700000000, 0)
+	signer := &HMACSigner{Key: []byte("secret"), Header: "X-Gateway-Signature", MaxAge: time.Minute, now: func() time.Time { return signed }}
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("POST", "/accounts/a/ai/run/model?x=1", strings.NewReader(`{"prompt":"Hi"}`))
+		require.NoError(t, signer.Sign(req, []byte(`{"prompt":"Hi"}`)))
+		return req
+	}
+
+	req := newRequest()
+	assert.NotEmpty(t, req.Header.Get("X-Gateway-Signature"))
+	assert.Equal(t, "1700000000", req.Header.Get(DefaultSignatureTimestampHeader))
+	assert.NoError(t, signer.Verify(req))
+
+	tampered := newRequest()
+	tampered.Body = http.NoBody
+	assert.ErrorIs(t, signer.Verify(tampered), ErrInvalidSignature)
+
+	other := &HMACSigner{Key: []byte("other")}
+	assert.ErrorIs(t, other.Verify(newRequest()), ErrInvalidSignature)
+
+	expired := newRequest()
+	signer.now = func() time.Time { return signed.Add(2 * time.Minute) }
+	assert.ErrorIs(t, signer.Verify(expired), ErrInvalidSignature)
+}
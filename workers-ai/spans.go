@@ -0,0 +1,227 @@
+package workersai
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Kinds of Span.
+const (
+	SpanRun  = "run"
+	SpanChat = "chat"
+	SpanTool = "tool"
+)
+
+// Span is an operation of a trace: the whole run, a chat request or a tool
+// execution. Chat spans are children of the run, and tool spans children
+// of the chat request whose response called the tool.
+type Span struct {
+	TraceID  string    `json:"trace_id"`
+	SpanID   string    `json:"span_id"`
+	ParentID string    `json:"parent_id,omitempty"`
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	// Attributes follow the OpenTelemetry semantic conventions for
+	// generative AI, e.g. "gen_ai.request.model".
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Spans converts the interactions of t to spans under a root span of the
+// run. Span IDs are derived from the sequence numbers of the interactions.
+func (t *Trace) Spans() []Span {
+	traceID := t.ID
+	if traceID == "" {
+		traceID = newTraceID()
+	}
+	root := Span{TraceID: traceID, SpanID: spanID(0), Name: "agent run", Kind: SpanRun}
+
+	spans := []Span{root}
+	// callers maps tool call IDs to the span of the chat request that
+	// returned them.
+	callers := make(map[string]string)
+	lastChat := root.SpanID
+	for _, i := range t.Interactions {
+		span := Span{
+			TraceID:  traceID,
+			SpanID:   spanID(i.Seq),
+			ParentID: root.SpanID,
+			Start:    i.Time,
+			End:      i.Time.Add(i.Duration),
+			Error:    i.Error,
+		}
+
+		switch i.Kind {
+		case InteractionChat:
+			span.Kind = SpanChat
+			span.Attributes = map[string]interface{}{"gen_ai.operation.name": "chat"}
+			if i.Request != nil {
+				span.Name = "chat " + i.Request.Model
+				span.Attributes["gen_ai.request.model"] = i.Request.Model
+			}
+			if response, err := i.ChatResponse(); err == nil && i.Error == "" {
+				usage := response.GetUsage()
+				span.Attributes["gen_ai.usage.input_tokens"] = usage.PromptTokens
+				span.Attributes["gen_ai.usage.output_tokens"] = usage.CompletionTokens
+				for _, call := range response.GetToolCalls() {
+					callers[call.ID] = span.SpanID
+				}
+			}
+			lastChat = span.SpanID
+		case InteractionTool:
+			span.Kind = SpanTool
+			span.ParentID = lastChat
+			if i.ToolCall != nil {
+				span.Name = "execute_tool " + i.ToolCall.Function.Name
+				span.Attributes = map[string]interface{}{
+					"gen_ai.operation.name": "execute_tool",
+					"gen_ai.tool.name":      i.ToolCall.Function.Name,
+					"gen_ai.tool.call.id":   i.ToolCall.ID,
+				}
+				if caller, ok := callers[i.ToolCall.ID]; ok {
+					span.ParentID = caller
+				}
+			}
+		default:
+			span.Name = i.Kind
+			span.Kind = i.Kind
+		}
+
+		if spans[0].Start.IsZero() || span.Start.Before(spans[0].Start) {
+			spans[0].Start = span.Start
+		}
+		if span.End.After(spans[0].End) {
+			spans[0].End = span.End
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// WriteJSON writes the spans of t as a JSON object with the trace ID and
+// the list of spans, for custom dashboards.
+func (t *Trace) WriteJSON(w io.Writer) error {
+	spans := t.Spans()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		TraceID string `json:"trace_id"`
+		Spans   []Span `json:"spans"`
+	}{spans[0].TraceID, spans})
+}
+
+// OTLP span kinds and status codes.
+const (
+	otlpKindInternal = 1
+	otlpKindClient   = 3
+	otlpStatusError  = 2
+)
+
+// WriteOTLP writes the spans of t as an OTLP/JSON export request, which
+// OpenTelemetry collectors and Jaeger accept, under the service name
+// serviceName.
+func (t *Trace) WriteOTLP(w io.Writer, serviceName string) error {
+	type otlpValue map[string]interface{}
+	type otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	type otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	type otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+
+	value := func(v interface{}) otlpValue {
+		switch v := v.(type) {
+		case string:
+			return otlpValue{"stringValue": v}
+		case int:
+			// OTLP/JSON encodes 64-bit integers as strings.
+			return otlpValue{"intValue": strconv.Itoa(v)}
+		case bool:
+			return otlpValue{"boolValue": v}
+		case float64:
+			return otlpValue{"doubleValue": v}
+		}
+		return otlpValue{"stringValue": fmt.Sprint(v)}
+	}
+
+	spans := t.Spans()
+	exported := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentID,
+			Name:              span.Name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		}
+		if span.Kind == SpanChat {
+			s.Kind = otlpKindClient
+		}
+		keys := make([]string, 0, len(span.Attributes))
+		for key := range span.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s.Attributes = append(s.Attributes, otlpAttribute{Key: key, Value: value(span.Attributes[key])})
+		}
+		if span.Error != "" {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: span.Error}
+		}
+		exported[i] = s
+	}
+
+	request := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttribute{{Key: "service.name", Value: value(serviceName)}},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "github.com/ashishdatta/workers-ai-golang/workers-ai"},
+				"spans": exported,
+			}},
+		}},
+	}
+	return json.NewEncoder(w).Encode(request)
+}
+
+// newTraceID returns a random trace ID.
+func newTraceID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		binary.BigEndian.PutUint64(id[8:], uint64(time.Now().UnixNano()))
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// spanID returns the span ID of the interaction numbered seq, 0 being the
+// run. OpenTelemetry doesn't allow all-zero IDs.
+func spanID(seq int) string {
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], uint64(seq)+1)
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,105 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTrace() *Trace {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	weather := ToolCall{ID: "call_1", Type: "function", Function: FunctionToCall{Name: "get_weather", Arguments: `{"location":"Paris"}`}}
+	return &Trace{
+		ID: "0102030405060708090a0b0c0d0e0f10",
+		Interactions: []Interaction{
+			{Seq: 1, Kind: InteractionChat, Time: start, Duration: time.Second,
+				Request:  &ChatCompletionRequest{Model: ModelLlama38B},
+				Response: recordedResult(toolCallResponse(weather))},
+			{Seq: 2, Kind: InteractionTool, Time: start.Add(time.Second), Duration: time.Second, ToolCall: &weather, ToolResult: "sunny"},
+			{Seq: 3, Kind: InteractionChat, Time: start.Add(2 * time.Second), Duration: time.Second,
+				Request: &ChatCompletionRequest{Model: ModelLlama38B}, Error: "API returned status 500: oops"},
+		},
+	}
+}
+
+func TestTrace_Spans(t *testing.T) {
+	spans := testTrace().Spans()
+	require.Len(t, spans, 4)
+
+	run, chat, tool, failed := spans[0], spans[1], spans[2], spans[3]
+	assert.Equal(t, SpanRun, run.Kind)
+	assert.Empty(t, run.ParentID)
+	assert.Equal(t, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), run.Start)
+	assert.Equal(t, 3*time.Second, run.End.Sub(run.Start))
+
+	assert.Equal(t, run.SpanID, chat.ParentID)
+	assert.Equal(t, "chat "+ModelLlama38B, chat.Name)
+	assert.Equal(t, ModelLlama38B, chat.Attributes["gen_ai.request.model"])
+
+	assert.Equal(t, chat.SpanID, tool.ParentID)
+	assert.Equal(t, "execute_tool get_weather", tool.Name)
+	assert.Equal(t, "call_1", tool.Attributes["gen_ai.tool.call.id"])
+
+	assert.Equal(t, run.SpanID, failed.ParentID)
+	assert.Equal(t, "API returned status 500: oops", failed.Error)
+
+	for _, span := range spans {
+		assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", span.TraceID)
+		assert.Len(t, span.SpanID, 16)
+	}
+}
+
+func TestTrace_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testTrace().WriteJSON(&buf))
+
+	var exported struct {
+		TraceID string `json:"trace_id"`
+		Spans   []Span `json:"spans"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", exported.TraceID)
+	assert.Len(t, exported.Spans, 4)
+}
+
+func TestTrace_WriteOTLP(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testTrace().WriteOTLP(&buf, "agent"))
+
+	var exported struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string            `json:"key"`
+					Value map[string]string `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []map[string]interface{} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	require.Len(t, exported.ResourceSpans, 1)
+	assert.Equal(t, "agent", exported.ResourceSpans[0].Resource.Attributes[0].Value["stringValue"])
+
+	spans := exported.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 4)
+	assert.Equal(t, "1748779200000000000", spans[0]["startTimeUnixNano"])
+	assert.Equal(t, float64(otlpKindClient), spans[1]["kind"])
+	assert.Equal(t, spans[1]["spanId"], spans[2]["parentSpanId"])
+	assert.Contains(t, spans[1]["attributes"], map[string]interface{}{
+		"key": "gen_ai.request.model", "value": map[string]interface{}{"stringValue": ModelLlama38B},
+	})
+	assert.Equal(t, map[string]interface{}{"code": float64(otlpStatusError), "message": "API returned status 500: oops"}, spans[3]["status"])
+}
+
+func TestRecorder_TraceID(t *testing.T) {
+	a, b := NewRecorder(nil).Trace(), NewRecorder(nil).Trace()
+	assert.Len(t, a.ID, 32)
+	assert.NotEqual(t, a.ID, b.ID)
+}
@@ -0,0 +1,165 @@
+package workersai
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// DefaultMaxSSEEventSize is the default limit of SSEReader.MaxEventSize.
+const DefaultMaxSSEEventSize = 16 << 20
+
+// ErrSSEEventTooLarge is returned by SSEReader.Next when an event exceeds
+// the reader's MaxEventSize.
+var ErrSSEEventTooLarge = errors.New("sse: event too large")
+
+// SSEEvent is a single server-sent event.
+type SSEEvent struct {
+	// ID is the value of the last "id" field seen on the stream.
+	ID string
+	// Event is the event type, empty for the default "message" type.
+	Event string
+	// Data is the event payload. Multiple "data" lines are joined with "\n".
+	Data string
+	// Retry is the reconnection time in milliseconds, if the event set one.
+	Retry int
+}
+
+// SSEReader reads server-sent events from a stream, following the parsing
+// rules of the HTML Living Standard: lines may end in LF, CRLF or CR,
+// comment lines start with ':', and an event is dispatched on a blank line.
+type SSEReader struct {
+	// MaxEventSize limits the size of a single event's data, protecting
+	// memory from misbehaving servers. Zero means DefaultMaxSSEEventSize.
+	MaxEventSize int
+	// OnComment, if set, is called with the text of every comment line.
+	// Servers send comments as keep-alives during long pauses.
+	OnComment func(comment string)
+
+	r      *bufio.Reader
+	lastID string
+	// skipLF is set after a line ended with CR, so that a following LF is
+	// treated as part of a CRLF pair rather than a blank line.
+	skipLF bool
+}
+
+// NewSSEReader returns a reader parsing events from r.
+func NewSSEReader(r io.Reader) *SSEReader {
+	return &SSEReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event. It returns io.EOF when the stream ends
+// cleanly and io.ErrUnexpectedEOF when it ends in the middle of an event.
+func (s *SSEReader) Next() (*SSEEvent, error) {
+	maxSize := s.MaxEventSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSSEEventSize
+	}
+
+	var (
+		data    bytes.Buffer
+		event   SSEEvent
+		hasData bool
+		pending bool // Whether any field of an undispatched event was seen.
+	)
+
+	for {
+		line, err := s.readLine(maxSize)
+		if err != nil {
+			if err == io.EOF && pending {
+				// The spec discards an incomplete event at the end of the stream.
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			if !hasData {
+				// Blank line without data: reset the event type and keep going.
+				event = SSEEvent{}
+				pending = false
+				continue
+			}
+			event.ID = s.lastID
+			event.Data = data.String()
+			return &event, nil
+		}
+
+		if line[0] == ':' {
+			if s.OnComment != nil {
+				s.OnComment(string(bytes.TrimPrefix(line[1:], []byte(" "))))
+			}
+			continue
+		}
+
+		pending = true
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			value = bytes.TrimPrefix(value, []byte(" "))
+		}
+
+		switch string(field) {
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			if data.Len()+len(value) > maxSize {
+				return nil, ErrSSEEventTooLarge
+			}
+			data.Write(value)
+			hasData = true
+		case "event":
+			event.Event = string(value)
+		case "id":
+			if !bytes.ContainsRune(value, 0) {
+				s.lastID = string(value)
+			}
+		case "retry":
+			if n, err := strconv.Atoi(string(value)); err == nil && n >= 0 {
+				event.Retry = n
+			}
+		}
+		// Unknown fields are ignored.
+	}
+}
+
+// readLine returns the next line without its terminator. It returns io.EOF
+// only if no bytes were read; a final line without terminator is returned.
+func (s *SSEReader) readLine(maxSize int) ([]byte, error) {
+	var line []byte
+
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return line, nil
+			}
+			return nil, err
+		}
+
+		if s.skipLF {
+			s.skipLF = false
+			if b == '\n' {
+				continue
+			}
+		}
+
+		switch b {
+		case '\n':
+			return line, nil
+		case '\r':
+			s.skipLF = true
+			return line, nil
+		}
+
+		if len(line) >= maxSize {
+			return nil, fmt.Errorf("%w: line exceeds %d bytes", ErrSSEEventTooLarge, maxSize)
+		}
+		line = append(line, b)
+	}
+}
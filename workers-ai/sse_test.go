@@ -0,0 +1,186 @@
+package workersai
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAllEvents(r *SSEReader) ([]SSEEvent, error) {
+	var events []SSEEvent
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, *event)
+	}
+}
+
+func TestSSEReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []SSEEvent
+	}{
+		{
+			name:  "single event",
+			input: "data: hello\n\n",
+			want:  []SSEEvent{{Data: "hello"}},
+		},
+		{
+			name:  "multi-line data",
+			input: "data: first\ndata: second\ndata\n\n",
+			want:  []SSEEvent{{Data: "first\nsecond\n"}},
+		},
+		{
+			name:  "CRLF line endings",
+			input: "data: a\r\n\r\ndata: b\r\n\r\n",
+			want:  []SSEEvent{{Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:  "CR line endings",
+			input: "data: a\r\rdata: b\r\r",
+			want:  []SSEEvent{{Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:  "comments and unknown fields are skipped",
+			input: ": keep-alive\n\nfoo: bar\ndata: x\n: inside\n\n",
+			want:  []SSEEvent{{Data: "x"}},
+		},
+		{
+			name:  "event, id and retry",
+			input: "event: update\nid: 7\nretry: 1500\ndata: {}\n\ndata: next\n\n",
+			want:  []SSEEvent{{Event: "update", ID: "7", Retry: 1500, Data: "{}"}, {ID: "7", Data: "next"}},
+		},
+		{
+			name:  "no space after colon",
+			input: "data:tight\n\n",
+			want:  []SSEEvent{{Data: "tight"}},
+		},
+		{
+			name:  "only the first space is removed",
+			input: "data:  two spaces\n\n",
+			want:  []SSEEvent{{Data: " two spaces"}},
+		},
+		{
+			name:  "blank lines without data dispatch nothing",
+			input: "\n\nevent: ignored\n\ndata: x\n\n",
+			want:  []SSEEvent{{Data: "x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readers := map[string]io.Reader{
+				"whole":    strings.NewReader(tt.input),
+				"one byte": iotest.OneByteReader(strings.NewReader(tt.input)),
+				"half":     iotest.HalfReader(strings.NewReader(tt.input)),
+			}
+			for name, r := range readers {
+				events, err := readAllEvents(NewSSEReader(r))
+				require.NoError(t, err, name)
+				assert.Equal(t, tt.want, events, name)
+			}
+		})
+	}
+}
+
+func TestSSEReader_Comments(t *testing.T) {
+	var comments []string
+	r := NewSSEReader(strings.NewReader(": ping\n:pong\ndata: x\n\n"))
+	r.OnComment = func(c string) { comments = append(comments, c) }
+
+	events, err := readAllEvents(r)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, []string{"ping", "pong"}, comments)
+}
+
+func TestSSEReader_UnexpectedEOF(t *testing.T) {
+	events, err := readAllEvents(NewSSEReader(strings.NewReader("data: a\n\ndata: cut")))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, []SSEEvent{{Data: "a"}}, events)
+}
+
+func TestSSEReader_LargeEvents(t *testing.T) {
+	large := strings.Repeat("x", 1<<20)
+
+	events, err := readAllEvents(NewSSEReader(strings.NewReader("data: " + large + "\n\n")))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, large, events[0].Data)
+
+	r := NewSSEReader(strings.NewReader("data: " + large + "\n\n"))
+	r.MaxEventSize = 1024
+	_, err = r.Next()
+	assert.True(t, errors.Is(err, ErrSSEEventTooLarge))
+
+	r = NewSSEReader(strings.NewReader("data: 1234\ndata: 5678\n\n"))
+	r.MaxEventSize = 8
+	_, err = r.Next()
+	assert.True(t, errors.Is(err, ErrSSEEventTooLarge))
+}
+
+func FuzzSSEReader(f *testing.F) {
+	f.Add("data: hello\n\n")
+	f.Add("data: a\r\ndata: b\r\n\r\n")
+	f.Add(": comment\rid: 1\revent: x\rretry: 10\rdata\r\r")
+	f.Add("data: {\"response\": \"hi\"}\n\ndata: [DONE]\n\n")
+	f.Add("data")
+	f.Add("\r\n\r\n\n\r")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		r := NewSSEReader(strings.NewReader(input))
+		r.MaxEventSize = 1 << 16
+
+		for i := 0; ; i++ {
+			event, err := r.Next()
+			if err != nil {
+				return
+			}
+			if strings.ContainsAny(event.Event, "\r\n") || strings.ContainsAny(event.ID, "\r\n") {
+				t.Fatalf("field contains a line break: %+v", event)
+			}
+			if strings.Contains(event.Data, "\r") {
+				t.Fatalf("data contains a CR: %q", event.Data)
+			}
+			if i > len(input) {
+				t.Fatalf("more events than input bytes")
+			}
+		}
+	})
+}
+
+func FuzzSSEReader_RoundTrip(f *testing.F) {
+	f.Add("hello", "\n")
+	f.Add("multi\nline\npayload", "\r\n")
+	f.Add("", "\r")
+
+	f.Fuzz(func(t *testing.T, data, eol string) {
+		if eol != "\n" && eol != "\r\n" && eol != "\r" {
+			return
+		}
+		if strings.ContainsRune(data, '\r') {
+			return
+		}
+
+		var b strings.Builder
+		for _, line := range strings.Split(data, "\n") {
+			b.WriteString("data: " + line + eol)
+		}
+		b.WriteString(eol)
+
+		event, err := NewSSEReader(strings.NewReader(b.String())).Next()
+		require.NoError(t, err)
+		assert.Equal(t, data, event.Data)
+	})
+}
@@ -0,0 +1,555 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStreamIdleTimeout is returned when a stream received nothing, not even
+// a keep-alive, for longer than Client.StreamIdleTimeout.
+var ErrStreamIdleTimeout = errors.New("stream idle timeout")
+
+// StreamChunk is a single increment of a streamed chat response.
+type StreamChunk struct {
+	// Content is the text generated since the previous chunk.
+	Content string
+	// ReasoningContent is the reasoning generated since the previous chunk,
+	// for models that expose it.
+	ReasoningContent string
+	// ToolCalls holds tool call fragments as sent by the model.
+	ToolCalls []ToolCall
+	// FinishReason is set on the last chunk of a choice, e.g. "stop" or "length".
+	FinishReason string
+	// Usage is set on the chunk reporting the token usage, usually the last one.
+	Usage *Usage
+	// Raw is the undecoded event data.
+	Raw json.RawMessage
+
+	// Elapsed is the time from sending the request to receiving the chunk.
+	Elapsed time.Duration
+	// Delta is the time since the previous chunk, or Elapsed for the first.
+	Delta time.Duration
+}
+
+// streamPayload covers both streaming formats: the legacy one sends
+// {"response": "..."} events while OpenAI-compatible models send
+// {"choices": [{"delta": {...}}]} events.
+type streamPayload struct {
+	Response  *string    `json:"response"`
+	ToolCalls []ToolCall `json:"tool_calls"`
+	Choices   []struct {
+		Delta struct {
+			Content          *string    `json:"content"`
+			ReasoningContent string     `json:"reasoning_content"`
+			ToolCalls        []ToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// ChatStream is a chat response streamed with server-sent events. Read it
+// with Recv until it returns io.EOF, and always Close it.
+//
+// A ChatStream is not safe for concurrent use.
+type ChatStream struct {
+	client  *Client
+	aliases map[string]string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	release func()
+	resp    *http.Response
+	events  *SSEReader
+
+	// watchdog aborts the request once the stream was idle for too long.
+	watchdog *time.Timer
+	idle     atomic.Bool
+
+	event RequestEvent
+	start time.Time
+
+	content      strings.Builder
+	reasoning    strings.Builder
+	finishReason string
+	chunks       int
+	firstChunk   time.Duration
+	lastChunk    time.Duration
+	end          time.Time
+	usage        *Usage
+	cancelled    bool
+	err          error
+}
+
+// StreamSummary is the response accumulated by a stream, complete or not.
+type StreamSummary struct {
+	// Content is the text received.
+	Content string
+	// ReasoningContent is the reasoning received, for models that expose it.
+	ReasoningContent string
+	// FinishReason is the reason the model stopped, empty if it didn't.
+	FinishReason string
+	// Usage is the token usage. If the stream ended before the model
+	// reported it, the completion tokens are estimated from the number of
+	// chunks received and UsageEstimated is set.
+	Usage          Usage
+	UsageEstimated bool
+	// Cancelled is set when the stream was stopped by the caller (context
+	// cancellation, Close or a callback error) before it completed.
+	Cancelled bool
+	// Stats describe the generation speed.
+	Stats StreamStats
+}
+
+// StreamStats describe the timing of a stream.
+type StreamStats struct {
+	// Chunks is the number of chunks received.
+	Chunks int
+	// TimeToFirstChunk is the time from sending the request to receiving
+	// the first chunk, roughly the time to the first token.
+	TimeToFirstChunk time.Duration
+	// Duration is the time from sending the request to the end of the
+	// stream, or until now while it is running.
+	Duration time.Duration
+	// TokensPerSecond is the completion tokens received after the first
+	// chunk divided by the time from the first chunk to the last one. It
+	// is zero with fewer than two chunks.
+	TokensPerSecond float64
+}
+
+// ChatStream starts a chat request whose response is streamed. Cancelling
+// ctx aborts the request.
+//
+// Use a ctx deadline to bound the total duration of a generation and
+// Client.StreamIdleTimeout to detect dead connections: slow models may take
+// minutes to finish but keep sending chunks or keep-alives meanwhile. Avoid
+// setting http.Client.Timeout for streaming, as it covers the whole body.
+func (c *Client) ChatStream(ctx context.Context, modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) (*ChatStream, error) {
+	request := ChatCompletionRequest{
+		Model:    modelID,
+		Messages: messages,
+		Tools:    tools,
+		Stream:   true,
+	}
+
+	if modelParams != nil {
+		request.ModelParameters = *modelParams
+	}
+
+	c.applyDefaults(&request)
+
+	if err := c.checkNeurons(ctx); err != nil {
+		return nil, err
+	}
+
+	// The scheduler slot is held until the stream finished.
+	release := func() {}
+	if c.Scheduler != nil {
+		var err error
+		if release, err = c.Scheduler.Acquire(ctx, PriorityInteractive); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Compatibility == CompatOpenAI {
+		request.Model = openAIModel(request.Model)
+	}
+	jsonData, err := c.codec().Marshal(withZeroParams(request))
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+
+	req, err := c.newRunRequest(reqCtx, c.chatURL(modelID), "application/json", jsonData)
+	if err != nil {
+		release()
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	for name, values := range c.cacheHeader(nil) {
+		req.Header[name] = values
+	}
+
+	stream := &ChatStream{
+		client:  c,
+		aliases: c.fieldAliases(),
+		ctx:     ctx,
+		cancel:  cancel,
+		release: release,
+		event:   RequestEvent{Model: modelID},
+		start:   time.Now(),
+	}
+	tagRequest(ctx, req, &stream.event)
+	if err := c.sign(req); err != nil {
+		stream.finish(err)
+		return nil, err
+	}
+
+	if c.StreamIdleTimeout > 0 {
+		stream.watchdog = time.AfterFunc(c.StreamIdleTimeout, func() {
+			stream.idle.Store(true)
+			cancel()
+		})
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		if stream.idle.Load() {
+			err = ErrStreamIdleTimeout
+		}
+		err = fmt.Errorf("failed to make request: %w", err)
+		stream.finish(err)
+		return nil, err
+	}
+	stream.event.StatusCode = resp.StatusCode
+	stream.event.CacheStatus = resp.Header.Get(cacheStatusHeader)
+
+	if err := c.decompressResponse(resp); err != nil {
+		resp.Body.Close()
+		stream.finish(err)
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := c.readResponse(resp)
+		c.debugLog("API Error - Status: %d, Body: %s", resp.StatusCode, string(body))
+		err = newAPIError(resp, body)
+		stream.finish(err)
+		return nil, err
+	}
+
+	stream.resp = resp
+	stream.events = NewSSEReader(&activityReader{r: resp.Body, stream: stream})
+	stream.events.OnComment = func(comment string) {
+		c.debugLog("Stream keep-alive: %s", comment)
+	}
+	return stream, nil
+}
+
+// StreamResult is an element of the channel returned by ChatStreamChan:
+// either a chunk or the error that ended the stream.
+type StreamResult struct {
+	Chunk *StreamChunk
+	Err   error
+}
+
+// ChatStreamFunc streams a chat response and calls fn with every chunk as it
+// arrives. The next chunk isn't read before fn returns, so a slow fn slows
+// the stream down instead of buffering it.
+//
+// If fn returns an error the request is aborted right away, so that the
+// model stops generating tokens, and that error is returned.
+//
+// The summary of what was received is returned even when the stream failed
+// or was cancelled, e.g. to keep a partial answer after "stop generating";
+// it is only nil if the request could not be started.
+func (c *Client) ChatStreamFunc(ctx context.Context, modelID string, messages []Message, tools []Tool, modelParams *ModelParameters, fn func(*StreamChunk) error) (*StreamSummary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.ChatStream(ctx, modelID, messages, tools, modelParams)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			summary := stream.Summary()
+			return &summary, nil
+		}
+		if err != nil {
+			summary := stream.Summary()
+			return &summary, err
+		}
+
+		if err := fn(chunk); err != nil {
+			stream.Close()
+			summary := stream.Summary()
+			return &summary, err
+		}
+	}
+}
+
+// ChatStreamChan streams a chat response over an unbuffered channel. The
+// channel is closed when the stream completes; if it fails, the last element
+// carries the error. Errors starting the request are returned directly.
+//
+// The stream only advances as fast as the channel is read. To stop early,
+// cancel ctx: this aborts the request and closes the channel.
+func (c *Client) ChatStreamChan(ctx context.Context, modelID string, messages []Message, tools []Tool, modelParams *ModelParameters) (<-chan StreamResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	stream, err := c.ChatStream(ctx, modelID, messages, tools, modelParams)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	results := make(chan StreamResult)
+	go func() {
+		defer close(results)
+		defer cancel()
+		defer stream.Close()
+
+		// A panic while decoding ends the stream with its error.
+		var panicErr error
+		defer func() {
+			if panicErr != nil {
+				select {
+				case results <- StreamResult{Err: panicErr}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+		defer recoverPanic(&panicErr)
+
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+
+			result := StreamResult{Chunk: chunk, Err: err}
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// Recv returns the next chunk of the response. It returns io.EOF once the
+// stream completed; any other error means the stream was interrupted.
+func (s *ChatStream) Recv() (*StreamChunk, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	for {
+		// Check for cancellation first: events may still be buffered after
+		// the request was aborted.
+		if s.idle.Load() {
+			return nil, s.fail(fmt.Errorf("failed to read stream: %w", ErrStreamIdleTimeout))
+		}
+		if ctxErr := s.ctx.Err(); ctxErr != nil {
+			s.cancelled = true
+			return nil, s.fail(fmt.Errorf("stream cancelled: %w", ctxErr))
+		}
+
+		event, err := s.events.Next()
+		if err != nil {
+			if s.idle.Load() {
+				return nil, s.fail(fmt.Errorf("failed to read stream: %w", ErrStreamIdleTimeout))
+			}
+			if ctxErr := s.ctx.Err(); ctxErr != nil {
+				s.cancelled = true
+				return nil, s.fail(fmt.Errorf("stream cancelled: %w", ctxErr))
+			}
+			if err == io.EOF {
+				// The stream ended without the [DONE] marker.
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, s.fail(fmt.Errorf("failed to read stream: %w", err))
+		}
+
+		if event.Data == "[DONE]" {
+			s.err = io.EOF
+			s.finish(nil)
+			return nil, io.EOF
+		}
+
+		if event.Event == "error" {
+			return nil, s.fail(fmt.Errorf("stream error: %s", event.Data))
+		}
+
+		s.client.debugLog("Stream Event: %s", event.Data)
+
+		chunk, err := parseStreamChunk([]byte(event.Data), s.aliases)
+		if err != nil {
+			return nil, s.fail(err)
+		}
+
+		chunk.Elapsed = time.Since(s.start)
+		chunk.Delta = chunk.Elapsed - s.lastChunk
+		if s.chunks == 0 {
+			s.firstChunk = chunk.Elapsed
+		}
+		s.lastChunk = chunk.Elapsed
+
+		s.chunks++
+		s.content.WriteString(chunk.Content)
+		s.reasoning.WriteString(chunk.ReasoningContent)
+		if chunk.FinishReason != "" {
+			s.finishReason = chunk.FinishReason
+		}
+		if chunk.Usage != nil {
+			s.usage = chunk.Usage
+		}
+
+		return chunk, nil
+	}
+}
+
+// Content returns the text received so far.
+func (s *ChatStream) Content() string {
+	return s.content.String()
+}
+
+// Usage returns the token usage reported by the stream, which is usually
+// only known once the stream completed.
+func (s *ChatStream) Usage() Usage {
+	if s.usage == nil {
+		return Usage{}
+	}
+	return *s.usage
+}
+
+// Summary returns what the stream received so far. It can be called at any
+// time, including after the stream failed or was closed early.
+func (s *ChatStream) Summary() StreamSummary {
+	summary := StreamSummary{
+		Content:          s.content.String(),
+		ReasoningContent: s.reasoning.String(),
+		FinishReason:     s.finishReason,
+		Cancelled:        s.cancelled,
+	}
+
+	if s.usage != nil {
+		summary.Usage = *s.usage
+	} else {
+		// Workers AI sends about one token per chunk.
+		summary.Usage = Usage{CompletionTokens: s.chunks, TotalTokens: s.chunks}
+		summary.UsageEstimated = true
+	}
+	summary.Stats = s.stats(summary.Usage)
+
+	return summary
+}
+
+// stats computes the timing of the stream given its usage.
+func (s *ChatStream) stats(usage Usage) StreamStats {
+	stats := StreamStats{
+		Chunks:           s.chunks,
+		TimeToFirstChunk: s.firstChunk,
+	}
+
+	end := s.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	stats.Duration = end.Sub(s.start)
+
+	// The tokens of the first chunk were generated before it arrived, so
+	// they don't count towards the time between the first and last chunk.
+	if generation := s.lastChunk - s.firstChunk; s.chunks > 1 && generation > 0 {
+		tokens := float64(usage.CompletionTokens) * float64(s.chunks-1) / float64(s.chunks)
+		stats.TokensPerSecond = tokens / generation.Seconds()
+	}
+	return stats
+}
+
+// Close releases the connection. Closing a stream before it completed
+// aborts the request.
+func (s *ChatStream) Close() error {
+	if s.resp == nil {
+		return nil
+	}
+	if s.err == nil {
+		s.cancelled = true
+		s.fail(fmt.Errorf("stream closed"))
+	}
+	return s.resp.Body.Close()
+}
+
+// fail records err as the terminal error of the stream and returns it.
+func (s *ChatStream) fail(err error) error {
+	s.err = err
+	s.finish(err)
+	return err
+}
+
+// finish releases the request context and reports the request to the
+// AfterResponse hooks.
+func (s *ChatStream) finish(err error) {
+	if s.watchdog != nil {
+		s.watchdog.Stop()
+	}
+	s.cancel()
+	s.release()
+
+	s.end = time.Now()
+	s.event.Duration = s.end.Sub(s.start)
+	s.event.Usage = s.Usage()
+	s.client.recordNeurons(s.event.Model, s.event.Usage)
+	summary := s.Summary()
+	s.event.Stream = &summary.Stats
+	s.event.Err = err
+	s.client.afterResponse(s.event, nil)
+}
+
+func parseStreamChunk(data []byte, aliases map[string]string) (*StreamChunk, error) {
+	var payload streamPayload
+	// Chunks with camelCase field names are decoded like the others.
+	normalized, _ := snakeCaseFields(data, "chunk", aliases)
+	if err := json.Unmarshal(normalized, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse stream chunk: %w", err)
+	}
+
+	chunk := &StreamChunk{
+		ToolCalls: payload.ToolCalls,
+		Usage:     payload.Usage,
+		Raw:       json.RawMessage(data),
+	}
+
+	if payload.Response != nil {
+		chunk.Content = *payload.Response
+	}
+
+	if len(payload.Choices) > 0 {
+		choice := payload.Choices[0]
+		if choice.Delta.Content != nil {
+			chunk.Content = *choice.Delta.Content
+		}
+		chunk.ReasoningContent = choice.Delta.ReasoningContent
+		chunk.ToolCalls = append(chunk.ToolCalls, choice.Delta.ToolCalls...)
+		if choice.FinishReason != nil {
+			chunk.FinishReason = *choice.FinishReason
+		}
+	}
+
+	return chunk, nil
+}
+
+// activityReader resets the idle watchdog of a stream whenever data arrives,
+// including keep-alive comments and partial events.
+type activityReader struct {
+	r      io.Reader
+	stream *ChatStream
+}
+
+func (a *activityReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if n > 0 && a.stream.watchdog != nil && !a.stream.idle.Load() {
+		a.stream.watchdog.Reset(a.stream.client.StreamIdleTimeout)
+	}
+	return n, err
+}
@@ -0,0 +1,381 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStreamServer(t *testing.T, events ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			w.(http.Flusher).Flush()
+		}
+	}))
+}
+
+func TestClient_ChatStream_Legacy(t *testing.T) {
+	server := newStreamServer(t,
+		`{"response": "Hello", "p": "abc"}`,
+		`{"response": " world"}`,
+		`{"response": "", "usage": {"prompt_tokens": 4, "completion_tokens": 2, "total_tokens": 6}}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var events []RequestEvent
+	client.Use(Hooks{AfterResponse: func(e RequestEvent) { events = append(events, e) }})
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var pieces []string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		pieces = append(pieces, chunk.Content)
+	}
+
+	assert.Equal(t, []string{"Hello", " world", ""}, pieces)
+	assert.Equal(t, "Hello world", stream.Content())
+	assert.Equal(t, 6, stream.Usage().TotalTokens)
+
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	require.Len(t, events, 1)
+	assert.NoError(t, events[0].Err)
+	assert.Equal(t, 6, events[0].Usage.TotalTokens)
+}
+
+func TestClient_ChatStream_OpenAI(t *testing.T) {
+	server := newStreamServer(t,
+		`{"choices": [{"delta": {"role": "assistant", "reasoning_content": "thinking"}}]}`,
+		`{"choices": [{"delta": {"content": "Hi"}}]}`,
+		`{"choices": [{"delta": {}, "finish_reason": "stop"}], "usage": {"total_tokens": 3}}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, nil, nil, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	chunk, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "thinking", chunk.ReasoningContent)
+
+	chunk, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", chunk.Content)
+
+	chunk, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "stop", chunk.FinishReason)
+	assert.Equal(t, 3, chunk.Usage.TotalTokens)
+
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestClient_ChatStream_Truncated(t *testing.T) {
+	server := newStreamServer(t, `{"response": "partial"}`)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, nil, nil, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, "partial", stream.Content())
+}
+
+func TestClient_ChatStream_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"success": false}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	_, err := client.ChatStream(context.Background(), ModelLlama38B, nil, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+}
+
+// newEndlessStreamServer streams chunks until the client goes away and
+// reports on cancelled when it does.
+func newEndlessStreamServer(cancelled chan<- struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+				return
+			case <-time.After(time.Millisecond):
+			}
+			fmt.Fprintf(w, "data: {\"response\": \"%d \"}\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+}
+
+func TestClient_ChatStreamFunc(t *testing.T) {
+	server := newStreamServer(t, `{"response": "a"}`, `{"response": "b"}`, `[DONE]`)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var content string
+	summary, err := client.ChatStreamFunc(context.Background(), ModelLlama38B, nil, nil, nil, func(chunk *StreamChunk) error {
+		content += chunk.Content
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ab", content)
+	assert.Equal(t, "ab", summary.Content)
+	assert.False(t, summary.Cancelled)
+}
+
+func TestClient_ChatStreamFunc_CallbackErrorCancels(t *testing.T) {
+	cancelled := make(chan struct{})
+	server := newEndlessStreamServer(cancelled)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stop := errors.New("stop")
+	chunks := 0
+	summary, err := client.ChatStreamFunc(context.Background(), ModelLlama38B, nil, nil, nil, func(chunk *StreamChunk) error {
+		chunks++
+		if chunks == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 3, chunks)
+	assert.Equal(t, "0 1 2 ", summary.Content)
+	assert.True(t, summary.Cancelled)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("request was not cancelled")
+	}
+}
+
+func TestClient_ChatStreamChan(t *testing.T) {
+	server := newStreamServer(t, `{"response": "a"}`, `{"response": "b"}`, `[DONE]`)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	results, err := client.ChatStreamChan(context.Background(), ModelLlama38B, nil, nil, nil)
+	require.NoError(t, err)
+
+	var content string
+	for result := range results {
+		require.NoError(t, result.Err)
+		content += result.Chunk.Content
+	}
+	assert.Equal(t, "ab", content)
+}
+
+func TestClient_ChatStreamChan_Error(t *testing.T) {
+	server := newStreamServer(t, `{"response": "a"}`, `not json`)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	results, err := client.ChatStreamChan(context.Background(), ModelLlama38B, nil, nil, nil)
+	require.NoError(t, err)
+
+	var got []StreamResult
+	for result := range results {
+		got = append(got, result)
+	}
+	require.Len(t, got, 2)
+	assert.NoError(t, got[0].Err)
+	assert.Error(t, got[1].Err)
+}
+
+func TestClient_ChatStreamChan_Cancel(t *testing.T) {
+	cancelled := make(chan struct{})
+	server := newEndlessStreamServer(cancelled)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := client.ChatStreamChan(ctx, ModelLlama38B, nil, nil, nil)
+	require.NoError(t, err)
+
+	<-results
+	cancel()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("request was not cancelled")
+	}
+
+	// The channel is closed once the producer noticed the cancellation.
+	for range results {
+	}
+}
+
+func TestClient_ChatStreamFunc_ContextCancelKeepsPartialResult(t *testing.T) {
+	cancelled := make(chan struct{})
+	server := newEndlessStreamServer(cancelled)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	summary, err := client.ChatStreamFunc(ctx, ModelLlama38B, nil, nil, nil, func(chunk *StreamChunk) error {
+		if chunk.Content == "1 " {
+			// Simulate the user pressing "stop generating".
+			cancel()
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, summary)
+	assert.True(t, summary.Cancelled)
+	assert.Equal(t, "0 1 ", summary.Content)
+	assert.True(t, summary.UsageEstimated)
+	assert.Equal(t, 2, summary.Usage.CompletionTokens)
+}
+
+func TestClient_ChatStream_IdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		// Keep-alives spaced below the idle timeout keep the stream open
+		// although no event arrives for longer than the timeout.
+		for i := 0; i < 4; i++ {
+			fmt.Fprint(w, ": keep-alive\n\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+		fmt.Fprint(w, "data: {\"response\": \"slow\"}\n\n")
+		w.(http.Flusher).Flush()
+
+		// Then the connection goes silent.
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.StreamIdleTimeout = 50 * time.Millisecond
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, nil, nil, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	chunk, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "slow", chunk.Content)
+
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, ErrStreamIdleTimeout)
+
+	summary := stream.Summary()
+	assert.False(t, summary.Cancelled)
+	assert.Equal(t, "slow", summary.Content)
+}
+
+func TestChatStream_Stats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 5; i++ {
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprint(w, "data: {\"response\": \"tok \"}\n\n")
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: {\"response\": \"\", \"usage\": {\"prompt_tokens\": 3, \"completion_tokens\": 5, \"total_tokens\": 8}}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	var events []RequestEvent
+	client.Use(Hooks{AfterResponse: func(e RequestEvent) { events = append(events, e) }})
+
+	var chunks []*StreamChunk
+	summary, err := client.ChatStreamFunc(context.Background(), ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil, nil, func(chunk *StreamChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, chunks, 6)
+	assert.GreaterOrEqual(t, chunks[0].Elapsed, 10*time.Millisecond)
+	assert.Equal(t, chunks[0].Elapsed, chunks[0].Delta)
+	for i := 1; i < len(chunks); i++ {
+		assert.Equal(t, chunks[i].Elapsed-chunks[i-1].Elapsed, chunks[i].Delta)
+	}
+
+	stats := summary.Stats
+	assert.Equal(t, 6, stats.Chunks)
+	assert.Equal(t, chunks[0].Elapsed, stats.TimeToFirstChunk)
+	assert.GreaterOrEqual(t, stats.Duration, chunks[5].Elapsed)
+	assert.Positive(t, stats.TokensPerSecond)
+	assert.Less(t, stats.TokensPerSecond, 200.0, "about 4 tokens in 40ms or more")
+
+	require.Len(t, events, 1)
+	require.NotNil(t, events[0].Stream)
+	assert.Equal(t, 6, events[0].Stream.Chunks)
+}
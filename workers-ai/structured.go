@@ -0,0 +1,310 @@
+package workersai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DefaultStructuredRetries is the number of re-prompts ChatJSON makes when
+// StructuredOptions.MaxRetries is zero.
+const DefaultStructuredRetries = 2
+
+// StructuredOptions configures ChatJSON.
+type StructuredOptions struct {
+	// MaxRetries caps the number of re-prompts after an invalid reply.
+	// Defaults to DefaultStructuredRetries; set it to -1 to disable retries.
+	MaxRetries int
+	// Schema, if set, is checked against the decoded reply: required
+	// properties must be present and properties must have their declared
+	// JSON type.
+	Schema *FunctionParameters
+	// Validate, if set, is called with the decoded value for checks that go
+	// beyond the schema.
+	Validate func(v interface{}) error
+	// DisableRepair turns off the repair of almost-valid JSON replies by
+	// RepairJSON, which otherwise happens before validation and is
+	// reported in ChatResponse.Repaired.
+	DisableRepair bool
+}
+
+// InvalidOutputError is returned by ChatJSON when no reply passed validation.
+type InvalidOutputError struct {
+	// Content is the last reply of the model.
+	Content string
+	// Errors are the validation errors, one per attempt.
+	Errors []error
+}
+
+func (e *InvalidOutputError) Error() string {
+	return fmt.Sprintf("invalid output after %d attempts: %v", len(e.Errors), e.Errors[len(e.Errors)-1])
+}
+
+func (e *InvalidOutputError) Unwrap() error {
+	return errors.Join(e.Errors...)
+}
+
+// ChatJSON sends request and decodes the JSON reply into out. When the reply
+// isn't valid JSON or fails validation, the model is asked again with the
+// errors appended to the conversation, up to opts.MaxRetries times. out,
+// a pointer, is only set once a reply is valid, and then to that reply
+// alone.
+//
+// The usage of the returned response covers all attempts.
+func (c *Client) ChatJSON(request ChatCompletionRequest, out interface{}, opts StructuredOptions) (*ChatResponse, error) {
+	return chatJSON(c, c.debugLog, request, out, opts)
+}
+
+// chatJSON implements ChatJSON for any client, logging with logf.
+func chatJSON(client ClientInterface, logf func(string, ...interface{}), request ChatCompletionRequest, out interface{}, opts StructuredOptions) (*ChatResponse, error) {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return nil, fmt.Errorf("out must be a non-nil pointer, got %T", out)
+	}
+	attempts := structuredAttempts(opts.MaxRetries)
+	messages := append([]Message(nil), request.Messages...)
+	invalid := &InvalidOutputError{}
+	var usage Usage
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		request.Messages = messages
+
+		resp, err := client.ChatCompletion(request)
+		if err != nil {
+			return nil, err
+		}
+		u := resp.GetUsage()
+		usage.PromptTokens += u.PromptTokens
+		usage.CompletionTokens += u.CompletionTokens
+		usage.TotalTokens += u.TotalTokens
+
+		// Decode every attempt into a new value, so that the fields of a
+		// failed one don't leak into out.
+		content := resp.GetContent()
+		value := reflect.New(target.Type().Elem())
+		repaired, err := decodeStructured(content, value.Interface(), opts)
+		if err == nil {
+			target.Elem().Set(value.Elem())
+			resp.setUsage(usage)
+			resp.Repaired = repaired
+			return resp, nil
+		}
+
+		logf("Invalid structured output (attempt %d): %v", attempt+1, err)
+		invalid.Content = content
+		invalid.Errors = append(invalid.Errors, err)
+
+		messages = append(messages,
+			ChatMessage{Role: "assistant", Content: content},
+			ChatMessage{Role: "user", Content: fmt.Sprintf("Your reply was invalid: %v. Reply again with only the corrected JSON.", err)},
+		)
+	}
+
+	return nil, invalid
+}
+
+// structuredAttempts returns the number of attempts allowed by maxRetries,
+// as documented on StructuredOptions.MaxRetries.
+func structuredAttempts(maxRetries int) int {
+	switch {
+	case maxRetries == 0:
+		return DefaultStructuredRetries + 1
+	case maxRetries < 0:
+		return 1
+	}
+	return maxRetries + 1
+}
+
+// decodeStructured unmarshals the JSON in content into out, repaired
+// unless opts.DisableRepair, and validates it. It reports whether the JSON
+// was repaired.
+func decodeStructured(content string, out interface{}, opts StructuredOptions) (repaired bool, err error) {
+	raw := []byte(extractJSON(content))
+	if !opts.DisableRepair && !json.Valid(raw) {
+		// Repair from the start of the value: a value cut off has no
+		// closing bracket for extractJSON to stop at.
+		unfenced := unfenceJSON(content)
+		if start := strings.IndexAny(unfenced, "{["); start >= 0 {
+			unfenced = unfenced[start:]
+		}
+		if fixed, ok := RepairJSON(unfenced); ok && json.Valid([]byte(fixed)) {
+			raw, repaired = []byte(fixed), true
+		}
+	}
+
+	if opts.Schema != nil {
+		var object map[string]interface{}
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return repaired, fmt.Errorf("reply is not a JSON object: %w", err)
+		}
+		if err := validateSchema(object, opts.Schema); err != nil {
+			return repaired, err
+		}
+	}
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return repaired, fmt.Errorf("reply is not valid JSON: %w", err)
+	}
+
+	if opts.Validate != nil {
+		return repaired, opts.Validate(out)
+	}
+	return repaired, nil
+}
+
+// extractJSON returns the JSON value in content, stripping Markdown code
+// fences and any prose around the outermost object or array.
+func extractJSON(content string) string {
+	content = unfenceJSON(content)
+
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return content
+	}
+	closing := "}"
+	if content[start] == '[' {
+		closing = "]"
+	}
+	if end := strings.LastIndex(content, closing); end > start {
+		return content[start : end+1]
+	}
+	return content
+}
+
+// unfenceJSON returns the content of the first Markdown code block of
+// content, if any.
+func unfenceJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if start := strings.Index(content, "```"); start >= 0 {
+		fenced := content[start+3:]
+		if end := strings.Index(fenced, "```"); end >= 0 {
+			fenced = fenced[:end]
+		}
+		// Drop the language tag, e.g. "json".
+		if newline := strings.IndexByte(fenced, '\n'); newline >= 0 && !strings.ContainsAny(fenced[:newline], "{[") {
+			fenced = fenced[newline+1:]
+		}
+		content = strings.TrimSpace(fenced)
+	}
+	return content
+}
+
+// validateSchema checks the required properties and the property types of
+// object against schema.
+func validateSchema(object map[string]interface{}, schema *FunctionParameters) error {
+	var problems []string
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			problems = append(problems, fmt.Sprintf("missing required property %q", name))
+		}
+	}
+	// Properties are checked in name order, so that the errors fed back
+	// to the model are deterministic.
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := object[name]
+		param, ok := schema.Properties[name]
+		if !ok || param == nil {
+			continue
+		}
+		if err := validateParameter(value, param); err != nil {
+			problems = append(problems, fmt.Sprintf("property %q %v", name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateObject checks the properties of a nested object, and the
+// values of one used as a map against AdditionalProperties.
+func validateObject(object map[string]interface{}, param *Parameter) error {
+	if len(param.Properties) > 0 || len(param.Required) > 0 {
+		// Like at the top level, null stands for a property left out.
+		present := make(map[string]interface{}, len(object))
+		for key, value := range object {
+			if value != nil {
+				present[key] = value
+			}
+		}
+		object = present
+		if err := validateSchema(object, &FunctionParameters{Type: "object", Properties: param.Properties, Required: param.Required}); err != nil {
+			return fmt.Errorf("has %v", err)
+		}
+	}
+	if param.AdditionalProperties == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validateParameter(object[key], param.AdditionalProperties); err != nil {
+			return fmt.Errorf("value %q %v", key, err)
+		}
+	}
+	return nil
+}
+
+func validateParameter(value interface{}, param *Parameter) error {
+	var ok bool
+	switch param.Type {
+	case "string":
+		var s string
+		if s, ok = value.(string); ok && len(param.Enum) > 0 {
+			for _, allowed := range param.Enum {
+				if s == allowed {
+					return nil
+				}
+			}
+			return fmt.Errorf("must be one of %s", strings.Join(param.Enum, ", "))
+		}
+	case "number":
+		_, ok = value.(float64)
+	case "integer":
+		var f float64
+		f, ok = value.(float64)
+		ok = ok && f == float64(int64(f))
+	case "boolean":
+		_, ok = value.(bool)
+	case "object":
+		var object map[string]interface{}
+		if object, ok = value.(map[string]interface{}); ok {
+			return validateObject(object, param)
+		}
+	case "array":
+		var items []interface{}
+		if items, ok = value.([]interface{}); ok && param.Items != nil {
+			for i, item := range items {
+				if err := validateParameter(item, param.Items); err != nil {
+					return fmt.Errorf("item %d %v", i, err)
+				}
+			}
+		}
+	default:
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("must be of type %s", param.Type)
+	}
+	return nil
+}
+
+func (r *ChatResponse) setUsage(u Usage) {
+	if r.IsLegacyResult {
+		r.LegacyResponse.Usage = u
+		return
+	}
+	r.ChatCompletionResponse.Usage = u
+}
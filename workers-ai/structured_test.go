@@ -0,0 +1,126 @@
+package workersai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ChatJSON(t *testing.T) {
+	replies := []string{
+		"Sure! Here it is: {\"name\": \"Ada\"",
+		"```json\n{\"name\": \"Ada\", \"age\": \"36\"}\n```",
+		"{\"name\": \"Ada\", \"age\": 36}",
+	}
+	var requests []ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q, "usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}}`, replies[len(requests)-1])
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	schema := &FunctionParameters{
+		Type: "object",
+		Properties: map[string]*Parameter{
+			"name": {Type: "string"},
+			"age":  {Type: "integer"},
+		},
+		Required: []string{"name", "age"},
+	}
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Who wrote the first program?"}}}
+
+	var person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	resp, err := client.ChatJSON(request, &person, StructuredOptions{Schema: schema})
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", person.Name)
+	assert.Equal(t, 36, person.Age)
+	assert.Equal(t, Usage{PromptTokens: 30, CompletionTokens: 15, TotalTokens: 45}, resp.GetUsage())
+
+	require.Len(t, requests, 3)
+	require.Len(t, requests[2].Messages, 5)
+	assert.Equal(t, ChatMessage{Role: "assistant", Content: replies[1]}, requests[2].Messages[3])
+	assert.Contains(t, requests[2].Messages[4].(ChatMessage).Content, `property "age" must be of type integer`)
+}
+
+func TestClient_ChatJSON_GivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "result": {"response": "{\"answer\": 41}"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	wrong := errors.New("wrong answer")
+	var out map[string]int
+	_, err := client.ChatJSON(ChatCompletionRequest{Model: ModelLlama38B}, &out, StructuredOptions{
+		MaxRetries: 1,
+		Validate: func(v interface{}) error {
+			if (*v.(*map[string]int))["answer"] != 42 {
+				return wrong
+			}
+			return nil
+		},
+	})
+
+	var invalid *InvalidOutputError
+	require.ErrorAs(t, err, &invalid)
+	assert.Len(t, invalid.Errors, 2)
+	assert.Equal(t, `{"answer": 41}`, invalid.Content)
+	assert.ErrorIs(t, err, wrong)
+}
+
+func TestClient_ChatJSON_FreshAttempts(t *testing.T) {
+	replies := []string{`{"name": "Ada", "age": "36"}`, `{"age": 36}`}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": map[string]string{"response": replies[calls]}})
+		calls++
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	// The name decoded from the failed attempt is discarded.
+	out := struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}{Name: "unset"}
+	_, err := client.ChatJSON(ChatCompletionRequest{Model: ModelLlama38B}, &out, StructuredOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "", out.Name)
+	assert.Equal(t, 36, out.Age)
+
+	var notPointer map[string]int
+	_, err = client.ChatJSON(ChatCompletionRequest{Model: ModelLlama38B}, notPointer, StructuredOptions{})
+	assert.EqualError(t, err, "out must be a non-nil pointer, got map[string]int")
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"a": 1}`:                         `{"a": 1}`,
+		"```json\n{\"a\": 1}\n```":         `{"a": 1}`,
+		"Here you go:\n```\n[1, 2]\n```":   `[1, 2]`,
+		`The answer is {"a": {"b": 2}}. ✓`: `{"a": {"b": 2}}`,
+		"no json here":                     "no json here",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, extractJSON(input), input)
+	}
+}
@@ -0,0 +1,176 @@
+package workersai
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// errTeeClosed is returned by TeeStream.Recv after Close.
+var errTeeClosed = errors.New("stream closed")
+
+// Tee splits the stream into n streams receiving the same chunks, so that
+// one generation can feed, for instance, a websocket to the user and a
+// server-side logger at the same time. The response body is read once, by
+// a goroutine, and each branch queues the chunks it has yet to receive: a
+// slow branch doesn't hold back the others.
+//
+// The stream must not be used directly after Tee; it is closed once it
+// ended or every branch was closed. The chunks are shared by the branches
+// and must not be modified.
+func (s *ChatStream) Tee(n int) []*TeeStream {
+	tee := &streamTee{source: s, open: n}
+	branches := make([]*TeeStream, n)
+	for i := range branches {
+		branches[i] = &TeeStream{tee: tee, ready: make(chan struct{}, 1)}
+	}
+	tee.branches = branches
+	go tee.pump()
+	return branches
+}
+
+// streamTee reads the source of the branches of ChatStream.Tee.
+type streamTee struct {
+	source   *ChatStream
+	branches []*TeeStream
+
+	mu      sync.Mutex
+	open    int
+	ended   bool
+	summary StreamSummary
+}
+
+// pump reads the source until it ends, or every branch was closed, and
+// hands the chunks to the open branches.
+func (t *streamTee) pump() {
+	defer t.source.Close()
+
+	for {
+		var chunk *StreamChunk
+		err := func() (err error) {
+			defer recoverPanic(&err)
+			chunk, err = t.source.Recv()
+			return err
+		}()
+
+		if err != nil {
+			t.mu.Lock()
+			if t.open == 0 {
+				// The request was aborted by closing the branches.
+				t.source.cancelled = true
+			}
+			t.ended = true
+			t.summary = t.source.Summary()
+			t.mu.Unlock()
+		}
+		for _, branch := range t.branches {
+			branch.push(StreamResult{Chunk: chunk, Err: err})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// TeeStream is a branch of a stream split by ChatStream.Tee. Read it with
+// Recv until it returns io.EOF, like a ChatStream, and always Close it.
+//
+// Each branch is meant to be read by its own goroutine; Close may be
+// called from any goroutine.
+type TeeStream struct {
+	tee   *streamTee
+	ready chan struct{}
+
+	mu      sync.Mutex
+	queue   []StreamResult
+	closed  bool
+	err     error
+	content strings.Builder
+}
+
+// push queues result unless the branch was closed.
+func (b *TeeStream) push(result StreamResult) {
+	b.mu.Lock()
+	if !b.closed {
+		b.queue = append(b.queue, result)
+	}
+	b.mu.Unlock()
+
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Recv returns the next chunk of the response. It returns io.EOF once the
+// stream completed; any other error means the stream was interrupted.
+func (b *TeeStream) Recv() (*StreamChunk, error) {
+	for {
+		b.mu.Lock()
+		if b.err != nil {
+			b.mu.Unlock()
+			return nil, b.err
+		}
+		if len(b.queue) > 0 {
+			result := b.queue[0]
+			b.queue[0] = StreamResult{}
+			b.queue = b.queue[1:]
+			if result.Err != nil {
+				b.err = result.Err
+			} else {
+				b.content.WriteString(result.Chunk.Content)
+			}
+			b.mu.Unlock()
+			return result.Chunk, result.Err
+		}
+		b.mu.Unlock()
+		<-b.ready
+	}
+}
+
+// Content returns the text received so far by this branch.
+func (b *TeeStream) Content() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.content.String()
+}
+
+// Summary returns the summary of the stream once it ended, and whether it
+// did. The stream may end before this branch received all of its chunks.
+func (b *TeeStream) Summary() (StreamSummary, bool) {
+	b.tee.mu.Lock()
+	defer b.tee.mu.Unlock()
+	return b.tee.summary, b.tee.ended
+}
+
+// Close detaches the branch from the stream. Closing the last open branch
+// before the stream completed aborts the request.
+func (b *TeeStream) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.queue = nil
+	if b.err == nil {
+		b.err = errTeeClosed
+	}
+	b.mu.Unlock()
+
+	// Wake up a Recv waiting in another goroutine.
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+
+	b.tee.mu.Lock()
+	b.tee.open--
+	last := b.tee.open == 0 && !b.tee.ended
+	b.tee.mu.Unlock()
+	if last {
+		// The pump sees the cancellation and closes the source.
+		b.tee.source.cancel()
+	}
+	return nil
+}
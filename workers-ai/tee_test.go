@@ -0,0 +1,109 @@
+package workersai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatStream_Tee(t *testing.T) {
+	server := newStreamServer(t,
+		`{"response": "Hello"}`,
+		`{"response": " world"}`,
+		`{"response": "", "usage": {"prompt_tokens": 4, "completion_tokens": 2, "total_tokens": 6}}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil, nil)
+	require.NoError(t, err)
+	branches := stream.Tee(2)
+	require.Len(t, branches, 2)
+
+	pieces := make([][]string, len(branches))
+	var wg sync.WaitGroup
+	for i, branch := range branches {
+		wg.Add(1)
+		go func(i int, branch *TeeStream) {
+			defer wg.Done()
+			defer branch.Close()
+			for {
+				chunk, err := branch.Recv()
+				if err == io.EOF {
+					return
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+				pieces[i] = append(pieces[i], chunk.Content)
+			}
+		}(i, branch)
+	}
+	wg.Wait()
+
+	for i, branch := range branches {
+		assert.Equal(t, []string{"Hello", " world", ""}, pieces[i])
+		assert.Equal(t, "Hello world", branch.Content())
+		summary, ended := branch.Summary()
+		assert.True(t, ended)
+		assert.Equal(t, "Hello world", summary.Content)
+		assert.Equal(t, 6, summary.Usage.TotalTokens)
+
+		_, err := branch.Recv()
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestChatStream_Tee_Close(t *testing.T) {
+	// The server sends a chunk, then waits for the client to go away.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"response\": \"Hello\"}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stream, err := client.ChatStream(context.Background(), ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil, nil)
+	require.NoError(t, err)
+	branches := stream.Tee(2)
+
+	chunk, err := branches[0].Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", chunk.Content)
+
+	// Closing one branch leaves the other one reading.
+	require.NoError(t, branches[1].Close())
+	_, err = branches[1].Recv()
+	assert.EqualError(t, err, "stream closed")
+	summary, ended := branches[0].Summary()
+	assert.False(t, ended)
+	assert.Empty(t, summary.Content)
+
+	// Closing the last one aborts the request.
+	done := make(chan error, 1)
+	go func() {
+		_, err := branches[0].Recv()
+		done <- err
+	}()
+	require.NoError(t, branches[0].Close())
+	assert.EqualError(t, <-done, "stream closed")
+
+	assert.Eventually(t, func() bool {
+		summary, ended := branches[0].Summary()
+		return ended && summary.Cancelled
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,195 @@
+package workersai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChatTemplate turns a conversation into the single prompt a base model
+// was trained on, with its special tokens, so that models without a chat
+// template of their own behave like chat models in raw prompt mode.
+type ChatTemplate struct {
+	Name string
+	// Render builds the prompt of messages, ending with the header of the
+	// assistant turn the model is to write.
+	Render func(messages []Message) (string, error)
+	// Stop lists the tokens ending the assistant turn. They are sent as the
+	// stop sequences of the request, and the reply is also cut at the
+	// first of them, for the models that ignore stop sequences and keep
+	// writing past them.
+	Stop []string
+}
+
+// TemplateLlama3 is the template of the Llama 3 family.
+var TemplateLlama3 = ChatTemplate{
+	Name: "llama3",
+	Render: func(messages []Message) (string, error) {
+		turns, err := templateTurns(messages)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		b.WriteString("<|begin_of_text|>")
+		for _, t := range turns {
+			fmt.Fprintf(&b, "<|start_header_id|>%s<|end_header_id|>\n\n%s<|eot_id|>", t.role, t.content)
+		}
+		b.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+		return b.String(), nil
+	},
+	Stop: []string{"<|eot_id|>", "<|end_of_text|>"},
+}
+
+// TemplateLlama2 is the template of Llama 2 and Code Llama, with the system
+// prompt in the first instruction.
+var TemplateLlama2 = ChatTemplate{
+	Name: "llama2",
+	Render: func(messages []Message) (string, error) {
+		return renderInst(messages, "<<SYS>>\n%s\n<</SYS>>\n\n")
+	},
+	Stop: []string{"</s>", "[INST]"},
+}
+
+// TemplateMistral is the template of Mistral Instruct, which has no system
+// role: the system prompt is prepended to the first user message.
+var TemplateMistral = ChatTemplate{
+	Name: "mistral",
+	Render: func(messages []Message) (string, error) {
+		return renderInst(messages, "%s\n\n")
+	},
+	Stop: []string{"</s>", "[INST]"},
+}
+
+// TemplateChatML is the ChatML template of the Qwen family.
+var TemplateChatML = ChatTemplate{
+	Name: "chatml",
+	Render: func(messages []Message) (string, error) {
+		turns, err := templateTurns(messages)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		for _, t := range turns {
+			fmt.Fprintf(&b, "<|im_start|>%s\n%s<|im_end|>\n", t.role, t.content)
+		}
+		b.WriteString("<|im_start|>assistant\n")
+		return b.String(), nil
+	},
+	Stop: []string{"<|im_end|>", "<|endoftext|>"},
+}
+
+// templateTurn is a message reduced to what templates render.
+type templateTurn struct {
+	role    string
+	content string
+}
+
+// templateTurns extracts the role and text of messages. Tool turns can't be
+// rendered; use ToolEmulation to turn them into text first.
+func templateTurns(messages []Message) ([]templateTurn, error) {
+	turns := make([]templateTurn, 0, len(messages))
+	for i, msg := range messages {
+		switch m := msg.(type) {
+		case ChatMessage:
+			if len(m.ToolCalls) > 0 {
+				return nil, fmt.Errorf("message %d: chat templates don't support tool calls", i)
+			}
+			turns = append(turns, templateTurn{role: m.Role, content: m.Content})
+		case ResponseMessage:
+			if len(m.ToolCalls) > 0 {
+				return nil, fmt.Errorf("message %d: chat templates don't support tool calls", i)
+			}
+			turn := templateTurn{role: m.Role}
+			if m.Content != nil {
+				turn.content = *m.Content
+			}
+			turns = append(turns, turn)
+		default:
+			return nil, fmt.Errorf("message %d: chat templates don't support %T", i, msg)
+		}
+	}
+	return turns, nil
+}
+
+// renderInst renders the [INST] templates of Llama 2 and Mistral, where
+// each user message is an instruction followed by the assistant's reply.
+// system formats the system prompt into the first instruction.
+func renderInst(messages []Message, system string) (string, error) {
+	turns, err := templateTurns(messages)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	var prefix string
+	for _, t := range turns {
+		switch t.role {
+		case "system":
+			prefix += fmt.Sprintf(system, t.content)
+		case "user":
+			fmt.Fprintf(&b, "<s>[INST] %s%s [/INST]", prefix, t.content)
+			prefix = ""
+		case "assistant":
+			fmt.Fprintf(&b, " %s </s>", t.content)
+		default:
+			return "", fmt.Errorf("chat template doesn't support role %q", t.role)
+		}
+	}
+	if prefix != "" {
+		return "", fmt.Errorf("chat template needs a user message after the system prompt")
+	}
+	return b.String(), nil
+}
+
+// rawPromptRequest is the input of text generation models in raw prompt
+// mode, where the prompt is sent without applying the model's template.
+type rawPromptRequest struct {
+	Prompt string   `json:"prompt"`
+	Raw    bool     `json:"raw"`
+	Stop   []string `json:"stop,omitempty"`
+	ModelParameters
+}
+
+// template returns the chat template the client applies for modelID.
+func (c *Client) template(modelID string) (ChatTemplate, bool) {
+	for model, template := range c.Templates {
+		if model == modelID || "@cf/"+model == modelID || model == "@cf/"+modelID {
+			return template, true
+		}
+	}
+	return ChatTemplate{}, false
+}
+
+// rawPrompt renders request with template for raw prompt mode.
+func rawPrompt(request ChatCompletionRequest, template ChatTemplate) (rawPromptRequest, error) {
+	if len(request.Tools) > 0 {
+		return rawPromptRequest{}, fmt.Errorf("chat template %s doesn't support tools; use ToolEmulation", template.Name)
+	}
+	prompt, err := template.Render(request.Messages)
+	if err != nil {
+		return rawPromptRequest{}, fmt.Errorf("failed to render chat template %s: %w", template.Name, err)
+	}
+	return rawPromptRequest{Prompt: prompt, Raw: true, Stop: template.Stop, ModelParameters: request.ModelParameters}, nil
+}
+
+// cutStop cuts the reply of r at the first stop token of t.
+func (t ChatTemplate) cutStop(r *ChatResponse) {
+	cut := func(s string) string {
+		for _, stop := range t.Stop {
+			if i := strings.Index(s, stop); i >= 0 {
+				s = s[:i]
+			}
+		}
+		return strings.TrimRight(s, " \n")
+	}
+
+	if r.IsLegacyResult {
+		r.LegacyResponse.Response = cut(r.LegacyResponse.Response)
+		return
+	}
+	for i := range r.ChatCompletionResponse.Choices {
+		if content := r.ChatCompletionResponse.Choices[i].Message.Content; content != nil {
+			cutContent := cut(*content)
+			r.ChatCompletionResponse.Choices[i].Message.Content = &cutContent
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package workersai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatTemplate_Render(t *testing.T) {
+	messages := []Message{
+		ChatMessage{Role: "system", Content: "Be brief."},
+		ChatMessage{Role: "user", Content: "Hi"},
+		ChatMessage{Role: "assistant", Content: "Hello!"},
+		ChatMessage{Role: "user", Content: "Capital of France?"},
+	}
+
+	tests := []struct {
+		template ChatTemplate
+		want     string
+	}{
+		{TemplateLlama3, "<|begin_of_text|>" +
+			"<|start_header_id|>system<|end_header_id|>\n\nBe brief.<|eot_id|>" +
+			"<|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\nHello!<|eot_id|>" +
+			"<|start_header_id|>user<|end_header_id|>\n\nCapital of France?<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\n"},
+		{TemplateLlama2, "<s>[INST] <<SYS>>\nBe brief.\n<</SYS>>\n\nHi [/INST] Hello! </s>" +
+			"<s>[INST] Capital of France? [/INST]"},
+		{TemplateMistral, "<s>[INST] Be brief.\n\nHi [/INST] Hello! </s>" +
+			"<s>[INST] Capital of France? [/INST]"},
+		{TemplateChatML, "<|im_start|>system\nBe brief.<|im_end|>\n" +
+			"<|im_start|>user\nHi<|im_end|>\n" +
+			"<|im_start|>assistant\nHello!<|im_end|>\n" +
+			"<|im_start|>user\nCapital of France?<|im_end|>\n" +
+			"<|im_start|>assistant\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template.Name, func(t *testing.T) {
+			prompt, err := tt.template.Render(messages)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, prompt)
+		})
+	}
+
+	_, err := TemplateLlama3.Render([]Message{ToolMessage{Role: "tool", Content: "sunny", ToolCallID: "call_1"}})
+	assert.Error(t, err)
+	_, err = TemplateMistral.Render([]Message{ChatMessage{Role: "system", Content: "Be brief."}})
+	assert.Error(t, err)
+}
+
+func TestClient_Templates(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"success": true, "result": {"response": "Paris.<|eot_id|><|start_header_id|>user<|end_header_id|>\n\nThanks"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.Templates = map[string]ChatTemplate{"meta/llama-3-8b": TemplateLlama3}
+
+	resp, err := client.Chat("@cf/meta/llama-3-8b", []Message{
+		ChatMessage{Role: "user", Content: "Capital of France?"},
+	}, &ModelParameters{MaxTokens: 20})
+	require.NoError(t, err)
+	assert.Equal(t, "Paris.", resp.GetContent())
+
+	assert.Equal(t, true, request["raw"])
+	assert.Equal(t, float64(20), request["max_tokens"])
+	assert.Equal(t, []interface{}{"<|eot_id|>", "<|end_of_text|>"}, request["stop"])
+	assert.NotContains(t, request, "messages")
+	assert.Equal(t, "<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\nCapital of France?<|eot_id|>"+
+		"<|start_header_id|>assistant<|end_header_id|>\n\n", request["prompt"])
+
+	_, err = client.ChatWithTools("@cf/meta/llama-3-8b", []Message{
+		ChatMessage{Role: "user", Content: "Weather?"},
+	}, []Tool{{Type: "function", Function: FunctionDefinition{Name: "get_weather"}}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// metadataHeader carries custom metadata that AI Gateway stores with the
+// logs of a request.
+const metadataHeader = "cf-aig-metadata"
+
+// TenantMetadataKey is the metadata key under which the tenant of a
+// request is sent to AI Gateway.
+const TenantMetadataKey = "tenant"
+
+type tagsKey struct{}
+
+// requestTags attribute requests to an end customer.
+type requestTags struct {
+	tenant   string
+	metadata map[string]string
+}
+
+// WithTenant tags the requests made with ctx as made on behalf of tenant.
+// The tenant is sent to AI Gateway as metadata and reported in
+// RequestEvent.Tenant, so that costs can be attributed per customer.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	tags := tagsFrom(ctx)
+	tags.tenant = tenant
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// WithMetadata adds metadata to the requests made with ctx. It is sent to
+// AI Gateway, which accepts at most five entries, and reported in
+// RequestEvent.Metadata. Entries of an outer context are kept unless
+// overridden.
+func WithMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	tags := tagsFrom(ctx)
+	merged := make(map[string]string, len(tags.metadata)+len(metadata))
+	for k, v := range tags.metadata {
+		merged[k] = v
+	}
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	tags.metadata = merged
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// TenantFromContext returns the tenant set with WithTenant, if any.
+func TenantFromContext(ctx context.Context) string {
+	return tagsFrom(ctx).tenant
+}
+
+func tagsFrom(ctx context.Context) requestTags {
+	tags, _ := ctx.Value(tagsKey{}).(requestTags)
+	return tags
+}
+
+// tagContext adds the tenant and metadata of request to ctx.
+func (request *ChatCompletionRequest) tagContext(ctx context.Context) context.Context {
+	if request.Tenant != "" {
+		ctx = WithTenant(ctx, request.Tenant)
+	}
+	if len(request.Metadata) > 0 {
+		ctx = WithMetadata(ctx, request.Metadata)
+	}
+	return ctx
+}
+
+// tagRequest records the tags of ctx in event and adds them to the
+// headers of req.
+func tagRequest(ctx context.Context, req *http.Request, event *RequestEvent) {
+	tags := tagsFrom(ctx)
+	event.Tenant = tags.tenant
+	event.Metadata = tags.metadata
+
+	if tags.tenant == "" && len(tags.metadata) == 0 {
+		return
+	}
+	metadata := make(map[string]string, len(tags.metadata)+1)
+	for k, v := range tags.metadata {
+		metadata[k] = v
+	}
+	if tags.tenant != "" {
+		metadata[TenantMetadataKey] = tags.tenant
+	}
+	if value, err := json.Marshal(metadata); err == nil {
+		req.Header.Set(metadataHeader, string(value))
+	}
+}
+
+// UsageTracker sums the token usage of a client's requests per tenant.
+// Register it with Client.Use(tracker.Hooks()). Requests without a tenant
+// are counted under "". A UsageTracker is safe for concurrent use.
+type UsageTracker struct {
+	mu       sync.Mutex
+	usage    map[string]Usage
+	requests map[string]int
+	// daily breaks the usage down per day, tenant and model, for Report.
+	daily map[usageKey]*UsageRow
+	now   func() time.Time
+}
+
+// NewUsageTracker returns an empty tracker.
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		usage:    make(map[string]Usage),
+		requests: make(map[string]int),
+		daily:    make(map[usageKey]*UsageRow),
+		now:      time.Now,
+	}
+}
+
+// Hooks returns the client hooks feeding the tracker.
+func (t *UsageTracker) Hooks() Hooks {
+	return Hooks{AfterResponse: t.record}
+}
+
+func (t *UsageTracker) record(event RequestEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage := t.usage[event.Tenant]
+	usage.PromptTokens += event.Usage.PromptTokens
+	usage.CompletionTokens += event.Usage.CompletionTokens
+	usage.TotalTokens += event.Usage.TotalTokens
+	t.usage[event.Tenant] = usage
+	t.requests[event.Tenant]++
+
+	key := usageKey{day: t.now().UTC().Format(reportDay), tenant: event.Tenant, model: event.Model}
+	row, ok := t.daily[key]
+	if !ok {
+		row = &UsageRow{Day: key.day, Tenant: key.tenant, Model: key.model}
+		t.daily[key] = row
+	}
+	row.add(event.Usage, event.Err != nil)
+}
+
+// Usage returns the usage of tenant so far.
+func (t *UsageTracker) Usage(tenant string) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage[tenant]
+}
+
+// Requests returns the number of requests of tenant so far.
+func (t *UsageTracker) Requests(tenant string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.requests[tenant]
+}
+
+// Tenants returns the tenants seen so far, sorted.
+func (t *UsageTracker) Tenants() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tenants := make([]string, 0, len(t.usage))
+	for tenant := range t.usage {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
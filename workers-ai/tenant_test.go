@@ -0,0 +1,69 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantTagging(t *testing.T) {
+	var headers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get(metadataHeader))
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi", "usage": {"prompt_tokens": 2, "completion_tokens": 1, "total_tokens": 3}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	tracker := NewUsageTracker()
+	client.Use(tracker.Hooks())
+	var events []RequestEvent
+	client.Use(Hooks{AfterResponse: func(e RequestEvent) { events = append(events, e) }})
+
+	messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+	_, err := client.ChatCompletion(ChatCompletionRequest{Model: ModelLlama38B, Messages: messages, Tenant: "acme", Metadata: map[string]string{"plan": "pro"}})
+	require.NoError(t, err)
+
+	ctx := WithMetadata(WithTenant(context.Background(), "globex"), map[string]string{"job": "nightly"})
+	assert.Equal(t, "globex", TenantFromContext(ctx))
+	for i := 0; i < 2; i++ {
+		_, err = client.ChatCompletionContext(ctx, ChatCompletionRequest{Model: ModelLlama38B, Messages: messages})
+		require.NoError(t, err)
+	}
+
+	_, err = client.Chat(ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+
+	require.Len(t, headers, 4)
+	var metadata map[string]string
+	require.NoError(t, json.Unmarshal([]byte(headers[0]), &metadata))
+	assert.Equal(t, map[string]string{"tenant": "acme", "plan": "pro"}, metadata)
+	metadata = nil
+	require.NoError(t, json.Unmarshal([]byte(headers[1]), &metadata))
+	assert.Equal(t, map[string]string{"tenant": "globex", "job": "nightly"}, metadata)
+	assert.Empty(t, headers[3])
+
+	assert.Equal(t, "acme", events[0].Tenant)
+	assert.Equal(t, map[string]string{"plan": "pro"}, events[0].Metadata)
+
+	assert.Equal(t, []string{"", "acme", "globex"}, tracker.Tenants())
+	assert.Equal(t, Usage{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6}, tracker.Usage("globex"))
+	assert.Equal(t, 2, tracker.Requests("globex"))
+	assert.Equal(t, 1, tracker.Requests(""))
+}
+
+func TestWithMetadata_Merges(t *testing.T) {
+	ctx := WithMetadata(context.Background(), map[string]string{"a": "1", "b": "2"})
+	inner := WithMetadata(ctx, map[string]string{"b": "3"})
+	assert.Equal(t, map[string]string{"a": "1", "b": "3"}, tagsFrom(inner).metadata)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, tagsFrom(ctx).metadata)
+}
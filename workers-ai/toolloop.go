@@ -0,0 +1,230 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultMaxToolSteps caps the model requests of a tool loop when
+// ToolBudget.MaxSteps is zero.
+const DefaultMaxToolSteps = 10
+
+// Limits of a ToolBudget, reported by BudgetExceededError.
+const (
+	BudgetTokens   = "tokens"
+	BudgetCalls    = "calls"
+	BudgetSteps    = "steps"
+	BudgetDuration = "duration"
+)
+
+// ToolBudget limits a tool loop. Zero fields don't limit it, but for
+// MaxSteps.
+type ToolBudget struct {
+	// MaxTokens caps the total tokens of the model requests. The loop
+	// stops before executing the tool calls of a reply exceeding it.
+	MaxTokens int
+	// MaxCalls caps the tool calls executed.
+	MaxCalls int
+	// MaxSteps caps the model requests. Defaults to DefaultMaxToolSteps.
+	MaxSteps int
+	// MaxDuration caps the wall-clock time of the loop, tool executions
+	// included.
+	MaxDuration time.Duration
+	// MaxRepeats caps the calls of a tool with the same arguments, to stop
+	// a model stuck calling it again and again. The loop then fails with
+	// a *RepeatedToolCallError.
+	MaxRepeats int
+	// CorrectRepeats makes the loop, the first time a call is repeated
+	// beyond MaxRepeats, skip it and tell the model so with a system
+	// message instead of failing.
+	CorrectRepeats bool
+}
+
+// ToolLoopResult is the outcome of a tool loop.
+type ToolLoopResult struct {
+	// Response is the last reply of the model, without tool calls.
+	Response *ChatResponse
+	// Messages is the conversation, from the messages of the request to
+	// the last reply.
+	Messages []Message
+	// Usage sums the usage of the model requests.
+	Usage Usage
+	// Steps counts the model requests; Calls the tool calls executed.
+	Steps   int
+	Calls   int
+	Elapsed time.Duration
+}
+
+// BudgetExceededError is returned by ToolRunner.Loop when it stopped on
+// a limit of its budget.
+type BudgetExceededError struct {
+	// Limit is the limit reached: BudgetTokens, BudgetCalls, BudgetSteps or
+	// BudgetDuration.
+	Limit string
+	// Result is the state of the loop when it stopped; its Messages are
+	// the partial conversation, and its Response the last reply, if any.
+	Result *ToolLoopResult
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("tool loop budget exceeded: %s (%d steps, %d calls, %d tokens, %s)",
+		e.Limit, e.Result.Steps, e.Result.Calls, e.Result.Usage.TotalTokens, e.Result.Elapsed.Round(time.Millisecond))
+}
+
+// RepeatedToolCallError is returned by ToolRunner.Loop when the model
+// repeated a tool call beyond ToolBudget.MaxRepeats.
+type RepeatedToolCallError struct {
+	Call ToolCall
+	// Count is the number of times the call was requested.
+	Count int
+	// Result is the state of the loop when it stopped.
+	Result *ToolLoopResult
+}
+
+func (e *RepeatedToolCallError) Error() string {
+	return fmt.Sprintf("tool loop stopped: %s called %d times with the same arguments", e.Call.Function.Name, e.Count)
+}
+
+// repeatCorrection is the system message telling the model it repeats a
+// tool call.
+const repeatCorrection = "You are calling the same tool with the same arguments over and over. " +
+	"Their results are already in the conversation: use them, try different arguments, or answer."
+
+// Loop sends request with client, executes the tool calls of the replies
+// and sends their results back, until the model replies without calling
+// tools or the budget is exhausted. The request is offered the tools of
+// the runner when it has none. The result so far is returned with errors
+// too.
+//
+// The tools run with the context of the loop, with the deadline of
+// MaxDuration: when the loop times out or ctx is cancelled, the running
+// tool is cancelled and the loop returns without waiting for it.
+func (r *ToolRunner) Loop(ctx context.Context, client ClientInterface, request ChatCompletionRequest, budget ToolBudget) (*ToolLoopResult, error) {
+	maxSteps := budget.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxToolSteps
+	}
+	if request.Tools == nil {
+		request.Tools = r.Tools()
+	}
+
+	start := time.Now()
+	loopCtx := ctx
+	if budget.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		loopCtx, cancel = context.WithTimeout(ctx, budget.MaxDuration)
+		defer cancel()
+	}
+
+	result := &ToolLoopResult{Messages: append([]Message(nil), request.Messages...)}
+	// repeats counts the calls by signature, for MaxRepeats.
+	repeats := make(map[string]int)
+	corrected := false
+	exceeded := func(limit string) error {
+		result.Elapsed = time.Since(start)
+		return &BudgetExceededError{Limit: limit, Result: result}
+	}
+	// timedOut reports whether err is the deadline of the budget rather
+	// than a cancellation by the caller.
+	timedOut := func(err error) bool {
+		return budget.MaxDuration > 0 && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+	}
+
+	for {
+		if result.Steps >= maxSteps {
+			return result, exceeded(BudgetSteps)
+		}
+		if err := loopCtx.Err(); err != nil {
+			if timedOut(err) {
+				return result, exceeded(BudgetDuration)
+			}
+			return result, err
+		}
+
+		request.Messages = result.Messages
+		resp, err := client.ChatCompletionContext(loopCtx, request)
+		if err != nil {
+			if timedOut(err) {
+				return result, exceeded(BudgetDuration)
+			}
+			return result, err
+		}
+		result.Steps++
+		result.Response = resp
+		usage := resp.GetUsage()
+		if usage.TotalTokens == 0 {
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		}
+		result.Usage.PromptTokens += usage.PromptTokens
+		result.Usage.CompletionTokens += usage.CompletionTokens
+		result.Usage.TotalTokens += usage.TotalTokens
+		result.Messages = append(result.Messages, resp.Message())
+
+		calls := resp.GetToolCalls()
+		if len(calls) == 0 {
+			result.Elapsed = time.Since(start)
+			return result, nil
+		}
+		if budget.MaxTokens > 0 && result.Usage.TotalTokens > budget.MaxTokens {
+			return result, exceeded(BudgetTokens)
+		}
+
+		correcting := false
+		for _, call := range calls {
+			if budget.MaxCalls > 0 && result.Calls >= budget.MaxCalls {
+				return result, exceeded(BudgetCalls)
+			}
+			signature := callSignature(call)
+			if budget.MaxRepeats > 0 && repeats[signature] >= budget.MaxRepeats {
+				if !budget.CorrectRepeats || corrected {
+					result.Elapsed = time.Since(start)
+					return result, &RepeatedToolCallError{Call: call, Count: repeats[signature] + 1, Result: result}
+				}
+				result.Messages = append(result.Messages, ToolMessage{
+					Role:       "tool",
+					Content:    "Error: not executed, this call was already made with the same arguments.",
+					ToolCallID: call.ID,
+				})
+				correcting = true
+				continue
+			}
+			repeats[signature]++
+
+			// The tools run with the context of the loop: they get its
+			// deadline, and are cancelled with it.
+			message, err := r.execute(loopCtx, call)
+			if err != nil && loopCtx.Err() != nil {
+				// The call was abandoned; the conversation ends with the
+				// reply requesting it.
+				if timedOut(loopCtx.Err()) {
+					return result, exceeded(BudgetDuration)
+				}
+				return result, loopCtx.Err()
+			}
+			result.Messages = append(result.Messages, message)
+			result.Calls++
+		}
+		if correcting {
+			result.Messages = append(result.Messages, ChatMessage{Role: "system", Content: repeatCorrection})
+			corrected = true
+		}
+	}
+}
+
+// callSignature identifies the calls of a tool with the same arguments,
+// whatever the order of their properties and the spacing.
+func callSignature(call ToolCall) string {
+	arguments := strings.TrimSpace(call.Function.Arguments)
+	var value interface{}
+	if err := json.Unmarshal([]byte(arguments), &value); err == nil {
+		// Maps are encoded with their keys sorted.
+		if canonical, err := json.Marshal(value); err == nil {
+			arguments = string(canonical)
+		}
+	}
+	return call.Function.Name + "\x00" + arguments
+}
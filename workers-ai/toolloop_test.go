@@ -0,0 +1,247 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolLoopServer replies with the tool calls of replies in turn, given as
+// "name(arguments)" separated by ";", or with the reply as content when it
+// calls no tool. Every reply uses 10 tokens.
+func toolLoopServer(t *testing.T, replies ...string) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1)) - 1
+		reply := replies[min(n, len(replies)-1)]
+		message := map[string]interface{}{"role": "assistant", "content": reply}
+		if strings.Contains(reply, "(") {
+			var calls []map[string]interface{}
+			for i, call := range strings.Split(reply, ";") {
+				name, arguments, _ := strings.Cut(strings.TrimSuffix(call, ")"), "(")
+				calls = append(calls, map[string]interface{}{
+					"id":       fmt.Sprintf("call_%d_%d", n, i),
+					"type":     "function",
+					"function": map[string]string{"name": name, "arguments": arguments},
+				})
+			}
+			message = map[string]interface{}{"role": "assistant", "tool_calls": calls}
+		}
+		result, err := json.Marshal(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{"index": 0, "message": message}},
+			"usage":   Usage{PromptTokens: 8, CompletionTokens: 2, TotalTokens: 10},
+		})
+		require.NoError(t, err)
+		fmt.Fprintf(w, `{"success": true, "result": %s}`, result)
+	}))
+	return server, &requests
+}
+
+func echoRunner() *ToolRunner {
+	registry := NewToolRegistry()
+	registry.Register(echoTool("echo"), func(ctx context.Context, arguments string) (string, error) { return arguments, nil })
+	return NewToolRunner(registry, ToolGuard{})
+}
+
+func TestToolRunnerLoop(t *testing.T) {
+	server, _ := toolLoopServer(t, `echo({"n":1});echo({"n":2})`, "All done.")
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Go"}}}
+	result, err := echoRunner().Loop(context.Background(), client, request, ToolBudget{})
+	require.NoError(t, err)
+	assert.Equal(t, "All done.", result.Response.GetContent())
+	assert.Equal(t, 2, result.Steps)
+	assert.Equal(t, 2, result.Calls)
+	assert.Equal(t, 20, result.Usage.TotalTokens)
+	require.Len(t, result.Messages, 5)
+	assert.Equal(t, ToolMessage{Role: "tool", Content: `{"n":2}`, ToolCallID: "call_0_1"}, result.Messages[3])
+}
+
+func TestToolRunnerLoopRecorder(t *testing.T) {
+	server, _ := toolLoopServer(t, `echo({"n":1})`, "All done.")
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	recorder := NewRecorder(client)
+
+	runner := echoRunner()
+	runner.Recorder = recorder
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Go"}}}
+	_, err := runner.Loop(context.Background(), recorder, request, ToolBudget{})
+	require.NoError(t, err)
+
+	interactions := recorder.Trace().Interactions
+	require.Len(t, interactions, 3)
+	assert.Equal(t, InteractionTool, interactions[1].Kind)
+	assert.Equal(t, "call_0_0", interactions[1].ToolCall.ID)
+	assert.Equal(t, `{"n":1}`, interactions[1].ToolResult)
+
+	replayer := NewReplayer(recorder.Trace())
+	_, err = replayer.ChatCompletion(request)
+	require.NoError(t, err)
+	result, err := replayer.ToolResult(*interactions[1].ToolCall)
+	require.NoError(t, err)
+	assert.Equal(t, `{"n":1}`, result)
+}
+
+func TestToolRunnerLoopBudgets(t *testing.T) {
+	server, _ := toolLoopServer(t, `echo({})`)
+	defer server.Close()
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Go"}}}
+
+	for _, tc := range []struct {
+		budget ToolBudget
+		limit  string
+		steps  int
+	}{
+		{ToolBudget{MaxSteps: 3}, BudgetSteps, 3},
+		{ToolBudget{MaxTokens: 25}, BudgetTokens, 3},
+		{ToolBudget{MaxCalls: 2}, BudgetCalls, 3},
+	} {
+		_, err := echoRunner().Loop(context.Background(), client, request, tc.budget)
+		var exceeded *BudgetExceededError
+		require.ErrorAs(t, err, &exceeded, tc.limit)
+		assert.Equal(t, tc.limit, exceeded.Limit)
+		assert.Equal(t, tc.steps, exceeded.Result.Steps, tc.limit)
+		assert.NotEmpty(t, exceeded.Result.Messages)
+	}
+
+	registry := NewToolRegistry()
+	registry.Register(echoTool("echo"), func(ctx context.Context, arguments string) (string, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "slow", nil
+	})
+	_, err := NewToolRunner(registry, ToolGuard{}).Loop(context.Background(), client, request, ToolBudget{MaxDuration: 30 * time.Millisecond})
+	var exceeded *BudgetExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, BudgetDuration, exceeded.Limit)
+
+	// A cancellation by the caller is no budget error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = echoRunner().Loop(ctx, client, request, ToolBudget{MaxDuration: time.Second})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestToolRunnerLoopCancelsTools(t *testing.T) {
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Go"}}}
+
+	type observed struct {
+		call        ToolCall
+		hasDeadline bool
+		err         error
+	}
+	calls := make(chan observed, 1)
+	registry := NewToolRegistry()
+	registry.Register(echoTool("echo"), func(ctx context.Context, arguments string) (string, error) { return arguments, nil })
+	registry.Register(echoTool("slow"), func(ctx context.Context, arguments string) (string, error) {
+		call, _ := ToolCallFromContext(ctx)
+		_, hasDeadline := ctx.Deadline()
+		<-ctx.Done()
+		calls <- observed{call, hasDeadline, ctx.Err()}
+		return "", ctx.Err()
+	})
+	runner := NewToolRunner(registry, ToolGuard{})
+
+	t.Run("budget deadline", func(t *testing.T) {
+		server, _ := toolLoopServer(t, `slow({});echo({})`, "All done.")
+		defer server.Close()
+		client := NewClient("test-account", "test-token")
+		client.BaseURL = server.URL
+
+		result, err := runner.Loop(context.Background(), client, request, ToolBudget{MaxDuration: 50 * time.Millisecond})
+		var budgetErr *BudgetExceededError
+		require.ErrorAs(t, err, &budgetErr)
+		assert.Equal(t, BudgetDuration, budgetErr.Limit)
+		assert.Equal(t, 0, result.Calls)
+		// The conversation ends with the reply requesting the tools.
+		require.Len(t, result.Messages, 2)
+
+		o := <-calls
+		assert.Equal(t, "call_0_0", o.call.ID)
+		assert.True(t, o.hasDeadline)
+		assert.ErrorIs(t, o.err, context.DeadlineExceeded)
+	})
+
+	t.Run("caller cancellation", func(t *testing.T) {
+		server, _ := toolLoopServer(t, `slow({});echo({})`, "All done.")
+		defer server.Close()
+		client := NewClient("test-account", "test-token")
+		client.BaseURL = server.URL
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		result, err := runner.Loop(ctx, client, request, ToolBudget{})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, result.Calls)
+
+		o := <-calls
+		assert.False(t, o.hasDeadline)
+		assert.ErrorIs(t, o.err, context.Canceled)
+	})
+}
+
+func TestToolRunnerLoopRepeats(t *testing.T) {
+	request := ChatCompletionRequest{Model: ModelLlama38B, Messages: []Message{ChatMessage{Role: "user", Content: "Go"}}}
+
+	t.Run("fail", func(t *testing.T) {
+		server, requests := toolLoopServer(t, `echo({"a":1,"b":2})`, `echo({"b": 2, "a": 1})`)
+		defer server.Close()
+		client := NewClient("test-account", "test-token")
+		client.BaseURL = server.URL
+
+		result, err := echoRunner().Loop(context.Background(), client, request, ToolBudget{MaxRepeats: 2})
+		var repeatErr *RepeatedToolCallError
+		require.ErrorAs(t, err, &repeatErr)
+		assert.EqualError(t, err, "tool loop stopped: echo called 3 times with the same arguments")
+		assert.Equal(t, 3, repeatErr.Count)
+		assert.Equal(t, "call_2_0", repeatErr.Call.ID)
+		assert.Equal(t, 2, result.Calls)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("correct", func(t *testing.T) {
+		server, requests := toolLoopServer(t, `echo({"n":1})`, `echo({"n":1})`, "Done.")
+		defer server.Close()
+		client := NewClient("test-account", "test-token")
+		client.BaseURL = server.URL
+
+		result, err := echoRunner().Loop(context.Background(), client, request, ToolBudget{MaxRepeats: 1, CorrectRepeats: true})
+		require.NoError(t, err)
+		assert.Equal(t, "Done.", result.Response.GetContent())
+		assert.Equal(t, 1, result.Calls)
+		assert.Equal(t, int32(3), requests.Load())
+		require.Len(t, result.Messages, 7)
+		skipped := result.Messages[4].(ToolMessage)
+		assert.Equal(t, "call_1_0", skipped.ToolCallID)
+		assert.Contains(t, skipped.Content, "not executed")
+		assert.Equal(t, ChatMessage{Role: "system", Content: repeatCorrection}, result.Messages[5])
+	})
+
+	t.Run("correct once", func(t *testing.T) {
+		server, _ := toolLoopServer(t, `echo({"n":1})`)
+		defer server.Close()
+		client := NewClient("test-account", "test-token")
+		client.BaseURL = server.URL
+
+		result, err := echoRunner().Loop(context.Background(), client, request, ToolBudget{MaxRepeats: 1, CorrectRepeats: true})
+		var repeatErr *RepeatedToolCallError
+		require.ErrorAs(t, err, &repeatErr)
+		assert.Equal(t, 2, repeatErr.Count)
+		assert.Equal(t, 3, result.Steps)
+	})
+}
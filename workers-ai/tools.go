@@ -0,0 +1,418 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Errors of ToolRunner.Run.
+var (
+	ErrUnknownTool    = errors.New("unknown tool")
+	ErrToolNotAllowed = errors.New("tool not allowed")
+	ErrToolTimeout    = errors.New("tool timed out")
+)
+
+// DefaultToolEnv lists the environment variables kept by ToolGuard.ScrubEnv
+// when KeepEnv is nil.
+var DefaultToolEnv = []string{"PATH", "HOME", "LANG", "TZ", "TMPDIR"}
+
+// ToolHandler executes a call of a tool with its JSON arguments and returns
+// the result sent back to the model. Its context carries the call, see
+// ToolCallFromContext, and is cancelled when the call times out or is
+// abandoned, e.g. at the deadline of a tool loop: handlers doing slow work
+// should stop then.
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// ToolRegistry holds the tools an application offers to models, with the
+// handlers executing them. A registry is shared by the agents of the
+// application, each running the tools through its own ToolRunner. It is
+// safe for concurrent use.
+//
+// Tool names may be namespaced with dots, e.g. "search.web" and
+// "db.query", see Namespace. A tool may have several versions; models are
+// only offered the selected one, and calls are routed to its handler.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]*toolVersions
+}
+
+// toolVersions are the versions of a tool.
+type toolVersions struct {
+	versions map[string]registeredTool
+	current  string
+}
+
+type registeredTool struct {
+	tool    Tool
+	handler ToolHandler
+}
+
+// NewToolRegistry returns an empty registry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]*toolVersions)}
+}
+
+// Register adds tool, executed by handler, replacing any unversioned tool
+// of the same name. It becomes the selected version of the tool.
+func (r *ToolRegistry) Register(tool Tool, handler ToolHandler) {
+	r.RegisterVersion(tool, "", handler)
+}
+
+// RegisterVersion adds version of tool, executed by handler, replacing any
+// definition of the same version. The version registered last is
+// selected, unless Use selected another one.
+func (r *ToolRegistry) RegisterVersion(tool Tool, version string, handler ToolHandler) {
+	if tool.Type == "" {
+		tool.Type = "function"
+	}
+	name := tool.Function.Name
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tools[name]
+	if !ok {
+		t = &toolVersions{versions: make(map[string]registeredTool)}
+		r.tools[name] = t
+	}
+	t.versions[version] = registeredTool{tool: tool, handler: handler}
+	t.current = version
+}
+
+// Use selects version of the tool called name, for the runners that don't
+// pin another one in ToolGuard.Versions.
+func (r *ToolRegistry) Use(name, version string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tools[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	if _, ok := t.versions[version]; !ok {
+		return fmt.Errorf("%w: %s version %q", ErrUnknownTool, name, version)
+	}
+	t.current = version
+	return nil
+}
+
+// Versions returns the versions of the tool called name, sorted.
+func (r *ToolRegistry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	if !ok {
+		return nil
+	}
+	versions := make([]string, 0, len(t.versions))
+	for version := range t.versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Namespace returns a view of the registry registering tools under
+// namespace, e.g. Namespace("db").Register of a tool "query" registers
+// "db.query".
+func (r *ToolRegistry) Namespace(namespace string) *ToolNamespace {
+	return &ToolNamespace{Registry: r, Prefix: namespace + "."}
+}
+
+// lookup returns version of the tool called name, or its selected version
+// when version is empty.
+func (r *ToolRegistry) lookup(name, version string) (registeredTool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	if !ok {
+		return registeredTool{}, false
+	}
+	if version == "" {
+		version = t.current
+	}
+	rt, ok := t.versions[version]
+	return rt, ok
+}
+
+// names returns the names of the registered tools, sorted.
+func (r *ToolRegistry) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ToolNamespace registers tools in a namespace of a ToolRegistry.
+type ToolNamespace struct {
+	Registry *ToolRegistry
+	// Prefix is prepended to the names of the tools, e.g. "db.".
+	Prefix string
+}
+
+// Register is ToolRegistry.Register in the namespace.
+func (n *ToolNamespace) Register(tool Tool, handler ToolHandler) {
+	n.RegisterVersion(tool, "", handler)
+}
+
+// RegisterVersion is ToolRegistry.RegisterVersion in the namespace.
+func (n *ToolNamespace) RegisterVersion(tool Tool, version string, handler ToolHandler) {
+	tool.Function.Name = n.Prefix + tool.Function.Name
+	n.Registry.RegisterVersion(tool, version, handler)
+}
+
+// ToolGuard restricts the execution of tools by an agent. The zero value
+// allows every tool without limits.
+type ToolGuard struct {
+	// Allowed lists the tools the agent may call. Nil allows all the tools
+	// of the registry. A name ending with ".*" allows a whole namespace,
+	// e.g. "search.*".
+	Allowed []string
+	// Versions pins the version of tools, by name, for the agent.
+	// Otherwise the version selected in the registry is used.
+	Versions map[string]string
+	// Timeout bounds every execution; Timeouts overrides it per tool. The
+	// context of the handler is cancelled at the deadline, and the call
+	// fails with ErrToolTimeout even if the handler doesn't return.
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+	// MaxOutput caps the length of results, in bytes. Longer results are
+	// cut and marked as truncated, the mark included in the length.
+	MaxOutput int
+	// ScrubEnv restricts the environment of tools to the variables named
+	// in KeepEnv, or DefaultToolEnv when nil. Handlers get it with ToolEnv,
+	// e.g. for exec.Cmd.Env. The values of the other variables whose names
+	// look like secrets are redacted from the results.
+	ScrubEnv bool
+	KeepEnv  []string
+}
+
+// ToolRunner executes the tool calls of an agent with the handlers of a
+// registry, within the limits of its guard.
+type ToolRunner struct {
+	Registry *ToolRegistry
+	Guard    ToolGuard
+	// Recorder, if set, records every execution of Execute and Loop, e.g.
+	// a Recorder also wrapping the client of the loop, for its trace to
+	// cover the tools.
+	Recorder ToolRecorder
+}
+
+// ToolRecorder records the executions of tools. Recorder implements it.
+type ToolRecorder interface {
+	RecordTool(call ToolCall, result string, err error, duration time.Duration)
+}
+
+// NewToolRunner returns a runner of the tools of registry, with guard.
+func NewToolRunner(registry *ToolRegistry, guard ToolGuard) *ToolRunner {
+	return &ToolRunner{Registry: registry, Guard: guard}
+}
+
+// Tools returns the definitions of the tools the agent may call, to offer
+// them to the model.
+func (r *ToolRunner) Tools() []Tool {
+	var tools []Tool
+	for _, name := range r.Registry.names() {
+		if !r.allowed(name) {
+			continue
+		}
+		if t, ok := r.Registry.lookup(name, r.Guard.Versions[name]); ok {
+			tools = append(tools, t.tool)
+		}
+	}
+	return tools
+}
+
+// Run executes call and returns its result. It returns as soon as ctx is
+// done, without waiting for a handler ignoring its context.
+func (r *ToolRunner) Run(ctx context.Context, call ToolCall) (string, error) {
+	name := call.Function.Name
+	if !r.allowed(name) {
+		return "", fmt.Errorf("%w: %s", ErrToolNotAllowed, name)
+	}
+	t, ok := r.Registry.lookup(name, r.Guard.Versions[name])
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+
+	parent := ctx
+	ctx = context.WithValue(ctx, toolCallKey{}, call)
+	timeout := r.Guard.Timeout
+	if d, ok := r.Guard.Timeouts[name]; ok {
+		timeout = d
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if r.Guard.ScrubEnv {
+		ctx = context.WithValue(ctx, toolEnvKey{}, r.env())
+	}
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		var o outcome
+		defer func() { done <- o }()
+		defer recoverPanic(&o.err)
+		o.result, o.err = t.handler(ctx, call.Function.Arguments)
+	}()
+
+	var result string
+	select {
+	case o := <-done:
+		if o.err != nil {
+			return "", fmt.Errorf("tool %s failed: %w", name, o.err)
+		}
+		result = o.result
+	case <-ctx.Done():
+		if err := parent.Err(); err != nil {
+			// Cancelled by the caller, e.g. at the deadline of a tool loop,
+			// rather than by the timeout of the tool.
+			return "", fmt.Errorf("tool %s cancelled: %w", name, err)
+		}
+		return "", fmt.Errorf("%w: %s after %s", ErrToolTimeout, name, timeout)
+	}
+
+	if r.Guard.ScrubEnv {
+		result = r.redactSecrets(result)
+	}
+	return truncateOutput(result, r.Guard.MaxOutput), nil
+}
+
+// Execute runs call and returns the message reporting its result, or its
+// error, to the model.
+func (r *ToolRunner) Execute(ctx context.Context, call ToolCall) ToolMessage {
+	message, _ := r.execute(ctx, call)
+	return message
+}
+
+// execute is Execute also returning the error of the call.
+func (r *ToolRunner) execute(ctx context.Context, call ToolCall) (ToolMessage, error) {
+	start := time.Now()
+	result, err := r.Run(ctx, call)
+	if r.Recorder != nil {
+		r.Recorder.RecordTool(call, result, err, time.Since(start))
+	}
+	if err != nil {
+		result = "Error: " + err.Error()
+	}
+	return ToolMessage{Role: "tool", Content: result, ToolCallID: call.ID}, err
+}
+
+func (r *ToolRunner) allowed(name string) bool {
+	if r.Guard.Allowed == nil {
+		return true
+	}
+	for _, allowed := range r.Guard.Allowed {
+		if allowed == name {
+			return true
+		}
+		if namespace, ok := strings.CutSuffix(allowed, "*"); ok && strings.HasSuffix(namespace, ".") && strings.HasPrefix(name, namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// env returns the environment kept by the guard, as KEY=value pairs.
+func (r *ToolRunner) env() []string {
+	keep := r.Guard.KeepEnv
+	if keep == nil {
+		keep = DefaultToolEnv
+	}
+	env := make([]string, 0, len(keep))
+	for _, name := range keep {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// redactSecrets replaces the values of the scrubbed environment variables
+// that look like secrets in result.
+func (r *ToolRunner) redactSecrets(result string) string {
+	keep := r.Guard.KeepEnv
+	if keep == nil {
+		keep = DefaultToolEnv
+	}
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[name] = true
+	}
+
+	for _, pair := range os.Environ() {
+		name, value, _ := strings.Cut(pair, "=")
+		// Short values would redact common words.
+		if kept[name] || len(value) < 8 || !secretName(name) {
+			continue
+		}
+		result = strings.ReplaceAll(result, value, Redacted)
+	}
+	return result
+}
+
+// secretName reports whether the environment variable name looks like it
+// holds a secret.
+func secretName(name string) bool {
+	name = strings.ToUpper(name)
+	for _, word := range []string{"TOKEN", "KEY", "SECRET", "PASSWORD", "CREDENTIAL"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateOutput cuts result to max bytes, on a rune boundary, and notes
+// how much was cut within those bytes, unless max is too small to hold the
+// note. A max of zero doesn't limit it.
+func truncateOutput(result string, max int) string {
+	if max <= 0 || len(result) <= max {
+		return result
+	}
+	note := func(n int) string { return fmt.Sprintf("\n[truncated %d bytes]", n) }
+	// The note for the whole length is at least as long as the final one.
+	cut := max - len(note(len(result)))
+	if cut < 0 {
+		cut, note = max, func(int) string { return "" }
+	}
+	for cut > 0 && !utf8.RuneStart(result[cut]) {
+		cut--
+	}
+	return result[:cut] + note(len(result)-cut)
+}
+
+type toolEnvKey struct{}
+
+type toolCallKey struct{}
+
+// ToolCallFromContext returns the call a tool handler was invoked for, e.g.
+// to tag its logs with the ID of the call.
+func ToolCallFromContext(ctx context.Context) (ToolCall, bool) {
+	call, ok := ctx.Value(toolCallKey{}).(ToolCall)
+	return call, ok
+}
+
+// ToolEnv returns the environment a tool handler should give to the
+// processes it starts: the variables kept by the guard of the runner when
+// it scrubs the environment, os.Environ otherwise.
+func ToolEnv(ctx context.Context) []string {
+	if env, ok := ctx.Value(toolEnvKey{}).([]string); ok {
+		return env
+	}
+	return os.Environ()
+}
@@ -0,0 +1,219 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoTool(name string) Tool {
+	return Tool{Function: FunctionDefinition{Name: name, Description: "Echoes its arguments"}}
+}
+
+func toolCall(id, name, arguments string) ToolCall {
+	return ToolCall{ID: id, Type: "function", Function: FunctionToCall{Name: name, Arguments: arguments}}
+}
+
+func TestToolRunnerAllowlist(t *testing.T) {
+	registry := NewToolRegistry()
+	echo := func(ctx context.Context, arguments string) (string, error) { return arguments, nil }
+	registry.Register(echoTool("search"), echo)
+	registry.Register(echoTool("shell"), echo)
+
+	runner := NewToolRunner(registry, ToolGuard{Allowed: []string{"search"}})
+	tools := runner.Tools()
+	require.Len(t, tools, 1)
+	assert.Equal(t, "search", tools[0].Function.Name)
+	assert.Equal(t, "function", tools[0].Type)
+
+	result, err := runner.Run(context.Background(), toolCall("1", "search", `{"q":"go"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"q":"go"}`, result)
+
+	_, err = runner.Run(context.Background(), toolCall("2", "shell", `{}`))
+	assert.ErrorIs(t, err, ErrToolNotAllowed)
+
+	_, err = runner.Run(context.Background(), toolCall("3", "missing", `{}`))
+	assert.ErrorIs(t, err, ErrToolNotAllowed)
+
+	unrestricted := NewToolRunner(registry, ToolGuard{})
+	assert.Len(t, unrestricted.Tools(), 2)
+	_, err = unrestricted.Run(context.Background(), toolCall("4", "missing", `{}`))
+	assert.ErrorIs(t, err, ErrUnknownTool)
+}
+
+func TestToolRunnerTimeouts(t *testing.T) {
+	registry := NewToolRegistry()
+	release := make(chan struct{})
+	defer close(release)
+	registry.Register(echoTool("stuck"), func(ctx context.Context, arguments string) (string, error) {
+		// Ignores its context, like a misbehaving tool.
+		<-release
+		return "done", nil
+	})
+	registry.Register(echoTool("slow"), func(ctx context.Context, arguments string) (string, error) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return "done", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	})
+
+	runner := NewToolRunner(registry, ToolGuard{
+		Timeout:  10 * time.Millisecond,
+		Timeouts: map[string]time.Duration{"slow": time.Second},
+	})
+
+	start := time.Now()
+	_, err := runner.Run(context.Background(), toolCall("1", "stuck", `{}`))
+	assert.ErrorIs(t, err, ErrToolTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+
+	result, err := runner.Run(context.Background(), toolCall("2", "slow", `{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "done", result)
+}
+
+func TestToolRunnerMaxOutput(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(echoTool("echo"), func(ctx context.Context, arguments string) (string, error) { return arguments, nil })
+	runner := NewToolRunner(registry, ToolGuard{MaxOutput: 24})
+
+	result, err := runner.Run(context.Background(), toolCall("1", "echo", "abcdefghijklmnopqrstuvwxyz"))
+	require.NoError(t, err)
+	assert.Equal(t, "abc\n[truncated 23 bytes]", result)
+	assert.Len(t, result, 24)
+
+	result, err = runner.Run(context.Background(), toolCall("2", "echo", "abcd"))
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", result)
+
+	// Multi-byte runes are not split.
+	assert.Equal(t, "ab\n[truncated 24 bytes]", truncateOutput("ab"+strings.Repeat("é", 12), 24))
+	// Without room for the note, the result is only cut.
+	assert.Equal(t, "abcde", truncateOutput("abcdefgh", 5))
+}
+
+func TestToolRunnerScrubEnv(t *testing.T) {
+	t.Setenv("WORKERSAI_TEST_API_TOKEN", "s3cr3t-value-123")
+	t.Setenv("WORKERSAI_TEST_LANG", "fr_FR")
+
+	registry := NewToolRegistry()
+	var env []string
+	registry.Register(echoTool("env"), func(ctx context.Context, arguments string) (string, error) {
+		env = ToolEnv(ctx)
+		return "token is s3cr3t-value-123", nil
+	})
+
+	runner := NewToolRunner(registry, ToolGuard{ScrubEnv: true, KeepEnv: []string{"WORKERSAI_TEST_LANG"}})
+	result, err := runner.Run(context.Background(), toolCall("1", "env", `{}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"WORKERSAI_TEST_LANG=fr_FR"}, env)
+	assert.Equal(t, "token is "+Redacted, result)
+
+	// Without scrubbing, tools see the whole environment.
+	runner.Guard.ScrubEnv = false
+	result, err = runner.Run(context.Background(), toolCall("2", "env", `{}`))
+	require.NoError(t, err)
+	assert.Contains(t, env, "WORKERSAI_TEST_API_TOKEN=s3cr3t-value-123")
+	assert.Contains(t, result, "s3cr3t-value-123")
+}
+
+func TestToolRunnerExecute(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(echoTool("fail"), func(ctx context.Context, arguments string) (string, error) {
+		return "", errors.New("disk full")
+	})
+	runner := NewToolRunner(registry, ToolGuard{})
+
+	msg := runner.Execute(context.Background(), toolCall("call_1", "fail", `{}`))
+	assert.Equal(t, "tool", msg.Role)
+	assert.Equal(t, "call_1", msg.ToolCallID)
+	assert.True(t, strings.HasPrefix(msg.Content, "Error: "))
+	assert.Contains(t, msg.Content, "disk full")
+}
+
+func TestToolRunnerRecoversPanics(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(echoTool("crash"), func(ctx context.Context, arguments string) (string, error) {
+		panic(errors.New("nil pointer in tool"))
+	})
+	runner := NewToolRunner(registry, ToolGuard{})
+
+	_, err := runner.Run(context.Background(), toolCall("1", "crash", `{}`))
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.EqualError(t, panicErr, "panic: nil pointer in tool")
+	assert.Contains(t, runner.Execute(context.Background(), toolCall("2", "crash", `{}`)).Content, "panic")
+}
+
+func TestToolRegistryNamespacesAndVersions(t *testing.T) {
+	registry := NewToolRegistry()
+	reply := func(result string) ToolHandler {
+		return func(ctx context.Context, arguments string) (string, error) { return result, nil }
+	}
+	search := registry.Namespace("search")
+	search.Register(echoTool("web"), reply("web"))
+	db := registry.Namespace("db")
+	v1 := echoTool("query")
+	v1.Function.Description = "Runs SQL"
+	db.RegisterVersion(v1, "v1", reply("query v1"))
+	v2 := echoTool("query")
+	v2.Function.Description = "Runs SQL with parameters"
+	db.RegisterVersion(v2, "v2", reply("query v2"))
+
+	assert.Equal(t, []string{"v1", "v2"}, registry.Versions("db.query"))
+
+	// The last registered version is selected.
+	runner := NewToolRunner(registry, ToolGuard{Allowed: []string{"db.*"}})
+	tools := runner.Tools()
+	require.Len(t, tools, 1)
+	assert.Equal(t, "db.query", tools[0].Function.Name)
+	assert.Equal(t, "Runs SQL with parameters", tools[0].Function.Description)
+	result, err := runner.Run(context.Background(), toolCall("1", "db.query", `{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "query v2", result)
+	_, err = runner.Run(context.Background(), toolCall("2", "search.web", `{}`))
+	assert.ErrorIs(t, err, ErrToolNotAllowed)
+
+	require.NoError(t, registry.Use("db.query", "v1"))
+	result, err = runner.Run(context.Background(), toolCall("3", "db.query", `{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "query v1", result)
+	assert.Equal(t, "Runs SQL", runner.Tools()[0].Function.Description)
+	assert.ErrorIs(t, registry.Use("db.query", "v3"), ErrUnknownTool)
+
+	// Runners may pin another version.
+	pinned := NewToolRunner(registry, ToolGuard{Versions: map[string]string{"db.query": "v2"}})
+	result, err = pinned.Run(context.Background(), toolCall("4", "db.query", `{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "query v2", result)
+	assert.Len(t, pinned.Tools(), 2)
+
+	pinned.Guard.Versions["db.query"] = "v9"
+	_, err = pinned.Run(context.Background(), toolCall("5", "db.query", `{}`))
+	assert.ErrorIs(t, err, ErrUnknownTool)
+}
+
+func TestToolRunnerCancellation(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(echoTool("wait"), func(ctx context.Context, arguments string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	runner := NewToolRunner(registry, ToolGuard{Timeout: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := runner.Run(ctx, toolCall("1", "wait", `{}`))
+	// The deadline of the caller is no timeout of the tool.
+	assert.NotErrorIs(t, err, ErrToolTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualError(t, err, "tool wait cancelled: context deadline exceeded")
+}
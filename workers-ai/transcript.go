@@ -0,0 +1,174 @@
+package workersai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExportFormat is the output format of ChatSession.Export.
+type ExportFormat string
+
+const (
+	ExportJSON     ExportFormat = "json"
+	ExportMarkdown ExportFormat = "markdown"
+)
+
+// Redacted replaces the content removed by the export redaction options.
+const Redacted = "[REDACTED]"
+
+// ExportOptions controls what ChatSession.Export leaves out.
+type ExportOptions struct {
+	// RedactSystemPrompt hides the system prompt.
+	RedactSystemPrompt bool
+	// RedactToolArguments hides the arguments of the tool calls made by the model.
+	RedactToolArguments bool
+	// RedactToolResults hides the content of tool messages.
+	RedactToolResults bool
+	// Redact, when set, is applied to every remaining message content and
+	// tool argument, e.g. to mask e-mail addresses or API keys.
+	Redact func(string) string
+}
+
+// Transcript is the exported form of a conversation.
+type Transcript struct {
+	Model    string              `json:"model"`
+	Messages []TranscriptMessage `json:"messages"`
+	Usage    Usage               `json:"usage"`
+}
+
+// TranscriptMessage is a single message of a Transcript, flattened from the
+// different Message types.
+type TranscriptMessage struct {
+	Role       string               `json:"role"`
+	Content    string               `json:"content,omitempty"`
+	ToolCalls  []TranscriptToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string               `json:"tool_call_id,omitempty"`
+}
+
+// TranscriptToolCall is a tool call made by the model in a Transcript.
+type TranscriptToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Export renders the conversation as a transcript in the given format,
+// e.g. to attach it to a bug report.
+func (s *ChatSession) Export(format ExportFormat, opts *ExportOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+
+	transcript := s.Transcript(*opts)
+
+	switch format {
+	case ExportJSON:
+		return json.MarshalIndent(transcript, "", "  ")
+	case ExportMarkdown:
+		return transcript.markdown(), nil
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+// Transcript returns the conversation, including the system prompt, with the
+// redactions of opts applied.
+func (s *ChatSession) Transcript(opts ExportOptions) Transcript {
+	redact := func(text string) string {
+		if opts.Redact != nil && text != "" {
+			return opts.Redact(text)
+		}
+		return text
+	}
+
+	transcript := Transcript{Model: s.Model, Usage: s.Usage}
+
+	for _, m := range s.History() {
+		var tm TranscriptMessage
+
+		switch msg := m.(type) {
+		case ChatMessage:
+			tm = TranscriptMessage{Role: msg.Role, Content: msg.Content}
+			tm.ToolCalls = transcriptToolCalls(msg.ToolCalls)
+		case ResponseMessage:
+			tm = TranscriptMessage{Role: msg.Role}
+			if msg.Content != nil {
+				tm.Content = *msg.Content
+			}
+			tm.ToolCalls = transcriptToolCalls(msg.ToolCalls)
+		case ToolMessage:
+			tm = TranscriptMessage{Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+		default:
+			continue
+		}
+
+		switch {
+		case tm.Role == "system" && opts.RedactSystemPrompt:
+			tm.Content = Redacted
+		case tm.Role == "tool" && opts.RedactToolResults:
+			tm.Content = Redacted
+		default:
+			tm.Content = redact(tm.Content)
+		}
+
+		for i := range tm.ToolCalls {
+			if opts.RedactToolArguments {
+				tm.ToolCalls[i].Arguments = Redacted
+			} else {
+				tm.ToolCalls[i].Arguments = redact(tm.ToolCalls[i].Arguments)
+			}
+		}
+
+		transcript.Messages = append(transcript.Messages, tm)
+	}
+
+	return transcript
+}
+
+func transcriptToolCalls(calls []ToolCall) []TranscriptToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]TranscriptToolCall, len(calls))
+	for i, call := range calls {
+		out[i] = TranscriptToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments}
+	}
+	return out
+}
+
+func (t Transcript) markdown() []byte {
+	var b bytes.Buffer
+
+	b.WriteString("# Chat transcript\n\n")
+	if t.Model != "" {
+		fmt.Fprintf(&b, "Model: `%s`\n\n", t.Model)
+	}
+
+	for _, m := range t.Messages {
+		role := m.Role
+		if role != "" {
+			role = strings.ToUpper(role[:1]) + role[1:]
+		}
+		if m.ToolCallID != "" {
+			fmt.Fprintf(&b, "## %s (`%s`)\n\n", role, m.ToolCallID)
+		} else {
+			fmt.Fprintf(&b, "## %s\n\n", role)
+		}
+
+		if m.Content != "" {
+			b.WriteString(m.Content)
+			b.WriteString("\n\n")
+		}
+
+		for _, call := range m.ToolCalls {
+			fmt.Fprintf(&b, "Tool call `%s` (`%s`):\n\n```json\n%s\n```\n\n", call.Name, call.ID, call.Arguments)
+		}
+	}
+
+	fmt.Fprintf(&b, "---\n\nUsage: %d prompt + %d completion = %d total tokens\n",
+		t.Usage.PromptTokens, t.Usage.CompletionTokens, t.Usage.TotalTokens)
+
+	return b.Bytes()
+}
@@ -0,0 +1,64 @@
+package workersai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportSession() *ChatSession {
+	content := "Let me check."
+	session := NewChatSession(nil, ModelLlama38B)
+	session.SystemPrompt = "Secret instructions"
+	session.Messages = []Message{
+		ChatMessage{Role: "user", Content: "Weather for bob@example.com?"},
+		ResponseMessage{Role: "assistant", Content: &content, ToolCalls: []ToolCall{
+			{ID: "call_1", Type: "function", Function: FunctionToCall{Name: "get_weather", Arguments: `{"user":"bob@example.com"}`}},
+		}},
+		ToolMessage{Role: "tool", Content: "sunny", ToolCallID: "call_1"},
+		ChatMessage{Role: "assistant", Content: "It is sunny."},
+	}
+	session.Usage = Usage{PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30}
+	return session
+}
+
+func TestChatSession_Export_JSON(t *testing.T) {
+	out, err := exportSession().Export(ExportJSON, &ExportOptions{
+		RedactSystemPrompt: true,
+		RedactToolResults:  true,
+		Redact: func(s string) string {
+			return strings.ReplaceAll(s, "bob@example.com", "<email>")
+		},
+	})
+	require.NoError(t, err)
+
+	var transcript Transcript
+	require.NoError(t, json.Unmarshal(out, &transcript))
+
+	assert.Equal(t, ModelLlama38B, transcript.Model)
+	assert.Equal(t, 30, transcript.Usage.TotalTokens)
+	require.Len(t, transcript.Messages, 5)
+	assert.Equal(t, TranscriptMessage{Role: "system", Content: Redacted}, transcript.Messages[0])
+	assert.Equal(t, "Weather for <email>?", transcript.Messages[1].Content)
+	assert.Equal(t, []TranscriptToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"user":"<email>"}`}}, transcript.Messages[2].ToolCalls)
+	assert.Equal(t, TranscriptMessage{Role: "tool", Content: Redacted, ToolCallID: "call_1"}, transcript.Messages[3])
+}
+
+func TestChatSession_Export_Markdown(t *testing.T) {
+	out, err := exportSession().Export(ExportMarkdown, &ExportOptions{RedactToolArguments: true})
+	require.NoError(t, err)
+
+	md := string(out)
+	assert.Contains(t, md, "Model: `@cf/meta/llama-3-8b-instruct`")
+	assert.Contains(t, md, "## System\n\nSecret instructions")
+	assert.Contains(t, md, "Tool call `get_weather` (`call_1`):\n\n```json\n[REDACTED]\n```")
+	assert.Contains(t, md, "## Tool (`call_1`)\n\nsunny")
+	assert.NotContains(t, md, `"user"`)
+	assert.True(t, strings.HasSuffix(md, "Usage: 20 prompt + 10 completion = 30 total tokens\n"))
+
+	_, err = exportSession().Export(ExportFormat("html"), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,267 @@
+package workersai
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSampleRate is the sample rate of the PCM audio written to a
+// TranscriptionStream when none is set. It is the rate Whisper works at.
+const DefaultSampleRate = 16000
+
+// DefaultTranscriptionSegment is the amount of audio a TranscriptionStream
+// transcribes at once when none is set.
+const DefaultTranscriptionSegment = 5 * time.Second
+
+// ErrTranscriptionStreamClosed is returned by writes to a closed
+// TranscriptionStream.
+var ErrTranscriptionStreamClosed = errors.New("transcription stream closed")
+
+// TranscriptionStreamOptions are the settings of StreamTranscription.
+type TranscriptionStreamOptions struct {
+	// SampleRate of the written audio. Defaults to DefaultSampleRate.
+	SampleRate int
+	// Segment is the amount of audio sent per request. Shorter segments
+	// lower the latency of the transcript, longer ones give the model more
+	// context. Defaults to DefaultTranscriptionSegment.
+	Segment time.Duration
+}
+
+// TranscriptUpdate is the transcript of one segment of a stream.
+type TranscriptUpdate struct {
+	Text string
+	// Start and End locate the segment within the stream.
+	Start time.Duration
+	End   time.Duration
+	// Words are timed in seconds from the start of the stream, if the model
+	// reports word timings.
+	Words []TranscriptionWord
+}
+
+// TranscriptionStream transcribes audio while it is being recorded, e.g. for
+// live captions. Write raw 16-bit little-endian mono PCM to it; every
+// Segment of audio is transcribed as soon as it is complete and the result
+// can be read with Recv, in order:
+//
+//	stream := client.StreamTranscription(ctx, workersai.ModelWhisper, nil)
+//	go func() {
+//		io.Copy(stream, microphone)
+//		stream.Close()
+//	}()
+//	for {
+//		update, err := stream.Recv()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+//
+// Workers AI has no streaming endpoint for Whisper, so each segment is a
+// separate request. Writes block while the previous segments are still
+// being transcribed.
+type TranscriptionStream struct {
+	client     *Client
+	ctx        context.Context
+	model      string
+	sampleRate int
+	// segmentBytes is the size of a segment of PCM audio.
+	segmentBytes int
+
+	mu      sync.Mutex
+	buf     []byte
+	samples int
+	closed  bool
+
+	segments chan audioSegment
+	updates  chan *TranscriptUpdate
+	err      error
+}
+
+type audioSegment struct {
+	pcm   []byte
+	start time.Duration
+}
+
+// StreamTranscription starts a transcription stream with a speech
+// recognition model. Cancelling ctx aborts the stream. A Segment shorter
+// than a sample fails the stream: Write and Recv return the error.
+func (c *Client) StreamTranscription(ctx context.Context, modelID string, opts *TranscriptionStreamOptions) *TranscriptionStream {
+	var o TranscriptionStreamOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.SampleRate <= 0 {
+		o.SampleRate = DefaultSampleRate
+	}
+	if o.Segment <= 0 {
+		o.Segment = DefaultTranscriptionSegment
+	}
+
+	s := &TranscriptionStream{
+		client:       c,
+		ctx:          ctx,
+		model:        modelID,
+		sampleRate:   o.SampleRate,
+		segmentBytes: 2 * int(o.Segment.Seconds()*float64(o.SampleRate)),
+		segments:     make(chan audioSegment, 1),
+		updates:      make(chan *TranscriptUpdate, 1),
+	}
+	if s.segmentBytes < 2 {
+		// Write would loop forever on empty segments: the stream fails
+		// right away instead.
+		s.err = fmt.Errorf("transcription segment of %v is shorter than a sample at %d Hz", o.Segment, o.SampleRate)
+		s.closed = true
+		close(s.segments)
+		close(s.updates)
+		return s
+	}
+	go s.run()
+	return s
+}
+
+// Write adds PCM audio to the stream.
+func (s *TranscriptionStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		if s.segmentBytes < 2 {
+			return 0, s.err
+		}
+		return 0, ErrTranscriptionStreamClosed
+	}
+
+	s.buf = append(s.buf, p...)
+	for len(s.buf) >= s.segmentBytes {
+		if err := s.queue(s.buf[:s.segmentBytes]); err != nil {
+			return 0, err
+		}
+		s.buf = append([]byte(nil), s.buf[s.segmentBytes:]...)
+	}
+	return len(p), nil
+}
+
+// Close transcribes the audio written since the last segment and ends the
+// stream once all segments are transcribed.
+func (s *TranscriptionStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var err error
+	if len(s.buf) >= 2 {
+		err = s.queue(s.buf[:len(s.buf)&^1])
+	}
+	s.buf = nil
+	close(s.segments)
+	return err
+}
+
+// queue hands pcm to the transcription goroutine. s.mu must be held.
+func (s *TranscriptionStream) queue(pcm []byte) error {
+	segment := audioSegment{pcm: pcm, start: s.duration(s.samples)}
+	s.samples += len(pcm) / 2
+	select {
+	case s.segments <- segment:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// Recv returns the next transcript update. It returns io.EOF once the
+// stream was closed and all audio is transcribed, or the error that ended
+// the stream.
+func (s *TranscriptionStream) Recv() (*TranscriptUpdate, error) {
+	update, ok := <-s.updates
+	if !ok {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	return update, nil
+}
+
+func (s *TranscriptionStream) run() {
+	defer close(s.updates)
+
+	for segment := range s.segments {
+		result, err := s.transcribe(segment)
+		if err != nil {
+			s.err = err
+			go s.drain()
+			return
+		}
+
+		update := &TranscriptUpdate{
+			Text:  strings.TrimSpace(result.Text),
+			Start: segment.start,
+			End:   segment.start + s.duration(len(segment.pcm)/2),
+			Words: shiftWords(result.Words, segment.start),
+		}
+
+		select {
+		case s.updates <- update:
+		case <-s.ctx.Done():
+			s.err = s.ctx.Err()
+			go s.drain()
+			return
+		}
+	}
+}
+
+// transcribe transcribes segment, returning a panic as its error.
+func (s *TranscriptionStream) transcribe(segment audioSegment) (result *TranscriptionResult, err error) {
+	defer recoverPanic(&err)
+	return s.client.transcribe(s.ctx, s.model, encodeWAV(segment.pcm, s.sampleRate), nil)
+}
+
+// drain discards the segments written after the stream failed, so that
+// writers don't block until Close.
+func (s *TranscriptionStream) drain() {
+	for range s.segments {
+	}
+}
+
+func (s *TranscriptionStream) duration(samples int) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(s.sampleRate)
+}
+
+// shiftWords moves the timings of words, transcribed from a segment
+// starting at offset, to the timeline of the whole audio.
+func shiftWords(words []TranscriptionWord, offset time.Duration) []TranscriptionWord {
+	var shifted []TranscriptionWord
+	for _, w := range words {
+		w.Start += offset.Seconds()
+		w.End += offset.Seconds()
+		shifted = append(shifted, w)
+	}
+	return shifted
+}
+
+// encodeWAV wraps 16-bit mono PCM in a WAV header.
+func encodeWAV(pcm []byte, sampleRate int) []byte {
+	wav := make([]byte, 44, 44+len(pcm))
+	copy(wav[0:], "RIFF")
+	binary.LittleEndian.PutUint32(wav[4:], uint32(36+len(pcm)))
+	copy(wav[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(wav[16:], 16)                   // fmt chunk size
+	binary.LittleEndian.PutUint16(wav[20:], 1)                    // PCM
+	binary.LittleEndian.PutUint16(wav[22:], 1)                    // mono
+	binary.LittleEndian.PutUint32(wav[24:], uint32(sampleRate))   // sample rate
+	binary.LittleEndian.PutUint32(wav[28:], uint32(2*sampleRate)) // byte rate
+	binary.LittleEndian.PutUint16(wav[32:], 2)                    // block align
+	binary.LittleEndian.PutUint16(wav[34:], 16)                   // bits per sample
+	copy(wav[36:], "data")
+	binary.LittleEndian.PutUint32(wav[40:], uint32(len(pcm)))
+	return append(wav, pcm...)
+}
@@ -0,0 +1,105 @@
+package workersai
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_StreamTranscription(t *testing.T) {
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wav, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "RIFF", string(wav[:4]))
+		assert.Equal(t, uint32(8000), binary.LittleEndian.Uint32(wav[24:]))
+		size := int(binary.LittleEndian.Uint32(wav[40:]))
+		assert.Equal(t, len(wav)-44, size)
+		sizes = append(sizes, size)
+		fmt.Fprintf(w, `{"success": true, "result": {"text": " part %d ", "words": [{"word": "part", "start": 0.5, "end": 0.75}]}}`, len(sizes))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stream := client.StreamTranscription(context.Background(), ModelWhisper, &TranscriptionStreamOptions{SampleRate: 8000, Segment: time.Second})
+
+	// 2.5 seconds of audio, written in uneven chunks.
+	go func() {
+		pcm := make([]byte, 2*8000*5/2)
+		for len(pcm) > 0 {
+			n := 3001
+			if n > len(pcm) {
+				n = len(pcm)
+			}
+			_, err := stream.Write(pcm[:n])
+			assert.NoError(t, err)
+			pcm = pcm[n:]
+		}
+		assert.NoError(t, stream.Close())
+	}()
+
+	var updates []*TranscriptUpdate
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		updates = append(updates, update)
+	}
+
+	assert.Equal(t, []int{16000, 16000, 8000}, sizes)
+	require.Len(t, updates, 3)
+	assert.Equal(t, "part 1", updates[0].Text)
+	assert.Equal(t, time.Duration(0), updates[0].Start)
+	assert.Equal(t, 2*time.Second, updates[2].Start)
+	assert.Equal(t, 2500*time.Millisecond, updates[2].End)
+	assert.Equal(t, []TranscriptionWord{{Word: "part", Start: 2.5, End: 2.75}}, updates[2].Words)
+
+	_, err := stream.Write([]byte{0, 0})
+	assert.ErrorIs(t, err, ErrTranscriptionStreamClosed)
+}
+
+func TestClient_StreamTranscription_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"success": false, "errors": [{"code": 5006, "message": "invalid audio"}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	stream := client.StreamTranscription(context.Background(), ModelWhisper, &TranscriptionStreamOptions{Segment: 100 * time.Millisecond})
+	for i := 0; i < 5; i++ {
+		_, err := stream.Write(make([]byte, 3200))
+		require.NoError(t, err)
+	}
+	require.NoError(t, stream.Close())
+
+	_, err := stream.Recv()
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.True(t, apiErr.HasCode(5006))
+}
+
+func TestClient_StreamTranscription_ShortSegment(t *testing.T) {
+	client := NewClient("test-account", "test-token")
+
+	stream := client.StreamTranscription(context.Background(), ModelWhisper, &TranscriptionStreamOptions{Segment: time.Microsecond})
+	_, err := stream.Write(make([]byte, 100))
+	assert.EqualError(t, err, "transcription segment of 1µs is shorter than a sample at 16000 Hz")
+	_, err = stream.Recv()
+	assert.EqualError(t, err, "transcription segment of 1µs is shorter than a sample at 16000 Hz")
+	assert.NoError(t, stream.Close())
+}
@@ -0,0 +1,311 @@
+package workersai
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TranslationOptions configures Translate and TranslateBatch.
+type TranslationOptions struct {
+	// SourceLang is the language of the input text, e.g. "en" or "english".
+	// Dedicated translation models default to English when it is empty.
+	SourceLang string
+	// TargetLang is the language to translate into.
+	TargetLang string
+	// PreserveMarkup protects HTML tags and Markdown syntax (code, link
+	// targets, list and heading markers) so they come back unchanged.
+	PreserveMarkup bool
+	// Glossary pins source terms to a fixed translation. Terms are matched
+	// case-sensitively on word boundaries.
+	Glossary map[string]string
+	// MaxContext is the number of previous texts of a batch, with their
+	// translations, sent as context with chat models. Defaults to
+	// DefaultTranslationContext; a negative value sends none.
+	MaxContext int
+}
+
+// DefaultTranslationContext is the number of previous texts a chat model
+// is given as context by TranslateBatch when MaxContext is not set. It
+// keeps long batches within the context window of the model.
+const DefaultTranslationContext = 8
+
+// translationRequest is the input of the dedicated translation models (m2m100).
+type translationRequest struct {
+	Text       string `json:"text"`
+	SourceLang string `json:"source_lang,omitempty"`
+	TargetLang string `json:"target_lang"`
+}
+
+// translationResult is the output of the dedicated translation models.
+type translationResult struct {
+	TranslatedText string `json:"translated_text"`
+}
+
+// markupPatterns match the fragments PreserveMarkup keeps out of the model's reach.
+var markupPatterns = []*regexp.Regexp{
+	regexp.MustCompile("(?s)```.*?```"),                          // Fenced code blocks.
+	regexp.MustCompile("`[^`\n]+`"),                              // Inline code.
+	regexp.MustCompile(`\]\([^)\s]+\)`),                          // Link and image targets.
+	regexp.MustCompile(`</?[A-Za-z][^<>]*>`),                     // HTML tags.
+	regexp.MustCompile(`(?m)^[ \t]*(#{1,6}|[-*+>]|\d+\.)[ \t]+`), // Block markers.
+}
+
+// Translate translates text using modelID.
+//
+// Dedicated translation models such as ModelM2M100 receive the text with
+// markup and glossary terms swapped for placeholders. Any other model is
+// treated as a chat model and is instructed through its system prompt.
+func (c *Client) Translate(modelID, text string, opts TranslationOptions) (string, error) {
+	results, err := c.TranslateBatch(modelID, []string{text}, opts)
+	if err != nil {
+		return "", err
+	}
+	return results[0], nil
+}
+
+// TranslateBatch translates every text in texts with the same options.
+//
+// With chat models the batch is sent as a single conversation, so earlier
+// segments and their translations give context to the later ones and
+// terminology stays consistent across the batch. Only the last
+// opts.MaxContext segments are kept in the conversation.
+func (c *Client) TranslateBatch(modelID string, texts []string, opts TranslationOptions) ([]string, error) {
+	result, err := c.translateBatch(modelID, texts, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	if failed := result.Failed(); len(failed) > 0 {
+		return nil, failed[0].Err
+	}
+	return result.Results(), nil
+}
+
+// TranslateBatchItems is TranslateBatch reporting the outcome of every
+// text rather than failing the batch on the first error. With chat
+// models, failed texts are left out of the conversation.
+func (c *Client) TranslateBatchItems(modelID string, texts []string, opts TranslationOptions) (*BatchResult[string], error) {
+	return c.translateBatch(modelID, texts, opts, false)
+}
+
+// RetryTranslation translates again the text of a failed item of
+// TranslateBatchItems, without the context of the rest of the batch.
+func (c *Client) RetryTranslation(token string) (string, error) {
+	var input translationRetry
+	if err := decodeRetryToken(token, retryTranslation, &input); err != nil {
+		return "", err
+	}
+	return c.Translate(input.Model, input.Text, input.Options)
+}
+
+// translationRetry is the input encoded in the retry token of a text.
+type translationRetry struct {
+	Model   string             `json:"model"`
+	Text    string             `json:"text"`
+	Options TranslationOptions `json:"options"`
+}
+
+// translateBatch translates texts, stopping at the first failure if
+// stopOnError.
+func (c *Client) translateBatch(modelID string, texts []string, opts TranslationOptions, stopOnError bool) (*BatchResult[string], error) {
+	if opts.TargetLang == "" {
+		return nil, fmt.Errorf("target language is required")
+	}
+
+	result := &BatchResult[string]{Items: make([]BatchItem[string], len(texts))}
+	dedicated := isTranslationModel(modelID)
+
+	var history []Message
+	if !dedicated {
+		history = []Message{ChatMessage{Role: "system", Content: translationPrompt(opts)}}
+	}
+
+	for i, text := range texts {
+		translated, usage, turn, err := c.translateText(modelID, text, opts, history)
+		if err != nil {
+			err = fmt.Errorf("failed to translate text %d: %w", i, err)
+		} else if !dedicated {
+			history = trimTranslationHistory(append(history, turn...), opts.MaxContext)
+		}
+		original := text
+		result.set(i, translated, usage, err, func() string {
+			return retryToken(retryTranslation, translationRetry{Model: modelID, Text: original, Options: opts})
+		})
+		if err != nil && stopOnError {
+			result.Items = result.Items[:i+1]
+			break
+		}
+	}
+
+	result.summarize()
+	return result, nil
+}
+
+// trimTranslationHistory drops the oldest texts of history, after its
+// system prompt, beyond the last maxContext, DefaultTranslationContext if
+// zero.
+func trimTranslationHistory(history []Message, maxContext int) []Message {
+	if maxContext == 0 {
+		maxContext = DefaultTranslationContext
+	}
+	keep := 2 * max(maxContext, 0)
+	if len(history)-1 <= keep {
+		return history
+	}
+	return append(history[:1], history[len(history)-keep:]...)
+}
+
+// translateText translates one text of a batch, after history with chat
+// models, and returns the messages to add to the history.
+func (c *Client) translateText(modelID, text string, opts TranslationOptions, history []Message) (string, Usage, []Message, error) {
+	masked := newPlaceholderSet(text)
+	if opts.PreserveMarkup {
+		text = masked.maskPatterns(text, markupPatterns)
+	}
+
+	var translated string
+	var usage Usage
+	var turn []Message
+	if isTranslationModel(modelID) {
+		text = masked.maskGlossary(text, opts.Glossary)
+
+		var result translationResult
+		err := c.run(modelID, translationRequest{
+			Text:       text,
+			SourceLang: opts.SourceLang,
+			TargetLang: opts.TargetLang,
+		}, &result)
+		if err != nil {
+			return "", usage, nil, err
+		}
+		translated = result.TranslatedText
+	} else {
+		turn = []Message{ChatMessage{Role: "user", Content: text}}
+		resp, err := c.Chat(modelID, append(history[:len(history):len(history)], turn...), nil)
+		if err != nil {
+			return "", usage, nil, err
+		}
+		usage = resp.GetUsage()
+		translated = strings.TrimSpace(resp.GetContent())
+		turn = append(turn, ChatMessage{Role: "assistant", Content: translated})
+	}
+
+	restored, err := masked.restore(translated)
+	if err != nil {
+		return "", usage, nil, err
+	}
+	return restored, usage, turn, nil
+}
+
+// isTranslationModel reports whether modelID is a dedicated translation model
+// rather than a chat model.
+func isTranslationModel(modelID string) bool {
+	return strings.Contains(modelID, "m2m100")
+}
+
+// translationPrompt builds the system prompt used when translating with a chat model.
+func translationPrompt(opts TranslationOptions) string {
+	var b strings.Builder
+
+	b.WriteString("You are a professional translator. Translate every user message")
+	if opts.SourceLang != "" {
+		fmt.Fprintf(&b, " from %s", opts.SourceLang)
+	}
+	fmt.Fprintf(&b, " into %s. Reply with the translation only, without notes or quotes.", opts.TargetLang)
+
+	if opts.PreserveMarkup {
+		b.WriteString(" Copy every placeholder in double braces, such as {{1234_0}}, exactly as it appears, in the matching position.")
+	}
+
+	if len(opts.Glossary) > 0 {
+		b.WriteString("\n\nAlways translate the following terms exactly as given:")
+		for _, term := range glossaryTerms(opts.Glossary) {
+			fmt.Fprintf(&b, "\n- %q => %q", term, opts.Glossary[term])
+		}
+	}
+
+	return b.String()
+}
+
+// glossaryTerms returns the glossary keys longest first, so that a term is
+// never shadowed by a shorter term it contains.
+func glossaryTerms(glossary map[string]string) []string {
+	terms := make([]string, 0, len(glossary))
+	for term := range glossary {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i]) != len(terms[j]) {
+			return len(terms[i]) > len(terms[j])
+		}
+		return terms[i] < terms[j]
+	})
+	return terms
+}
+
+// placeholderSet swaps fragments of a text for numbered placeholders and
+// puts them back after translation. The placeholders, {{nonce_n}}, start
+// with a random number, so that text looking like a placeholder isn't
+// mistaken for one.
+type placeholderSet struct {
+	nonce  int
+	values []string
+}
+
+// newPlaceholderSet returns a set for masking text, whose nonce doesn't
+// appear in it.
+func newPlaceholderSet(text string) *placeholderSet {
+	for {
+		nonce := 1000 + rand.Intn(9000)
+		if !strings.Contains(text, fmt.Sprintf("{{%d_", nonce)) {
+			return &placeholderSet{nonce: nonce}
+		}
+	}
+}
+
+func (p *placeholderSet) placeholder(i int) string {
+	return fmt.Sprintf("{{%d_%d}}", p.nonce, i)
+}
+
+func (p *placeholderSet) add(value string) string {
+	p.values = append(p.values, value)
+	return p.placeholder(len(p.values) - 1)
+}
+
+func (p *placeholderSet) maskPatterns(text string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		text = re.ReplaceAllStringFunc(text, p.add)
+	}
+	return text
+}
+
+func (p *placeholderSet) maskGlossary(text string, glossary map[string]string) string {
+	for _, term := range glossaryTerms(glossary) {
+		re := regexp.MustCompile(`\b` + regexp.QuoteMeta(term) + `\b`)
+		target := glossary[term]
+		text = re.ReplaceAllStringFunc(text, func(string) string {
+			return p.add(target)
+		})
+	}
+	return text
+}
+
+// restore replaces the placeholders in text with their original values. It
+// fails if the model dropped any of them. All are replaced in a single
+// pass, so that the restored values are never searched for placeholders.
+func (p *placeholderSet) restore(text string) (string, error) {
+	if len(p.values) == 0 {
+		return text, nil
+	}
+	pairs := make([]string, 0, 2*len(p.values))
+	for i, value := range p.values {
+		placeholder := p.placeholder(i)
+		if !strings.Contains(text, placeholder) {
+			return "", fmt.Errorf("translation dropped placeholder %s for %q", placeholder, value)
+		}
+		pairs = append(pairs, placeholder, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text), nil
+}
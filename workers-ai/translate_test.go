@@ -0,0 +1,140 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// placeholderNumbers matches the placeholders of a masked text, capturing
+// their number without the nonce.
+var placeholderNumbers = regexp.MustCompile(`\{\{\d+_(\d+)\}\}`)
+
+func TestClient_Translate_DedicatedModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.URL.Path, "/ai/run/@cf/meta/m2m100-1.2b")
+
+		var req translationRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "fr", req.TargetLang)
+
+		// The markup and the glossary term must have been masked.
+		assert.Equal(t, "{{0}}Open {{2}} now{{1}}", placeholderNumbers.ReplaceAllString(req.Text, "{{$1}}"))
+
+		w.Header().Set("Content-Type", "application/json")
+		translated := strings.NewReplacer("Open", "Ouvrez", "now", "maintenant").Replace(req.Text)
+		fmt.Fprintf(w, `{"success": true, "result": {"translated_text": %q}}`, translated)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	got, err := client.Translate(ModelM2M100, "<b>Open Workers AI now</b>", TranslationOptions{
+		TargetLang:     "fr",
+		PreserveMarkup: true,
+		Glossary:       map[string]string{"Workers AI": "Workers AI"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "<b>Ouvrez Workers AI maintenant</b>", got)
+}
+
+func TestClient_TranslateBatch_ChatModel(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		// Every call carries the system prompt plus the previous turns.
+		require.Len(t, req.Messages, 2*calls)
+		system := req.Messages[0].(ChatMessage)
+		assert.Contains(t, system.Content, "into German")
+		assert.Contains(t, system.Content, `"invoice" => "Rechnung"`)
+
+		last := req.Messages[len(req.Messages)-1].(ChatMessage)
+		reply := strings.ToUpper(last.Content)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "result": {"response": %q}}`, reply)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	got, err := client.TranslateBatch(ModelLlama38B, []string{"first `code`", "second"}, TranslationOptions{
+		TargetLang:     "German",
+		PreserveMarkup: true,
+		Glossary:       map[string]string{"invoice": "Rechnung"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"FIRST `code`", "SECOND"}, got)
+	assert.Equal(t, 2, calls)
+}
+
+func TestClient_Translate_DroppedPlaceholder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"success": true, "result": {"translated_text": "Bonjour"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	_, err := client.Translate(ModelM2M100, "<p>Hello</p>", TranslationOptions{TargetLang: "fr", PreserveMarkup: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dropped placeholder")
+}
+
+func TestClient_Translate_PlaceholderLookalikes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req translationRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		fmt.Fprintf(w, `{"success": true, "result": {"translated_text": %q}}`, strings.Replace(req.Text, "Use", "Utilisez", 1))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	// Text looking like placeholders, inside masked values or not, comes
+	// back as is.
+	got, err := client.Translate(ModelM2M100, "Use {{0}} and `{{1}}`", TranslationOptions{TargetLang: "fr", PreserveMarkup: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Utilisez {{0}} and `{{1}}`", got)
+}
+
+func TestClient_TranslateBatch_MaxContext(t *testing.T) {
+	var lengths []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		lengths = append(lengths, len(req.Messages))
+		fmt.Fprint(w, `{"success": true, "result": {"response": "ok"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	texts := []string{"one", "two", "three", "four"}
+
+	_, err := client.TranslateBatch(ModelLlama38B, texts, TranslationOptions{TargetLang: "fr", MaxContext: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 4, 4, 4}, lengths)
+
+	lengths = nil
+	_, err = client.TranslateBatch(ModelLlama38B, texts, TranslationOptions{TargetLang: "fr", MaxContext: -1})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 2, 2}, lengths)
+
+	assert.Len(t, trimTranslationHistory(make([]Message, 1+2*20), 0), 1+2*DefaultTranslationContext)
+}
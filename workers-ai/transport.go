@@ -0,0 +1,27 @@
+package workersai
+
+import (
+	"net/http"
+	"time"
+)
+
+// Connection pool defaults of NewTransport.
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 64
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// NewTransport returns the transport of the clients made by NewClient.
+// It is http.DefaultTransport, which uses HTTP/2 when the server supports
+// it, with a connection pool sized for many concurrent requests: the
+// default keeps only two idle connections per host, so that under load
+// HTTP/1.1 connections keep being closed and opened again.
+func NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConns = DefaultMaxIdleConns
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	return transport
+}
@@ -0,0 +1,110 @@
+package workersai
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransport(t *testing.T) {
+	transport := NewTransport()
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Equal(t, DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.NotSame(t, http.DefaultTransport, transport)
+
+	client := NewClient("test-account", "test-token")
+	assert.IsType(t, &http.Transport{}, client.HTTPClient.Transport)
+}
+
+func TestNewTransport_HTTP2(t *testing.T) {
+	var proto string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+		fmt.Fprint(w, `{"success": true, "result": {"response": "Hi"}}`)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+	client.HTTPClient.Transport.(*http.Transport).TLSClientConfig = testTLSConfig(server)
+
+	_, err := client.Chat(ModelLlama38B, []Message{ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", proto)
+}
+
+// testTLSConfig returns a TLS configuration trusting server.
+func testTLSConfig(server *httptest.Server) *tls.Config {
+	return server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+}
+
+// BenchmarkChatThroughput measures chat requests made by about 64
+// goroutines against a local server, with the transport of NewTransport
+// and with an untuned http.DefaultTransport. Besides the time per request
+// it reports conns/op, the connections opened per request: the default
+// pool keeps two idle connections per host, so HTTP/1.1 connections keep
+// being closed and opened again. Run it with
+//
+//	go test -run '^$' -bench ChatThroughput -benchmem ./workers-ai
+func BenchmarkChatThroughput(b *testing.B) {
+	for _, bench := range []struct {
+		name      string
+		http2     bool
+		transport func() *http.Transport
+	}{
+		{"HTTP1/Default", false, func() *http.Transport { return http.DefaultTransport.(*http.Transport).Clone() }},
+		{"HTTP1/Tuned", false, NewTransport},
+		{"HTTP2/Tuned", true, NewTransport},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var conns atomic.Int64
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"success": true, "result": {"response": "Hi", "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}}`)
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Add(1)
+				}
+			}
+
+			transport := bench.transport()
+			if bench.http2 {
+				server.EnableHTTP2 = true
+				server.StartTLS()
+				transport.TLSClientConfig = testTLSConfig(server)
+			} else {
+				server.Start()
+			}
+			defer server.Close()
+			defer transport.CloseIdleConnections()
+
+			client := NewClient("test-account", "test-token")
+			client.BaseURL = server.URL
+			client.HTTPClient = &http.Client{Transport: transport}
+			messages := []Message{ChatMessage{Role: "user", Content: "Hi"}}
+
+			b.SetParallelism(max(1, 64/runtime.GOMAXPROCS(0)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := client.Chat(ModelLlama38B, messages, nil); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
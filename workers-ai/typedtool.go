@@ -0,0 +1,202 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ToolRegistrar is implemented by ToolRegistry and ToolNamespace.
+type ToolRegistrar interface {
+	Register(tool Tool, handler ToolHandler)
+}
+
+// RegisterTool registers a tool named name executed by fn, with the
+// parameters derived from TArgs by ToolParameters. The arguments of the
+// calls are checked against the schema, the problems being reported to
+// the model, and decoded into a TArgs; the result is returned to the
+// model as is when it is a string, and as JSON otherwise.
+func RegisterTool[TArgs, TResult any](registry ToolRegistrar, name, description string, fn func(ctx context.Context, args TArgs) (TResult, error)) error {
+	params, err := ToolParameters[TArgs]()
+	if err != nil {
+		return fmt.Errorf("tool %s: %w", name, err)
+	}
+
+	tool := Tool{Type: "function", Function: FunctionDefinition{Name: name, Description: description, Parameters: params}}
+	registry.Register(tool, func(ctx context.Context, arguments string) (string, error) {
+		if strings.TrimSpace(arguments) == "" {
+			arguments = "{}"
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(arguments), &object); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		// Models send null for the optional arguments they leave out.
+		for key, value := range object {
+			if value == nil {
+				delete(object, key)
+			}
+		}
+		if err := validateSchema(object, &params); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		var args TArgs
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		result, err := fn(ctx, args)
+		if err != nil {
+			return "", err
+		}
+		if s, ok := interface{}(result).(string); ok {
+			return s, nil
+		}
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode result: %w", err)
+		}
+		return string(encoded), nil
+	})
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ToolParameters derives the parameters of a tool from the struct T, as
+// encoding/json sees it: the properties are named after the json tags of
+// the exported fields, and the fields without omitempty that aren't
+// pointers are required. Struct fields become nested objects described
+// the same way, and maps objects with additionalProperties giving the type
+// of their values. A field may be described with a description tag,
+// and a string field restricted with an enum tag of comma-separated
+// values:
+//
+//	type WeatherArgs struct {
+//		City  string `json:"city" description:"The city name"`
+//		Units string `json:"units,omitempty" enum:"celsius,fahrenheit"`
+//	}
+func ToolParameters[T any]() (FunctionParameters, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return FunctionParameters{}, fmt.Errorf("arguments must be a struct, not %s", t)
+	}
+	params := FunctionParameters{Type: "object", Properties: make(map[string]*Parameter)}
+	if err := addStructFields(&params, t, map[reflect.Type]bool{t: true}); err != nil {
+		return FunctionParameters{}, err
+	}
+	return params, nil
+}
+
+// addStructFields adds the fields of the struct t to params, those of
+// embedded structs included. visiting holds the structs being described,
+// to stop at recursive types.
+func addStructFields(params *FunctionParameters, t reflect.Type, visiting map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				if err := addStructFields(params, fieldType, visiting); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		param, err := parameterFor(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		param.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			param.Enum = strings.Split(enum, ",")
+		}
+		params.Properties[name] = param
+
+		omitEmpty := false
+		for _, option := range strings.Split(options, ",") {
+			omitEmpty = omitEmpty || option == "omitempty"
+		}
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			params.Required = append(params.Required, name)
+		}
+	}
+	return nil
+}
+
+// parameterFor returns the parameter of a value of type t. A struct
+// already in visiting, i.e. a recursive one, is a plain object.
+func parameterFor(t reflect.Type, visiting map[reflect.Type]bool) (*Parameter, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Parameter{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Parameter{Type: "string"}, nil
+	case reflect.Bool:
+		return &Parameter{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Parameter{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Parameter{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Encoded as base64 by encoding/json.
+			return &Parameter{Type: "string"}, nil
+		}
+		items, err := parameterFor(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &Parameter{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			// The values can be anything.
+			return &Parameter{Type: "object"}, nil
+		}
+		values, err := parameterFor(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &Parameter{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return &Parameter{Type: "object"}, nil
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		nested := FunctionParameters{Type: "object", Properties: make(map[string]*Parameter)}
+		if err := addStructFields(&nested, t, visiting); err != nil {
+			return nil, err
+		}
+		return &Parameter{Type: "object", Properties: nested.Properties, Required: nested.Required}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
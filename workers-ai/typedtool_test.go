@@ -0,0 +1,129 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pagination struct {
+	Limit int `json:"limit,omitempty" description:"Maximum number of results"`
+}
+
+type searchArgs struct {
+	pagination
+	Query   string     `json:"query" description:"What to search for"`
+	Sort    string     `json:"sort,omitempty" enum:"relevance,date"`
+	Tags    []string   `json:"tags,omitempty"`
+	Since   *time.Time `json:"since"`
+	Exact   bool
+	Ignored string `json:"-"`
+	hidden  string
+}
+
+type treeNode struct {
+	Label    string     `json:"label"`
+	Children []treeNode `json:"children,omitempty"`
+}
+
+type reportArgs struct {
+	Filter struct {
+		Field string `json:"field" description:"Field to filter on"`
+		Value string `json:"value,omitempty"`
+	} `json:"filter"`
+	Weights map[string]float64     `json:"weights,omitempty"`
+	Extra   map[string]interface{} `json:"extra,omitempty"`
+	Tree    *treeNode              `json:"tree,omitempty"`
+}
+
+type searchResult struct {
+	Hits []string `json:"hits"`
+}
+
+func TestToolParameters(t *testing.T) {
+	params, err := ToolParameters[searchArgs]()
+	require.NoError(t, err)
+
+	assert.Equal(t, FunctionParameters{
+		Type: "object",
+		Properties: map[string]*Parameter{
+			"limit": {Type: "integer", Description: "Maximum number of results"},
+			"query": {Type: "string", Description: "What to search for"},
+			"sort":  {Type: "string", Enum: []string{"relevance", "date"}},
+			"tags":  {Type: "array", Items: &Parameter{Type: "string"}},
+			"since": {Type: "string"},
+			"Exact": {Type: "boolean"},
+		},
+		Required: []string{"query", "Exact"},
+	}, params)
+
+	params, err = ToolParameters[reportArgs]()
+	require.NoError(t, err)
+	assert.Equal(t, &Parameter{
+		Type: "object",
+		Properties: map[string]*Parameter{
+			"field": {Type: "string", Description: "Field to filter on"},
+			"value": {Type: "string"},
+		},
+		Required: []string{"field"},
+	}, params.Properties["filter"])
+	assert.Equal(t, &Parameter{Type: "object", AdditionalProperties: &Parameter{Type: "number"}}, params.Properties["weights"])
+	assert.Equal(t, &Parameter{Type: "object"}, params.Properties["extra"])
+	// Recursive types stop at a plain object.
+	assert.Equal(t, &Parameter{Type: "array", Items: &Parameter{Type: "object"}}, params.Properties["tree"].Properties["children"])
+
+	object := map[string]interface{}{
+		"filter":  map[string]interface{}{"value": 1.0, "other": nil},
+		"weights": map[string]interface{}{"a": 1.0, "b": "high"},
+	}
+	assert.EqualError(t, validateSchema(object, &params), `property "filter" has missing required property "field"; property "value" must be of type string; property "weights" value "b" must be of type number`)
+
+	_, err = ToolParameters[string]()
+	assert.EqualError(t, err, "arguments must be a struct, not string")
+	_, err = ToolParameters[struct{ C chan int }]()
+	assert.EqualError(t, err, "field C: unsupported type chan int")
+}
+
+func TestRegisterTool(t *testing.T) {
+	registry := NewToolRegistry()
+	err := RegisterTool(registry, "search", "Searches the docs", func(ctx context.Context, args searchArgs) (searchResult, error) {
+		if args.Query == "fail" {
+			return searchResult{}, errors.New("index unavailable")
+		}
+		return searchResult{Hits: []string{args.Query, args.Sort}}, nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, RegisterTool(registry.Namespace("text"), "upper", "Upper-cases text", func(ctx context.Context, args struct {
+		Text string `json:"text"`
+	}) (string, error) {
+		return "TEXT: " + args.Text, nil
+	}))
+
+	runner := NewToolRunner(registry, ToolGuard{})
+	tools := runner.Tools()
+	require.Len(t, tools, 2)
+	assert.Equal(t, "search", tools[0].Function.Name)
+	assert.Equal(t, "Searches the docs", tools[0].Function.Description)
+	assert.Equal(t, []string{"query", "Exact"}, tools[0].Function.Parameters.Required)
+
+	result, err := runner.Run(context.Background(), toolCall("1", "search", `{"query": "go", "sort": "date", "Exact": true, "since": null}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"hits": ["go", "date"]}`, result)
+
+	result, err = runner.Run(context.Background(), toolCall("2", "text.upper", `{"text": "hi"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "TEXT: hi", result)
+
+	_, err = runner.Run(context.Background(), toolCall("3", "search", `{"sort": "size", "Exact": "yes"}`))
+	assert.EqualError(t, err, `tool search failed: invalid arguments: missing required property "query"; property "Exact" must be of type boolean; property "sort" must be one of relevance, date`)
+
+	_, err = runner.Run(context.Background(), toolCall("4", "search", `{"query": "fail", "Exact": false}`))
+	assert.EqualError(t, err, "tool search failed: index unavailable")
+
+	_, err = runner.Run(context.Background(), toolCall("5", "search", `not json`))
+	assert.ErrorContains(t, err, "invalid arguments")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // https://platform.openai.com/docs/guides/function-calling?api-mode=responses#overview
@@ -43,6 +44,12 @@ type Parameter struct {
 	Maximum     interface{} `json:"maximum,omitempty"`
 	Enum        []string    `json:"enum,omitempty"`
 	Items       *Parameter  `json:"items,omitempty"` // Used when type is "array".
+	// Properties and Required describe the fields of an "object".
+	Properties map[string]*Parameter `json:"properties,omitempty"`
+	Required   []string              `json:"required,omitempty"`
+	// AdditionalProperties is the type of the values of an "object" used
+	// as a map.
+	AdditionalProperties *Parameter `json:"additionalProperties,omitempty"`
 }
 
 // =================================================================================
@@ -107,17 +114,85 @@ type FunctionToCall struct {
 // consistent structure.
 type ChatResponse struct {
 	Success   bool            `json:"success"`
-	Errors    []string        `json:"errors"`
+	Errors    []APIMessage    `json:"errors"`
 	Messages  []interface{}   `json:"messages"`
 	ResultRaw json.RawMessage `json:"result"`
 
-	// IsLegacyResult is a flag set during unmarshaling to indicate which
-	// format was detected.
+	// Format is the format of the result detected while unmarshaling.
+	Format ResponseFormat `json:"-"`
+	// IsLegacyResult is set when the content is held by LegacyResponse
+	// rather than ChatCompletionResponse. Prefer Format to branch on the
+	// format of the result.
 	IsLegacyResult bool `json:"-"`
 	// ChatCompletionResponse holds the standard OpenAI-compatible response.
 	ChatCompletionResponse ChatCompletionResponse
 	// LegacyResponse holds the legacy response.
 	LegacyResponse LegacyResponse
+
+	// FilterVerdicts lists the verdicts of the response filters that
+	// annotated or redacted the response, and of those that allowed it
+	// with matches, e.g. of RuleFilter rules with FilterAllow.
+	FilterVerdicts []FilterVerdict `json:"-"`
+	// Diagnostics lists the parts of the result that had an unexpected
+	// shape and were skipped or converted while decoding.
+	Diagnostics []DecodeDiagnostic `json:"-"`
+	// Dropped lists the messages OverflowRecovery removed from the request
+	// to fit the context window, oldest first; Summary is the summary that
+	// replaced them with OverflowSummarize.
+	Dropped []Message `json:"-"`
+	Summary string    `json:"-"`
+	// Continuations is the number of follow-up requests AutoContinue made
+	// to complete the response.
+	Continuations int `json:"-"`
+	// CacheStatus is the AI Gateway cache status of the response, e.g.
+	// CacheHit or CacheMiss. It is empty when no gateway was used.
+	CacheStatus string `json:"-"`
+	// Hedged is set when the response came from the duplicate request
+	// sent by Client.Hedging.
+	Hedged bool `json:"-"`
+	// Repaired is set by ChatJSON when the reply was invalid JSON that
+	// RepairJSON fixed.
+	Repaired bool `json:"-"`
+
+	model   string
+	latency time.Duration
+	// aliases are the field aliases of the client decoding the response,
+	// fieldAliases if nil, and codec its Client.JSON, StdJSON if nil.
+	aliases map[string]string
+	codec   JSONCodec
+}
+
+// ResponseFormat is the format of the result of a chat response.
+type ResponseFormat int
+
+const (
+	// FormatUnknown is reported for missing, null and non-object results.
+	FormatUnknown ResponseFormat = iota
+	// FormatOpenAI is the OpenAI-compatible format, with a "choices" array.
+	FormatOpenAI
+	// FormatHybrid has OpenAI-style tool calls, with IDs, but no "choices".
+	FormatHybrid
+	// FormatLegacy is the native Workers AI format, with a "response" text.
+	FormatLegacy
+)
+
+func (f ResponseFormat) String() string {
+	switch f {
+	case FormatUnknown:
+		return "unknown"
+	case FormatOpenAI:
+		return "openai"
+	case FormatHybrid:
+		return "hybrid"
+	case FormatLegacy:
+		return "legacy"
+	}
+	return fmt.Sprintf("ResponseFormat(%d)", int(f))
+}
+
+// Raw returns the "result" field of the response as received.
+func (cr *ChatResponse) Raw() json.RawMessage {
+	return cr.ResultRaw
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for ChatResponse.
@@ -126,27 +201,37 @@ type ChatResponse struct {
 // - the hybrid format (modern tool calls without "choices")
 // - and the legacy format.
 func (cr *ChatResponse) UnmarshalJSON(data []byte) error {
-	// A temporary struct is used to capture the top-level fields and the
-	// raw, unparsed 'result' JSON.
-	type TempChatResponse struct {
-		Success   bool            `json:"success"`
-		Errors    []string        `json:"errors"`
-		Messages  []interface{}   `json:"messages"`
-		ResultRaw json.RawMessage `json:"result"`
+	// The envelope is decoded field by field, so that a field of
+	// unexpected type, e.g. a string of errors, doesn't lose the others.
+	var shell map[string]json.RawMessage
+	if err := cr.unmarshal(data, &shell); err != nil {
+		return fmt.Errorf("failed to unmarshal initial response shell: %w", err)
 	}
+	cr.decodeField(shell, "success", "success", &cr.Success)
+	cr.decodeField(shell, "errors", "errors", &cr.Errors)
+	cr.decodeField(shell, "messages", "messages", &cr.Messages)
+	cr.ResultRaw = shell["result"]
 
-	var temp TempChatResponse
-	if err := json.Unmarshal(data, &temp); err != nil {
-		return fmt.Errorf("failed to unmarshal initial response shell: %w", err)
+	if len(cr.ResultRaw) < 2 || isNull(cr.ResultRaw) { // Check for empty, null or "{}"
+		return nil
 	}
 
-	// Copy the definite top-level fields.
-	cr.Success = temp.Success
-	cr.Errors = temp.Errors
-	cr.Messages = temp.Messages
-	cr.ResultRaw = temp.ResultRaw
+	// Some models name the fields in camelCase; they are decoded from a
+	// copy of the result using the snake_case names. ResultRaw is kept as
+	// received.
+	normalized, renamed := snakeCaseFields(cr.ResultRaw, "result", cr.aliases)
+	for _, path := range renamed {
+		cr.diagnose(path, "camelCase name accepted")
+	}
 
-	if len(cr.ResultRaw) < 2 { // Check for empty or "{}"
+	// A result that isn't an object can't be in any of the formats; keep
+	// what it says as the legacy response text.
+	var fields map[string]json.RawMessage
+	if err := cr.unmarshal(normalized, &fields); err != nil {
+		cr.IsLegacyResult = true
+		cr.Format = FormatUnknown
+		cr.diagnose("result", "not an object")
+		cr.LegacyResponse.Response = cr.decodeContent(cr.ResultRaw, "result")
 		return nil
 	}
 
@@ -161,24 +246,39 @@ func (cr *ChatResponse) UnmarshalJSON(data []byte) error {
 	var probe ResultProbe
 	// We only care about whether this unmarshaling works and what fields are populated,
 	// so we can ignore the error.
-	_ = json.Unmarshal(cr.ResultRaw, &probe)
+	_ = cr.unmarshal(normalized, &probe)
+
+	// Responses of unexpected shape are decoded field by field, collecting
+	// the problems in Diagnostics instead of failing.
+	defer cr.checkUsage(fields)
 
 	// Case 1: Standard OpenAI format (has a "choices" array).
 	if probe.Choices != nil {
 		cr.IsLegacyResult = false
-		return json.Unmarshal(cr.ResultRaw, &cr.ChatCompletionResponse)
+		cr.Format = FormatOpenAI
+		if err := cr.unmarshal(normalized, &cr.ChatCompletionResponse); err != nil {
+			cr.ChatCompletionResponse = ChatCompletionResponse{}
+			cr.decodeChatCompletionLeniently(fields)
+		}
+		cr.checkChoices()
+		return nil
 	}
 
 	// Case 2: Hybrid format (no "choices", but has modern tool calls with an "id").
 	if probe.ToolCalls != nil && len(*probe.ToolCalls) > 0 && (*probe.ToolCalls)[0].ID != "" {
 		cr.IsLegacyResult = false
+		cr.Format = FormatHybrid
 		// Manually construct the ChatCompletionResponse since 'choices' is missing.
 		var result struct {
 			ToolCalls []ToolCall `json:"tool_calls"`
 			Usage     Usage      `json:"usage"`
 		}
-		if err := json.Unmarshal(cr.ResultRaw, &result); err != nil {
-			return fmt.Errorf("failed to parse hybrid response result: %w", err)
+		if err := cr.unmarshal(normalized, &result); err != nil {
+			result.ToolCalls, result.Usage = nil, Usage{}
+			cr.decodeField(fields, "tool_calls", "result.tool_calls", &result.ToolCalls)
+			if raw, ok := fields["usage"]; ok {
+				result.Usage = cr.decodeUsage(raw, "result.usage")
+			}
 		}
 		cr.ChatCompletionResponse.Choices = []Choice{
 			{
@@ -194,7 +294,44 @@ func (cr *ChatResponse) UnmarshalJSON(data []byte) error {
 
 	// Case 3: Fallback to legacy format.
 	cr.IsLegacyResult = true
-	return json.Unmarshal(cr.ResultRaw, &cr.LegacyResponse)
+	cr.Format = FormatLegacy
+	if err := cr.unmarshal(normalized, &cr.LegacyResponse); err != nil {
+		cr.LegacyResponse = LegacyResponse{}
+		cr.decodeLegacyLeniently(fields)
+	}
+	return nil
+}
+
+// APIMessage is an entry of the "errors" or "messages" array of a response
+// envelope.
+type APIMessage struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// UnmarshalJSON accepts both the {"code": ..., "message": ...} objects
+// returned by the API and plain strings, as sent by older endpoints.
+func (m *APIMessage) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*m = APIMessage{Message: text}
+		return nil
+	}
+
+	type plain APIMessage
+	var msg plain
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to unmarshal API message: %w", err)
+	}
+	*m = APIMessage(msg)
+	return nil
+}
+
+func (m APIMessage) String() string {
+	if m.Code == 0 {
+		return m.Message
+	}
+	return fmt.Sprintf("%d: %s", m.Code, m.Message)
 }
 
 // ChatCompletionRequest is the complete payload sent to the Chat Completions API.
@@ -206,7 +343,37 @@ type ChatCompletionRequest struct {
 	Messages []Message `json:"messages"` // Can contain ChatMessage or ToolMessage.
 	Tools    []Tool    `json:"tools,omitempty"`
 	Stream   bool      `json:"stream,omitempty"`
+	// ResponseFormat constrains the output of models supporting JSON mode.
+	ResponseFormat *OutputFormat `json:"response_format,omitempty"`
 	ModelParameters
+
+	// Cache overrides the client's AI Gateway cache options for this
+	// request. It is sent as headers, not in the body.
+	Cache *CacheOptions `json:"-"`
+	// Priority is the scheduling class of the request when the client has
+	// a Scheduler.
+	Priority Priority `json:"-"`
+	// Tenant and Metadata tag the request like WithTenant and
+	// WithMetadata.
+	Tenant   string            `json:"-"`
+	Metadata map[string]string `json:"-"`
+	// Language, if set, makes the reply be in a given language.
+	Language *LanguageOptions `json:"-"`
+	// Prefill, if set, is the start of the reply, to steer the output of
+	// the model, e.g. "{" for JSON. With a chat template of
+	// Client.Templates it starts the assistant turn of the raw prompt;
+	// otherwise it is sent as a trailing assistant message, which most
+	// chat models continue. The content of the response starts with it,
+	// see MergePrefill.
+	Prefill string `json:"-"`
+}
+
+// OutputFormat is the response_format of a request in JSON mode.
+type OutputFormat struct {
+	// Type is "json_object" or "json_schema".
+	Type string `json:"type"`
+	// JSONSchema is the schema of the output when Type is "json_schema".
+	JSONSchema interface{} `json:"json_schema,omitempty"`
 }
 
 // Parameters to be set in the ChatCompletionRequest
@@ -230,6 +397,15 @@ type ModelParameters struct {
 	//
 	// Should not be used in conjuction with TopK
 	TopP float64 `json:"top_p,omitempty"`
+
+	// N is the number of completions to generate for the prompt, for models
+	// that support it. Read them with ChatResponse.GetCompletions.
+	N int `json:"n,omitempty"`
+
+	// Zero lists the parameters set to 0 on purpose, e.g. ParamTemperature
+	// for greedy decoding. A zero field otherwise means unset: it is not
+	// sent, and ChatDefaults fill it in.
+	Zero Params `json:"-"`
 }
 
 // UnmarshalJSON provides custom unmarshaling logic for the ChatCompletionRequest.
@@ -345,7 +521,7 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
-// ListModels is unpacked into this type
+// ModelsResponse maps the names of models to their description.
 type ModelsResponse map[string]*ModelInfo
 
 // Model attributes struct
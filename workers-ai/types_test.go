@@ -150,6 +150,30 @@ func TestChatResponse_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestChatResponse_Format(t *testing.T) {
+	tests := []struct {
+		result string
+		format ResponseFormat
+	}{
+		{`{"choices": [{"message": {"role": "assistant", "content": "Hi"}}]}`, FormatOpenAI},
+		{`{"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "f", "arguments": "{}"}}]}`, FormatHybrid},
+		{`{"response": "Hi"}`, FormatLegacy},
+		{`"Hi"`, FormatUnknown},
+		{`null`, FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			var resp ChatResponse
+			require.NoError(t, json.Unmarshal([]byte(`{"success": true, "result": `+tt.result+`}`), &resp))
+			assert.Equal(t, tt.format, resp.Format)
+			assert.JSONEq(t, tt.result, string(resp.Raw()))
+		})
+	}
+
+	assert.Equal(t, "ResponseFormat(9)", ResponseFormat(9).String())
+}
+
 func TestChatCompletionRequest_UnmarshalJSON(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -407,3 +431,18 @@ func TestLegacyResponse_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestChatResponse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"objects": `{"success": false, "errors": [{"code": 3040, "message": "Capacity temporarily exceeded"}], "result": {}}`,
+		"strings": `{"success": false, "errors": ["Capacity temporarily exceeded"], "result": {}}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			var resp ChatResponse
+			require.NoError(t, json.Unmarshal([]byte(body), &resp))
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, "Capacity temporarily exceeded", resp.Errors[0].Message)
+		})
+	}
+}
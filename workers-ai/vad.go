@@ -0,0 +1,196 @@
+package workersai
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Defaults of VADOptions.
+const (
+	DefaultVADFrame     = 30 * time.Millisecond
+	DefaultVADThreshold = 0.01
+	DefaultVADMinPause  = 500 * time.Millisecond
+	DefaultVADMinSpeech = 100 * time.Millisecond
+	DefaultVADPadding   = 150 * time.Millisecond
+)
+
+// VADOptions tune DetectSpeech. Zero fields take the defaults above.
+type VADOptions struct {
+	// SampleRate of the audio. Defaults to DefaultSampleRate.
+	SampleRate int
+	// Frame is the unit of audio classified as speech or silence.
+	Frame time.Duration
+	// Threshold is the RMS level, as a fraction of full scale, from which a
+	// frame counts as speech. Raise it for noisy recordings.
+	Threshold float64
+	// MinPause is the shortest silence that splits the audio.
+	MinPause time.Duration
+	// MinSpeech is the shortest sound kept as speech; shorter ones, like
+	// clicks, are dropped.
+	MinSpeech time.Duration
+	// Padding is the silence kept before and after each segment, so that
+	// soft word onsets and endings aren't cut off. A negative padding
+	// disables it.
+	Padding time.Duration
+}
+
+func (o *VADOptions) withDefaults() VADOptions {
+	var opts VADOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = DefaultSampleRate
+	}
+	if opts.Frame <= 0 {
+		opts.Frame = DefaultVADFrame
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultVADThreshold
+	}
+	if opts.MinPause <= 0 {
+		opts.MinPause = DefaultVADMinPause
+	}
+	if opts.MinSpeech <= 0 {
+		opts.MinSpeech = DefaultVADMinSpeech
+	}
+	if opts.Padding < 0 {
+		opts.Padding = 0
+	} else if opts.Padding == 0 {
+		opts.Padding = DefaultVADPadding
+	}
+	return opts
+}
+
+// SpeechSegment is a stretch of speech found by DetectSpeech.
+type SpeechSegment struct {
+	// Start and End locate the segment within the audio.
+	Start time.Duration
+	End   time.Duration
+	// PCM is the audio of the segment, sharing memory with the input.
+	PCM []byte
+}
+
+// DetectSpeech finds the speech in 16-bit little-endian mono PCM audio by
+// its energy, trimming the silence around it and splitting it on pauses.
+// It is a simple voice activity detector meant to cut the audio sent to
+// speech recognition, not to tell speech from music or noise.
+func DetectSpeech(pcm []byte, opts *VADOptions) []SpeechSegment {
+	o := opts.withDefaults()
+
+	frameSamples := int(o.Frame.Seconds() * float64(o.SampleRate))
+	if frameSamples < 1 {
+		frameSamples = 1
+	}
+	samples := len(pcm) / 2
+	frames := (samples + frameSamples - 1) / frameSamples
+
+	// Runs of speech frames, as [start, end) frame indexes.
+	var runs [][2]int
+	for f := 0; f < frames; f++ {
+		start := f * frameSamples
+		end := start + frameSamples
+		if end > samples {
+			end = samples
+		}
+		if rms(pcm[2*start:2*end]) < o.Threshold {
+			continue
+		}
+		if n := len(runs); n > 0 && runs[n-1][1] == f {
+			runs[n-1][1] = f + 1
+		} else {
+			runs = append(runs, [2]int{f, f + 1})
+		}
+	}
+
+	toSamples := func(d time.Duration) int {
+		return int(d.Seconds() * float64(o.SampleRate))
+	}
+	minPause, minSpeech, padding := toSamples(o.MinPause), toSamples(o.MinSpeech), toSamples(o.Padding)
+
+	// Join the runs separated by less than a pause, then drop the short ones.
+	var spans [][2]int
+	for _, run := range runs {
+		start, end := run[0]*frameSamples, run[1]*frameSamples
+		if end > samples {
+			end = samples
+		}
+		if n := len(spans); n > 0 && start-spans[n-1][1] < minPause {
+			spans[n-1][1] = end
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+	}
+
+	var segments []SpeechSegment
+	for i, span := range spans {
+		if span[1]-span[0] < minSpeech {
+			continue
+		}
+
+		start, end := span[0]-padding, span[1]+padding
+		// Padding never reaches past the middle of a pause.
+		if start < 0 {
+			start = 0
+		}
+		if i > 0 && start < (spans[i-1][1]+span[0])/2 {
+			start = (spans[i-1][1] + span[0]) / 2
+		}
+		if end > samples {
+			end = samples
+		}
+		if i < len(spans)-1 && end > (span[1]+spans[i+1][0])/2 {
+			end = (span[1] + spans[i+1][0]) / 2
+		}
+
+		segments = append(segments, SpeechSegment{
+			Start: time.Duration(start) * time.Second / time.Duration(o.SampleRate),
+			End:   time.Duration(end) * time.Second / time.Duration(o.SampleRate),
+			PCM:   pcm[2*start : 2*end],
+		})
+	}
+	return segments
+}
+
+// rms returns the root mean square level of 16-bit PCM as a fraction of full
+// scale.
+func rms(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		sample := float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / 32768
+		sum += sample * sample
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// TranscribeSpeech transcribes only the speech of 16-bit mono PCM audio: it
+// runs DetectSpeech and sends each segment to modelID on its own. Silence
+// isn't billed and the timings of the updates, and of their words, are
+// those of the segments within the audio.
+func (c *Client) TranscribeSpeech(ctx context.Context, modelID string, pcm []byte, opts *VADOptions) ([]TranscriptUpdate, error) {
+	sampleRate := opts.withDefaults().SampleRate
+
+	var updates []TranscriptUpdate
+	for i, segment := range DetectSpeech(pcm, opts) {
+		result, err := c.transcribe(ctx, modelID, encodeWAV(segment.PCM, sampleRate), nil)
+		if err != nil {
+			return updates, fmt.Errorf("failed to transcribe segment %d: %w", i, err)
+		}
+
+		updates = append(updates, TranscriptUpdate{
+			Text:  strings.TrimSpace(result.Text),
+			Start: segment.Start,
+			End:   segment.End,
+			Words: shiftWords(result.Words, segment.Start),
+		})
+	}
+	return updates, nil
+}
@@ -0,0 +1,79 @@
+package workersai
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAudio builds 16 kHz PCM from alternating silence and tone durations.
+func testAudio(durations ...time.Duration) []byte {
+	var pcm []byte
+	for i, d := range durations {
+		n := int(d.Seconds() * DefaultSampleRate)
+		for s := 0; s < n; s++ {
+			var sample int16
+			if i%2 == 1 {
+				sample = int16(8000 * math.Sin(2*math.Pi*440*float64(s)/DefaultSampleRate))
+			}
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(sample))
+		}
+	}
+	return pcm
+}
+
+func TestDetectSpeech(t *testing.T) {
+	ms := time.Millisecond
+	// Silence, speech, short pause, speech, long pause, click, long pause,
+	// speech, silence.
+	pcm := testAudio(960*ms, 990*ms, 210*ms, 600*ms, 1200*ms, 30*ms, 1200*ms, 510*ms, 600*ms)
+
+	segments := DetectSpeech(pcm, nil)
+	require.Len(t, segments, 2)
+
+	// The short pause doesn't split, the padding is kept around.
+	assert.Equal(t, 810*ms, segments[0].Start)
+	assert.Equal(t, 2910*ms, segments[0].End)
+	assert.Equal(t, 2*int(2100*ms.Seconds()*DefaultSampleRate), len(segments[0].PCM))
+
+	// The click is dropped.
+	assert.Equal(t, 5040*ms, segments[1].Start)
+	assert.Equal(t, 5850*ms, segments[1].End)
+
+	assert.Empty(t, DetectSpeech(testAudio(time.Second), nil))
+	assert.Len(t, DetectSpeech(pcm, &VADOptions{MinSpeech: 10 * ms, Padding: -1}), 3)
+}
+
+func TestClient_TranscribeSpeech(t *testing.T) {
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wav, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		sizes = append(sizes, len(wav)-44)
+		fmt.Fprint(w, `{"success": true, "result": {"text": "hello", "words": [{"word": "hello", "start": 0.25, "end": 0.5}]}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	pcm := testAudio(2*time.Second, time.Second, 2*time.Second, time.Second)
+	updates, err := client.TranscribeSpeech(context.Background(), ModelWhisper, pcm, &VADOptions{Frame: 10 * time.Millisecond, Padding: -1})
+	require.NoError(t, err)
+
+	// Only the speech was sent.
+	assert.Equal(t, []int{2 * DefaultSampleRate, 2 * DefaultSampleRate}, sizes)
+	require.Len(t, updates, 2)
+	assert.Equal(t, 5*time.Second, updates[1].Start)
+	assert.Equal(t, 6*time.Second, updates[1].End)
+	assert.Equal(t, []TranscriptionWord{{Word: "hello", Start: 5.25, End: 5.5}}, updates[1].Words)
+}
@@ -0,0 +1,68 @@
+package workersai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors returned by Client.Validate. They are wrapped with details.
+var (
+	ErrInvalidToken      = errors.New("invalid API token")
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrMissingPermission = errors.New("API token lacks permission")
+)
+
+// NewClientWithValidation is NewClient followed by Validate, failing fast on
+// bad credentials instead of at the first request.
+func NewClientWithValidation(ctx context.Context, accountID, apiToken string) (*Client, error) {
+	client := NewClient(accountID, apiToken)
+	if err := client.Validate(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Validate checks that the API token is active and that it may read the
+// Workers AI models of the account. It can't check the permission to run
+// models without running one. A token whose account resources don't include
+// the account fails with ErrMissingPermission, as the API doesn't tell it
+// apart from a missing permission.
+func (c *Client) Validate(ctx context.Context) error {
+	if c.AccountID == "" {
+		return fmt.Errorf("%w: account ID is empty", ErrAccountNotFound)
+	}
+	if c.APIToken == "" {
+		return fmt.Errorf("%w: token is empty", ErrInvalidToken)
+	}
+
+	var token struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	// User tokens and account owned tokens are verified at different endpoints.
+	status, err := c.apiGet(ctx, "/user/tokens/verify", &token)
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		status, err = c.apiGet(ctx, fmt.Sprintf("/accounts/%s/tokens/verify", c.AccountID), &token)
+	}
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	case err != nil:
+		return fmt.Errorf("failed to verify token: %w", err)
+	case token.Status != "active":
+		return fmt.Errorf("%w: token status is %q", ErrInvalidToken, token.Status)
+	}
+
+	// The account is probed through Workers AI itself: reading the account
+	// requires a permission that tokens made for Workers AI lack.
+	report, err := c.Ping(ctx)
+	switch {
+	case report != nil && report.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s doesn't exist", ErrAccountNotFound, c.AccountID)
+	case report != nil && report.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: Workers AI Read on account %s is required", ErrMissingPermission, c.AccountID)
+	}
+	return err
+}
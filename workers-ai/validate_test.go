@@ -0,0 +1,96 @@
+package workersai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		handlers map[string]func(w http.ResponseWriter)
+		wantErr  error
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name: "account owned token",
+			handlers: map[string]func(w http.ResponseWriter){
+				"/user/tokens/verify": errorStatus(http.StatusUnauthorized),
+			},
+		},
+		{
+			name: "invalid token",
+			handlers: map[string]func(w http.ResponseWriter){
+				"/user/tokens/verify":                  errorStatus(http.StatusUnauthorized),
+				"/accounts/test-account/tokens/verify": errorStatus(http.StatusUnauthorized),
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "expired token",
+			handlers: map[string]func(w http.ResponseWriter){
+				"/user/tokens/verify": func(w http.ResponseWriter) {
+					fmt.Fprint(w, `{"success": true, "result": {"id": "abc", "status": "expired"}}`)
+				},
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "unknown account",
+			handlers: map[string]func(w http.ResponseWriter){
+				"/accounts/test-account/ai/models/search": errorStatus(http.StatusNotFound),
+			},
+			wantErr: ErrAccountNotFound,
+		},
+		{
+			name: "no permission to read the account",
+			handlers: map[string]func(w http.ResponseWriter){
+				"/accounts/test-account": errorStatus(http.StatusForbidden),
+			},
+		},
+		{
+			name: "no Workers AI permission",
+			handlers: map[string]func(w http.ResponseWriter){
+				"/accounts/test-account/ai/models/search": errorStatus(http.StatusForbidden),
+			},
+			wantErr: ErrMissingPermission,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if handler, ok := tt.handlers[r.URL.Path]; ok {
+					handler(w)
+					return
+				}
+				fmt.Fprint(w, `{"success": true, "result": {"id": "abc", "status": "active"}}`)
+			}))
+			defer server.Close()
+
+			client := NewClient("test-account", "test-token")
+			client.BaseURL = server.URL
+
+			err := client.Validate(context.Background())
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func errorStatus(code int) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(code)
+		fmt.Fprint(w, `{"success": false, "errors": [{"code": 1000, "message": "nope"}]}`)
+	}
+}
@@ -0,0 +1,433 @@
+package workersai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// DefaultTopK is the number of matches a vector query returns when TopK is
+// not set.
+const DefaultTopK = 5
+
+// Values of VectorQuery.ReturnMetadata.
+const (
+	ReturnMetadataNone    = "none"
+	ReturnMetadataIndexed = "indexed"
+	ReturnMetadataAll     = "all"
+)
+
+// Condition is a filter on one metadata field, built with Eq, Ne, In,
+// NotIn, Lt, Lte, Gt and Gte. Combine conditions on the same field with
+// And, e.g. for a range.
+type Condition map[string]interface{}
+
+func Eq(value interface{}) Condition  { return Condition{"$eq": value} }
+func Ne(value interface{}) Condition  { return Condition{"$ne": value} }
+func Lt(value interface{}) Condition  { return Condition{"$lt": value} }
+func Lte(value interface{}) Condition { return Condition{"$lte": value} }
+func Gt(value interface{}) Condition  { return Condition{"$gt": value} }
+func Gte(value interface{}) Condition { return Condition{"$gte": value} }
+
+// In matches fields equal to any of values.
+func In(values ...interface{}) Condition { return Condition{"$in": values} }
+
+// NotIn matches fields equal to none of values.
+func NotIn(values ...interface{}) Condition { return Condition{"$nin": values} }
+
+// And returns the conjunction of conditions on the same field.
+func And(conditions ...Condition) Condition {
+	and := Condition{}
+	for _, c := range conditions {
+		for op, value := range c {
+			and[op] = value
+		}
+	}
+	return and
+}
+
+// MetadataFilter restricts a vector query to the vectors whose metadata
+// match every condition. Keys are metadata fields, dotted for nested ones;
+// only fields with a metadata index can be filtered on.
+//
+//	workersai.MetadataFilter{
+//		"tenant": workersai.Eq("acme"),
+//		"year":   workersai.And(workersai.Gte(2020), workersai.Lt(2024)),
+//	}
+type MetadataFilter map[string]Condition
+
+// VectorQuery are the settings of a vector query.
+type VectorQuery struct {
+	// TopK is the number of matches to return. Defaults to DefaultTopK.
+	// Vectorize allows at most 100, or 20 with values or all metadata.
+	TopK int
+	// Namespace restricts the query to the vectors of one namespace, e.g.
+	// one per tenant.
+	Namespace string
+	Filter    MetadataFilter
+	// MinScore drops matches scoring below it. Scores depend on the metric
+	// of the index: for cosine similarity they range from -1 to 1.
+	MinScore float64
+	// ReturnValues includes the vectors in the matches.
+	ReturnValues bool
+	// ReturnMetadata is ReturnMetadataNone, ReturnMetadataIndexed or
+	// ReturnMetadataAll, the default.
+	ReturnMetadata string
+}
+
+// VectorMatch is a vector found by a query.
+type VectorMatch struct {
+	ID        string                 `json:"id"`
+	Score     float64                `json:"score"`
+	Values    []float32              `json:"values,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Namespace string                 `json:"namespace,omitempty"`
+}
+
+// vectorQueryRequest is the body of the Vectorize query endpoint.
+type vectorQueryRequest struct {
+	Vector         []float32      `json:"vector"`
+	TopK           int            `json:"topK"`
+	Namespace      string         `json:"namespace,omitempty"`
+	Filter         MetadataFilter `json:"filter,omitempty"`
+	ReturnValues   bool           `json:"returnValues,omitempty"`
+	ReturnMetadata string         `json:"returnMetadata"`
+}
+
+// VectorizeIndex queries a Vectorize index through the Cloudflare API.
+type VectorizeIndex struct {
+	Client *Client
+	Name   string
+}
+
+// VectorizeIndex returns the Vectorize index called name, of the client's
+// account.
+func (c *Client) VectorizeIndex(name string) *VectorizeIndex {
+	return &VectorizeIndex{Client: c, Name: name}
+}
+
+// Query returns the vectors of the index closest to vector, best first.
+// opts may be nil.
+func (i *VectorizeIndex) Query(ctx context.Context, vector []float32, opts *VectorQuery) ([]VectorMatch, error) {
+	var q VectorQuery
+	if opts != nil {
+		q = *opts
+	}
+	if q.TopK <= 0 {
+		q.TopK = DefaultTopK
+	}
+	if q.ReturnMetadata == "" {
+		q.ReturnMetadata = ReturnMetadataAll
+	}
+
+	request := vectorQueryRequest{
+		Vector:         vector,
+		TopK:           q.TopK,
+		Namespace:      q.Namespace,
+		Filter:         q.Filter,
+		ReturnValues:   q.ReturnValues,
+		ReturnMetadata: q.ReturnMetadata,
+	}
+
+	var result struct {
+		Matches []VectorMatch `json:"matches"`
+	}
+	path := fmt.Sprintf("/accounts/%s/vectorize/v2/indexes/%s/query", i.Client.AccountID, url.PathEscape(i.Name))
+	if _, err := i.Client.apiPost(ctx, path, request, &result); err != nil {
+		return nil, fmt.Errorf("failed to query index %s: %w", i.Name, err)
+	}
+
+	matches := result.Matches[:0]
+	for _, m := range result.Matches {
+		if q.MinScore == 0 || m.Score >= q.MinScore {
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
+
+// Vector is a vector to insert into an index, with the metadata returned
+// by queries.
+type Vector struct {
+	ID        string                 `json:"id"`
+	Values    []float32              `json:"values"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Namespace string                 `json:"namespace,omitempty"`
+}
+
+// Upsert inserts vectors into the index, replacing those with the same IDs,
+// and returns the ID of the mutation. Vectorize applies mutations
+// asynchronously: the vectors may take a few seconds to show up in
+// queries.
+func (i *VectorizeIndex) Upsert(ctx context.Context, vectors []Vector) (string, error) {
+	// The endpoint takes one JSON vector per line.
+	var body bytes.Buffer
+	for _, v := range vectors {
+		line, err := i.Client.codec().Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal vector %s: %w", v.ID, err)
+		}
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+
+	var envelope struct {
+		Result struct {
+			MutationID string `json:"mutationId"`
+		} `json:"result"`
+	}
+	path := fmt.Sprintf("/accounts/%s/vectorize/v2/indexes/%s/upsert", i.Client.AccountID, url.PathEscape(i.Name))
+	if _, err := i.Client.apiDecode(ctx, "POST", path, "application/x-ndjson", body.Bytes(), &envelope); err != nil {
+		return "", fmt.Errorf("failed to upsert into index %s: %w", i.Name, err)
+	}
+	return envelope.Result.MutationID, nil
+}
+
+// vectorIDsRequest is the body of the Vectorize endpoints taking vector
+// IDs.
+type vectorIDsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// GetByIDs returns the vectors of the index with the given IDs. IDs not in
+// the index are left out.
+func (i *VectorizeIndex) GetByIDs(ctx context.Context, ids []string) ([]Vector, error) {
+	var vectors []Vector
+	path := fmt.Sprintf("/accounts/%s/vectorize/v2/indexes/%s/get_by_ids", i.Client.AccountID, url.PathEscape(i.Name))
+	if _, err := i.Client.apiPost(ctx, path, vectorIDsRequest{IDs: ids}, &vectors); err != nil {
+		return nil, fmt.Errorf("failed to get vectors from index %s: %w", i.Name, err)
+	}
+	return vectors, nil
+}
+
+// DeleteByIDs deletes the vectors with the given IDs from the index and
+// returns the ID of the mutation. Like upserts, deletions are applied
+// asynchronously.
+func (i *VectorizeIndex) DeleteByIDs(ctx context.Context, ids []string) (string, error) {
+	var result struct {
+		MutationID string `json:"mutationId"`
+	}
+	path := fmt.Sprintf("/accounts/%s/vectorize/v2/indexes/%s/delete_by_ids", i.Client.AccountID, url.PathEscape(i.Name))
+	if _, err := i.Client.apiPost(ctx, path, vectorIDsRequest{IDs: ids}, &result); err != nil {
+		return "", fmt.Errorf("failed to delete vectors from index %s: %w", i.Name, err)
+	}
+	return result.MutationID, nil
+}
+
+// Retriever finds the documents relevant to a question for
+// retrieval-augmented generation: it embeds the question and queries a
+// Vectorize index holding the embeddings of the documents.
+type Retriever struct {
+	Client ClientInterface
+	// EmbeddingModel must be the model the index was filled with.
+	EmbeddingModel   string
+	EmbeddingOptions *EmbeddingOptions
+	Index            *VectorizeIndex
+	// Query is the default query; Retrieve can narrow it down.
+	Query VectorQuery
+}
+
+// NewRetriever returns a retriever embedding questions with
+// embeddingModel through client and looking them up in index.
+func NewRetriever(client *Client, embeddingModel string, index *VectorizeIndex) *Retriever {
+	return &Retriever{Client: client, EmbeddingModel: embeddingModel, Index: index}
+}
+
+// ErrOutOfScope is returned by Retriever.Retrieve for options that would
+// query outside the filter or the namespace of the retriever.
+var ErrOutOfScope = errors.New("query out of the retriever's scope")
+
+// Retrieve returns the matches for text. The fields of opts that are set
+// override r.Query, but its filter and namespace can only narrow r.Query
+// down: its conditions are combined with those of r.Query, and a different
+// namespace fails with ErrOutOfScope. This way a shared retriever can be
+// scoped per tenant or document set.
+func (r *Retriever) Retrieve(ctx context.Context, text string, opts *VectorQuery) ([]VectorMatch, error) {
+	query, err := r.Query.merge(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	embedding, err := r.Client.EmbedContext(ctx, r.EmbeddingModel, []string{text}, r.EmbeddingOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return r.Index.Query(ctx, embedding.Data[0], &query)
+}
+
+// merge returns q with the set fields of override applied. The filter of
+// override is combined with that of q, and its namespace must be that of
+// q, if any.
+func (q VectorQuery) merge(override *VectorQuery) (VectorQuery, error) {
+	if override == nil {
+		return q, nil
+	}
+	if override.TopK != 0 {
+		q.TopK = override.TopK
+	}
+	if override.Namespace != "" {
+		if q.Namespace != "" && override.Namespace != q.Namespace {
+			return q, fmt.Errorf("%w: namespace %q instead of %q", ErrOutOfScope, override.Namespace, q.Namespace)
+		}
+		q.Namespace = override.Namespace
+	}
+	if override.MinScore != 0 {
+		q.MinScore = override.MinScore
+	}
+	if override.ReturnValues {
+		q.ReturnValues = true
+	}
+	if override.ReturnMetadata != "" {
+		q.ReturnMetadata = override.ReturnMetadata
+	}
+	if len(override.Filter) > 0 {
+		filter := make(MetadataFilter, len(q.Filter)+len(override.Filter))
+		for field, c := range q.Filter {
+			filter[field] = c
+		}
+		for field, c := range override.Filter {
+			combined, err := combineConditions(filter[field], c)
+			if err != nil {
+				return q, fmt.Errorf("%w: filter on %s: %v", ErrOutOfScope, field, err)
+			}
+			filter[field] = combined
+		}
+		q.Filter = filter
+	}
+	return q, nil
+}
+
+// combineConditions returns the conjunction of the conditions a and b on
+// a field. An operator in both keeps the most restrictive of the values
+// where one is: the highest lower bound, the lowest upper bound, the
+// intersection of $in and the union of $nin. Otherwise the values must be
+// equal.
+func combineConditions(a, b Condition) (Condition, error) {
+	combined := make(Condition, len(a)+len(b))
+	for op, value := range a {
+		combined[op] = value
+	}
+	for op, value := range b {
+		current, ok := combined[op]
+		if !ok || equalValues(current, value) {
+			combined[op] = value
+			continue
+		}
+
+		var err error
+		switch op {
+		case "$gt", "$gte":
+			combined[op], err = bound(current, value, 1)
+		case "$lt", "$lte":
+			combined[op], err = bound(current, value, -1)
+		case "$in":
+			combined[op], err = intersect(current, value)
+		case "$nin":
+			combined[op], err = union(current, value)
+		default:
+			err = fmt.Errorf("%s %v and %v", op, current, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return combined, nil
+}
+
+// bound returns the greater of a and b for sign 1, the lesser for -1.
+func bound(a, b interface{}, sign int) (interface{}, error) {
+	c, ok := compareValues(a, b)
+	if !ok {
+		return nil, fmt.Errorf("incomparable bounds %v and %v", a, b)
+	}
+	if c*sign >= 0 {
+		return a, nil
+	}
+	return b, nil
+}
+
+func intersect(a, b interface{}) (interface{}, error) {
+	as, aok := a.([]interface{})
+	bs, bok := b.([]interface{})
+	if !aok || !bok {
+		return nil, fmt.Errorf("$in %v and %v", a, b)
+	}
+	var values []interface{}
+	for _, v := range bs {
+		if containsValue(as, v) {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("$in %v and %v have no value in common", a, b)
+	}
+	return values, nil
+}
+
+func union(a, b interface{}) (interface{}, error) {
+	as, aok := a.([]interface{})
+	bs, bok := b.([]interface{})
+	if !aok || !bok {
+		return nil, fmt.Errorf("$nin %v and %v", a, b)
+	}
+	values := append([]interface{}(nil), as...)
+	for _, v := range bs {
+		if !containsValue(values, v) {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, value := range values {
+		if equalValues(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(a, b interface{}) bool {
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues compares two strings or two numbers of any type. It
+// returns false for other values.
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	}
+	x, xok := number(a)
+	y, yok := number(b)
+	switch {
+	case !xok || !yok:
+		return 0, false
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func number(v interface{}) (float64, bool) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,130 @@
+package workersai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVectorizeIndex_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/accounts/test-account/vectorize/v2/indexes/docs/query", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, []interface{}{0.5, 0.25}, req["vector"])
+		assert.Equal(t, 3.0, req["topK"])
+		assert.Equal(t, "acme", req["namespace"])
+		assert.Equal(t, "all", req["returnMetadata"])
+		assert.Equal(t, map[string]interface{}{
+			"type": map[string]interface{}{"$in": []interface{}{"faq", "manual"}},
+			"year": map[string]interface{}{"$gte": 2020.0, "$lt": 2024.0},
+		}, req["filter"])
+
+		fmt.Fprint(w, `{"success": true, "result": {"count": 3, "matches": [
+			{"id": "a", "score": 0.9, "metadata": {"type": "faq"}, "namespace": "acme"},
+			{"id": "b", "score": 0.75},
+			{"id": "c", "score": 0.4}
+		]}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	matches, err := client.VectorizeIndex("docs").Query(context.Background(), []float32{0.5, 0.25}, &VectorQuery{
+		TopK:      3,
+		Namespace: "acme",
+		Filter: MetadataFilter{
+			"type": In("faq", "manual"),
+			"year": And(Gte(2020), Lt(2024)),
+		},
+		MinScore: 0.5,
+	})
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, VectorMatch{ID: "a", Score: 0.9, Metadata: map[string]interface{}{"type": "faq"}, Namespace: "acme"}, matches[0])
+	assert.Equal(t, "b", matches[1].ID)
+}
+
+func TestRetriever(t *testing.T) {
+	var queries []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ModelBAAI) {
+			fmt.Fprint(w, `{"success": true, "result": {"shape": [1, 2], "data": [[1, 0]]}}`)
+			return
+		}
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		queries = append(queries, req)
+		fmt.Fprint(w, `{"success": true, "result": {"matches": [{"id": "a", "score": 0.8}]}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	retriever := NewRetriever(client, ModelBAAI, client.VectorizeIndex("docs"))
+	retriever.Query = VectorQuery{TopK: 10, Filter: MetadataFilter{"tenant": Eq("acme"), "year": Gte(2020)}}
+
+	matches, err := retriever.Retrieve(context.Background(), "How do I reset my password?", &VectorQuery{
+		Namespace: "acme",
+		Filter:    MetadataFilter{"lang": Eq("en"), "tenant": Eq("acme"), "year": And(Gte(2022), Lt(2024))},
+	})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	require.Len(t, queries, 1)
+	assert.Equal(t, []interface{}{1.0, 0.0}, queries[0]["vector"])
+	assert.Equal(t, 10.0, queries[0]["topK"])
+	assert.Equal(t, "acme", queries[0]["namespace"])
+	assert.Equal(t, map[string]interface{}{
+		"lang":   map[string]interface{}{"$eq": "en"},
+		"tenant": map[string]interface{}{"$eq": "acme"},
+		"year":   map[string]interface{}{"$gte": 2022.0, "$lt": 2024.0},
+	}, queries[0]["filter"])
+
+	// The retriever's own query is left alone.
+	assert.Equal(t, MetadataFilter{"tenant": Eq("acme"), "year": Gte(2020)}, retriever.Query.Filter)
+
+	// Options can't widen the scope of the retriever.
+	_, err = retriever.Retrieve(context.Background(), "question", &VectorQuery{Filter: MetadataFilter{"tenant": Eq("globex")}})
+	assert.ErrorIs(t, err, ErrOutOfScope)
+	retriever.Query.Namespace = "acme"
+	_, err = retriever.Retrieve(context.Background(), "question", &VectorQuery{Namespace: "globex"})
+	assert.ErrorIs(t, err, ErrOutOfScope)
+	assert.Len(t, queries, 1)
+}
+
+func TestCombineConditions(t *testing.T) {
+	combined, err := combineConditions(And(Gt(1), Lte(10.0)), And(Gt(3), Lte(20)))
+	require.NoError(t, err)
+	assert.Equal(t, Condition{"$gt": 3, "$lte": 10.0}, combined)
+
+	combined, err = combineConditions(In("a", "b", "c"), In("c", "b", "d"))
+	require.NoError(t, err)
+	assert.Equal(t, In("c", "b"), combined)
+	_, err = combineConditions(In("a"), In("b"))
+	assert.Error(t, err)
+
+	combined, err = combineConditions(NotIn("a"), NotIn("b"))
+	require.NoError(t, err)
+	assert.Equal(t, NotIn("a", "b"), combined)
+
+	combined, err = combineConditions(Eq(2), Eq(2.0))
+	require.NoError(t, err)
+	assert.Equal(t, Condition{"$eq": 2.0}, combined)
+	_, err = combineConditions(Ne("a"), Ne("b"))
+	assert.Error(t, err)
+	_, err = combineConditions(Gt("a"), Gt(1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package workersai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ImageToTextOptions are the optional settings of CaptionImage.
+type ImageToTextOptions struct {
+	// Prompt steers the description, e.g. "Describe the chart in one sentence".
+	Prompt string
+	// MaxTokens caps the length of the generated text.
+	MaxTokens int
+}
+
+// OCRPreset selects the instructions ExtractText gives to the vision model.
+type OCRPreset string
+
+const (
+	// OCRPlainText extracts all readable text in reading order.
+	OCRPlainText OCRPreset = "plain"
+	// OCRMarkdown extracts the text and keeps headings, lists and tables as Markdown.
+	OCRMarkdown OCRPreset = "markdown"
+	// OCRKeyValue extracts labelled fields (forms, receipts) as "key: value" lines.
+	OCRKeyValue OCRPreset = "key_value"
+)
+
+// ocrPrompts holds the instructions sent for each OCRPreset.
+var ocrPrompts = map[OCRPreset]string{
+	OCRPlainText: "Transcribe all text visible in this image exactly as written, in natural reading order. " +
+		"Output only the transcribed text, with no commentary.",
+	OCRMarkdown: "Transcribe all text visible in this image as Markdown, keeping headings, lists and tables. " +
+		"Output only the Markdown, with no commentary.",
+	OCRKeyValue: "Extract every labelled field in this image (such as on a form or receipt) as one \"key: value\" pair per line. " +
+		"Output only the pairs, with no commentary.",
+}
+
+// imageToTextRequest is the input shared by the image-to-text and vision models.
+// The image is sent as an array of byte values.
+type imageToTextRequest struct {
+	Image     []int     `json:"image"`
+	Prompt    string    `json:"prompt,omitempty"`
+	Messages  []Message `json:"messages,omitempty"`
+	MaxTokens int       `json:"max_tokens,omitempty"`
+}
+
+// imageToTextResult covers both output shapes: image-to-text models return a
+// "description" while vision chat models return a "response".
+type imageToTextResult struct {
+	Description string `json:"description"`
+	Response    string `json:"response"`
+}
+
+// CaptionImage describes image using an image-to-text model such as
+// ModelLlava15 or ModelUFormGen2 and returns the description as plain text.
+func (c *Client) CaptionImage(modelID string, image []byte, opts *ImageToTextOptions) (string, error) {
+	request := imageToTextRequest{
+		Image:  imageBytes(image),
+		Prompt: "Generate a caption for this image",
+	}
+	if opts != nil {
+		if opts.Prompt != "" {
+			request.Prompt = opts.Prompt
+		}
+		request.MaxTokens = opts.MaxTokens
+	}
+
+	var result imageToTextResult
+	if err := c.run(modelID, request, &result); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.text()), nil
+}
+
+// ExtractText reads the text in image using a vision model such as
+// ModelLlama32Vision11B, following the instructions of preset.
+//
+// The result is returned as plain text: a Markdown code fence wrapped around
+// the whole answer by the model is removed.
+func (c *Client) ExtractText(modelID string, image []byte, preset OCRPreset) (string, error) {
+	prompt, ok := ocrPrompts[preset]
+	if !ok {
+		return "", fmt.Errorf("unknown OCR preset: %s", preset)
+	}
+
+	request := imageToTextRequest{
+		Image:    imageBytes(image),
+		Messages: []Message{ChatMessage{Role: "user", Content: prompt}},
+	}
+
+	var result imageToTextResult
+	if err := c.run(modelID, request, &result); err != nil {
+		return "", err
+	}
+	return stripCodeFence(result.text()), nil
+}
+
+func (r imageToTextResult) text() string {
+	if r.Response != "" {
+		return r.Response
+	}
+	return r.Description
+}
+
+// imageBytes converts image to the integer array expected by the API, since
+// encoding/json would otherwise send a []byte as a base64 string.
+func imageBytes(image []byte) []int {
+	values := make([]int, len(image))
+	for i, b := range image {
+		values[i] = int(b)
+	}
+	return values
+}
+
+// stripCodeFence removes a Markdown code fence enclosing the whole of text.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+
+	inner := strings.TrimSuffix(text[3:], "```")
+	// Drop the language tag on the opening line, e.g. ```markdown.
+	if i := strings.IndexByte(inner, '\n'); i >= 0 && !strings.ContainsAny(inner[:i], " \t") {
+		inner = inner[i+1:]
+	}
+	return strings.TrimSpace(inner)
+}
@@ -0,0 +1,57 @@
+package workersai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CaptionImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.URL.Path, ModelLlava15)
+
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, []interface{}{float64(1), float64(2), float64(255)}, req["image"])
+		assert.Equal(t, "Generate a caption for this image", req["prompt"])
+
+		fmt.Fprint(w, `{"success": true, "result": {"description": " A cat on a sofa. "}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	caption, err := client.CaptionImage(ModelLlava15, []byte{1, 2, 255}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "A cat on a sofa.", caption)
+}
+
+func TestClient_ExtractText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []ChatMessage `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Messages, 1)
+		assert.Contains(t, req.Messages[0].Content, "Markdown")
+
+		fmt.Fprint(w, `{"success": true, "result": {"response": "`+"```markdown\\n# Invoice\\n\\nTotal: 10\\n```"+`"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	text, err := client.ExtractText(ModelLlama32Vision11B, []byte{0}, OCRMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, "# Invoice\n\nTotal: 10", text)
+
+	_, err = client.ExtractText(ModelLlama32Vision11B, []byte{0}, OCRPreset("unknown"))
+	require.Error(t, err)
+}
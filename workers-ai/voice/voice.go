@@ -0,0 +1,227 @@
+// Package voice chains speech recognition, a chat turn and speech synthesis
+// into a single call for building voice assistants on Workers AI.
+package voice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// Pipeline runs voice turns against a Workers AI client. The conversation
+// history is kept between turns, so consecutive calls to ProcessAudioTurn
+// form a single conversation.
+//
+// A Pipeline is not safe for concurrent use.
+type Pipeline struct {
+	Client workersai.ClientInterface
+
+	TranscriptionModel string
+	ChatModel          string
+	SpeechModel        string
+
+	// SystemPrompt is sent as the first message of the conversation when set.
+	SystemPrompt string
+	// ModelParameters are passed to every chat turn.
+	ModelParameters *workersai.ModelParameters
+	// SpeechOptions are passed to every speech synthesis call.
+	SpeechOptions *workersai.SpeechOptions
+	// OnSegment, if set, is called with every sentence of the reply as
+	// soon as it is synthesized, in order and from another goroutine, e.g.
+	// to start playing the reply while the model is still writing it.
+	OnSegment func(Segment)
+
+	// History holds the user and assistant turns so far, without the system prompt.
+	History []workersai.Message
+}
+
+// Turn is the outcome of a single voice turn.
+type Turn struct {
+	// Transcript is the recognized text of the user's audio.
+	Transcript string
+	// Reply is the assistant's text answer.
+	Reply string
+	// Audio is the synthesized reply: the audio of the segments one after
+	// the other, which makes a valid stream of MP3, the format of the
+	// default speech model.
+	Audio []byte
+	// Segments are the sentences of the reply, synthesized one by one.
+	Segments []Segment
+	// Usage is the token usage of the chat turn.
+	Usage workersai.Usage
+}
+
+// Segment is a part of a reply, one or more sentences, and its speech.
+type Segment struct {
+	Text  string
+	Audio []byte
+}
+
+// chatStreamer is implemented by *workersai.Client.
+type chatStreamer interface {
+	ChatStreamFunc(ctx context.Context, modelID string, messages []workersai.Message, tools []workersai.Tool, modelParams *workersai.ModelParameters, fn func(*workersai.StreamChunk) error) (*workersai.StreamSummary, error)
+}
+
+// New returns a Pipeline using Whisper, Llama 3 8B and MeloTTS.
+func New(client workersai.ClientInterface) *Pipeline {
+	return &Pipeline{
+		Client:             client,
+		TranscriptionModel: workersai.ModelWhisper,
+		ChatModel:          workersai.ModelLlama38B,
+		SpeechModel:        workersai.ModelMeloTTS,
+	}
+}
+
+// ProcessAudioTurn transcribes audio, asks the chat model for a reply and
+// synthesizes the reply to speech.
+//
+// When the client can stream, e.g. a *workersai.Client, the reply is
+// synthesized sentence by sentence while the model writes it, so that
+// OnSegment gets the first sentence long before the reply is complete.
+// Otherwise each stage is a blocking call. The turn is only appended to
+// History once the chat model answered, so a failed turn can simply be
+// retried.
+func (p *Pipeline) ProcessAudioTurn(audio []byte) (*Turn, error) {
+	transcription, err := p.Client.Transcribe(p.TranscriptionModel, audio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+
+	transcript := strings.TrimSpace(transcription.Text)
+	if transcript == "" {
+		return nil, fmt.Errorf("no speech recognized in audio")
+	}
+
+	userMessage := workersai.ChatMessage{Role: "user", Content: transcript}
+
+	messages := make([]workersai.Message, 0, len(p.History)+2)
+	if p.SystemPrompt != "" {
+		messages = append(messages, workersai.ChatMessage{Role: "system", Content: p.SystemPrompt})
+	}
+	messages = append(messages, p.History...)
+	messages = append(messages, userMessage)
+
+	turn := &Turn{Transcript: transcript}
+	synth := p.synthesize(turn)
+	var splitter sentenceSplitter
+
+	if streamer, ok := p.Client.(chatStreamer); ok {
+		summary, err := streamer.ChatStreamFunc(context.Background(), p.ChatModel, messages, nil, p.ModelParameters, func(chunk *workersai.StreamChunk) error {
+			if sentences := splitter.add(chunk.Content); sentences != "" {
+				synth.queue(sentences)
+			}
+			return nil
+		})
+		if err != nil {
+			synth.wait()
+			return nil, fmt.Errorf("failed to get chat reply: %w", err)
+		}
+		turn.Reply = strings.TrimSpace(summary.Content)
+		turn.Usage = summary.Usage
+	} else {
+		resp, err := p.Client.Chat(p.ChatModel, messages, p.ModelParameters)
+		if err != nil {
+			synth.wait()
+			return nil, fmt.Errorf("failed to get chat reply: %w", err)
+		}
+		turn.Reply = strings.TrimSpace(resp.GetContent())
+		turn.Usage = resp.GetUsage()
+		// The reply is complete already: it is synthesized at once.
+		splitter.pending = turn.Reply
+	}
+	if rest := splitter.flush(); rest != "" {
+		synth.queue(rest)
+	}
+
+	p.History = append(p.History, userMessage, workersai.ChatMessage{Role: "assistant", Content: turn.Reply})
+
+	if err := synth.wait(); err != nil {
+		return turn, fmt.Errorf("failed to synthesize reply: %w", err)
+	}
+	return turn, nil
+}
+
+// synthesizer synthesizes the segments of a reply in order, in the
+// background, adding them to its turn.
+type synthesizer struct {
+	segments chan string
+	done     chan error
+}
+
+func (p *Pipeline) synthesize(turn *Turn) *synthesizer {
+	s := &synthesizer{segments: make(chan string, 64), done: make(chan error, 1)}
+	go func() {
+		var err error
+		for text := range s.segments {
+			if err != nil {
+				continue
+			}
+			var audio []byte
+			if audio, err = p.Client.TextToSpeech(p.SpeechModel, text, p.SpeechOptions); err != nil {
+				continue
+			}
+			segment := Segment{Text: text, Audio: audio}
+			turn.Segments = append(turn.Segments, segment)
+			turn.Audio = append(turn.Audio, audio...)
+			if p.OnSegment != nil {
+				p.OnSegment(segment)
+			}
+		}
+		s.done <- err
+	}()
+	return s
+}
+
+func (s *synthesizer) queue(text string) {
+	s.segments <- text
+}
+
+// wait returns once every queued segment is synthesized, with the first
+// error. No segment can be queued afterwards.
+func (s *synthesizer) wait() error {
+	close(s.segments)
+	return <-s.done
+}
+
+// sentenceSplitter cuts streamed text into sentences, at the punctuation
+// ending them when followed by a space.
+type sentenceSplitter struct {
+	pending string
+}
+
+// add appends text and returns the sentences it completed, if any, as a
+// single string.
+func (s *sentenceSplitter) add(text string) string {
+	s.pending += text
+	end := -1
+	for i, r := range s.pending {
+		if i == 0 || !unicode.IsSpace(r) {
+			continue
+		}
+		if last, _ := utf8.DecodeLastRuneInString(s.pending[:i]); strings.ContainsRune(".!?;:。！？", last) {
+			end = i
+		}
+	}
+	if end < 0 {
+		return ""
+	}
+	sentences := strings.TrimSpace(s.pending[:end])
+	s.pending = s.pending[end:]
+	return sentences
+}
+
+// flush returns the text left after the last sentence.
+func (s *sentenceSplitter) flush() string {
+	rest := strings.TrimSpace(s.pending)
+	s.pending = ""
+	return rest
+}
+
+// Reset clears the conversation history.
+func (p *Pipeline) Reset() {
+	p.History = nil
+}
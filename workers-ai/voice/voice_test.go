@@ -0,0 +1,124 @@
+package voice
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+	"github.com/ashishdatta/workers-ai-golang/workers-ai/workersaimock"
+)
+
+func TestPipeline_ProcessAudioTurn(t *testing.T) {
+	chatCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, workersai.ModelWhisper):
+			assert.Equal(t, "application/octet-stream", r.Header.Get("Content-Type"))
+			audio, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "fake-audio", string(audio))
+			fmt.Fprint(w, `{"success": true, "result": {"text": " What time is it? "}}`)
+
+		case strings.HasSuffix(r.URL.Path, workersai.ModelLlama38B):
+			chatCalls++
+			var req workersai.ChatCompletionRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			// System prompt, the previous turn (if any) and the new user message.
+			require.Len(t, req.Messages, 2*chatCalls)
+			assert.Equal(t, "What time is it?", req.Messages[len(req.Messages)-1].(workersai.ChatMessage).Content)
+			assert.True(t, req.Stream)
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range []string{
+				`{"response": "It is"}`, `{"response": " noon. Have"}`, `{"response": " a nice day!"}`,
+				`{"response": "", "usage": {"total_tokens": 7}}`, `[DONE]`,
+			} {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+
+		case strings.HasSuffix(r.URL.Path, workersai.ModelMeloTTS):
+			var req map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			fmt.Fprintf(w, `{"success": true, "result": {"audio": %q}}`, base64.StdEncoding.EncodeToString([]byte("mp3("+req["prompt"]+")")))
+
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	pipeline := New(client)
+	pipeline.SystemPrompt = "You are a voice assistant."
+	var played []string
+	pipeline.OnSegment = func(segment Segment) { played = append(played, segment.Text) }
+
+	for i := 0; i < 2; i++ {
+		turn, err := pipeline.ProcessAudioTurn([]byte("fake-audio"))
+		require.NoError(t, err)
+		assert.Equal(t, "What time is it?", turn.Transcript)
+		assert.Equal(t, "It is noon. Have a nice day!", turn.Reply)
+		assert.Equal(t, []Segment{
+			{Text: "It is noon.", Audio: []byte("mp3(It is noon.)")},
+			{Text: "Have a nice day!", Audio: []byte("mp3(Have a nice day!)")},
+		}, turn.Segments)
+		assert.Equal(t, []byte("mp3(It is noon.)mp3(Have a nice day!)"), turn.Audio)
+		assert.Equal(t, 7, turn.Usage.TotalTokens)
+	}
+	assert.Len(t, pipeline.History, 4)
+	assert.Equal(t, []string{"It is noon.", "Have a nice day!", "It is noon.", "Have a nice day!"}, played)
+
+	pipeline.Reset()
+	assert.Empty(t, pipeline.History)
+}
+
+func TestPipeline_ProcessAudioTurn_NoSpeech(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "result": {"text": ""}}`)
+	}))
+	defer server.Close()
+
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = server.URL
+
+	_, err := New(client).ProcessAudioTurn([]byte("silence"))
+	require.Error(t, err)
+}
+
+func TestPipeline_ProcessAudioTurn_NoStreaming(t *testing.T) {
+	client := &workersaimock.Client{}
+	client.On("Transcribe", workersai.ModelWhisper, []byte("audio")).Return(&workersai.TranscriptionResult{Text: "Hi"}, nil)
+	client.On("Chat", workersai.ModelLlama38B, mock.Anything, mock.Anything).Return(workersaimock.TextResponse("Hello. How are you?"), nil)
+	client.On("TextToSpeech", workersai.ModelMeloTTS, "Hello. How are you?", mock.Anything).Return([]byte("mp3"), nil)
+
+	turn, err := New(client).ProcessAudioTurn([]byte("audio"))
+	require.NoError(t, err)
+	assert.Equal(t, []Segment{{Text: "Hello. How are you?", Audio: []byte("mp3")}}, turn.Segments)
+	assert.Equal(t, []byte("mp3"), turn.Audio)
+	client.AssertExpectations(t)
+}
+
+func TestSentenceSplitter(t *testing.T) {
+	var s sentenceSplitter
+	assert.Equal(t, "", s.add("It costs 3.5"))
+	assert.Equal(t, "", s.add(" euros."))
+	// The sentences completed at once are grouped.
+	assert.Equal(t, "It costs 3.5 euros. Really?", s.add(" Really? Yes"))
+	assert.Equal(t, "", s.add(" it"))
+	assert.Equal(t, "Yes it", s.flush())
+	assert.Equal(t, "", s.flush())
+}
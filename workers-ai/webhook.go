@@ -0,0 +1,130 @@
+package workersai
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// WebhookSecretHeader is the header in which Cloudflare sends the secret
+// of a webhook destination with every notification.
+const WebhookSecretHeader = "cf-webhook-auth"
+
+// maxWebhookBody bounds the notifications read by WebhookHandler.
+const maxWebhookBody = 1 << 20
+
+// JobNotification is a completion notification of an asynchronous job.
+type JobNotification struct {
+	// RequestID identifies the job, as returned when it was queued.
+	RequestID string `json:"request_id"`
+	Status    string `json:"status"`
+	// Result is the output of the job, if the notification carries it.
+	Result json.RawMessage `json:"result,omitempty"`
+	// Raw is the whole notification.
+	Raw json.RawMessage `json:"-"`
+}
+
+// WebhookHandler receives the completion notifications of asynchronous jobs
+// and hands them to whoever waits for the job. Mount it on the URL given as
+// webhook destination, with the same secret:
+//
+//	webhooks := workersai.NewWebhookHandler(os.Getenv("WEBHOOK_SECRET"))
+//	http.Handle("/workers-ai/callback", webhooks)
+//
+//	done := webhooks.Register(requestID)
+//	notification := <-done
+//
+// Notifications with a missing or wrong secret are rejected with 401.
+// A WebhookHandler is safe for concurrent use.
+type WebhookHandler struct {
+	// Secret is compared with the WebhookSecretHeader of notifications.
+	Secret string
+	// OnUnknown, if set, is called with the notifications of jobs that
+	// aren't registered, e.g. ones queued before a restart.
+	OnUnknown func(JobNotification)
+
+	mu      sync.Mutex
+	pending map[string]chan JobNotification
+}
+
+// NewWebhookHandler returns a handler accepting notifications sent with
+// secret.
+func NewWebhookHandler(secret string) *WebhookHandler {
+	return &WebhookHandler{Secret: secret}
+}
+
+// Register marks requestID as pending and returns the channel its
+// notification will be delivered on. The channel is closed after the
+// notification, or without one by Forget. Registering a pending requestID
+// again forgets the previous registration, whose waiter would otherwise
+// block forever.
+func (h *WebhookHandler) Register(requestID string) <-chan JobNotification {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pending == nil {
+		h.pending = make(map[string]chan JobNotification)
+	}
+	if previous, ok := h.pending[requestID]; ok {
+		close(previous)
+	}
+	done := make(chan JobNotification, 1)
+	h.pending[requestID] = done
+	return done
+}
+
+// Forget stops waiting for requestID, e.g. after a timeout.
+func (h *WebhookHandler) Forget(requestID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if done, ok := h.pending[requestID]; ok {
+		delete(h.pending, requestID)
+		close(done)
+	}
+}
+
+// Pending returns the number of jobs waiting for their notification.
+func (h *WebhookHandler) Pending() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.pending)
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(WebhookSecretHeader)), []byte(h.Secret)) != 1 {
+		http.Error(w, "invalid webhook secret", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "failed to read notification", http.StatusBadRequest)
+		return
+	}
+	var notification JobNotification
+	if err := json.Unmarshal(body, &notification); err != nil || notification.RequestID == "" {
+		http.Error(w, "invalid notification", http.StatusBadRequest)
+		return
+	}
+	notification.Raw = body
+
+	h.mu.Lock()
+	done, ok := h.pending[notification.RequestID]
+	delete(h.pending, notification.RequestID)
+	h.mu.Unlock()
+
+	// Unknown jobs are acknowledged too, so that they aren't redelivered.
+	if ok {
+		done <- notification
+		close(done)
+	} else if h.OnUnknown != nil {
+		h.OnUnknown(notification)
+	}
+	w.WriteHeader(http.StatusOK)
+}
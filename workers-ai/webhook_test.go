@@ -0,0 +1,75 @@
+package workersai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postNotification(h http.Handler, secret, body string) int {
+	req := httptest.NewRequest("POST", "/callback", strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set(WebhookSecretHeader, secret)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookHandler(t *testing.T) {
+	webhooks := NewWebhookHandler("s3cret")
+
+	var unknown []JobNotification
+	webhooks.OnUnknown = func(n JobNotification) { unknown = append(unknown, n) }
+
+	done := webhooks.Register("job-1")
+	assert.Equal(t, 1, webhooks.Pending())
+
+	body := `{"request_id": "job-1", "status": "done", "result": {"response": "Hi"}}`
+	assert.Equal(t, http.StatusUnauthorized, postNotification(webhooks, "", body))
+	assert.Equal(t, http.StatusUnauthorized, postNotification(webhooks, "wrong", body))
+	assert.Equal(t, http.StatusBadRequest, postNotification(webhooks, "s3cret", `{"status": "done"}`))
+	assert.Equal(t, 1, webhooks.Pending())
+
+	assert.Equal(t, http.StatusOK, postNotification(webhooks, "s3cret", body))
+	notification, ok := <-done
+	require.True(t, ok)
+	assert.Equal(t, "job-1", notification.RequestID)
+	assert.Equal(t, "done", notification.Status)
+	assert.JSONEq(t, `{"response": "Hi"}`, string(notification.Result))
+	assert.JSONEq(t, body, string(notification.Raw))
+	_, ok = <-done
+	assert.False(t, ok)
+	assert.Zero(t, webhooks.Pending())
+
+	// A repeated notification is no longer pending.
+	assert.Equal(t, http.StatusOK, postNotification(webhooks, "s3cret", body))
+	require.Len(t, unknown, 1)
+	assert.Equal(t, "job-1", unknown[0].RequestID)
+}
+
+func TestWebhookHandler_Forget(t *testing.T) {
+	webhooks := NewWebhookHandler("s3cret")
+
+	done := webhooks.Register("job-1")
+	webhooks.Forget("job-1")
+	_, ok := <-done
+	assert.False(t, ok)
+	assert.Zero(t, webhooks.Pending())
+
+	// A second registration closes the channel of the first.
+	first := webhooks.Register("job-2")
+	second := webhooks.Register("job-2")
+	_, ok = <-first
+	assert.False(t, ok)
+	assert.Equal(t, 1, webhooks.Pending())
+	assert.Equal(t, http.StatusOK, postNotification(webhooks, "s3cret", `{"request_id": "job-2"}`))
+	assert.Equal(t, "job-2", (<-second).RequestID)
+
+	// Without a secret, nothing is accepted.
+	assert.Equal(t, http.StatusUnauthorized, postNotification(NewWebhookHandler(""), "", `{"request_id": "job-1"}`))
+}
@@ -0,0 +1,143 @@
+// Package workersaimock provides a testify mock of workersai.ClientInterface.
+//
+//	client := &workersaimock.Client{}
+//	client.On("Chat", workersai.ModelLlama38B, mock.Anything, mock.Anything).
+//		Return(workersaimock.TextResponse("Hello!"), nil)
+package workersaimock
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// Client is a mock implementation of workersai.ClientInterface.
+type Client struct {
+	mock.Mock
+}
+
+var _ workersai.ClientInterface = (*Client)(nil)
+
+// TextResponse builds a ChatResponse whose GetContent returns content, for
+// use as a return value of Chat and ChatWithTools.
+func TextResponse(content string) *workersai.ChatResponse {
+	return &workersai.ChatResponse{
+		Success: true,
+		ChatCompletionResponse: workersai.ChatCompletionResponse{
+			Choices: []workersai.Choice{{
+				Message:      workersai.ResponseMessage{Role: "assistant", Content: &content},
+				FinishReason: "stop",
+			}},
+		},
+	}
+}
+
+// ToolCallResponse builds a ChatResponse whose GetToolCalls returns calls.
+func ToolCallResponse(calls ...workersai.ToolCall) *workersai.ChatResponse {
+	return &workersai.ChatResponse{
+		Success: true,
+		ChatCompletionResponse: workersai.ChatCompletionResponse{
+			Choices: []workersai.Choice{{
+				Message:      workersai.ResponseMessage{Role: "assistant", ToolCalls: calls},
+				FinishReason: "tool_calls",
+			}},
+		},
+	}
+}
+
+func (m *Client) Chat(modelID string, messages []workersai.Message, modelParams *workersai.ModelParameters) (*workersai.ChatResponse, error) {
+	args := m.Called(modelID, messages, modelParams)
+	return chatResponse(args, 0), args.Error(1)
+}
+
+func (m *Client) ChatWithTools(modelID string, messages []workersai.Message, tools []workersai.Tool, modelParams *workersai.ModelParameters) (*workersai.ChatResponse, error) {
+	args := m.Called(modelID, messages, tools, modelParams)
+	return chatResponse(args, 0), args.Error(1)
+}
+
+func (m *Client) ChatCompletion(request workersai.ChatCompletionRequest) (*workersai.ChatResponse, error) {
+	args := m.Called(request)
+	return chatResponse(args, 0), args.Error(1)
+}
+
+func (m *Client) ChatCompletionContext(ctx context.Context, request workersai.ChatCompletionRequest) (*workersai.ChatResponse, error) {
+	args := m.Called(ctx, request)
+	return chatResponse(args, 0), args.Error(1)
+}
+
+func (m *Client) ListModels() ([]workersai.ModelInfo, error) {
+	args := m.Called()
+	models, _ := args.Get(0).([]workersai.ModelInfo)
+	return models, args.Error(1)
+}
+
+func (m *Client) GetModelInfo(modelID string) (*workersai.ModelInfo, error) {
+	args := m.Called(modelID)
+	info, _ := args.Get(0).(*workersai.ModelInfo)
+	return info, args.Error(1)
+}
+
+func (m *Client) Translate(modelID, text string, opts workersai.TranslationOptions) (string, error) {
+	args := m.Called(modelID, text, opts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *Client) TranslateBatch(modelID string, texts []string, opts workersai.TranslationOptions) ([]string, error) {
+	args := m.Called(modelID, texts, opts)
+	results, _ := args.Get(0).([]string)
+	return results, args.Error(1)
+}
+
+func (m *Client) Transcribe(modelID string, audio []byte) (*workersai.TranscriptionResult, error) {
+	args := m.Called(modelID, audio)
+	result, _ := args.Get(0).(*workersai.TranscriptionResult)
+	return result, args.Error(1)
+}
+
+func (m *Client) TextToSpeech(modelID, text string, opts *workersai.SpeechOptions) ([]byte, error) {
+	args := m.Called(modelID, text, opts)
+	audio, _ := args.Get(0).([]byte)
+	return audio, args.Error(1)
+}
+
+func (m *Client) CaptionImage(modelID string, image []byte, opts *workersai.ImageToTextOptions) (string, error) {
+	args := m.Called(modelID, image, opts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *Client) ExtractText(modelID string, image []byte, preset workersai.OCRPreset) (string, error) {
+	args := m.Called(modelID, image, preset)
+	return args.String(0), args.Error(1)
+}
+
+func (m *Client) GenerateImage(modelID, prompt string, opts *workersai.ImageOptions) (*workersai.ImageResult, error) {
+	args := m.Called(modelID, prompt, opts)
+	result, _ := args.Get(0).(*workersai.ImageResult)
+	return result, args.Error(1)
+}
+
+func (m *Client) Embed(modelID string, texts []string, opts *workersai.EmbeddingOptions) (*workersai.EmbeddingResult, error) {
+	args := m.Called(modelID, texts, opts)
+	result, _ := args.Get(0).(*workersai.EmbeddingResult)
+	return result, args.Error(1)
+}
+
+func (m *Client) EmbedContext(ctx context.Context, modelID string, texts []string, opts *workersai.EmbeddingOptions) (*workersai.EmbeddingResult, error) {
+	args := m.Called(ctx, modelID, texts, opts)
+	result, _ := args.Get(0).(*workersai.EmbeddingResult)
+	return result, args.Error(1)
+}
+
+func (m *Client) Ping(ctx context.Context) (*workersai.HealthReport, error) {
+	args := m.Called(ctx)
+	report, _ := args.Get(0).(*workersai.HealthReport)
+	return report, args.Error(1)
+}
+
+// chatResponse reads a *ChatResponse return value, tolerating a nil one.
+func chatResponse(args mock.Arguments, index int) *workersai.ChatResponse {
+	resp, _ := args.Get(index).(*workersai.ChatResponse)
+	return resp
+}
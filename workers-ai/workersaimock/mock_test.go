@@ -0,0 +1,44 @@
+package workersaimock
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+func TestClient_Chat(t *testing.T) {
+	client := &Client{}
+	client.On("Chat", workersai.ModelLlama38B, mock.Anything, (*workersai.ModelParameters)(nil)).
+		Return(TextResponse("Hello!"), nil).Once()
+	client.On("Chat", workersai.ModelMistral7B, mock.Anything, mock.Anything).
+		Return(nil, errors.New("boom")).Once()
+
+	var api workersai.ClientInterface = client
+
+	resp, err := api.Chat(workersai.ModelLlama38B, []workersai.Message{workersai.ChatMessage{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello!", resp.GetContent())
+
+	resp, err = api.Chat(workersai.ModelMistral7B, nil, nil)
+	require.EqualError(t, err, "boom")
+	assert.Nil(t, resp)
+
+	client.AssertExpectations(t)
+}
+
+func TestToolCallResponse(t *testing.T) {
+	call := workersai.ToolCall{ID: "call_1", Type: "function", Function: workersai.FunctionToCall{Name: "get_weather", Arguments: "{}"}}
+
+	client := &Client{}
+	client.On("ChatWithTools", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(ToolCallResponse(call), nil)
+
+	resp, err := client.ChatWithTools(workersai.ModelLlama38B, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []workersai.ToolCall{call}, resp.GetToolCalls())
+}
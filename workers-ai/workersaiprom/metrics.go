@@ -0,0 +1,184 @@
+// Package workersaiprom exports Prometheus metrics for a workersai.Client.
+//
+// It lives in its own module so that the client library doesn't depend on
+// the Prometheus client.
+//
+//	metrics, err := workersaiprom.New(prometheus.DefaultRegisterer)
+//	if err != nil {
+//		return err
+//	}
+//	client.Use(metrics.Hooks())
+package workersaiprom
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// Namespace prefixes the names of all metrics.
+const Namespace = "workersai"
+
+// Metrics holds the collectors fed by the client hooks.
+type Metrics struct {
+	// Requests counts requests by model and HTTP status. Requests that got
+	// no response are counted with status "error".
+	Requests *prometheus.CounterVec
+	// Duration observes request latency in seconds by model.
+	Duration *prometheus.HistogramVec
+	// Tokens counts tokens by model and type ("prompt" or "completion").
+	Tokens *prometheus.CounterVec
+	// Cache counts requests answered through AI Gateway by model and cache
+	// status ("hit" or "miss").
+	Cache *prometheus.CounterVec
+	// TimeToFirstChunk observes the seconds until the first chunk of a
+	// stream by model.
+	TimeToFirstChunk *prometheus.HistogramVec
+	// TokensPerSecond observes the generation speed of streams by model.
+	TokensPerSecond *prometheus.HistogramVec
+}
+
+// New creates the metrics and registers them with reg.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_total",
+			Help:      "Total number of Workers AI requests by model and status.",
+		}, []string{"model", "status"}),
+		Duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of Workers AI requests by model.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80},
+		}, []string{"model"}),
+		Tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "tokens_total",
+			Help:      "Total number of tokens processed by model and type.",
+		}, []string{"model", "type"}),
+		Cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "cache_requests_total",
+			Help:      "Total number of AI Gateway requests by model and cache status.",
+		}, []string{"model", "status"}),
+		TimeToFirstChunk: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "stream_time_to_first_chunk_seconds",
+			Help:      "Time until the first chunk of Workers AI streams by model.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"model"}),
+		TokensPerSecond: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "stream_tokens_per_second",
+			Help:      "Generation speed of Workers AI streams by model.",
+			Buckets:   []float64{5, 10, 20, 40, 60, 80, 120, 160, 240, 320},
+		}, []string{"model"}),
+	}
+
+	collectors := []prometheus.Collector{m.Requests, m.Duration, m.Tokens, m.Cache, m.TimeToFirstChunk, m.TokensPerSecond}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			// Reuse the collectors of an earlier registration, so that
+			// several clients can share the same metrics.
+			var are prometheus.AlreadyRegisteredError
+			if !errors.As(err, &are) {
+				return nil, err
+			}
+			switch existing := are.ExistingCollector.(type) {
+			case *prometheus.CounterVec:
+				switch c {
+				case prometheus.Collector(m.Requests):
+					m.Requests = existing
+				case prometheus.Collector(m.Tokens):
+					m.Tokens = existing
+				case prometheus.Collector(m.Cache):
+					m.Cache = existing
+				}
+			case *prometheus.HistogramVec:
+				switch c {
+				case prometheus.Collector(m.Duration):
+					m.Duration = existing
+				case prometheus.Collector(m.TimeToFirstChunk):
+					m.TimeToFirstChunk = existing
+				case prometheus.Collector(m.TokensPerSecond):
+					m.TokensPerSecond = existing
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Hooks returns the client hooks recording into m.
+func (m *Metrics) Hooks() workersai.Hooks {
+	return workersai.Hooks{AfterResponse: m.observe}
+}
+
+func (m *Metrics) observe(event workersai.RequestEvent) {
+	status := "error"
+	if event.StatusCode != 0 {
+		status = strconv.Itoa(event.StatusCode)
+	}
+
+	m.Requests.WithLabelValues(event.Model, status).Inc()
+	m.Duration.WithLabelValues(event.Model).Observe(event.Duration.Seconds())
+
+	if event.CacheStatus != "" {
+		m.Cache.WithLabelValues(event.Model, strings.ToLower(event.CacheStatus)).Inc()
+	}
+
+	if stats := event.Stream; stats != nil && stats.Chunks > 0 {
+		m.TimeToFirstChunk.WithLabelValues(event.Model).Observe(stats.TimeToFirstChunk.Seconds())
+		if stats.TokensPerSecond > 0 {
+			m.TokensPerSecond.WithLabelValues(event.Model).Observe(stats.TokensPerSecond)
+		}
+	}
+
+	if event.Usage.PromptTokens > 0 {
+		m.Tokens.WithLabelValues(event.Model, "prompt").Add(float64(event.Usage.PromptTokens))
+	}
+	if event.Usage.CompletionTokens > 0 {
+		m.Tokens.WithLabelValues(event.Model, "completion").Add(float64(event.Usage.CompletionTokens))
+	}
+}
+
+// SchedulerCollector exports the queue depth by priority and the requests
+// in flight of a workersai.Scheduler. Register it alongside the metrics:
+//
+//	reg.MustRegister(workersaiprom.NewSchedulerCollector(scheduler))
+type SchedulerCollector struct {
+	scheduler *workersai.Scheduler
+	depth     *prometheus.Desc
+	inFlight  *prometheus.Desc
+}
+
+// NewSchedulerCollector returns a collector reading s on every scrape.
+func NewSchedulerCollector(s *workersai.Scheduler) *SchedulerCollector {
+	return &SchedulerCollector{
+		scheduler: s,
+		depth: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "scheduler", "queue_depth"),
+			"Number of requests waiting in the scheduler by priority.", []string{"priority"}, nil),
+		inFlight: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "scheduler", "in_flight"),
+			"Number of requests started by the scheduler and not yet finished.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *SchedulerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depth
+	ch <- c.inFlight
+}
+
+// Collect implements prometheus.Collector.
+func (c *SchedulerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, p := range []workersai.Priority{workersai.PriorityInteractive, workersai.PriorityBatch} {
+		ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(c.scheduler.QueueDepth(p)), p.String())
+	}
+	ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(c.scheduler.InFlight()))
+}
@@ -0,0 +1,108 @@
+package workersaiprom
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+	"github.com/ashishdatta/workers-ai-golang/workers-ai/workersaitest"
+)
+
+func TestMetrics(t *testing.T) {
+	server := workersaitest.NewServer()
+	defer server.Close()
+
+	server.AddFault(workersaitest.RateLimited(1, time.Second))
+
+	reg := prometheus.NewRegistry()
+	metrics, err := New(reg)
+	require.NoError(t, err)
+
+	client := server.NewClient()
+	client.Use(metrics.Hooks())
+
+	messages := []workersai.Message{workersai.ChatMessage{Role: "user", Content: "Hi"}}
+	_, err = client.Chat(workersai.ModelLlama38B, messages, nil)
+	require.Error(t, err)
+	_, err = client.Chat(workersai.ModelLlama38B, messages, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Requests.WithLabelValues(workersai.ModelLlama38B, "429")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Requests.WithLabelValues(workersai.ModelLlama38B, "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Tokens.WithLabelValues(workersai.ModelLlama38B, "prompt")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Tokens.WithLabelValues(workersai.ModelLlama38B, "completion")))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.Duration))
+
+	// Registering again shares the existing collectors.
+	again, err := New(reg)
+	require.NoError(t, err)
+	assert.Same(t, metrics.Requests, again.Requests)
+	assert.Same(t, metrics.Tokens, again.Tokens)
+	assert.Same(t, metrics.Duration, again.Duration)
+	assert.Same(t, metrics.Cache, again.Cache)
+	assert.Same(t, metrics.TimeToFirstChunk, again.TimeToFirstChunk)
+	assert.Same(t, metrics.TokensPerSecond, again.TokensPerSecond)
+}
+
+func TestMetrics_Stream(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := New(reg)
+	require.NoError(t, err)
+
+	hooks := metrics.Hooks()
+	hooks.AfterResponse(workersai.RequestEvent{Model: workersai.ModelLlama38B, StatusCode: 200})
+	hooks.AfterResponse(workersai.RequestEvent{Model: workersai.ModelLlama38B, StatusCode: 200, Stream: &workersai.StreamStats{
+		Chunks:           10,
+		TimeToFirstChunk: 200 * time.Millisecond,
+		Duration:         time.Second,
+		TokensPerSecond:  42,
+	}})
+
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.TimeToFirstChunk))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.TokensPerSecond))
+}
+
+func TestMetrics_Cache(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := New(reg)
+	require.NoError(t, err)
+
+	hooks := metrics.Hooks()
+	hooks.AfterResponse(workersai.RequestEvent{Model: workersai.ModelLlama38B, StatusCode: 200, CacheStatus: workersai.CacheHit})
+	hooks.AfterResponse(workersai.RequestEvent{Model: workersai.ModelLlama38B, StatusCode: 200, CacheStatus: workersai.CacheMiss})
+	hooks.AfterResponse(workersai.RequestEvent{Model: workersai.ModelLlama38B, StatusCode: 200, CacheStatus: workersai.CacheHit})
+	hooks.AfterResponse(workersai.RequestEvent{Model: workersai.ModelLlama38B, StatusCode: 200})
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.Cache.WithLabelValues(workersai.ModelLlama38B, "hit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Cache.WithLabelValues(workersai.ModelLlama38B, "miss")))
+}
+
+func TestSchedulerCollector(t *testing.T) {
+	scheduler := workersai.NewScheduler(0, 1)
+	release, err := scheduler.Acquire(context.Background(), workersai.PriorityInteractive)
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Acquire(ctx, workersai.PriorityBatch) //nolint:errcheck
+	require.Eventually(t, func() bool { return scheduler.QueueDepth(workersai.PriorityBatch) == 1 }, time.Second, time.Millisecond)
+
+	expected := `
+# HELP workersai_scheduler_in_flight Number of requests started by the scheduler and not yet finished.
+# TYPE workersai_scheduler_in_flight gauge
+workersai_scheduler_in_flight 1
+# HELP workersai_scheduler_queue_depth Number of requests waiting in the scheduler by priority.
+# TYPE workersai_scheduler_queue_depth gauge
+workersai_scheduler_queue_depth{priority="batch"} 1
+workersai_scheduler_queue_depth{priority="interactive"} 0
+`
+	assert.NoError(t, testutil.CollectAndCompare(NewSchedulerCollector(scheduler), strings.NewReader(expected)))
+}
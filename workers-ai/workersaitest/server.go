@@ -0,0 +1,282 @@
+// Package workersaitest provides an in-process fake of the Workers AI REST
+// API for tests, with programmable fault injection to exercise retry and
+// streaming logic.
+package workersaitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// DefaultResult is the result returned for models without a configured response.
+const DefaultResult = `{"response": "ok", "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}}`
+
+// Server is a fake Workers AI API listening on a local address.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]json.RawMessage
+	streams   map[string][]string
+	faults    []*Fault
+	requests  []Request
+}
+
+// Request is a request received by the Server.
+type Request struct {
+	Model  string
+	Header http.Header
+	Body   []byte
+}
+
+// Fault describes a failure the Server injects into matching requests.
+// Latency is applied first; then, if set, Status is returned instead of a
+// normal response, otherwise the body is corrupted as configured.
+type Fault struct {
+	// Model restricts the fault to one model. Empty matches every model.
+	Model string
+	// Times is how many requests the fault applies to. Zero means every request.
+	Times int
+
+	// Latency delays the response.
+	Latency time.Duration
+	// Status is the HTTP status code to fail with, e.g. 429 or 503.
+	Status int
+	// RetryAfter sets the Retry-After header of a failed response.
+	RetryAfter time.Duration
+	// Body is the body of a failed response. Defaults to a Cloudflare error envelope.
+	Body string
+	// MalformedJSON cuts the JSON response in half.
+	MalformedJSON bool
+	// TruncateStreamAfter drops the connection of a streaming response after
+	// that many events, before the terminating [DONE] event.
+	TruncateStreamAfter int
+
+	hits int
+}
+
+// Latency returns a fault delaying every response by d.
+func Latency(d time.Duration) *Fault {
+	return &Fault{Latency: d}
+}
+
+// RateLimited returns a fault answering the next times requests with 429
+// Too Many Requests and the given Retry-After.
+func RateLimited(times int, retryAfter time.Duration) *Fault {
+	return &Fault{Times: times, Status: http.StatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+// MalformedJSON returns a fault corrupting the next times responses.
+func MalformedJSON(times int) *Fault {
+	return &Fault{Times: times, MalformedJSON: true}
+}
+
+// TruncatedStream returns a fault dropping the next times streaming
+// responses after events events.
+func TruncatedStream(times, events int) *Fault {
+	return &Fault{Times: times, TruncateStreamAfter: events}
+}
+
+// NewServer starts a Server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		responses: make(map[string]json.RawMessage),
+		streams:   make(map[string][]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// NewClient returns a workersai.Client talking to the Server.
+func (s *Server) NewClient() *workersai.Client {
+	client := workersai.NewClient("test-account", "test-token")
+	client.BaseURL = s.URL
+	return client
+}
+
+// SetResponse sets the "result" returned for modelID. result is marshaled
+// to JSON unless it is a string or json.RawMessage, which are used verbatim.
+func (s *Server) SetResponse(modelID string, result interface{}) {
+	var raw json.RawMessage
+	switch v := result.(type) {
+	case string:
+		raw = json.RawMessage(v)
+	case json.RawMessage:
+		raw = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("workersaitest: failed to marshal result: %v", err))
+		}
+		raw = b
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[normalizeModel(modelID)] = raw
+}
+
+// SetStream sets the "data:" payloads streamed for modelID when the request
+// asks for a stream. The terminating [DONE] event is added by the Server.
+func (s *Server) SetStream(modelID string, events ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[normalizeModel(modelID)] = events
+}
+
+// AddFault injects f into the following requests. Every matching fault
+// applies, in the order they were added: latencies add up, the first
+// Status wins, and the body corruptions combine, so that e.g. a Latency
+// fault and a RateLimited one can be stacked.
+func (s *Server) AddFault(f *Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, f)
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	model := ""
+	if i := strings.Index(r.URL.Path, "/ai/run/"); i >= 0 {
+		model = normalizeModel(r.URL.Path[i+len("/ai/run/"):])
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Model: model, Header: r.Header.Clone(), Body: body})
+	fault := s.matchFault(model)
+	result, ok := s.responses[model]
+	if !ok {
+		result = json.RawMessage(DefaultResult)
+	}
+	events, hasStream := s.streams[model]
+	s.mu.Unlock()
+
+	if fault != nil && fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if fault != nil && fault.Status != 0 {
+		if fault.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((fault.RetryAfter+time.Second-1)/time.Second)))
+		}
+		errBody := fault.Body
+		if errBody == "" {
+			errBody = fmt.Sprintf(`{"success": false, "errors": [{"code": %d, "message": %q}], "messages": [], "result": null}`,
+				fault.Status, http.StatusText(fault.Status))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(fault.Status)
+		io.WriteString(w, errBody)
+		return
+	}
+
+	var probe struct {
+		Stream bool `json:"stream"`
+	}
+	_ = json.Unmarshal(body, &probe)
+
+	if probe.Stream {
+		if !hasStream {
+			events = []string{string(result)}
+		}
+		s.writeStream(w, events, fault)
+		return
+	}
+
+	envelope := fmt.Sprintf(`{"success": true, "errors": [], "messages": [], "result": %s}`, result)
+	if fault != nil && fault.MalformedJSON {
+		envelope = envelope[:len(envelope)/2]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, envelope)
+}
+
+func (s *Server) writeStream(w http.ResponseWriter, events []string, fault *Fault) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+
+	for i, event := range events {
+		if fault != nil && fault.TruncateStreamAfter > 0 && i >= fault.TruncateStreamAfter {
+			// Abort the connection without the terminating chunk.
+			panic(http.ErrAbortHandler)
+		}
+		if fault != nil && fault.MalformedJSON && i == len(events)-1 {
+			event = event[:len(event)/2]
+		}
+		fmt.Fprintf(w, "data: %s\n\n", event)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if fault != nil && fault.TruncateStreamAfter > 0 {
+		panic(http.ErrAbortHandler)
+	}
+
+	io.WriteString(w, "data: [DONE]\n\n")
+}
+
+// matchFault returns the combination of the active faults for model, or
+// nil if there are none, and records their use. The caller must hold s.mu.
+func (s *Server) matchFault(model string) *Fault {
+	var combined *Fault
+	for _, f := range s.faults {
+		if f.Model != "" && normalizeModel(f.Model) != model {
+			continue
+		}
+		if f.Times > 0 && f.hits >= f.Times {
+			continue
+		}
+		f.hits++
+		if combined == nil {
+			combined = &Fault{}
+		}
+		combined.Latency += f.Latency
+		if combined.Status == 0 && f.Status != 0 {
+			combined.Status, combined.RetryAfter, combined.Body = f.Status, f.RetryAfter, f.Body
+		}
+		combined.MalformedJSON = combined.MalformedJSON || f.MalformedJSON
+		if f.TruncateStreamAfter > 0 && (combined.TruncateStreamAfter == 0 || f.TruncateStreamAfter < combined.TruncateStreamAfter) {
+			combined.TruncateStreamAfter = f.TruncateStreamAfter
+		}
+	}
+	return combined
+}
+
+// normalizeModel adds the "@cf/" prefix the client adds to bare model IDs.
+func normalizeModel(modelID string) string {
+	if !strings.HasPrefix(modelID, "@cf/") {
+		return "@cf/" + modelID
+	}
+	return modelID
+}
@@ -0,0 +1,142 @@
+package workersaitest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+var hello = []workersai.Message{workersai.ChatMessage{Role: "user", Content: "Hello"}}
+
+func TestServer_Response(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.SetResponse(workersai.ModelLlama38B, map[string]string{"response": "Hi there"})
+
+	resp, err := server.NewClient().Chat(workersai.ModelLlama38B, hello, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", resp.GetContent())
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, workersai.ModelLlama38B, requests[0].Model)
+	assert.Equal(t, "Bearer test-token", requests[0].Header.Get("Authorization"))
+}
+
+func TestServer_RateLimited(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddFault(RateLimited(1, 2*time.Second))
+	client := server.NewClient()
+
+	_, err := client.Chat(workersai.ModelLlama38B, hello, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+
+	// The fault only applied once.
+	resp, err := client.Chat(workersai.ModelLlama38B, hello, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.GetContent())
+}
+
+func TestServer_RetryAfterHeader(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddFault(RateLimited(0, 1500*time.Millisecond))
+
+	resp, err := http.Post(server.URL+"/accounts/a/ai/run/@cf/m", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+}
+
+func TestServer_Latency(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddFault(&Fault{Model: workersai.ModelMistral7B, Latency: 50 * time.Millisecond})
+	client := server.NewClient()
+
+	start := time.Now()
+	_, err := client.Chat(workersai.ModelLlama38B, hello, nil)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	start = time.Now()
+	_, err = client.Chat(workersai.ModelMistral7B, hello, nil)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestServer_StackedFaults(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddFault(Latency(30 * time.Millisecond))
+	server.AddFault(RateLimited(1, time.Second))
+	server.AddFault(&Fault{Times: 1, Status: http.StatusServiceUnavailable})
+	client := server.NewClient()
+
+	// Both the latency and the first status apply, and the second status
+	// is used up too.
+	start := time.Now()
+	_, err := client.Chat(workersai.ModelLlama38B, hello, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+	start = time.Now()
+	resp, err := client.Chat(workersai.ModelLlama38B, hello, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.GetContent())
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+func TestServer_MalformedJSON(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddFault(MalformedJSON(1))
+
+	_, err := server.NewClient().Chat(workersai.ModelLlama38B, hello, nil)
+	require.Error(t, err)
+}
+
+func TestServer_TruncatedStream(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.SetStream(workersai.ModelLlama38B, `{"response": "a"}`, `{"response": "b"}`, `{"response": "c"}`)
+	server.AddFault(TruncatedStream(1, 2))
+
+	post := func() (string, error) {
+		resp, err := http.Post(server.URL+"/accounts/a/ai/run/"+workersai.ModelLlama38B, "application/json", strings.NewReader(`{"stream": true}`))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		return string(b), err
+	}
+
+	body, err := post()
+	require.Error(t, err)
+	assert.Contains(t, body, `"b"`)
+	assert.NotContains(t, body, `"c"`)
+	assert.NotContains(t, body, "[DONE]")
+
+	body, err = post()
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+}
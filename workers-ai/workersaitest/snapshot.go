@@ -0,0 +1,139 @@
+package workersaitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// SnapshotDir is the directory of the golden files, relative to the
+// directory of the test.
+var SnapshotDir = filepath.Join("testdata", "snapshots")
+
+// UpdateSnapshots makes the snapshot helpers write the golden files instead
+// of comparing with them, like the -update flag of the test binary. The
+// package defines the flag unless an imported package did already; tests
+// with golden files of their own read it with flag.Lookup("update")
+// rather than define it again.
+var UpdateSnapshots = false
+
+func init() {
+	if flag.Lookup("update") == nil {
+		flag.Bool("update", false, "write the golden files of the snapshots")
+	}
+}
+
+// updating reports whether the golden files are to be written.
+func updating() bool {
+	if UpdateSnapshots {
+		return true
+	}
+	f := flag.Lookup("update")
+	return f != nil && f.Value.String() == "true"
+}
+
+// MatchRequestSnapshot compares request, serialized as it is sent to the
+// API, with the golden file of name, to catch accidental changes of the
+// code building prompts. Run the tests with -update to create the golden
+// files or accept intended changes; a missing golden file fails the test
+// otherwise.
+func MatchRequestSnapshot(t testing.TB, name string, request workersai.ChatCompletionRequest) {
+	t.Helper()
+	MatchSnapshot(t, name, request)
+}
+
+// MatchSnapshot is MatchRequestSnapshot for any value encodable as JSON,
+// such as a message list.
+func MatchSnapshot(t testing.TB, name string, v interface{}) {
+	t.Helper()
+
+	got, err := CanonicalJSON(v)
+	if err != nil {
+		t.Fatalf("snapshot %s: %v", name, err)
+	}
+
+	path := filepath.Join(SnapshotDir, name+".json")
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("snapshot %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("snapshot %s: %v", name, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("snapshot %s: %s doesn't exist, run the tests with -update to create it", name, path)
+		return
+	}
+	if err != nil {
+		t.Fatalf("snapshot %s: %v", name, err)
+		return
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("snapshot %s doesn't match %s:\n%s", name, path, lineDiff(string(want), string(got)))
+	}
+}
+
+// CanonicalJSON encodes v as indented JSON with sorted object keys, so that
+// equal values always give the same bytes.
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode: %w", err)
+	}
+
+	// Decoding into interface{} sorts the keys of the objects when they
+	// are encoded again; UseNumber keeps the numbers as written.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode: %w", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(generic); err != nil {
+		return nil, fmt.Errorf("failed to encode: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// lineDiff lists the lines of want and got that differ, prefixed with "-"
+// and "+" respectively.
+func lineDiff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		fmt.Fprintf(&b, "line %d:\n", i+1)
+		if i < len(wantLines) {
+			fmt.Fprintf(&b, "-%s\n", w)
+		}
+		if i < len(gotLines) {
+			fmt.Fprintf(&b, "+%s\n", g)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package workersaitest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workersai "github.com/ashishdatta/workers-ai-golang/workers-ai"
+)
+
+// recordingTB records the failures of a snapshot helper.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func greetingRequest(name string) workersai.ChatCompletionRequest {
+	request := workersai.ChatCompletionRequest{
+		Model: workersai.ModelLlama38B,
+		Messages: []workersai.Message{
+			workersai.ChatMessage{Role: "system", Content: "You greet people <politely>."},
+			workersai.ChatMessage{Role: "user", Content: "Greet " + name},
+		},
+	}
+	request.MaxTokens = 64
+	return request
+}
+
+func TestMatchRequestSnapshot(t *testing.T) {
+	MatchRequestSnapshot(t, "greeting", greetingRequest("Ada"))
+}
+
+func TestMatchSnapshot_Mismatch(t *testing.T) {
+	dir := SnapshotDir
+	SnapshotDir = t.TempDir()
+	defer func() { SnapshotDir = dir }()
+	update := flag.Lookup("update").Value.String()
+	defer flag.Set("update", update)
+	require.NoError(t, flag.Set("update", "false"))
+
+	// A missing golden file fails rather than being created.
+	tb := &recordingTB{TB: t}
+	MatchRequestSnapshot(tb, "greeting", greetingRequest("Ada"))
+	require.Len(t, tb.errors, 1)
+	assert.Contains(t, tb.errors[0], "run the tests with -update")
+	_, err := os.Stat(filepath.Join(SnapshotDir, "greeting.json"))
+	require.True(t, os.IsNotExist(err))
+
+	// -update creates it.
+	require.NoError(t, flag.Set("update", "true"))
+	tb = &recordingTB{TB: t}
+	MatchRequestSnapshot(tb, "greeting", greetingRequest("Ada"))
+	require.NoError(t, flag.Set("update", "false"))
+	assert.Empty(t, tb.errors)
+	_, err = os.Stat(filepath.Join(SnapshotDir, "greeting.json"))
+	require.NoError(t, err)
+
+	MatchRequestSnapshot(tb, "greeting", greetingRequest("Grace"))
+	require.Len(t, tb.errors, 1)
+	assert.Contains(t, tb.errors[0], `-      "content": "Greet Ada",`)
+	assert.Contains(t, tb.errors[0], `+      "content": "Greet Grace",`)
+
+	UpdateSnapshots = true
+	defer func() { UpdateSnapshots = false }()
+	MatchRequestSnapshot(tb, "greeting", greetingRequest("Grace"))
+	UpdateSnapshots = false
+	MatchRequestSnapshot(tb, "greeting", greetingRequest("Grace"))
+	assert.Len(t, tb.errors, 1)
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	a, err := CanonicalJSON(map[string]interface{}{"b": 1, "a": []int{2, 3}})
+	require.NoError(t, err)
+	b, err := CanonicalJSON(struct {
+		B int   `json:"b"`
+		A []int `json:"a"`
+	}{1, []int{2, 3}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "{\n  \"a\": [\n    2,\n    3\n  ],\n  \"b\": 1\n}\n", string(a))
+	assert.Equal(t, a, b)
+}